@@ -0,0 +1,80 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/common"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/config/resolver"
+	"github.com/DataDog/datadog-agent/pkg/forwarder"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	forwarderCmd.AddCommand(forwarderReplayCmd)
+	AgentCmd.AddCommand(forwarderCmd)
+}
+
+var forwarderCmd = &cobra.Command{
+	Use:   "forwarder",
+	Short: "Forwarder related commands",
+	Long:  ``,
+}
+
+var forwarderReplayCmd = &cobra.Command{
+	Use:   "replay <journal directory>",
+	Short: "Send the payloads of a forwarder journal",
+	Long: `Send the payloads written by the forwarder to its journal ('forwarder_journal.mode'),
+for instance on a host without network access, using the API keys and the endpoints
+configured on this host. The payloads keep their original timestamps.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if flagNoColor {
+			color.NoColor = true
+		}
+
+		err := common.SetupConfig(confFilePath)
+		if err != nil {
+			return fmt.Errorf("unable to set up global agent configuration: %v", err)
+		}
+
+		err = config.SetupLogger(loggerName, config.GetEnvDefault("DD_LOG_LEVEL", "warn"), "", "", false, true, false)
+		if err != nil {
+			fmt.Printf("Cannot setup logger, exiting: %v\n", err)
+			return err
+		}
+
+		return replayForwarderJournal(args[0])
+	},
+}
+
+func replayForwarderJournal(journalPath string) error {
+	keysPerDomain, err := config.GetMultipleEndpoints()
+	if err != nil {
+		return fmt.Errorf("misconfiguration of agent endpoints: %v", err)
+	}
+
+	timeout := config.Datadog.GetDuration("forwarder_timeout") * time.Second
+	stats, err := forwarder.ReplayJournal(journalPath, resolver.NewSingleDomainResolvers(keysPerDomain), timeout)
+	fmt.Fprintf(color.Output, "Replayed %d payloads from %d journal files: %d transactions sent, %s\n",
+		stats.Payloads, stats.Files, stats.Transactions-stats.Errors, errorCountString(stats.Errors))
+	if err != nil {
+		return fmt.Errorf("cannot replay the journal: %v", err)
+	}
+	return nil
+}
+
+func errorCountString(errors int) string {
+	if errors == 0 {
+		return color.GreenString("no error")
+	}
+	return color.RedString("%d errors", errors)
+}
@@ -38,6 +38,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/metadata"
 	"github.com/DataDog/datadog-agent/pkg/metadata/host"
 	"github.com/DataDog/datadog-agent/pkg/metadata/inventories"
+	"github.com/DataDog/datadog-agent/pkg/netflow"
 	"github.com/DataDog/datadog-agent/pkg/otlp"
 	"github.com/DataDog/datadog-agent/pkg/pidfile"
 	"github.com/DataDog/datadog-agent/pkg/snmp/traps"
@@ -405,6 +406,16 @@ func StartAgent() error {
 		}
 	}
 
+	// Start NetFlow server
+	if netflow.IsEnabled() {
+		sender, err := demux.GetDefaultSender()
+		if err != nil {
+			log.Errorf("Failed to get default sender for netflow server: %s", err)
+		} else if err = netflow.StartServer(sender); err != nil {
+			log.Errorf("Failed to start netflow server: %s", err)
+		}
+	}
+
 	// start logs-agent
 	if config.Datadog.GetBool("logs_enabled") || config.Datadog.GetBool("log_enabled") {
 		if config.Datadog.GetBool("log_enabled") {
@@ -482,6 +493,7 @@ func StopAgent() {
 		common.MetadataScheduler.Stop()
 	}
 	traps.StopServer()
+	netflow.StopServer()
 	api.StopServer()
 	clcrunnerapi.StopCLCRunnerServer()
 	jmx.StopJmxfetch()
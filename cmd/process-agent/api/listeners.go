@@ -0,0 +1,45 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/DataDog/datadog-agent/pkg/process/net"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// listenersHandler relays the listening sockets inventoried by the system-probe, which the
+// process-agent reaches once its connections check is running
+func listenersHandler(w http.ResponseWriter, _ *http.Request) {
+	sysProbeUtil, err := net.GetRemoteSystemProbeUtil()
+	if err != nil {
+		writeError(err, http.StatusServiceUnavailable, w)
+		return
+	}
+
+	listeners, err := sysProbeUtil.GetListeners()
+	if err != nil {
+		code := http.StatusInternalServerError
+		if errors.Is(err, net.ErrListenerInventoryDisabled) {
+			code = http.StatusNotFound
+		}
+		writeError(err, code, w)
+		return
+	}
+
+	b, err := json.Marshal(listeners)
+	if err != nil {
+		writeError(err, http.StatusInternalServerError, w)
+		return
+	}
+
+	if _, err = w.Write(b); err != nil {
+		_ = log.Warn("received listeners from the system-probe but failed to write them to the client:", err)
+	}
+}
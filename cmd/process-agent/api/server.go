@@ -26,6 +26,7 @@ func setupHandlers(r *mux.Router) {
 	r.HandleFunc("/config/{setting}", settingshttp.Server.SetValue).Methods("POST")
 	r.HandleFunc("/agent/status", statusHandler).Methods("GET")
 	r.HandleFunc("/check/{check}", checkHandler).Methods("GET")
+	r.HandleFunc("/listeners", listenersHandler).Methods("GET")
 }
 
 // StartServer starts the config server
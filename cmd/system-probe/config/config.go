@@ -38,6 +38,7 @@ const (
 	NetworkTracerModule        ModuleName = "network_tracer"
 	OOMKillProbeModule         ModuleName = "oom_kill_probe"
 	TCPQueueLengthTracerModule ModuleName = "tcp_queue_length_tracer"
+	DNSMonitorModule           ModuleName = "dns_monitor"
 	SecurityRuntimeModule      ModuleName = "security_runtime"
 	ProcessModule              ModuleName = "process"
 )
@@ -209,6 +210,10 @@ func load(configPath string) (*Config, error) {
 		log.Info("system_probe_config.enable_tcp_queue_length detected, will enable system-probe with TCP queue length check")
 		c.EnabledModules[TCPQueueLengthTracerModule] = struct{}{}
 	}
+	if cfg.GetBool(key(spNS, "enable_dns_check")) {
+		log.Info("system_probe_config.enable_dns_check detected, will enable system-probe with DNS check")
+		c.EnabledModules[DNSMonitorModule] = struct{}{}
+	}
 	if cfg.GetBool(key(spNS, "enable_oom_kill")) {
 		log.Info("system_probe_config.enable_oom_kill detected, will enable system-probe with OOM Kill check")
 		c.EnabledModules[OOMKillProbeModule] = struct{}{}
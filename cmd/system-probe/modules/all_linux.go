@@ -18,6 +18,7 @@ import (
 var All = []module.Factory{
 	NetworkTracer,
 	TCPQueueLength,
+	DNSMonitor,
 	OOMKillProbe,
 	SecurityRuntime,
 	Process,
@@ -0,0 +1,58 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+// +build linux
+
+package modules
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/DataDog/datadog-agent/cmd/system-probe/api/module"
+	"github.com/DataDog/datadog-agent/cmd/system-probe/config"
+	"github.com/DataDog/datadog-agent/cmd/system-probe/utils"
+	"github.com/DataDog/datadog-agent/pkg/collector/corechecks/ebpf/probe"
+	networkconfig "github.com/DataDog/datadog-agent/pkg/network/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// DNSMonitor Factory
+var DNSMonitor = module.Factory{
+	Name:             config.DNSMonitorModule,
+	ConfigNamespaces: []string{"network_config"},
+	Fn: func(cfg *config.Config) (module.Module, error) {
+		log.Infof("Starting the DNS monitor")
+		m, err := probe.NewDNSMonitor(networkconfig.New())
+		if err != nil {
+			return nil, fmt.Errorf("unable to start the DNS monitor: %w", err)
+		}
+		return &dnsMonitorModule{m}, nil
+	},
+}
+
+var _ module.Module = &dnsMonitorModule{}
+
+type dnsMonitorModule struct {
+	*probe.DNSMonitor
+}
+
+func (d *dnsMonitorModule) Register(httpMux *module.Router) error {
+	httpMux.HandleFunc("/check/dns", utils.WithConcurrencyLimit(utils.DefaultMaxConcurrentRequests, func(w http.ResponseWriter, req *http.Request) {
+		stats := d.DNSMonitor.GetAndFlush()
+		utils.WriteAsJSON(w, stats)
+	}))
+
+	return nil
+}
+
+func (d *dnsMonitorModule) GetStats() map[string]interface{} {
+	stats := make(map[string]interface{})
+	for k, v := range d.DNSMonitor.GetStats() {
+		stats[k] = v
+	}
+	return stats
+}
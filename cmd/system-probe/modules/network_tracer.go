@@ -24,8 +24,11 @@ import (
 	"github.com/DataDog/datadog-agent/cmd/system-probe/utils"
 	"github.com/DataDog/datadog-agent/pkg/network"
 	networkconfig "github.com/DataDog/datadog-agent/pkg/network/config"
+	dbdebugging "github.com/DataDog/datadog-agent/pkg/network/database/debugging"
 	"github.com/DataDog/datadog-agent/pkg/network/encoding"
+	"github.com/DataDog/datadog-agent/pkg/network/flowexport"
 	"github.com/DataDog/datadog-agent/pkg/network/http/debugging"
+	kafkadebugging "github.com/DataDog/datadog-agent/pkg/network/kafka/debugging"
 	"github.com/DataDog/datadog-agent/pkg/network/tracer"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
@@ -51,7 +54,22 @@ var NetworkTracer = module.Factory{
 		log.Infof("Creating tracer for: %s", filepath.Base(os.Args[0]))
 
 		t, err := tracer.NewTracer(ncfg)
-		return &networkTracer{tracer: t}, err
+		if err != nil {
+			return &networkTracer{tracer: t}, err
+		}
+
+		nt := &networkTracer{tracer: t}
+		if ncfg.EnableFlowExport {
+			nt.exporter, err = flowexport.NewExporter(ncfg, func() (*network.Connections, error) {
+				return t.GetActiveConnections(flowexport.ClientID)
+			})
+			if err != nil {
+				log.Errorf("could not start flow export: %s", err)
+			} else {
+				nt.exporter.Start()
+			}
+		}
+		return nt, nil
 	},
 }
 
@@ -59,11 +77,15 @@ var _ module.Module = &networkTracer{}
 
 type networkTracer struct {
 	tracer       *tracer.Tracer
+	exporter     *flowexport.Exporter
 	restartTimer *time.Timer
 }
 
 func (nt *networkTracer) GetStats() map[string]interface{} {
 	stats, _ := nt.tracer.GetStats()
+	if stats != nil && nt.exporter != nil {
+		stats["flow_export"] = nt.exporter.GetStats()
+	}
 	return stats
 }
 
@@ -91,6 +113,38 @@ func (nt *networkTracer) Register(httpMux *module.Router) error {
 		logRequests(id, count, len(cs.Conns), start)
 	}))
 
+	httpMux.HandleFunc("/tcp_health", func(w http.ResponseWriter, req *http.Request) {
+		cs, err := nt.tracer.GetActiveConnections(getClientID(req))
+		if err != nil {
+			log.Errorf("unable to retrieve connections: %s", err)
+			w.WriteHeader(500)
+			return
+		}
+		defer network.Reclaim(cs)
+
+		utils.WriteAsJSON(w, encoding.FormatTCPHealth(cs.Conns))
+	})
+
+	httpMux.HandleFunc("/listeners", func(w http.ResponseWriter, req *http.Request) {
+		listeners, err := nt.tracer.GetListeners()
+		if err != nil {
+			writeListenersError(w, err)
+			return
+		}
+
+		utils.WriteAsJSON(w, encoding.FormatListeners(listeners))
+	})
+
+	httpMux.HandleFunc("/listeners/events", func(w http.ResponseWriter, req *http.Request) {
+		events, err := nt.tracer.GetListenerEvents(getClientID(req))
+		if err != nil {
+			writeListenersError(w, err)
+			return
+		}
+
+		utils.WriteAsJSON(w, encoding.FormatListenerEvents(events))
+	})
+
 	httpMux.HandleFunc("/debug/net_maps", func(w http.ResponseWriter, req *http.Request) {
 		cs, err := nt.tracer.DebugNetworkMaps()
 		if err != nil {
@@ -127,6 +181,30 @@ func (nt *networkTracer) Register(httpMux *module.Router) error {
 		utils.WriteAsJSON(w, debugging.HTTP(cs.HTTP, cs.DNS))
 	})
 
+	httpMux.HandleFunc("/debug/kafka_monitoring", func(w http.ResponseWriter, req *http.Request) {
+		id := getClientID(req)
+		cs, err := nt.tracer.GetActiveConnections(id)
+		if err != nil {
+			log.Errorf("unable to retrieve connections: %s", err)
+			w.WriteHeader(500)
+			return
+		}
+
+		utils.WriteAsJSON(w, kafkadebugging.Kafka(cs.Kafka, cs.DNS))
+	})
+
+	httpMux.HandleFunc("/debug/database_monitoring", func(w http.ResponseWriter, req *http.Request) {
+		id := getClientID(req)
+		cs, err := nt.tracer.GetActiveConnections(id)
+		if err != nil {
+			log.Errorf("unable to retrieve connections: %s", err)
+			w.WriteHeader(500)
+			return
+		}
+
+		utils.WriteAsJSON(w, dbdebugging.Database(cs.Database, cs.DNS))
+	})
+
 	// /debug/ebpf_maps as default will dump all registered maps/perfmaps
 	// an optional ?maps= argument could be pass with a list of map name : ?maps=map1,map2,map3
 	httpMux.HandleFunc("/debug/ebpf_maps", func(w http.ResponseWriter, req *http.Request) {
@@ -167,6 +245,9 @@ func (nt *networkTracer) Register(httpMux *module.Router) error {
 
 // Close will stop all system probe activities
 func (nt *networkTracer) Close() {
+	if nt.exporter != nil {
+		nt.exporter.Stop()
+	}
 	nt.tracer.Stop()
 }
 
@@ -181,6 +262,15 @@ func logRequests(client string, count uint64, connectionsCount int, start time.T
 	}
 }
 
+func writeListenersError(w http.ResponseWriter, err error) {
+	if errors.Is(err, tracer.ErrListenerInventoryDisabled) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	log.Errorf("unable to retrieve listeners: %s", err)
+	w.WriteHeader(500)
+}
+
 func getClientID(req *http.Request) string {
 	var clientID = network.DEBUGCLIENT
 	if rawCID := req.URL.Query().Get("client_id"); rawCID != "" {
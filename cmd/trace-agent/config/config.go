@@ -13,6 +13,7 @@ import (
 	"net/url"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -209,6 +210,32 @@ func applyDatadogConfig(c *config.AgentConfig) error {
 		c.MaxRemoteTPS = coreconfig.Datadog.GetFloat64("apm_config.max_remote_traces_per_second")
 	}
 
+	if coreconfig.Datadog.IsSet("apm_config.tail_sampling.enabled") {
+		c.TailSampling.Enabled = coreconfig.Datadog.GetBool("apm_config.tail_sampling.enabled")
+	}
+	if k := "apm_config.tail_sampling.decision_wait"; coreconfig.Datadog.IsSet(k) {
+		if wait := coreconfig.Datadog.GetInt(k); wait > 0 {
+			c.TailSampling.DecisionWait = getDuration(wait)
+		} else {
+			log.Warnf("Invalid value for %q: %d, it must be a positive number of seconds. Using the default %s.", k, wait, c.TailSampling.DecisionWait)
+		}
+	}
+	if k := "apm_config.tail_sampling.max_traces"; coreconfig.Datadog.IsSet(k) {
+		if max := coreconfig.Datadog.GetInt(k); max > 0 {
+			c.TailSampling.MaxTraces = max
+		} else {
+			log.Warnf("Invalid value for %q: %d, it must be positive. Using the default %d.", k, max, c.TailSampling.MaxTraces)
+		}
+	}
+	if k := "apm_config.tail_sampling.policies"; coreconfig.Datadog.IsSet(k) {
+		var policies []*config.TailSamplingPolicy
+		if err := coreconfig.Datadog.UnmarshalKey(k, &policies); err != nil {
+			log.Errorf("Bad format for %q, it should be a list of policies of the form '{\"type\": \"latency\", \"threshold_ms\": 500}': %v", k, err)
+		} else {
+			c.TailSampling.Policies = policies
+		}
+	}
+
 	if k := "apm_config.ignore_resources"; coreconfig.Datadog.IsSet(k) {
 		c.Ignore["resource"] = coreconfig.Datadog.GetStringSlice(k)
 	}
@@ -228,6 +255,63 @@ func applyDatadogConfig(c *config.AgentConfig) error {
 		}
 	}
 
+	if k := "apm_config.span_rules"; coreconfig.Datadog.IsSet(k) {
+		var rules []*config.SpanRule
+		if err := coreconfig.Datadog.UnmarshalKey(k, &rules); err != nil {
+			log.Errorf("Bad format for %q, it should be a list of rules of the form '{\"service\": \"web-*\", \"action\": \"drop\"}': %v", k, err)
+		} else {
+			c.SpanRules = rules
+		}
+	}
+	if k := "apm_config.span_metrics"; coreconfig.Datadog.IsSet(k) {
+		var defs []*config.SpanMetric
+		if err := coreconfig.Datadog.UnmarshalKey(k, &defs); err != nil {
+			log.Errorf("Bad format for %q, it should be a list of metrics of the form '{\"name\": \"payments.count\", \"service\": \"payments\"}': %v", k, err)
+		} else {
+			c.SpanMetrics = defs
+		}
+	}
+	if k := "apm_config.span_metrics_max_cardinality"; coreconfig.Datadog.IsSet(k) {
+		c.SpanMetricsMaxCardinality = coreconfig.Datadog.GetInt(k)
+	}
+
+	if k := "apm_config.extra_aggregators"; coreconfig.Datadog.IsSet(k) {
+		c.ExtraAggregators = coreconfig.Datadog.GetStringSlice(k)
+	}
+	if k := "apm_config.extra_aggregators_max_cardinality"; coreconfig.Datadog.IsSet(k) {
+		c.ExtraAggregatorsMaxCardinality = coreconfig.Datadog.GetInt(k)
+	}
+
+	if k := "apm_config.zipkin.enabled"; coreconfig.Datadog.IsSet(k) {
+		c.Zipkin.Enabled = coreconfig.Datadog.GetBool(k)
+	}
+	if k := "apm_config.jaeger.enabled"; coreconfig.Datadog.IsSet(k) {
+		c.Jaeger.Enabled = coreconfig.Datadog.GetBool(k)
+	}
+	if k := "apm_config.jaeger.grpc_port"; coreconfig.Datadog.IsSet(k) {
+		c.Jaeger.GRPCPort = coreconfig.Datadog.GetInt(k)
+	}
+
+	if k := "apm_config.local_traces.enabled"; coreconfig.Datadog.IsSet(k) {
+		c.LocalTraces.Enabled = coreconfig.Datadog.GetBool(k)
+	}
+	if k := "apm_config.local_traces.max_traces"; coreconfig.Datadog.IsSet(k) {
+		c.LocalTraces.MaxTraces = coreconfig.Datadog.GetInt(k)
+	}
+	if k := "apm_config.local_traces.max_spans_per_trace"; coreconfig.Datadog.IsSet(k) {
+		c.LocalTraces.MaxSpansPerTrace = coreconfig.Datadog.GetInt(k)
+	}
+
+	if k := "apm_config.service_map.enabled"; coreconfig.Datadog.IsSet(k) {
+		c.ServiceMap.Enabled = coreconfig.Datadog.GetBool(k)
+	}
+	if k := "apm_config.service_map.max_edges"; coreconfig.Datadog.IsSet(k) {
+		c.ServiceMap.MaxEdges = coreconfig.Datadog.GetInt(k)
+	}
+	if k := "apm_config.service_map.edge_ttl_seconds"; coreconfig.Datadog.IsSet(k) {
+		c.ServiceMap.EdgeTTL = getDuration(coreconfig.Datadog.GetInt(k))
+	}
+
 	if coreconfig.Datadog.IsSet("bind_host") || coreconfig.Datadog.IsSet("apm_config.apm_non_local_traffic") {
 		if coreconfig.Datadog.IsSet("bind_host") {
 			host := coreconfig.Datadog.GetString("bind_host")
@@ -321,6 +405,19 @@ func applyDatadogConfig(c *config.AgentConfig) error {
 			log.Errorf("Error reading writer config %q: %v", key, err)
 		}
 	}
+	if k := "apm_config.disk_queue.enabled"; coreconfig.Datadog.IsSet(k) {
+		c.DiskQueue.Enabled = coreconfig.Datadog.GetBool(k)
+	}
+	c.DiskQueue.Path = filepath.Join(coreconfig.Datadog.GetString("run_path"), "apm_payloads_to_retry")
+	if k := "apm_config.disk_queue.path"; coreconfig.Datadog.IsSet(k) {
+		c.DiskQueue.Path = coreconfig.Datadog.GetString(k)
+	}
+	if k := "apm_config.disk_queue.max_size_bytes"; coreconfig.Datadog.IsSet(k) {
+		c.DiskQueue.MaxSizeBytes = coreconfig.Datadog.GetInt64(k)
+	}
+	if k := "apm_config.disk_queue.max_age_seconds"; coreconfig.Datadog.IsSet(k) {
+		c.DiskQueue.MaxAge = getDuration(coreconfig.Datadog.GetInt(k))
+	}
 	if coreconfig.Datadog.IsSet("apm_config.connection_reset_interval") {
 		c.ConnectionResetInterval = getDuration(coreconfig.Datadog.GetInt("apm_config.connection_reset_interval"))
 	}
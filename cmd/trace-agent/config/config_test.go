@@ -830,6 +830,146 @@ func TestLoadEnv(t *testing.T) {
 		assert.Contains(cfg.ReplaceTags, rule2)
 	})
 
+	env = "DD_APM_SPAN_RULES"
+	t.Run(env, func(t *testing.T) {
+		defer cleanConfig()()
+		assert := assert.New(t)
+		err := os.Setenv(env, `[{"name":"health", "resource":"GET /health*", "action":"drop"}, {"service":"web", "meta":{"env":"prod"}, "action":"sample", "sample_rate":0.5, "remove_tags":["secret"]}]`)
+		assert.NoError(err)
+		defer os.Unsetenv(env)
+		cfg, err := LoadConfigFile("./testdata/full.yaml")
+		assert.NoError(err)
+		assert.Equal([]*config.SpanRule{
+			{Name: "health", Resource: "GET /health*", Action: "drop"},
+			{Service: "web", Meta: map[string]string{"env": "prod"}, Action: "sample", SampleRate: 0.5, RemoveTags: []string{"secret"}},
+		}, cfg.SpanRules)
+	})
+
+	env = "DD_APM_SPAN_METRICS"
+	t.Run(env, func(t *testing.T) {
+		defer cleanConfig()()
+		assert := assert.New(t)
+		err := os.Setenv(env, `[{"name":"payments.amount", "type":"distribution", "service":"payments", "value":"payment.amount", "group_by":["currency"]}]`)
+		assert.NoError(err)
+		defer os.Unsetenv(env)
+		err = os.Setenv("DD_APM_SPAN_METRICS_MAX_CARDINALITY", "20")
+		assert.NoError(err)
+		defer os.Unsetenv("DD_APM_SPAN_METRICS_MAX_CARDINALITY")
+		cfg, err := LoadConfigFile("./testdata/full.yaml")
+		assert.NoError(err)
+		assert.Equal([]*config.SpanMetric{
+			{Name: "payments.amount", Type: "distribution", Service: "payments", Value: "payment.amount", GroupBy: []string{"currency"}},
+		}, cfg.SpanMetrics)
+		assert.Equal(20, cfg.SpanMetricsMaxCardinality)
+	})
+
+	env = "DD_APM_EXTRA_AGGREGATORS"
+	t.Run(env, func(t *testing.T) {
+		defer cleanConfig()()
+		assert := assert.New(t)
+		err := os.Setenv(env, "peer.service db.instance")
+		assert.NoError(err)
+		defer os.Unsetenv(env)
+		err = os.Setenv("DD_APM_EXTRA_AGGREGATORS_MAX_CARDINALITY", "20")
+		assert.NoError(err)
+		defer os.Unsetenv("DD_APM_EXTRA_AGGREGATORS_MAX_CARDINALITY")
+		cfg, err := LoadConfigFile("./testdata/full.yaml")
+		assert.NoError(err)
+		assert.Equal([]string{"peer.service", "db.instance"}, cfg.ExtraAggregators)
+		assert.Equal(20, cfg.ExtraAggregatorsMaxCardinality)
+	})
+
+	env = "DD_APM_ZIPKIN_ENABLED"
+	t.Run(env, func(t *testing.T) {
+		defer cleanConfig()()
+		assert := assert.New(t)
+		err := os.Setenv(env, "true")
+		assert.NoError(err)
+		defer os.Unsetenv(env)
+		cfg, err := LoadConfigFile("./testdata/full.yaml")
+		assert.NoError(err)
+		assert.True(cfg.Zipkin.Enabled)
+	})
+
+	env = "DD_APM_JAEGER_ENABLED"
+	t.Run(env, func(t *testing.T) {
+		defer cleanConfig()()
+		assert := assert.New(t)
+		err := os.Setenv(env, "true")
+		assert.NoError(err)
+		defer os.Unsetenv(env)
+		err = os.Setenv("DD_APM_JAEGER_GRPC_PORT", "14250")
+		assert.NoError(err)
+		defer os.Unsetenv("DD_APM_JAEGER_GRPC_PORT")
+		cfg, err := LoadConfigFile("./testdata/full.yaml")
+		assert.NoError(err)
+		assert.True(cfg.Jaeger.Enabled)
+		assert.Equal(14250, cfg.Jaeger.GRPCPort)
+	})
+
+	env = "DD_APM_LOCAL_TRACES_ENABLED"
+	t.Run(env, func(t *testing.T) {
+		defer cleanConfig()()
+		assert := assert.New(t)
+		err := os.Setenv(env, "true")
+		assert.NoError(err)
+		defer os.Unsetenv(env)
+		err = os.Setenv("DD_APM_LOCAL_TRACES_MAX_TRACES", "50")
+		assert.NoError(err)
+		defer os.Unsetenv("DD_APM_LOCAL_TRACES_MAX_TRACES")
+		err = os.Setenv("DD_APM_LOCAL_TRACES_MAX_SPANS_PER_TRACE", "200")
+		assert.NoError(err)
+		defer os.Unsetenv("DD_APM_LOCAL_TRACES_MAX_SPANS_PER_TRACE")
+		cfg, err := LoadConfigFile("./testdata/full.yaml")
+		assert.NoError(err)
+		assert.True(cfg.LocalTraces.Enabled)
+		assert.Equal(50, cfg.LocalTraces.MaxTraces)
+		assert.Equal(200, cfg.LocalTraces.MaxSpansPerTrace)
+	})
+
+	env = "DD_APM_SERVICE_MAP_ENABLED"
+	t.Run(env, func(t *testing.T) {
+		defer cleanConfig()()
+		assert := assert.New(t)
+		err := os.Setenv(env, "true")
+		assert.NoError(err)
+		defer os.Unsetenv(env)
+		err = os.Setenv("DD_APM_SERVICE_MAP_MAX_EDGES", "20")
+		assert.NoError(err)
+		defer os.Unsetenv("DD_APM_SERVICE_MAP_MAX_EDGES")
+		err = os.Setenv("DD_APM_SERVICE_MAP_EDGE_TTL_SECONDS", "60")
+		assert.NoError(err)
+		defer os.Unsetenv("DD_APM_SERVICE_MAP_EDGE_TTL_SECONDS")
+		cfg, err := LoadConfigFile("./testdata/full.yaml")
+		assert.NoError(err)
+		assert.True(cfg.ServiceMap.Enabled)
+		assert.Equal(20, cfg.ServiceMap.MaxEdges)
+		assert.Equal(time.Minute, cfg.ServiceMap.EdgeTTL)
+	})
+
+	env = "DD_APM_DISK_QUEUE_ENABLED"
+	t.Run(env, func(t *testing.T) {
+		defer cleanConfig()()
+		assert := assert.New(t)
+		for k, v := range map[string]string{
+			env:                                 "true",
+			"DD_APM_DISK_QUEUE_PATH":            "/var/lib/datadog/apm",
+			"DD_APM_DISK_QUEUE_MAX_SIZE_BYTES":  "1048576",
+			"DD_APM_DISK_QUEUE_MAX_AGE_SECONDS": "3600",
+		} {
+			assert.NoError(os.Setenv(k, v))
+			defer os.Unsetenv(k)
+		}
+		cfg, err := LoadConfigFile("./testdata/full.yaml")
+		assert.NoError(err)
+		assert.Equal(config.DiskQueueConfig{
+			Enabled:      true,
+			Path:         "/var/lib/datadog/apm",
+			MaxSizeBytes: 1048576,
+			MaxAge:       time.Hour,
+		}, cfg.DiskQueue)
+	})
+
 	env = "DD_APM_FILTER_TAGS_REQUIRE"
 	t.Run(env, func(t *testing.T) {
 		defer cleanConfig()()
@@ -1037,3 +1177,27 @@ func TestLoadEnv(t *testing.T) {
 		}
 	})
 }
+
+func TestTailSamplingConfig(t *testing.T) {
+	for _, tt := range []struct {
+		decisionWait, maxTraces string
+		expected                config.TailSamplingConfig
+	}{
+		{"30", "1000", config.TailSamplingConfig{DecisionWait: 30 * time.Second, MaxTraces: 1000}},
+		// non-positive values are ignored
+		{"0", "0", config.TailSamplingConfig{DecisionWait: 10 * time.Second, MaxTraces: 50000}},
+		{"-5", "-1", config.TailSamplingConfig{DecisionWait: 10 * time.Second, MaxTraces: 50000}},
+	} {
+		t.Run(tt.decisionWait, func(t *testing.T) {
+			defer cleanConfig()()
+			os.Setenv("DD_APM_TAIL_SAMPLING_DECISION_WAIT", tt.decisionWait)
+			defer os.Unsetenv("DD_APM_TAIL_SAMPLING_DECISION_WAIT")
+			os.Setenv("DD_APM_TAIL_SAMPLING_MAX_TRACES", tt.maxTraces)
+			defer os.Unsetenv("DD_APM_TAIL_SAMPLING_MAX_TRACES")
+
+			cfg, err := LoadConfigFile("./testdata/full.yaml")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, cfg.TailSampling)
+		})
+	}
+}
@@ -0,0 +1,146 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// FIXME: we require the `cgo` build tag because of this dep relationship:
+// github.com/DataDog/datadog-agent/pkg/process/net depends on `github.com/DataDog/agent-payload/v5/process`,
+// which has a hard dependency on `github.com/DataDog/zstd_0`, which requires CGO.
+// Should be removed once `github.com/DataDog/agent-payload/v5/process` can be imported with CGO disabled.
+//go:build cgo && linux
+// +build cgo,linux
+
+package ebpf
+
+import (
+	"math"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
+	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/collector/corechecks/ebpf/probe"
+	dd_config "github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/network/dns"
+	process_net "github.com/DataDog/datadog-agent/pkg/process/net"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	dnsCheckName = "dns"
+)
+
+// DNSConfig is the config of the DNS check
+type DNSConfig struct {
+	CollectDNS bool `yaml:"collect_dns"`
+}
+
+// DNSCheck grabs the DNS statistics of the DNS servers queried by the host
+type DNSCheck struct {
+	core.CheckBase
+	instance *DNSConfig
+}
+
+func init() {
+	core.RegisterCheck(dnsCheckName, DNSFactory)
+}
+
+// DNSFactory is exported for integration testing
+func DNSFactory() check.Check {
+	return &DNSCheck{
+		CheckBase: core.NewCheckBase(dnsCheckName),
+		instance:  &DNSConfig{},
+	}
+}
+
+// Parse parses the check configuration
+func (c *DNSConfig) Parse(data []byte) error {
+	// default values
+	c.CollectDNS = true
+
+	return yaml.Unmarshal(data, c)
+}
+
+// Configure parses the check configuration and init the check
+func (d *DNSCheck) Configure(config, initConfig integration.Data, source string) error {
+	// TODO: Remove that hard-code and put it somewhere else
+	process_net.SetSystemProbePath(dd_config.Datadog.GetString("system_probe_config.sysprobe_socket"))
+
+	err := d.CommonConfigure(config, source)
+	if err != nil {
+		return err
+	}
+
+	return d.instance.Parse(config)
+}
+
+// Run executes the check
+func (d *DNSCheck) Run() error {
+	if !d.instance.CollectDNS {
+		return nil
+	}
+
+	sysProbeUtil, err := process_net.GetRemoteSystemProbeUtil()
+	if err != nil {
+		return err
+	}
+
+	data, err := sysProbeUtil.GetCheck("dns")
+	if err != nil {
+		return err
+	}
+
+	sender, err := d.GetSender()
+	if err != nil {
+		return err
+	}
+
+	stats, ok := data.([]probe.DNSStats)
+	if !ok {
+		return log.Errorf("Raw data has incorrect type")
+	}
+
+	for _, s := range stats {
+		submitDNSStats(sender, s)
+	}
+
+	sender.Commit()
+	return nil
+}
+
+func submitDNSStats(sender aggregator.Sender, s probe.DNSStats) {
+	tags := []string{"dns_server:" + s.ServerIP, "protocol:" + s.Protocol, "query_type:" + s.QueryType}
+	if s.Domain != "" {
+		tags = append(tags, "domain:"+s.Domain)
+	}
+
+	sender.Count("dns.timeouts", float64(s.Timeouts), "", tags)
+	for rcode, count := range s.CountByRcode {
+		sender.Count("dns.responses", float64(count), "", withTag(tags, "rcode:"+dns.RcodeName(rcode)))
+	}
+
+	submitLatencyHistogram(sender, s.SuccessLatencies, withTag(tags, "status:success"))
+	submitLatencyHistogram(sender, s.FailureLatencies, withTag(tags, "status:failure"))
+}
+
+// withTag returns a copy of the tags with an extra tag, as the sender keeps the slices it is given
+func withTag(tags []string, tag string) []string {
+	return append(append(make([]string, 0, len(tags)+1), tags...), tag)
+}
+
+// submitLatencyHistogram submits the buckets of a latency histogram, in seconds, as a distribution
+func submitLatencyHistogram(sender aggregator.Sender, h dns.LatencyHistogram, tags []string) {
+	lowerBound := 0.0
+	for i, count := range h {
+		upperBound := math.Inf(1)
+		if i < len(dns.LatencyBucketBounds) {
+			upperBound = float64(dns.LatencyBucketBounds[i]) / 1e6
+		}
+		if count > 0 {
+			sender.HistogramBucket("dns.response_latency", int64(count), lowerBound, upperBound, false, "", tags, false)
+		}
+		lowerBound = upperBound
+	}
+}
@@ -0,0 +1,52 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux_bpf
+// +build linux_bpf
+
+package probe
+
+import (
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/pkg/network/config"
+	"github.com/DataDog/datadog-agent/pkg/network/dns"
+)
+
+// DNSMonitor snoops the DNS traffic of the host to report the health of its DNS servers, independently of the network tracer
+type DNSMonitor struct {
+	reverseDNS dns.ReverseDNS
+}
+
+// NewDNSMonitor creates and starts a DNSMonitor
+func NewDNSMonitor(cfg *config.Config) (*DNSMonitor, error) {
+	// the monitor only needs the DNS statistics, the reverse DNS cache is left empty
+	cfg.CollectDNSStats = true
+
+	reverseDNS, err := dns.NewReverseDNS(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create the DNS monitor: %w", err)
+	}
+	if err := reverseDNS.Start(); err != nil {
+		reverseDNS.Close()
+		return nil, fmt.Errorf("unable to start the DNS monitor: %w", err)
+	}
+	return &DNSMonitor{reverseDNS: reverseDNS}, nil
+}
+
+// Close releases the resources of the monitor
+func (m *DNSMonitor) Close() {
+	m.reverseDNS.Close()
+}
+
+// GetAndFlush returns the DNS statistics by server, domain and query type collected since its previous call
+func (m *DNSMonitor) GetAndFlush() []DNSStats {
+	return aggregateDNSStats(m.reverseDNS.GetDNSStats())
+}
+
+// GetStats returns the telemetry of the monitor
+func (m *DNSMonitor) GetStats() map[string]int64 {
+	return m.reverseDNS.GetStats()
+}
@@ -0,0 +1,35 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !linux_bpf
+// +build !linux_bpf
+
+package probe
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/ebpf"
+	"github.com/DataDog/datadog-agent/pkg/network/config"
+)
+
+// DNSMonitor is not implemented on non-linux systems
+type DNSMonitor struct{}
+
+// NewDNSMonitor is not implemented on non-linux systems
+func NewDNSMonitor(cfg *config.Config) (*DNSMonitor, error) {
+	return nil, ebpf.ErrNotImplemented
+}
+
+// Close is not implemented on non-linux systems
+func (m *DNSMonitor) Close() {}
+
+// GetAndFlush is not implemented on non-linux systems
+func (m *DNSMonitor) GetAndFlush() []DNSStats {
+	return nil
+}
+
+// GetStats is not implemented on non-linux systems
+func (m *DNSMonitor) GetStats() map[string]int64 {
+	return nil
+}
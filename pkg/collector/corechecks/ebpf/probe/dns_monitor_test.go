@@ -0,0 +1,59 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package probe
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go4.org/intern"
+
+	"github.com/DataDog/datadog-agent/pkg/network/dns"
+	"github.com/DataDog/datadog-agent/pkg/process/util"
+)
+
+func TestAggregateDNSStats(t *testing.T) {
+	server := util.AddressFromString("10.0.0.53")
+	client := util.AddressFromString("10.0.0.1")
+	domain := intern.GetByString("example.com")
+
+	newStats := func(rcode uint32, latency uint64) map[*intern.Value]map[dns.QueryType]dns.Stats {
+		s := dns.Stats{Timeouts: 1, CountByRcode: map[uint32]uint32{rcode: 1}}
+		if rcode == dns.RcodeNoError {
+			s.SuccessLatencies.Add(latency)
+		} else {
+			s.FailureLatencies.Add(latency)
+		}
+		return map[*intern.Value]map[dns.QueryType]dns.Stats{domain: {dns.TypeA: s}}
+	}
+
+	stats := dns.StatsByKeyByNameByType{
+		{ServerIP: server, ClientIP: client, ClientPort: 1000, Protocol: syscall.IPPROTO_UDP}: newStats(dns.RcodeNoError, 50),
+		{ServerIP: server, ClientIP: client, ClientPort: 1001, Protocol: syscall.IPPROTO_UDP}: newStats(dns.RcodeNXDomain, 2000),
+		{ServerIP: server, ClientIP: client, ClientPort: 1002, Protocol: syscall.IPPROTO_TCP}: newStats(dns.RcodeNoError, 50),
+	}
+
+	aggregated := aggregateDNSStats(stats)
+	require.Len(t, aggregated, 2)
+	if aggregated[0].Protocol != "udp" {
+		aggregated[0], aggregated[1] = aggregated[1], aggregated[0]
+	}
+
+	udp := aggregated[0]
+	assert.Equal(t, "10.0.0.53", udp.ServerIP)
+	assert.Equal(t, "example.com", udp.Domain)
+	assert.Equal(t, "A", udp.QueryType)
+	assert.Equal(t, uint32(2), udp.Timeouts)
+	assert.Equal(t, map[uint32]uint32{dns.RcodeNoError: 1, dns.RcodeNXDomain: 1}, udp.CountByRcode)
+	assert.Equal(t, uint32(1), udp.SuccessLatencies[0])
+	assert.Equal(t, uint32(1), udp.FailureLatencies.Count())
+
+	tcp := aggregated[1]
+	assert.Equal(t, "tcp", tcp.Protocol)
+	assert.Equal(t, uint32(1), tcp.Timeouts)
+}
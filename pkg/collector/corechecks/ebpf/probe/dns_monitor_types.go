@@ -0,0 +1,83 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package probe
+
+import (
+	"syscall"
+
+	"github.com/google/gopacket/layers"
+
+	"github.com/DataDog/datadog-agent/pkg/network/dns"
+)
+
+// DNSStats contains the statistics of the queries of a domain and query type to a DNS server
+type DNSStats struct {
+	ServerIP  string `json:"server_ip"`
+	Protocol  string `json:"protocol"`
+	Domain    string `json:"domain"`
+	QueryType string `json:"query_type"`
+
+	Timeouts     uint32            `json:"timeouts"`
+	CountByRcode map[uint32]uint32 `json:"count_by_rcode"`
+	// SuccessLatencies and FailureLatencies are histograms of the latencies in microseconds, with the buckets of dns.LatencyBucketBounds
+	SuccessLatencies dns.LatencyHistogram `json:"success_latencies"`
+	FailureLatencies dns.LatencyHistogram `json:"failure_latencies"`
+}
+
+type dnsStatsKey struct {
+	serverIP  string
+	protocol  uint8
+	domain    string
+	queryType dns.QueryType
+}
+
+// aggregateDNSStats merges the DNS statistics of the clients of each server
+func aggregateDNSStats(stats dns.StatsByKeyByNameByType) []DNSStats {
+	byServer := make(map[dnsStatsKey]*DNSStats)
+	for key, byDomain := range stats {
+		for domain, byQueryType := range byDomain {
+			for queryType, s := range byQueryType {
+				k := dnsStatsKey{
+					serverIP:  key.ServerIP.String(),
+					protocol:  key.Protocol,
+					domain:    domain.Get().(string),
+					queryType: queryType,
+				}
+				agg, ok := byServer[k]
+				if !ok {
+					agg = &DNSStats{
+						ServerIP:     k.serverIP,
+						Protocol:     protocolName(k.protocol),
+						Domain:       k.domain,
+						QueryType:    layers.DNSType(queryType).String(),
+						CountByRcode: make(map[uint32]uint32),
+					}
+					byServer[k] = agg
+				}
+
+				agg.Timeouts += s.Timeouts
+				for rcode, count := range s.CountByRcode {
+					agg.CountByRcode[rcode] += count
+				}
+				agg.SuccessLatencies.Merge(s.SuccessLatencies)
+				agg.FailureLatencies.Merge(s.FailureLatencies)
+			}
+		}
+	}
+
+	all := make([]DNSStats, 0, len(byServer))
+	for _, s := range byServer {
+		all = append(all, *s)
+	}
+	return all
+}
+
+func protocolName(protocol uint8) string {
+	if protocol == syscall.IPPROTO_TCP {
+		return "tcp"
+	}
+	return "udp"
+}
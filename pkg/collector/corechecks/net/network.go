@@ -21,6 +21,10 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/autodiscovery/integration"
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
 	core "github.com/DataDog/datadog-agent/pkg/collector/corechecks"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/network/encoding"
+	process_net "github.com/DataDog/datadog-agent/pkg/process/net"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 	"github.com/shirou/gopsutil/v3/net"
 	yaml "gopkg.in/yaml.v2"
@@ -28,6 +32,14 @@ import (
 
 const (
 	networkCheckName = "network"
+
+	// tcpHealthClientID is the client ID the check uses to get from the system-probe the TCP
+	// health events counted since its last run
+	tcpHealthClientID = "network-check"
+
+	// listenerEventsClientID is the client ID the check uses to get from the system-probe the
+	// unexpected listeners appeared since its last run
+	listenerEventsClientID = "network-check"
 )
 
 var (
@@ -68,6 +80,17 @@ var (
 	udpStateMetricsSuffixMapping = map[string]string{
 		"NONE": "connections",
 	}
+
+	tcpHealthMetrics = []struct {
+		name  string
+		value func(encoding.TCPHealth) uint32
+	}{
+		{"system.net.tcp.health.rst_sent", func(h encoding.TCPHealth) uint32 { return h.Stats.RSTSent }},
+		{"system.net.tcp.health.rst_received", func(h encoding.TCPHealth) uint32 { return h.Stats.RSTReceived }},
+		{"system.net.tcp.health.zero_window_probes", func(h encoding.TCPHealth) uint32 { return h.Stats.ZeroWindowProbes }},
+		{"system.net.tcp.health.syn_retransmits", func(h encoding.TCPHealth) uint32 { return h.Stats.SYNRetransmits }},
+		{"system.net.tcp.health.out_of_order", func(h encoding.TCPHealth) uint32 { return h.Stats.OutOfOrder }},
+	}
 )
 
 // NetworkCheck represent a network check
@@ -79,6 +102,8 @@ type NetworkCheck struct {
 
 type networkInstanceConfig struct {
 	CollectConnectionState   bool     `yaml:"collect_connection_state"`
+	CollectTCPHealth         bool     `yaml:"collect_tcp_health"`
+	CollectListeners         bool     `yaml:"collect_listeners"`
 	ExcludedInterfaces       []string `yaml:"excluded_interfaces"`
 	ExcludedInterfaceRe      string   `yaml:"excluded_interface_re"`
 	ExcludedInterfacePattern *regexp.Regexp
@@ -96,6 +121,9 @@ type networkStats interface {
 	ProtoCounters(protocols []string) ([]net.ProtoCountersStat, error)
 	Connections(kind string) ([]net.ConnectionStat, error)
 	NetstatTCPExtCounters() (map[string]int64, error)
+	TCPHealth() ([]encoding.TCPHealth, error)
+	Listeners() ([]encoding.Listener, error)
+	ListenerEvents() ([]encoding.ListenerEvent, error)
 }
 
 type defaultNetworkStats struct{}
@@ -116,6 +144,30 @@ func (n defaultNetworkStats) NetstatTCPExtCounters() (map[string]int64, error) {
 	return netstatTCPExtCounters()
 }
 
+func (n defaultNetworkStats) TCPHealth() ([]encoding.TCPHealth, error) {
+	sysProbeUtil, err := process_net.GetRemoteSystemProbeUtil()
+	if err != nil {
+		return nil, err
+	}
+	return sysProbeUtil.GetTCPHealth(tcpHealthClientID)
+}
+
+func (n defaultNetworkStats) Listeners() ([]encoding.Listener, error) {
+	sysProbeUtil, err := process_net.GetRemoteSystemProbeUtil()
+	if err != nil {
+		return nil, err
+	}
+	return sysProbeUtil.GetListeners()
+}
+
+func (n defaultNetworkStats) ListenerEvents() ([]encoding.ListenerEvent, error) {
+	sysProbeUtil, err := process_net.GetRemoteSystemProbeUtil()
+	if err != nil {
+		return nil, err
+	}
+	return sysProbeUtil.GetListenerEvents(listenerEventsClientID)
+}
+
 // Run executes the check
 func (c *NetworkCheck) Run() error {
 	sender, err := c.GetSender()
@@ -179,6 +231,33 @@ func (c *NetworkCheck) Run() error {
 		submitConnectionsMetrics(sender, "tcp6", tcpStateMetricsSuffixMapping, connectionsStats)
 	}
 
+	if c.config.instance.CollectTCPHealth {
+		// the TCP health events are counted by the system-probe, which may not be running
+		health, err := c.net.TCPHealth()
+		if err != nil {
+			log.Debugf("could not get the TCP health events from the system-probe: %s", err)
+		} else {
+			submitTCPHealthMetrics(sender, health)
+		}
+	}
+
+	if c.config.instance.CollectListeners {
+		// the listeners are inventoried by the system-probe, which may not be running
+		listeners, err := c.net.Listeners()
+		if err != nil {
+			log.Debugf("could not get the listeners from the system-probe: %s", err)
+		} else {
+			submitListenersMetrics(sender, listeners)
+		}
+
+		events, err := c.net.ListenerEvents()
+		if err != nil {
+			log.Debugf("could not get the listener events from the system-probe: %s", err)
+		} else {
+			submitListenerEvents(sender, events)
+		}
+	}
+
 	sender.Commit()
 	return nil
 }
@@ -233,6 +312,67 @@ func submitConnectionsMetrics(sender aggregator.Sender, protocolName string, sta
 	}
 }
 
+// submitTCPHealthMetrics submits the TCP health counters per connection direction: the remote
+// endpoints are not reported as tags, their cardinality is unbounded.
+func submitTCPHealthMetrics(sender aggregator.Sender, health []encoding.TCPHealth) {
+	countsPerDirection := make(map[string][]float64)
+	for _, h := range health {
+		counts, ok := countsPerDirection[h.Direction]
+		if !ok {
+			counts = make([]float64, len(tcpHealthMetrics))
+			countsPerDirection[h.Direction] = counts
+		}
+		for i, metric := range tcpHealthMetrics {
+			counts[i] += float64(metric.value(h))
+		}
+	}
+
+	for direction, counts := range countsPerDirection {
+		tags := []string{"direction:" + direction}
+		for i, metric := range tcpHealthMetrics {
+			if counts[i] > 0 {
+				sender.Count(metric.name, counts[i], "", tags)
+			}
+		}
+	}
+}
+
+func submitListenersMetrics(sender aggregator.Sender, listeners []encoding.Listener) {
+	counts := make(map[[2]string]int)
+	for _, l := range listeners {
+		counts[[2]string{l.Protocol, l.Family}]++
+	}
+	for key, count := range counts {
+		tags := []string{"protocol:" + key[0], "family:" + key[1]}
+		sender.Gauge("system.net.listeners", float64(count), "", tags)
+	}
+}
+
+func submitListenerEvents(sender aggregator.Sender, events []encoding.ListenerEvent) {
+	for _, e := range events {
+		addr := fmt.Sprintf("%s:%d", e.Addr, e.Port)
+		if e.Family == "v6" {
+			addr = fmt.Sprintf("[%s]:%d", e.Addr, e.Port)
+		}
+		sender.Event(metrics.Event{
+			Title:          fmt.Sprintf("Unexpected %s listener on port %d", e.Protocol, e.Port),
+			Text:           fmt.Sprintf("Process %d started listening on %s in network namespace %d, on a port which is not allowed.", e.Pid, addr, e.NetNS),
+			Ts:             e.Timestamp.Unix(),
+			Priority:       metrics.EventPriorityNormal,
+			AlertType:      metrics.EventAlertTypeWarning,
+			AggregationKey: fmt.Sprintf("listener:%s:%d", e.Protocol, e.Port),
+			SourceTypeName: networkCheckName,
+			EventType:      "unexpected_listener",
+			Tags: []string{
+				"protocol:" + e.Protocol,
+				"family:" + e.Family,
+				fmt.Sprintf("port:%d", e.Port),
+				fmt.Sprintf("pid:%d", e.Pid),
+			},
+		})
+	}
+}
+
 func netstatTCPExtCounters() (map[string]int64, error) {
 
 	f, err := os.Open("/proc/net/netstat")
@@ -293,6 +433,10 @@ func (c *NetworkCheck) Configure(rawInstance integration.Data, rawInitConfig int
 		return err
 	}
 
+	if c.config.instance.CollectTCPHealth || c.config.instance.CollectListeners {
+		process_net.SetSystemProbePath(config.Datadog.GetString("system_probe_config.sysprobe_socket"))
+	}
+
 	if c.config.instance.ExcludedInterfaceRe != "" {
 		pattern, err := regexp.Compile(c.config.instance.ExcludedInterfaceRe)
 		if err != nil {
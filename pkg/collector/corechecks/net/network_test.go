@@ -10,8 +10,12 @@ package net
 
 import (
 	"testing"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/network"
+	"github.com/DataDog/datadog-agent/pkg/network/encoding"
 	"github.com/shirou/gopsutil/v3/net"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -32,6 +36,10 @@ type fakeNetworkStats struct {
 	connectionStatsTCP6Error    error
 	netstatTCPExtCountersValues map[string]int64
 	netstatTCPExtCountersError  error
+	tcpHealth                   []encoding.TCPHealth
+	tcpHealthError              error
+	listeners                   []encoding.Listener
+	listenerEvents              []encoding.ListenerEvent
 }
 
 // IOCounters returns the inner values of counterStats and counterStatsError
@@ -63,6 +71,18 @@ func (n *fakeNetworkStats) NetstatTCPExtCounters() (map[string]int64, error) {
 	return n.netstatTCPExtCountersValues, n.netstatTCPExtCountersError
 }
 
+func (n *fakeNetworkStats) TCPHealth() ([]encoding.TCPHealth, error) {
+	return n.tcpHealth, n.tcpHealthError
+}
+
+func (n *fakeNetworkStats) Listeners() ([]encoding.Listener, error) {
+	return n.listeners, nil
+}
+
+func (n *fakeNetworkStats) ListenerEvents() ([]encoding.ListenerEvent, error) {
+	return n.listenerEvents, nil
+}
+
 func TestDefaultConfiguration(t *testing.T) {
 	check := NetworkCheck{}
 	check.Configure([]byte(``), []byte(``), "test")
@@ -514,3 +534,122 @@ excluded_interface_re: "eth[0-9]"
 	mockSender.AssertCalled(t, "Rate", "system.net.packets_out.drop", float64(32), "", lo0Tags)
 	mockSender.AssertCalled(t, "Rate", "system.net.packets_out.error", float64(33), "", lo0Tags)
 }
+
+func TestTCPHealth(t *testing.T) {
+	net := &fakeNetworkStats{
+		tcpHealth: []encoding.TCPHealth{
+			{
+				RemoteAddr:  "10.2.2.2",
+				Port:        5432,
+				Direction:   "outgoing",
+				Connections: 2,
+				Stats:       network.TCPHealthStats{RSTReceived: 2, SYNRetransmits: 3},
+			},
+			{
+				RemoteAddr:  "10.3.3.3",
+				Port:        8080,
+				Direction:   "incoming",
+				Connections: 1,
+				Stats:       network.TCPHealthStats{ZeroWindowProbes: 4, OutOfOrder: 5, RSTSent: 1},
+			},
+			{
+				RemoteAddr:  "10.4.4.4",
+				Port:        8080,
+				Direction:   "incoming",
+				Connections: 3,
+				Stats:       network.TCPHealthStats{RSTSent: 2},
+			},
+		},
+	}
+
+	networkCheck := NetworkCheck{
+		net: net,
+	}
+
+	rawInstanceConfig := []byte(`
+collect_tcp_health: true
+`)
+
+	err := networkCheck.Configure(rawInstanceConfig, []byte(``), "test")
+	assert.Nil(t, err)
+
+	mockSender := mocksender.NewMockSender(networkCheck.ID())
+
+	mockSender.On("Gauge", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	mockSender.On("Rate", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	mockSender.On("MonotonicCount", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	mockSender.On("Count", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	mockSender.On("Commit").Return()
+
+	err = networkCheck.Run()
+	assert.Nil(t, err)
+
+	// The counters are aggregated per direction
+	outgoingTags := []string{"direction:outgoing"}
+	mockSender.AssertCalled(t, "Count", "system.net.tcp.health.rst_received", float64(2), "", outgoingTags)
+	mockSender.AssertCalled(t, "Count", "system.net.tcp.health.syn_retransmits", float64(3), "", outgoingTags)
+	mockSender.AssertNotCalled(t, "Count", "system.net.tcp.health.rst_sent", mock.Anything, "", outgoingTags)
+
+	incomingTags := []string{"direction:incoming"}
+	mockSender.AssertCalled(t, "Count", "system.net.tcp.health.rst_sent", float64(3), "", incomingTags)
+	mockSender.AssertCalled(t, "Count", "system.net.tcp.health.zero_window_probes", float64(4), "", incomingTags)
+	mockSender.AssertCalled(t, "Count", "system.net.tcp.health.out_of_order", float64(5), "", incomingTags)
+	mockSender.AssertNumberOfCalls(t, "Count", 5)
+}
+
+func TestListeners(t *testing.T) {
+	ts := time.Unix(1635933600, 0)
+	net := &fakeNetworkStats{
+		listeners: []encoding.Listener{
+			{Pid: 10, NetNS: 1, Protocol: "tcp", Family: "v4", Addr: "0.0.0.0", Port: 22},
+			{Pid: 10, NetNS: 1, Protocol: "tcp", Family: "v6", Addr: "::", Port: 22},
+			{Pid: 20, NetNS: 1, Protocol: "tcp", Family: "v4", Addr: "127.0.0.1", Port: 8080},
+			{Pid: 30, NetNS: 1, Protocol: "udp", Family: "v4", Addr: "0.0.0.0", Port: 53},
+		},
+		listenerEvents: []encoding.ListenerEvent{
+			{
+				Listener:  encoding.Listener{Pid: 42, NetNS: 1, Protocol: "tcp", Family: "v6", Addr: "::", Port: 4444},
+				Timestamp: ts,
+			},
+		},
+	}
+
+	networkCheck := NetworkCheck{
+		net: net,
+	}
+
+	rawInstanceConfig := []byte(`
+collect_listeners: true
+`)
+
+	err := networkCheck.Configure(rawInstanceConfig, []byte(``), "test")
+	assert.Nil(t, err)
+
+	mockSender := mocksender.NewMockSender(networkCheck.ID())
+
+	mockSender.On("Gauge", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	mockSender.On("Rate", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	mockSender.On("MonotonicCount", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	mockSender.On("Event", mock.Anything).Return()
+	mockSender.On("Commit").Return()
+
+	err = networkCheck.Run()
+	assert.Nil(t, err)
+
+	mockSender.AssertCalled(t, "Gauge", "system.net.listeners", float64(2), "", []string{"protocol:tcp", "family:v4"})
+	mockSender.AssertCalled(t, "Gauge", "system.net.listeners", float64(1), "", []string{"protocol:tcp", "family:v6"})
+	mockSender.AssertCalled(t, "Gauge", "system.net.listeners", float64(1), "", []string{"protocol:udp", "family:v4"})
+
+	mockSender.AssertCalled(t, "Event", metrics.Event{
+		Title:          "Unexpected tcp listener on port 4444",
+		Text:           "Process 42 started listening on [::]:4444 in network namespace 1, on a port which is not allowed.",
+		Ts:             ts.Unix(),
+		Priority:       metrics.EventPriorityNormal,
+		AlertType:      metrics.EventAlertTypeWarning,
+		AggregationKey: "listener:tcp:4444",
+		SourceTypeName: "network",
+		EventType:      "unexpected_listener",
+		Tags:           []string{"protocol:tcp", "family:v6", "port:4444", "pid:42"},
+	})
+	mockSender.AssertNumberOfCalls(t, "Event", 1)
+}
@@ -0,0 +1,86 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package devicestore keeps the metadata of the devices monitored by the SNMP check, so that the other
+// network devices features of the agent, like the flow collector, can enrich their data with it.
+package devicestore
+
+import (
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/corechecks/snmp/internal/metadata"
+)
+
+// DeviceMetadata contains device metadata
+type DeviceMetadata = metadata.DeviceMetadata
+
+// InterfaceMetadata contains interface metadata
+type InterfaceMetadata = metadata.InterfaceMetadata
+
+// entryTTL is how long the metadata of a device is kept after the last check run that reported it
+const entryTTL = time.Hour
+
+// Device holds the last metadata reported for a device and its interfaces
+type Device struct {
+	Metadata DeviceMetadata
+	// Interfaces are indexed by ifIndex
+	Interfaces map[int32]InterfaceMetadata
+
+	updated time.Time
+}
+
+type deviceKey struct {
+	namespace string
+	ipAddress string
+}
+
+var (
+	devicesMu sync.RWMutex
+	devices   = make(map[deviceKey]*Device)
+
+	timeNow = time.Now
+)
+
+// SetDevice stores the metadata of a device, replacing the previous one.
+// The previous interfaces are kept when none are given, which happens when the device is unreachable.
+func SetDevice(namespace string, device DeviceMetadata, interfaces []InterfaceMetadata) {
+	key := deviceKey{namespace: namespace, ipAddress: device.IPAddress}
+
+	devicesMu.Lock()
+	defer devicesMu.Unlock()
+
+	now := timeNow()
+	for k, d := range devices {
+		if now.Sub(d.updated) > entryTTL {
+			delete(devices, k)
+		}
+	}
+
+	d := &Device{
+		Metadata:   device,
+		Interfaces: make(map[int32]InterfaceMetadata, len(interfaces)),
+		updated:    now,
+	}
+	for _, itf := range interfaces {
+		d.Interfaces[itf.Index] = itf
+	}
+	if prev, ok := devices[key]; ok && len(interfaces) == 0 {
+		d.Interfaces = prev.Interfaces
+	}
+	devices[key] = d
+}
+
+// GetDevice returns the metadata of the device with the given IP address in the namespace, if the SNMP check reported it recently
+func GetDevice(namespace string, ipAddress string) (*Device, bool) {
+	devicesMu.RLock()
+	defer devicesMu.RUnlock()
+
+	d, ok := devices[deviceKey{namespace: namespace, ipAddress: ipAddress}]
+	if !ok || timeNow().Sub(d.updated) > entryTTL {
+		return nil, false
+	}
+	return d, true
+}
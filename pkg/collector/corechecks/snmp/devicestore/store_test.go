@@ -0,0 +1,57 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package devicestore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func resetStore(now time.Time) {
+	devicesMu.Lock()
+	defer devicesMu.Unlock()
+	devices = make(map[deviceKey]*Device)
+	timeNow = func() time.Time { return now }
+}
+
+func TestSetGetDevice(t *testing.T) {
+	now := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	resetStore(now)
+	defer func() { timeNow = time.Now }()
+
+	SetDevice("default", DeviceMetadata{ID: "default:1.2.3.4", IPAddress: "1.2.3.4", Name: "router"}, []InterfaceMetadata{
+		{DeviceID: "default:1.2.3.4", Index: 1, Name: "eth0"},
+		{DeviceID: "default:1.2.3.4", Index: 2, Name: "eth1"},
+	})
+
+	d, ok := GetDevice("default", "1.2.3.4")
+	require.True(t, ok)
+	assert.Equal(t, "router", d.Metadata.Name)
+	assert.Equal(t, "eth1", d.Interfaces[2].Name)
+
+	_, ok = GetDevice("other", "1.2.3.4")
+	assert.False(t, ok)
+	_, ok = GetDevice("default", "1.2.3.5")
+	assert.False(t, ok)
+
+	// an unreachable device is reported without interfaces
+	SetDevice("default", DeviceMetadata{ID: "default:1.2.3.4", IPAddress: "1.2.3.4", Status: 2}, nil)
+	d, ok = GetDevice("default", "1.2.3.4")
+	require.True(t, ok)
+	assert.Empty(t, d.Metadata.Name)
+	assert.Len(t, d.Interfaces, 2)
+
+	// the devices no longer reported expire
+	timeNow = func() time.Time { return now.Add(entryTTL + time.Second) }
+	_, ok = GetDevice("default", "1.2.3.4")
+	assert.False(t, ok)
+
+	SetDevice("default", DeviceMetadata{ID: "default:5.6.7.8", IPAddress: "5.6.7.8"}, nil)
+	assert.Len(t, devices, 1)
+}
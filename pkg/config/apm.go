@@ -34,6 +34,9 @@ func setupAPM(config Config) {
 	config.SetKnown("apm_config.obfuscation.remove_stack_traces")
 	config.SetKnown("apm_config.obfuscation.redis.enabled")
 	config.SetKnown("apm_config.obfuscation.memcached.enabled")
+	config.SetKnown("apm_config.obfuscation.graphql.enabled")
+	config.SetKnown("apm_config.obfuscation.dynamodb.enabled")
+	config.SetKnown("apm_config.obfuscation.sensitive_data")
 	config.SetKnown("apm_config.filter_tags.require")
 	config.SetKnown("apm_config.filter_tags.reject")
 	config.SetKnown("apm_config.extra_sample_rate")
@@ -72,6 +75,24 @@ func setupAPM(config Config) {
 	config.BindEnv("apm_config.errors_per_second", "DD_APM_ERROR_TPS")
 	config.BindEnv("apm_config.disable_rare_sampler", "DD_APM_DISABLE_RARE_SAMPLER")
 	config.BindEnv("apm_config.max_remote_traces_per_second", "DD_APM_MAX_REMOTE_TPS")
+	config.BindEnv("apm_config.tail_sampling.enabled", "DD_APM_TAIL_SAMPLING_ENABLED")
+	config.BindEnv("apm_config.tail_sampling.decision_wait", "DD_APM_TAIL_SAMPLING_DECISION_WAIT")
+	config.BindEnv("apm_config.tail_sampling.max_traces", "DD_APM_TAIL_SAMPLING_MAX_TRACES")
+	config.BindEnv("apm_config.extra_aggregators", "DD_APM_EXTRA_AGGREGATORS")
+	config.BindEnv("apm_config.extra_aggregators_max_cardinality", "DD_APM_EXTRA_AGGREGATORS_MAX_CARDINALITY")
+	config.BindEnv("apm_config.zipkin.enabled", "DD_APM_ZIPKIN_ENABLED")
+	config.BindEnv("apm_config.jaeger.enabled", "DD_APM_JAEGER_ENABLED")
+	config.BindEnv("apm_config.jaeger.grpc_port", "DD_APM_JAEGER_GRPC_PORT")
+	config.BindEnv("apm_config.local_traces.enabled", "DD_APM_LOCAL_TRACES_ENABLED")
+	config.BindEnv("apm_config.local_traces.max_traces", "DD_APM_LOCAL_TRACES_MAX_TRACES")
+	config.BindEnv("apm_config.local_traces.max_spans_per_trace", "DD_APM_LOCAL_TRACES_MAX_SPANS_PER_TRACE")
+	config.BindEnv("apm_config.service_map.enabled", "DD_APM_SERVICE_MAP_ENABLED")
+	config.BindEnv("apm_config.service_map.max_edges", "DD_APM_SERVICE_MAP_MAX_EDGES")
+	config.BindEnv("apm_config.service_map.edge_ttl_seconds", "DD_APM_SERVICE_MAP_EDGE_TTL_SECONDS")
+	config.BindEnv("apm_config.disk_queue.enabled", "DD_APM_DISK_QUEUE_ENABLED")
+	config.BindEnv("apm_config.disk_queue.path", "DD_APM_DISK_QUEUE_PATH")
+	config.BindEnv("apm_config.disk_queue.max_size_bytes", "DD_APM_DISK_QUEUE_MAX_SIZE_BYTES")
+	config.BindEnv("apm_config.disk_queue.max_age_seconds", "DD_APM_DISK_QUEUE_MAX_AGE_SECONDS")
 
 	config.BindEnv("apm_config.max_memory", "DD_APM_MAX_MEMORY")
 	config.BindEnv("apm_config.max_cpu_percent", "DD_APM_MAX_CPU_PERCENT")
@@ -84,6 +105,9 @@ func setupAPM(config Config) {
 	config.BindEnv("apm_config.profiling_additional_endpoints", "DD_APM_PROFILING_ADDITIONAL_ENDPOINTS")
 	config.BindEnv("apm_config.additional_endpoints", "DD_APM_ADDITIONAL_ENDPOINTS")
 	config.BindEnv("apm_config.replace_tags", "DD_APM_REPLACE_TAGS")
+	config.BindEnv("apm_config.span_rules", "DD_APM_SPAN_RULES")
+	config.BindEnv("apm_config.span_metrics", "DD_APM_SPAN_METRICS")
+	config.BindEnv("apm_config.span_metrics_max_cardinality", "DD_APM_SPAN_METRICS_MAX_CARDINALITY")
 	config.BindEnv("apm_config.analyzed_spans", "DD_APM_ANALYZED_SPANS")
 	config.BindEnv("apm_config.ignore_resources", "DD_APM_IGNORE_RESOURCES", "DD_IGNORE_RESOURCE")
 	config.BindEnv("apm_config.receiver_socket", "DD_APM_RECEIVER_SOCKET")
@@ -125,6 +149,22 @@ func setupAPM(config Config) {
 		return out
 	})
 
+	config.SetEnvKeyTransformer("apm_config.span_rules", func(in string) interface{} {
+		var out []map[string]interface{}
+		if err := json.Unmarshal([]byte(in), &out); err != nil {
+			log.Warnf(`"apm_config.span_rules" can not be parsed: %v`, err)
+		}
+		return out
+	})
+
+	config.SetEnvKeyTransformer("apm_config.span_metrics", func(in string) interface{} {
+		var out []map[string]interface{}
+		if err := json.Unmarshal([]byte(in), &out); err != nil {
+			log.Warnf(`"apm_config.span_metrics" can not be parsed: %v`, err)
+		}
+		return out
+	})
+
 	config.SetEnvKeyTransformer("apm_config.analyzed_spans", func(in string) interface{} {
 		out, err := parseAnalyzedSpans(in)
 		if err != nil {
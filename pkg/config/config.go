@@ -433,6 +433,8 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("enable_events_stream_payload_serialization", true)
 	config.BindEnvAndSetDefault("enable_sketch_stream_payload_serialization", true)
 	config.BindEnvAndSetDefault("enable_json_stream_shared_compressor_buffers", true)
+	config.BindEnvAndSetDefault("serializer_compressor_kind", "") // "" uses the compression the agent was built with
+	config.BindEnvAndSetDefault("serializer_compressor_level", 0) // 0 uses the default level of the compression
 
 	// Warning: do not change the following values. Your payloads will get dropped by Datadog's intake.
 	config.BindEnvAndSetDefault("serializer_max_payload_size", 2*megaByte+megaByte/2)
@@ -458,6 +460,22 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("forwarder_apikey_validation_interval", DefaultAPIKeyValidationInterval) // in minutes
 	config.BindEnvAndSetDefault("forwarder_num_workers", 1)
 	config.BindEnvAndSetDefault("forwarder_stop_timeout", 2)
+	// Forwarder bandwidth limits in bytes per second, 0 means disabled
+	config.BindEnvAndSetDefault("forwarder_bandwidth_limit", 0)
+	config.BindEnvAndSetDefault("forwarder_bandwidth_limits.series", 0)
+	config.BindEnvAndSetDefault("forwarder_bandwidth_limits.sketches", 0)
+	config.BindEnvAndSetDefault("forwarder_bandwidth_limits.check_runs", 0)
+	config.BindEnvAndSetDefault("forwarder_bandwidth_limits.processes", 0)
+	// Forwarder HTTP clients, can be overridden per domain with `forwarder_domain_http_settings`
+	config.BindEnvAndSetDefault("forwarder_http2_enabled", false)
+	config.BindEnvAndSetDefault("forwarder_http2_max_concurrent_streams", 0) // 0 means no limit other than `forwarder_num_workers`
+	config.BindEnvAndSetDefault("forwarder_max_idle_conns_per_host", 5)
+	config.BindEnvAndSetDefault("forwarder_idle_conn_timeout", 90) // in seconds
+	config.BindEnvAndSetDefault("forwarder_coalesce_window_ms", 0) // 0 disables the coalescing
+	config.BindEnvAndSetDefault("forwarder_coalesce_max_payload_size", 16*1024)
+	config.BindEnvAndSetDefault("forwarder_coalesce_max_transactions", 10)
+	config.BindEnvAndSetDefault("forwarder_domain_http_settings", map[string]interface{}{})
+	config.BindEnvAndSetDefault("forwarder_domain_compressors", map[string]interface{}{})
 	// Forwarder retry settings
 	config.BindEnvAndSetDefault("forwarder_backoff_factor", 2)
 	config.BindEnvAndSetDefault("forwarder_backoff_base", 2)
@@ -472,6 +490,15 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("forwarder_storage_max_size_in_bytes", 0)                // 0 means disabled. This is a BETA feature.
 	config.BindEnvAndSetDefault("forwarder_storage_max_disk_ratio", 0.80)                // Do not store transactions on disk when the disk usage exceeds 80% of the disk capacity. Use 80% as some applications do not behave well when the disk space is very small.
 	config.BindEnvAndSetDefault("forwarder_retry_queue_capacity_time_interval_sec", 900) // 15 mins
+	config.BindEnvAndSetDefault("forwarder_storage_encryption_key", "")                  // Empty means the retry files are not encrypted.
+	config.BindEnvAndSetDefault("forwarder_storage_encryption_key_file", "")
+	config.BindEnvAndSetDefault("forwarder_storage_scrubbed_headers", []string{}) // The API key header is always scrubbed.
+
+	// Forwarder journal
+	config.BindEnvAndSetDefault("forwarder_journal.mode", "") // "" (disabled), "journal" or "journal_and_send"
+	config.BindEnvAndSetDefault("forwarder_journal.path", "")
+	config.BindEnvAndSetDefault("forwarder_journal.max_file_size", 10*1024*1024)
+	config.BindEnvAndSetDefault("forwarder_journal.max_size", 1024*1024*1024) // 0 means unlimited
 
 	// Forwarder channels buffer size
 	config.BindEnvAndSetDefault("forwarder_high_prio_buffer_size", 100)
@@ -639,6 +666,14 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("snmp_traps_config.stop_timeout", 5) // in seconds
 	config.SetKnown("snmp_traps_config.users")
 
+	// NetFlow
+	config.BindEnvAndSetDefault("network_devices.netflow.enabled", false, "DD_NETWORK_DEVICES_NETFLOW_ENABLED")
+	config.BindEnvAndSetDefault("network_devices.netflow.stop_timeout", 5)                // in seconds
+	config.BindEnvAndSetDefault("network_devices.netflow.aggregator_flush_interval", 300) // in seconds
+	config.BindEnvAndSetDefault("network_devices.netflow.aggregator_buffer_size", 10000)
+	config.BindEnvAndSetDefault("network_devices.netflow.aggregator_max_flows", 100000)
+	config.SetKnown("network_devices.netflow.listeners")
+
 	// Kube ApiServer
 	config.BindEnvAndSetDefault("kubernetes_kubeconfig_path", "")
 	config.BindEnvAndSetDefault("kubernetes_apiserver_ca_path", "")
@@ -813,6 +848,7 @@ func InitConfig(config Config) {
 	bindEnvAndSetLogsConfigKeys(config, "database_monitoring.activity.")
 	bindEnvAndSetLogsConfigKeys(config, "database_monitoring.metrics.")
 	bindEnvAndSetLogsConfigKeys(config, "network_devices.metadata.")
+	bindEnvAndSetLogsConfigKeys(config, "network_devices.netflow.forwarder.")
 	config.BindEnvAndSetDefault("network_devices.namespace", "default")
 
 	config.BindEnvAndSetDefault("logs_config.dd_port", 10516)
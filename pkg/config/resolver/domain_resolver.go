@@ -11,6 +11,7 @@ package resolver
 import (
 	"github.com/DataDog/datadog-agent/pkg/forwarder/endpoints"
 	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 )
 
 // DestinationType is used to identified the expected endpoint
@@ -36,19 +37,25 @@ type DomainResolver interface {
 	GetAlternateDomains() []string
 	// SetBaseDomain sets the base domain to a new value
 	SetBaseDomain(domain string)
+	// GetCompressor returns the compression of the payloads sent to `domain`, one of the domains returned by
+	// `Resolve()`. nil means the payloads are sent as they were compressed by the serializer.
+	GetCompressor(domain string) compression.Compressor
+	// SetCompressor sets the compression of the payloads sent to `domain`
+	SetCompressor(domain string, compressor compression.Compressor)
 }
 
 // SingleDomainResolver will always return the same host
 type SingleDomainResolver struct {
-	domain  string
-	apiKeys []string
+	domain     string
+	apiKeys    []string
+	compressor compression.Compressor
 }
 
 // NewSingleDomainResolver creates a SingleDomainResolver with its destination domain & API keys
 func NewSingleDomainResolver(domain string, apiKeys []string) *SingleDomainResolver {
 	return &SingleDomainResolver{
-		domain,
-		apiKeys,
+		domain:  domain,
+		apiKeys: apiKeys,
 	}
 }
 
@@ -86,6 +93,22 @@ func (r *SingleDomainResolver) GetAlternateDomains() []string {
 	return []string{}
 }
 
+// GetCompressor returns the compression of the payloads sent to the only destination of a SingleDomainResolver
+func (r *SingleDomainResolver) GetCompressor(domain string) compression.Compressor {
+	if domain != r.domain {
+		return nil
+	}
+	return r.compressor
+}
+
+// SetCompressor sets the compression of the payloads sent to the only destination of a SingleDomainResolver,
+// other domains are ignored
+func (r *SingleDomainResolver) SetCompressor(domain string, compressor compression.Compressor) {
+	if domain == r.domain {
+		r.compressor = compressor
+	}
+}
+
 type destination struct {
 	domain string
 	dType  DestinationType
@@ -97,15 +120,17 @@ type MultiDomainResolver struct {
 	apiKeys             []string
 	overrides           map[string]destination
 	alternateDomainList []string
+	compressors         map[string]compression.Compressor
 }
 
 // NewMultiDomainResolver initializes a MultiDomainResolver with its API keys and base destination
 func NewMultiDomainResolver(baseDomain string, apiKeys []string) *MultiDomainResolver {
 	return &MultiDomainResolver{
-		baseDomain,
-		apiKeys,
-		make(map[string]destination),
-		[]string{},
+		baseDomain:          baseDomain,
+		apiKeys:             apiKeys,
+		overrides:           make(map[string]destination),
+		alternateDomainList: []string{},
+		compressors:         make(map[string]compression.Compressor),
 	}
 }
 
@@ -137,6 +162,16 @@ func (r *MultiDomainResolver) GetAlternateDomains() []string {
 	return r.alternateDomainList
 }
 
+// GetCompressor returns the compression of the payloads sent to `domain`
+func (r *MultiDomainResolver) GetCompressor(domain string) compression.Compressor {
+	return r.compressors[domain]
+}
+
+// SetCompressor sets the compression of the payloads sent to `domain`
+func (r *MultiDomainResolver) SetCompressor(domain string, compressor compression.Compressor) {
+	r.compressors[domain] = compressor
+}
+
 // RegisterAlternateDestination adds an alternate destination to a MultiDomainResolver.
 // The resolver will match transaction.Endpoint.Name against forwarderName to check if the request shall
 // be diverted.
@@ -135,7 +135,25 @@ func InitSystemProbeConfig(cfg Config) {
 	// network_config namespace only
 	cfg.BindEnv(join(netNS, "enable_http_monitoring"), "DD_SYSTEM_PROBE_NETWORK_ENABLE_HTTP_MONITORING")
 	cfg.BindEnv(join(netNS, "enable_https_monitoring"), "DD_SYSTEM_PROBE_NETWORK_ENABLE_HTTPS_MONITORING")
+	cfg.BindEnv(join(netNS, "enable_http2_monitoring"), "DD_SYSTEM_PROBE_NETWORK_ENABLE_HTTP2_MONITORING")
+	cfg.BindEnv(join(netNS, "enable_go_tls_support"), "DD_SYSTEM_PROBE_NETWORK_ENABLE_GO_TLS_SUPPORT")
+	cfg.BindEnv(join(netNS, "enable_kafka_monitoring"), "DD_SYSTEM_PROBE_NETWORK_ENABLE_KAFKA_MONITORING")
+	cfg.BindEnv(join(netNS, "enable_postgres_monitoring"), "DD_SYSTEM_PROBE_NETWORK_ENABLE_POSTGRES_MONITORING")
+	cfg.BindEnv(join(netNS, "enable_mysql_monitoring"), "DD_SYSTEM_PROBE_NETWORK_ENABLE_MYSQL_MONITORING")
 	cfg.BindEnvAndSetDefault(join(netNS, "enable_gateway_lookup"), false, "DD_SYSTEM_PROBE_NETWORK_ENABLE_GATEWAY_LOOKUP")
+
+	// flow export
+	cfg.BindEnvAndSetDefault(join(netNS, "flow_export.enabled"), false, "DD_SYSTEM_PROBE_NETWORK_FLOW_EXPORT_ENABLED")
+	cfg.BindEnvAndSetDefault(join(netNS, "flow_export.collector_address"), "", "DD_SYSTEM_PROBE_NETWORK_FLOW_EXPORT_COLLECTOR_ADDRESS")
+	cfg.BindEnvAndSetDefault(join(netNS, "flow_export.protocol"), "ipfix", "DD_SYSTEM_PROBE_NETWORK_FLOW_EXPORT_PROTOCOL")
+	cfg.BindEnvAndSetDefault(join(netNS, "flow_export.interval"), 30*time.Second)
+	cfg.BindEnvAndSetDefault(join(netNS, "flow_export.observation_domain_id"), 0)
+	cfg.BindEnvAndSetDefault(join(netNS, "flow_export.enterprise_number"), 0)
+
+	// listener inventory
+	cfg.BindEnvAndSetDefault(join(netNS, "listeners.enabled"), false, "DD_SYSTEM_PROBE_NETWORK_LISTENERS_ENABLED")
+	cfg.BindEnvAndSetDefault(join(netNS, "listeners.interval"), 30*time.Second)
+	cfg.BindEnvAndSetDefault(join(netNS, "listeners.allowed_ports"), []string{}, "DD_SYSTEM_PROBE_NETWORK_LISTENERS_ALLOWED_PORTS")
 	httpRules := join(netNS, "http_replace_rules")
 	cfg.BindEnv(httpRules, "DD_SYSTEM_PROBE_NETWORK_HTTP_REPLACE_RULES")
 	cfg.SetEnvKeyTransformer(httpRules, func(in string) interface{} {
@@ -159,6 +177,8 @@ func InitSystemProbeConfig(cfg Config) {
 	cfg.BindEnvAndSetDefault(join(spNS, "enable_oom_kill"), false)
 	// tcp_queue_length module
 	cfg.BindEnvAndSetDefault(join(spNS, "enable_tcp_queue_length"), false)
+	// dns_monitor module
+	cfg.BindEnvAndSetDefault(join(spNS, "enable_dns_check"), false)
 	// process module
 	// nested within system_probe_config to not conflict with process-agent's process_config
 	cfg.BindEnvAndSetDefault(join(spNS, "process_config.enabled"), false, "DD_SYSTEM_PROBE_PROCESS_ENABLED")
@@ -4,4 +4,4 @@
 
 package runtime
 
-var Conntrack = NewRuntimeAsset("conntrack.c", "dfe89c29e3c401bcf0300ab144ac8503fc3b3b6f5eb13f1eebce5d7575947e26")
+var Conntrack = NewRuntimeAsset("conntrack.c", "9a82efd18d411abe2af057c1df1bdaeb4ec980d5f0cb2ea152b4a957e7dbfc51")
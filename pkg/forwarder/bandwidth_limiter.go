@@ -0,0 +1,214 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package forwarder
+
+import (
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/endpoints"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// bandwidthEndpointTypes are the endpoint types which can have their own bandwidth limit
+// with the setting `forwarder_bandwidth_limits.<type>`.
+var bandwidthEndpointTypes = map[string][]string{
+	"series": {
+		endpoints.V1SeriesEndpoint.Name,
+		endpoints.SeriesEndpoint.Name,
+	},
+	"sketches": {
+		endpoints.V1SketchSeriesEndpoint.Name,
+		endpoints.SketchSeriesEndpoint.Name,
+	},
+	"check_runs": {
+		endpoints.V1CheckRunsEndpoint.Name,
+		endpoints.ServiceChecksEndpoint.Name,
+	},
+	"processes": {
+		endpoints.ProcessesEndpoint.Name,
+		endpoints.ProcessDiscoveryEndpoint.Name,
+		endpoints.RtProcessesEndpoint.Name,
+		endpoints.ContainerEndpoint.Name,
+		endpoints.RtContainerEndpoint.Name,
+		endpoints.ConnectionsEndpoint.Name,
+	},
+}
+
+// tokenBucket is a token bucket where a token is a byte.
+// The bucket can go into debt so a payload bigger than the burst size can still be sent.
+type tokenBucket struct {
+	bytesPerSec float64
+	burst       float64
+	tokens      float64
+	last        time.Time
+}
+
+func newTokenBucket(bytesPerSec int, now time.Time) *tokenBucket {
+	// Allow sending one second worth of data at once.
+	burst := float64(bytesPerSec)
+	return &tokenBucket{
+		bytesPerSec: float64(bytesPerSec),
+		burst:       burst,
+		tokens:      burst,
+		last:        now,
+	}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	b.last = now
+	b.tokens += elapsed * b.bytesPerSec
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+// canTake returns whether `size` bytes can be sent without waiting.
+// A payload bigger than the burst size can be sent when the bucket is full.
+func (b *tokenBucket) canTake(size int) bool {
+	return b.tokens >= float64(size) || b.tokens >= b.burst
+}
+
+func (b *tokenBucket) take(size int) {
+	b.tokens -= float64(size)
+}
+
+// giveBack returns `size` bytes taken for a payload which was not sent.
+func (b *tokenBucket) giveBack(size int) {
+	b.tokens += float64(size)
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+// delay returns how long to wait until the bucket is no longer in debt.
+func (b *tokenBucket) delay() time.Duration {
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.bytesPerSec * float64(time.Second))
+}
+
+// bandwidthLimiter limits the number of bytes per second sent by the forwarder
+// globally and per endpoint type. It is shared by all the workers of all the domains.
+type bandwidthLimiter struct {
+	m              sync.Mutex
+	global         *tokenBucket
+	byEndpointName map[string]*tokenBucket
+	now            func() time.Time
+}
+
+// newBandwidthLimiter creates a new bandwidthLimiter. A limit of 0 means no limit.
+// It returns nil when there is no limit at all.
+func newBandwidthLimiter(globalBytesPerSec int, bytesPerSecByEndpointType map[string]int) *bandwidthLimiter {
+	now := time.Now()
+	limiter := &bandwidthLimiter{
+		byEndpointName: make(map[string]*tokenBucket),
+		now:            time.Now,
+	}
+	if globalBytesPerSec > 0 {
+		limiter.global = newTokenBucket(globalBytesPerSec, now)
+	}
+	for endpointType, bytesPerSec := range bytesPerSecByEndpointType {
+		if bytesPerSec <= 0 {
+			continue
+		}
+		endpointNames, found := bandwidthEndpointTypes[endpointType]
+		if !found {
+			log.Warnf("Unknown endpoint type '%s' for the forwarder bandwidth limits", endpointType)
+			continue
+		}
+		// The endpoints of the same type share the same bucket.
+		bucket := newTokenBucket(bytesPerSec, now)
+		for _, name := range endpointNames {
+			limiter.byEndpointName[name] = bucket
+		}
+	}
+
+	if limiter.global == nil && len(limiter.byEndpointName) == 0 {
+		return nil
+	}
+	return limiter
+}
+
+// newBandwidthLimiterFromConfig creates a new bandwidthLimiter from the configuration.
+func newBandwidthLimiterFromConfig() *bandwidthLimiter {
+	bytesPerSecByEndpointType := make(map[string]int)
+	for endpointType := range bandwidthEndpointTypes {
+		bytesPerSecByEndpointType[endpointType] = config.Datadog.GetInt("forwarder_bandwidth_limits." + endpointType)
+	}
+	limiter := newBandwidthLimiter(config.Datadog.GetInt("forwarder_bandwidth_limit"), bytesPerSecByEndpointType)
+	if limiter != nil {
+		log.Infof("Forwarder bandwidth limit is enabled")
+	}
+	return limiter
+}
+
+func (l *bandwidthLimiter) getBuckets(endpointName string) []*tokenBucket {
+	var buckets []*tokenBucket
+	if l.global != nil {
+		buckets = append(buckets, l.global)
+	}
+	if bucket, found := l.byEndpointName[endpointName]; found {
+		buckets = append(buckets, bucket)
+	}
+	return buckets
+}
+
+// tryReserve consumes `size` bytes and returns true if the payload can be sent
+// right away. Otherwise, it returns false and nothing is consumed.
+func (l *bandwidthLimiter) tryReserve(endpointName string, size int) bool {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	buckets := l.getBuckets(endpointName)
+	now := l.now()
+	for _, b := range buckets {
+		b.refill(now)
+		if !b.canTake(size) {
+			return false
+		}
+	}
+	for _, b := range buckets {
+		b.take(size)
+	}
+	return true
+}
+
+// reserve consumes `size` bytes and returns how long to wait before sending the payload.
+func (l *bandwidthLimiter) reserve(endpointName string, size int) time.Duration {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	var delay time.Duration
+	now := l.now()
+	for _, b := range l.getBuckets(endpointName) {
+		b.refill(now)
+		// Wait for the previous debt before taking the tokens for this payload.
+		if d := b.delay(); d > delay {
+			delay = d
+		}
+		b.take(size)
+	}
+	return delay
+}
+
+// release gives back the `size` bytes consumed by reserve for a payload which was eventually not sent.
+func (l *bandwidthLimiter) release(endpointName string, size int) {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	now := l.now()
+	for _, b := range l.getBuckets(endpointName) {
+		b.refill(now)
+		b.giveBack(size)
+	}
+}
@@ -0,0 +1,93 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+// +build test
+
+package forwarder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/forwarder/endpoints"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestBandwidthLimiter(globalBytesPerSec int, bytesPerSecByEndpointType map[string]int) (*bandwidthLimiter, *time.Time) {
+	now := time.Now()
+	limiter := newBandwidthLimiter(globalBytesPerSec, bytesPerSecByEndpointType)
+	if limiter != nil {
+		limiter.now = func() time.Time { return now }
+		for _, b := range limiter.getBuckets(endpoints.SeriesEndpoint.Name) {
+			b.last = now
+		}
+	}
+	return limiter, &now
+}
+
+func TestBandwidthLimiterDisabled(t *testing.T) {
+	assert.Nil(t, newBandwidthLimiter(0, map[string]int{"series": 0, "unknown": 10}))
+}
+
+func TestBandwidthLimiterTryReserve(t *testing.T) {
+	a := assert.New(t)
+	limiter, now := newTestBandwidthLimiter(100, nil)
+
+	a.True(limiter.tryReserve(endpoints.SeriesEndpoint.Name, 60))
+	a.False(limiter.tryReserve(endpoints.SeriesEndpoint.Name, 60))
+	a.True(limiter.tryReserve(endpoints.SketchSeriesEndpoint.Name, 40))
+
+	*now = now.Add(500 * time.Millisecond)
+	a.True(limiter.tryReserve(endpoints.SeriesEndpoint.Name, 50))
+	a.False(limiter.tryReserve(endpoints.SeriesEndpoint.Name, 1))
+
+	// A payload bigger than the burst can be sent when the bucket is full.
+	*now = now.Add(10 * time.Second)
+	a.True(limiter.tryReserve(endpoints.SeriesEndpoint.Name, 250))
+	a.False(limiter.tryReserve(endpoints.SeriesEndpoint.Name, 1))
+}
+
+func TestBandwidthLimiterPerEndpointType(t *testing.T) {
+	a := assert.New(t)
+	limiter, _ := newTestBandwidthLimiter(0, map[string]int{"series": 100})
+
+	a.True(limiter.tryReserve(endpoints.V1SeriesEndpoint.Name, 100))
+	// series_v1 and series_v2 share the same limit.
+	a.False(limiter.tryReserve(endpoints.SeriesEndpoint.Name, 10))
+	// No limit for the other endpoints.
+	a.True(limiter.tryReserve(endpoints.SketchSeriesEndpoint.Name, 1000))
+}
+
+func TestBandwidthLimiterReserve(t *testing.T) {
+	a := assert.New(t)
+	limiter, now := newTestBandwidthLimiter(100, nil)
+
+	a.Equal(time.Duration(0), limiter.reserve(endpoints.SeriesEndpoint.Name, 150))
+	a.Equal(500*time.Millisecond, limiter.reserve(endpoints.SeriesEndpoint.Name, 100))
+	a.Equal(1500*time.Millisecond, limiter.reserve(endpoints.SeriesEndpoint.Name, 100))
+
+	*now = now.Add(3 * time.Second)
+	a.Equal(time.Duration(0), limiter.reserve(endpoints.SeriesEndpoint.Name, 100))
+}
+
+func TestBandwidthLimiterRelease(t *testing.T) {
+	a := assert.New(t)
+	limiter, now := newTestBandwidthLimiter(100, nil)
+
+	a.Equal(time.Duration(0), limiter.reserve(endpoints.SeriesEndpoint.Name, 150))
+	a.Equal(500*time.Millisecond, limiter.reserve(endpoints.SeriesEndpoint.Name, 100))
+
+	// The second payload is not sent, only the debt of the first one is left.
+	limiter.release(endpoints.SeriesEndpoint.Name, 100)
+	a.Equal(500*time.Millisecond, limiter.reserve(endpoints.SeriesEndpoint.Name, 10))
+	limiter.release(endpoints.SeriesEndpoint.Name, 10)
+
+	// The tokens given back never exceed the burst size.
+	*now = now.Add(time.Second)
+	limiter.release(endpoints.SeriesEndpoint.Name, 1000)
+	a.True(limiter.tryReserve(endpoints.SeriesEndpoint.Name, 100))
+	a.False(limiter.tryReserve(endpoints.SeriesEndpoint.Name, 1))
+}
@@ -0,0 +1,251 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package forwarder
+
+import (
+	"net/http"
+
+	"github.com/mitchellh/mapstructure"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/endpoints"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// DomainCompressionForwarder is implemented by the forwarders sending the payloads to some domains
+// with their own compression, set in `forwarder_domain_compressors`. The payloads submitted to the
+// forwarder itself are sent to every domain: the payloads are built once per compression and each
+// build is submitted to the Forwarder returned by ForCompressor.
+type DomainCompressionForwarder interface {
+	Forwarder
+	// DomainCompressors returns the compressions of the domains with their own compression
+	DomainCompressors() []compression.Compressor
+	// ForCompressor returns a Forwarder submitting the payloads only to the domains compressing them
+	// with `compressor`, one of DomainCompressors, or to the other domains when `compressor` is nil.
+	ForCompressor(compressor compression.Compressor) Forwarder
+}
+
+// Compile-time check to ensure that DefaultForwarder and SyncForwarder implement the DomainCompressionForwarder interface
+var _ DomainCompressionForwarder = &DefaultForwarder{}
+var _ DomainCompressionForwarder = &SyncForwarder{}
+
+type domainCompressorSettings struct {
+	Kind  string `mapstructure:"kind"`
+	Level int    `mapstructure:"level"`
+}
+
+// getDomainCompressor returns the compression set for `domain` in `forwarder_domain_compressors`,
+// or nil when the payloads are sent to `domain` as they were compressed by the serializer.
+func getDomainCompressor(domain string) compression.Compressor {
+	// The domains are not valid configuration paths as they contain dots, so the
+	// map is read as a whole.
+	settingsPerDomain := config.Datadog.GetStringMap("forwarder_domain_compressors")
+	rawSettings, found := settingsPerDomain[domain]
+	if !found {
+		return nil
+	}
+
+	var settings domainCompressorSettings
+	if err := mapstructure.WeakDecode(rawSettings, &settings); err != nil {
+		log.Errorf("Cannot read the compressor settings of the domain '%s', its payloads are sent as serialized: %v", domain, err)
+		return nil
+	}
+	compressor, err := compression.NewCompressor(settings.Kind, settings.Level)
+	if err != nil {
+		log.Errorf("Invalid compressor settings for the domain '%s', its payloads are sent as serialized: %v", domain, err)
+		return nil
+	}
+	log.Infof("The payloads sent to '%s' are compressed with %s", domain, settings.Kind)
+	return compressor
+}
+
+// domainCompressor returns the compression of the payloads sent to `domain` set in the domain resolvers.
+func (f *DefaultForwarder) domainCompressor(domain string) compression.Compressor {
+	for _, dr := range f.domainResolvers {
+		if c := dr.GetCompressor(domain); c != nil {
+			return c
+		}
+	}
+	return nil
+}
+
+// hasOwnCompression returns whether the payloads sent to `domain` have their own compression.
+func (f *DefaultForwarder) hasOwnCompression(domain string) bool {
+	return f.domainCompressor(domain) != nil
+}
+
+// DomainCompressors returns the compressions of the domains with their own compression
+func (f *DefaultForwarder) DomainCompressors() []compression.Compressor {
+	var compressors []compression.Compressor
+	seen := make(map[compression.Compressor]struct{})
+	for domain := range f.domainForwarders {
+		compressor := f.domainCompressor(domain)
+		if compressor == nil {
+			continue
+		}
+		if _, found := seen[compressor]; !found {
+			seen[compressor] = struct{}{}
+			compressors = append(compressors, compressor)
+		}
+	}
+	return compressors
+}
+
+// ForCompressor returns a Forwarder submitting the payloads only to the domains compressing them with `compressor`,
+// or to the domains without their own compression when `compressor` is nil.
+func (f *DefaultForwarder) ForCompressor(compressor compression.Compressor) Forwarder {
+	return &compressionForwarder{
+		forwarder:  f,
+		send:       f.sendHTTPTransactions,
+		compressor: compressor,
+	}
+}
+
+// DomainCompressors returns the compressions of the domains with their own compression
+func (f *SyncForwarder) DomainCompressors() []compression.Compressor {
+	return f.defaultForwarder.DomainCompressors()
+}
+
+// ForCompressor returns a Forwarder synchronously submitting the payloads only to the domains compressing
+// them with `compressor`, or to the domains without their own compression when `compressor` is nil.
+func (f *SyncForwarder) ForCompressor(compressor compression.Compressor) Forwarder {
+	return &compressionForwarder{
+		forwarder:  f.defaultForwarder,
+		send:       f.sendHTTPTransactions,
+		compressor: compressor,
+	}
+}
+
+// compressionForwarder submits the payloads to the domains of `forwarder` compressing them with `compressor`.
+// It is started and stopped with the forwarder it submits the payloads to.
+type compressionForwarder struct {
+	forwarder  *DefaultForwarder
+	send       func(transactions []*transaction.HTTPTransaction) error
+	compressor compression.Compressor
+}
+
+// Start starts the compression forwarder: nothing to do.
+func (f *compressionForwarder) Start() error {
+	return nil
+}
+
+// Stop stops the compression forwarder: nothing to do.
+func (f *compressionForwarder) Stop() {
+}
+
+func (f *compressionForwarder) isDomainCompressed(domain string) bool {
+	return f.forwarder.domainCompressor(domain) == f.compressor
+}
+
+func (f *compressionForwarder) createAdvancedHTTPTransactions(endpoint transaction.Endpoint, payloads Payloads, apiKeyInQueryString bool, extra http.Header, priority transaction.Priority, storableOnDisk bool) []*transaction.HTTPTransaction {
+	return f.forwarder.createDomainsHTTPTransactions(endpoint, payloads, apiKeyInQueryString, extra, priority, storableOnDisk, f.isDomainCompressed)
+}
+
+func (f *compressionForwarder) createHTTPTransactions(endpoint transaction.Endpoint, payloads Payloads, apiKeyInQueryString bool, extra http.Header) []*transaction.HTTPTransaction {
+	return f.createAdvancedHTTPTransactions(endpoint, payloads, apiKeyInQueryString, extra, transaction.TransactionPriorityNormal, true)
+}
+
+func (f *compressionForwarder) submit(endpoint transaction.Endpoint, payload Payloads, apiKeyInQueryString bool, extra http.Header) error {
+	return f.send(f.createHTTPTransactions(endpoint, payload, apiKeyInQueryString, extra))
+}
+
+// submitV1Intake sends the payloads to the V1 intake, which requires the Content-Type header to be set
+func (f *compressionForwarder) submitV1Intake(payload Payloads, extra http.Header, priority transaction.Priority, storableOnDisk bool) error {
+	transactions := f.createAdvancedHTTPTransactions(endpoints.V1IntakeEndpoint, payload, true, extra, priority, storableOnDisk)
+	for _, t := range transactions {
+		t.Headers.Set("Content-Type", "application/json")
+	}
+	return f.send(transactions)
+}
+
+func (f *compressionForwarder) submitProcessLikePayload(ep transaction.Endpoint, payload Payloads, extra http.Header, retryable bool) (chan Response, error) {
+	return f.forwarder.submitProcessLikeTransactions(f.createHTTPTransactions(ep, payload, false, extra), retryable)
+}
+
+// SubmitV1Series will send timeserie to v1 endpoint
+func (f *compressionForwarder) SubmitV1Series(payload Payloads, extra http.Header) error {
+	return f.submit(endpoints.V1SeriesEndpoint, payload, true, extra)
+}
+
+// SubmitSeries will send timeseries to the v2 endpoint
+func (f *compressionForwarder) SubmitSeries(payload Payloads, extra http.Header) error {
+	return f.submit(endpoints.SeriesEndpoint, payload, false, extra)
+}
+
+// SubmitV1Intake will send payloads to the universal `/intake/` endpoint used by Agent v.5
+func (f *compressionForwarder) SubmitV1Intake(payload Payloads, extra http.Header) error {
+	return f.submitV1Intake(payload, extra, transaction.TransactionPriorityNormal, true)
+}
+
+// SubmitV1CheckRuns will send service checks to v1 endpoint
+func (f *compressionForwarder) SubmitV1CheckRuns(payload Payloads, extra http.Header) error {
+	return f.submit(endpoints.V1CheckRunsEndpoint, payload, true, extra)
+}
+
+// SubmitSketchSeries will send payloads to Datadog backend - PROTOTYPE FOR PERCENTILE
+func (f *compressionForwarder) SubmitSketchSeries(payload Payloads, extra http.Header) error {
+	return f.submit(endpoints.SketchSeriesEndpoint, payload, false, extra)
+}
+
+// SubmitHostMetadata will send a host_metadata tag type payload to Datadog backend.
+func (f *compressionForwarder) SubmitHostMetadata(payload Payloads, extra http.Header) error {
+	// Host metadata contains the API KEY and should not be stored on disk.
+	return f.submitV1Intake(payload, extra, transaction.TransactionPriorityHigh, false)
+}
+
+// SubmitAgentChecksMetadata will send a agentchecks_metadata tag type payload to Datadog backend.
+func (f *compressionForwarder) SubmitAgentChecksMetadata(payload Payloads, extra http.Header) error {
+	// Agentchecks metadata contains the API KEY and should not be stored on disk.
+	return f.submitV1Intake(payload, extra, transaction.TransactionPriorityNormal, false)
+}
+
+// SubmitMetadata will send a metadata type payload to Datadog backend.
+func (f *compressionForwarder) SubmitMetadata(payload Payloads, extra http.Header) error {
+	return f.submit(endpoints.V1MetadataEndpoint, payload, false, extra)
+}
+
+// SubmitProcessChecks sends process checks
+func (f *compressionForwarder) SubmitProcessChecks(payload Payloads, extra http.Header) (chan Response, error) {
+	return f.submitProcessLikePayload(endpoints.ProcessesEndpoint, payload, extra, true)
+}
+
+// SubmitProcessDiscoveryChecks sends process discovery checks
+func (f *compressionForwarder) SubmitProcessDiscoveryChecks(payload Payloads, extra http.Header) (chan Response, error) {
+	return f.submitProcessLikePayload(endpoints.ProcessDiscoveryEndpoint, payload, extra, true)
+}
+
+// SubmitRTProcessChecks sends real time process checks
+func (f *compressionForwarder) SubmitRTProcessChecks(payload Payloads, extra http.Header) (chan Response, error) {
+	return f.submitProcessLikePayload(endpoints.RtProcessesEndpoint, payload, extra, false)
+}
+
+// SubmitContainerChecks sends container checks
+func (f *compressionForwarder) SubmitContainerChecks(payload Payloads, extra http.Header) (chan Response, error) {
+	return f.submitProcessLikePayload(endpoints.ContainerEndpoint, payload, extra, true)
+}
+
+// SubmitRTContainerChecks sends real time container checks
+func (f *compressionForwarder) SubmitRTContainerChecks(payload Payloads, extra http.Header) (chan Response, error) {
+	return f.submitProcessLikePayload(endpoints.RtContainerEndpoint, payload, extra, false)
+}
+
+// SubmitConnectionChecks sends connection checks
+func (f *compressionForwarder) SubmitConnectionChecks(payload Payloads, extra http.Header) (chan Response, error) {
+	return f.submitProcessLikePayload(endpoints.ConnectionsEndpoint, payload, extra, true)
+}
+
+// SubmitOrchestratorChecks sends orchestrator checks
+func (f *compressionForwarder) SubmitOrchestratorChecks(payload Payloads, extra http.Header, payloadType int) (chan Response, error) {
+	bumpOrchestratorPayload(payloadType)
+	return f.submitProcessLikePayload(orchestratorEndpoint(), payload, extra, true)
+}
+
+// SubmitContainerLifecycleEvents sends container lifecycle events
+func (f *compressionForwarder) SubmitContainerLifecycleEvents(payload Payloads, extra http.Header) error {
+	return f.submit(endpoints.ContainerLifecycleEndpoint, payload, false, extra)
+}
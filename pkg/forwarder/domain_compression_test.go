@@ -0,0 +1,128 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+// +build test
+
+package forwarder
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/config/resolver"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/internal/journal"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
+)
+
+func TestGetDomainCompressor(t *testing.T) {
+	mockConfig := config.Mock()
+	mockConfig.Set("forwarder_domain_compressors", map[string]interface{}{
+		"https://proxy.example.com":   map[string]interface{}{"kind": "zlib", "level": "1"},
+		"https://invalid.example.com": map[string]interface{}{"kind": "lz4"},
+	})
+	defer mockConfig.Set("forwarder_domain_compressors", map[string]interface{}{})
+
+	expected, err := compression.NewCompressor(compression.ZlibKind, 1)
+	require.NoError(t, err)
+	assert.Equal(t, expected, getDomainCompressor("https://proxy.example.com"))
+	assert.Nil(t, getDomainCompressor("https://invalid.example.com"))
+	assert.Nil(t, getDomainCompressor("https://app.datadoghq.com"))
+}
+
+func TestDomainCompressors(t *testing.T) {
+	mockConfig := config.Mock()
+	mockConfig.Set("forwarder_domain_compressors", map[string]interface{}{
+		"datadog.bar": map[string]interface{}{"kind": "none"},
+	})
+	defer mockConfig.Set("forwarder_domain_compressors", map[string]interface{}{})
+
+	forwarder := NewDefaultForwarder(NewOptionsWithResolvers(resolver.NewSingleDomainResolvers(keysWithMultipleDomains)))
+	compressors := forwarder.DomainCompressors()
+	require.Len(t, compressors, 1)
+	assert.Same(t, forwarder.domainCompressor("datadog.bar"), compressors[0])
+	assert.Nil(t, forwarder.domainCompressor(testVersionDomain))
+
+	noDomainCompressors := NewDefaultForwarder(NewOptionsWithResolvers(resolver.NewSingleDomainResolvers(validKeysPerDomain)))
+	assert.Empty(t, noDomainCompressors.DomainCompressors())
+}
+
+func TestForCompressor(t *testing.T) {
+	mockConfig := config.Mock()
+	mockConfig.Set("forwarder_domain_compressors", map[string]interface{}{
+		"datadog.bar": map[string]interface{}{"kind": "none"},
+	})
+	defer mockConfig.Set("forwarder_domain_compressors", map[string]interface{}{})
+
+	forwarder := NewDefaultForwarder(NewOptionsWithResolvers(resolver.NewSingleDomainResolvers(keysWithMultipleDomains)))
+	p1 := []byte("A payload")
+	endpoint := transaction.Endpoint{Route: "/api/foo", Name: "foo"}
+
+	// The forwarder itself sends the payloads to every domain
+	require.Len(t, forwarder.createHTTPTransactions(endpoint, Payloads{&p1}, false, nil), 3)
+
+	domainsOf := func(f Forwarder) []string {
+		var domains []string
+		for _, tr := range f.(*compressionForwarder).createHTTPTransactions(endpoint, Payloads{&p1}, false, nil) {
+			domains = append(domains, tr.Domain)
+		}
+		return domains
+	}
+	assert.Equal(t, []string{testVersionDomain, testVersionDomain}, domainsOf(forwarder.ForCompressor(nil)))
+	assert.Equal(t, []string{"datadog.bar"}, domainsOf(forwarder.ForCompressor(forwarder.DomainCompressors()[0])))
+}
+
+func TestTransactionJournalSkipsDomainCompression(t *testing.T) {
+	journalPath, err := ioutil.TempDir("", "journal")
+	require.NoError(t, err)
+	defer os.RemoveAll(journalPath)
+
+	mockConfig := config.Mock()
+	mockConfig.Set("forwarder_journal.mode", journalModeJournal)
+	mockConfig.Set("forwarder_journal.path", journalPath)
+	mockConfig.Set("forwarder_domain_compressors", map[string]interface{}{
+		"datadog.bar": map[string]interface{}{"kind": "none"},
+	})
+	defer mockConfig.Set("forwarder_journal.mode", "")
+	defer mockConfig.Set("forwarder_journal.path", "")
+	defer mockConfig.Set("forwarder_domain_compressors", map[string]interface{}{})
+
+	options := NewOptionsWithResolvers(resolver.NewSingleDomainResolvers(keysWithMultipleDomains))
+	options.EnabledFeatures = SetFeature(options.EnabledFeatures, CoreFeatures)
+	forwarder := NewDefaultForwarder(options)
+	require.NotNil(t, forwarder.journal)
+
+	serialized := []byte("serialized")
+	compressedForDomain := []byte("compressed for datadog.bar")
+	endpoint := transaction.Endpoint{Route: "/api/foo", Name: "foo"}
+	transactions := forwarder.ForCompressor(nil).(*compressionForwarder).createHTTPTransactions(endpoint, Payloads{&serialized}, false, nil)
+	transactions = append(transactions, forwarder.ForCompressor(forwarder.DomainCompressors()[0]).(*compressionForwarder).createHTTPTransactions(endpoint, Payloads{&compressedForDomain}, false, nil)...)
+	require.Len(t, transactions, 3)
+
+	// The payload compressed for datadog.bar is neither written nor sent in "journal" mode
+	assert.Empty(t, forwarder.journal.add(transactions, forwarder.hasOwnCompression))
+	forwarder.journal.close()
+
+	filenames, err := journal.ListFiles(journalPath)
+	require.NoError(t, err)
+	var payloads []string
+	for _, filename := range filenames {
+		require.NoError(t, journal.ReadFile(filename, func(record []byte) error {
+			entries, err := journal.UnmarshalEntries(record)
+			require.NoError(t, err)
+			for _, e := range entries {
+				payloads = append(payloads, string(e.Payload))
+			}
+			return nil
+		}))
+	}
+	assert.Equal(t, []string{"serialized"}, payloads)
+}
@@ -40,6 +40,10 @@ type domainForwarder struct {
 	m                         sync.Mutex // To control Start/Stop races
 	transactionPrioritySorter retry.TransactionPrioritySorter
 	blockedList               *blockedEndpoints
+	bandwidthLimiter          *bandwidthLimiter
+	httpSettings              domainHTTPSettings
+	httpClientProvider        *httpClientProvider
+	coalescer                 *transactionCoalescer
 }
 
 func newDomainForwarder(
@@ -47,7 +51,13 @@ func newDomainForwarder(
 	retryQueue *retry.TransactionRetryQueue,
 	numberOfWorkers int,
 	connectionResetInterval time.Duration,
-	transactionPrioritySorter retry.TransactionPrioritySorter) *domainForwarder {
+	transactionPrioritySorter retry.TransactionPrioritySorter,
+	optionalBandwidthLimiter *bandwidthLimiter,
+	httpSettings domainHTTPSettings) *domainForwarder {
+	if httpSettings.HTTP2Enabled && httpSettings.MaxConcurrentStreams > numberOfWorkers {
+		log.Infof("Only %d of the %d HTTP/2 concurrent streams of the domain '%s' are used as there are %d workers: increase forwarder_num_workers to use more",
+			numberOfWorkers, httpSettings.MaxConcurrentStreams, domain, numberOfWorkers)
+	}
 	return &domainForwarder{
 		domain:                    domain,
 		numberOfWorkers:           numberOfWorkers,
@@ -56,6 +66,9 @@ func newDomainForwarder(
 		internalState:             Stopped,
 		blockedList:               newBlockedEndpoints(),
 		transactionPrioritySorter: transactionPrioritySorter,
+		bandwidthLimiter:          optionalBandwidthLimiter,
+		httpSettings:              httpSettings,
+		httpClientProvider:        newHTTPClientProvider(httpSettings),
 	}
 }
 
@@ -159,6 +172,7 @@ func (f *domainForwarder) scheduleConnectionResets() {
 		select {
 		case <-ticker.C:
 			log.Debugf("Scheduling reset of connections used for domain: %q", f.domain)
+			f.httpClientProvider.resetSharedClient()
 			for _, worker := range f.workers {
 				worker.ScheduleConnectionReset()
 			}
@@ -180,6 +194,7 @@ func (f *domainForwarder) init() {
 	f.stopRetry = make(chan bool)
 	f.stopConnectionReset = make(chan bool)
 	f.workers = []*Worker{}
+	f.coalescer = newTransactionCoalescer(f.httpSettings, f.sendToWorkers)
 }
 
 // Start starts a domainForwarder.
@@ -196,7 +211,8 @@ func (f *domainForwarder) Start() error {
 	f.init()
 
 	for i := 0; i < f.numberOfWorkers; i++ {
-		w := NewWorker(f.highPrio, f.lowPrio, f.requeuedTransaction, f.blockedList)
+		w := NewWorker(f.highPrio, f.lowPrio, f.requeuedTransaction, f.blockedList, f.bandwidthLimiter)
+		w.setHTTPClientFactory(f.httpClientProvider.getClient)
 		w.Start()
 		f.workers = append(f.workers, w)
 	}
@@ -220,6 +236,10 @@ func (f *domainForwarder) Stop(purgeHighPrio bool) {
 		return
 	}
 
+	// The coalesced transactions are sent to the workers before they stop.
+	if f.coalescer != nil {
+		f.coalescer.flush()
+	}
 	if f.connectionResetInterval != 0 {
 		f.stopConnectionReset <- true
 	}
@@ -244,13 +264,27 @@ func (f *domainForwarder) State() uint32 {
 }
 
 func (f *domainForwarder) sendHTTPTransactions(t transaction.Transaction) {
+	if f.coalescer != nil && f.coalescer.add(t) {
+		return
+	}
+	f.sendToWorkers(t)
+}
+
+func (f *domainForwarder) sendToWorkers(t transaction.Transaction) {
 	// We don't want to block the collector if the highPrio queue is full
 	select {
 	case f.highPrio <- t:
 	default:
-		f.addToTransactionRetryQueue(t)
-		highPriorityQueueFull.Add(1)
-		tlmTxHighPriorityQueueFull.Inc(f.domain, t.GetEndpointName())
+		// The retry queue stores the transactions one by one.
+		transactions := []transaction.Transaction{t}
+		if group, ok := t.(*coalescedTransaction); ok {
+			transactions = group.transactions
+		}
+		for _, t := range transactions {
+			f.addToTransactionRetryQueue(t)
+			highPriorityQueueFull.Add(1)
+			tlmTxHighPriorityQueueFull.Inc(f.domain, t.GetEndpointName())
+		}
 		log.Debugf("Adding the transaction to the retry queue because the forwarder input queue for %s is full; consider increasing forwarder_num_workers", f.domain)
 	}
 }
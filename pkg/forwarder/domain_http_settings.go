@@ -0,0 +1,164 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package forwarder
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/mitchellh/mapstructure"
+)
+
+// domainHTTPSettings are the settings of the HTTP clients used by a domainForwarder.
+type domainHTTPSettings struct {
+	HTTP2Enabled bool `mapstructure:"http2_enabled"`
+	// MaxConcurrentStreams is the number of transactions sent at the same time
+	// on the HTTP/2 connection. 0 means the number of workers.
+	MaxConcurrentStreams int `mapstructure:"http2_max_concurrent_streams"`
+	MaxIdleConnsPerHost  int `mapstructure:"max_idle_conns_per_host"`
+	// IdleConnTimeout is in seconds.
+	IdleConnTimeout int `mapstructure:"idle_conn_timeout"`
+	// The transactions to the same target whose payload is smaller than CoalesceMaxPayloadSize
+	// are grouped during CoalesceWindowMs, up to CoalesceMaxTransactions, and sent by the same worker.
+	CoalesceWindowMs        int `mapstructure:"coalesce_window_ms"`
+	CoalesceMaxPayloadSize  int `mapstructure:"coalesce_max_payload_size"`
+	CoalesceMaxTransactions int `mapstructure:"coalesce_max_transactions"`
+}
+
+// getDomainHTTPSettings returns the HTTP settings for `domain`. The settings defined
+// in `forwarder_domain_http_settings` for this domain override the global ones.
+func getDomainHTTPSettings(domain string) domainHTTPSettings {
+	settings := domainHTTPSettings{
+		HTTP2Enabled:         config.Datadog.GetBool("forwarder_http2_enabled"),
+		MaxConcurrentStreams: config.Datadog.GetInt("forwarder_http2_max_concurrent_streams"),
+		MaxIdleConnsPerHost:  config.Datadog.GetInt("forwarder_max_idle_conns_per_host"),
+		IdleConnTimeout:      config.Datadog.GetInt("forwarder_idle_conn_timeout"),
+
+		CoalesceWindowMs:        config.Datadog.GetInt("forwarder_coalesce_window_ms"),
+		CoalesceMaxPayloadSize:  config.Datadog.GetInt("forwarder_coalesce_max_payload_size"),
+		CoalesceMaxTransactions: config.Datadog.GetInt("forwarder_coalesce_max_transactions"),
+	}
+
+	// The domains are not valid configuration paths as they contain dots, so the
+	// map is read as a whole.
+	settingsPerDomain := config.Datadog.GetStringMap("forwarder_domain_http_settings")
+	if overrides, found := settingsPerDomain[domain]; found {
+		// Only the fields set for the domain are overridden.
+		if err := mapstructure.WeakDecode(overrides, &settings); err != nil {
+			log.Errorf("Cannot read the HTTP settings of the domain '%s': %v", domain, err)
+		}
+	}
+	return settings
+}
+
+func (s domainHTTPSettings) newHTTPClient() *http.Client {
+	transport := httputils.CreateHTTPTransport()
+	if s.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = s.MaxIdleConnsPerHost
+	}
+	if s.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = time.Duration(s.IdleConnTimeout) * time.Second
+	}
+	var roundTripper http.RoundTripper = transport
+	if s.HTTP2Enabled {
+		if err := httputils.ConfigureHTTP2(transport, true); err != nil {
+			log.Errorf("Cannot enable HTTP/2, using HTTP/1.1: %v", err)
+		}
+		if s.MaxConcurrentStreams > 0 {
+			roundTripper = newStreamLimitTransport(transport, s.MaxConcurrentStreams)
+		}
+	}
+
+	return &http.Client{
+		Timeout:   config.Datadog.GetDuration("forwarder_timeout") * time.Second,
+		Transport: roundTripper,
+	}
+}
+
+// streamLimitTransport limits the number of requests in flight on the transport, as the
+// workers of a domain share the same HTTP/2 connection. A request is in flight until the
+// body of its response is closed.
+type streamLimitTransport struct {
+	*http.Transport
+	streams chan struct{}
+}
+
+func newStreamLimitTransport(transport *http.Transport, maxConcurrentStreams int) *streamLimitTransport {
+	return &streamLimitTransport{
+		Transport: transport,
+		streams:   make(chan struct{}, maxConcurrentStreams),
+	}
+}
+
+// RoundTrip waits for a stream to be available before sending the request.
+func (t *streamLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	select {
+	case t.streams <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+
+	resp, err := t.Transport.RoundTrip(req)
+	if err != nil {
+		<-t.streams
+		return nil, err
+	}
+	resp.Body = &streamBody{ReadCloser: resp.Body, release: func() { <-t.streams }}
+	return resp, nil
+}
+
+// streamBody releases its stream when it is closed.
+type streamBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *streamBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// httpClientProvider provides the HTTP clients used by the workers of a domainForwarder.
+// Without HTTP/2, each worker has its own client and so its own connection.
+// With HTTP/2, the workers share the same client so their transactions are multiplexed
+// on a single connection, which saves TLS handshakes.
+type httpClientProvider struct {
+	settings     domainHTTPSettings
+	m            sync.Mutex
+	sharedClient *http.Client
+}
+
+func newHTTPClientProvider(settings domainHTTPSettings) *httpClientProvider {
+	return &httpClientProvider{settings: settings}
+}
+
+// getClient returns the HTTP client a worker must use.
+func (p *httpClientProvider) getClient() *http.Client {
+	if !p.settings.HTTP2Enabled {
+		return p.settings.newHTTPClient()
+	}
+
+	p.m.Lock()
+	defer p.m.Unlock()
+	if p.sharedClient == nil {
+		p.sharedClient = p.settings.newHTTPClient()
+	}
+	return p.sharedClient
+}
+
+// resetSharedClient makes the next calls to `getClient` return a new client.
+func (p *httpClientProvider) resetSharedClient() {
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.sharedClient = nil
+}
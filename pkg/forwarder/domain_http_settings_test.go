@@ -0,0 +1,105 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+// +build test
+
+package forwarder
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+func TestGetDomainHTTPSettings(t *testing.T) {
+	mockConfig := config.Mock()
+	mockConfig.Set("forwarder_max_idle_conns_per_host", 10)
+	mockConfig.Set("forwarder_domain_http_settings", map[string]interface{}{
+		"https://proxy.example.com": map[string]interface{}{
+			"http2_enabled":                true,
+			"http2_max_concurrent_streams": "20",
+			"coalesce_window_ms":           50,
+		},
+	})
+	defer mockConfig.Set("forwarder_max_idle_conns_per_host", 5)
+	defer mockConfig.Set("forwarder_domain_http_settings", map[string]interface{}{})
+
+	settings := getDomainHTTPSettings("https://app.datadoghq.com")
+	assert.False(t, settings.HTTP2Enabled)
+	assert.Equal(t, 10, settings.MaxIdleConnsPerHost)
+
+	settings = getDomainHTTPSettings("https://proxy.example.com")
+	assert.True(t, settings.HTTP2Enabled)
+	assert.Equal(t, 20, settings.MaxConcurrentStreams)
+	assert.Equal(t, 10, settings.MaxIdleConnsPerHost)
+	assert.Equal(t, 50, settings.CoalesceWindowMs)
+	assert.Equal(t, 16*1024, settings.CoalesceMaxPayloadSize)
+}
+
+func TestHTTPClientProvider(t *testing.T) {
+	provider := newHTTPClientProvider(domainHTTPSettings{IdleConnTimeout: 10})
+	client := provider.getClient()
+	assert.NotSame(t, client, provider.getClient())
+	assert.Equal(t, 10*time.Second, client.Transport.(*http.Transport).IdleConnTimeout)
+
+	provider = newHTTPClientProvider(domainHTTPSettings{HTTP2Enabled: true})
+	client = provider.getClient()
+	assert.Same(t, client, provider.getClient())
+	assert.Contains(t, client.Transport.(*http.Transport).TLSNextProto, "h2")
+
+	provider = newHTTPClientProvider(domainHTTPSettings{HTTP2Enabled: true, MaxConcurrentStreams: 4})
+	client = provider.getClient()
+	assert.Contains(t, client.Transport.(*streamLimitTransport).TLSNextProto, "h2")
+
+	provider.resetSharedClient()
+	assert.NotSame(t, client, provider.getClient())
+}
+
+func TestStreamLimitTransport(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-release
+		}
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: newStreamLimitTransport(&http.Transport{}, 1)}
+
+	slowDone := make(chan struct{})
+	go func() {
+		defer close(slowDone)
+		resp, err := client.Get(server.URL + "/slow")
+		if assert.NoError(t, err) {
+			resp.Body.Close()
+		}
+	}()
+
+	// The only stream is used by the slow request.
+	assert.Eventually(t, func() bool {
+		return len(client.Transport.(*streamLimitTransport).streams) == 1
+	}, time.Second, time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/fast", nil)
+	_, err := client.Do(req)
+	assert.Error(t, err)
+
+	// The stream is released when the body of the response is closed.
+	close(release)
+	<-slowDone
+	resp, err := client.Get(server.URL + "/fast")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Len(t, client.Transport.(*streamLimitTransport).streams, 0)
+}
@@ -7,6 +7,7 @@ package forwarder
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"path"
 	"strconv"
@@ -207,6 +208,7 @@ type DefaultForwarder struct {
 	agentName                       string
 	queueDurationCapacity           *retry.QueueDurationCapacity
 	retryQueueDurationCapacityMutex sync.Mutex
+	journal                         *transactionJournal
 }
 
 // NewDefaultForwarder returns a new DefaultForwarder.
@@ -228,10 +230,18 @@ func NewDefaultForwarder(options *Options) *DefaultForwarder {
 	var optionalRemovalPolicy *retry.FileRemovalPolicy
 	storageMaxSize := config.Datadog.GetInt64("forwarder_storage_max_size_in_bytes")
 	var diskUsageLimit *retry.DiskUsageLimit
+	var optionalEncryption *retry.TransactionEncryption
+	var encryptionErr error
+	if storageMaxSize != 0 && agentName != "" {
+		optionalEncryption, encryptionErr = getStorageEncryption()
+	}
 
 	// Disk Persistence is a core-only feature for now.
 	if storageMaxSize == 0 {
 		log.Infof("Retry queue storage on disk is disabled")
+	} else if encryptionErr != nil {
+		// Do not fall back to plain text files when the encryption is requested but cannot be used.
+		log.Errorf("Retry queue storage on disk is disabled. Cannot initialize the encryption: %v", encryptionErr)
 	} else if agentName != "" {
 		storagePath := config.Datadog.GetString("forwarder_storage_path")
 		if storagePath == "" {
@@ -260,12 +270,14 @@ func NewDefaultForwarder(options *Options) *DefaultForwarder {
 	}
 
 	flushToDiskMemRatio := config.Datadog.GetFloat64("forwarder_flush_to_disk_mem_ratio")
+	scrubbedHeaders := append([]string{apiHTTPHeaderKey}, config.Datadog.GetStringSlice("forwarder_storage_scrubbed_headers")...)
 	domainForwarderSort := transaction.SortByCreatedTimeAndPriority{HighPriorityFirst: true}
 	transactionContainerSort := transaction.SortByCreatedTimeAndPriority{HighPriorityFirst: false}
 	var queueDiskSpaceUsedList []retry.QueueDiskSpaceUsed
+	bandwidthLimiter := newBandwidthLimiterFromConfig()
 
-	for domain, resolver := range options.DomainResolvers {
-		domain, _ := config.AddAgentVersionToDomain(domain, "app")
+	for configuredDomain, resolver := range options.DomainResolvers {
+		domain, _ := config.AddAgentVersionToDomain(configuredDomain, "app")
 		resolver.SetBaseDomain(domain)
 		if resolver.GetAPIKeys() == nil || len(resolver.GetAPIKeys()) == 0 {
 			log.Errorf("No API keys for domain '%s', dropping domain ", domain)
@@ -284,20 +296,26 @@ func NewDefaultForwarder(options *Options) *DefaultForwarder {
 				flushToDiskMemRatio,
 				domainFolderPath,
 				diskUsageLimit,
+				optionalEncryption,
+				scrubbedHeaders,
 				transactionContainerSort,
 				resolver)
 			f.domainResolvers[domain] = resolver
+			resolver.SetCompressor(domain, getDomainCompressor(configuredDomain))
 			queueDiskSpaceUsedList = append(queueDiskSpaceUsedList, transactionContainer)
 			fwd := newDomainForwarder(
 				domain,
 				transactionContainer,
 				options.NumberOfWorkers,
 				options.ConnectionResetInterval,
-				domainForwarderSort)
+				domainForwarderSort,
+				bandwidthLimiter,
+				getDomainHTTPSettings(configuredDomain))
 			f.domainForwarders[domain] = fwd
 			// Register all alternate domains for each forwarder
 			for _, v := range resolver.GetAlternateDomains() {
 				f.domainForwarders[v] = fwd
+				resolver.SetCompressor(v, getDomainCompressor(v))
 			}
 		}
 	}
@@ -312,6 +330,16 @@ func NewDefaultForwarder(options *Options) *DefaultForwarder {
 			queueDiskSpaceUsedList)
 	}
 
+	// The journal is a core-only feature like disk persistence.
+	if f.agentName != "" {
+		optionalJournal, err := newTransactionJournalFromConfig()
+		if err != nil {
+			log.Errorf("Forwarder journal is disabled: %v", err)
+		} else {
+			f.journal = optionalJournal
+		}
+	}
+
 	if optionalRemovalPolicy != nil {
 		filesRemoved, err := optionalRemovalPolicy.RemoveUnknownDomains()
 		if err != nil {
@@ -323,6 +351,36 @@ func NewDefaultForwarder(options *Options) *DefaultForwarder {
 	return f
 }
 
+// getStorageEncryption returns the encryption used for the retry files or nil
+// when the encryption is not enabled.
+// The key is read from `forwarder_storage_encryption_key`, which can use the secret backend,
+// or from the file `forwarder_storage_encryption_key_file`.
+func getStorageEncryption() (*retry.TransactionEncryption, error) {
+	key := config.Datadog.GetString("forwarder_storage_encryption_key")
+	keyFile := config.Datadog.GetString("forwarder_storage_encryption_key_file")
+
+	if key != "" && keyFile != "" {
+		return nil, fmt.Errorf("'forwarder_storage_encryption_key' and 'forwarder_storage_encryption_key_file' cannot be both set")
+	}
+
+	if keyFile != "" {
+		content, err := ioutil.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read the encryption key file: %v", err)
+		}
+		key = strings.TrimSpace(string(content))
+		if key == "" {
+			return nil, fmt.Errorf("the encryption key file %v is empty", keyFile)
+		}
+	}
+
+	if key == "" {
+		return nil, nil
+	}
+	log.Infof("Retry queue storage on disk is encrypted")
+	return retry.NewTransactionEncryption([]byte(key))
+}
+
 func getAgentName(options *Options) string {
 	if HasFeature(options.EnabledFeatures, CoreFeatures) {
 		return "core"
@@ -405,6 +463,9 @@ func (f *DefaultForwarder) Stop() {
 	}
 
 	f.healthChecker.Stop()
+	if f.journal != nil {
+		f.journal.close()
+	}
 
 	f.healthChecker = nil
 	f.domainForwarders = map[string]*domainForwarder{}
@@ -424,14 +485,24 @@ func (f *DefaultForwarder) createHTTPTransactions(endpoint transaction.Endpoint,
 }
 
 func (f *DefaultForwarder) createAdvancedHTTPTransactions(endpoint transaction.Endpoint, payloads Payloads, apiKeyInQueryString bool, extra http.Header, priority transaction.Priority, storableOnDisk bool) []*transaction.HTTPTransaction {
+	return f.createDomainsHTTPTransactions(endpoint, payloads, apiKeyInQueryString, extra, priority, storableOnDisk, nil)
+}
+
+// createDomainsHTTPTransactions creates the transactions of the domains accepted by `domainFilter`,
+// or of every domain when it is nil.
+func (f *DefaultForwarder) createDomainsHTTPTransactions(endpoint transaction.Endpoint, payloads Payloads, apiKeyInQueryString bool, extra http.Header, priority transaction.Priority, storableOnDisk bool, domainFilter func(domain string) bool) []*transaction.HTTPTransaction {
 	transactions := make([]*transaction.HTTPTransaction, 0, len(payloads)*len(f.domainForwarders))
 	allowArbitraryTags := config.Datadog.GetBool("allow_arbitrary_tags")
 
 	for _, payload := range payloads {
 		for domain, dr := range f.domainResolvers {
+			resolvedDomain, _ := dr.Resolve(endpoint)
+			if domainFilter != nil && !domainFilter(resolvedDomain) {
+				continue
+			}
 			for _, apiKey := range dr.GetAPIKeys() {
 				t := transaction.NewHTTPTransaction()
-				t.Domain, _ = dr.Resolve(endpoint)
+				t.Domain = resolvedDomain
 				t.Endpoint = endpoint
 				if apiKeyInQueryString {
 					t.Endpoint.Route = fmt.Sprintf("%s?api_key=%s", endpoint.Route, apiKey)
@@ -469,6 +540,15 @@ func (f *DefaultForwarder) sendHTTPTransactions(transactions []*transaction.HTTP
 	if atomic.LoadUint32(&f.internalState) == Stopped {
 		return fmt.Errorf("the forwarder is not started")
 	}
+	if f.journal != nil {
+		notJournaled := f.journal.add(transactions, f.hasOwnCompression)
+		if !f.journal.send {
+			transactions = notJournaled
+			if len(transactions) == 0 {
+				return nil
+			}
+		}
+	}
 	if config.Datadog.GetBool("telemetry.enabled") {
 		f.retryQueueDurationCapacityMutex.Lock()
 		defer f.retryQueueDurationCapacityMutex.Unlock()
@@ -615,13 +695,14 @@ func (f *DefaultForwarder) SubmitConnectionChecks(payload Payloads, extra http.H
 // SubmitOrchestratorChecks sends orchestrator checks
 func (f *DefaultForwarder) SubmitOrchestratorChecks(payload Payloads, extra http.Header, payloadType int) (chan Response, error) {
 	bumpOrchestratorPayload(payloadType)
+	return f.submitProcessLikePayload(orchestratorEndpoint(), payload, extra, true)
+}
 
-	endpoint := endpoints.OrchestratorEndpoint
+func orchestratorEndpoint() transaction.Endpoint {
 	if config.Datadog.IsSet("orchestrator_explorer.use_legacy_endpoint") {
-		endpoint = endpoints.LegacyOrchestratorEndpoint
+		return endpoints.LegacyOrchestratorEndpoint
 	}
-
-	return f.submitProcessLikePayload(endpoint, payload, extra, true)
+	return endpoints.OrchestratorEndpoint
 }
 
 // SubmitContainerLifecycleEvents sends container lifecycle events
@@ -631,7 +712,10 @@ func (f *DefaultForwarder) SubmitContainerLifecycleEvents(payload Payloads, extr
 }
 
 func (f *DefaultForwarder) submitProcessLikePayload(ep transaction.Endpoint, payload Payloads, extra http.Header, retryable bool) (chan Response, error) {
-	transactions := f.createHTTPTransactions(ep, payload, false, extra)
+	return f.submitProcessLikeTransactions(f.createHTTPTransactions(ep, payload, false, extra), retryable)
+}
+
+func (f *DefaultForwarder) submitProcessLikeTransactions(transactions []*transaction.HTTPTransaction, retryable bool) (chan Response, error) {
 	results := make(chan Response, len(transactions))
 	internalResults := make(chan Response, len(transactions))
 	expectedResponses := len(transactions)
@@ -0,0 +1,108 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package journal
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/forwarder/internal/retry"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
+
+	proto "github.com/golang/protobuf/proto"
+)
+
+const entrySerializerVersion = 1
+
+// The API key is not stored, only the fact it must be added to the query string.
+const apiKeyQueryString = "?api_key="
+
+// Entry is a payload submitted to the forwarder.
+// It contains neither the domain nor the API key: they are provided
+// by the Agent replaying the journal.
+type Entry struct {
+	Endpoint            transaction.Endpoint
+	APIKeyInQueryString bool
+	Headers             http.Header
+	Payload             []byte
+	CreatedAt           time.Time
+	Priority            transaction.Priority
+	Retryable           bool
+}
+
+// MarshalEntries serializes entries as a journal record.
+func MarshalEntries(entries []Entry) ([]byte, error) {
+	collection := retry.HttpTransactionProtoCollection{Version: entrySerializerVersion}
+	for _, e := range entries {
+		priority := retry.TransactionPriorityProto_NORMAL
+		if e.Priority == transaction.TransactionPriorityHigh {
+			priority = retry.TransactionPriorityProto_HIGH
+		}
+		headers := make(map[string]*retry.HeaderValuesProto)
+		for key, values := range e.Headers {
+			headers[key] = &retry.HeaderValuesProto{Values: values}
+		}
+		route := e.Endpoint.Route
+		if e.APIKeyInQueryString {
+			route += apiKeyQueryString
+		}
+		collection.Values = append(collection.Values, &retry.HttpTransactionProto{
+			Endpoint:  &retry.EndpointProto{Route: route, Name: e.Endpoint.Name},
+			Headers:   headers,
+			Payload:   e.Payload,
+			CreatedAt: e.CreatedAt.Unix(),
+			Retryable: e.Retryable,
+			Priority:  priority,
+		})
+	}
+	return proto.Marshal(&collection)
+}
+
+// UnmarshalEntries deserializes a journal record.
+func UnmarshalEntries(record []byte) ([]Entry, error) {
+	collection := retry.HttpTransactionProtoCollection{}
+	if err := proto.Unmarshal(record, &collection); err != nil {
+		return nil, err
+	}
+	if collection.Version != entrySerializerVersion {
+		return nil, fmt.Errorf("unsupported journal version %v", collection.Version)
+	}
+
+	var entries []Entry
+	for _, tr := range collection.Values {
+		if tr.Endpoint == nil {
+			return nil, fmt.Errorf("invalid journal entry: no endpoint")
+		}
+		priority := transaction.TransactionPriorityNormal
+		if tr.Priority == retry.TransactionPriorityProto_HIGH {
+			priority = transaction.TransactionPriorityHigh
+		}
+		headers := make(http.Header)
+		for key, values := range tr.Headers {
+			headers[key] = values.Values
+		}
+		route, apiKeyInQueryString := trimAPIKeyQueryString(tr.Endpoint.Route)
+		entries = append(entries, Entry{
+			Endpoint:            transaction.Endpoint{Route: route, Name: tr.Endpoint.Name},
+			APIKeyInQueryString: apiKeyInQueryString,
+			Headers:             headers,
+			Payload:             tr.Payload,
+			CreatedAt:           time.Unix(tr.CreatedAt, 0),
+			Priority:            priority,
+			Retryable:           tr.Retryable,
+		})
+	}
+	return entries, nil
+}
+
+func trimAPIKeyQueryString(route string) (string, bool) {
+	if strings.HasSuffix(route, apiKeyQueryString) {
+		return strings.TrimSuffix(route, apiKeyQueryString), true
+	}
+	return route, false
+}
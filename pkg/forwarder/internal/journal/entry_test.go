@@ -0,0 +1,46 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package journal
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalUnmarshalEntries(t *testing.T) {
+	r := require.New(t)
+	entries := []Entry{
+		{
+			Endpoint:            transaction.Endpoint{Route: "/api/v1/series", Name: "series_v1"},
+			APIKeyInQueryString: true,
+			Headers:             http.Header{"Content-Type": []string{"application/json"}},
+			Payload:             []byte{1, 2, 3},
+			CreatedAt:           time.Unix(1600000000, 0),
+			Priority:            transaction.TransactionPriorityHigh,
+			Retryable:           true,
+		},
+		{
+			Endpoint:  transaction.Endpoint{Route: "/api/beta/sketches", Name: "sketches_v2"},
+			Headers:   http.Header{},
+			Payload:   []byte{4},
+			CreatedAt: time.Unix(1600000001, 0),
+			Priority:  transaction.TransactionPriorityNormal,
+		},
+	}
+
+	record, err := MarshalEntries(entries)
+	r.NoError(err)
+	decoded, err := UnmarshalEntries(record)
+	r.NoError(err)
+	r.Equal(entries, decoded)
+
+	_, err = UnmarshalEntries([]byte{1, 2, 3})
+	r.Error(err)
+}
@@ -0,0 +1,81 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package journal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Each record is prefixed by its size as a big endian uint32.
+const recordHeaderSize = 4
+
+// ListFiles returns the journal files in `path` from the oldest to the newest.
+func ListFiles(path string) ([]string, error) {
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var filenames []string
+	for _, entry := range entries {
+		if entry.Mode().IsRegular() && filepath.Ext(entry.Name()) == journalFileExtension {
+			filenames = append(filenames, filepath.Join(path, entry.Name()))
+		}
+	}
+	sort.Strings(filenames)
+	return filenames, nil
+}
+
+// ReadFile calls `f` for each record of the journal file `filename`.
+// A truncated record at the end of the file, for instance when the Agent
+// was stopped while writing it, is ignored: its size is larger than the
+// bytes remaining in the file, which are not read.
+func ReadFile(filename string, f func(record []byte) error) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	remaining := info.Size()
+
+	reader := bufio.NewReader(file)
+	var header [recordHeaderSize]byte
+	for {
+		if _, err := io.ReadFull(reader, header[:]); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil
+			}
+			return err
+		}
+		remaining -= recordHeaderSize
+		recordSize := int64(binary.BigEndian.Uint32(header[:]))
+		if recordSize > remaining {
+			return nil
+		}
+		remaining -= recordSize
+		record := make([]byte, recordSize)
+		if _, err := io.ReadFull(reader, record); err != nil {
+			if err == io.ErrUnexpectedEOF || err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("cannot read the journal file %v: %v", filename, err)
+		}
+		if err := f(record); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,145 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package journal
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const journalFileExtension = ".journal"
+
+// The file names are sorted in the order they were created.
+const journalFileFormat = "2006_01_02__15_04_05.000000000"
+
+// Writer appends records to journal files. A new file is created when the current
+// one reaches `maxFileSize` and the oldest files are removed when the journal
+// exceeds `maxSize`.
+type Writer struct {
+	m           sync.Mutex
+	path        string
+	maxFileSize int64
+	maxSize     int64
+
+	file      *os.File
+	fileSize  int64
+	filenames []string
+	totalSize int64
+}
+
+// NewWriter creates a new instance of Writer. `maxSize` equal to 0 means the size of the journal is not limited.
+func NewWriter(path string, maxFileSize int64, maxSize int64) (*Writer, error) {
+	if maxFileSize <= 0 {
+		return nil, fmt.Errorf("invalid maximum size for a journal file: %v", maxFileSize)
+	}
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return nil, err
+	}
+
+	filenames, err := ListFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	totalSize := int64(0)
+	for _, filename := range filenames {
+		if info, err := os.Stat(filename); err == nil {
+			totalSize += info.Size()
+		}
+	}
+
+	return &Writer{
+		path:        path,
+		maxFileSize: maxFileSize,
+		maxSize:     maxSize,
+		filenames:   filenames,
+		totalSize:   totalSize,
+	}, nil
+}
+
+// Write appends a record to the journal.
+func (w *Writer) Write(record []byte) error {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	recordSize := int64(len(record) + recordHeaderSize)
+	if recordSize > w.maxFileSize {
+		return fmt.Errorf("the record of %v bytes is larger than the maximum size of a journal file: %v", len(record), w.maxFileSize)
+	}
+	if w.file == nil || w.fileSize+recordSize > w.maxFileSize {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+	w.removeOldestFiles(recordSize)
+
+	var header [recordHeaderSize]byte
+	binary.BigEndian.PutUint32(header[:], uint32(len(record)))
+	if _, err := w.file.Write(append(header[:], record...)); err != nil {
+		return err
+	}
+	w.fileSize += recordSize
+	w.totalSize += recordSize
+	return nil
+}
+
+// Close closes the current journal file.
+func (w *Writer) Close() error {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+func (w *Writer) rotate() error {
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			log.Errorf("Cannot close the journal file %v: %v", w.file.Name(), err)
+		}
+		w.file = nil
+	}
+
+	filename := filepath.Join(w.path, time.Now().UTC().Format(journalFileFormat)+journalFileExtension)
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	w.file = file
+	w.fileSize = 0
+	w.filenames = append(w.filenames, filename)
+	return nil
+}
+
+// removeOldestFiles removes the oldest files, except the current one, to keep the journal under `maxSize`.
+func (w *Writer) removeOldestFiles(recordSize int64) {
+	if w.maxSize <= 0 {
+		return
+	}
+	for len(w.filenames) > 1 && w.totalSize+recordSize > w.maxSize {
+		filename := w.filenames[0]
+		w.filenames = w.filenames[1:]
+		info, err := os.Stat(filename)
+		if err == nil {
+			w.totalSize -= info.Size()
+			err = os.Remove(filename)
+		}
+		if err != nil {
+			log.Errorf("Cannot remove the journal file %v: %v", filename, err)
+		} else {
+			log.Warnf("Maximum disk space for the forwarder journal is reached. Removing %s", filename)
+		}
+	}
+}
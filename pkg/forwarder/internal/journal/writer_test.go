@@ -0,0 +1,130 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package journal
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriterReadFile(t *testing.T) {
+	r := require.New(t)
+	path, err := ioutil.TempDir("", "journal")
+	r.NoError(err)
+	defer os.RemoveAll(path)
+
+	w, err := NewWriter(path, 1000, 0)
+	r.NoError(err)
+	r.NoError(w.Write([]byte("record1")))
+	r.NoError(w.Write([]byte("record2")))
+	r.NoError(w.Close())
+
+	filenames, err := ListFiles(path)
+	r.NoError(err)
+	r.Len(filenames, 1)
+	r.Equal([]string{"record1", "record2"}, readRecords(r, filenames[0]))
+}
+
+func TestWriterRotation(t *testing.T) {
+	r := require.New(t)
+	path, err := ioutil.TempDir("", "journal")
+	r.NoError(err)
+	defer os.RemoveAll(path)
+
+	// Each record uses 11 bytes with its header.
+	w, err := NewWriter(path, 22, 44)
+	r.NoError(err)
+	for _, record := range []string{"record1", "record2", "record3", "record4", "record5", "record6"} {
+		r.NoError(w.Write([]byte(record)))
+	}
+	r.NoError(w.Close())
+
+	filenames, err := ListFiles(path)
+	r.NoError(err)
+	r.Len(filenames, 2)
+	r.Equal([]string{"record3", "record4"}, readRecords(r, filenames[0]))
+	r.Equal([]string{"record5", "record6"}, readRecords(r, filenames[1]))
+
+	// Existing files are taken into account after a restart.
+	w, err = NewWriter(path, 22, 44)
+	r.NoError(err)
+	r.NoError(w.Write([]byte("record7")))
+	r.NoError(w.Close())
+	filenames, err = ListFiles(path)
+	r.NoError(err)
+	r.Len(filenames, 2)
+	r.Equal([]string{"record7"}, readRecords(r, filenames[1]))
+}
+
+func TestReadFileTruncated(t *testing.T) {
+	r := require.New(t)
+	path, err := ioutil.TempDir("", "journal")
+	r.NoError(err)
+	defer os.RemoveAll(path)
+
+	w, err := NewWriter(path, 1000, 0)
+	r.NoError(err)
+	r.NoError(w.Write([]byte("record1")))
+	r.NoError(w.Write([]byte("record2")))
+	r.NoError(w.Close())
+
+	filenames, err := ListFiles(path)
+	r.NoError(err)
+	info, err := os.Stat(filenames[0])
+	r.NoError(err)
+	r.NoError(os.Truncate(filenames[0], info.Size()-2))
+	r.Equal([]string{"record1"}, readRecords(r, filenames[0]))
+}
+
+func TestWriteRecordTooLarge(t *testing.T) {
+	r := require.New(t)
+	path, err := ioutil.TempDir("", "journal")
+	r.NoError(err)
+	defer os.RemoveAll(path)
+
+	w, err := NewWriter(path, 10, 0)
+	r.NoError(err)
+	r.NoError(w.Write([]byte("record")))
+	r.Error(w.Write([]byte("record1")))
+	r.NoError(w.Close())
+}
+
+func TestReadFilePartialRecord(t *testing.T) {
+	r := require.New(t)
+	path, err := ioutil.TempDir("", "journal")
+	r.NoError(err)
+	defer os.RemoveAll(path)
+
+	// the file only contains the beginning of a record of 16 bytes
+	filename := filepath.Join(path, "partial"+journalFileExtension)
+	r.NoError(ioutil.WriteFile(filename, []byte{0, 0, 0, 16, 'r', 'e', 'c'}, 0600))
+	r.Empty(readRecords(r, filename))
+}
+
+func TestReadFileRecordLargerThanRemainingBytes(t *testing.T) {
+	r := require.New(t)
+	path, err := ioutil.TempDir("", "journal")
+	r.NoError(err)
+	defer os.RemoveAll(path)
+
+	// the size of the second record fits in the file but not in the bytes remaining after the first one
+	filename := filepath.Join(path, "truncated"+journalFileExtension)
+	r.NoError(ioutil.WriteFile(filename, []byte{0, 0, 0, 3, 'r', 'e', 'c', 0, 0, 0, 8, 'r', 'e', 'c', 'o'}, 0600))
+	r.Equal([]string{"rec"}, readRecords(r, filename))
+}
+
+func readRecords(r *require.Assertions, filename string) []string {
+	var records []string
+	r.NoError(ReadFile(filename, func(record []byte) error {
+		records = append(records, string(record))
+		return nil
+	}))
+	return records
+}
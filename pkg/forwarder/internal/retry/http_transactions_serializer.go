@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"net/http"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -36,12 +37,20 @@ type HTTPTransactionsSerializer struct {
 	apiKeyToPlaceholder *strings.Replacer
 	placeholderToAPIKey *strings.Replacer
 	resolver            resolver.DomainResolver
+	scrubbedHeaders     map[string]struct{}
 }
 
-// NewHTTPTransactionsSerializer creates a new instance of HTTPTransactionsSerializer
-func NewHTTPTransactionsSerializer(resolver resolver.DomainResolver) *HTTPTransactionsSerializer {
+// NewHTTPTransactionsSerializer creates a new instance of HTTPTransactionsSerializer.
+// The values of `scrubbedHeaders` are never stored: an API key is replaced by a placeholder
+// and re-injected when the transaction is deserialized, any other value is dropped.
+func NewHTTPTransactionsSerializer(resolver resolver.DomainResolver, scrubbedHeaders []string) *HTTPTransactionsSerializer {
 	apiKeyToPlaceholder, placeholderToAPIKey := createReplacers(resolver.GetAPIKeys())
 
+	headers := make(map[string]struct{})
+	for _, h := range scrubbedHeaders {
+		headers[http.CanonicalHeaderKey(h)] = struct{}{}
+	}
+
 	return &HTTPTransactionsSerializer{
 		collection: HttpTransactionProtoCollection{
 			Version: transactionsSerializerVersion,
@@ -49,6 +58,7 @@ func NewHTTPTransactionsSerializer(resolver resolver.DomainResolver) *HTTPTransa
 		apiKeyToPlaceholder: apiKeyToPlaceholder,
 		placeholderToAPIKey: placeholderToAPIKey,
 		resolver:            resolver,
+		scrubbedHeaders:     headers,
 	}
 }
 
@@ -189,12 +199,38 @@ func fromTransactionPriorityProto(priority TransactionPriorityProto) (transactio
 func (s *HTTPTransactionsSerializer) toHeaderProto(headers http.Header) map[string]*HeaderValuesProto {
 	headersProto := make(map[string]*HeaderValuesProto)
 	for key, headerValues := range headers {
-		headerValuesProto := HeaderValuesProto{Values: common.StringSliceTransform(headerValues, s.replaceAPIKeys)}
+		values := common.StringSliceTransform(headerValues, s.replaceAPIKeys)
+		if _, found := s.scrubbedHeaders[http.CanonicalHeaderKey(key)]; found {
+			values = scrubHeaderValues(key, values)
+		}
+		headerValuesProto := HeaderValuesProto{Values: values}
 		headersProto[key] = &headerValuesProto
 	}
 	return headersProto
 }
 
+// scrubHeaderValues keeps only the values which are API key placeholders.
+func scrubHeaderValues(key string, values []string) []string {
+	var scrubbed []string
+	for _, v := range values {
+		if isAPIKeyPlaceholder(v) {
+			scrubbed = append(scrubbed, v)
+		} else {
+			log.Debugf("The header %v contains a value which is not a known API key, the value is not stored on disk", key)
+		}
+	}
+	return scrubbed
+}
+
+func isAPIKeyPlaceholder(str string) bool {
+	if !strings.HasPrefix(str, placeHolderPrefix) || !strings.HasSuffix(str, squareChar) {
+		return false
+	}
+	index := strings.TrimSuffix(strings.TrimPrefix(str, placeHolderPrefix), squareChar)
+	_, err := strconv.Atoi(index)
+	return err == nil
+}
+
 func toTransactionPriorityProto(priority transaction.Priority) (TransactionPriorityProto, error) {
 	switch priority {
 	case transaction.TransactionPriorityNormal:
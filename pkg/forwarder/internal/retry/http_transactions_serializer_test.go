@@ -36,7 +36,7 @@ func runTestHTTPSerializeDeserializeWithResolver(t *testing.T, d string, r resol
 	a := assert.New(t)
 	tr := createHTTPTransactionTests(d)
 
-	serializer := NewHTTPTransactionsSerializer(r)
+	serializer := NewHTTPTransactionsSerializer(r, nil)
 
 	a.NoError(serializer.Add(tr))
 	bytes, err := serializer.GetBytesAndReset()
@@ -61,7 +61,7 @@ func runTestHTTPSerializeDeserializeWithResolver(t *testing.T, d string, r resol
 func TestPartialDeserialize(t *testing.T) {
 	a := assert.New(t)
 	initialTransaction := createHTTPTransactionTests(domain)
-	serializer := NewHTTPTransactionsSerializer(resolver.NewSingleDomainResolver(domain, nil))
+	serializer := NewHTTPTransactionsSerializer(resolver.NewSingleDomainResolver(domain, nil), nil)
 
 	a.NoError(serializer.Add(initialTransaction))
 	a.NoError(serializer.Add(initialTransaction))
@@ -83,7 +83,7 @@ func TestPartialDeserialize(t *testing.T) {
 func TestHTTPTransactionSerializerMissingAPIKey(t *testing.T) {
 	r := require.New(t)
 
-	serializer := NewHTTPTransactionsSerializer(resolver.NewSingleDomainResolver(domain, []string{apiKey1, apiKey2}))
+	serializer := NewHTTPTransactionsSerializer(resolver.NewSingleDomainResolver(domain, []string{apiKey1, apiKey2}), nil)
 
 	r.NoError(serializer.Add(createHTTPTransactionWithHeaderTests(http.Header{"Key": []string{apiKey1}}, domain)))
 	r.NoError(serializer.Add(createHTTPTransactionWithHeaderTests(http.Header{"Key": []string{apiKey2}}, domain)))
@@ -94,12 +94,38 @@ func TestHTTPTransactionSerializerMissingAPIKey(t *testing.T) {
 	r.NoError(err)
 	r.Equal(0, errorCount)
 
-	serializerMissingAPIKey := NewHTTPTransactionsSerializer(resolver.NewSingleDomainResolver(domain, []string{apiKey1}))
+	serializerMissingAPIKey := NewHTTPTransactionsSerializer(resolver.NewSingleDomainResolver(domain, []string{apiKey1}), nil)
 	_, errorCount, err = serializerMissingAPIKey.Deserialize(bytes)
 	r.NoError(err)
 	r.Equal(1, errorCount)
 }
 
+func TestHTTPTransactionSerializerScrubbedHeaders(t *testing.T) {
+	r := require.New(t)
+
+	serializer := NewHTTPTransactionsSerializer(resolver.NewSingleDomainResolver(domain, []string{apiKey1, apiKey2}), []string{"dd-api-key", "Authorization"})
+	header := http.Header{
+		"Dd-Api-Key":    []string{apiKey2, "unknownKey"},
+		"Authorization": []string{"Bearer token"},
+		"Key":           []string{"value"},
+	}
+	r.NoError(serializer.Add(createHTTPTransactionWithHeaderTests(header, domain)))
+	bytes, err := serializer.GetBytesAndReset()
+	r.NoError(err)
+	r.NotContains(string(bytes), apiKey2)
+	r.NotContains(string(bytes), "unknownKey")
+	r.NotContains(string(bytes), "Bearer token")
+
+	transactions, errorCount, err := serializer.Deserialize(bytes)
+	r.NoError(err)
+	r.Equal(0, errorCount)
+	r.Len(transactions, 1)
+	tr := transactions[0].(*transaction.HTTPTransaction)
+	r.Equal([]string{apiKey2}, tr.Headers["Dd-Api-Key"])
+	r.Empty(tr.Headers["Authorization"])
+	r.Equal([]string{"value"}, tr.Headers["Key"])
+}
+
 func TestHTTPTransactionFieldsCount(t *testing.T) {
 	tr := transaction.HTTPTransaction{}
 	transactionType := reflect.TypeOf(tr)
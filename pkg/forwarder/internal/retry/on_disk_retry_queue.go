@@ -28,6 +28,7 @@ type onDiskRetryQueue struct {
 	diskUsageLimit     *DiskUsageLimit
 	filenames          []string
 	currentSizeInBytes int64
+	optionalEncryption *TransactionEncryption
 	telemetry          onDiskRetryQueueTelemetry
 }
 
@@ -35,6 +36,7 @@ func newOnDiskRetryQueue(
 	serializer *HTTPTransactionsSerializer,
 	storagePath string,
 	diskUsageLimit *DiskUsageLimit,
+	optionalEncryption *TransactionEncryption,
 	telemetry onDiskRetryQueueTelemetry) (*onDiskRetryQueue, error) {
 
 	if err := os.MkdirAll(storagePath, 0700); err != nil {
@@ -42,10 +44,11 @@ func newOnDiskRetryQueue(
 	}
 
 	storage := &onDiskRetryQueue{
-		serializer:     serializer,
-		storagePath:    storagePath,
-		diskUsageLimit: diskUsageLimit,
-		telemetry:      telemetry,
+		serializer:         serializer,
+		storagePath:        storagePath,
+		diskUsageLimit:     diskUsageLimit,
+		optionalEncryption: optionalEncryption,
+		telemetry:          telemetry,
 	}
 
 	if err := storage.reloadExistingRetryFiles(); err != nil {
@@ -77,6 +80,11 @@ func (s *onDiskRetryQueue) Serialize(transactions []transaction.Transaction) err
 	if err != nil {
 		return err
 	}
+	if s.optionalEncryption != nil {
+		if bytes, err = s.optionalEncryption.encrypt(bytes); err != nil {
+			return err
+		}
+	}
 	bufferSize := int64(len(bytes))
 
 	if err := s.makeRoomFor(bufferSize); err != nil {
@@ -122,6 +130,12 @@ func (s *onDiskRetryQueue) Deserialize() ([]transaction.Transaction, error) {
 		return nil, err
 	}
 
+	if s.optionalEncryption != nil {
+		if bytes, err = s.optionalEncryption.decrypt(bytes); err != nil {
+			return nil, fmt.Errorf("cannot decrypt the retry file %v: %v", path, err)
+		}
+	}
+
 	transactions, errorsCount, err := s.serializer.Deserialize(bytes)
 	if err != nil {
 		return nil, err
@@ -91,6 +91,36 @@ func TestOnDiskRetryQueueReloadExistingRetryFiles(t *testing.T) {
 	a.Equal([]string{"endpoint1", "endpoint2"}, getEndpointsFromTransactions(transactions))
 }
 
+func TestOnDiskRetryQueueEncryption(t *testing.T) {
+	a := assert.New(t)
+	path, clean := createTmpFolder(a)
+	defer clean()
+
+	encryption, err := NewTransactionEncryption([]byte("secret"))
+	a.NoError(err)
+	q := newTestOnDiskRetryQueueWithEncryption(a, path, 1000, encryption)
+	a.NoError(q.Serialize(createHTTPTransactionCollectionTests("endpoint1", "endpoint2")))
+	a.Equal(1, q.getFilesCount())
+
+	content, err := ioutil.ReadFile(q.filenames[0])
+	a.NoError(err)
+	a.NotContains(string(content), "endpoint1")
+
+	// The files cannot be read with a different key.
+	otherEncryption, err := NewTransactionEncryption([]byte("other secret"))
+	a.NoError(err)
+	otherQueue := newTestOnDiskRetryQueueWithEncryption(a, path, 1000, otherEncryption)
+	a.Equal(1, otherQueue.getFilesCount())
+	a.NoError(q.Serialize(createHTTPTransactionCollectionTests("endpoint3")))
+
+	transactions, err := q.Deserialize()
+	a.NoError(err)
+	a.Equal([]string{"endpoint3"}, getEndpointsFromTransactions(transactions))
+	_, err = otherQueue.Deserialize()
+	a.Error(err)
+	a.Equal(0, otherQueue.getFilesCount())
+}
+
 func createHTTPTransactionCollectionTests(endpoints ...string) []transaction.Transaction {
 	var transactions []transaction.Transaction
 
@@ -119,6 +149,10 @@ func getEndpointsFromTransactions(transactions []transaction.Transaction) []stri
 }
 
 func newTestOnDiskRetryQueue(a *assert.Assertions, path string, maxSizeInBytes int64) *onDiskRetryQueue {
+	return newTestOnDiskRetryQueueWithEncryption(a, path, maxSizeInBytes, nil)
+}
+
+func newTestOnDiskRetryQueueWithEncryption(a *assert.Assertions, path string, maxSizeInBytes int64, encryption *TransactionEncryption) *onDiskRetryQueue {
 	telemetry := newOnDiskRetryQueueTelemetry("domain")
 	disk := diskUsageRetrieverMock{
 		diskUsage: &filesystem.DiskUsage{
@@ -126,7 +160,7 @@ func newTestOnDiskRetryQueue(a *assert.Assertions, path string, maxSizeInBytes i
 			Total:     10000,
 		}}
 	diskUsageLimit := NewDiskUsageLimit("", disk, maxSizeInBytes, 1)
-	storage, err := newOnDiskRetryQueue(NewHTTPTransactionsSerializer(resolver.NewSingleDomainResolver(domainName, nil)), path, diskUsageLimit, telemetry)
+	storage, err := newOnDiskRetryQueue(NewHTTPTransactionsSerializer(resolver.NewSingleDomainResolver(domainName, nil), nil), path, diskUsageLimit, encryption, telemetry)
 	a.NoError(err)
 	return storage
 }
@@ -0,0 +1,70 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package retry
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+)
+
+// The label is part of the key derivation so the key used for the retry files
+// is different from the key material provided by the user.
+const transactionEncryptionKeyLabel = "datadog-agent forwarder retry queue"
+
+// TransactionEncryption encrypts and decrypts the content of the retry files
+// using AES-256-GCM.
+type TransactionEncryption struct {
+	aead cipher.AEAD
+}
+
+// NewTransactionEncryption creates a new instance of TransactionEncryption.
+// The AES key is derived from `secret` which can be of any length.
+func NewTransactionEncryption(secret []byte) (*TransactionEncryption, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("the encryption key for the retry files is empty")
+	}
+
+	key := deriveTransactionEncryptionKey(secret)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &TransactionEncryption{aead: aead}, nil
+}
+
+// encrypt returns the nonce followed by the encrypted and authenticated data.
+func (e *TransactionEncryption) encrypt(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, e.aead.NonceSize(), e.aead.NonceSize()+len(plaintext)+e.aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return e.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// decrypt reverses `encrypt`. An error is returned if the data was not
+// encrypted with the same key or was modified.
+func (e *TransactionEncryption) decrypt(data []byte) ([]byte, error) {
+	nonceSize := e.aead.NonceSize()
+	if len(data) < nonceSize+e.aead.Overhead() {
+		return nil, errors.New("the encrypted retry file is too small")
+	}
+	return e.aead.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+}
+
+func deriveTransactionEncryptionKey(secret []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte(transactionEncryptionKeyLabel))
+	h.Write(secret)
+	return h.Sum(nil)
+}
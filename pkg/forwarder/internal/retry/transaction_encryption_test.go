@@ -0,0 +1,53 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package retry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionEncryption(t *testing.T) {
+	r := require.New(t)
+	encryption, err := NewTransactionEncryption([]byte("secret"))
+	r.NoError(err)
+
+	plaintext := []byte("payload")
+	data, err := encryption.encrypt(plaintext)
+	r.NoError(err)
+	r.NotContains(string(data), string(plaintext))
+
+	decrypted, err := encryption.decrypt(data)
+	r.NoError(err)
+	r.Equal(plaintext, decrypted)
+
+	// The data is modified
+	data[len(data)-1] ^= 0xff
+	_, err = encryption.decrypt(data)
+	r.Error(err)
+
+	_, err = encryption.decrypt([]byte{1, 2, 3})
+	r.Error(err)
+}
+
+func TestTransactionEncryptionWrongKey(t *testing.T) {
+	r := require.New(t)
+	encryption, err := NewTransactionEncryption([]byte("secret"))
+	r.NoError(err)
+	otherEncryption, err := NewTransactionEncryption([]byte("other secret"))
+	r.NoError(err)
+
+	data, err := encryption.encrypt([]byte("payload"))
+	r.NoError(err)
+	_, err = otherEncryption.decrypt(data)
+	r.Error(err)
+}
+
+func TestTransactionEncryptionEmptyKey(t *testing.T) {
+	_, err := NewTransactionEncryption(nil)
+	require.Error(t, err)
+}
@@ -46,14 +46,21 @@ func BuildTransactionRetryQueue(
 	flushToStorageRatio float64,
 	optionalDomainFolderPath string,
 	optionalDiskUsageLimit *DiskUsageLimit,
+	optionalEncryption *TransactionEncryption,
+	scrubbedHeaders []string,
 	dropPrioritySorter TransactionPrioritySorter,
 	resolver resolver.DomainResolver) *TransactionRetryQueue {
 	var storage DiskTransactionSerializer
 	var err error
 
 	if optionalDomainFolderPath != "" && optionalDiskUsageLimit != nil {
-		serializer := NewHTTPTransactionsSerializer(resolver)
-		storage, err = newOnDiskRetryQueue(serializer, optionalDomainFolderPath, optionalDiskUsageLimit, newOnDiskRetryQueueTelemetry(resolver.GetBaseDomain()))
+		serializer := NewHTTPTransactionsSerializer(resolver, scrubbedHeaders)
+		storage, err = newOnDiskRetryQueue(
+			serializer,
+			optionalDomainFolderPath,
+			optionalDiskUsageLimit,
+			optionalEncryption,
+			newOnDiskRetryQueueTelemetry(resolver.GetBaseDomain()))
 
 		// If the storage on disk cannot be used, log the error and continue.
 		// Returning `nil, err` would mean not using `TransactionRetryQueue` and so not using `forwarder_retry_queue_payloads_max_size` config.
@@ -26,6 +26,8 @@ var (
 	transactionsRetried              = expvar.Int{}
 	transactionsRetriedByEndpoint    = expvar.Map{}
 	transactionsRetryQueueSize       = expvar.Int{}
+	transactionsThrottled            = expvar.Int{}
+	transactionsCoalesced            = expvar.Int{}
 
 	tlmTxInputBytes = telemetry.NewCounter("transactions", "input_bytes",
 		[]string{"domain", "endpoint"}, "Incoming transaction sizes in bytes")
@@ -39,6 +41,10 @@ var (
 		[]string{"domain", "endpoint"}, "Transaction retry count")
 	tlmTxRetryQueueSize = telemetry.NewGauge("transactions", "retry_queue_size",
 		[]string{"domain"}, "Retry queue size")
+	tlmTxThrottled = telemetry.NewCounter("transactions", "throttled",
+		[]string{"endpoint"}, "Count of transactions deferred to the retry queue because of the bandwidth limit")
+	tlmTxCoalesced = telemetry.NewCounter("transactions", "coalesced",
+		[]string{"endpoint"}, "Count of transactions sent back to back with other small transactions to the same endpoint")
 )
 
 func init() {
@@ -102,4 +108,6 @@ func initTransactionsExpvars() {
 	transaction.TransactionsExpvars.Set("Retried", &transactionsRetried)
 	transaction.TransactionsExpvars.Set("RetriedByEndpoint", &transactionsRetriedByEndpoint)
 	transaction.TransactionsExpvars.Set("RetryQueueSize", &transactionsRetryQueueSize)
+	transaction.TransactionsExpvars.Set("Throttled", &transactionsThrottled)
+	transaction.TransactionsExpvars.Set("Coalesced", &transactionsCoalesced)
 }
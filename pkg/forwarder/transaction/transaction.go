@@ -32,6 +32,8 @@ var (
 
 	connectionDNSSuccess         = expvar.Int{}
 	connectionConnectSuccess     = expvar.Int{}
+	connectionReused             = expvar.Int{}
+	connectionTLSHandshake       = expvar.Int{}
 	transactionsConnectionEvents = expvar.Map{}
 
 	// TransactionsDropped is the number of transaction dropped.
@@ -102,12 +104,21 @@ var Trace = &httptrace.ClientTrace{
 		tlmConnectEvents.Inc("connection_success")
 		log.Tracef("New successful connection to address: %q", addr)
 	},
+	GotConn: func(connInfo httptrace.GotConnInfo) {
+		if connInfo.Reused {
+			connectionReused.Add(1)
+			tlmConnectEvents.Inc("connection_reused")
+		}
+	},
 	TLSHandshakeDone: func(tlsState tls.ConnectionState, err error) {
 		if err != nil {
 			transactionsTLSErrors.Add(1)
 			tlmTxErrors.Inc("unknown", "unknown", "tls_handshake_failure")
 			log.Errorf("TLS Handshake failure: %s", err)
+			return
 		}
+		connectionTLSHandshake.Add(1)
+		tlmConnectEvents.Inc("tls_handshake_success")
 	},
 }
 
@@ -134,6 +145,8 @@ func init() {
 	ForwarderExpvars.Set("Transactions", &TransactionsExpvars)
 	transactionsConnectionEvents.Set("DNSSuccess", &connectionDNSSuccess)
 	transactionsConnectionEvents.Set("ConnectSuccess", &connectionConnectSuccess)
+	transactionsConnectionEvents.Set("ConnectionReused", &connectionReused)
+	transactionsConnectionEvents.Set("TLSHandshakeSuccess", &connectionTLSHandshake)
 	TransactionsExpvars.Set("ConnectionEvents", &transactionsConnectionEvents)
 	TransactionsExpvars.Set("Dropped", &TransactionsDropped)
 	TransactionsExpvars.Set("DroppedByEndpoint", &TransactionsDroppedByEndpoint)
@@ -0,0 +1,182 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package forwarder
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
+)
+
+var errCoalescedNotSerializable = errors.New("coalesced transactions are not serializable")
+
+// coalescedTransaction holds small transactions to the same target which are sent back to back
+// by a single worker, so they reuse the same connection instead of opening one per worker.
+// It never reaches the retry queue: the worker handles each of its transactions on its own.
+type coalescedTransaction struct {
+	transactions []transaction.Transaction
+	payloadSize  int
+}
+
+var _ transaction.Transaction = &coalescedTransaction{}
+
+// Process sends the transactions one after the other. Workers don't call it as they need to
+// handle the retries of each transaction, it is only there to satisfy the Transaction interface.
+func (t *coalescedTransaction) Process(ctx context.Context, client *http.Client) error {
+	for _, tr := range t.transactions {
+		if err := tr.Process(ctx, client); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetCreatedAt returns the creation time of the oldest transaction.
+func (t *coalescedTransaction) GetCreatedAt() time.Time {
+	return t.transactions[0].GetCreatedAt()
+}
+
+// GetTarget returns the target shared by the transactions.
+func (t *coalescedTransaction) GetTarget() string {
+	return t.transactions[0].GetTarget()
+}
+
+// GetPriority returns the highest priority of the transactions.
+func (t *coalescedTransaction) GetPriority() transaction.Priority {
+	for _, tr := range t.transactions {
+		if tr.GetPriority() == transaction.TransactionPriorityHigh {
+			return transaction.TransactionPriorityHigh
+		}
+	}
+	return transaction.TransactionPriorityNormal
+}
+
+// GetEndpointName returns the endpoint name shared by the transactions.
+func (t *coalescedTransaction) GetEndpointName() string {
+	return t.transactions[0].GetEndpointName()
+}
+
+// GetPayloadSize returns the total size of the payloads.
+func (t *coalescedTransaction) GetPayloadSize() int {
+	return t.payloadSize
+}
+
+// SerializeTo always fails: the transactions are serialized one by one when they are retried.
+func (t *coalescedTransaction) SerializeTo(transaction.TransactionsSerializer) error {
+	return errCoalescedNotSerializable
+}
+
+// transactionCoalescer groups the small transactions sent to the same target during a short window.
+// Without it, consecutive small transactions are spread over all the workers and thus over as many
+// connections, which means as many TLS handshakes when the connections are reset, for instance
+// by a proxy.
+type transactionCoalescer struct {
+	window          time.Duration
+	maxPayloadSize  int
+	maxTransactions int
+	send            func(transaction.Transaction)
+
+	m       sync.Mutex
+	pending map[string]*coalescedTransaction
+	timers  map[string]*time.Timer
+	// sending tracks the groups being sent by the timers, so that flush waits for them
+	sending sync.WaitGroup
+}
+
+// newTransactionCoalescer returns a transactionCoalescer sending the groups of transactions with `send`.
+// It returns nil when the coalescing is disabled.
+func newTransactionCoalescer(settings domainHTTPSettings, send func(transaction.Transaction)) *transactionCoalescer {
+	if settings.CoalesceWindowMs <= 0 || settings.CoalesceMaxPayloadSize <= 0 || settings.CoalesceMaxTransactions <= 1 {
+		return nil
+	}
+	return &transactionCoalescer{
+		window:          time.Duration(settings.CoalesceWindowMs) * time.Millisecond,
+		maxPayloadSize:  settings.CoalesceMaxPayloadSize,
+		maxTransactions: settings.CoalesceMaxTransactions,
+		send:            send,
+		pending:         make(map[string]*coalescedTransaction),
+		timers:          make(map[string]*time.Timer),
+	}
+}
+
+// add returns false if the transaction is too big to be coalesced or has a high priority, and
+// must be sent right away.
+func (c *transactionCoalescer) add(t transaction.Transaction) bool {
+	if t.GetPriority() == transaction.TransactionPriorityHigh {
+		return false
+	}
+	size := t.GetPayloadSize()
+	if size > c.maxPayloadSize {
+		return false
+	}
+
+	target := t.GetTarget()
+	c.m.Lock()
+	group, found := c.pending[target]
+	if !found {
+		group = &coalescedTransaction{}
+		c.pending[target] = group
+		c.timers[target] = time.AfterFunc(c.window, func() { c.flushTarget(target, group) })
+	}
+	group.transactions = append(group.transactions, t)
+	group.payloadSize += size
+	full := len(group.transactions) >= c.maxTransactions
+	c.m.Unlock()
+
+	if full {
+		c.flushTarget(target, group)
+	}
+	return true
+}
+
+// flushTarget sends the group of transactions of `target` if it is still pending.
+func (c *transactionCoalescer) flushTarget(target string, group *coalescedTransaction) {
+	c.m.Lock()
+	if c.pending[target] != group {
+		// already flushed
+		c.m.Unlock()
+		return
+	}
+	delete(c.pending, target)
+	c.timers[target].Stop()
+	delete(c.timers, target)
+	c.sending.Add(1)
+	c.m.Unlock()
+
+	defer c.sending.Done()
+	c.sendGroup(group)
+}
+
+// flush sends all the pending groups of transactions, and waits for the groups being sent.
+func (c *transactionCoalescer) flush() {
+	c.m.Lock()
+	pending := c.pending
+	for _, timer := range c.timers {
+		timer.Stop()
+	}
+	c.pending = make(map[string]*coalescedTransaction)
+	c.timers = make(map[string]*time.Timer)
+	c.m.Unlock()
+
+	for _, group := range pending {
+		c.sendGroup(group)
+	}
+	c.sending.Wait()
+}
+
+func (c *transactionCoalescer) sendGroup(group *coalescedTransaction) {
+	if len(group.transactions) == 1 {
+		c.send(group.transactions[0])
+		return
+	}
+	transactionsCoalesced.Add(int64(len(group.transactions)))
+	tlmTxCoalesced.Add(float64(len(group.transactions)), group.GetEndpointName())
+	c.send(group)
+}
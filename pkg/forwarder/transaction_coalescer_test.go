@@ -0,0 +1,141 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+// +build test
+
+package forwarder
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
+)
+
+type sentTransactions struct {
+	m    sync.Mutex
+	sent []transaction.Transaction
+}
+
+func (s *sentTransactions) send(t transaction.Transaction) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.sent = append(s.sent, t)
+}
+
+func (s *sentTransactions) get() []transaction.Transaction {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return append([]transaction.Transaction{}, s.sent...)
+}
+
+func newCoalescerTestTransaction(target string, size int) *testTransaction {
+	tr := newTestTransaction()
+	tr.On("GetTarget").Return(target)
+	tr.On("GetPayloadSize").Return(size)
+	return tr
+}
+
+func TestNewTransactionCoalescerDisabled(t *testing.T) {
+	send := func(transaction.Transaction) {}
+	assert.Nil(t, newTransactionCoalescer(domainHTTPSettings{}, send))
+	assert.Nil(t, newTransactionCoalescer(domainHTTPSettings{CoalesceWindowMs: 10, CoalesceMaxPayloadSize: 10, CoalesceMaxTransactions: 1}, send))
+	assert.NotNil(t, newTransactionCoalescer(domainHTTPSettings{CoalesceWindowMs: 10, CoalesceMaxPayloadSize: 10, CoalesceMaxTransactions: 2}, send))
+}
+
+func TestTransactionCoalescerMaxTransactions(t *testing.T) {
+	sent := &sentTransactions{}
+	c := newTransactionCoalescer(domainHTTPSettings{CoalesceWindowMs: 60000, CoalesceMaxPayloadSize: 100, CoalesceMaxTransactions: 2}, sent.send)
+
+	t1 := newCoalescerTestTransaction("/api/v1/series", 10)
+	t2 := newCoalescerTestTransaction("/api/v1/check_run", 10)
+	t3 := newCoalescerTestTransaction("/api/v1/series", 20)
+
+	assert.True(t, c.add(t1))
+	assert.True(t, c.add(t2))
+	assert.Len(t, sent.get(), 0)
+
+	// the group of the series endpoint is full
+	assert.True(t, c.add(t3))
+	require.Len(t, sent.get(), 1)
+	group, ok := sent.get()[0].(*coalescedTransaction)
+	require.True(t, ok)
+	assert.Equal(t, []transaction.Transaction{t1, t3}, group.transactions)
+	assert.Equal(t, 30, group.GetPayloadSize())
+	assert.Equal(t, "/api/v1/series", group.GetTarget())
+
+	// a group of a single transaction is sent as is
+	c.flush()
+	require.Len(t, sent.get(), 2)
+	assert.Same(t, t2, sent.get()[1])
+}
+
+func TestTransactionCoalescerWindow(t *testing.T) {
+	sent := &sentTransactions{}
+	c := newTransactionCoalescer(domainHTTPSettings{CoalesceWindowMs: 10, CoalesceMaxPayloadSize: 100, CoalesceMaxTransactions: 10}, sent.send)
+
+	assert.True(t, c.add(newCoalescerTestTransaction("/api/v1/series", 10)))
+	assert.True(t, c.add(newCoalescerTestTransaction("/api/v1/series", 10)))
+
+	assert.Eventually(t, func() bool { return len(sent.get()) == 1 }, time.Second, time.Millisecond)
+	assert.Len(t, sent.get()[0].(*coalescedTransaction).transactions, 2)
+}
+
+func TestTransactionCoalescerBigPayload(t *testing.T) {
+	sent := &sentTransactions{}
+	c := newTransactionCoalescer(domainHTTPSettings{CoalesceWindowMs: 10, CoalesceMaxPayloadSize: 100, CoalesceMaxTransactions: 10}, sent.send)
+
+	assert.False(t, c.add(newCoalescerTestTransaction("/api/v1/series", 101)))
+	c.flush()
+	assert.Len(t, sent.get(), 0)
+}
+
+func TestTransactionCoalescerHighPriority(t *testing.T) {
+	sent := &sentTransactions{}
+	c := newTransactionCoalescer(domainHTTPSettings{CoalesceWindowMs: 60000, CoalesceMaxPayloadSize: 100, CoalesceMaxTransactions: 10}, sent.send)
+
+	assert.True(t, c.add(newCoalescerTestTransaction("/api/v1/series", 10)))
+
+	// a high priority transaction is not held for the window, even with a pending group for its target
+	tr := transaction.NewHTTPTransaction()
+	tr.Domain = "https://example.com"
+	tr.Endpoint.Route = "/api/v1/series"
+	payload := []byte("payload")
+	tr.Payload = &payload
+	tr.Priority = transaction.TransactionPriorityHigh
+	assert.False(t, c.add(tr))
+	assert.Len(t, sent.get(), 0)
+
+	c.flush()
+	require.Len(t, sent.get(), 1)
+	assert.NotSame(t, tr, sent.get()[0])
+}
+
+func TestWorkerProcessCoalescedTransaction(t *testing.T) {
+	highPrio := make(chan transaction.Transaction)
+	lowPrio := make(chan transaction.Transaction)
+	requeue := make(chan transaction.Transaction, 1)
+	w := NewWorker(highPrio, lowPrio, requeue, newBlockedEndpoints(), nil)
+	w.Start()
+	defer w.Stop(false)
+
+	t1 := newTestTransaction()
+	t1.On("Process", w.Client).Return(nil)
+	t1.On("GetTarget").Return("")
+	t2 := newTestTransaction()
+	t2.On("Process", w.Client).Return(nil)
+	t2.On("GetTarget").Return("")
+
+	highPrio <- &coalescedTransaction{transactions: []transaction.Transaction{t1, t2}}
+	<-t1.processed
+	<-t2.processed
+	t1.AssertExpectations(t)
+	t2.AssertExpectations(t)
+}
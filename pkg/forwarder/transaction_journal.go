@@ -0,0 +1,196 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package forwarder
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/config/resolver"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/internal/journal"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// journalModeJournal writes the transactions to the journal instead of sending them.
+	journalModeJournal = "journal"
+	// journalModeJournalAndSend writes the transactions to the journal and sends them.
+	journalModeJournalAndSend = "journal_and_send"
+)
+
+// transactionJournal writes the payloads submitted to the forwarder to a rotating
+// on-disk journal so they can be replayed later, possibly from another host,
+// with `agent forwarder replay`.
+type transactionJournal struct {
+	writer *journal.Writer
+	send   bool
+}
+
+// newTransactionJournalFromConfig returns nil when the journal is disabled.
+func newTransactionJournalFromConfig() (*transactionJournal, error) {
+	mode := config.Datadog.GetString("forwarder_journal.mode")
+	if mode == "" {
+		return nil, nil
+	}
+	if mode != journalModeJournal && mode != journalModeJournalAndSend {
+		return nil, fmt.Errorf("invalid value for 'forwarder_journal.mode': '%s', valid values are '%s' and '%s'", mode, journalModeJournal, journalModeJournalAndSend)
+	}
+
+	journalPath := config.Datadog.GetString("forwarder_journal.path")
+	if journalPath == "" {
+		journalPath = path.Join(config.Datadog.GetString("run_path"), "forwarder_journal")
+	}
+	writer, err := journal.NewWriter(
+		journalPath,
+		config.Datadog.GetInt64("forwarder_journal.max_file_size"),
+		config.Datadog.GetInt64("forwarder_journal.max_size"))
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Forwarder journal is enabled in '%s' mode, writing to %s", mode, journalPath)
+	return &transactionJournal{
+		writer: writer,
+		send:   mode == journalModeJournalAndSend,
+	}, nil
+}
+
+// add writes the payloads of `transactions` to the journal, one record per payload, and returns
+// the transactions which are not written. They are sent even in "journal" mode.
+// A payload is sent to every domain with every API key but it is written only once.
+// The transactions which cannot be stored on disk, because their payload contains
+// the API key, are not written, nor are the transactions whose payload cannot be written,
+// for instance because it is larger than the maximum size of a journal file or the disk is full.
+// The journalable transactions of the domains with their own compression are neither written nor
+// returned: their payloads are the payloads of the other domains compressed differently, and the
+// journal is replayed to every domain.
+func (j *transactionJournal) add(transactions []*transaction.HTTPTransaction, hasOwnCompression func(domain string) bool) []*transaction.HTTPTransaction {
+	written := make(map[*[]byte]bool)
+	var notJournaled []*transaction.HTTPTransaction
+	for _, t := range transactions {
+		if !isJournalable(t) {
+			notJournaled = append(notJournaled, t)
+			continue
+		}
+		if hasOwnCompression(t.Domain) {
+			continue
+		}
+		ok, found := written[t.Payload]
+		if !found {
+			ok = j.write(t)
+			written[t.Payload] = ok
+		}
+		if !ok {
+			notJournaled = append(notJournaled, t)
+		}
+	}
+	return notJournaled
+}
+
+// write writes the payload of `t` to the journal and returns whether it succeeded.
+func (j *transactionJournal) write(t *transaction.HTTPTransaction) bool {
+	record, err := journal.MarshalEntries([]journal.Entry{newJournalEntry(t)})
+	if err == nil {
+		err = j.writer.Write(record)
+	}
+	if err != nil {
+		log.Errorf("Cannot write a payload for the endpoint %s to the forwarder journal, it is sent instead: %v", t.Endpoint.Name, err)
+		return false
+	}
+	return true
+}
+
+func isJournalable(t *transaction.HTTPTransaction) bool {
+	return t.Payload != nil && t.StorableOnDisk
+}
+
+func (j *transactionJournal) close() {
+	if err := j.writer.Close(); err != nil {
+		log.Errorf("Cannot close the forwarder journal: %v", err)
+	}
+}
+
+func newJournalEntry(t *transaction.HTTPTransaction) journal.Entry {
+	endpoint := t.Endpoint
+	apiKeyInQueryString := false
+	if i := strings.Index(endpoint.Route, "?api_key="); i >= 0 {
+		endpoint.Route = endpoint.Route[:i]
+		apiKeyInQueryString = true
+	}
+	headers := t.Headers.Clone()
+	headers.Del(apiHTTPHeaderKey)
+
+	return journal.Entry{
+		Endpoint:            endpoint,
+		APIKeyInQueryString: apiKeyInQueryString,
+		Headers:             headers,
+		Payload:             *t.Payload,
+		CreatedAt:           t.CreatedAt,
+		Priority:            t.Priority,
+		Retryable:           t.Retryable,
+	}
+}
+
+// JournalReplayStats contains the result of ReplayJournal.
+type JournalReplayStats struct {
+	Files        int
+	Payloads     int
+	Transactions int
+	Errors       int
+}
+
+// ReplayJournal synchronously sends the payloads of the journal stored in `journalPath`, from
+// the oldest to the newest, to the domains and with the API keys of `domainResolvers`.
+// The payloads are sent as they were submitted and so keep their original timestamps.
+func ReplayJournal(journalPath string, domainResolvers map[string]resolver.DomainResolver, timeout time.Duration) (JournalReplayStats, error) {
+	var stats JournalReplayStats
+	filenames, err := journal.ListFiles(journalPath)
+	if err != nil {
+		return stats, err
+	}
+
+	f := NewSyncForwarder(domainResolvers, timeout)
+	for _, filename := range filenames {
+		err := journal.ReadFile(filename, func(record []byte) error {
+			entries, err := journal.UnmarshalEntries(record)
+			if err != nil {
+				return fmt.Errorf("cannot read a record of %v: %v", filename, err)
+			}
+			for _, e := range entries {
+				stats.Payloads++
+				transactions := f.defaultForwarder.createJournalTransactions(e)
+				for _, t := range transactions {
+					stats.Transactions++
+					if err := t.Process(context.Background(), f.client); err != nil {
+						log.Warnf("Cannot replay a payload for the endpoint %s: %v", e.Endpoint.Name, err)
+						stats.Errors++
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return stats, err
+		}
+		stats.Files++
+	}
+	return stats, nil
+}
+
+func (f *DefaultForwarder) createJournalTransactions(e journal.Entry) []*transaction.HTTPTransaction {
+	payload := e.Payload
+	transactions := f.createAdvancedHTTPTransactions(e.Endpoint, Payloads{&payload}, e.APIKeyInQueryString, e.Headers, e.Priority, true)
+	for _, t := range transactions {
+		t.CreatedAt = e.CreatedAt
+		t.Retryable = e.Retryable
+	}
+	return transactions
+}
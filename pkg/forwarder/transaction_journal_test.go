@@ -0,0 +1,162 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+// +build test
+
+package forwarder
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/config/resolver"
+)
+
+func TestTransactionJournalReplay(t *testing.T) {
+	r := require.New(t)
+	journalPath, err := ioutil.TempDir("", "journal")
+	r.NoError(err)
+	defer os.RemoveAll(journalPath)
+
+	mockConfig := config.Mock()
+	mockConfig.Set("forwarder_journal.mode", journalModeJournal)
+	mockConfig.Set("forwarder_journal.path", journalPath)
+	defer mockConfig.Set("forwarder_journal.mode", "")
+	defer mockConfig.Set("forwarder_journal.path", "")
+
+	var m sync.Mutex
+	var sent []string
+	live := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		m.Lock()
+		defer m.Unlock()
+		sent = append(sent, req.URL.Path+" "+string(body))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer live.Close()
+
+	options := NewOptionsWithResolvers(resolver.NewSingleDomainResolvers(map[string][]string{
+		live.URL: {"live_api_key"},
+	}))
+	options.DisableAPIKeyChecking = true
+	options.EnabledFeatures = SetFeature(options.EnabledFeatures, CoreFeatures)
+	f := NewDefaultForwarder(options)
+	r.NotNil(f.journal)
+	r.NoError(f.Start())
+
+	series := []byte("series")
+	checkRuns := []byte("check runs")
+	hostMetadata := []byte("host metadata")
+	r.NoError(f.SubmitSeries(Payloads{&series}, http.Header{"Key": []string{"value"}}))
+	r.NoError(f.SubmitV1CheckRuns(Payloads{&checkRuns}, nil))
+	// Host metadata contains the API key: it is not written to the journal but it is sent.
+	r.NoError(f.SubmitHostMetadata(Payloads{&hostMetadata}, nil))
+	r.Eventually(func() bool {
+		m.Lock()
+		defer m.Unlock()
+		return len(sent) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	f.Stop()
+	m.Lock()
+	r.Equal([]string{"/intake/ host metadata"}, sent)
+	m.Unlock()
+
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		m.Lock()
+		defer m.Unlock()
+		requests = append(requests, req.URL.String()+" "+req.Header.Get("DD-Api-Key")+" "+req.Header.Get("Key")+" "+string(body))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	stats, err := ReplayJournal(journalPath, resolver.NewSingleDomainResolvers(map[string][]string{
+		ts.URL: {"api_key"},
+	}), 5*time.Second)
+	r.NoError(err)
+	r.Equal(JournalReplayStats{Files: 1, Payloads: 2, Transactions: 2}, stats)
+
+	sort.Strings(requests)
+	r.Equal([]string{
+		"/api/v1/check_run?api_key=api_key api_key  check runs",
+		"/api/v2/series api_key value series",
+	}, requests)
+}
+
+func TestTransactionJournalWriteFailure(t *testing.T) {
+	r := require.New(t)
+	journalPath, err := ioutil.TempDir("", "journal")
+	r.NoError(err)
+	defer os.RemoveAll(journalPath)
+
+	mockConfig := config.Mock()
+	mockConfig.Set("forwarder_journal.mode", journalModeJournal)
+	mockConfig.Set("forwarder_journal.path", journalPath)
+	mockConfig.Set("forwarder_journal.max_file_size", 1000)
+	defer mockConfig.Set("forwarder_journal.mode", "")
+	defer mockConfig.Set("forwarder_journal.path", "")
+	defer mockConfig.Set("forwarder_journal.max_file_size", 10*1024*1024)
+
+	var m sync.Mutex
+	var sent []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		m.Lock()
+		defer m.Unlock()
+		sent = append(sent, req.URL.Path+" "+string(body))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	options := NewOptionsWithResolvers(resolver.NewSingleDomainResolvers(map[string][]string{
+		ts.URL: {"api_key1", "api_key2"},
+	}))
+	options.DisableAPIKeyChecking = true
+	options.EnabledFeatures = SetFeature(options.EnabledFeatures, CoreFeatures)
+	f := NewDefaultForwarder(options)
+	r.NotNil(f.journal)
+	r.NoError(f.Start())
+	defer f.Stop()
+
+	sentCount := func() int {
+		m.Lock()
+		defer m.Unlock()
+		return len(sent)
+	}
+
+	// Each payload is written to its own record: the small one fits, the large one is sent.
+	small := []byte("small")
+	large := bytes.Repeat([]byte("l"), 1000)
+	r.NoError(f.SubmitSeries(Payloads{&small, &large}, nil))
+	r.Eventually(func() bool { return sentCount() == 2 }, 5*time.Second, 10*time.Millisecond)
+
+	// The journal cannot be written anymore: the payloads are sent.
+	r.NoError(os.RemoveAll(journalPath))
+	f.journal.close()
+	r.NoError(f.SubmitV1CheckRuns(Payloads{&small}, nil))
+	r.Eventually(func() bool { return sentCount() == 4 }, 5*time.Second, 10*time.Millisecond)
+
+	m.Lock()
+	defer m.Unlock()
+	sort.Strings(sent)
+	r.Equal([]string{
+		"/api/v1/check_run small",
+		"/api/v1/check_run small",
+		"/api/v2/series " + string(large),
+		"/api/v2/series " + string(large),
+	}, sent)
+}
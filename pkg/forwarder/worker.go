@@ -36,6 +36,8 @@ type Worker struct {
 	stopChan            chan struct{}
 	stopped             chan struct{}
 	blockedList         *blockedEndpoints
+	bandwidthLimiter    *bandwidthLimiter
+	httpClientFactory   func() *http.Client
 }
 
 // NewWorker returns a new worker to consume Transaction from inputChan
@@ -44,7 +46,8 @@ func NewWorker(
 	highPrioChan <-chan transaction.Transaction,
 	lowPrioChan <-chan transaction.Transaction,
 	requeueChan chan<- transaction.Transaction,
-	blocked *blockedEndpoints) *Worker {
+	blocked *blockedEndpoints,
+	optionalBandwidthLimiter *bandwidthLimiter) *Worker {
 	return &Worker{
 		HighPrio:            highPrioChan,
 		LowPrio:             lowPrioChan,
@@ -53,7 +56,9 @@ func NewWorker(
 		stopChan:            make(chan struct{}),
 		stopped:             make(chan struct{}),
 		Client:              newHTTPClient(),
+		httpClientFactory:   newHTTPClient,
 		blockedList:         blocked,
+		bandwidthLimiter:    optionalBandwidthLimiter,
 	}
 }
 
@@ -164,6 +169,15 @@ func (w *Worker) callProcess(t transaction.Transaction) error {
 }
 
 func (w *Worker) process(ctx context.Context, t transaction.Transaction) {
+	// The coalesced transactions are sent one after the other on the connection of the worker,
+	// each of them being retried on its own.
+	if group, ok := t.(*coalescedTransaction); ok {
+		for _, tr := range group.transactions {
+			w.process(ctx, tr)
+		}
+		return
+	}
+
 	requeue := func() {
 		select {
 		case w.RequeueChan <- t:
@@ -177,6 +191,8 @@ func (w *Worker) process(ctx context.Context, t transaction.Transaction) {
 	if w.blockedList.isBlock(target) {
 		requeue()
 		log.Errorf("Too many errors for endpoint '%s': retrying later", target)
+	} else if !w.waitForBandwidth(ctx, t) {
+		requeue()
 	} else if err := t.Process(ctx, w.Client); err != nil {
 		w.blockedList.close(target)
 		requeue()
@@ -186,11 +202,53 @@ func (w *Worker) process(ctx context.Context, t transaction.Transaction) {
 	}
 }
 
+// waitForBandwidth returns true when the transaction can be sent without exceeding the bandwidth limits.
+// Transactions with a normal priority are not sent if the limit is reached and are deferred
+// to the retry queue, transactions with a high priority wait until they can be sent.
+func (w *Worker) waitForBandwidth(ctx context.Context, t transaction.Transaction) bool {
+	if w.bandwidthLimiter == nil {
+		return true
+	}
+
+	endpointName := t.GetEndpointName()
+	if t.GetPriority() == transaction.TransactionPriorityNormal {
+		if w.bandwidthLimiter.tryReserve(endpointName, t.GetPayloadSize()) {
+			return true
+		}
+		transactionsThrottled.Add(1)
+		tlmTxThrottled.Inc(endpointName)
+		log.Debugf("Bandwidth limit reached for endpoint '%s': retrying later", endpointName)
+		return false
+	}
+
+	delay := w.bandwidthLimiter.reserve(endpointName, t.GetPayloadSize())
+	if delay <= 0 {
+		return true
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		// The payload is not sent: the bytes are given back so they don't throttle other payloads.
+		w.bandwidthLimiter.release(endpointName, t.GetPayloadSize())
+		return false
+	}
+}
+
 // resetConnections resets the connections by replacing the HTTP client used by
 // the worker, in order to create new connections when the next transactions are processed.
 // It must not be called while a transaction is being processed.
 func (w *Worker) resetConnections() {
 	log.Debug("Resetting worker's connections")
 	w.Client.CloseIdleConnections()
-	w.Client = newHTTPClient()
+	w.Client = w.httpClientFactory()
+}
+
+// setHTTPClientFactory replaces the function used to create the HTTP client of the worker.
+// It must be called before the worker is started.
+func (w *Worker) setHTTPClientFactory(factory func() *http.Client) {
+	w.httpClientFactory = factory
+	w.Client = factory()
 }
@@ -24,7 +24,7 @@ func TestNewWorker(t *testing.T) {
 	lowPrio := make(chan transaction.Transaction)
 	requeue := make(chan transaction.Transaction)
 
-	w := NewWorker(highPrio, lowPrio, requeue, newBlockedEndpoints())
+	w := NewWorker(highPrio, lowPrio, requeue, newBlockedEndpoints(), nil)
 	assert.NotNil(t, w)
 	assert.Equal(t, w.Client.Timeout, config.Datadog.GetDuration("forwarder_timeout")*time.Second)
 }
@@ -38,7 +38,7 @@ func TestNewNoSSLWorker(t *testing.T) {
 	mockConfig.Set("skip_ssl_validation", true)
 	defer mockConfig.Set("skip_ssl_validation", false)
 
-	w := NewWorker(highPrio, lowPrio, requeue, newBlockedEndpoints())
+	w := NewWorker(highPrio, lowPrio, requeue, newBlockedEndpoints(), nil)
 	assert.True(t, w.Client.Transport.(*http.Transport).TLSClientConfig.InsecureSkipVerify)
 }
 
@@ -46,7 +46,7 @@ func TestWorkerStart(t *testing.T) {
 	highPrio := make(chan transaction.Transaction)
 	lowPrio := make(chan transaction.Transaction)
 	requeue := make(chan transaction.Transaction, 1)
-	w := NewWorker(highPrio, lowPrio, requeue, newBlockedEndpoints())
+	w := NewWorker(highPrio, lowPrio, requeue, newBlockedEndpoints(), nil)
 
 	mock := newTestTransaction()
 	mock.On("Process", w.Client).Return(nil).Times(1)
@@ -76,7 +76,7 @@ func TestWorkerRetry(t *testing.T) {
 	highPrio := make(chan transaction.Transaction)
 	lowPrio := make(chan transaction.Transaction)
 	requeue := make(chan transaction.Transaction, 1)
-	w := NewWorker(highPrio, lowPrio, requeue, newBlockedEndpoints())
+	w := NewWorker(highPrio, lowPrio, requeue, newBlockedEndpoints(), nil)
 
 	mock := newTestTransaction()
 	mock.On("Process", w.Client).Return(fmt.Errorf("some kind of error")).Times(1)
@@ -97,7 +97,7 @@ func TestWorkerRetryBlockedTransaction(t *testing.T) {
 	highPrio := make(chan transaction.Transaction)
 	lowPrio := make(chan transaction.Transaction)
 	requeue := make(chan transaction.Transaction, 1)
-	w := NewWorker(highPrio, lowPrio, requeue, newBlockedEndpoints())
+	w := NewWorker(highPrio, lowPrio, requeue, newBlockedEndpoints(), nil)
 
 	mock := newTestTransaction()
 	mock.On("GetTarget").Return("error_url").Times(1)
@@ -118,7 +118,7 @@ func TestWorkerResetConnections(t *testing.T) {
 	highPrio := make(chan transaction.Transaction)
 	lowPrio := make(chan transaction.Transaction)
 	requeue := make(chan transaction.Transaction, 1)
-	w := NewWorker(highPrio, lowPrio, requeue, newBlockedEndpoints())
+	w := NewWorker(highPrio, lowPrio, requeue, newBlockedEndpoints(), nil)
 
 	mock := newTestTransaction()
 	mock.On("Process", w.Client).Return(nil).Times(1)
@@ -161,7 +161,7 @@ func TestWorkerPurgeOnStop(t *testing.T) {
 	highPrio := make(chan transaction.Transaction, 1)
 	lowPrio := make(chan transaction.Transaction, 1)
 	requeue := make(chan transaction.Transaction, 1)
-	w := NewWorker(highPrio, lowPrio, requeue, newBlockedEndpoints())
+	w := NewWorker(highPrio, lowPrio, requeue, newBlockedEndpoints(), nil)
 	// making stopChan non blocking on insert and closing stopped channel
 	// to avoid blocking in the Stop method since we don't actually start
 	// the workder
@@ -189,3 +189,33 @@ func TestWorkerPurgeOnStop(t *testing.T) {
 	mockTransaction.AssertNumberOfCalls(t, "Process", 1)
 	mockRetryTransaction.AssertNumberOfCalls(t, "Process", 0)
 }
+
+func TestWorkerBandwidthLimit(t *testing.T) {
+	highPrio := make(chan transaction.Transaction)
+	lowPrio := make(chan transaction.Transaction)
+	requeue := make(chan transaction.Transaction, 1)
+	w := NewWorker(highPrio, lowPrio, requeue, newBlockedEndpoints(), newBandwidthLimiter(100, nil))
+
+	mock := newTestTransaction()
+	mock.On("Process", w.Client).Return(nil).Times(1)
+	mock.On("GetTarget").Return("").Times(1)
+	mock.On("GetPayloadSize").Return(100).Times(1)
+
+	mock2 := newTestTransaction()
+	mock2.On("GetTarget").Return("").Times(1)
+	mock2.On("GetPayloadSize").Return(100).Times(1)
+
+	w.Start()
+	highPrio <- mock
+	<-mock.processed
+
+	// The bandwidth limit is reached, the transaction with a normal priority is deferred.
+	highPrio <- mock2
+	retryTransaction := <-requeue
+	assert.Equal(t, mock2, retryTransaction)
+	w.Stop(false)
+
+	mock.AssertExpectations(t)
+	mock2.AssertExpectations(t)
+	mock2.AssertNumberOfCalls(t, "Process", 0)
+}
@@ -0,0 +1,160 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package netflow
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/epforwarder"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// flowKey identifies the flows aggregated together: the flows of a 5-tuple going through the same interfaces of a device
+type flowKey struct {
+	flowType        FlowType
+	exporterAddr    string
+	srcAddr         string
+	dstAddr         string
+	srcPort         uint16
+	dstPort         uint16
+	ipProtocol      uint8
+	inputInterface  uint32
+	outputInterface uint32
+}
+
+func newFlowKey(f *Flow) flowKey {
+	return flowKey{
+		flowType:        f.FlowType,
+		exporterAddr:    f.ExporterAddr.String(),
+		srcAddr:         f.SrcAddr.String(),
+		dstAddr:         f.DstAddr.String(),
+		srcPort:         f.SrcPort,
+		dstPort:         f.DstPort,
+		ipProtocol:      f.IPProtocol,
+		inputInterface:  f.InputInterface,
+		outputInterface: f.OutputInterface,
+	}
+}
+
+// flowAggregator accumulates the flows received by the listeners and sends them to the event platform every flush interval
+type flowAggregator struct {
+	flowIn        chan *Flow
+	flows         map[flowKey]*Flow
+	maxFlows      int
+	droppedFlows  int
+	flushInterval time.Duration
+	namespace     string
+	sender        aggregator.Sender
+
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+func newFlowAggregator(sender aggregator.Sender, config *Config) *flowAggregator {
+	return &flowAggregator{
+		flowIn:        make(chan *Flow, config.AggregatorBufferSize),
+		flows:         make(map[flowKey]*Flow),
+		maxFlows:      config.AggregatorMaxFlows,
+		flushInterval: time.Duration(config.AggregatorFlushInterval) * time.Second,
+		namespace:     config.Namespace,
+		sender:        sender,
+		stop:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+}
+
+func (agg *flowAggregator) start() {
+	log.Infof("Flow aggregator started, flushing every %s", agg.flushInterval)
+	go agg.run()
+}
+
+// stopAndFlush stops the aggregator, flushing the flows received so far
+func (agg *flowAggregator) stopAndFlush() {
+	close(agg.stop)
+	<-agg.stopped
+}
+
+func (agg *flowAggregator) run() {
+	defer close(agg.stopped)
+
+	flushTicker := time.NewTicker(agg.flushInterval)
+	defer flushTicker.Stop()
+	for {
+		select {
+		case flow := <-agg.flowIn:
+			agg.add(flow)
+		case <-flushTicker.C:
+			agg.flush()
+		case <-agg.stop:
+			// the listeners are stopped first, so the remaining flows can be drained
+			for {
+				select {
+				case flow := <-agg.flowIn:
+					agg.add(flow)
+				default:
+					agg.flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (agg *flowAggregator) add(flow *Flow) {
+	key := newFlowKey(flow)
+	aggFlow, ok := agg.flows[key]
+	if !ok {
+		// the new flows are dropped until the next flush once the limit is reached,
+		// the flows already aggregated keep being updated
+		if len(agg.flows) >= agg.maxFlows {
+			agg.droppedFlows++
+			netflowFlowsDropped.Add(1)
+			return
+		}
+		agg.flows[key] = flow
+		return
+	}
+
+	aggFlow.Bytes += flow.Bytes
+	aggFlow.Packets += flow.Packets
+	aggFlow.TCPFlags |= flow.TCPFlags
+	if flow.SamplingRate > aggFlow.SamplingRate {
+		aggFlow.SamplingRate = flow.SamplingRate
+	}
+	if flow.StartTimestamp < aggFlow.StartTimestamp {
+		aggFlow.StartTimestamp = flow.StartTimestamp
+	}
+	if flow.EndTimestamp > aggFlow.EndTimestamp {
+		aggFlow.EndTimestamp = flow.EndTimestamp
+	}
+}
+
+func (agg *flowAggregator) flush() {
+	if agg.droppedFlows > 0 {
+		log.Warnf("Dropped %d flows, the aggregator already contained %d flows: increase network_devices.netflow.aggregator_max_flows to aggregate them", agg.droppedFlows, agg.maxFlows)
+		agg.droppedFlows = 0
+	}
+	if len(agg.flows) == 0 {
+		return
+	}
+
+	flushed := 0
+	for _, flow := range agg.flows {
+		payloadBytes, err := json.Marshal(buildPayload(flow, agg.namespace))
+		if err != nil {
+			log.Errorf("Error marshalling flow payload: %s", err)
+			continue
+		}
+		agg.sender.EventPlatformEvent(string(payloadBytes), epforwarder.EventTypeNetworkDevicesNetFlow)
+		flushed++
+	}
+	log.Debugf("Flushed %d aggregated flows", flushed)
+	netflowFlowsFlushed.Add(int64(flushed))
+
+	agg.flows = make(map[flowKey]*Flow)
+}
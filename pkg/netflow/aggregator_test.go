@@ -0,0 +1,147 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package netflow
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+	"github.com/DataDog/datadog-agent/pkg/collector/corechecks/snmp/devicestore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func testFlow(bytes uint64, start, end uint64) *Flow {
+	return &Flow{
+		FlowType:        TypeNetFlow9,
+		ExporterAddr:    testExporter,
+		SrcAddr:         net.IP{10, 0, 0, 1},
+		DstAddr:         net.IP{10, 0, 0, 2},
+		SrcPort:         40000,
+		DstPort:         443,
+		IPProtocol:      6,
+		TCPFlags:        0x02,
+		InputInterface:  1,
+		OutputInterface: 2,
+		Bytes:           bytes,
+		Packets:         1,
+		StartTimestamp:  start,
+		EndTimestamp:    end,
+	}
+}
+
+func TestAggregator(t *testing.T) {
+	sender := mocksender.NewMockSender("netflow")
+	sender.On("EventPlatformEvent", mock.Anything, mock.Anything).Return()
+
+	devicestore.SetDevice("agg-test", devicestore.DeviceMetadata{ID: "agg-test:192.0.2.1", IPAddress: "192.0.2.1", Name: "router"},
+		[]devicestore.InterfaceMetadata{{Index: 1, Name: "eth1", Alias: "uplink"}})
+
+	agg := newFlowAggregator(sender, &Config{AggregatorFlushInterval: 3600, AggregatorBufferSize: 10, AggregatorMaxFlows: 10, Namespace: "agg-test"})
+	agg.start()
+
+	agg.flowIn <- testFlow(100, 1000, 1010)
+	second := testFlow(50, 990, 1005)
+	second.TCPFlags = 0x10
+	agg.flowIn <- second
+	other := testFlow(10, 1000, 1000)
+	other.DstPort = 80
+	agg.flowIn <- other
+	agg.stopAndFlush()
+
+	sender.AssertNumberOfCalls(t, "EventPlatformEvent", 2)
+	var payloads []FlowPayload
+	for _, call := range sender.Calls {
+		if call.Method != "EventPlatformEvent" {
+			continue
+		}
+		assert.Equal(t, "network-devices-netflow", call.Arguments.String(1))
+		var payload FlowPayload
+		require.NoError(t, json.Unmarshal([]byte(call.Arguments.String(0)), &payload))
+		payloads = append(payloads, payload)
+	}
+	if payloads[0].Destination.Port != 443 {
+		payloads[0], payloads[1] = payloads[1], payloads[0]
+	}
+
+	assert.Equal(t, FlowPayload{
+		FlowType:    "netflow9",
+		Start:       990,
+		End:         1010,
+		Bytes:       150,
+		Packets:     2,
+		IPProtocol:  6,
+		TCPFlags:    0x12,
+		Exporter:    ExporterPayload{IP: "192.0.2.1", Namespace: "agg-test", DeviceID: "agg-test:192.0.2.1", Name: "router"},
+		Source:      EndpointPayload{IP: "10.0.0.1", Port: 40000},
+		Destination: EndpointPayload{IP: "10.0.0.2", Port: 443},
+		Ingress:     InterfacePayload{Index: 1, Name: "eth1", Alias: "uplink"},
+		Egress:      InterfacePayload{Index: 2},
+	}, payloads[0])
+	assert.Equal(t, uint64(10), payloads[1].Bytes)
+}
+
+func TestAggregatorMaxFlows(t *testing.T) {
+	sender := mocksender.NewMockSender("netflow-max-flows")
+	sender.On("EventPlatformEvent", mock.Anything, mock.Anything).Return()
+
+	agg := newFlowAggregator(sender, &Config{AggregatorFlushInterval: 3600, AggregatorBufferSize: 10, AggregatorMaxFlows: 2, Namespace: "max-flows"})
+	dropped := netflowFlowsDropped.Value()
+
+	for port := uint16(1); port <= 4; port++ {
+		flow := testFlow(10, 1000, 1000)
+		flow.DstPort = port
+		agg.add(flow)
+	}
+	// the flows already aggregated are still updated
+	first := testFlow(5, 1000, 1000)
+	first.DstPort = 1
+	agg.add(first)
+
+	assert.Len(t, agg.flows, 2)
+	assert.Equal(t, uint64(15), agg.flows[newFlowKey(first)].Bytes)
+	assert.Equal(t, int64(2), netflowFlowsDropped.Value()-dropped)
+
+	agg.flush()
+	sender.AssertNumberOfCalls(t, "EventPlatformEvent", 2)
+
+	// the new flows are accepted again after the flush
+	agg.add(testFlow(10, 1000, 1000))
+	assert.Len(t, agg.flows, 1)
+	assert.Equal(t, int64(2), netflowFlowsDropped.Value()-dropped)
+}
+
+func TestBuildPayloadWithoutDevice(t *testing.T) {
+	payload := buildPayload(testFlow(100, 1000, 1010), "unknown-ns")
+	assert.Equal(t, ExporterPayload{IP: "192.0.2.1", Namespace: "unknown-ns"}, payload.Exporter)
+	assert.Equal(t, InterfacePayload{Index: 1}, payload.Ingress)
+}
+
+func TestFlowListener(t *testing.T) {
+	flows := make(chan *Flow, 10)
+	listener, err := startFlowListener(ListenerConfig{FlowType: TypeNetFlow5, BindHost: "127.0.0.1"}, flows)
+	require.NoError(t, err)
+	defer listener.stop()
+
+	conn, err := net.Dial("udp", listener.conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write(buildNetFlow5Packet())
+	require.NoError(t, err)
+
+	select {
+	case flow := <-flows:
+		assert.Equal(t, TypeNetFlow5, flow.FlowType)
+		assert.True(t, flow.ExporterAddr.Equal(net.IP{127, 0, 0, 1}))
+		assert.Equal(t, uint64(1500), flow.Bytes)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no flow received")
+	}
+}
@@ -0,0 +1,91 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package netflow
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/collector/corechecks/snmp/common"
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+// IsEnabled returns whether the flow collector is enabled in the Agent configuration.
+func IsEnabled() bool {
+	return config.Datadog.GetBool("network_devices.netflow.enabled")
+}
+
+// ListenerConfig contains the configuration of a flow listener.
+// YAML field tags provided for test marshalling purposes.
+type ListenerConfig struct {
+	FlowType FlowType `mapstructure:"flow_type" yaml:"flow_type"`
+	Port     uint16   `mapstructure:"port" yaml:"port"`
+	BindHost string   `mapstructure:"bind_host" yaml:"bind_host"`
+}
+
+// Addr returns the host:port address to listen on.
+func (c *ListenerConfig) Addr() string {
+	return fmt.Sprintf("%s:%d", c.BindHost, c.Port)
+}
+
+// Config contains the configuration of the flow collector.
+// YAML field tags provided for test marshalling purposes.
+type Config struct {
+	Listeners               []ListenerConfig `mapstructure:"listeners" yaml:"listeners"`
+	StopTimeout             int              `mapstructure:"stop_timeout" yaml:"stop_timeout"`
+	AggregatorFlushInterval int              `mapstructure:"aggregator_flush_interval" yaml:"aggregator_flush_interval"`
+	AggregatorBufferSize    int              `mapstructure:"aggregator_buffer_size" yaml:"aggregator_buffer_size"`
+	AggregatorMaxFlows      int              `mapstructure:"aggregator_max_flows" yaml:"aggregator_max_flows"`
+	Namespace               string           `mapstructure:"-" yaml:"-"`
+}
+
+// ReadConfig builds and returns configuration from Agent configuration.
+func ReadConfig() (*Config, error) {
+	var c Config
+	err := config.Datadog.UnmarshalKey("network_devices.netflow", &c)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(c.Listeners) == 0 {
+		return nil, fmt.Errorf("no listener configured in network_devices.netflow.listeners")
+	}
+	for i := range c.Listeners {
+		l := &c.Listeners[i]
+		l.FlowType = FlowType(strings.ToLower(string(l.FlowType)))
+		if !l.FlowType.isValid() {
+			return nil, fmt.Errorf("invalid flow_type %q, expected one of %s", l.FlowType, strings.Join(flowTypeNames(), ", "))
+		}
+		if l.Port == 0 {
+			l.Port = l.FlowType.defaultPort()
+		}
+		if l.BindHost == "" {
+			// Default to global bind_host option.
+			l.BindHost = config.GetBindHost()
+		}
+	}
+
+	// Set defaults.
+	if c.StopTimeout == 0 {
+		c.StopTimeout = defaultStopTimeout
+	}
+	if c.AggregatorFlushInterval == 0 {
+		c.AggregatorFlushInterval = defaultAggregatorFlushInterval
+	}
+	if c.AggregatorBufferSize == 0 {
+		c.AggregatorBufferSize = defaultAggregatorBufferSize
+	}
+	if c.AggregatorMaxFlows == 0 {
+		c.AggregatorMaxFlows = defaultAggregatorMaxFlows
+	}
+
+	c.Namespace, err = common.NormalizeNamespace(config.Datadog.GetString("network_devices.namespace"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid network_devices.namespace: %w", err)
+	}
+
+	return &c, nil
+}
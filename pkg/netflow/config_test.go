@@ -0,0 +1,94 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package netflow
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// configure sets the Datadog Agent configuration from a YAML string
+func configure(t *testing.T, yamlConfig string) {
+	config.Datadog.SetConfigType("yaml")
+	err := config.Datadog.ReadConfig(strings.NewReader(yamlConfig))
+	require.NoError(t, err)
+}
+
+func TestReadConfig(t *testing.T) {
+	configure(t, `
+network_devices:
+  namespace: my-ns
+  netflow:
+    enabled: true
+    stop_timeout: 10
+    aggregator_flush_interval: 60
+    aggregator_buffer_size: 100
+    aggregator_max_flows: 1000
+    listeners:
+      - flow_type: NetFlow9
+        port: 1234
+        bind_host: 127.0.0.1
+      - flow_type: sflow5
+`)
+
+	assert.True(t, IsEnabled())
+	cfg, err := ReadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, &Config{
+		Listeners: []ListenerConfig{
+			{FlowType: TypeNetFlow9, Port: 1234, BindHost: "127.0.0.1"},
+			{FlowType: TypeSFlow5, Port: 6343, BindHost: "localhost"},
+		},
+		StopTimeout:             10,
+		AggregatorFlushInterval: 60,
+		AggregatorBufferSize:    100,
+		AggregatorMaxFlows:      1000,
+		Namespace:               "my-ns",
+	}, cfg)
+	assert.Equal(t, "127.0.0.1:1234", cfg.Listeners[0].Addr())
+}
+
+func TestReadConfigDefaults(t *testing.T) {
+	configure(t, `
+network_devices:
+  netflow:
+    listeners:
+      - flow_type: ipfix
+`)
+
+	assert.False(t, IsEnabled())
+	cfg, err := ReadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, uint16(4739), cfg.Listeners[0].Port)
+	assert.Equal(t, defaultStopTimeout, cfg.StopTimeout)
+	assert.Equal(t, defaultAggregatorFlushInterval, cfg.AggregatorFlushInterval)
+	assert.Equal(t, defaultAggregatorBufferSize, cfg.AggregatorBufferSize)
+	assert.Equal(t, defaultAggregatorMaxFlows, cfg.AggregatorMaxFlows)
+	assert.Equal(t, defaultNamespace, cfg.Namespace)
+}
+
+func TestReadConfigErrors(t *testing.T) {
+	configure(t, `
+network_devices:
+  netflow:
+    enabled: true
+`)
+	_, err := ReadConfig()
+	assert.EqualError(t, err, "no listener configured in network_devices.netflow.listeners")
+
+	configure(t, `
+network_devices:
+  netflow:
+    listeners:
+      - flow_type: netflow7
+`)
+	_, err = ReadConfig()
+	assert.EqualError(t, err, `invalid flow_type "netflow7", expected one of netflow5, netflow9, ipfix, sflow5`)
+}
@@ -0,0 +1,22 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package netflow
+
+const (
+	defaultStopTimeout             = 5
+	defaultAggregatorFlushInterval = 300
+	defaultAggregatorBufferSize    = 10000
+	defaultAggregatorMaxFlows      = 100000
+	defaultNamespace               = "default"
+
+	// Standard UDP ports of the flow protocols
+	defaultNetFlowPort = uint16(2055)
+	defaultIPFIXPort   = uint16(4739)
+	defaultSFlowPort   = uint16(6343)
+
+	// maxPacketSize is the maximum size of a UDP datagram
+	maxPacketSize = 65535
+)
@@ -0,0 +1,62 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package netflow
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+var errPacketTooShort = errors.New("packet too short")
+
+// decoder decodes the packets received by a listener into flows.
+// It keeps the templates sent by the NetFlow v9 and IPFIX exporters, and is not safe for concurrent use.
+type decoder struct {
+	flowType  FlowType
+	templates map[templateKey]*template
+}
+
+func newDecoder(flowType FlowType) *decoder {
+	return &decoder{
+		flowType:  flowType,
+		templates: make(map[templateKey]*template),
+	}
+}
+
+// decode returns the flows of a packet. The flows decoded before an error in the packet are returned with the error.
+func (d *decoder) decode(packet []byte, exporter net.IP, now time.Time) ([]*Flow, error) {
+	switch d.flowType {
+	case TypeNetFlow5:
+		return decodeNetFlow5(packet, exporter)
+	case TypeNetFlow9:
+		return d.decodeNetFlow9(packet, exporter)
+	case TypeIPFIX:
+		return d.decodeIPFIX(packet, exporter)
+	case TypeSFlow5:
+		return decodeSFlow5(packet, exporter, now)
+	default:
+		return nil, fmt.Errorf("unsupported flow type %q", d.flowType)
+	}
+}
+
+// readUint reads a big-endian unsigned integer of up to 8 bytes, since NetFlow v9 and IPFIX
+// allow the exporters to shorten the integer fields
+func readUint(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+// copyIP returns a copy of an address read from a packet, so that the packet buffer can be reused
+func copyIP(b []byte) net.IP {
+	ip := make(net.IP, len(b))
+	copy(ip, b)
+	return ip
+}
@@ -0,0 +1,311 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package netflow
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testExporter = net.ParseIP("192.0.2.1").To4()
+
+// buildNetFlow5Packet returns a NetFlow v5 packet exported at 1000.5s, by a device started at 900s,
+// with a record of a flow from 600ms to 1100ms of uptime
+func buildNetFlow5Packet() []byte {
+	packet := make([]byte, netflow5HeaderSize+netflow5RecordSize)
+	binary.BigEndian.PutUint16(packet, 5)
+	binary.BigEndian.PutUint16(packet[2:], 1)
+	binary.BigEndian.PutUint32(packet[4:], 100500)
+	binary.BigEndian.PutUint32(packet[8:], 1000)
+	binary.BigEndian.PutUint32(packet[12:], 500000000)
+	binary.BigEndian.PutUint16(packet[22:], 1<<14|64)
+
+	r := packet[netflow5HeaderSize:]
+	copy(r[0:], []byte{10, 0, 0, 1})
+	copy(r[4:], []byte{10, 0, 0, 2})
+	binary.BigEndian.PutUint16(r[12:], 1)
+	binary.BigEndian.PutUint16(r[14:], 2)
+	binary.BigEndian.PutUint32(r[16:], 10)
+	binary.BigEndian.PutUint32(r[20:], 1500)
+	binary.BigEndian.PutUint32(r[24:], 600)
+	binary.BigEndian.PutUint32(r[28:], 2100)
+	binary.BigEndian.PutUint16(r[32:], 40000)
+	binary.BigEndian.PutUint16(r[34:], 443)
+	r[37] = 0x12
+	r[38] = 6
+	return packet
+}
+
+func TestDecodeNetFlow5(t *testing.T) {
+	flows, err := newDecoder(TypeNetFlow5).decode(buildNetFlow5Packet(), testExporter, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, []*Flow{{
+		FlowType:        TypeNetFlow5,
+		ExporterAddr:    testExporter,
+		SrcAddr:         net.IP{10, 0, 0, 1},
+		DstAddr:         net.IP{10, 0, 0, 2},
+		SrcPort:         40000,
+		DstPort:         443,
+		IPProtocol:      6,
+		TCPFlags:        0x12,
+		InputInterface:  1,
+		OutputInterface: 2,
+		Bytes:           1500,
+		Packets:         10,
+		SamplingRate:    64,
+		StartTimestamp:  900,
+		EndTimestamp:    902,
+	}}, flows)
+
+	_, err = newDecoder(TypeNetFlow5).decode(buildNetFlow5Packet()[:60], testExporter, time.Now())
+	assert.Equal(t, errPacketTooShort, err)
+}
+
+type testField struct {
+	id         uint16
+	length     uint16
+	enterprise bool
+	value      []byte
+}
+
+func appendSet(b []byte, setID uint16, body []byte) []byte {
+	b = append(b, 0, 0, 0, 0)
+	binary.BigEndian.PutUint16(b[len(b)-4:], setID)
+	binary.BigEndian.PutUint16(b[len(b)-2:], uint16(setHeaderSize+len(body)))
+	return append(b, body...)
+}
+
+func templateSet(templateID uint16, fields []testField) []byte {
+	body := make([]byte, 4, 4+8*len(fields))
+	binary.BigEndian.PutUint16(body, templateID)
+	binary.BigEndian.PutUint16(body[2:], uint16(len(fields)))
+	for _, f := range fields {
+		id := f.id
+		if f.enterprise {
+			id |= enterpriseBit
+		}
+		body = append(body, byte(id>>8), byte(id), byte(f.length>>8), byte(f.length))
+		if f.enterprise {
+			body = append(body, 0, 0, 0xc3, 0x50)
+		}
+	}
+	return body
+}
+
+func dataRecord(fields []testField) []byte {
+	var record []byte
+	for _, f := range fields {
+		if f.length == variableLength {
+			record = append(record, byte(len(f.value)))
+		}
+		record = append(record, f.value...)
+	}
+	return record
+}
+
+func uint32Bytes(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return b
+}
+
+var netflow9Fields = []testField{
+	{id: fieldIPv4SrcAddr, length: 4, value: []byte{10, 0, 0, 1}},
+	{id: fieldIPv4DstAddr, length: 4, value: []byte{10, 0, 0, 2}},
+	{id: fieldL4SrcPort, length: 2, value: []byte{0x9c, 0x40}},
+	{id: fieldL4DstPort, length: 2, value: []byte{0, 53}},
+	{id: fieldProtocol, length: 1, value: []byte{17}},
+	{id: fieldInputSNMP, length: 2, value: []byte{0, 3}},
+	{id: fieldOutputSNMP, length: 2, value: []byte{0, 4}},
+	// counters can be shortened by the exporters
+	{id: fieldInBytes, length: 4, value: uint32Bytes(200)},
+	{id: fieldInPkts, length: 2, value: []byte{0, 2}},
+	{id: fieldFirstSwitched, length: 4, value: uint32Bytes(95000)},
+	{id: fieldLastSwitched, length: 4, value: uint32Bytes(99000)},
+	{id: 999, length: 3, value: []byte{1, 2, 3}},
+}
+
+func netflow9Packet(sets ...[]byte) []byte {
+	packet := make([]byte, netflow9HeaderSize)
+	binary.BigEndian.PutUint16(packet, 9)
+	binary.BigEndian.PutUint32(packet[4:], 100000)
+	binary.BigEndian.PutUint32(packet[8:], 1000)
+	binary.BigEndian.PutUint32(packet[16:], 7)
+	for _, set := range sets {
+		packet = append(packet, set...)
+	}
+	return packet
+}
+
+func TestDecodeNetFlow9(t *testing.T) {
+	d := newDecoder(TypeNetFlow9)
+	record := dataRecord(netflow9Fields)
+	// two records padded to 32 bits
+	data := appendSet(nil, 256, append(append(append([]byte{}, record...), record...), 0, 0))
+
+	// the data received before its template is dropped
+	flows, err := d.decode(netflow9Packet(data), testExporter, time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, flows)
+
+	templates := appendSet(nil, netflow9TemplateSetID, templateSet(256, netflow9Fields))
+	flows, err = d.decode(netflow9Packet(templates, data), testExporter, time.Now())
+	require.NoError(t, err)
+	require.Len(t, flows, 2)
+	assert.Equal(t, &Flow{
+		FlowType:        TypeNetFlow9,
+		ExporterAddr:    testExporter,
+		SrcAddr:         net.IP{10, 0, 0, 1},
+		DstAddr:         net.IP{10, 0, 0, 2},
+		SrcPort:         40000,
+		DstPort:         53,
+		IPProtocol:      17,
+		InputInterface:  3,
+		OutputInterface: 4,
+		Bytes:           200,
+		Packets:         2,
+		StartTimestamp:  995,
+		EndTimestamp:    999,
+	}, flows[0])
+
+	// the templates are kept per exporter
+	flows, err = d.decode(netflow9Packet(data), net.IP{192, 0, 2, 2}, time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, flows)
+
+	_, err = d.decode(netflow9Packet(data[:len(data)-6]), testExporter, time.Now())
+	assert.Error(t, err)
+}
+
+func ipfixPacket(sets ...[]byte) []byte {
+	packet := make([]byte, ipfixHeaderSize)
+	binary.BigEndian.PutUint16(packet, 10)
+	binary.BigEndian.PutUint32(packet[4:], 1000)
+	binary.BigEndian.PutUint32(packet[12:], 7)
+	for _, set := range sets {
+		packet = append(packet, set...)
+	}
+	binary.BigEndian.PutUint16(packet[2:], uint16(len(packet)))
+	return packet
+}
+
+func TestDecodeIPFIX(t *testing.T) {
+	src, dst := net.ParseIP("2001:db8::1"), net.ParseIP("2001:db8::2")
+	fields := []testField{
+		{id: fieldIPv6SrcAddr, length: 16, value: src},
+		{id: fieldIPv6DstAddr, length: 16, value: dst},
+		{id: fieldProtocol, length: 1, value: []byte{6}},
+		{id: fieldTCPFlags, length: 1, value: []byte{0x1b}},
+		{id: fieldInBytes, length: 8, value: []byte{0, 0, 0, 0, 0, 0, 0x10, 0}},
+		{id: fieldFlowStartMillis, length: 8, value: []byte{0, 0, 0, 0, 0, 0x0e, 0x4e, 0x1c}},
+		// enterprise and variable-length fields are skipped
+		{id: 1, length: 4, enterprise: true, value: []byte{0, 0, 0, 1}},
+		{id: 2, length: variableLength, enterprise: true, value: []byte("my-app")},
+		{id: 82, length: variableLength, value: []byte("eth0")},
+	}
+
+	d := newDecoder(TypeIPFIX)
+	templates := appendSet(nil, ipfixTemplateSetID, templateSet(300, fields))
+	data := appendSet(nil, 300, dataRecord(fields))
+	flows, err := d.decode(ipfixPacket(templates, data), testExporter, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, []*Flow{{
+		FlowType:       TypeIPFIX,
+		ExporterAddr:   testExporter,
+		SrcAddr:        src,
+		DstAddr:        dst,
+		IPProtocol:     6,
+		TCPFlags:       0x1b,
+		Bytes:          4096,
+		StartTimestamp: 937,
+		EndTimestamp:   1000,
+	}}, flows)
+
+	// the templates are withdrawn with an empty template
+	withdrawal := appendSet(nil, ipfixTemplateSetID, templateSet(300, nil))
+	flows, err = d.decode(ipfixPacket(withdrawal, data), testExporter, time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, flows)
+
+	_, err = d.decode(ipfixPacket(data)[:20], testExporter, time.Now())
+	assert.Error(t, err)
+}
+
+func buildSFlowDatagram(t *testing.T) []byte {
+	buf := gopacket.NewSerializeBuffer()
+	ip := &layers.IPv4{Version: 4, TTL: 64, Protocol: layers.IPProtocolTCP, SrcIP: net.IP{10, 0, 0, 1}, DstIP: net.IP{10, 0, 0, 2}}
+	tcp := &layers.TCP{SrcPort: 40000, DstPort: 443, SYN: true, ACK: true}
+	require.NoError(t, tcp.SetNetworkLayerForChecksum(ip))
+	err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true},
+		&layers.Ethernet{SrcMAC: net.HardwareAddr{0, 1, 2, 3, 4, 5}, DstMAC: net.HardwareAddr{0, 1, 2, 3, 4, 6}, EthernetType: layers.EthernetTypeIPv4},
+		ip, tcp)
+	require.NoError(t, err)
+	header := buf.Bytes()
+	for len(header)%4 != 0 {
+		header = append(header, 0)
+	}
+
+	u32 := func(b []byte, values ...uint32) []byte {
+		for _, v := range values {
+			b = append(b, uint32Bytes(v)...)
+		}
+		return b
+	}
+
+	// raw packet header record: protocol, frame length, stripped, header length, header
+	record := u32(nil, uint32(layers.SFlowTypeRawPacketFlow), uint32(16+len(header)), 1, 1514, 4, uint32(len(buf.Bytes())))
+	record = append(record, header...)
+
+	// flow sample: sequence, source ID, sampling rate, sample pool, drops, input, output, records
+	sample := u32(nil, 1, 0, 512, 512, 0, 5, 6, 1)
+	sample = append(sample, record...)
+
+	// datagram: version, agent address, sub-agent ID, sequence, uptime, samples
+	datagram := u32(nil, 5, 1)
+	datagram = append(datagram, testExporter...)
+	datagram = u32(datagram, 0, 1, 1000, 1)
+	datagram = u32(datagram, uint32(layers.SFlowTypeFlowSample), uint32(len(sample)))
+	return append(datagram, sample...)
+}
+
+func TestDecodeSFlow5(t *testing.T) {
+	now := time.Unix(1000, 0)
+	flows, err := newDecoder(TypeSFlow5).decode(buildSFlowDatagram(t), testExporter, now)
+	require.NoError(t, err)
+	assert.Equal(t, []*Flow{{
+		FlowType:        TypeSFlow5,
+		ExporterAddr:    testExporter,
+		SrcAddr:         net.IP{10, 0, 0, 1},
+		DstAddr:         net.IP{10, 0, 0, 2},
+		SrcPort:         40000,
+		DstPort:         443,
+		IPProtocol:      6,
+		TCPFlags:        0x12,
+		InputInterface:  5,
+		OutputInterface: 6,
+		Bytes:           1514,
+		Packets:         1,
+		SamplingRate:    512,
+		StartTimestamp:  1000,
+		EndTimestamp:    1000,
+	}}, flows)
+
+	_, err = newDecoder(TypeSFlow5).decode(buildSFlowDatagram(t)[:40], testExporter, now)
+	assert.Error(t, err)
+}
+
+func TestSFlowIfIndex(t *testing.T) {
+	assert.Equal(t, uint32(5), sflowIfIndex(5))
+	// packets sent to several interfaces
+	assert.Equal(t, uint32(0), sflowIfIndex(0x80000003))
+}
@@ -0,0 +1,79 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package netflow
+
+import (
+	"net"
+)
+
+// FlowType is the protocol a flow was received with
+type FlowType string
+
+// Supported flow types
+const (
+	TypeNetFlow5 FlowType = "netflow5"
+	TypeNetFlow9 FlowType = "netflow9"
+	TypeIPFIX    FlowType = "ipfix"
+	TypeSFlow5   FlowType = "sflow5"
+)
+
+var flowTypes = []FlowType{TypeNetFlow5, TypeNetFlow9, TypeIPFIX, TypeSFlow5}
+
+func flowTypeNames() []string {
+	names := make([]string, 0, len(flowTypes))
+	for _, t := range flowTypes {
+		names = append(names, string(t))
+	}
+	return names
+}
+
+func (t FlowType) isValid() bool {
+	for _, ft := range flowTypes {
+		if t == ft {
+			return true
+		}
+	}
+	return false
+}
+
+func (t FlowType) defaultPort() uint16 {
+	switch t {
+	case TypeIPFIX:
+		return defaultIPFIXPort
+	case TypeSFlow5:
+		return defaultSFlowPort
+	default:
+		return defaultNetFlowPort
+	}
+}
+
+// Flow is a unidirectional flow, as decoded from a NetFlow, IPFIX or sFlow packet
+type Flow struct {
+	FlowType FlowType
+	// ExporterAddr is the address of the device which sent the flow
+	ExporterAddr net.IP
+
+	SrcAddr    net.IP
+	DstAddr    net.IP
+	SrcPort    uint16
+	DstPort    uint16
+	IPProtocol uint8
+	// TCPFlags is the union of the TCP flags of the packets of the flow
+	TCPFlags uint8
+
+	// InputInterface and OutputInterface are the ifIndex of the interfaces of the device
+	InputInterface  uint32
+	OutputInterface uint32
+
+	Bytes   uint64
+	Packets uint64
+	// SamplingRate is the number of packets the flow was sampled from, 0 when unknown
+	SamplingRate uint64
+
+	// StartTimestamp and EndTimestamp are in seconds since the epoch
+	StartTimestamp uint64
+	EndTimestamp   uint64
+}
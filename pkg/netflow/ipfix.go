@@ -0,0 +1,34 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package netflow
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+const ipfixHeaderSize = 16
+
+// decodeIPFIX decodes an IPFIX message, made of sets of templates and of data records.
+// The options templates and their data are ignored.
+func (d *decoder) decodeIPFIX(packet []byte, exporter net.IP) ([]*Flow, error) {
+	if len(packet) < ipfixHeaderSize {
+		return nil, errPacketTooShort
+	}
+	if version := binary.BigEndian.Uint16(packet); version != 10 {
+		return nil, fmt.Errorf("invalid IPFIX version %d", version)
+	}
+
+	length := int(binary.BigEndian.Uint16(packet[2:]))
+	if length < ipfixHeaderSize || length > len(packet) {
+		return nil, fmt.Errorf("invalid IPFIX message length %d", length)
+	}
+	exportTime := uint64(binary.BigEndian.Uint32(packet[4:]))
+	domainID := binary.BigEndian.Uint32(packet[12:])
+
+	return d.decodeSets(packet[ipfixHeaderSize:length], exporter, domainID, recordTimes{exportTime: exportTime * 1000}, true)
+}
@@ -0,0 +1,74 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package netflow
+
+import (
+	"net"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// flowListener receives the packets of one flow type on a UDP port and decodes them
+type flowListener struct {
+	config  ListenerConfig
+	conn    net.PacketConn
+	decoder *decoder
+	flowOut chan<- *Flow
+	stopped chan struct{}
+}
+
+func startFlowListener(c ListenerConfig, flowOut chan<- *Flow) (*flowListener, error) {
+	conn, err := net.ListenPacket("udp", c.Addr())
+	if err != nil {
+		return nil, err
+	}
+
+	l := &flowListener{
+		config:  c,
+		conn:    conn,
+		decoder: newDecoder(c.FlowType),
+		flowOut: flowOut,
+		stopped: make(chan struct{}),
+	}
+	log.Infof("Start listening for %s flows on %s", c.FlowType, c.Addr())
+	go l.run()
+	return l, nil
+}
+
+func (l *flowListener) run() {
+	defer close(l.stopped)
+
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, addr, err := l.conn.ReadFrom(buf)
+		if err != nil {
+			// the connection is closed by stop
+			return
+		}
+		netflowPackets.Add(1)
+
+		var exporter net.IP
+		if udpAddr, ok := addr.(*net.UDPAddr); ok {
+			exporter = udpAddr.IP
+		}
+		flows, err := l.decoder.decode(buf[:n], exporter, time.Now())
+		if err != nil {
+			log.Debugf("Error decoding %s packet from %s on listener %s: %s", l.config.FlowType, addr, l.config.Addr(), err)
+			netflowPacketErrors.Add(1)
+		}
+		netflowFlows.Add(int64(len(flows)))
+		for _, flow := range flows {
+			l.flowOut <- flow
+		}
+	}
+}
+
+func (l *flowListener) stop() {
+	log.Infof("Stop listening on %s", l.config.Addr())
+	l.conn.Close()
+	<-l.stopped
+}
@@ -0,0 +1,65 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package netflow
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+const (
+	netflow5HeaderSize = 24
+	netflow5RecordSize = 48
+)
+
+// decodeNetFlow5 decodes a NetFlow v5 packet, made of a header and fixed-size records
+func decodeNetFlow5(packet []byte, exporter net.IP) ([]*Flow, error) {
+	if len(packet) < netflow5HeaderSize {
+		return nil, errPacketTooShort
+	}
+	if version := binary.BigEndian.Uint16(packet); version != 5 {
+		return nil, fmt.Errorf("invalid NetFlow v5 version %d", version)
+	}
+
+	count := int(binary.BigEndian.Uint16(packet[2:]))
+	sysUptime := uint64(binary.BigEndian.Uint32(packet[4:]))
+	unixSecs := uint64(binary.BigEndian.Uint32(packet[8:]))
+	unixNsecs := uint64(binary.BigEndian.Uint32(packet[12:]))
+	// The 2 most significant bits are the sampling mode
+	samplingRate := uint64(binary.BigEndian.Uint16(packet[22:]) & 0x3fff)
+
+	// The flow times are in milliseconds of device uptime
+	bootTime := unixSecs*1000 + unixNsecs/1000000 - sysUptime
+
+	records := packet[netflow5HeaderSize:]
+	if len(records) < count*netflow5RecordSize {
+		return nil, errPacketTooShort
+	}
+
+	flows := make([]*Flow, 0, count)
+	for i := 0; i < count; i++ {
+		r := records[i*netflow5RecordSize:]
+		flows = append(flows, &Flow{
+			FlowType:        TypeNetFlow5,
+			ExporterAddr:    exporter,
+			SrcAddr:         copyIP(r[0:4]),
+			DstAddr:         copyIP(r[4:8]),
+			InputInterface:  uint32(binary.BigEndian.Uint16(r[12:])),
+			OutputInterface: uint32(binary.BigEndian.Uint16(r[14:])),
+			Packets:         uint64(binary.BigEndian.Uint32(r[16:])),
+			Bytes:           uint64(binary.BigEndian.Uint32(r[20:])),
+			StartTimestamp:  (bootTime + uint64(binary.BigEndian.Uint32(r[24:]))) / 1000,
+			EndTimestamp:    (bootTime + uint64(binary.BigEndian.Uint32(r[28:]))) / 1000,
+			SrcPort:         binary.BigEndian.Uint16(r[32:]),
+			DstPort:         binary.BigEndian.Uint16(r[34:]),
+			TCPFlags:        r[37],
+			IPProtocol:      r[38],
+			SamplingRate:    samplingRate,
+		})
+	}
+	return flows, nil
+}
@@ -0,0 +1,71 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package netflow
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+const netflow9HeaderSize = 20
+
+// decodeNetFlow9 decodes a NetFlow v9 packet, made of sets of templates and of data records.
+// The options templates and their data are ignored.
+func (d *decoder) decodeNetFlow9(packet []byte, exporter net.IP) ([]*Flow, error) {
+	if len(packet) < netflow9HeaderSize {
+		return nil, errPacketTooShort
+	}
+	if version := binary.BigEndian.Uint16(packet); version != 9 {
+		return nil, fmt.Errorf("invalid NetFlow v9 version %d", version)
+	}
+
+	sysUptime := uint64(binary.BigEndian.Uint32(packet[4:]))
+	unixSecs := uint64(binary.BigEndian.Uint32(packet[8:]))
+	sourceID := binary.BigEndian.Uint32(packet[16:])
+	times := recordTimes{
+		exportTime: unixSecs * 1000,
+		bootTime:   unixSecs*1000 - sysUptime,
+	}
+
+	return d.decodeSets(packet[netflow9HeaderSize:], exporter, sourceID, times, false)
+}
+
+// decodeSets decodes the sets of NetFlow v9 and IPFIX packets, which only differ by the ID of their template sets
+func (d *decoder) decodeSets(sets []byte, exporter net.IP, domainID uint32, times recordTimes, ipfix bool) ([]*Flow, error) {
+	templateSetID := uint16(netflow9TemplateSetID)
+	if ipfix {
+		templateSetID = ipfixTemplateSetID
+	}
+
+	var flows []*Flow
+	for len(sets) > 0 {
+		if len(sets) < setHeaderSize {
+			return flows, errPacketTooShort
+		}
+		setID := binary.BigEndian.Uint16(sets)
+		length := int(binary.BigEndian.Uint16(sets[2:]))
+		if length < setHeaderSize || length > len(sets) {
+			return flows, fmt.Errorf("invalid set length %d", length)
+		}
+		body := sets[setHeaderSize:length]
+		sets = sets[length:]
+
+		switch {
+		case setID == templateSetID:
+			if err := d.decodeTemplates(body, exporter, domainID, ipfix); err != nil {
+				return flows, err
+			}
+		case setID >= minDataSetID:
+			setFlows, err := d.decodeDataSet(body, exporter, domainID, setID, times, ipfix)
+			flows = append(flows, setFlows...)
+			if err != nil {
+				return flows, err
+			}
+		}
+	}
+	return flows, nil
+}
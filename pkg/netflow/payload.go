@@ -0,0 +1,89 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package netflow
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/collector/corechecks/snmp/devicestore"
+)
+
+// FlowPayload contains an aggregated flow, as sent to the event platform
+type FlowPayload struct {
+	FlowType     string           `json:"type"`
+	SamplingRate uint64           `json:"sampling_rate"`
+	Start        uint64           `json:"start"` // in seconds
+	End          uint64           `json:"end"`   // in seconds
+	Bytes        uint64           `json:"bytes"`
+	Packets      uint64           `json:"packets"`
+	IPProtocol   uint8            `json:"ip_protocol"`
+	TCPFlags     uint8            `json:"tcp_flags,omitempty"`
+	Exporter     ExporterPayload  `json:"exporter"`
+	Source       EndpointPayload  `json:"source"`
+	Destination  EndpointPayload  `json:"destination"`
+	Ingress      InterfacePayload `json:"ingress"`
+	Egress       InterfacePayload `json:"egress"`
+}
+
+// ExporterPayload contains the device which exported a flow, enriched with the metadata collected by the SNMP check
+type ExporterPayload struct {
+	IP        string `json:"ip"`
+	Namespace string `json:"namespace"`
+	DeviceID  string `json:"device_id,omitempty"`
+	Name      string `json:"name,omitempty"`
+}
+
+// EndpointPayload contains the source or the destination of a flow
+type EndpointPayload struct {
+	IP   string `json:"ip"`
+	Port uint16 `json:"port"`
+}
+
+// InterfacePayload contains an interface of the exporter
+type InterfacePayload struct {
+	Index uint32 `json:"index"`
+	Name  string `json:"name,omitempty"`
+	Alias string `json:"alias,omitempty"`
+}
+
+func buildPayload(flow *Flow, namespace string) FlowPayload {
+	payload := FlowPayload{
+		FlowType:     string(flow.FlowType),
+		SamplingRate: flow.SamplingRate,
+		Start:        flow.StartTimestamp,
+		End:          flow.EndTimestamp,
+		Bytes:        flow.Bytes,
+		Packets:      flow.Packets,
+		IPProtocol:   flow.IPProtocol,
+		TCPFlags:     flow.TCPFlags,
+		Exporter: ExporterPayload{
+			IP:        flow.ExporterAddr.String(),
+			Namespace: namespace,
+		},
+		Source:      EndpointPayload{IP: flow.SrcAddr.String(), Port: flow.SrcPort},
+		Destination: EndpointPayload{IP: flow.DstAddr.String(), Port: flow.DstPort},
+		Ingress:     InterfacePayload{Index: flow.InputInterface},
+		Egress:      InterfacePayload{Index: flow.OutputInterface},
+	}
+
+	device, ok := devicestore.GetDevice(namespace, payload.Exporter.IP)
+	if !ok {
+		return payload
+	}
+	payload.Exporter.DeviceID = device.Metadata.ID
+	payload.Exporter.Name = device.Metadata.Name
+	enrichInterface(&payload.Ingress, device)
+	enrichInterface(&payload.Egress, device)
+	return payload
+}
+
+func enrichInterface(itf *InterfacePayload, device *devicestore.Device) {
+	if itf.Index == 0 {
+		return
+	}
+	if metadata, ok := device.Interfaces[int32(itf.Index)]; ok {
+		itf.Name = metadata.Name
+		itf.Alias = metadata.Alias
+	}
+}
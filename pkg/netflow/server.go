@@ -0,0 +1,94 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package netflow
+
+import (
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// Server manages the flow listeners and the aggregator forwarding their flows.
+type Server struct {
+	config     *Config
+	listeners  []*flowListener
+	aggregator *flowAggregator
+}
+
+var (
+	serverInstance *Server
+	startError     error
+)
+
+// StartServer starts the global flow server.
+func StartServer(sender aggregator.Sender) error {
+	server, err := NewServer(sender)
+	serverInstance = server
+	startError = err
+	return err
+}
+
+// StopServer stops the global flow server, if it is running.
+func StopServer() {
+	if serverInstance != nil {
+		serverInstance.Stop()
+		serverInstance = nil
+		startError = nil
+	}
+}
+
+// IsRunning returns whether the flow server is currently running.
+func IsRunning() bool {
+	return serverInstance != nil
+}
+
+// NewServer configures and returns a running flow server.
+func NewServer(sender aggregator.Sender) (*Server, error) {
+	config, err := ReadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	server := &Server{
+		config:     config,
+		aggregator: newFlowAggregator(sender, config),
+	}
+	for _, listenerConfig := range config.Listeners {
+		listener, err := startFlowListener(listenerConfig, server.aggregator.flowIn)
+		if err != nil {
+			server.stopListeners()
+			return nil, err
+		}
+		server.listeners = append(server.listeners, listener)
+	}
+	server.aggregator.start()
+
+	return server, nil
+}
+
+// Stop stops the Server, flushing the aggregated flows.
+func (s *Server) Stop() {
+	stopped := make(chan interface{})
+
+	go func() {
+		s.stopListeners()
+		s.aggregator.stopAndFlush()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Duration(s.config.StopTimeout) * time.Second):
+		log.Errorf("Stopping server. Timeout after %d seconds", s.config.StopTimeout)
+	}
+}
+
+func (s *Server) stopListeners() {
+	for _, listener := range s.listeners {
+		listener.stop()
+	}
+}
@@ -0,0 +1,116 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package netflow
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// sFlow interfaces of the compact flow samples hold their format in the 2 most significant bits,
+// the format of the ifIndex values being 0
+const (
+	sflowInterfaceFormatMask = 0xc0000000
+	sflowInterfaceValueMask  = 0x3fffffff
+)
+
+// decodeSFlow5 decodes the flow samples of an sFlow v5 datagram. Each sampled packet header is a flow of one packet.
+// The counter samples are ignored.
+func decodeSFlow5(packet []byte, exporter net.IP, now time.Time) (flows []*Flow, err error) {
+	// the datagram is decoded by gopacket, which is not hardened against malformed inputs
+	defer func() {
+		if r := recover(); r != nil {
+			flows, err = nil, fmt.Errorf("invalid sFlow datagram: %v", r)
+		}
+	}()
+
+	var datagram layers.SFlowDatagram
+	if err := datagram.DecodeFromBytes(packet, gopacket.NilDecodeFeedback); err != nil {
+		return nil, err
+	}
+	if datagram.DatagramVersion != 5 {
+		return nil, fmt.Errorf("invalid sFlow version %d", datagram.DatagramVersion)
+	}
+
+	timestamp := uint64(now.Unix())
+	for _, sample := range datagram.FlowSamples {
+		inputInterface, outputInterface := sample.InputInterface, sample.OutputInterface
+		if sample.Format == layers.SFlowTypeFlowSample {
+			inputInterface, outputInterface = sflowIfIndex(inputInterface), sflowIfIndex(outputInterface)
+		}
+
+		for _, record := range sample.Records {
+			raw, ok := record.(layers.SFlowRawPacketFlowRecord)
+			if !ok || raw.Header == nil {
+				continue
+			}
+
+			flow := &Flow{
+				FlowType:        TypeSFlow5,
+				ExporterAddr:    exporter,
+				InputInterface:  inputInterface,
+				OutputInterface: outputInterface,
+				Bytes:           uint64(raw.FrameLength),
+				Packets:         1,
+				SamplingRate:    uint64(sample.SamplingRate),
+				StartTimestamp:  timestamp,
+				EndTimestamp:    timestamp,
+			}
+			if !decodeSampledHeader(raw.Header, flow) {
+				continue
+			}
+			flows = append(flows, flow)
+		}
+	}
+	return flows, nil
+}
+
+// sflowIfIndex returns the ifIndex of a compact sFlow interface, or 0 if it holds something else,
+// like the number of interfaces a packet was sent to
+func sflowIfIndex(value uint32) uint32 {
+	if value&sflowInterfaceFormatMask != 0 {
+		return 0
+	}
+	return value & sflowInterfaceValueMask
+}
+
+// decodeSampledHeader fills the addresses, ports and protocol of a flow from the header of a sampled packet.
+// It returns false if the packet is not an IP packet.
+func decodeSampledHeader(header gopacket.Packet, flow *Flow) bool {
+	switch ip := header.NetworkLayer().(type) {
+	case *layers.IPv4:
+		flow.SrcAddr, flow.DstAddr = copyIP(ip.SrcIP), copyIP(ip.DstIP)
+		flow.IPProtocol = uint8(ip.Protocol)
+	case *layers.IPv6:
+		flow.SrcAddr, flow.DstAddr = copyIP(ip.SrcIP), copyIP(ip.DstIP)
+		flow.IPProtocol = uint8(ip.NextHeader)
+	default:
+		return false
+	}
+
+	switch l4 := header.TransportLayer().(type) {
+	case *layers.TCP:
+		flow.SrcPort, flow.DstPort = uint16(l4.SrcPort), uint16(l4.DstPort)
+		flow.TCPFlags = tcpFlags(l4)
+	case *layers.UDP:
+		flow.SrcPort, flow.DstPort = uint16(l4.SrcPort), uint16(l4.DstPort)
+	}
+	return true
+}
+
+func tcpFlags(tcp *layers.TCP) uint8 {
+	var flags uint8
+	for i, set := range []bool{tcp.FIN, tcp.SYN, tcp.RST, tcp.PSH, tcp.ACK, tcp.URG, tcp.ECE, tcp.CWR} {
+		if set {
+			flags |= 1 << i
+		}
+	}
+	return flags
+}
@@ -0,0 +1,220 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package netflow
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+const (
+	setHeaderSize = 4
+
+	// netflow9TemplateSetID and ipfixTemplateSetID identify the sets of templates, the data sets
+	// being identified by the ID of their template, from 256
+	netflow9TemplateSetID = 0
+	ipfixTemplateSetID    = 2
+	minDataSetID          = 256
+
+	// variableLength is the field length of the IPFIX variable-length information elements
+	variableLength = 0xffff
+	// enterpriseBit flags the IPFIX information elements followed by a private enterprise number
+	enterpriseBit = 0x8000
+)
+
+// Field types of NetFlow v9, which are also the IANA identifiers of the IPFIX information elements
+const (
+	fieldInBytes          = 1
+	fieldInPkts           = 2
+	fieldProtocol         = 4
+	fieldTCPFlags         = 6
+	fieldL4SrcPort        = 7
+	fieldIPv4SrcAddr      = 8
+	fieldInputSNMP        = 10
+	fieldL4DstPort        = 11
+	fieldIPv4DstAddr      = 12
+	fieldOutputSNMP       = 14
+	fieldLastSwitched     = 21
+	fieldFirstSwitched    = 22
+	fieldIPv6SrcAddr      = 27
+	fieldIPv6DstAddr      = 28
+	fieldSamplingInterval = 34
+	fieldFlowStartSeconds = 150
+	fieldFlowEndSeconds   = 151
+	fieldFlowStartMillis  = 152
+	fieldFlowEndMillis    = 153
+)
+
+type templateKey struct {
+	exporter string
+	// domainID is the source ID of NetFlow v9 and the observation domain ID of IPFIX
+	domainID   uint32
+	templateID uint16
+}
+
+type templateField struct {
+	id         uint16
+	length     uint16
+	enterprise bool
+}
+
+type template struct {
+	fields []templateField
+	// minRecordSize is the size of a record whose variable-length fields are empty,
+	// which tells the records from the padding at the end of a set
+	minRecordSize int
+}
+
+// decodeTemplates stores the templates of a template set
+func (d *decoder) decodeTemplates(body []byte, exporter net.IP, domainID uint32, ipfix bool) error {
+	for len(body) >= 4 {
+		templateID := binary.BigEndian.Uint16(body)
+		fieldCount := int(binary.BigEndian.Uint16(body[2:]))
+		body = body[4:]
+		if templateID < minDataSetID {
+			return fmt.Errorf("invalid template ID %d", templateID)
+		}
+		key := templateKey{exporter: exporter.String(), domainID: domainID, templateID: templateID}
+		if ipfix && fieldCount == 0 {
+			// template withdrawal
+			delete(d.templates, key)
+			continue
+		}
+
+		t := &template{fields: make([]templateField, 0, fieldCount)}
+		for i := 0; i < fieldCount; i++ {
+			if len(body) < 4 {
+				return errPacketTooShort
+			}
+			f := templateField{id: binary.BigEndian.Uint16(body), length: binary.BigEndian.Uint16(body[2:])}
+			body = body[4:]
+			if ipfix && f.id&enterpriseBit != 0 {
+				if len(body) < 4 {
+					return errPacketTooShort
+				}
+				// the enterprise-specific fields are not decoded, only skipped
+				f.id &^= enterpriseBit
+				f.enterprise = true
+				body = body[4:]
+			}
+
+			if ipfix && f.length == variableLength {
+				t.minRecordSize++
+			} else {
+				t.minRecordSize += int(f.length)
+			}
+			t.fields = append(t.fields, f)
+		}
+		if t.minRecordSize == 0 {
+			return fmt.Errorf("invalid empty template %d", templateID)
+		}
+
+		d.templates[key] = t
+	}
+	return nil
+}
+
+// recordTimes holds the times of the packet the records were received in, in milliseconds since the epoch
+type recordTimes struct {
+	exportTime uint64
+	// bootTime is the time the exporter started, which the NetFlow v9 flow times are relative to
+	bootTime uint64
+}
+
+// decodeDataSet decodes the records of a data set with their template.
+// It returns no flow and no error when the template is unknown, as it may be sent later by the exporter.
+func (d *decoder) decodeDataSet(body []byte, exporter net.IP, domainID uint32, setID uint16, times recordTimes, ipfix bool) ([]*Flow, error) {
+	t, ok := d.templates[templateKey{exporter: exporter.String(), domainID: domainID, templateID: setID}]
+	if !ok {
+		return nil, nil
+	}
+
+	var flows []*Flow
+	for len(body) >= t.minRecordSize {
+		flow := &Flow{
+			FlowType:     d.flowType,
+			ExporterAddr: exporter,
+		}
+		var startMillis, endMillis uint64
+		for _, f := range t.fields {
+			length := int(f.length)
+			if ipfix && f.length == variableLength {
+				if len(body) < 1 {
+					return flows, errPacketTooShort
+				}
+				length, body = int(body[0]), body[1:]
+				if length == 0xff {
+					if len(body) < 2 {
+						return flows, errPacketTooShort
+					}
+					length, body = int(binary.BigEndian.Uint16(body)), body[2:]
+				}
+			}
+			if len(body) < length {
+				return flows, errPacketTooShort
+			}
+			value := body[:length]
+			body = body[length:]
+			if f.enterprise {
+				continue
+			}
+
+			switch f.id {
+			case fieldInBytes:
+				flow.Bytes = readUint(value)
+			case fieldInPkts:
+				flow.Packets = readUint(value)
+			case fieldProtocol:
+				flow.IPProtocol = uint8(readUint(value))
+			case fieldTCPFlags:
+				flow.TCPFlags = uint8(readUint(value))
+			case fieldL4SrcPort:
+				flow.SrcPort = uint16(readUint(value))
+			case fieldL4DstPort:
+				flow.DstPort = uint16(readUint(value))
+			case fieldIPv4SrcAddr, fieldIPv6SrcAddr:
+				flow.SrcAddr = copyIP(value)
+			case fieldIPv4DstAddr, fieldIPv6DstAddr:
+				flow.DstAddr = copyIP(value)
+			case fieldInputSNMP:
+				flow.InputInterface = uint32(readUint(value))
+			case fieldOutputSNMP:
+				flow.OutputInterface = uint32(readUint(value))
+			case fieldSamplingInterval:
+				flow.SamplingRate = readUint(value)
+			case fieldFirstSwitched:
+				if !ipfix {
+					startMillis = times.bootTime + readUint(value)
+				}
+			case fieldLastSwitched:
+				if !ipfix {
+					endMillis = times.bootTime + readUint(value)
+				}
+			case fieldFlowStartSeconds:
+				startMillis = readUint(value) * 1000
+			case fieldFlowEndSeconds:
+				endMillis = readUint(value) * 1000
+			case fieldFlowStartMillis:
+				startMillis = readUint(value)
+			case fieldFlowEndMillis:
+				endMillis = readUint(value)
+			}
+		}
+
+		// the flows without times are considered to have been observed when they were exported
+		if startMillis == 0 {
+			startMillis = times.exportTime
+		}
+		if endMillis == 0 {
+			endMillis = times.exportTime
+		}
+		flow.StartTimestamp = startMillis / 1000
+		flow.EndTimestamp = endMillis / 1000
+		flows = append(flows, flow)
+	}
+	return flows, nil
+}
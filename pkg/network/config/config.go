@@ -25,6 +25,10 @@ const (
 
 	defaultOffsetThreshold = 400
 	maxOffsetThreshold     = 3000
+
+	defaultFlowExportInterval = 30 * time.Second
+
+	defaultListenerInventoryInterval = 30 * time.Second
 )
 
 // Config stores all flags used by the network eBPF tracer
@@ -69,9 +73,27 @@ type Config struct {
 	EnableHTTPMonitoring bool
 
 	// EnableHTTPMonitoring specifies whether the tracer should monitor HTTPS traffic
-	// Supported libraries: OpenSSL
+	// Supported libraries: OpenSSL, and Go's crypto/tls when EnableGoTLSSupport is set
 	EnableHTTPSMonitoring bool
 
+	// EnableGoTLSSupport specifies whether the tracer should monitor the HTTPS traffic of the
+	// statically linked Go binaries, by attaching uprobes to crypto/tls.
+	// It requires HTTPS monitoring to be enabled.
+	EnableGoTLSSupport bool
+
+	// EnableHTTP2Monitoring specifies whether the tracer should monitor HTTP/2 traffic, including gRPC.
+	// It requires HTTP monitoring to be enabled.
+	EnableHTTP2Monitoring bool
+
+	// EnableKafkaMonitoring specifies whether the tracer should monitor the Kafka Produce and Fetch requests
+	EnableKafkaMonitoring bool
+
+	// EnablePostgresMonitoring specifies whether the tracer should monitor the queries sent to PostgreSQL servers
+	EnablePostgresMonitoring bool
+
+	// EnableMySQLMonitoring specifies whether the tracer should monitor the queries sent to MySQL servers
+	EnableMySQLMonitoring bool
+
 	// UDPConnTimeout determines the length of traffic inactivity between two
 	// (IP, port)-pairs before declaring a UDP connection as inactive. This is
 	// set to /proc/sys/net/netfilter/nf_conntrack_udp_timeout on Linux by
@@ -107,6 +129,14 @@ type Config struct {
 	// get flushed on every client request (default 30s check interval)
 	MaxHTTPStatsBuffered int
 
+	// MaxKafkaStatsBuffered represents the maximum number of Kafka stats we'll buffer in memory. These stats
+	// get flushed on every client request (default 30s check interval)
+	MaxKafkaStatsBuffered int
+
+	// MaxDatabaseStatsBuffered represents the maximum number of database query stats we'll buffer in memory. These stats
+	// get flushed on every client request (default 30s check interval)
+	MaxDatabaseStatsBuffered int
+
 	// MaxConnectionsStateBuffered represents the maximum number of state objects that we'll store in memory. These state objects store
 	// the stats for a connection so we can accurately determine traffic change between client requests.
 	MaxConnectionsStateBuffered int
@@ -159,6 +189,36 @@ type Config struct {
 	// RecordedQueryTypes enables specific DNS query types to be recorded
 	RecordedQueryTypes []string
 
+	// EnableFlowExport enables the export of the connection deltas as IPFIX or NetFlow v9 records
+	EnableFlowExport bool
+
+	// FlowExportCollectorAddress is the host:port of the UDP flow collector the records are sent to
+	FlowExportCollectorAddress string
+
+	// FlowExportProtocol is the format of the exported records, either "ipfix" or "netflow9"
+	FlowExportProtocol string
+
+	// FlowExportInterval determines how often the connection deltas are exported.
+	// It must be lower than ClientStateExpiry, or the flow exporter state would expire between two exports.
+	FlowExportInterval time.Duration
+
+	// FlowExportObservationDomainID is the observation domain (IPFIX) or source (NetFlow v9) ID of the exported records
+	FlowExportObservationDomainID uint32
+
+	// FlowExportEnterpriseNumber is the private enterprise number of the process, container and DNS fields
+	// of the IPFIX records, which have no IANA information element. These fields are not exported when it is 0.
+	FlowExportEnterpriseNumber uint32
+
+	// EnableListenerInventory enables the inventory of the listening sockets of the host, along with their process
+	EnableListenerInventory bool
+
+	// ListenerInventoryInterval determines how often the listening sockets are read from the /proc filesystem
+	ListenerInventoryInterval time.Duration
+
+	// ListenerAllowedPorts is the list of ports, or ranges of ports such as "8000-8999", on which a new listener
+	// is expected. A new listener on any other port is recorded as an event.
+	ListenerAllowedPorts []string
+
 	// HTTP replace rules
 	HTTPReplaceRules []*ReplaceRule
 }
@@ -206,8 +266,17 @@ func New() *Config {
 
 		EnableHTTPMonitoring:  cfg.GetBool(join(netNS, "enable_http_monitoring")),
 		EnableHTTPSMonitoring: cfg.GetBool(join(netNS, "enable_https_monitoring")),
+		EnableHTTP2Monitoring: cfg.GetBool(join(netNS, "enable_http2_monitoring")),
+		EnableGoTLSSupport:    cfg.GetBool(join(netNS, "enable_go_tls_support")),
 		MaxHTTPStatsBuffered:  100000,
 
+		EnableKafkaMonitoring: cfg.GetBool(join(netNS, "enable_kafka_monitoring")),
+		MaxKafkaStatsBuffered: 100000,
+
+		EnablePostgresMonitoring: cfg.GetBool(join(netNS, "enable_postgres_monitoring")),
+		EnableMySQLMonitoring:    cfg.GetBool(join(netNS, "enable_mysql_monitoring")),
+		MaxDatabaseStatsBuffered: 100000,
+
 		EnableConntrack:              cfg.GetBool(join(spNS, "enable_conntrack")),
 		ConntrackMaxStateSize:        cfg.GetInt(join(spNS, "conntrack_max_state_size")),
 		ConntrackRateLimit:           cfg.GetInt(join(spNS, "conntrack_rate_limit")),
@@ -221,6 +290,17 @@ func New() *Config {
 		DriverBufferSize:     cfg.GetInt(join(spNS, "windows.driver_buffer_size")),
 
 		RecordedQueryTypes: cfg.GetStringSlice(join(netNS, "dns_recorded_query_types")),
+
+		EnableFlowExport:              cfg.GetBool(join(netNS, "flow_export.enabled")),
+		FlowExportCollectorAddress:    cfg.GetString(join(netNS, "flow_export.collector_address")),
+		FlowExportProtocol:            strings.ToLower(cfg.GetString(join(netNS, "flow_export.protocol"))),
+		FlowExportInterval:            cfg.GetDuration(join(netNS, "flow_export.interval")),
+		FlowExportObservationDomainID: uint32(cfg.GetInt64(join(netNS, "flow_export.observation_domain_id"))),
+		FlowExportEnterpriseNumber:    uint32(cfg.GetInt64(join(netNS, "flow_export.enterprise_number"))),
+
+		EnableListenerInventory:   cfg.GetBool(join(netNS, "listeners.enabled")),
+		ListenerInventoryInterval: cfg.GetDuration(join(netNS, "listeners.interval")),
+		ListenerAllowedPorts:      cfg.GetStringSlice(join(netNS, "listeners.allowed_ports")),
 	}
 
 	httpRRKey := join(netNS, "http_replace_rules")
@@ -262,5 +342,31 @@ func New() *Config {
 		}
 	}
 
+	if c.EnableHTTP2Monitoring && !c.EnableHTTPMonitoring {
+		log.Warn("network tracer HTTP/2 monitoring disabled: it requires HTTP monitoring to be enabled")
+		c.EnableHTTP2Monitoring = false
+	}
+
+	if c.EnableGoTLSSupport && !c.EnableHTTPSMonitoring {
+		log.Warn("network tracer Go TLS support disabled: it requires HTTPS monitoring to be enabled")
+		c.EnableGoTLSSupport = false
+	}
+
+	if c.EnableFlowExport {
+		if c.FlowExportCollectorAddress == "" {
+			log.Warn("network tracer flow export disabled: network_config.flow_export.collector_address is not set")
+			c.EnableFlowExport = false
+		}
+		if c.FlowExportInterval <= 0 || c.FlowExportInterval >= c.ClientStateExpiry {
+			log.Warnf("network_config.flow_export.interval must be between 0 and %s. Setting it to the default of %s", c.ClientStateExpiry, defaultFlowExportInterval)
+			c.FlowExportInterval = defaultFlowExportInterval
+		}
+	}
+
+	if c.EnableListenerInventory && c.ListenerInventoryInterval <= 0 {
+		log.Warnf("network_config.listeners.interval must be positive. Setting it to the default of %s", defaultListenerInventoryInterval)
+		c.ListenerInventoryInterval = defaultListenerInventoryInterval
+	}
+
 	return c
 }
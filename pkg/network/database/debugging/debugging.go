@@ -0,0 +1,88 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package debugging
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/network/database"
+	"github.com/DataDog/datadog-agent/pkg/process/util"
+	"github.com/DataDog/sketches-go/ddsketch"
+)
+
+// QuerySummary represents a (debug-friendly) aggregated view of the executions
+// of a query over a (client, server) tuple
+type QuerySummary struct {
+	Client             Address
+	Server             Address
+	DNS                string
+	Protocol           string
+	Query              string
+	Count              int
+	ErrorCount         int
+	FirstLatencySample float64
+	LatencyP50         float64
+}
+
+// Address represents represents a IP:Port
+type Address struct {
+	IP   string
+	Port uint16
+}
+
+// Database returns a debug-friendly representation of map[database.Key]database.RequestStats
+func Database(stats map[database.Key]database.RequestStats, dns map[util.Address][]string) []QuerySummary {
+	all := make([]QuerySummary, 0, len(stats))
+	for k, v := range stats {
+		clientAddr := formatIP(k.SrcIPLow, k.SrcIPHigh)
+		serverAddr := formatIP(k.DstIPLow, k.DstIPHigh)
+
+		all = append(all, QuerySummary{
+			Client: Address{
+				IP:   clientAddr.String(),
+				Port: k.SrcPort,
+			},
+			Server: Address{
+				IP:   serverAddr.String(),
+				Port: k.DstPort,
+			},
+			DNS:                getDNS(dns, serverAddr),
+			Protocol:           k.Protocol.String(),
+			Query:              k.Query,
+			Count:              v.Count,
+			ErrorCount:         v.ErrorCount,
+			FirstLatencySample: v.FirstLatencySample,
+			LatencyP50:         getSketchQuantile(v.Latencies, 0.5),
+		})
+	}
+
+	return all
+}
+
+func formatIP(low, high uint64) util.Address {
+	// As for HTTP, we don't have socket family information for database queries:
+	// assume it's only IPv6 if higher order bits are set.
+	if high > 0 || (low>>32) > 0 {
+		return util.V6Address(low, high)
+	}
+
+	return util.V4Address(uint32(low))
+}
+
+func getDNS(dns map[util.Address][]string, addr util.Address) string {
+	if names := dns[addr]; len(names) > 0 {
+		return names[0]
+	}
+
+	return ""
+}
+
+func getSketchQuantile(sketch *ddsketch.DDSketch, percentile float64) float64 {
+	if sketch == nil {
+		return 0.0
+	}
+
+	val, _ := sketch.GetValueAtQuantile(percentile)
+	return val
+}
@@ -0,0 +1,123 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package database
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/network/latency"
+	"github.com/DataDog/datadog-agent/pkg/network/segments"
+)
+
+const (
+	// maxPendingPerConn is the maximum number of queries waiting for their response per connection.
+	maxPendingPerConn = 1000
+	// maxStatementsPerConn is the maximum number of prepared statements remembered per connection.
+	maxStatementsPerConn = 1000
+)
+
+// transaction is the execution of a query: the query sent by the client and the server response.
+type transaction struct {
+	// key identifies the connection and holds the query, not obfuscated yet
+	key     Key
+	failed  bool
+	latency float64
+}
+
+// completeFunc is called by the protocol decoders with each query completed by a response.
+type completeFunc func(query string, failed bool, started, ended uint64)
+
+// protocolConn decodes the messages of a connection for a given protocol.
+type protocolConn interface {
+	// frame returns the size of the message starting at data, header included. It returns false
+	// if the header is not captured, or is not valid.
+	frame(fromClient bool, data []byte) (int, bool)
+	// starts reports whether data plausibly starts with the header of a message, once the
+	// message boundaries of a direction were lost.
+	starts(fromClient bool, data []byte) bool
+	// lost forgets the state which the messages missed in a direction may have changed.
+	lost(fromClient bool)
+	// message decodes a message, of which only the beginning may be captured.
+	message(fromClient bool, msg []byte, timestamp uint64, done completeFunc)
+	// encrypted reports whether the connection switched to TLS: it can't be decoded anymore.
+	encrypted() bool
+}
+
+// conn decodes the messages of a database connection with the protocolConn of its protocol.
+type conn struct {
+	protocolConn
+	d *decoder
+	// key identifies the connection and its protocol
+	key Key
+}
+
+// decoder decodes the messages of the captured segments into transactions. Only the beginning
+// of each segment is captured, so the queries longer than the captured part of their message
+// are truncated. When the header of a message is not captured, the decoding of its direction
+// resumes at the next segment starting with a plausible message.
+type decoder struct {
+	*segments.Decoder
+}
+
+func newDecoder(maxConns, maxBuffered int) *decoder {
+	d := &decoder{}
+	d.Decoder = segments.NewDecoder(maxConns, maxBuffered, func(seg segments.Segment) segments.Parser {
+		c := &conn{d: d, key: connKey(seg.Conn, Protocol(seg.Protocol))}
+		switch c.key.Protocol {
+		case ProtocolPostgres:
+			c.protocolConn = newPostgresConn()
+		case ProtocolMySQL:
+			c.protocolConn = newMySQLConn()
+		default:
+			return nil
+		}
+		return c
+	})
+	return d
+}
+
+func (c *conn) Frame(fromClient bool, data []byte) (int, bool) {
+	return c.frame(fromClient, data)
+}
+
+func (c *conn) Starts(fromClient bool, data []byte) bool {
+	return c.starts(fromClient, data)
+}
+
+func (c *conn) Lost(fromClient bool) {
+	c.lost(fromClient)
+}
+
+func (c *conn) Message(fromClient bool, msg []byte, truncated bool, timestamp uint64) bool {
+	c.message(fromClient, msg, timestamp, c.complete)
+	return true
+}
+
+func (c *conn) Encrypted() bool {
+	return c.encrypted()
+}
+
+// Expire does nothing: the number of queries waiting for their response is bounded by the
+// protocol decoders.
+func (c *conn) Expire(now uint64) {}
+
+func (c *conn) complete(query string, failed bool, started, ended uint64) {
+	if query == "" || ended < started {
+		return
+	}
+	key := c.key
+	key.Query = query
+	c.d.Complete(transaction{key: key, failed: failed, latency: latency.NSTimestampToFloat(ended - started)})
+}
+
+// cstring returns the NUL-terminated string starting at b, and the data following it.
+// The string is truncated to the data captured when its end is not.
+func cstring(b []byte) (string, []byte) {
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i]), b[i+1:]
+		}
+	}
+	return string(b), nil
+}
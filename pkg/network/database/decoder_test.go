@@ -0,0 +1,307 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package database
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/network/segments"
+	"github.com/DataDog/datadog-agent/pkg/process/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pgMessage returns a PostgreSQL message of type typ, made of the fields given. The strings are
+// NUL-terminated.
+func pgMessage(typ byte, fields ...interface{}) []byte {
+	var body []byte
+	for _, f := range fields {
+		switch v := f.(type) {
+		case string:
+			body = append(append(body, v...), 0)
+		case byte:
+			body = append(body, v)
+		case int16:
+			var b [2]byte
+			binary.BigEndian.PutUint16(b[:], uint16(v))
+			body = append(body, b[:]...)
+		case int32:
+			var b [4]byte
+			binary.BigEndian.PutUint32(b[:], uint32(v))
+			body = append(body, b[:]...)
+		}
+	}
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(body)+4))
+	if typ == 0 {
+		// the startup messages have no type
+		return append(size[:], body...)
+	}
+	return append(append([]byte{typ}, size[:]...), body...)
+}
+
+// mysqlPacket returns a MySQL packet with the sequence ID seq.
+func mysqlPacket(seq byte, payload ...byte) []byte {
+	n := len(payload)
+	return append([]byte{byte(n), byte(n >> 8), byte(n >> 16), seq}, payload...)
+}
+
+func concat(messages ...[]byte) []byte {
+	var b []byte
+	for _, m := range messages {
+		b = append(b, m...)
+	}
+	return b
+}
+
+// testCaptureSize is the size of the beginning of the segments captured by the eBPF programs.
+const testCaptureSize = 256
+
+// captured returns the segment seg of which only the first testCaptureSize bytes are captured.
+func captured(seg segments.Segment) segments.Segment {
+	if len(seg.Data) > testCaptureSize {
+		seg.Data = seg.Data[:testCaptureSize]
+	}
+	return seg
+}
+
+// testConn writes both directions of a database connection as segments.
+type testConn struct {
+	conn                 segments.ConnKey
+	protocol             Protocol
+	clientSeq, serverSeq uint32
+}
+
+func newTestConn(protocol Protocol) *testConn {
+	k := NewKey(
+		util.AddressFromString("1.1.1.1"),
+		util.AddressFromString("2.2.2.2"),
+		1234,
+		5432,
+		ProtocolUnknown,
+		"",
+	)
+	return &testConn{
+		conn: segments.ConnKey{
+			SrcIPHigh: k.SrcIPHigh,
+			SrcIPLow:  k.SrcIPLow,
+			SrcPort:   k.SrcPort,
+			DstIPHigh: k.DstIPHigh,
+			DstIPLow:  k.DstIPLow,
+			DstPort:   k.DstPort,
+		},
+		protocol: protocol,
+	}
+}
+
+func (c *testConn) segment(fromClient bool, timestamp uint64, data ...[]byte) segments.Segment {
+	seq := &c.serverSeq
+	if fromClient {
+		seq = &c.clientSeq
+	}
+	b := concat(data...)
+	seg := segments.Segment{
+		Conn:       c.conn,
+		Protocol:   uint8(c.protocol),
+		Timestamp:  timestamp,
+		Seq:        *seq,
+		FromClient: fromClient,
+		Length:     len(b),
+		Data:       b,
+	}
+	*seq += uint32(len(b))
+	return seg
+}
+
+func (c *testConn) key(query string) Key {
+	k := connKey(c.conn, c.protocol)
+	k.Query = query
+	return k
+}
+
+// protocolConn returns the protocolConn decoding the connection c.
+func (c *testConn) protocolConn(d *decoder) protocolConn {
+	return d.Parser(c.conn).(*conn).protocolConn
+}
+
+func decodeAll(d *decoder, segs ...segments.Segment) []transaction {
+	var txs []transaction
+	for _, seg := range segs {
+		d.Decode(seg, func(tx interface{}) {
+			txs = append(txs, tx.(transaction))
+		})
+	}
+	return txs
+}
+
+func TestDecoderMessageAcrossSegments(t *testing.T) {
+	d := newDecoder(100, 100)
+	c := newTestConn(ProtocolPostgres)
+
+	// a long query is split over two segments, followed by a second query in the same segment
+	long := pgMessage('Q', "SELECT * FROM users WHERE name IN ('"+strings.Repeat("x", 100)+"')")
+	split := 40
+
+	txs := decodeAll(d,
+		c.segment(true, 1000, long[:split]),
+		c.segment(true, 1100, long[split:], pgMessage('Q', "SELECT 1")),
+		c.segment(false, 2000, pgMessage('C', "SELECT 1"), pgMessage('Z', byte('I'))),
+		c.segment(false, 3000, pgMessage('C', "SELECT 1"), pgMessage('Z', byte('I'))),
+	)
+	require.Len(t, txs, 2)
+	// only the beginning of the query is decoded
+	assert.Equal(t, "SELECT * FROM users WHERE name IN (", txs[0].key.Query)
+	assert.Equal(t, transaction{key: c.key("SELECT 1"), latency: 1900}, txs[1])
+	assert.Zero(t, d.Lost)
+}
+
+func TestDecoderLost(t *testing.T) {
+	d := newDecoder(100, 100)
+	c := newTestConn(ProtocolMySQL)
+
+	c.segment(true, 1000, mysqlPacket(0, append([]byte{mysqlComQuery}, "SELECT 1"...)...)) // missed
+	txs := decodeAll(d,
+		c.segment(true, 2000, mysqlPacket(0, append([]byte{mysqlComQuery}, "SELECT 2"...)...)),
+		c.segment(false, 3000, mysqlPacket(1, mysqlOK, 0, 0, 2, 0, 0, 0)),
+	)
+	// the first segment seen sets the expected sequence number
+	require.Len(t, txs, 1)
+
+	c.segment(true, 4000, mysqlPacket(0, append([]byte{mysqlComQuery}, "SELECT 3"...)...)) // missed
+	txs = decodeAll(d,
+		c.segment(true, 5000, mysqlPacket(0, append([]byte{mysqlComQuery}, "SELECT 4"...)...)),
+		c.segment(false, 6000, mysqlPacket(1, mysqlOK, 0, 0, 2, 0, 0, 0)),
+	)
+	// the decoding resumes at the next command
+	require.Len(t, txs, 1)
+	assert.Equal(t, transaction{key: c.key("SELECT 4"), latency: 1000}, txs[0])
+	assert.EqualValues(t, 1, d.Lost)
+}
+
+func TestDecoderPostgresNotCaptured(t *testing.T) {
+	d := newDecoder(100, 100)
+	c := newTestConn(ProtocolPostgres)
+	row := strings.Repeat("x", 400)
+
+	// the end of the first response and of the second query is not captured
+	q1 := c.segment(true, 1000, pgMessage('Q', "SELECT name FROM users"))
+	r1 := c.segment(false, 2000,
+		pgMessage('T', int16(1), "name", int32(0), int16(0), int32(25), int16(-1), int32(-1), int16(0)),
+		pgMessage('D', int16(1), int32(len(row)), row),
+		pgMessage('C', "SELECT 1"),
+		pgReady(),
+	)
+	q2 := c.segment(true, 3000, pgMessage('Q', "SELECT '"+row+"'"), pgMessage('Q', "SELECT 2"))
+	r2 := c.segment(false, 4000, pgMessage('C', "SELECT 1"), pgReady(), pgMessage('C', "SELECT 1"), pgReady())
+	q3 := c.segment(true, 5000, pgMessage('Q', "SELECT 3"))
+	r3 := c.segment(false, 6000, pgMessage('C', "SELECT 1"), pgReady())
+
+	txs := decodeAll(d, captured(q1), captured(r1), captured(q2), captured(r2), captured(q3), captured(r3))
+	assert.EqualValues(t, 2, d.Lost)
+	require.Len(t, txs, 2)
+	assert.Equal(t, transaction{key: c.key("SELECT 3"), latency: 1000}, txs[1])
+	// only the beginning of the long query is captured
+	assert.True(t, strings.HasPrefix(txs[0].key.Query, "SELECT 'xxx"))
+	assert.Equal(t, float64(1000), txs[0].latency)
+}
+
+func TestDecoderMySQLNotCaptured(t *testing.T) {
+	d := newDecoder(100, 100)
+	c := newTestConn(ProtocolMySQL)
+	row := []byte(strings.Repeat("x", 400))
+
+	// the result set is sent in a single segment, of which only the first packets are captured
+	q1 := c.segment(true, 1000, mysqlCommand(mysqlComQuery, []byte("SELECT name FROM users")...))
+	r1 := c.segment(false, 2000,
+		mysqlPacket(1, 1),
+		mysqlPacket(2, append([]byte{3}, "def"...)...),
+		mysqlPacket(3, append([]byte{0xfc, 0x90, 0x01}, row...)...),
+		mysqlPacket(4, 0xfe, 0, 0, 2, 0),
+	)
+	q2 := c.segment(true, 3000, mysqlCommand(mysqlComQuery, []byte("SELECT 2")...))
+	r2 := c.segment(false, 4000, mysqlErrPacket(1))
+
+	txs := decodeAll(d, captured(q1), captured(r1), captured(q2), captured(r2))
+	assert.EqualValues(t, 1, d.Lost)
+	require.Len(t, txs, 2)
+	assert.Equal(t, transaction{key: c.key("SELECT name FROM users"), latency: 1000}, txs[0])
+	assert.Equal(t, transaction{key: c.key("SELECT 2"), failed: true, latency: 1000}, txs[1])
+}
+
+func TestDecoderStarts(t *testing.T) {
+	pg := newPostgresConn()
+	pg.startupDone = true
+	assert.True(t, pg.starts(true, pgMessage('Q', "SELECT 1")))
+	assert.True(t, pg.starts(true, pgMessage('S')))
+	assert.True(t, pg.starts(false, pgReady()))
+	assert.True(t, pg.starts(false, pgMessage('D', int16(1), int32(1), "x")))
+	assert.False(t, pg.starts(true, pgMessage('S', "x")))
+	assert.False(t, pg.starts(true, pgMessage('Z', byte('I'))))
+	assert.False(t, pg.starts(false, pgMessage('Z', "II")))
+	assert.False(t, pg.starts(false, []byte("xxxxxxxxxx")))
+	assert.False(t, pg.starts(false, []byte{'C', 0, 0, 0}))
+
+	my := newMySQLConn()
+	assert.True(t, my.starts(true, mysqlCommand(mysqlComQuery, []byte("SELECT 1")...)))
+	assert.False(t, my.starts(true, mysqlCommand(0x42)))
+	assert.False(t, my.starts(true, mysqlPacket(2, mysqlComQuery)))
+	// the responses are only decoded with the command they answer
+	assert.False(t, my.starts(false, mysqlOKPacket(1)))
+	my.pending = &mysqlRequest{command: mysqlComQuery}
+	assert.True(t, my.starts(false, mysqlOKPacket(1)))
+	assert.False(t, my.starts(false, mysqlOKPacket(2)))
+}
+
+func TestDecoderDuplicates(t *testing.T) {
+	d := newDecoder(100, 100)
+	c := newTestConn(ProtocolPostgres)
+
+	req := c.segment(true, 1000, pgMessage('Q', "SELECT 1"))
+	resp := c.segment(false, 2000, pgMessage('C', "SELECT 1"), pgMessage('Z', byte('I')))
+	dupResp := resp
+	dupResp.Timestamp = 2100
+
+	txs := decodeAll(d, req, req, resp, dupResp)
+	require.Len(t, txs, 1)
+	assert.Equal(t, float64(1000), txs[0].latency)
+	assert.Zero(t, d.Lost)
+}
+
+func TestDecoderFlush(t *testing.T) {
+	d := newDecoder(100, 100)
+	c := newTestConn(ProtocolPostgres)
+
+	req := c.segment(true, 1000, pgMessage('Q', "SELECT 1"))
+	resp := c.segment(false, 2000, pgMessage('C', "SELECT 1"), pgMessage('Z', byte('I')))
+
+	var txs []transaction
+	done := func(tx interface{}) { txs = append(txs, tx.(transaction)) }
+
+	// the segments are received out of order
+	d.Add(resp)
+	d.Add(req)
+	d.Flush(1500, done)
+	assert.Empty(t, txs)
+	assert.Equal(t, 1, d.Buffered())
+
+	d.Flush(2500, done)
+	require.Len(t, txs, 1)
+	assert.Zero(t, d.Buffered())
+}
+
+func TestDecoderUnknownProtocol(t *testing.T) {
+	d := newDecoder(100, 100)
+	c := newTestConn(ProtocolUnknown)
+
+	txs := decodeAll(d,
+		c.segment(true, 1000, pgMessage('Q', "SELECT 1")),
+		c.segment(false, 2000, pgMessage('C', "SELECT 1"), pgMessage('Z', byte('I'))),
+	)
+	assert.Empty(t, txs)
+	assert.Zero(t, d.Conns())
+}
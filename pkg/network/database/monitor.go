@@ -0,0 +1,147 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux_bpf
+// +build linux_bpf
+
+package database
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/network/config"
+	netebpf "github.com/DataDog/datadog-agent/pkg/network/ebpf"
+	"github.com/DataDog/datadog-agent/pkg/network/segments"
+	"github.com/DataDog/ebpf/manager"
+)
+
+const (
+	databaseConnsMap             = "database_conns"
+	databaseBatchesMap           = "database_batches"
+	databaseBatchStateMap        = "database_batch_state"
+	databaseScratchMap           = "database_scratch"
+	databaseNotificationsPerfMap = "database_notifications"
+
+	// ELF section of the BPF_PROG_TYPE_SOCKET_FILTER program used
+	// to capture the PostgreSQL and MySQL traffic
+	databaseSocketFilter = "socket/database_filter"
+)
+
+// Monitor captures the database segments with a segments.Monitor, decodes them into
+// queries, and aggregates metrics per obfuscated query.
+type Monitor struct {
+	monitor           *segments.Monitor
+	telemetry         *segments.Telemetry
+	statkeeper        *statKeeper
+	mux               sync.Mutex
+	telemetrySnapshot *segments.Telemetry
+}
+
+// NewMonitor returns a new Monitor instance
+func NewMonitor(c *config.Config) (*Monitor, error) {
+	var constantEditors []manager.ConstantEditor
+	if c.EnablePostgresMonitoring {
+		constantEditors = append(constantEditors, manager.ConstantEditor{
+			Name:  "postgres_monitoring_enabled",
+			Value: uint64(1),
+		})
+	}
+	if c.EnableMySQLMonitoring {
+		constantEditors = append(constantEditors, manager.ConstantEditor{
+			Name:  "mysql_monitoring_enabled",
+			Value: uint64(1),
+		})
+	}
+
+	telemetry := segments.NewTelemetry()
+	statkeeper := newStatKeeper(c, telemetry)
+	monitor, err := segments.NewMonitor(c, segments.ProgramSpec{
+		Name:             "database",
+		SocketFilter:     databaseSocketFilter,
+		ConnsMap:         databaseConnsMap,
+		BatchesMap:       databaseBatchesMap,
+		BatchStateMap:    databaseBatchStateMap,
+		ScratchMap:       databaseScratchMap,
+		NotificationsMap: databaseNotificationsPerfMap,
+		Batches:          batchSpec,
+		RuntimeCompile:   getRuntimeCompiledDatabase,
+		Prebuilt:         netebpf.ReadDatabaseModule,
+		ConstantEditors:  constantEditors,
+	}, statkeeper)
+	if err != nil {
+		return nil, fmt.Errorf("error setting up database ebpf program: %s", err)
+	}
+
+	return &Monitor{
+		monitor:    monitor,
+		telemetry:  telemetry,
+		statkeeper: statkeeper,
+	}, nil
+}
+
+// Start consuming database events
+func (m *Monitor) Start() error {
+	if m == nil {
+		return nil
+	}
+
+	return m.monitor.Start()
+}
+
+// GetDatabaseStats returns a map of database stats stored in the following format:
+// [source, dest tuple, protocol, query] -> RequestStats object
+func (m *Monitor) GetDatabaseStats() map[Key]RequestStats {
+	if m == nil {
+		return nil
+	}
+
+	var stats map[Key]RequestStats
+	var delta segments.Telemetry
+	ok := m.monitor.Do(func() {
+		delta = m.telemetry.Reset()
+		delta.Report("database")
+		stats = m.statkeeper.GetAndResetAllStats()
+	})
+	if !ok {
+		return nil
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.telemetrySnapshot = &delta
+	return stats
+}
+
+// GetStats returns the telemetry of the last GetDatabaseStats call
+func (m *Monitor) GetStats() map[string]int64 {
+	if m == nil {
+		return nil
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if m.telemetrySnapshot == nil {
+		return nil
+	}
+
+	return map[string]int64{
+		"database_queries_processed":    m.telemetrySnapshot.Requests,
+		"database_queries_failed":       m.telemetrySnapshot.Errors,
+		"database_queries_dropped":      m.telemetrySnapshot.Dropped,
+		"database_obfuscation_failures": m.telemetrySnapshot.Unparsed,
+		"database_segments_lost":        m.telemetrySnapshot.SegmentsLost,
+		"database_connections_lost":     m.telemetrySnapshot.ConnsLost,
+	}
+}
+
+// Stop database monitoring
+func (m *Monitor) Stop() {
+	if m == nil {
+		return
+	}
+
+	m.monitor.Stop()
+}
@@ -0,0 +1,170 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package database
+
+import (
+	"encoding/binary"
+)
+
+// Commands of the MySQL client/server protocol.
+// See https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_command_phase.html
+const (
+	mysqlComSleep            = 0x00
+	mysqlComQuit             = 0x01
+	mysqlComQuery            = 0x03
+	mysqlComStmtPrepare      = 0x16
+	mysqlComStmtExecute      = 0x17
+	mysqlComStmtSendLongData = 0x18
+	mysqlComStmtClose        = 0x19
+	mysqlComEnd              = 0x20
+
+	mysqlProtocolVersion10 = 0x0a
+	mysqlClientProtocol41  = 0x200
+	mysqlClientSSL         = 0x800
+	mysqlERR               = 0xff
+	mysqlOK                = 0x00
+)
+
+type mysqlRequest struct {
+	command byte
+	query   string
+	started uint64
+}
+
+// mysqlConn decodes the MySQL client/server protocol. The client sends a single command at a time
+// and waits for its response, of which the first packet tells whether the command succeeded.
+// The queries are sent either with COM_QUERY, or prepared with COM_STMT_PREPARE and executed with
+// COM_STMT_EXECUTE.
+type mysqlConn struct {
+	tls bool
+	// pending is the command waiting for its response
+	pending *mysqlRequest
+	// queries of the prepared statements, by statement ID
+	statements map[uint32]string
+}
+
+func newMySQLConn() *mysqlConn {
+	return &mysqlConn{
+		statements: make(map[uint32]string),
+	}
+}
+
+func (c *mysqlConn) encrypted() bool {
+	return c.tls
+}
+
+func (c *mysqlConn) frame(fromClient bool, data []byte) (int, bool) {
+	if len(data) < 4 {
+		return 0, false
+	}
+	return 4 + int(uint32(data[0])|uint32(data[1])<<8|uint32(data[2])<<16), true
+}
+
+// starts reports whether data starts with a packet of the handshake, a command, or the first
+// packet of the response to the pending command.
+func (c *mysqlConn) starts(fromClient bool, data []byte) bool {
+	if len(data) < 5 || data[0] == 0 && data[1] == 0 && data[2] == 0 {
+		return false
+	}
+	seq, payload := data[3], data[4:]
+	if fromClient {
+		switch seq {
+		case 0:
+			return payload[0] > mysqlComSleep && payload[0] < mysqlComEnd
+		case 1:
+			return len(payload) >= 2 && binary.LittleEndian.Uint16(payload)&mysqlClientProtocol41 != 0
+		}
+		return false
+	}
+	switch seq {
+	case 0:
+		return payload[0] == mysqlProtocolVersion10
+	case 1:
+		return c.pending != nil
+	}
+	return false
+}
+
+// lost forgets the pending command: its response may be missed.
+func (c *mysqlConn) lost(fromClient bool) {
+	c.pending = nil
+}
+
+func (c *mysqlConn) message(fromClient bool, msg []byte, timestamp uint64, done completeFunc) {
+	seq := msg[3]
+	payload := msg[4:]
+	if len(payload) == 0 {
+		return
+	}
+	if fromClient {
+		c.clientMessage(seq, payload, timestamp)
+	} else {
+		c.serverMessage(seq, payload, timestamp, done)
+	}
+}
+
+func (c *mysqlConn) clientMessage(seq byte, payload []byte, timestamp uint64) {
+	if seq == 1 {
+		// the handshake response, or the SSL request sent instead before switching to TLS
+		if len(payload) >= 2 && binary.LittleEndian.Uint16(payload)&mysqlClientSSL != 0 {
+			c.tls = true
+		}
+		return
+	}
+	if seq != 0 {
+		return
+	}
+
+	req := &mysqlRequest{command: payload[0], started: timestamp}
+	body := payload[1:]
+	switch payload[0] {
+	case mysqlComQuery:
+		// with CLIENT_QUERY_ATTRIBUTES, the query is preceded by the number of parameters and
+		// the parameter set count, which is always 1
+		if len(body) >= 2 && body[0] == 0x00 && body[1] == 0x01 {
+			body = body[2:]
+		}
+		req.query = string(body)
+	case mysqlComStmtPrepare:
+		req.query = string(body)
+	case mysqlComStmtExecute:
+		if len(body) >= 4 {
+			req.query = c.statements[binary.LittleEndian.Uint32(body)]
+		}
+	case mysqlComStmtClose:
+		if len(body) >= 4 {
+			delete(c.statements, binary.LittleEndian.Uint32(body))
+		}
+		return
+	case mysqlComQuit, mysqlComStmtSendLongData:
+		// no response
+		return
+	}
+	c.pending = req
+}
+
+func (c *mysqlConn) serverMessage(seq byte, payload []byte, timestamp uint64, done completeFunc) {
+	req := c.pending
+	if req == nil || seq != 1 {
+		// the packets after the first one of a response
+		return
+	}
+	c.pending = nil
+
+	failed := payload[0] == mysqlERR
+	switch req.command {
+	case mysqlComStmtPrepare:
+		// the response starts with the statement ID
+		if !failed && payload[0] == mysqlOK && len(payload) >= 5 {
+			id := binary.LittleEndian.Uint32(payload[1:])
+			if _, ok := c.statements[id]; ok || len(c.statements) < maxStatementsPerConn {
+				c.statements[id] = req.query
+			}
+		}
+	case mysqlComQuery, mysqlComStmtExecute:
+		done(req.query, failed, req.started, timestamp)
+	}
+}
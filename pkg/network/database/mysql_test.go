@@ -0,0 +1,86 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mysqlCommand(command byte, args ...byte) []byte {
+	return mysqlPacket(0, append([]byte{command}, args...)...)
+}
+
+func mysqlOKPacket(seq byte) []byte {
+	return mysqlPacket(seq, mysqlOK, 0, 0, 2, 0, 0, 0)
+}
+
+func mysqlErrPacket(seq byte) []byte {
+	return mysqlPacket(seq, append([]byte{mysqlERR, 0x7a, 0x04, '#'}, "42S02Table doesn't exist"...)...)
+}
+
+func TestMySQLQuery(t *testing.T) {
+	d := newDecoder(100, 100)
+	c := newTestConn(ProtocolMySQL)
+
+	greeting := mysqlPacket(0, append([]byte{0x0a}, "8.0.28\x00"...)...)
+	handshake := mysqlPacket(1, 0x0d, 0xa2, 0x00, 0x00)
+	txs := decodeAll(d,
+		c.segment(false, 1000, greeting),
+		c.segment(true, 1100, handshake),
+		c.segment(false, 1200, mysqlOKPacket(2)),
+		c.segment(true, 2000, mysqlCommand(mysqlComQuery, []byte("SELECT * FROM users WHERE id = 1")...)),
+		// a result set: column count, column definition, EOF, row and EOF
+		c.segment(false, 3000, mysqlPacket(1, 1), mysqlPacket(2, 3, 'd', 'e', 'f'), mysqlPacket(3, 0xfe, 0, 0, 2, 0)),
+		c.segment(false, 3100, mysqlPacket(4, 1, '1'), mysqlPacket(5, 0xfe, 0, 0, 2, 0)),
+		c.segment(true, 4000, mysqlCommand(mysqlComQuery, append([]byte{0x00, 0x01}, "SELECT * FROM missing"...)...)),
+		c.segment(false, 6000, mysqlErrPacket(1)),
+		c.segment(true, 7000, mysqlCommand(0x0e)), // COM_PING
+		c.segment(false, 7100, mysqlOKPacket(1)),
+		c.segment(true, 8000, mysqlCommand(mysqlComQuit)),
+	)
+	require.Len(t, txs, 2)
+	assert.Equal(t, transaction{key: c.key("SELECT * FROM users WHERE id = 1"), latency: 1000}, txs[0])
+	assert.Equal(t, transaction{key: c.key("SELECT * FROM missing"), failed: true, latency: 2000}, txs[1])
+	assert.Nil(t, c.protocolConn(d).(*mysqlConn).pending)
+}
+
+func TestMySQLPreparedStatement(t *testing.T) {
+	d := newDecoder(100, 100)
+	c := newTestConn(ProtocolMySQL)
+
+	stmtID := []byte{7, 0, 0, 0}
+	txs := decodeAll(d,
+		c.segment(true, 1000, mysqlCommand(mysqlComStmtPrepare, []byte("SELECT * FROM users WHERE id = ?")...)),
+		c.segment(false, 1500, mysqlPacket(1, append(append([]byte{mysqlOK}, stmtID...), 1, 0, 1, 0, 0, 0)...)),
+		c.segment(true, 2000, mysqlCommand(mysqlComStmtExecute, append(stmtID, 0, 1, 0, 0, 0)...)),
+		c.segment(false, 3000, mysqlPacket(1, 1)),
+		c.segment(true, 4000, mysqlCommand(mysqlComStmtExecute, append(stmtID, 0, 1, 0, 0, 0)...)),
+		c.segment(false, 4500, mysqlErrPacket(1)),
+		c.segment(true, 5000, mysqlCommand(mysqlComStmtClose, stmtID...)),
+	)
+	require.Len(t, txs, 2)
+	assert.Equal(t, transaction{key: c.key("SELECT * FROM users WHERE id = ?"), latency: 1000}, txs[0])
+	assert.Equal(t, transaction{key: c.key("SELECT * FROM users WHERE id = ?"), failed: true, latency: 500}, txs[1])
+	assert.Empty(t, c.protocolConn(d).(*mysqlConn).statements)
+}
+
+func TestMySQLSSL(t *testing.T) {
+	d := newDecoder(100, 100)
+	c := newTestConn(ProtocolMySQL)
+
+	// the client sends an SSL request instead of the handshake response
+	txs := decodeAll(d,
+		c.segment(false, 1000, mysqlPacket(0, append([]byte{0x0a}, "8.0.28\x00"...)...)),
+		c.segment(true, 1100, mysqlPacket(1, 0x0d, 0xaa, 0x00, 0x00)),
+		c.segment(true, 1200, []byte{0x16, 0x03, 0x01, 0x02, 0x00}),
+	)
+	assert.Empty(t, txs)
+	assert.True(t, c.protocolConn(d).encrypted())
+	assert.Zero(t, d.Lost)
+}
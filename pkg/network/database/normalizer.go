@@ -0,0 +1,57 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package database
+
+import (
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/obfuscate"
+)
+
+// normalizer obfuscates the queries captured, so that the executions of a query with different
+// literals are aggregated together and no sensitive data is reported.
+type normalizer struct {
+	obfuscator *obfuscate.Obfuscator
+	// cache holds the obfuscated queries by query. The obfuscated queries are interned.
+	cache      map[string]string
+	maxEntries int
+}
+
+func newNormalizer(maxEntries int) *normalizer {
+	return &normalizer{
+		obfuscator: obfuscate.NewObfuscator(obfuscate.Config{}),
+		cache:      make(map[string]string),
+		maxEntries: maxEntries,
+	}
+}
+
+// normalize returns the obfuscated query. As only the beginning of the queries is captured, a
+// query truncated in the middle of a string literal is obfuscated up to that literal. It returns
+// an error if the query can't be tokenized.
+func (n *normalizer) normalize(query string) (string, error) {
+	if v, ok := n.cache[query]; ok {
+		return v, nil
+	}
+	oq, err := n.obfuscator.ObfuscateSQLString(query)
+	if err != nil {
+		i := strings.LastIndexAny(query, `'"`)
+		if i <= 0 {
+			return "", err
+		}
+		if oq, err = n.obfuscator.ObfuscateSQLString(query[:i]); err != nil {
+			return "", err
+		}
+	}
+	if len(n.cache) < n.maxEntries {
+		n.cache[query] = oq.Query
+	}
+	return oq.Query, nil
+}
+
+// reset empties the cache, which is done when the stats are reset.
+func (n *normalizer) reset() {
+	n.cache = make(map[string]string)
+}
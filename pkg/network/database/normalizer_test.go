@@ -0,0 +1,36 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizer(t *testing.T) {
+	n := newNormalizer(1)
+
+	query, err := n.normalize("SELECT * FROM users WHERE id = 42 AND name = 'bob'")
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM users WHERE id = ? AND name = ?", query)
+	query, err = n.normalize("SELECT * FROM users WHERE id = $1")
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM users WHERE id = ?", query)
+	assert.Len(t, n.cache, 1)
+
+	// a query truncated in a string literal is obfuscated up to the literal
+	query, err = n.normalize("SELECT * FROM users WHERE id = 1 AND name = 'bo")
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM users WHERE id = ? AND name =", query)
+
+	_, err = n.normalize("SELECT * FROM `us")
+	assert.Error(t, err)
+
+	n.reset()
+	assert.Empty(t, n.cache)
+}
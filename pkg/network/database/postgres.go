@@ -0,0 +1,272 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package database
+
+import (
+	"encoding/binary"
+)
+
+// Codes of the messages sent by PostgreSQL clients before the startup is complete.
+// See https://www.postgresql.org/docs/current/protocol-message-formats.html
+const (
+	postgresProtocolVersion3 = 196608
+	postgresCancelRequest    = 80877102
+	postgresSSLRequest       = 80877103
+	postgresGSSENCRequest    = 80877104
+)
+
+// postgresMaxMessageSize is the maximum size of the messages accepted by PostgreSQL.
+const postgresMaxMessageSize = 1<<30 - 1
+
+// postgresClientMessages and postgresServerMessages are the sizes of the messages sent after the
+// startup, by type, or 0 for the messages of variable size.
+var (
+	postgresClientMessages = map[byte]int32{
+		'B': 0, 'C': 0, 'd': 0, 'c': 4, 'f': 0, 'D': 0, 'E': 0, 'H': 4,
+		'F': 0, 'P': 0, 'p': 0, 'Q': 0, 'S': 4, 'X': 4,
+	}
+	postgresServerMessages = map[byte]int32{
+		'R': 0, 'K': 12, '2': 4, '3': 4, 'C': 0, 'd': 0, 'c': 4, 'G': 0,
+		'H': 0, 'W': 0, 'D': 0, 'I': 4, 'E': 0, 'V': 0, 'v': 0, 'n': 4,
+		'N': 0, 'A': 0, 't': 0, 'S': 0, '1': 4, 's': 4, 'Z': 5, 'T': 0,
+	}
+)
+
+type postgresRequestKind int
+
+const (
+	// a query sent with the simple query protocol, completed by ReadyForQuery
+	postgresSimpleQuery postgresRequestKind = iota
+	// the execution of a portal, completed by CommandComplete
+	postgresExecute
+	// a Sync message, after which the server stops skipping the messages after an error
+	postgresSync
+	// client messages missed, of which the responses are skipped until the next ReadyForQuery
+	postgresLost
+)
+
+type postgresRequest struct {
+	kind    postgresRequestKind
+	query   string
+	started uint64
+	failed  bool
+}
+
+// postgresConn decodes the PostgreSQL frontend/backend protocol, version 3. The queries are
+// sent either with a Query message, or with the extended query protocol: the query of a
+// Parse message is executed with Bind and Execute messages, and the server responds to each
+// Execute until an error occurs. The responses come in the order of the requests.
+type postgresConn struct {
+	// startupDone reports whether the client sent its startup message, or whether the
+	// connection was first seen after it
+	startupDone bool
+	// sslRequested reports whether the server is about to answer an SSLRequest or
+	// GSSENCRequest, with a single byte
+	sslRequested bool
+	tls          bool
+	// lastResponse is the time of the last server message decoded
+	lastResponse uint64
+
+	pending []*postgresRequest
+	// queries of the prepared statements and portals, by name
+	statements map[string]string
+	portals    map[string]string
+}
+
+func newPostgresConn() *postgresConn {
+	return &postgresConn{
+		statements: make(map[string]string),
+		portals:    make(map[string]string),
+	}
+}
+
+func (c *postgresConn) encrypted() bool {
+	return c.tls
+}
+
+func (c *postgresConn) frame(fromClient bool, data []byte) (int, bool) {
+	if !fromClient && c.sslRequested {
+		return 1, true
+	}
+	if fromClient && !c.startupDone {
+		// the messages sent before the startup is complete have no type
+		if len(data) >= 8 {
+			switch binary.BigEndian.Uint32(data[4:]) {
+			case postgresProtocolVersion3, postgresCancelRequest, postgresSSLRequest, postgresGSSENCRequest:
+				return int(int32(binary.BigEndian.Uint32(data))), true
+			}
+		}
+		// the connection was first seen after its startup
+		c.startupDone = true
+	}
+	if len(data) < 5 {
+		return 0, false
+	}
+	size := int32(binary.BigEndian.Uint32(data[1:]))
+	if size < 4 {
+		return 0, false
+	}
+	return 1 + int(size), true
+}
+
+func (c *postgresConn) starts(fromClient bool, data []byte) bool {
+	if !fromClient && c.sslRequested {
+		return len(data) == 1
+	}
+	if fromClient && !c.startupDone && len(data) >= 8 {
+		switch binary.BigEndian.Uint32(data[4:]) {
+		case postgresProtocolVersion3, postgresCancelRequest, postgresSSLRequest, postgresGSSENCRequest:
+			return true
+		}
+	}
+	if len(data) < 5 {
+		return false
+	}
+	messages := postgresServerMessages
+	if fromClient {
+		messages = postgresClientMessages
+	}
+	fixed, ok := messages[data[0]]
+	if !ok {
+		return false
+	}
+	size := int32(binary.BigEndian.Uint32(data[1:]))
+	if fixed != 0 {
+		return size == fixed
+	}
+	return size >= 4 && size <= postgresMaxMessageSize
+}
+
+// lost handles the messages missed in a direction. The responses to the client messages
+// missed are skipped until the next ReadyForQuery. The pending requests sent before the last
+// server message decoded are answered, at least partly, by the server messages missed.
+func (c *postgresConn) lost(fromClient bool) {
+	c.startupDone = true
+	c.sslRequested = false
+	if fromClient {
+		c.push(&postgresRequest{kind: postgresLost})
+		return
+	}
+	for len(c.pending) > 0 && c.pending[0].started <= c.lastResponse {
+		c.pending = c.pending[1:]
+	}
+}
+
+func (c *postgresConn) message(fromClient bool, msg []byte, timestamp uint64, done completeFunc) {
+	if fromClient {
+		c.clientMessage(msg, timestamp)
+	} else {
+		c.serverMessage(msg, timestamp, done)
+	}
+}
+
+func (c *postgresConn) clientMessage(msg []byte, timestamp uint64) {
+	if !c.startupDone {
+		switch binary.BigEndian.Uint32(msg[4:]) {
+		case postgresProtocolVersion3:
+			c.startupDone = true
+		case postgresSSLRequest, postgresGSSENCRequest:
+			c.sslRequested = true
+		}
+		return
+	}
+
+	body := msg[5:]
+	switch msg[0] {
+	case 'Q':
+		query, _ := cstring(body)
+		c.push(&postgresRequest{kind: postgresSimpleQuery, query: query, started: timestamp})
+	case 'P':
+		name, rest := cstring(body)
+		query, _ := cstring(rest)
+		if _, ok := c.statements[name]; ok || len(c.statements) < maxStatementsPerConn {
+			c.statements[name] = query
+		}
+	case 'B':
+		portal, rest := cstring(body)
+		statement, _ := cstring(rest)
+		if _, ok := c.portals[portal]; ok || len(c.portals) < maxStatementsPerConn {
+			c.portals[portal] = c.statements[statement]
+		}
+	case 'E':
+		portal, _ := cstring(body)
+		c.push(&postgresRequest{kind: postgresExecute, query: c.portals[portal], started: timestamp})
+	case 'S':
+		c.push(&postgresRequest{kind: postgresSync, started: timestamp})
+	case 'C':
+		// Close of a statement ('S') or a portal ('P')
+		if len(body) > 0 {
+			name, _ := cstring(body[1:])
+			if body[0] == 'S' {
+				delete(c.statements, name)
+			} else {
+				delete(c.portals, name)
+			}
+		}
+	}
+}
+
+func (c *postgresConn) serverMessage(msg []byte, timestamp uint64, done completeFunc) {
+	if c.sslRequested {
+		c.sslRequested = false
+		c.tls = msg[0] == 'S' || msg[0] == 'G'
+		return
+	}
+	c.lastResponse = timestamp
+
+	switch msg[0] {
+	case 'C', 'I', 's':
+		// CommandComplete, EmptyQueryResponse, PortalSuspended
+		if req := c.front(); req != nil && req.kind == postgresExecute {
+			c.pending = c.pending[1:]
+			done(req.query, false, req.started, timestamp)
+		}
+	case 'E':
+		// ErrorResponse
+		req := c.front()
+		if req == nil {
+			return
+		}
+		if req.kind == postgresSimpleQuery {
+			req.failed = true
+			return
+		}
+		if req.kind == postgresExecute {
+			c.pending = c.pending[1:]
+			done(req.query, true, req.started, timestamp)
+		}
+		// the server skips the messages until the next Sync
+		for len(c.pending) > 0 && c.pending[0].kind == postgresExecute {
+			c.pending = c.pending[1:]
+		}
+	case 'Z':
+		// ReadyForQuery: the end of a simple query, or the response to a Sync
+		for len(c.pending) > 0 {
+			req := c.pending[0]
+			c.pending = c.pending[1:]
+			if req.kind == postgresSimpleQuery {
+				done(req.query, req.failed, req.started, timestamp)
+				return
+			}
+			if req.kind == postgresSync || req.kind == postgresLost {
+				return
+			}
+		}
+	}
+}
+
+func (c *postgresConn) push(req *postgresRequest) {
+	if len(c.pending) < maxPendingPerConn {
+		c.pending = append(c.pending, req)
+	}
+}
+
+func (c *postgresConn) front() *postgresRequest {
+	if len(c.pending) == 0 {
+		return nil
+	}
+	return c.pending[0]
+}
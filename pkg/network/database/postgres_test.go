@@ -0,0 +1,134 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pgReady() []byte {
+	return pgMessage('Z', byte('I'))
+}
+
+func pgError() []byte {
+	return pgMessage('E', byte('S'), "ERROR", byte('C'), "42P01", byte(0))
+}
+
+func TestPostgresSimpleQuery(t *testing.T) {
+	d := newDecoder(100, 100)
+	c := newTestConn(ProtocolPostgres)
+
+	txs := decodeAll(d,
+		c.segment(true, 1000, pgMessage(0, int32(postgresProtocolVersion3), "user", "postgres", byte(0))),
+		c.segment(false, 1100, pgMessage('R', int32(0)), pgMessage('S', "server_version", "14.2"), pgReady()),
+		c.segment(true, 2000, pgMessage('Q', "SELECT * FROM users WHERE id = 1")),
+		c.segment(false, 3000, pgMessage('T', int16(0)), pgMessage('D', int16(0)), pgMessage('C', "SELECT 1"), pgReady()),
+		c.segment(true, 4000, pgMessage('Q', "SELECT * FROM missing")),
+		c.segment(false, 6000, pgError(), pgReady()),
+		c.segment(true, 7000, pgMessage('Q', "")),
+		c.segment(false, 7500, pgMessage('I'), pgReady()),
+	)
+	require.Len(t, txs, 2)
+	assert.Equal(t, transaction{key: c.key("SELECT * FROM users WHERE id = 1"), latency: 1000}, txs[0])
+	assert.Equal(t, transaction{key: c.key("SELECT * FROM missing"), failed: true, latency: 2000}, txs[1])
+	assert.Empty(t, c.protocolConn(d).(*postgresConn).pending)
+}
+
+func TestPostgresExtendedQuery(t *testing.T) {
+	d := newDecoder(100, 100)
+	c := newTestConn(ProtocolPostgres)
+
+	txs := decodeAll(d,
+		// a named statement executed twice, and an unnamed one, in a pipeline
+		c.segment(true, 1000,
+			pgMessage('P', "s1", "SELECT * FROM users WHERE id = $1", int16(0)),
+			pgMessage('B', "", "s1", int16(0), int16(0), int16(0)),
+			pgMessage('E', "", int32(0)),
+			pgMessage('B', "p1", "s1", int16(0), int16(0), int16(0)),
+			pgMessage('E', "p1", int32(0)),
+			pgMessage('S'),
+			pgMessage('P', "", "UPDATE users SET name = $1", int16(0)),
+			pgMessage('B', "", "", int16(0), int16(0), int16(0)),
+			pgMessage('E', "", int32(0)),
+			pgMessage('S'),
+		),
+		c.segment(false, 2000,
+			pgMessage('1'), pgMessage('2'), pgMessage('C', "SELECT 1"),
+			pgMessage('2'), pgMessage('C', "SELECT 1"), pgReady(),
+		),
+		c.segment(false, 3000, pgMessage('1'), pgMessage('2'), pgError(), pgReady()),
+	)
+	require.Len(t, txs, 3)
+	assert.Equal(t, transaction{key: c.key("SELECT * FROM users WHERE id = $1"), latency: 1000}, txs[0])
+	assert.Equal(t, transaction{key: c.key("SELECT * FROM users WHERE id = $1"), latency: 1000}, txs[1])
+	assert.Equal(t, transaction{key: c.key("UPDATE users SET name = $1"), failed: true, latency: 2000}, txs[2])
+	assert.Empty(t, c.protocolConn(d).(*postgresConn).pending)
+}
+
+func TestPostgresErrorSkipsUntilSync(t *testing.T) {
+	d := newDecoder(100, 100)
+	c := newTestConn(ProtocolPostgres)
+
+	// after an error, the server skips the Execute messages until the next Sync
+	txs := decodeAll(d,
+		c.segment(true, 1000,
+			pgMessage('P', "", "INSERT INTO t VALUES ($1)", int16(0)),
+			pgMessage('B', "", "", int16(0), int16(0), int16(0)),
+			pgMessage('E', "", int32(0)),
+			pgMessage('E', "", int32(0)),
+			pgMessage('S'),
+			pgMessage('Q', "COMMIT"),
+		),
+		c.segment(false, 2000, pgError(), pgReady(), pgMessage('C', "COMMIT"), pgReady()),
+	)
+	require.Len(t, txs, 2)
+	assert.True(t, txs[0].failed)
+	assert.Equal(t, transaction{key: c.key("COMMIT"), latency: 1000}, txs[1])
+}
+
+func TestPostgresSSL(t *testing.T) {
+	for response, encrypted := range map[byte]bool{'S': true, 'N': false} {
+		d := newDecoder(100, 100)
+		c := newTestConn(ProtocolPostgres)
+
+		txs := decodeAll(d,
+			c.segment(true, 1000, pgMessage(0, int32(postgresSSLRequest))),
+			c.segment(false, 1100, []byte{response}),
+		)
+		assert.Empty(t, txs)
+		assert.Equal(t, encrypted, c.protocolConn(d).encrypted())
+		if encrypted {
+			continue
+		}
+
+		txs = decodeAll(d,
+			c.segment(true, 1200, pgMessage(0, int32(postgresProtocolVersion3), "user", "postgres", byte(0))),
+			c.segment(false, 1300, pgMessage('R', int32(0)), pgReady()),
+			c.segment(true, 2000, pgMessage('Q', "SELECT 1")),
+			c.segment(false, 3000, pgMessage('C', "SELECT 1"), pgReady()),
+		)
+		require.Len(t, txs, 1)
+		assert.Equal(t, "SELECT 1", txs[0].key.Query)
+	}
+}
+
+func TestPostgresClose(t *testing.T) {
+	d := newDecoder(100, 100)
+	c := newTestConn(ProtocolPostgres)
+
+	decodeAll(d, c.segment(true, 1000,
+		pgMessage('P', "s1", "SELECT 1", int16(0)),
+		pgMessage('B', "p1", "s1", int16(0), int16(0), int16(0)),
+		pgMessage('C', byte('S'), "s1"),
+		pgMessage('C', byte('P'), "p1"),
+	))
+	pc := c.protocolConn(d).(*postgresConn)
+	assert.Empty(t, pc.statements)
+	assert.Empty(t, pc.portals)
+}
@@ -0,0 +1,94 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux_bpf
+// +build linux_bpf
+
+package database
+
+import (
+	"sync/atomic"
+
+	"github.com/DataDog/datadog-agent/pkg/network/config"
+	"github.com/DataDog/datadog-agent/pkg/network/segments"
+)
+
+// maxBufferedSegments is the maximum number of database segments buffered until they are decoded
+const maxBufferedSegments = 50000
+
+type statKeeper struct {
+	stats      map[Key]RequestStats
+	maxEntries int
+	telemetry  *segments.Telemetry
+	decoder    *decoder
+
+	// normalizer holds the obfuscated queries, interned
+	// this is rotated with the stats map
+	normalizer *normalizer
+}
+
+func newStatKeeper(c *config.Config, telemetry *segments.Telemetry) *statKeeper {
+	return &statKeeper{
+		stats:      make(map[Key]RequestStats),
+		maxEntries: c.MaxDatabaseStatsBuffered,
+		telemetry:  telemetry,
+		decoder:    newDecoder(int(c.MaxTrackedConnections), maxBufferedSegments),
+		normalizer: newNormalizer(c.MaxDatabaseStatsBuffered),
+	}
+}
+
+// Process buffers the database segments until they are decoded by Flush
+func (s *statKeeper) Process(segs []segments.Segment) {
+	for _, seg := range segs {
+		s.decoder.Add(seg)
+	}
+}
+
+// Flush decodes the database segments captured before the monotonic timestamp before,
+// and aggregates the queries they complete
+func (s *statKeeper) Flush(before uint64) {
+	s.decoder.Flush(before, func(tx interface{}) {
+		s.add(tx.(transaction))
+	})
+	s.telemetry.Collect(s.decoder.Decoder)
+	atomic.StoreInt64(&s.telemetry.Aggregations, int64(len(s.stats)))
+}
+
+// SegmentsLost counts the database segments lost because they weren't read fast enough
+func (s *statKeeper) SegmentsLost(n int) {
+	atomic.AddInt64(&s.telemetry.SegmentsLost, int64(n))
+}
+
+func (s *statKeeper) GetAndResetAllStats() map[Key]RequestStats {
+	ret := s.stats // No deep copy needed since `s.stats` gets reset
+	s.stats = make(map[Key]RequestStats)
+	s.normalizer.reset()
+	return ret
+}
+
+func (s *statKeeper) add(tx transaction) {
+	atomic.AddInt64(&s.telemetry.Requests, 1)
+	if tx.failed {
+		atomic.AddInt64(&s.telemetry.Errors, 1)
+	}
+
+	query, err := s.normalizer.normalize(tx.key.Query)
+	if err != nil {
+		// the query is not reported, as it could hold sensitive data
+		atomic.AddInt64(&s.telemetry.Unparsed, 1)
+		return
+	}
+
+	key := tx.key
+	key.Query = query
+	stats, ok := s.stats[key]
+	if !ok && len(s.stats) >= s.maxEntries {
+		atomic.AddInt64(&s.telemetry.Dropped, 1)
+		return
+	}
+
+	stats.AddRequest(tx.failed, tx.latency)
+	s.stats[key] = stats
+}
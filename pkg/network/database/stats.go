@@ -0,0 +1,105 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package database
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/network/latency"
+	"github.com/DataDog/datadog-agent/pkg/network/segments"
+	"github.com/DataDog/datadog-agent/pkg/process/util"
+)
+
+// Protocol is the type used to represent the database protocols monitored
+type Protocol int
+
+const (
+	// ProtocolUnknown represents an unknown protocol
+	ProtocolUnknown Protocol = iota
+	// ProtocolPostgres represents the PostgreSQL frontend/backend protocol
+	ProtocolPostgres
+	// ProtocolMySQL represents the MySQL client/server protocol
+	ProtocolMySQL
+)
+
+// String returns a string representing the database protocol
+func (p Protocol) String() string {
+	switch p {
+	case ProtocolPostgres:
+		return "postgres"
+	case ProtocolMySQL:
+		return "mysql"
+	default:
+		return "unknown"
+	}
+}
+
+// Key is an identifier for a group of database queries
+type Key struct {
+	SrcIPHigh uint64
+	SrcIPLow  uint64
+	SrcPort   uint16
+
+	DstIPHigh uint64
+	DstIPLow  uint64
+	DstPort   uint16
+
+	Protocol Protocol
+	// Query is the obfuscated query
+	Query string
+}
+
+// NewKey generates a new Key
+func NewKey(saddr, daddr util.Address, sport, dport uint16, protocol Protocol, query string) Key {
+	saddrl, saddrh := util.ToLowHigh(saddr)
+	daddrl, daddrh := util.ToLowHigh(daddr)
+	return Key{
+		SrcIPHigh: saddrh,
+		SrcIPLow:  saddrl,
+		SrcPort:   sport,
+		DstIPHigh: daddrh,
+		DstIPLow:  daddrl,
+		DstPort:   dport,
+		Protocol:  protocol,
+		Query:     query,
+	}
+}
+
+// connKey returns the Key of the connection k using protocol, without query.
+func connKey(k segments.ConnKey, protocol Protocol) Key {
+	return Key{
+		SrcIPHigh: k.SrcIPHigh,
+		SrcIPLow:  k.SrcIPLow,
+		SrcPort:   k.SrcPort,
+		DstIPHigh: k.DstIPHigh,
+		DstIPLow:  k.DstIPLow,
+		DstPort:   k.DstPort,
+		Protocol:  protocol,
+	}
+}
+
+// RequestStats stores stats for the executions of a particular query
+type RequestStats struct {
+	// Count is the number of executions, including the ones which failed, and
+	// Latencies their latencies
+	latency.Stats
+	// ErrorCount is the number of executions which failed
+	ErrorCount int
+}
+
+// CombineWith merges the data in 2 RequestStats objects
+// newStats is kept as it is, while the method receiver gets mutated
+func (r *RequestStats) CombineWith(newStats RequestStats) {
+	r.ErrorCount += newStats.ErrorCount
+	r.Stats.CombineWith(newStats.Stats)
+}
+
+// AddRequest takes information about the execution of a query and adds it to the request stats
+func (r *RequestStats) AddRequest(failed bool, latency float64) {
+	if failed {
+		r.ErrorCount++
+	}
+
+	r.Add(latency)
+}
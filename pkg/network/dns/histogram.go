@@ -0,0 +1,47 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package dns
+
+// LatencyBucketBounds are the upper bounds, in microseconds, of the buckets of the latency histograms.
+// The last bucket of a histogram holds the latencies above the last bound.
+var LatencyBucketBounds = [...]uint64{
+	100, 250, 500,
+	1000, 2500, 5000,
+	10000, 25000, 50000,
+	100000, 250000, 500000,
+	1000000,
+}
+
+// LatencyHistogram counts DNS response latencies by bucket of LatencyBucketBounds.
+// It is a value type, so that it can be stored and merged along with the other fields of Stats.
+type LatencyHistogram [len(LatencyBucketBounds) + 1]uint32
+
+// Add adds a latency in microseconds to the histogram
+func (h *LatencyHistogram) Add(latency uint64) {
+	for i, bound := range LatencyBucketBounds {
+		if latency <= bound {
+			h[i]++
+			return
+		}
+	}
+	h[len(LatencyBucketBounds)]++
+}
+
+// Merge adds the counts of another histogram to the histogram
+func (h *LatencyHistogram) Merge(other LatencyHistogram) {
+	for i, count := range other {
+		h[i] += count
+	}
+}
+
+// Count returns the number of latencies in the histogram
+func (h *LatencyHistogram) Count() uint32 {
+	var count uint32
+	for _, c := range h {
+		count += c
+	}
+	return count
+}
@@ -0,0 +1,43 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package dns
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatencyHistogram(t *testing.T) {
+	var h LatencyHistogram
+	h.Add(0)
+	h.Add(100)
+	h.Add(101)
+	h.Add(30000)
+	h.Add(1000000)
+	h.Add(5000000)
+
+	assert.Equal(t, uint32(2), h[0])
+	assert.Equal(t, uint32(1), h[1])
+	assert.Equal(t, uint32(1), h[8])
+	assert.Equal(t, uint32(1), h[len(LatencyBucketBounds)-1])
+	assert.Equal(t, uint32(1), h[len(LatencyBucketBounds)])
+	assert.Equal(t, uint32(6), h.Count())
+
+	var other LatencyHistogram
+	other.Add(50)
+	h.Merge(other)
+	assert.Equal(t, uint32(3), h[0])
+	assert.Equal(t, uint32(7), h.Count())
+}
+
+func TestRcodeName(t *testing.T) {
+	assert.Equal(t, "noerror", RcodeName(RcodeNoError))
+	assert.Equal(t, "servfail", RcodeName(RcodeServFail))
+	assert.Equal(t, "nxdomain", RcodeName(RcodeNXDomain))
+	assert.Equal(t, "refused", RcodeName(RcodeRefused))
+	assert.Equal(t, "23", RcodeName(23))
+}
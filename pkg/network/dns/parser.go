@@ -70,6 +70,10 @@ type dnsParser struct {
 	collectDNSStats    bool
 	collectDNSDomains  bool
 	recordedQueryTypes map[layers.DNSType]struct{}
+
+	tcpReassembler *tcpDNSReassembler
+	// pendingMessages are the DNS messages completed by the last TCP segment, after the first one
+	pendingMessages [][]byte
 }
 
 func newDNSParser(layerType gopacket.LayerType, cfg *config.Config) *dnsParser {
@@ -106,16 +110,28 @@ func newDNSParser(layerType gopacket.LayerType, cfg *config.Config) *dnsParser {
 		collectDNSStats:    cfg.CollectDNSStats,
 		collectDNSDomains:  cfg.CollectDNSDomains,
 		recordedQueryTypes: queryTypes,
+		tcpReassembler:     newTCPDNSReassembler(),
 	}
 }
 
 func (p *dnsParser) ParseInto(data []byte, t *translation, pktInfo *dnsPacketInfo) error {
+	p.pendingMessages = nil
 	err := p.decoder.DecodeLayers(data, &p.layers)
 
 	if p.decoder.Truncated {
 		return errTruncated
 	}
 
+	// The TCP segments which do not hold exactly one DNS message are reassembled
+	if _, unsupported := err.(gopacket.UnsupportedLayerType); unsupported && p.layers[len(p.layers)-1] == layers.LayerTypeTCP {
+		messages := p.reassembleTCP()
+		if len(messages) == 0 {
+			return errSkippedPayload
+		}
+		p.pendingMessages = messages[1:]
+		return p.parseMessageInto(messages[0], t, pktInfo)
+	}
+
 	if err != nil {
 		return err
 	}
@@ -125,6 +141,46 @@ func (p *dnsParser) ParseInto(data []byte, t *translation, pktInfo *dnsPacketInf
 		return errSkippedPayload
 	}
 
+	return p.parseDNSInto(t, pktInfo)
+}
+
+// HasPendingMessage returns whether the last TCP segment parsed completed other DNS messages
+func (p *dnsParser) HasPendingMessage() bool {
+	return len(p.pendingMessages) > 0
+}
+
+// ParseNextInto parses the next DNS message completed by the last TCP segment parsed
+func (p *dnsParser) ParseNextInto(t *translation, pktInfo *dnsPacketInfo) error {
+	msg := p.pendingMessages[0]
+	p.pendingMessages = p.pendingMessages[1:]
+	return p.parseMessageInto(msg, t, pktInfo)
+}
+
+func (p *dnsParser) reassembleTCP() [][]byte {
+	key := tcpStreamKey{transport: p.tcpPayload.TransportFlow()}
+	for _, layer := range p.layers {
+		switch layer {
+		case layers.LayerTypeIPv4:
+			key.network = p.ipv4Payload.NetworkFlow()
+		case layers.LayerTypeIPv6:
+			key.network = p.ipv6Payload.NetworkFlow()
+		}
+	}
+	return p.tcpReassembler.add(key, p.tcpPayload.Seq, p.tcpPayload.TCP.LayerPayload(), p.tcpPayload.FIN || p.tcpPayload.RST)
+}
+
+// parseMessageInto parses a DNS message reassembled from TCP segments, the other layers being the ones of the last segment
+func (p *dnsParser) parseMessageInto(msg []byte, t *translation, pktInfo *dnsPacketInfo) error {
+	if err := p.dnsPayload.DecodeFromBytes(msg, gopacket.NilDecodeFeedback); err != nil {
+		return err
+	}
+	if p.layers[len(p.layers)-1] != layers.LayerTypeDNS {
+		p.layers = append(p.layers, layers.LayerTypeDNS)
+	}
+	return p.parseDNSInto(t, pktInfo)
+}
+
+func (p *dnsParser) parseDNSInto(t *translation, pktInfo *dnsPacketInfo) error {
 	if err := p.parseAnswerInto(p.dnsPayload, t, pktInfo); err != nil {
 		return err
 	}
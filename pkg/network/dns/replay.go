@@ -0,0 +1,95 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build windows || linux_bpf
+// +build windows linux_bpf
+
+package dns
+
+import (
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/network/config"
+	"github.com/DataDog/datadog-agent/pkg/network/replay"
+)
+
+// ReplayResult holds what the DNS snooper computed out of a capture
+type ReplayResult struct {
+	Stats StatsByKeyByNameByType
+	// Telemetry holds the counters reported by the snooper, such as the decoding errors
+	Telemetry map[string]int64
+}
+
+// ReplayPcap feeds the packets of a pcap or pcapng file to the parser and stat keeper of the DNS snooper.
+// The packets are filtered as the eBPF socket filter does, and the queries are expired based on the
+// capture timestamps rather than on the wall clock. The queries sent during the last DNSTimeout of the
+// capture and left unanswered are ignored, since they may be answered after the end of the capture.
+func ReplayPcap(cfg *config.Config, path string) (*ReplayResult, error) {
+	source, err := replay.NewSource(path)
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
+
+	snooper := newReplaySnooper(cfg, source)
+	defer snooper.cache.Close()
+
+	var (
+		pkt        replay.Packet
+		lastExpiry time.Time
+		last       time.Time
+	)
+	err = source.VisitPackets(nil, func(data []byte, ts time.Time) error {
+		if !replay.DecodePacket(data, &pkt) {
+			return nil
+		}
+		if pkt.SPort != 53 && (!cfg.CollectDNSStats || pkt.DPort != 53) {
+			return nil
+		}
+
+		if snooper.statKeeper != nil {
+			if lastExpiry.IsZero() {
+				lastExpiry = ts
+			}
+			if ts.Sub(lastExpiry) >= cfg.DNSTimeout {
+				snooper.statKeeper.removeExpiredStates(ts.Add(-cfg.DNSTimeout))
+				lastExpiry = ts
+			}
+		}
+		last = ts
+		return snooper.processPacket(data, ts)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ReplayResult{}
+	if snooper.statKeeper != nil {
+		snooper.statKeeper.removeExpiredStates(last.Add(-cfg.DNSTimeout))
+		result.Stats = snooper.statKeeper.GetAndResetAllStats()
+	}
+	result.Telemetry = snooper.GetStats()
+	delete(result.Telemetry, "timestamp_micro_secs")
+	return result, nil
+}
+
+// newReplaySnooper returns a socketFilterSnooper which does not poll its source,
+// the packets being processed synchronously by the caller
+func newReplaySnooper(cfg *config.Config, source packetSource) *socketFilterSnooper {
+	var statKeeper *dnsStatKeeper
+	if cfg.CollectDNSStats {
+		statKeeper = newUnscheduledDNSStatkeeper(cfg.DNSTimeout, cfg.MaxDNSStats)
+	}
+
+	return &socketFilterSnooper{
+		source:          source,
+		parser:          newDNSParser(source.PacketType(), cfg),
+		cache:           newReverseDNSCache(dnsCacheSize, dnsCacheExpirationPeriod),
+		statKeeper:      statKeeper,
+		translation:     new(translation),
+		exit:            make(chan struct{}),
+		collectLocalDNS: cfg.CollectLocalDNS,
+	}
+}
@@ -0,0 +1,120 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build windows || linux_bpf
+// +build windows linux_bpf
+
+package dns
+
+import (
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go4.org/intern"
+
+	"github.com/DataDog/datadog-agent/pkg/network/replay/testutil"
+	"github.com/DataDog/datadog-agent/pkg/process/util"
+)
+
+func dnsMessage(t *testing.T, id uint16, name string, response bool, rcode layers.DNSResponseCode) []byte {
+	msg := &layers.DNS{
+		ID:           id,
+		QR:           response,
+		RD:           true,
+		ResponseCode: rcode,
+		Questions:    []layers.DNSQuestion{{Name: []byte(name), Type: layers.DNSTypeA, Class: layers.DNSClassIN}},
+	}
+	if response && rcode == layers.DNSResponseCodeNoErr {
+		msg.Answers = []layers.DNSResourceRecord{{
+			Name:  []byte(name),
+			Type:  layers.DNSTypeA,
+			Class: layers.DNSClassIN,
+			TTL:   60,
+			IP:    net.ParseIP("10.1.1.1").To4(),
+		}}
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	require.NoError(t, msg.SerializeTo(buf, gopacket.SerializeOptions{FixLengths: true}))
+	return buf.Bytes()
+}
+
+func TestReplayPcap(t *testing.T) {
+	start := time.Unix(1600000000, 0)
+	query := func(offset time.Duration, id uint16, name string) testutil.Packet {
+		return testutil.UDPPacket(start.Add(offset), "10.0.0.1", "8.8.8.8", 40000, 53, dnsMessage(t, id, name, false, 0))
+	}
+	response := func(offset time.Duration, id uint16, name string, rcode layers.DNSResponseCode) testutil.Packet {
+		return testutil.UDPPacket(start.Add(offset), "8.8.8.8", "10.0.0.1", 53, 40000, dnsMessage(t, id, name, true, rcode))
+	}
+
+	path := testutil.WritePcap(t,
+		query(0, 1, "example.com"),
+		query(time.Millisecond, 2, "unanswered.com"),
+		response(5*time.Millisecond, 1, "example.com", layers.DNSResponseCodeNoErr),
+		query(10*time.Second, 3, "nxdomain.com"),
+		response(10*time.Second+2*time.Millisecond, 3, "nxdomain.com", layers.DNSResponseCodeNXDomain),
+		// answered after the end of the capture
+		query(10*time.Second+3*time.Millisecond, 4, "late.com"),
+	)
+
+	cfg := testConfig()
+	cfg.CollectDNSStats = true
+	cfg.CollectDNSDomains = true
+	cfg.DNSTimeout = time.Second
+
+	result, err := ReplayPcap(cfg, path)
+	require.NoError(t, err)
+
+	key := Key{
+		ServerIP:   util.AddressFromString("8.8.8.8"),
+		ClientIP:   util.AddressFromString("10.0.0.1"),
+		ClientPort: 40000,
+		Protocol:   syscall.IPPROTO_UDP,
+	}
+	require.Len(t, result.Stats, 1)
+	byDomain := result.Stats[key]
+	require.Len(t, byDomain, 3)
+
+	success := byDomain[intern.GetByString("example.com")][QueryType(layers.DNSTypeA)]
+	assert.Equal(t, map[uint32]uint32{RcodeNoError: 1}, success.CountByRcode)
+	assert.Equal(t, uint64(5000), success.SuccessLatencySum)
+	assert.Equal(t, uint32(1), success.SuccessLatencies.Count())
+
+	timeout := byDomain[intern.GetByString("unanswered.com")][QueryType(layers.DNSTypeA)]
+	assert.Equal(t, uint32(1), timeout.Timeouts)
+
+	failure := byDomain[intern.GetByString("nxdomain.com")][QueryType(layers.DNSTypeA)]
+	assert.Equal(t, map[uint32]uint32{RcodeNXDomain: 1}, failure.CountByRcode)
+	assert.Equal(t, uint64(2000), failure.FailureLatencySum)
+
+	assert.Equal(t, int64(6), result.Telemetry["packets_read"])
+	assert.Equal(t, int64(4), result.Telemetry["queries"])
+	assert.Equal(t, int64(1), result.Telemetry["successes"])
+	assert.Equal(t, int64(1), result.Telemetry["errors"])
+	assert.Equal(t, int64(0), result.Telemetry["decoding_errors"])
+}
+
+func TestReplayPcapSkipsOtherTraffic(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	path := testutil.WritePcap(t,
+		testutil.UDPPacket(now, "10.0.0.1", "10.0.0.2", 40000, 5353, []byte("not a dns payload")),
+		testutil.TCPPacket(now, "10.0.0.1", "10.0.0.2", 40000, 80, 1, 0x18, []byte("GET / HTTP/1.1\r\n")),
+	)
+
+	cfg := testConfig()
+	cfg.CollectDNSStats = true
+	result, err := ReplayPcap(cfg, path)
+	require.NoError(t, err)
+	assert.Empty(t, result.Stats)
+	assert.Equal(t, int64(0), result.Telemetry["decoding_errors"])
+	assert.Equal(t, int64(0), result.Telemetry["queries"])
+}
@@ -131,6 +131,7 @@ func (s *socketFilterSnooper) GetStats() map[string]int64 {
 
 	stats["decoding_errors"] = atomic.LoadInt64(&s.decodingErrors)
 	stats["truncated_packets"] = atomic.LoadInt64(&s.truncatedPkts)
+	stats["tcp_dropped_segments"] = atomic.LoadInt64(&s.parser.tcpReassembler.dropped)
 	stats["timestamp_micro_secs"] = time.Now().UnixNano() / 1000
 	stats["queries"] = atomic.LoadInt64(&s.queries)
 	stats["successes"] = atomic.LoadInt64(&s.successes)
@@ -168,8 +169,20 @@ func (s *socketFilterSnooper) Close() {
 func (s *socketFilterSnooper) processPacket(data []byte, ts time.Time) error {
 	t := s.getCachedTranslation()
 	pktInfo := dnsPacketInfo{}
+	s.processDNSMessage(s.parser.ParseInto(data, t, &pktInfo), t, pktInfo, ts)
 
-	if err := s.parser.ParseInto(data, t, &pktInfo); err != nil {
+	// A TCP segment can complete several DNS messages
+	for s.parser.HasPendingMessage() {
+		t = s.getCachedTranslation()
+		pktInfo = dnsPacketInfo{}
+		s.processDNSMessage(s.parser.ParseNextInto(t, &pktInfo), t, pktInfo, ts)
+	}
+	return nil
+}
+
+// processDNSMessage records a DNS message parsed with the given error
+func (s *socketFilterSnooper) processDNSMessage(err error, t *translation, pktInfo dnsPacketInfo, ts time.Time) {
+	if err != nil {
 		switch err {
 		case errSkippedPayload: // no need to count or log cases where the packet is valid but has no relevant content
 		case errTruncated:
@@ -177,7 +190,7 @@ func (s *socketFilterSnooper) processPacket(data []byte, ts time.Time) error {
 		default:
 			atomic.AddInt64(&s.decodingErrors, 1)
 		}
-		return nil
+		return
 	}
 
 	if s.statKeeper != nil && (s.collectLocalDNS || !pktInfo.key.ServerIP.IsLoopback()) {
@@ -192,8 +205,6 @@ func (s *socketFilterSnooper) processPacket(data []byte, ts time.Time) error {
 	} else {
 		atomic.AddInt64(&s.queries, 1)
 	}
-
-	return nil
 }
 
 func (s *socketFilterSnooper) pollPackets() {
@@ -72,14 +72,7 @@ type dnsStatKeeper struct {
 }
 
 func newDNSStatkeeper(timeout time.Duration, maxStats int) *dnsStatKeeper {
-	statsKeeper := &dnsStatKeeper{
-		stats:            make(StatsByKeyByNameByType),
-		state:            make(map[stateKey]stateValue),
-		expirationPeriod: timeout,
-		exit:             make(chan struct{}),
-		maxSize:          maxStateMapSize,
-		maxStats:         maxStats,
-	}
+	statsKeeper := newUnscheduledDNSStatkeeper(timeout, maxStats)
 
 	ticker := time.NewTicker(statsKeeper.expirationPeriod)
 	go func() {
@@ -96,6 +89,19 @@ func newDNSStatkeeper(timeout time.Duration, maxStats int) *dnsStatKeeper {
 	return statsKeeper
 }
 
+// newUnscheduledDNSStatkeeper returns a dnsStatKeeper whose states are only expired by calling removeExpiredStates.
+// It must not be closed.
+func newUnscheduledDNSStatkeeper(timeout time.Duration, maxStats int) *dnsStatKeeper {
+	return &dnsStatKeeper{
+		stats:            make(StatsByKeyByNameByType),
+		state:            make(map[stateKey]stateValue),
+		expirationPeriod: timeout,
+		exit:             make(chan struct{}),
+		maxSize:          maxStateMapSize,
+		maxStats:         maxStats,
+	}
+}
+
 func microSecs(t time.Time) uint64 {
 	return uint64(t.UnixNano() / 1000)
 }
@@ -157,8 +163,10 @@ func (d *dnsStatKeeper) ProcessPacketInfo(info dnsPacketInfo, ts time.Time) {
 		byqtype.CountByRcode[uint32(info.rCode)]++
 		if info.pktType == successfulResponse {
 			byqtype.SuccessLatencySum += latency
+			byqtype.SuccessLatencies.Add(latency)
 		} else if info.pktType == failedResponse {
 			byqtype.FailureLatencySum += latency
+			byqtype.FailureLatencies.Add(latency)
 		}
 	}
 	stats[start.qtype] = byqtype
@@ -63,6 +63,17 @@ func testLatency(
 	assert.Equal(t, expectedSuccessLatency, stats[key][d][TypeA].SuccessLatencySum)
 	assert.Equal(t, expectedFailureLatency, stats[key][d][TypeA].FailureLatencySum)
 	assert.Equal(t, expectedTimeouts, stats[key][d][TypeA].Timeouts)
+
+	// each response is also counted in the histogram of its latency
+	var expectedSuccesses, expectedFailures uint32
+	if expectedSuccessLatency > 0 {
+		expectedSuccesses = 1
+	}
+	if expectedFailureLatency > 0 {
+		expectedFailures = 1
+	}
+	assert.Equal(t, expectedSuccesses, stats[key][d][TypeA].SuccessLatencies[0])
+	assert.Equal(t, expectedFailures, stats[key][d][TypeA].FailureLatencies[0])
 }
 
 func TestSuccessLatency(t *testing.T) {
@@ -40,11 +40,11 @@ func (m *tcpWithDNSSupport) hasSelfContainedDNSPayload() bool {
 }
 
 func (m *tcpWithDNSSupport) NextLayerType() gopacket.LayerType {
-	// TODO: deal with TCP fragmentation and out-of-order segments
 	if m.hasSelfContainedDNSPayload() {
 		return layers.LayerTypeDNS
 	}
-	return m.TCP.NextLayerType()
+	// The other segments are left to the tcpDNSReassembler of the parser
+	return gopacket.LayerTypePayload
 }
 
 func (m *tcpWithDNSSupport) LayerPayload() []byte {
@@ -0,0 +1,131 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build windows || linux_bpf
+// +build windows linux_bpf
+
+package dns
+
+import (
+	"encoding/binary"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/gopacket"
+)
+
+const (
+	// maxTCPStreams limits the number of TCP streams with a partial DNS message
+	maxTCPStreams = 1000
+	// tcpStreamTimeout is how long a partial DNS message is kept without new segments
+	tcpStreamTimeout = 10 * time.Second
+)
+
+type tcpStreamKey struct {
+	network   gopacket.Flow
+	transport gopacket.Flow
+}
+
+type tcpStream struct {
+	buf      []byte
+	nextSeq  uint32
+	lastSeen time.Time
+}
+
+// tcpDNSReassembler rebuilds the DNS messages of TCP streams, which are prefixed by their 2-bytes length
+// and can be split across segments, or share a segment with other messages (RFC 7766).
+// Only the streams with a partial message are kept. The segments received out-of-order reset their stream,
+// and the reassembler is not safe for concurrent use.
+type tcpDNSReassembler struct {
+	// dropped counts the segments which could not be reassembled.
+	// It is at the beginning of the struct to keep it 64-bit aligned, as it is read atomically by the telemetry.
+	dropped int64
+
+	streams map[tcpStreamKey]*tcpStream
+	now     func() time.Time
+}
+
+func newTCPDNSReassembler() *tcpDNSReassembler {
+	return &tcpDNSReassembler{
+		streams: make(map[tcpStreamKey]*tcpStream),
+		now:     time.Now,
+	}
+}
+
+// add adds the payload of a segment to its stream, and returns the DNS messages it completes.
+// The messages are only valid until the next call.
+func (r *tcpDNSReassembler) add(key tcpStreamKey, seq uint32, payload []byte, closing bool) [][]byte {
+	if len(payload) == 0 {
+		if closing {
+			delete(r.streams, key)
+		}
+		return nil
+	}
+
+	now := r.now()
+	stream, ok := r.streams[key]
+	if ok {
+		switch diff := int32(seq - stream.nextSeq); {
+		case diff > 0:
+			// a segment is missing, the stream restarts from this segment
+			atomic.AddInt64(&r.dropped, 1)
+			stream.buf = stream.buf[:0]
+		case diff < 0:
+			// retransmission of data already added
+			if int(-diff) >= len(payload) {
+				return nil
+			}
+			payload = payload[-diff:]
+			seq -= uint32(diff)
+		}
+	} else {
+		if len(r.streams) >= maxTCPStreams && !r.expire(now) {
+			atomic.AddInt64(&r.dropped, 1)
+			return nil
+		}
+		stream = &tcpStream{}
+		r.streams[key] = stream
+	}
+	stream.buf = append(stream.buf, payload...)
+	stream.nextSeq = seq + uint32(len(payload))
+	stream.lastSeen = now
+
+	var messages [][]byte
+	buf := stream.buf
+	for len(buf) >= 2 {
+		length := int(binary.BigEndian.Uint16(buf))
+		if length == 0 {
+			// not a DNS stream, or not aligned on a message
+			atomic.AddInt64(&r.dropped, 1)
+			delete(r.streams, key)
+			return messages
+		}
+		if len(buf) < 2+length {
+			break
+		}
+		messages = append(messages, buf[2:2+length])
+		buf = buf[2+length:]
+	}
+
+	if len(buf) == 0 || closing {
+		delete(r.streams, key)
+	} else if len(messages) > 0 {
+		// the messages returned keep pointing to the previous buffer
+		stream.buf = append([]byte(nil), buf...)
+	}
+	return messages
+}
+
+// expire removes the streams without segments since tcpStreamTimeout, and returns whether any was removed
+func (r *tcpDNSReassembler) expire(now time.Time) bool {
+	removed := false
+	for key, stream := range r.streams {
+		if now.Sub(stream.lastSeen) > tcpStreamTimeout {
+			delete(r.streams, key)
+			removed = true
+		}
+	}
+	return removed
+}
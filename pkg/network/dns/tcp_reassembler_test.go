@@ -0,0 +1,171 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build windows || linux_bpf
+// +build windows linux_bpf
+
+package dns
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/network/config"
+	"github.com/DataDog/datadog-agent/pkg/process/util"
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testStreamKey = tcpStreamKey{
+	network:   gopacket.NewFlow(layers.EndpointIPv4, []byte{10, 0, 0, 1}, []byte{10, 0, 0, 2}),
+	transport: gopacket.NewFlow(layers.EndpointTCPPort, []byte{0x9c, 0x40}, []byte{0, 53}),
+}
+
+// tcpDNSPayload returns DNS messages prefixed by their length, as sent over TCP
+func tcpDNSPayload(messages ...[]byte) []byte {
+	var payload []byte
+	for _, msg := range messages {
+		payload = append(payload, byte(len(msg)>>8), byte(len(msg)))
+		payload = append(payload, msg...)
+	}
+	return payload
+}
+
+func TestTCPReassemblerSplitMessage(t *testing.T) {
+	r := newTCPDNSReassembler()
+	payload := tcpDNSPayload([]byte("first message"))
+
+	assert.Empty(t, r.add(testStreamKey, 1000, payload[:5], false))
+	// retransmission
+	assert.Empty(t, r.add(testStreamKey, 1000, payload[:5], false))
+	messages := r.add(testStreamKey, 1003, payload[3:], false)
+	assert.Equal(t, [][]byte{[]byte("first message")}, messages)
+	assert.Empty(t, r.streams, "the streams without partial message are not kept")
+}
+
+func TestTCPReassemblerSeveralMessages(t *testing.T) {
+	r := newTCPDNSReassembler()
+	payload := tcpDNSPayload([]byte("first"), []byte("second"), []byte("third"))
+
+	messages := r.add(testStreamKey, 1000, payload[:12], false)
+	assert.Equal(t, [][]byte{[]byte("first")}, messages)
+	messages = r.add(testStreamKey, 1012, payload[12:], false)
+	assert.Equal(t, [][]byte{[]byte("second"), []byte("third")}, messages)
+	assert.Empty(t, r.streams)
+}
+
+func TestTCPReassemblerMissingSegment(t *testing.T) {
+	r := newTCPDNSReassembler()
+	first := tcpDNSPayload([]byte("first message"))
+	second := tcpDNSPayload([]byte("second"))
+
+	assert.Empty(t, r.add(testStreamKey, 1000, first[:5], false))
+	// the end of the first message is lost, the stream restarts from the next segment
+	messages := r.add(testStreamKey, 1000+uint32(len(first)), second, false)
+	assert.Equal(t, [][]byte{[]byte("second")}, messages)
+	assert.Equal(t, int64(1), r.dropped)
+}
+
+func TestTCPReassemblerClose(t *testing.T) {
+	r := newTCPDNSReassembler()
+	payload := tcpDNSPayload([]byte("first message"))
+
+	assert.Empty(t, r.add(testStreamKey, 1000, payload[:5], false))
+	assert.Empty(t, r.add(testStreamKey, 1005, nil, true))
+	assert.Empty(t, r.streams)
+}
+
+func TestTCPReassemblerLimit(t *testing.T) {
+	now := time.Now()
+	r := newTCPDNSReassembler()
+	r.now = func() time.Time { return now }
+
+	for i := 0; i < maxTCPStreams; i++ {
+		key := tcpStreamKey{network: testStreamKey.network, transport: gopacket.NewFlow(layers.EndpointTCPPort, []byte{byte(i >> 8), byte(i)}, []byte{0, 53})}
+		r.add(key, 0, []byte{0, 10}, false)
+	}
+	assert.Len(t, r.streams, maxTCPStreams)
+	r.add(testStreamKey, 0, []byte{0, 10}, false)
+	assert.Equal(t, int64(1), r.dropped)
+
+	// the expired streams make room for the new ones
+	now = now.Add(tcpStreamTimeout + time.Second)
+	r.add(testStreamKey, 0, []byte{0, 10}, false)
+	assert.Len(t, r.streams, 1)
+}
+
+func buildTCPSegment(t *testing.T, seq uint32, payload []byte) []byte {
+	buf := gopacket.NewSerializeBuffer()
+	ip := &layers.IPv4{Version: 4, TTL: 64, Protocol: layers.IPProtocolTCP, SrcIP: net.IP{8, 8, 8, 8}, DstIP: net.IP{10, 0, 0, 1}}
+	tcp := &layers.TCP{SrcPort: 53, DstPort: 40000, Seq: seq, ACK: true, PSH: true}
+	require.NoError(t, tcp.SetNetworkLayerForChecksum(ip))
+	err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}, ip, tcp, gopacket.Payload(payload))
+	require.NoError(t, err)
+	return buf.Bytes()
+}
+
+func buildDNSResponse(t *testing.T, id uint16, name string, rcode layers.DNSResponseCode) []byte {
+	buf := gopacket.NewSerializeBuffer()
+	dns := &layers.DNS{
+		ID:           id,
+		QR:           true,
+		ResponseCode: rcode,
+		Questions:    []layers.DNSQuestion{{Name: []byte(name), Type: layers.DNSTypeA, Class: layers.DNSClassIN}},
+	}
+	if rcode == layers.DNSResponseCodeNoErr {
+		dns.Answers = []layers.DNSResourceRecord{{Name: []byte(name), Type: layers.DNSTypeA, Class: layers.DNSClassIN, TTL: 60, IP: net.IP{1, 2, 3, 4}}}
+	}
+	require.NoError(t, gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true}, dns))
+	return buf.Bytes()
+}
+
+func newTestTranslation() *translation {
+	return &translation{ips: make(map[util.Address]time.Time)}
+}
+
+func TestParseDNSOverTCPSegments(t *testing.T) {
+	cfg := config.New()
+	cfg.CollectDNSStats = true
+	cfg.CollectDNSDomains = true
+	p := newDNSParser(layers.LayerTypeIPv4, cfg)
+
+	payload := tcpDNSPayload(
+		buildDNSResponse(t, 1, "example.com", layers.DNSResponseCodeNoErr),
+		buildDNSResponse(t, 2, "nope.example.com", layers.DNSResponseCodeNXDomain),
+	)
+
+	// the first segment holds a part of the first response
+	var pktInfo dnsPacketInfo
+	err := p.ParseInto(buildTCPSegment(t, 1000, payload[:20]), newTestTranslation(), &pktInfo)
+	assert.Equal(t, errSkippedPayload, err)
+	assert.False(t, p.HasPendingMessage())
+
+	// the second segment completes both responses
+	tr := newTestTranslation()
+	pktInfo = dnsPacketInfo{}
+	require.NoError(t, p.ParseInto(buildTCPSegment(t, 1020, payload[20:]), tr, &pktInfo))
+	assert.Equal(t, successfulResponse, pktInfo.pktType)
+	assert.Equal(t, uint16(1), pktInfo.transactionID)
+	assert.Equal(t, uint16(40000), pktInfo.key.ClientPort)
+	assert.Equal(t, "example.com", tr.dns)
+
+	require.True(t, p.HasPendingMessage())
+	pktInfo = dnsPacketInfo{}
+	require.NoError(t, p.ParseNextInto(newTestTranslation(), &pktInfo))
+	assert.Equal(t, failedResponse, pktInfo.pktType)
+	assert.Equal(t, uint8(RcodeNXDomain), pktInfo.rCode)
+	assert.Equal(t, uint16(2), pktInfo.transactionID)
+	assert.False(t, p.HasPendingMessage())
+
+	// a segment with a single message is decoded without reassembly
+	pktInfo = dnsPacketInfo{}
+	single := tcpDNSPayload(buildDNSResponse(t, 3, "example.com", layers.DNSResponseCodeServFail))
+	require.NoError(t, p.ParseInto(buildTCPSegment(t, 2000, single), newTestTranslation(), &pktInfo))
+	assert.Equal(t, uint8(RcodeServFail), pktInfo.rCode)
+	assert.Empty(t, p.tcpReassembler.streams)
+}
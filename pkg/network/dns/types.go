@@ -6,6 +6,8 @@
 package dns
 
 import (
+	"strconv"
+
 	"github.com/DataDog/datadog-agent/pkg/process/util"
 	"github.com/google/gopacket/layers"
 	"go4.org/intern"
@@ -66,4 +68,44 @@ type Stats struct {
 	SuccessLatencySum uint64
 	FailureLatencySum uint64
 	CountByRcode      map[uint32]uint32
+	// SuccessLatencies and FailureLatencies are the distributions of the latencies summed above
+	SuccessLatencies LatencyHistogram
+	FailureLatencies LatencyHistogram
+}
+
+// Response codes of the DNS responses, see https://www.iana.org/assignments/dns-parameters/dns-parameters.xhtml#dns-parameters-6
+const (
+	RcodeNoError  = 0
+	RcodeFormErr  = 1
+	RcodeServFail = 2
+	RcodeNXDomain = 3
+	RcodeNotImp   = 4
+	RcodeRefused  = 5
+	RcodeYXDomain = 6
+	RcodeYXRRSet  = 7
+	RcodeNXRRSet  = 8
+	RcodeNotAuth  = 9
+	RcodeNotZone  = 10
+)
+
+var rcodeNames = map[uint32]string{
+	RcodeNoError:  "noerror",
+	RcodeFormErr:  "formerr",
+	RcodeServFail: "servfail",
+	RcodeNXDomain: "nxdomain",
+	RcodeNotImp:   "notimp",
+	RcodeRefused:  "refused",
+	RcodeYXDomain: "yxdomain",
+	RcodeYXRRSet:  "yxrrset",
+	RcodeNXRRSet:  "nxrrset",
+	RcodeNotAuth:  "notauth",
+	RcodeNotZone:  "notzone",
+}
+
+// RcodeName returns the lowercase mnemonic of a response code, like nxdomain, or its number if it is not a standard one
+func RcodeName(rcode uint32) string {
+	if name, ok := rcodeNames[rcode]; ok {
+		return name
+	}
+	return strconv.FormatUint(uint64(rcode), 10)
 }
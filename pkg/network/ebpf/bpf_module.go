@@ -44,6 +44,21 @@ func ReadHTTPModule(bpfDir string, debug bool) (bytecode.AssetReader, error) {
 	return ebpfReader, nil
 }
 
+// ReadKafkaModule from the asset file
+func ReadKafkaModule(bpfDir string, debug bool) (bytecode.AssetReader, error) {
+	file := "kafka.o"
+	if debug {
+		file = "kafka-debug.o"
+	}
+
+	ebpfReader, err := bytecode.GetReader(bpfDir, file)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't find asset: %s", err)
+	}
+
+	return ebpfReader, nil
+}
+
 // ReadDNSModule from the asset file
 func ReadDNSModule(bpfDir string, debug bool) (bytecode.AssetReader, error) {
 	file := "dns.o"
@@ -73,3 +88,18 @@ func ReadOffsetBPFModule(bpfDir string, debug bool) (bytecode.AssetReader, error
 
 	return ebpfReader, nil
 }
+
+// ReadDatabaseModule from the asset file
+func ReadDatabaseModule(bpfDir string, debug bool) (bytecode.AssetReader, error) {
+	file := "database.o"
+	if debug {
+		file = "database-debug.o"
+	}
+
+	ebpfReader, err := bytecode.GetReader(bpfDir, file)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't find asset: %s", err)
+	}
+
+	return ebpfReader, nil
+}
@@ -0,0 +1,72 @@
+#ifndef __DATABASE_TYPES_H
+#define __DATABASE_TYPES_H
+
+#include "tracer.h"
+
+// This determines the size of the beginning of each TCP segment captured on a database connection.
+// The queries longer than the captured part of their message are truncated.
+#define DATABASE_BUFFER_SIZE 256
+// This controls the number of database segments read from userspace at a time
+#define DATABASE_BATCH_SIZE 10
+// The greater this number is the less likely are colisions/data-races between the flushes
+#define DATABASE_BATCH_PAGES 10
+
+#define DATABASE_PROTOCOL_POSTGRES 1
+#define DATABASE_PROTOCOL_MYSQL 2
+
+// Number of bytes of the query text checked to classify a connection
+#define DATABASE_CLASSIFY_QUERY_SIZE 16
+
+// PostgreSQL frontend/backend protocol, version 3
+#define POSTGRES_PROTOCOL_VERSION_3 196608
+#define POSTGRES_SSL_REQUEST 80877103
+#define POSTGRES_MAX_STARTUP_SIZE 10000
+#define POSTGRES_MAX_MESSAGE_SIZE (1 << 24)
+
+// MySQL client/server protocol
+#define MYSQL_HEADER_SIZE 4
+#define MYSQL_COM_QUERY 0x03
+#define MYSQL_COM_STMT_PREPARE 0x16
+#define MYSQL_HANDSHAKE_V10 0x0a
+
+// The queries are matched to their responses differently for each protocol, and the prepared
+// statements are identified by name or ID: this can't be done in eBPF. Instead, the beginning of
+// each TCP segment of a database connection is sent to userspace, where the messages are decoded.
+typedef struct {
+    conn_tuple_t tup;
+    __u64 timestamp;
+    __u32 seq;
+    // segment_len is the length of the TCP payload, of which only captured_len bytes are in data
+    __u32 segment_len;
+    __u16 captured_len;
+    __u8 protocol;
+    __u8 from_client;
+    __u8 fin;
+    char data[DATABASE_BUFFER_SIZE];
+} database_segment_t;
+
+typedef struct {
+    __u64 idx;
+    __u8 pos;
+    database_segment_t segments[DATABASE_BATCH_SIZE];
+} database_batch_t;
+
+// The batches of segments are managed like the batches of HTTP transactions.
+// See http_batch_state_t, http_batch_key_t and http_batch_notification_t.
+typedef struct {
+    __u64 idx;
+    __u8 pos;
+    __u64 idx_to_notify;
+} database_batch_state_t;
+
+typedef struct {
+    __u32 cpu;
+    __u32 page_num;
+} database_batch_key_t;
+
+typedef struct {
+    __u32 cpu;
+    __u64 batch_idx;
+} database_batch_notification_t;
+
+#endif
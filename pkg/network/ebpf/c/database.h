@@ -0,0 +1,241 @@
+#ifndef __DATABASE_H
+#define __DATABASE_H
+
+#include "tracer.h"
+#include "sock.h"
+#include "database-types.h"
+#include "database-maps.h"
+
+#include <uapi/linux/ptrace.h>
+
+static __always_inline void database_notify_batch(struct pt_regs *ctx) {
+    u32 cpu = bpf_get_smp_processor_id();
+
+    database_batch_state_t *batch_state = bpf_map_lookup_elem(&database_batch_state, &cpu);
+    if (batch_state == NULL || batch_state->idx_to_notify == batch_state->idx) {
+        // batch is not ready to be flushed
+        return;
+    }
+
+    // See http_notify_batch for why the struct is zeroed
+    database_batch_notification_t notification = { 0 };
+    notification.cpu = cpu;
+    notification.batch_idx = batch_state->idx_to_notify;
+
+    bpf_perf_event_output(ctx, &database_notifications, cpu, &notification, sizeof(database_batch_notification_t));
+    log_debug("database batch notification flushed: cpu: %d idx: %d\n", notification.cpu, notification.batch_idx);
+    batch_state->idx_to_notify++;
+}
+
+static __always_inline void database_enqueue(database_segment_t *segment) {
+    // Retrieve the active batch number for this CPU
+    u32 cpu = bpf_get_smp_processor_id();
+    database_batch_state_t *batch_state = bpf_map_lookup_elem(&database_batch_state, &cpu);
+    if (batch_state == NULL) {
+        return;
+    }
+
+    database_batch_key_t key;
+    __builtin_memset(&key, 0, sizeof(database_batch_key_t));
+    key.cpu = cpu;
+    key.page_num = batch_state->idx % DATABASE_BATCH_PAGES;
+
+    database_batch_t *batch = bpf_map_lookup_elem(&database_batches, &key);
+    if (batch == NULL) {
+        return;
+    }
+
+    // The slot is written with an unrolled loop for the Kernel 4.4 verifier (see http_enqueue)
+#pragma unroll
+    for (int i = 0; i < DATABASE_BATCH_SIZE; i++) {
+        if (i == batch_state->pos) {
+            __builtin_memcpy(&batch->segments[i], segment, sizeof(database_segment_t));
+        }
+    }
+
+    log_debug("database segment enqueued: cpu: %d batch_idx: %d pos: %d\n", cpu, batch_state->idx, batch_state->pos);
+    batch_state->pos++;
+
+    // Copy batch state information for user-space
+    batch->idx = batch_state->idx;
+    batch->pos = batch_state->pos;
+
+    // If we have filled the batch we move to the next one
+    if (batch_state->pos == DATABASE_BATCH_SIZE) {
+        batch_state->idx++;
+        batch_state->pos = 0;
+    }
+}
+
+// database_is_text reports whether the size bytes starting at offset are printable, as the
+// beginning of a query is. NUL bytes are accepted when allow_nul is set.
+static __always_inline int database_is_text(struct __sk_buff *skb, u32 offset, u32 size, int allow_nul) {
+#pragma unroll
+    for (int i = 0; i < DATABASE_CLASSIFY_QUERY_SIZE; i++) {
+        if (i >= size) {
+            break;
+        }
+        char c = load_byte(skb, offset + i);
+        if (c == 0 && allow_nul) {
+            continue;
+        }
+        if ((c < ' ' || c > '~') && c != '\t' && c != '\n' && c != '\r') {
+            return 0;
+        }
+    }
+    return 1;
+}
+
+// postgres_is_client_message reports whether the segment starting at offset begins with a
+// PostgreSQL startup message, an SSL request, or a Query or Parse message.
+static __always_inline int postgres_is_client_message(struct __sk_buff *skb, u32 offset, u32 len) {
+    if (len < 8) {
+        return 0;
+    }
+
+    s32 size = load_word(skb, offset);
+    s32 code = load_word(skb, offset + 4);
+    if (size >= 8 && size <= POSTGRES_MAX_STARTUP_SIZE && (code == POSTGRES_PROTOCOL_VERSION_3 || code == POSTGRES_SSL_REQUEST)) {
+        return 1;
+    }
+
+    char type = load_byte(skb, offset);
+    size = load_word(skb, offset + 1);
+    if (size <= 4 || size >= POSTGRES_MAX_MESSAGE_SIZE) {
+        return 0;
+    }
+    // the Query message holds the query, the Parse message the statement name and the query
+    if (type == 'Q') {
+        return database_is_text(skb, offset + 5, len - 5, 0);
+    }
+    if (type == 'P') {
+        return database_is_text(skb, offset + 5, len - 5, 1);
+    }
+    return 0;
+}
+
+static __always_inline u32 mysql_packet_size(struct __sk_buff *skb, u32 offset) {
+    return load_byte(skb, offset) | load_byte(skb, offset + 1) << 8 | load_byte(skb, offset + 2) << 16;
+}
+
+// mysql_is_client_command reports whether the segment starting at offset begins with a MySQL
+// COM_QUERY or COM_STMT_PREPARE packet.
+static __always_inline int mysql_is_client_command(struct __sk_buff *skb, u32 offset, u32 len) {
+    if (len < MYSQL_HEADER_SIZE + 2) {
+        return 0;
+    }
+
+    u32 size = mysql_packet_size(skb, offset);
+    u8 seq = load_byte(skb, offset + 3);
+    u8 command = load_byte(skb, offset + 4);
+    if (seq != 0 || size < 2 || (command != MYSQL_COM_QUERY && command != MYSQL_COM_STMT_PREPARE)) {
+        return 0;
+    }
+
+    u32 query_offset = MYSQL_HEADER_SIZE + 1;
+    if (command == MYSQL_COM_QUERY && load_byte(skb, offset + query_offset) == 0) {
+        // the query is preceded by its attributes: no parameter, and a single parameter set
+        if (len < query_offset + 3 || load_byte(skb, offset + query_offset + 1) != 1) {
+            return 0;
+        }
+        query_offset += 2;
+    }
+    return database_is_text(skb, offset + query_offset, len - query_offset, 0);
+}
+
+// mysql_is_server_greeting reports whether the segment starting at offset begins with the
+// initial handshake packet of a MySQL server, which starts with the server version.
+static __always_inline int mysql_is_server_greeting(struct __sk_buff *skb, u32 offset, u32 len) {
+    if (len < MYSQL_HEADER_SIZE + 2) {
+        return 0;
+    }
+
+    u32 size = mysql_packet_size(skb, offset);
+    u8 seq = load_byte(skb, offset + 3);
+    u8 version = load_byte(skb, offset + 4);
+    char major = load_byte(skb, offset + 5);
+    if (seq != 0 || size < 2 || size > 1024 || version != MYSQL_HANDSHAKE_V10 || major < '0' || major > '9') {
+        return 0;
+    }
+    return database_is_text(skb, offset + 5, len - 5, 1);
+}
+
+// database_classify returns the DATABASE_PROTOCOL_* used by the connection of the segment,
+// or 0 if the segment does not identify a database protocol.
+static __always_inline u8 database_classify(struct __sk_buff *skb, u32 offset, u32 len, int from_client) {
+    if (from_client) {
+        if (postgres_monitoring_enabled() && postgres_is_client_message(skb, offset, len)) {
+            return DATABASE_PROTOCOL_POSTGRES;
+        }
+        if (mysql_monitoring_enabled() && mysql_is_client_command(skb, offset, len)) {
+            return DATABASE_PROTOCOL_MYSQL;
+        }
+        return 0;
+    }
+    if (mysql_monitoring_enabled() && mysql_is_server_greeting(skb, offset, len)) {
+        return DATABASE_PROTOCOL_MYSQL;
+    }
+    return 0;
+}
+
+static __always_inline void database_read_segment(struct __sk_buff *skb, u32 offset, u32 len, database_segment_t *segment) {
+#pragma unroll
+    for (int i = 0; i < DATABASE_BUFFER_SIZE; i++) {
+        if (i >= len) {
+            break;
+        }
+        segment->data[i] = load_byte(skb, offset + i);
+    }
+}
+
+// database_process sends the segments of the PostgreSQL and MySQL connections to userspace. A
+// connection is classified when the client sends a startup message or a query, or when a MySQL
+// server sends its greeting; the segments sent before are not captured.
+static __always_inline int database_process(struct __sk_buff *skb, skb_info_t *skb_info, int from_client) {
+    u32 len = 0;
+    if (skb->len > skb_info->data_off) {
+        len = skb->len - skb_info->data_off;
+    }
+    u8 fin = (skb_info->tcp_flags & TCPHDR_FIN) != 0;
+
+    u8 protocol = 0;
+    __u8 *conn = bpf_map_lookup_elem(&database_conns, &skb_info->tup);
+    if (conn != NULL) {
+        protocol = *conn;
+    } else {
+        protocol = database_classify(skb, skb_info->data_off, len, from_client);
+        if (protocol == 0) {
+            return 0;
+        }
+        bpf_map_update_elem(&database_conns, &skb_info->tup, &protocol, BPF_NOEXIST);
+    }
+
+    if (len == 0 && !fin) {
+        return 0;
+    }
+
+    u32 cpu = bpf_get_smp_processor_id();
+    database_segment_t *segment = bpf_map_lookup_elem(&database_scratch, &cpu);
+    if (segment == NULL) {
+        return 0;
+    }
+
+    __builtin_memcpy(&segment->tup, &skb_info->tup, sizeof(conn_tuple_t));
+    segment->timestamp = bpf_ktime_get_ns();
+    segment->seq = skb_info->tcp_seq;
+    segment->segment_len = len;
+    segment->captured_len = len < DATABASE_BUFFER_SIZE ? len : DATABASE_BUFFER_SIZE;
+    segment->protocol = protocol;
+    segment->from_client = from_client;
+    segment->fin = fin;
+    database_read_segment(skb, skb_info->data_off, len, segment);
+    database_enqueue(segment);
+
+    if (fin) {
+        bpf_map_delete_elem(&database_conns, &skb_info->tup);
+    }
+
+    return 0;
+}
+
+#endif
@@ -0,0 +1,60 @@
+#ifndef __GO_TLS_H
+#define __GO_TLS_H
+
+#include "tracer.h"
+#include "bpf_helpers.h"
+#include "http-types.h"
+#include "http-maps.h"
+
+#include <uapi/linux/ptrace.h>
+
+// The crypto/tls uprobes support the register-based calling convention of Go 1.17+ on amd64,
+// and Go 1.18+ on arm64: the arguments and the results are passed in the same registers,
+// and the current goroutine (runtime.g) is held in a dedicated register.
+#if defined(__x86_64__)
+
+#define GO_PARAM1(x) ((x)->ax)
+#define GO_PARAM2(x) ((x)->bx)
+#define GO_PARAM3(x) ((x)->cx)
+#define GOROUTINE(x) ((x)->r14)
+
+#elif defined(__aarch64__)
+
+#define GO_PARAM1(x) ((x)->regs[0])
+#define GO_PARAM2(x) ((x)->regs[1])
+#define GO_PARAM3(x) ((x)->regs[2])
+#define GOROUTINE(x) ((x)->regs[28])
+
+#else
+#error "Unsupported platform"
+#endif
+
+static __always_inline int read_goroutine_id(struct pt_regs *ctx, go_tls_offsets_t *offsets, u64 *goroutine_id) {
+    void *g = (void *)GOROUTINE(ctx);
+    return bpf_probe_read(goroutine_id, sizeof(*goroutine_id), g + offsets->goroutine_id_offset);
+}
+
+// read_conn_fd reads the socket file descriptor of a *crypto/tls.Conn, following
+// tls.Conn.conn (a net.Conn holding a *net.TCPConn) -> net.conn.fd -> net.netFD.pfd -> poll.FD.Sysfd
+static __always_inline int read_conn_fd(void *conn, go_tls_offsets_t *offsets, u32 *fd) {
+    // the data word of the net.Conn interface follows its type word
+    void *tcp_conn = NULL;
+    if (bpf_probe_read(&tcp_conn, sizeof(tcp_conn), conn + offsets->conn_conn_offset + sizeof(void *)) || tcp_conn == NULL) {
+        return -1;
+    }
+
+    void *net_fd = NULL;
+    if (bpf_probe_read(&net_fd, sizeof(net_fd), tcp_conn + offsets->net_conn_fd_offset) || net_fd == NULL) {
+        return -1;
+    }
+
+    s64 sysfd = 0;
+    if (bpf_probe_read(&sysfd, sizeof(sysfd), net_fd + offsets->netfd_pfd_offset + offsets->pfd_sysfd_offset) || sysfd < 0) {
+        return -1;
+    }
+
+    *fd = (u32)sysfd;
+    return 0;
+}
+
+#endif
@@ -45,6 +45,56 @@ struct bpf_map_def SEC("maps/http_batch_state") http_batch_state = {
     .namespace = "",
 };
 
+/* This map holds the TCP connections (normalized as client, server) on which an HTTP/2 connection preface was seen */
+struct bpf_map_def SEC("maps/http2_conns") http2_conns = {
+    .type = BPF_MAP_TYPE_HASH,
+    .key_size = sizeof(conn_tuple_t),
+    .value_size = sizeof(__u8),
+    .max_entries = 1, // This will get overridden at runtime using max_tracked_connections
+    .pinning = 0,
+    .namespace = "",
+};
+
+/* This map used for notifying userspace that a HTTP/2 batch is ready to be consumed */
+struct bpf_map_def SEC("maps/http2_notifications") http2_notifications = {
+    .type = BPF_MAP_TYPE_PERF_EVENT_ARRAY,
+    .key_size = sizeof(__u32),
+    .value_size = sizeof(__u32),
+    .max_entries = 0, // This will get overridden at runtime
+    .pinning = 0,
+    .namespace = "",
+};
+
+/* This map stores the HTTP/2 segments in batches so they can be consumed by userspace */
+struct bpf_map_def SEC("maps/http2_batches") http2_batches = {
+    .type = BPF_MAP_TYPE_HASH,
+    .key_size = sizeof(http_batch_key_t),
+    .value_size = sizeof(http2_batch_t),
+    .max_entries = 1024,
+    .pinning = 0,
+    .namespace = "",
+};
+
+/* This map holds one entry per CPU storing state associated to current http2 batch */
+struct bpf_map_def SEC("maps/http2_batch_state") http2_batch_state = {
+    .type = BPF_MAP_TYPE_HASH,
+    .key_size = sizeof(__u32),
+    .value_size = sizeof(http_batch_state_t),
+    .max_entries = 1024,
+    .pinning = 0,
+    .namespace = "",
+};
+
+/* This map holds one http2_segment_t per CPU, as they don't fit in the eBPF stack */
+struct bpf_map_def SEC("maps/http2_scratch") http2_scratch = {
+    .type = BPF_MAP_TYPE_HASH,
+    .key_size = sizeof(__u32),
+    .value_size = sizeof(http2_segment_t),
+    .max_entries = 1024,
+    .pinning = 0,
+    .namespace = "",
+};
+
 struct bpf_map_def SEC("maps/ssl_sock_by_ctx") ssl_sock_by_ctx = {
     .type = BPF_MAP_TYPE_HASH,
     .key_size = sizeof(void *),
@@ -81,6 +131,35 @@ struct bpf_map_def SEC("maps/fd_by_ssl_bio") fd_by_ssl_bio = {
     .namespace = "",
 };
 
+/* This map holds the offsets of the fields read by the crypto/tls uprobes, per Go process */
+struct bpf_map_def SEC("maps/go_tls_offsets") go_tls_offsets = {
+    .type = BPF_MAP_TYPE_HASH,
+    .key_size = sizeof(__u32), // pid
+    .value_size = sizeof(go_tls_offsets_t),
+    .max_entries = 1024,
+    .pinning = 0,
+    .namespace = "",
+};
+
+/* This map stores the arguments of crypto/tls.(*Conn).Read until it returns, per goroutine */
+struct bpf_map_def SEC("maps/go_tls_read_args") go_tls_read_args = {
+    .type = BPF_MAP_TYPE_HASH,
+    .key_size = sizeof(go_tls_read_key_t),
+    .value_size = sizeof(go_tls_read_args_t),
+    .max_entries = 1024,
+    .pinning = 0,
+    .namespace = "",
+};
+
+struct bpf_map_def SEC("maps/go_tls_sock_by_conn") go_tls_sock_by_conn = {
+    .type = BPF_MAP_TYPE_HASH,
+    .key_size = sizeof(go_tls_conn_key_t),
+    .value_size = sizeof(ssl_sock_t),
+    .max_entries = 1, // This will get overridden at runtime using max_tracked_connections
+    .pinning = 0,
+    .namespace = "",
+};
+
 struct bpf_map_def SEC("maps/open_at_args") open_at_args = {
     .type = BPF_MAP_TYPE_HASH,
     .key_size = sizeof(__u64), // pid_tgid
@@ -85,6 +85,37 @@ typedef struct {
     __u64 batch_idx;
 } http_batch_notification_t;
 
+// This determines the size of the beginning of the TCP segments captured for HTTP/2 connections
+#define HTTP2_BUFFER_SIZE 160
+// This controls the number of HTTP/2 segments read from userspace at a time
+#define HTTP2_BATCH_SIZE 10
+#define HTTP2_BATCH_PAGES 10
+// Size of the connection preface sent by HTTP/2 clients: "PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n"
+#define HTTP2_PREFACE_SIZE 24
+
+// HTTP/2 frames can't be matched into transactions in eBPF, because their headers are
+// compressed with a per-connection state (HPACK). Instead, the beginning of each TCP segment
+// of an HTTP/2 connection is sent to userspace, where the frames are decoded.
+// The batches of segments are managed like the batches of HTTP transactions, with the same
+// state, key and notification types.
+typedef struct {
+    conn_tuple_t tup;
+    __u64 timestamp;
+    __u32 seq;
+    // segment_len is the length of the TCP payload, of which only captured_len bytes are in data
+    __u32 segment_len;
+    __u16 captured_len;
+    __u8 from_client;
+    __u8 fin;
+    char data[HTTP2_BUFFER_SIZE];
+} http2_segment_t;
+
+typedef struct {
+    __u64 idx;
+    __u8 pos;
+    http2_segment_t segments[HTTP2_BATCH_SIZE];
+} http2_batch_t;
+
 // OpenSSL types
 typedef struct {
     void *ctx;
@@ -96,6 +127,32 @@ typedef struct {
     __u32 fd;
 } ssl_sock_t;
 
+// Go TLS types
+// The offsets of the fields read by the crypto/tls uprobes, which depend on the Go version
+// a binary was built with. They are written by userspace for each Go process monitored.
+typedef struct {
+    __u64 goroutine_id_offset; // runtime.g.goid
+    __u64 conn_conn_offset;    // crypto/tls.Conn.conn (net.Conn interface)
+    __u64 net_conn_fd_offset;  // net.conn.fd (*net.netFD), embedded in net.TCPConn
+    __u64 netfd_pfd_offset;    // net.netFD.pfd (internal/poll.FD)
+    __u64 pfd_sysfd_offset;    // internal/poll.FD.Sysfd
+} go_tls_offsets_t;
+
+typedef struct {
+    __u32 pid;
+    __u64 goroutine_id;
+} go_tls_read_key_t;
+
+typedef struct {
+    void *conn;
+    void *buf;
+} go_tls_read_args_t;
+
+typedef struct {
+    __u32 pid;
+    void *conn;
+} go_tls_conn_key_t;
+
  #define LIB_PATH_MAX_SIZE 120
 
 typedef struct {
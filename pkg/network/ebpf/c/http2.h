@@ -0,0 +1,142 @@
+#ifndef __HTTP2_H
+#define __HTTP2_H
+
+#include "tracer.h"
+#include "sock.h"
+#include "http-types.h"
+#include "http-maps.h"
+
+#include <uapi/linux/ptrace.h>
+
+static __always_inline void http2_notify_batch(struct pt_regs *ctx) {
+    u32 cpu = bpf_get_smp_processor_id();
+
+    http_batch_state_t *batch_state = bpf_map_lookup_elem(&http2_batch_state, &cpu);
+    if (batch_state == NULL || batch_state->idx_to_notify == batch_state->idx) {
+        // batch is not ready to be flushed
+        return;
+    }
+
+    // See http_notify_batch for why the struct is zeroed
+    http_batch_notification_t notification = { 0 };
+    notification.cpu = cpu;
+    notification.batch_idx = batch_state->idx_to_notify;
+
+    bpf_perf_event_output(ctx, &http2_notifications, cpu, &notification, sizeof(http_batch_notification_t));
+    log_debug("http2 batch notification flushed: cpu: %d idx: %d\n", notification.cpu, notification.batch_idx);
+    batch_state->idx_to_notify++;
+}
+
+static __always_inline void http2_enqueue(http2_segment_t *segment) {
+    // Retrieve the active batch number for this CPU
+    u32 cpu = bpf_get_smp_processor_id();
+    http_batch_state_t *batch_state = bpf_map_lookup_elem(&http2_batch_state, &cpu);
+    if (batch_state == NULL) {
+        return;
+    }
+
+    http_batch_key_t key;
+    __builtin_memset(&key, 0, sizeof(http_batch_key_t));
+    key.cpu = cpu;
+    key.page_num = batch_state->idx % HTTP2_BATCH_PAGES;
+
+    http2_batch_t *batch = bpf_map_lookup_elem(&http2_batches, &key);
+    if (batch == NULL) {
+        return;
+    }
+
+    // The slot is written with an unrolled loop for the Kernel 4.4 verifier (see http_enqueue)
+#pragma unroll
+    for (int i = 0; i < HTTP2_BATCH_SIZE; i++) {
+        if (i == batch_state->pos) {
+            __builtin_memcpy(&batch->segments[i], segment, sizeof(http2_segment_t));
+        }
+    }
+
+    log_debug("http2 segment enqueued: cpu: %d batch_idx: %d pos: %d\n", cpu, batch_state->idx, batch_state->pos);
+    batch_state->pos++;
+
+    // Copy batch state information for user-space
+    batch->idx = batch_state->idx;
+    batch->pos = batch_state->pos;
+
+    // If we have filled the batch we move to the next one
+    if (batch_state->pos == HTTP2_BATCH_SIZE) {
+        batch_state->idx++;
+        batch_state->pos = 0;
+    }
+}
+
+static __always_inline int http2_is_preface(struct __sk_buff *skb, u32 offset) {
+    const char preface[] = "PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n";
+
+#pragma unroll
+    for (int i = 0; i < HTTP2_PREFACE_SIZE; i++) {
+        if (load_byte(skb, offset + i) != preface[i]) {
+            return 0;
+        }
+    }
+    return 1;
+}
+
+static __always_inline void http2_read_segment(struct __sk_buff *skb, u32 offset, u32 len, http2_segment_t *segment) {
+#pragma unroll
+    for (int i = 0; i < HTTP2_BUFFER_SIZE; i++) {
+        if (i >= len) {
+            break;
+        }
+        segment->data[i] = load_byte(skb, offset + i);
+    }
+}
+
+// http2_process sends the segments of the HTTP/2 connections to userspace. A connection is
+// recognized by the connection preface sent by the client, so the connections established
+// before the program is loaded are not monitored.
+static __always_inline int http2_process(struct __sk_buff *skb, skb_info_t *skb_info, int from_client) {
+    if (!http2_monitoring_enabled()) {
+        return 0;
+    }
+
+    u32 len = 0;
+    if (skb->len > skb_info->data_off) {
+        len = skb->len - skb_info->data_off;
+    }
+    u8 fin = (skb_info->tcp_flags & TCPHDR_FIN) != 0;
+
+    __u8 *conn = bpf_map_lookup_elem(&http2_conns, &skb_info->tup);
+    if (conn == NULL) {
+        if (!from_client || len < HTTP2_PREFACE_SIZE || !http2_is_preface(skb, skb_info->data_off)) {
+            return 0;
+        }
+        __u8 seen = 1;
+        bpf_map_update_elem(&http2_conns, &skb_info->tup, &seen, BPF_NOEXIST);
+    }
+
+    if (len == 0 && !fin) {
+        return 0;
+    }
+
+    u32 cpu = bpf_get_smp_processor_id();
+    http2_segment_t *segment = bpf_map_lookup_elem(&http2_scratch, &cpu);
+    if (segment == NULL) {
+        return 0;
+    }
+
+    __builtin_memcpy(&segment->tup, &skb_info->tup, sizeof(conn_tuple_t));
+    segment->timestamp = bpf_ktime_get_ns();
+    segment->seq = skb_info->tcp_seq;
+    segment->segment_len = len;
+    segment->captured_len = len < HTTP2_BUFFER_SIZE ? len : HTTP2_BUFFER_SIZE;
+    segment->from_client = from_client;
+    segment->fin = fin;
+    http2_read_segment(skb, skb_info->data_off, len, segment);
+    http2_enqueue(segment);
+
+    if (fin) {
+        bpf_map_delete_elem(&http2_conns, &skb_info->tup);
+    }
+
+    return 0;
+}
+
+#endif
@@ -0,0 +1,58 @@
+#ifndef __KAFKA_MAPS_H
+#define __KAFKA_MAPS_H
+
+#include "tracer.h"
+#include "bpf_helpers.h"
+#include "kafka-types.h"
+
+/* This map holds the TCP connections (normalized as client, server) classified as Kafka */
+struct bpf_map_def SEC("maps/kafka_conns") kafka_conns = {
+    .type = BPF_MAP_TYPE_HASH,
+    .key_size = sizeof(conn_tuple_t),
+    .value_size = sizeof(__u8),
+    .max_entries = 1, // This will get overridden at runtime using max_tracked_connections
+    .pinning = 0,
+    .namespace = "",
+};
+
+/* This map used for notifying userspace that a Kafka batch is ready to be consumed */
+struct bpf_map_def SEC("maps/kafka_notifications") kafka_notifications = {
+    .type = BPF_MAP_TYPE_PERF_EVENT_ARRAY,
+    .key_size = sizeof(__u32),
+    .value_size = sizeof(__u32),
+    .max_entries = 0, // This will get overridden at runtime
+    .pinning = 0,
+    .namespace = "",
+};
+
+/* This map stores the Kafka segments in batches so they can be consumed by userspace */
+struct bpf_map_def SEC("maps/kafka_batches") kafka_batches = {
+    .type = BPF_MAP_TYPE_HASH,
+    .key_size = sizeof(kafka_batch_key_t),
+    .value_size = sizeof(kafka_batch_t),
+    .max_entries = 1024,
+    .pinning = 0,
+    .namespace = "",
+};
+
+/* This map holds one entry per CPU storing state associated to current kafka batch */
+struct bpf_map_def SEC("maps/kafka_batch_state") kafka_batch_state = {
+    .type = BPF_MAP_TYPE_HASH,
+    .key_size = sizeof(__u32),
+    .value_size = sizeof(kafka_batch_state_t),
+    .max_entries = 1024,
+    .pinning = 0,
+    .namespace = "",
+};
+
+/* This map holds one kafka_segment_t per CPU, as they don't fit in the eBPF stack */
+struct bpf_map_def SEC("maps/kafka_scratch") kafka_scratch = {
+    .type = BPF_MAP_TYPE_HASH,
+    .key_size = sizeof(__u32),
+    .value_size = sizeof(kafka_segment_t),
+    .max_entries = 1024,
+    .pinning = 0,
+    .namespace = "",
+};
+
+#endif
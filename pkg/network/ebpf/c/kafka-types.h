@@ -0,0 +1,63 @@
+#ifndef __KAFKA_TYPES_H
+#define __KAFKA_TYPES_H
+
+#include "tracer.h"
+
+// This determines the size of the beginning of each TCP segment captured on a Kafka connection
+#define KAFKA_BUFFER_SIZE 160
+// This controls the number of Kafka segments read from userspace at a time
+#define KAFKA_BATCH_SIZE 10
+// The greater this number is the less likely are colisions/data-races between the flushes
+#define KAFKA_BATCH_PAGES 10
+
+// Size of the request header fields checked to classify a connection as Kafka:
+// message_size (INT32), api_key (INT16), api_version (INT16), correlation_id (INT32), client_id length (INT16)
+#define KAFKA_MIN_REQUEST_SIZE 14
+#define KAFKA_MAX_MESSAGE_SIZE (1 << 24)
+#define KAFKA_MAX_API_VERSION 15
+// Maximum length of the client_id checked to classify a connection
+#define KAFKA_MAX_CLIENT_ID_SIZE 32
+
+#define KAFKA_API_PRODUCE 0
+#define KAFKA_API_FETCH 1
+
+// The Kafka requests and responses are matched by correlation ID, and their topics are
+// encoded in variable length fields: they can't be decoded in eBPF. Instead, the beginning
+// of each TCP segment of a Kafka connection is sent to userspace, where the messages are decoded.
+typedef struct {
+    conn_tuple_t tup;
+    __u64 timestamp;
+    __u32 seq;
+    // segment_len is the length of the TCP payload, of which only captured_len bytes are in data
+    __u32 segment_len;
+    __u16 captured_len;
+    __u8 from_client;
+    __u8 fin;
+    char data[KAFKA_BUFFER_SIZE];
+} kafka_segment_t;
+
+typedef struct {
+    __u64 idx;
+    __u8 pos;
+    kafka_segment_t segments[KAFKA_BATCH_SIZE];
+} kafka_batch_t;
+
+// The batches of segments are managed like the batches of HTTP transactions.
+// See http_batch_state_t, http_batch_key_t and http_batch_notification_t.
+typedef struct {
+    __u64 idx;
+    __u8 pos;
+    __u64 idx_to_notify;
+} kafka_batch_state_t;
+
+typedef struct {
+    __u32 cpu;
+    __u32 page_num;
+} kafka_batch_key_t;
+
+typedef struct {
+    __u32 cpu;
+    __u64 batch_idx;
+} kafka_batch_notification_t;
+
+#endif
@@ -0,0 +1,165 @@
+#ifndef __KAFKA_H
+#define __KAFKA_H
+
+#include "tracer.h"
+#include "kafka-types.h"
+#include "kafka-maps.h"
+
+#include <uapi/linux/ptrace.h>
+
+static __always_inline void kafka_notify_batch(struct pt_regs *ctx) {
+    u32 cpu = bpf_get_smp_processor_id();
+
+    kafka_batch_state_t *batch_state = bpf_map_lookup_elem(&kafka_batch_state, &cpu);
+    if (batch_state == NULL || batch_state->idx_to_notify == batch_state->idx) {
+        // batch is not ready to be flushed
+        return;
+    }
+
+    // See http_notify_batch for why the struct is zeroed
+    kafka_batch_notification_t notification = { 0 };
+    notification.cpu = cpu;
+    notification.batch_idx = batch_state->idx_to_notify;
+
+    bpf_perf_event_output(ctx, &kafka_notifications, cpu, &notification, sizeof(kafka_batch_notification_t));
+    log_debug("kafka batch notification flushed: cpu: %d idx: %d\n", notification.cpu, notification.batch_idx);
+    batch_state->idx_to_notify++;
+}
+
+static __always_inline void kafka_enqueue(kafka_segment_t *segment) {
+    // Retrieve the active batch number for this CPU
+    u32 cpu = bpf_get_smp_processor_id();
+    kafka_batch_state_t *batch_state = bpf_map_lookup_elem(&kafka_batch_state, &cpu);
+    if (batch_state == NULL) {
+        return;
+    }
+
+    kafka_batch_key_t key;
+    __builtin_memset(&key, 0, sizeof(kafka_batch_key_t));
+    key.cpu = cpu;
+    key.page_num = batch_state->idx % KAFKA_BATCH_PAGES;
+
+    kafka_batch_t *batch = bpf_map_lookup_elem(&kafka_batches, &key);
+    if (batch == NULL) {
+        return;
+    }
+
+    // The slot is written with an unrolled loop for the Kernel 4.4 verifier (see http_enqueue)
+#pragma unroll
+    for (int i = 0; i < KAFKA_BATCH_SIZE; i++) {
+        if (i == batch_state->pos) {
+            __builtin_memcpy(&batch->segments[i], segment, sizeof(kafka_segment_t));
+        }
+    }
+
+    log_debug("kafka segment enqueued: cpu: %d batch_idx: %d pos: %d\n", cpu, batch_state->idx, batch_state->pos);
+    batch_state->pos++;
+
+    // Copy batch state information for user-space
+    batch->idx = batch_state->idx;
+    batch->pos = batch_state->pos;
+
+    // If we have filled the batch we move to the next one
+    if (batch_state->pos == KAFKA_BATCH_SIZE) {
+        batch_state->idx++;
+        batch_state->pos = 0;
+    }
+}
+
+// kafka_is_request reports whether the segment starting at offset begins with the header of a
+// Kafka Produce or Fetch request. The checks on the header fields and on the client ID keep the
+// connections of other protocols from being classified as Kafka.
+static __always_inline int kafka_is_request(struct __sk_buff *skb, u32 offset, u32 len) {
+    if (len < KAFKA_MIN_REQUEST_SIZE) {
+        return 0;
+    }
+
+    s32 message_size = load_word(skb, offset);
+    s16 api_key = load_half(skb, offset + 4);
+    s16 api_version = load_half(skb, offset + 6);
+    s32 correlation_id = load_word(skb, offset + 8);
+    s16 client_id_size = load_half(skb, offset + 12);
+
+    if (message_size < KAFKA_MIN_REQUEST_SIZE - 4 || message_size >= KAFKA_MAX_MESSAGE_SIZE) {
+        return 0;
+    }
+    if (api_key != KAFKA_API_PRODUCE && api_key != KAFKA_API_FETCH) {
+        return 0;
+    }
+    if (api_version < 0 || api_version > KAFKA_MAX_API_VERSION || correlation_id < 0) {
+        return 0;
+    }
+    if (client_id_size < -1 || client_id_size > message_size - (KAFKA_MIN_REQUEST_SIZE - 4)) {
+        return 0;
+    }
+
+#pragma unroll
+    for (int i = 0; i < KAFKA_MAX_CLIENT_ID_SIZE; i++) {
+        if (i >= client_id_size || KAFKA_MIN_REQUEST_SIZE + i >= len) {
+            break;
+        }
+        char c = load_byte(skb, offset + KAFKA_MIN_REQUEST_SIZE + i);
+        if (c < ' ' || c > '~') {
+            return 0;
+        }
+    }
+    return 1;
+}
+
+static __always_inline void kafka_read_segment(struct __sk_buff *skb, u32 offset, u32 len, kafka_segment_t *segment) {
+#pragma unroll
+    for (int i = 0; i < KAFKA_BUFFER_SIZE; i++) {
+        if (i >= len) {
+            break;
+        }
+        segment->data[i] = load_byte(skb, offset + i);
+    }
+}
+
+// kafka_process sends the segments of the Kafka connections to userspace. A connection is
+// classified as Kafka when the client sends a Produce or Fetch request; the segments sent
+// before are not captured.
+static __always_inline int kafka_process(struct __sk_buff *skb, skb_info_t *skb_info, int from_client) {
+    u32 len = 0;
+    if (skb->len > skb_info->data_off) {
+        len = skb->len - skb_info->data_off;
+    }
+    u8 fin = (skb_info->tcp_flags & TCPHDR_FIN) != 0;
+
+    __u8 *conn = bpf_map_lookup_elem(&kafka_conns, &skb_info->tup);
+    if (conn == NULL) {
+        if (!from_client || !kafka_is_request(skb, skb_info->data_off, len)) {
+            return 0;
+        }
+        __u8 seen = 1;
+        bpf_map_update_elem(&kafka_conns, &skb_info->tup, &seen, BPF_NOEXIST);
+    }
+
+    if (len == 0 && !fin) {
+        return 0;
+    }
+
+    u32 cpu = bpf_get_smp_processor_id();
+    kafka_segment_t *segment = bpf_map_lookup_elem(&kafka_scratch, &cpu);
+    if (segment == NULL) {
+        return 0;
+    }
+
+    __builtin_memcpy(&segment->tup, &skb_info->tup, sizeof(conn_tuple_t));
+    segment->timestamp = bpf_ktime_get_ns();
+    segment->seq = skb_info->tcp_seq;
+    segment->segment_len = len;
+    segment->captured_len = len < KAFKA_BUFFER_SIZE ? len : KAFKA_BUFFER_SIZE;
+    segment->from_client = from_client;
+    segment->fin = fin;
+    kafka_read_segment(skb, skb_info->data_off, len, segment);
+    kafka_enqueue(segment);
+
+    if (fin) {
+        bpf_map_delete_elem(&kafka_conns, &skb_info->tup);
+    }
+
+    return 0;
+}
+
+#endif
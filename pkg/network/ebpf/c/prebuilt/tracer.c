@@ -363,6 +363,42 @@ int kprobe__tcp_retransmit_skb_pre_4_7_0(struct pt_regs* ctx) {
     return handle_retransmit(sk, 1);
 }
 
+SEC("kprobe/tcp_send_active_reset")
+int kprobe__tcp_send_active_reset(struct pt_regs* ctx) {
+    struct sock* sk = (struct sock*)PT_REGS_PARM1(ctx);
+    log_debug("kprobe/tcp_send_active_reset\n");
+
+    tcp_health_stats_t stats = { .rst_sent = 1 };
+    return handle_tcp_health_stats(sk, stats);
+}
+
+SEC("kprobe/tcp_reset")
+int kprobe__tcp_reset(struct pt_regs* ctx) {
+    struct sock* sk = (struct sock*)PT_REGS_PARM1(ctx);
+    log_debug("kprobe/tcp_reset\n");
+
+    tcp_health_stats_t stats = { .rst_received = 1 };
+    return handle_tcp_health_stats(sk, stats);
+}
+
+SEC("kprobe/tcp_send_probe0")
+int kprobe__tcp_send_probe0(struct pt_regs* ctx) {
+    struct sock* sk = (struct sock*)PT_REGS_PARM1(ctx);
+    log_debug("kprobe/tcp_send_probe0\n");
+
+    tcp_health_stats_t stats = { .zero_window_probes = 1 };
+    return handle_tcp_health_stats(sk, stats);
+}
+
+SEC("kprobe/tcp_data_queue_ofo")
+int kprobe__tcp_data_queue_ofo(struct pt_regs* ctx) {
+    struct sock* sk = (struct sock*)PT_REGS_PARM1(ctx);
+    log_debug("kprobe/tcp_data_queue_ofo\n");
+
+    tcp_health_stats_t stats = { .out_of_order = 1 };
+    return handle_tcp_health_stats(sk, stats);
+}
+
 SEC("kprobe/tcp_set_state")
 int kprobe__tcp_set_state(struct pt_regs* ctx) {
     u8 state = (u8)PT_REGS_PARM2(ctx);
@@ -19,6 +19,12 @@ static __always_inline __u16 read_sport(struct sock* skp) {
     return sport;
 }
 
+static __always_inline u8 read_sock_state(struct sock* skp) {
+    u8 state = 0;
+    bpf_probe_read(&state, sizeof(state), (void*)&skp->sk_state);
+    return state;
+}
+
 /**
  * Reads values into a `conn_tuple_t` from a `sock`. Any values that are already set in conn_tuple_t
  * are not overwritten. Returns 1 success, 0 otherwise.
@@ -3,7 +3,9 @@
 #include "ip.h"
 #include "ipv6.h"
 #include "http.h"
+#include "http2.h"
 #include "sockfd.h"
+#include "go-tls.h"
 #include "conn-tuple.h"
 
 // TODO: Replace those by injected constants based on system configuration
@@ -56,6 +58,7 @@ int socket__http_filter(struct __sk_buff* skb) {
     __builtin_memset(buffer, 0, sizeof(buffer));
     read_skb_data(skb, skb_info.data_off, buffer);
     http_process(buffer, &skb_info, src_port);
+    http2_process(skb, &skb_info, src_port == skb_info.tup.sport);
     return 0;
 }
 
@@ -64,15 +67,11 @@ int socket__http_filter(struct __sk_buff* skb) {
 SEC("kretprobe/tcp_sendmsg")
 int kretprobe__tcp_sendmsg(struct pt_regs* ctx) {
     http_notify_batch(ctx);
+    http2_notify_batch(ctx);
     return 0;
 }
 
-static __always_inline conn_tuple_t* tup_from_ssl_ctx(void *ssl_ctx, u64 pid_tgid) {
-    ssl_sock_t *ssl_sock = bpf_map_lookup_elem(&ssl_sock_by_ctx, &ssl_ctx);
-    if (ssl_sock == NULL) {
-        return NULL;
-    }
-
+static __always_inline conn_tuple_t* tup_from_ssl_sock(ssl_sock_t *ssl_sock, u64 pid_tgid) {
     if (ssl_sock->tup.sport != 0 && ssl_sock->tup.dport != 0) {
         return &ssl_sock->tup;
     }
@@ -101,6 +100,15 @@ static __always_inline conn_tuple_t* tup_from_ssl_ctx(void *ssl_ctx, u64 pid_tgi
     return &ssl_sock->tup;
 }
 
+static __always_inline conn_tuple_t* tup_from_ssl_ctx(void *ssl_ctx, u64 pid_tgid) {
+    ssl_sock_t *ssl_sock = bpf_map_lookup_elem(&ssl_sock_by_ctx, &ssl_ctx);
+    if (ssl_sock == NULL) {
+        return NULL;
+    }
+
+    return tup_from_ssl_sock(ssl_sock, pid_tgid);
+}
+
 static __always_inline void init_ssl_sock(void *ssl_ctx, u32 socket_fd) {
     ssl_sock_t ssl_sock = { 0 };
     ssl_sock.fd = socket_fd;
@@ -240,6 +248,160 @@ int uprobe__SSL_shutdown(struct pt_regs* ctx) {
     return 0;
 }
 
+static __always_inline conn_tuple_t* tup_from_go_tls_conn(void *conn, u64 pid_tgid, go_tls_offsets_t *offsets) {
+    go_tls_conn_key_t key;
+    __builtin_memset(&key, 0, sizeof(key));
+    key.pid = pid_tgid >> 32;
+    key.conn = conn;
+
+    ssl_sock_t *ssl_sock = bpf_map_lookup_elem(&go_tls_sock_by_conn, &key);
+    if (ssl_sock == NULL) {
+        // the code path below should be executed only once during the lifecycle of a TLS connection
+        ssl_sock_t new_sock = { 0 };
+        if (read_conn_fd(conn, offsets, &new_sock.fd)) {
+            return NULL;
+        }
+        bpf_map_update_elem(&go_tls_sock_by_conn, &key, &new_sock, BPF_ANY);
+        ssl_sock = bpf_map_lookup_elem(&go_tls_sock_by_conn, &key);
+        if (ssl_sock == NULL) {
+            return NULL;
+        }
+    }
+
+    return tup_from_ssl_sock(ssl_sock, pid_tgid);
+}
+
+// func (c *Conn) Write(b []byte) (int, error)
+SEC("uprobe/crypto_tls_conn_write")
+int uprobe__crypto_tls_conn_write(struct pt_regs* ctx) {
+    u64 pid_tgid = bpf_get_current_pid_tgid();
+    u32 pid = pid_tgid >> 32;
+    go_tls_offsets_t *offsets = bpf_map_lookup_elem(&go_tls_offsets, &pid);
+    if (offsets == NULL) {
+        return 0;
+    }
+
+    void *conn = (void *)GO_PARAM1(ctx);
+    conn_tuple_t *t = tup_from_go_tls_conn(conn, pid_tgid, offsets);
+    if (t == NULL) {
+        return 0;
+    }
+
+    void *tls_buffer = (void *)GO_PARAM2(ctx);
+    u64 len = (u64)GO_PARAM3(ctx);
+    char buffer[HTTP_BUFFER_SIZE];
+    __builtin_memset(buffer, 0, sizeof(buffer));
+    if (len >= HTTP_BUFFER_SIZE) {
+        bpf_probe_read(buffer, sizeof(buffer), tls_buffer);
+    }
+
+    skb_info_t skb_info = {0};
+    __builtin_memcpy(&skb_info.tup, t, sizeof(conn_tuple_t));
+    http_process(buffer, &skb_info, skb_info.tup.sport);
+    return 0;
+}
+
+// func (c *Conn) Read(b []byte) (int, error)
+SEC("uprobe/crypto_tls_conn_read")
+int uprobe__crypto_tls_conn_read(struct pt_regs* ctx) {
+    u64 pid_tgid = bpf_get_current_pid_tgid();
+    u32 pid = pid_tgid >> 32;
+    go_tls_offsets_t *offsets = bpf_map_lookup_elem(&go_tls_offsets, &pid);
+    if (offsets == NULL) {
+        return 0;
+    }
+
+    go_tls_read_key_t key;
+    __builtin_memset(&key, 0, sizeof(key));
+    key.pid = pid;
+    if (read_goroutine_id(ctx, offsets, &key.goroutine_id)) {
+        return 0;
+    }
+
+    go_tls_read_args_t args = {0};
+    args.conn = (void *)GO_PARAM1(ctx);
+    args.buf = (void *)GO_PARAM2(ctx);
+    bpf_map_update_elem(&go_tls_read_args, &key, &args, BPF_ANY);
+    return 0;
+}
+
+// Go doesn't support uretprobes, as they modify the stack which the Go runtime may move:
+// this uprobe is attached to each return instruction of crypto/tls.(*Conn).Read instead.
+SEC("uprobe/crypto_tls_conn_read_return")
+int uprobe__crypto_tls_conn_read_return(struct pt_regs* ctx) {
+    u64 pid_tgid = bpf_get_current_pid_tgid();
+    u32 pid = pid_tgid >> 32;
+    go_tls_offsets_t *offsets = bpf_map_lookup_elem(&go_tls_offsets, &pid);
+    if (offsets == NULL) {
+        return 0;
+    }
+
+    go_tls_read_key_t key;
+    __builtin_memset(&key, 0, sizeof(key));
+    key.pid = pid;
+    if (read_goroutine_id(ctx, offsets, &key.goroutine_id)) {
+        return 0;
+    }
+
+    go_tls_read_args_t *args = bpf_map_lookup_elem(&go_tls_read_args, &key);
+    if (args == NULL) {
+        return 0;
+    }
+
+    conn_tuple_t *t = tup_from_go_tls_conn(args->conn, pid_tgid, offsets);
+    if (t == NULL) {
+        goto cleanup;
+    }
+
+    // the number of bytes read is the first result
+    s64 len = (s64)GO_PARAM1(ctx);
+    char buffer[HTTP_BUFFER_SIZE];
+    __builtin_memset(buffer, 0, sizeof(buffer));
+    if (len >= HTTP_BUFFER_SIZE) {
+        bpf_probe_read(buffer, sizeof(buffer), args->buf);
+    }
+
+    skb_info_t skb_info = {0};
+    __builtin_memcpy(&skb_info.tup, t, sizeof(conn_tuple_t));
+    http_process(buffer, &skb_info, skb_info.tup.sport);
+ cleanup:
+    bpf_map_delete_elem(&go_tls_read_args, &key);
+    return 0;
+}
+
+// func (c *Conn) Close() error
+SEC("uprobe/crypto_tls_conn_close")
+int uprobe__crypto_tls_conn_close(struct pt_regs* ctx) {
+    u64 pid_tgid = bpf_get_current_pid_tgid();
+    u32 pid = pid_tgid >> 32;
+    go_tls_offsets_t *offsets = bpf_map_lookup_elem(&go_tls_offsets, &pid);
+    if (offsets == NULL) {
+        return 0;
+    }
+
+    void *conn = (void *)GO_PARAM1(ctx);
+    conn_tuple_t *t = tup_from_go_tls_conn(conn, pid_tgid, offsets);
+    if (t == NULL) {
+        return 0;
+    }
+
+    char buffer[HTTP_BUFFER_SIZE];
+    __builtin_memset(buffer, 0, sizeof(buffer));
+
+    skb_info_t skb_info = {0};
+    __builtin_memcpy(&skb_info.tup, t, sizeof(conn_tuple_t));
+
+    skb_info.tcp_flags |= TCPHDR_FIN;
+    http_process(buffer, &skb_info, skb_info.tup.sport);
+
+    go_tls_conn_key_t key;
+    __builtin_memset(&key, 0, sizeof(key));
+    key.pid = pid;
+    key.conn = conn;
+    bpf_map_delete_elem(&go_tls_sock_by_conn, &key);
+    return 0;
+}
+
 SEC("kprobe/do_sys_open")
 int kprobe__do_sys_open(struct pt_regs* ctx) {
     char *path_argument = (char *)PT_REGS_PARM2(ctx);
@@ -20,6 +20,24 @@ static __always_inline bool dns_stats_enabled() {
     return val == ENABLED;
 }
 
+static __always_inline bool http2_monitoring_enabled() {
+    __u64 val = 0;
+    LOAD_CONSTANT("http2_monitoring_enabled", val);
+    return val == ENABLED;
+}
+
+static __always_inline bool postgres_monitoring_enabled() {
+    __u64 val = 0;
+    LOAD_CONSTANT("postgres_monitoring_enabled", val);
+    return val == ENABLED;
+}
+
+static __always_inline bool mysql_monitoring_enabled() {
+    __u64 val = 0;
+    LOAD_CONSTANT("mysql_monitoring_enabled", val);
+    return val == ENABLED;
+}
+
 static __always_inline __u64 offset_family() {
     __u64 val = 0;
     LOAD_CONSTANT("offset_family", val);
@@ -177,6 +195,13 @@ static __always_inline bool check_family(struct sock* sk, u16 expected_family) {
     return family == expected_family;
 }
 
+static __always_inline u8 read_sock_state(struct sock* sk) {
+    u8 state = 0;
+    // skc_state directly follows skc_family in struct sock_common
+    bpf_probe_read(&state, sizeof(state), ((char*)sk) + offset_family() + sizeof(u16));
+    return state;
+}
+
 /**
  * Reads values into a `conn_tuple_t` from a `sock`. Any values that are already set in conn_tuple_t
  * are not overwritten. Returns 1 success, 0 otherwise.
@@ -136,4 +136,18 @@ struct bpf_map_def SEC("maps/do_sendfile_args") do_sendfile_args = {
     .namespace = "",
 };
 
+/* This is a key/value store with the keys being a conn_tuple_t (but without the PID being used)
+ * and the values being a tcp_health_stats_t *. Entries are not removed when the connection is
+ * closed, so the RSTs sent by tcp_close are counted: user space deletes them once it has read
+ * the closed connection.
+ */
+struct bpf_map_def SEC("maps/tcp_health_stats") tcp_health_stats = {
+    .type = BPF_MAP_TYPE_HASH,
+    .key_size = sizeof(conn_tuple_t),
+    .value_size = sizeof(tcp_health_stats_t),
+    .max_entries = 0, // This will get overridden at runtime using max_tracked_connections
+    .pinning = 0,
+    .namespace = "",
+};
+
 #endif
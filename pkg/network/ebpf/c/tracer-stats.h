@@ -4,6 +4,7 @@
 #include "tracer.h"
 
 static int read_conn_tuple(conn_tuple_t *t, struct sock *skp, u64 pid_tgid, metadata_mask_t type);
+static u8 read_sock_state(struct sock *skp);
 
 static __always_inline void update_conn_state(conn_tuple_t *t, conn_stats_ts_t *stats, size_t sent_bytes, size_t recv_bytes) {
     if (t->metadata & CONN_TYPE_TCP || stats->flags & CONN_ASSURED) {
@@ -123,6 +124,42 @@ static __always_inline int handle_message(conn_tuple_t *t, size_t sent_bytes, si
     return 0;
 }
 
+static __always_inline void update_tcp_health_stats(conn_tuple_t *t, tcp_health_stats_t stats) {
+    // query stats without the PID from the tuple
+    __u32 pid = t->pid;
+    t->pid = 0;
+
+    // initialize-if-no-exist the connection health, and load it
+    tcp_health_stats_t empty = {};
+    bpf_map_update_elem(&tcp_health_stats, t, &empty, BPF_NOEXIST);
+
+    tcp_health_stats_t *val = bpf_map_lookup_elem(&tcp_health_stats, t);
+    t->pid = pid;
+    if (val == NULL) {
+        return;
+    }
+
+    if (stats.rst_sent > 0) {
+        __sync_fetch_and_add(&val->rst_sent, stats.rst_sent);
+    }
+
+    if (stats.rst_received > 0) {
+        __sync_fetch_and_add(&val->rst_received, stats.rst_received);
+    }
+
+    if (stats.zero_window_probes > 0) {
+        __sync_fetch_and_add(&val->zero_window_probes, stats.zero_window_probes);
+    }
+
+    if (stats.syn_retransmits > 0) {
+        __sync_fetch_and_add(&val->syn_retransmits, stats.syn_retransmits);
+    }
+
+    if (stats.out_of_order > 0) {
+        __sync_fetch_and_add(&val->out_of_order, stats.out_of_order);
+    }
+}
+
 static __always_inline int handle_retransmit(struct sock *sk, int segs) {
     conn_tuple_t t = {};
     u64 zero = 0;
@@ -134,6 +171,25 @@ static __always_inline int handle_retransmit(struct sock *sk, int segs) {
     tcp_stats_t stats = { .retransmits = segs, .rtt = 0, .rtt_var = 0 };
     update_tcp_stats(&t, stats);
 
+    // the SYN of a connection which is not established yet is being retransmitted
+    if (read_sock_state(sk) == TCP_SYN_SENT) {
+        tcp_health_stats_t health = { .syn_retransmits = segs };
+        update_tcp_health_stats(&t, health);
+    }
+
+    return 0;
+}
+
+static __always_inline int handle_tcp_health_stats(struct sock *sk, tcp_health_stats_t stats) {
+    conn_tuple_t t = {};
+    u64 zero = 0;
+
+    if (!read_conn_tuple(&t, sk, zero, CONN_TYPE_TCP)) {
+        return 0;
+    }
+
+    update_tcp_health_stats(&t, stats);
+
     return 0;
 }
 
@@ -93,12 +93,13 @@ typedef struct {
 
 // skb_info_t embeds a conn_tuple_t extracted from the skb object as well as
 // some ancillary data such as the data offset (the byte offset pointing to
-// where the application payload begins) and the TCP flags if applicable.
+// where the application payload begins), the TCP sequence number and flags if applicable.
 // This struct is populated by calling `read_conn_tuple_skb` from a program type
 // that manipulates a `__sk_buff` object.
 typedef struct {
     conn_tuple_t tup;
     __u32 data_off;
+    __u32 tcp_seq;
     __u8 tcp_flags;
 } skb_info_t;
 
@@ -151,4 +152,17 @@ typedef struct {
     __u32 fd;
 } pid_fd_t;
 
+// TCP health counters of a connection. They are kept apart from tcp_stats_t so the
+// connections batched on close don't grow, and are deleted by user space once read
+typedef struct {
+    __u32 rst_sent;
+    __u32 rst_received;
+    // Zero window probes sent, while the peer advertises a zero receive window
+    __u32 zero_window_probes;
+    // SYN segments retransmitted while connecting
+    __u32 syn_retransmits;
+    // Segments received out of order
+    __u32 out_of_order;
+} tcp_health_stats_t;
+
 #endif
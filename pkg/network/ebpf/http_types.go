@@ -18,3 +18,5 @@ type HTTPConnTuple C.conn_tuple_t
 type HTTPBatchState C.http_batch_state_t
 type SSLSock C.ssl_sock_t
 type SSLReadArgs C.ssl_read_args_t
+type GoTLSOffsets C.go_tls_offsets_t
+type GoTLSConnKey C.go_tls_conn_key_t
@@ -28,3 +28,15 @@ type SSLReadArgs struct {
 	Ctx *byte
 	Buf *byte
 }
+type GoTLSOffsets struct {
+	Goroutine_id_offset uint64
+	Conn_conn_offset    uint64
+	Net_conn_fd_offset  uint64
+	Netfd_pfd_offset    uint64
+	Pfd_sysfd_offset    uint64
+}
+type GoTLSConnKey struct {
+	Pid       uint32
+	Pad_cgo_0 [4]byte
+	Conn      *byte
+}
@@ -67,6 +67,13 @@ type UDPRecvSock struct {
 type BindSyscallArgs struct {
 	Port uint16
 }
+type TCPHealthStats struct {
+	Rst_sent           uint32
+	Rst_received       uint32
+	Zero_window_probes uint32
+	Syn_retransmits    uint32
+	Out_of_order       uint32
+}
 
 type _Ctype_struct_sock uint64
 type _Ctype_struct_msghdr uint64
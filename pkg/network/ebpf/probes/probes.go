@@ -73,6 +73,15 @@ const (
 	TCPRetransmit       ProbeName = "kprobe/tcp_retransmit_skb"
 	TCPRetransmitPre470 ProbeName = "kprobe/tcp_retransmit_skb/pre_4_7_0"
 
+	// TCPSendActiveReset traces the tcp_send_active_reset() function, which sends a RST
+	TCPSendActiveReset ProbeName = "kprobe/tcp_send_active_reset"
+	// TCPReset traces the tcp_reset() function, called when a RST is received
+	TCPReset ProbeName = "kprobe/tcp_reset"
+	// TCPSendProbe0 traces the tcp_send_probe0() function, which sends zero window probes
+	TCPSendProbe0 ProbeName = "kprobe/tcp_send_probe0"
+	// TCPDataQueueOFO traces the tcp_data_queue_ofo() function, which queues segments received out of order
+	TCPDataQueueOFO ProbeName = "kprobe/tcp_data_queue_ofo"
+
 	// InetCskAcceptReturn traces the return value for the inet_csk_accept syscall
 	InetCskAcceptReturn ProbeName = "kretprobe/inet_csk_accept"
 
@@ -129,6 +138,7 @@ const (
 	ConntrackTelemetryMap BPFMapName = "conntrack_telemetry"
 	SockFDLookupArgsMap   BPFMapName = "sockfd_lookup_args"
 	DoSendfileArgsMap     BPFMapName = "do_sendfile_args"
+	TcpHealthStatsMap     BPFMapName = "tcp_health_stats"
 	SockByPidFDMap        BPFMapName = "sock_by_pid_fd"
 	PidFDBySockMap        BPFMapName = "pid_fd_by_sock"
 )
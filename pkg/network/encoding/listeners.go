@@ -0,0 +1,72 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package encoding
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/network"
+)
+
+// Listener is a listening socket along with the process owning it. The process
+// is identified by its PID, from which its container can be resolved.
+type Listener struct {
+	Pid      uint32 `json:"pid"`
+	NetNS    uint32 `json:"netns"`
+	Protocol string `json:"protocol"`
+	Family   string `json:"family"`
+	Addr     string `json:"addr"`
+	Port     uint16 `json:"port"`
+}
+
+// ListenerEvent is a new listener on a port which is not allowed
+type ListenerEvent struct {
+	Listener
+	Timestamp time.Time `json:"timestamp"`
+}
+
+func formatListener(l network.Listener) Listener {
+	return Listener{
+		Pid:      l.Pid,
+		NetNS:    l.NetNS,
+		Protocol: strings.ToLower(l.Type.String()),
+		Family:   l.Family.String(),
+		Addr:     l.Addr.String(),
+		Port:     l.Port,
+	}
+}
+
+// FormatListeners formats the listeners, sorted by protocol, port and address
+func FormatListeners(listeners []network.Listener) []Listener {
+	result := make([]Listener, 0, len(listeners))
+	for _, l := range listeners {
+		result = append(result, formatListener(l))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Protocol != result[j].Protocol {
+			return result[i].Protocol < result[j].Protocol
+		}
+		if result[i].Port != result[j].Port {
+			return result[i].Port < result[j].Port
+		}
+		if result[i].Addr != result[j].Addr {
+			return result[i].Addr < result[j].Addr
+		}
+		return result[i].NetNS < result[j].NetNS
+	})
+	return result
+}
+
+// FormatListenerEvents formats the listener events, keeping their order
+func FormatListenerEvents(events []network.ListenerEvent) []ListenerEvent {
+	result := make([]ListenerEvent, 0, len(events))
+	for _, e := range events {
+		result = append(result, ListenerEvent{Listener: formatListener(e.Listener), Timestamp: e.Timestamp})
+	}
+	return result
+}
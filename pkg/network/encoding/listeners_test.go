@@ -0,0 +1,47 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package encoding
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/network"
+	"github.com/DataDog/datadog-agent/pkg/process/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatListeners(t *testing.T) {
+	listeners := []network.Listener{
+		{Pid: 30, NetNS: 1, Type: network.UDP, Family: network.AFINET, Addr: util.AddressFromString("0.0.0.0"), Port: 53},
+		{Pid: 10, NetNS: 1, Type: network.TCP, Family: network.AFINET6, Addr: util.AddressFromString("::"), Port: 22},
+		{Pid: 20, NetNS: 2, Type: network.TCP, Family: network.AFINET, Addr: util.AddressFromString("127.0.0.1"), Port: 8080},
+		{Pid: 10, NetNS: 1, Type: network.TCP, Family: network.AFINET, Addr: util.AddressFromString("0.0.0.0"), Port: 22},
+	}
+
+	assert.Equal(t, []Listener{
+		{Pid: 10, NetNS: 1, Protocol: "tcp", Family: "v4", Addr: "0.0.0.0", Port: 22},
+		{Pid: 10, NetNS: 1, Protocol: "tcp", Family: "v6", Addr: "::", Port: 22},
+		{Pid: 20, NetNS: 2, Protocol: "tcp", Family: "v4", Addr: "127.0.0.1", Port: 8080},
+		{Pid: 30, NetNS: 1, Protocol: "udp", Family: "v4", Addr: "0.0.0.0", Port: 53},
+	}, FormatListeners(listeners))
+}
+
+func TestFormatListenerEvents(t *testing.T) {
+	ts := time.Date(2021, 11, 3, 10, 0, 0, 0, time.UTC)
+	events := FormatListenerEvents([]network.ListenerEvent{
+		{
+			Listener:  network.Listener{Pid: 42, NetNS: 1, Type: network.TCP, Family: network.AFINET, Addr: util.AddressFromString("0.0.0.0"), Port: 4444},
+			Timestamp: ts,
+		},
+	})
+
+	b, err := json.Marshal(events)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"pid":42,"netns":1,"protocol":"tcp","family":"v4","addr":"0.0.0.0","port":4444,"timestamp":"2021-11-03T10:00:00Z"}]`, string(b))
+}
@@ -0,0 +1,76 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package encoding
+
+import (
+	"sort"
+
+	"github.com/DataDog/datadog-agent/pkg/network"
+	"github.com/DataDog/datadog-agent/pkg/process/util"
+)
+
+// TCPHealth holds the TCP health events of the connections between the host and a remote endpoint.
+// The connections are identified by the port of the service: the remote port for the outgoing
+// connections, and the local one for the incoming connections.
+type TCPHealth struct {
+	RemoteAddr string `json:"remote_addr"`
+	Port       uint16 `json:"port"`
+	Direction  string `json:"direction"`
+	// Connections is the number of connections with events
+	Connections int                    `json:"connections"`
+	Stats       network.TCPHealthStats `json:"stats"`
+}
+
+type tcpHealthKey struct {
+	remote    util.Address
+	port      uint16
+	direction network.ConnectionDirection
+}
+
+// FormatTCPHealth aggregates by remote endpoint the TCP health events counted since the last
+// request of the client, leaving out the connections without any event
+func FormatTCPHealth(conns []network.ConnectionStats) []TCPHealth {
+	byKey := make(map[tcpHealthKey]*TCPHealth)
+	var health []*TCPHealth
+	for i := range conns {
+		conn := &conns[i]
+		if conn.Type != network.TCP || conn.LastTCPHealth.IsZero() {
+			continue
+		}
+
+		key := tcpHealthKey{remote: conn.Dest, port: conn.DPort, direction: conn.Direction}
+		if conn.Direction == network.INCOMING {
+			key.port = conn.SPort
+		}
+		h, ok := byKey[key]
+		if !ok {
+			h = &TCPHealth{
+				RemoteAddr: conn.Dest.String(),
+				Port:       key.port,
+				Direction:  conn.Direction.String(),
+			}
+			byKey[key] = h
+			health = append(health, h)
+		}
+		h.Connections++
+		h.Stats = h.Stats.Add(conn.LastTCPHealth)
+	}
+
+	result := make([]TCPHealth, 0, len(health))
+	for _, h := range health {
+		result = append(result, *h)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].RemoteAddr != result[j].RemoteAddr {
+			return result[i].RemoteAddr < result[j].RemoteAddr
+		}
+		if result[i].Port != result[j].Port {
+			return result[i].Port < result[j].Port
+		}
+		return result[i].Direction < result[j].Direction
+	})
+	return result
+}
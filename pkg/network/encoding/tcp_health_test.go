@@ -0,0 +1,65 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package encoding
+
+import (
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/network"
+	"github.com/DataDog/datadog-agent/pkg/process/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatTCPHealth(t *testing.T) {
+	local := util.AddressFromString("10.1.1.1")
+	server := util.AddressFromString("10.2.2.2")
+	client := util.AddressFromString("10.3.3.3")
+
+	conns := []network.ConnectionStats{
+		{
+			Source: local, Dest: server, SPort: 40000, DPort: 5432,
+			Type: network.TCP, Direction: network.OUTGOING,
+			LastTCPHealth: network.TCPHealthStats{RSTReceived: 1, SYNRetransmits: 2},
+		},
+		{
+			Source: local, Dest: server, SPort: 40001, DPort: 5432,
+			Type: network.TCP, Direction: network.OUTGOING,
+			LastTCPHealth: network.TCPHealthStats{RSTReceived: 1, OutOfOrder: 4},
+		},
+		{
+			// no event since the last request
+			Source: local, Dest: server, SPort: 40002, DPort: 5432,
+			Type: network.TCP, Direction: network.OUTGOING,
+			MonotonicTCPHealth: network.TCPHealthStats{RSTSent: 3},
+		},
+		{
+			Source: local, Dest: client, SPort: 8080, DPort: 50000,
+			Type: network.TCP, Direction: network.INCOMING,
+			LastTCPHealth: network.TCPHealthStats{RSTSent: 1, ZeroWindowProbes: 5},
+		},
+		{
+			Source: local, Dest: server, SPort: 40003, DPort: 53,
+			Type: network.UDP, Direction: network.OUTGOING,
+		},
+	}
+
+	assert.Equal(t, []TCPHealth{
+		{
+			RemoteAddr:  "10.2.2.2",
+			Port:        5432,
+			Direction:   "outgoing",
+			Connections: 2,
+			Stats:       network.TCPHealthStats{RSTReceived: 2, SYNRetransmits: 2, OutOfOrder: 4},
+		},
+		{
+			RemoteAddr:  "10.3.3.3",
+			Port:        8080,
+			Direction:   "incoming",
+			Connections: 1,
+			Stats:       network.TCPHealthStats{RSTSent: 1, ZeroWindowProbes: 5},
+		},
+	}, FormatTCPHealth(conns))
+}
@@ -11,8 +11,10 @@ import (
 	"strings"
 	"time"
 
+	"github.com/DataDog/datadog-agent/pkg/network/database"
 	"github.com/DataDog/datadog-agent/pkg/network/dns"
 	"github.com/DataDog/datadog-agent/pkg/network/http"
+	"github.com/DataDog/datadog-agent/pkg/network/kafka"
 	"github.com/DataDog/datadog-agent/pkg/process/util"
 	"github.com/dustin/go-humanize"
 )
@@ -121,6 +123,8 @@ type Connections struct {
 	ConnTelemetry               map[ConnTelemetryType]int64
 	CompilationTelemetryByAsset map[string]RuntimeCompilationTelemetry
 	HTTP                        map[http.Key]http.RequestStats
+	Kafka                       map[kafka.Key]kafka.RequestStats
+	Database                    map[database.Key]database.RequestStats
 	DNSStats                    dns.StatsByKeyByNameByType
 }
 
@@ -145,6 +149,11 @@ const (
 	MonotonicDNSPacketsDropped         ConnTelemetryType = "dns_packets_dropped"
 	HTTPRequestsDropped                ConnTelemetryType = "http_requests_dropped"
 	HTTPRequestsMissed                 ConnTelemetryType = "http_requests_missed"
+	TCPRSTSent                         ConnTelemetryType = "tcp_rst_sent"
+	TCPRSTReceived                     ConnTelemetryType = "tcp_rst_received"
+	TCPZeroWindowProbes                ConnTelemetryType = "tcp_zero_window_probes"
+	TCPSYNRetransmits                  ConnTelemetryType = "tcp_syn_retransmits"
+	TCPOutOfOrder                      ConnTelemetryType = "tcp_out_of_order"
 )
 
 //revive:enable
@@ -159,6 +168,11 @@ var (
 		NPMDriverFlowsMissedMaxExceeded,
 		HTTPRequestsDropped,
 		HTTPRequestsMissed,
+		TCPRSTSent,
+		TCPRSTReceived,
+		TCPZeroWindowProbes,
+		TCPSYNRetransmits,
+		TCPOutOfOrder,
 	}
 
 	// MonotonicConnTelemetryTypes lists all the possible monotonic telemetry which can be bundled
@@ -222,6 +236,9 @@ type ConnectionStats struct {
 	MonotonicTCPClosed uint32
 	LastTCPClosed      uint32
 
+	MonotonicTCPHealth TCPHealthStats
+	LastTCPHealth      TCPHealthStats
+
 	Pid   uint32
 	NetNS uint32
 
@@ -238,6 +255,63 @@ type ConnectionStats struct {
 	IsAssured bool
 }
 
+// TCPHealthStats counts the TCP events revealing an unhealthy connection
+type TCPHealthStats struct {
+	RSTSent     uint32 `json:"rst_sent"`
+	RSTReceived uint32 `json:"rst_received"`
+	// ZeroWindowProbes counts the probes sent while the peer advertises a zero receive window
+	ZeroWindowProbes uint32 `json:"zero_window_probes"`
+	// SYNRetransmits counts the SYN segments retransmitted while connecting
+	SYNRetransmits uint32 `json:"syn_retransmits"`
+	// OutOfOrder counts the segments received out of order
+	OutOfOrder uint32 `json:"out_of_order"`
+}
+
+// Add returns the sum of s and o
+func (s TCPHealthStats) Add(o TCPHealthStats) TCPHealthStats {
+	return TCPHealthStats{
+		RSTSent:          s.RSTSent + o.RSTSent,
+		RSTReceived:      s.RSTReceived + o.RSTReceived,
+		ZeroWindowProbes: s.ZeroWindowProbes + o.ZeroWindowProbes,
+		SYNRetransmits:   s.SYNRetransmits + o.SYNRetransmits,
+		OutOfOrder:       s.OutOfOrder + o.OutOfOrder,
+	}
+}
+
+// Sub returns the difference of s and o
+func (s TCPHealthStats) Sub(o TCPHealthStats) TCPHealthStats {
+	return TCPHealthStats{
+		RSTSent:          s.RSTSent - o.RSTSent,
+		RSTReceived:      s.RSTReceived - o.RSTReceived,
+		ZeroWindowProbes: s.ZeroWindowProbes - o.ZeroWindowProbes,
+		SYNRetransmits:   s.SYNRetransmits - o.SYNRetransmits,
+		OutOfOrder:       s.OutOfOrder - o.OutOfOrder,
+	}
+}
+
+// IsZero returns whether none of the events was counted
+func (s TCPHealthStats) IsZero() bool {
+	return s == TCPHealthStats{}
+}
+
+// resetWrapped returns the totals s with the counters greater than the ones of current reset:
+// these counters wrapped, or restarted from zero, since s was recorded
+func (s TCPHealthStats) resetWrapped(current TCPHealthStats) TCPHealthStats {
+	reset := func(total, current uint32) uint32 {
+		if current < total {
+			return 0
+		}
+		return total
+	}
+	return TCPHealthStats{
+		RSTSent:          reset(s.RSTSent, current.RSTSent),
+		RSTReceived:      reset(s.RSTReceived, current.RSTReceived),
+		ZeroWindowProbes: reset(s.ZeroWindowProbes, current.ZeroWindowProbes),
+		SYNRetransmits:   reset(s.SYNRetransmits, current.SYNRetransmits),
+		OutOfOrder:       reset(s.OutOfOrder, current.OutOfOrder),
+	}
+}
+
 // Via has info about the routing decision for a flow
 type Via struct {
 	Subnet Subnet
@@ -268,8 +342,9 @@ func (c ConnectionStats) IsExpired(now uint64, timeout uint64) bool {
 // ByteKey returns a unique key for this connection represented as a byte array
 // It's as following:
 //
-//     4B      2B      2B     .5B     .5B      4/16B        4/16B   = 17/41B
-//    32b     16b     16b      4b      4b     32/128b      32/128b
+//	 4B      2B      2B     .5B     .5B      4/16B        4/16B   = 17/41B
+//	32b     16b     16b      4b      4b     32/128b      32/128b
+//
 // |  PID  | SPORT | DPORT | Family | Type |  SrcAddr  |  DestAddr
 func (c ConnectionStats) ByteKey(buf []byte) ([]byte, error) {
 	n := 0
@@ -366,6 +441,16 @@ func ConnectionSummary(c *ConnectionStats, names map[util.Address][]string) stri
 			time.Duration(c.RTT)*time.Microsecond,
 			time.Duration(c.RTTVar)*time.Microsecond,
 		)
+		if !c.MonotonicTCPHealth.IsZero() {
+			h, l := c.MonotonicTCPHealth, c.LastTCPHealth
+			str += fmt.Sprintf(
+				", RST %d sent (+%d) %d received (+%d), %d zero window probes (+%d), %d SYN retransmits (+%d), %d out of order (+%d)",
+				h.RSTSent, l.RSTSent, h.RSTReceived, l.RSTReceived,
+				h.ZeroWindowProbes, l.ZeroWindowProbes,
+				h.SYNRetransmits, l.SYNRetransmits,
+				h.OutOfOrder, l.OutOfOrder,
+			)
+		}
 	}
 
 	return str
@@ -0,0 +1,423 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package flowexport
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/network"
+	"github.com/DataDog/datadog-agent/pkg/process/util"
+)
+
+// Supported export protocols
+const (
+	ProtocolIPFIX     = "ipfix"
+	ProtocolNetFlowV9 = "netflow9"
+)
+
+const (
+	// maxMessageSize keeps the messages within the MTU of most networks, since a flow collector
+	// cannot reassemble a record split across UDP datagrams
+	maxMessageSize = 1400
+
+	ipfixVersion       = 10
+	ipfixHeaderSize    = 16
+	ipfixTemplateSet   = 2
+	netflowV9Version   = 9
+	netflowHeaderSize  = 20
+	netflowTemplateSet = 0
+	setHeaderSize      = 4
+
+	templateIDv4 = 256
+	templateIDv6 = 257
+
+	// variableLength is the field length of the IPFIX variable-length information elements
+	variableLength = 0xffff
+	// enterpriseBit flags the IPFIX information elements followed by a private enterprise number
+	enterpriseBit = 0x8000
+)
+
+// Information elements, from the IANA IPFIX registry. The identifiers below 128 are the NetFlow v9 field types.
+const (
+	ieOctetDeltaCount                  = 1
+	iePacketDeltaCount                 = 2
+	ieProtocolIdentifier               = 4
+	ieSourceTransportPort              = 7
+	ieSourceIPv4Address                = 8
+	ieDestinationTransportPort         = 11
+	ieDestinationIPv4Address           = 12
+	ieFlowEndSysUpTime                 = 21
+	ieFlowStartSysUpTime               = 22
+	ieSourceIPv6Address                = 27
+	ieDestinationIPv6Address           = 28
+	ieFlowDirection                    = 61
+	ieFlowStartMilliseconds            = 152
+	ieFlowEndMilliseconds              = 153
+	iePostNATSourceIPv4Address         = 225
+	iePostNATDestinationIPv4Address    = 226
+	iePostNAPTSourceTransportPort      = 227
+	iePostNAPTDestinationTransportPort = 228
+	iePostNATSourceIPv6Address         = 281
+	iePostNATDestinationIPv6Address    = 282
+)
+
+// Enterprise-specific information elements, exported under the configured private enterprise number
+const (
+	ieProcessID = iota + 1
+	ieProcessName
+	ieContainerID
+	ieSourceHostname
+	ieDestinationHostname
+)
+
+type field struct {
+	id         uint16
+	length     uint16
+	enterprise bool
+}
+
+type template struct {
+	id     uint16
+	fields []field
+}
+
+// encoder serializes flow records into IPFIX or NetFlow v9 messages.
+// It is not safe for concurrent use.
+type encoder struct {
+	protocol         string
+	domainID         uint32
+	enterpriseNumber uint32
+	templates        map[network.ConnectionFamily]template
+
+	// start is the time the exporter started, the system uptime of NetFlow v9
+	start time.Time
+	// sequence is the number of messages sent for NetFlow v9, and the number of data records sent for IPFIX
+	sequence uint32
+}
+
+func newEncoder(protocol string, domainID, enterpriseNumber uint32, start time.Time) (*encoder, error) {
+	if protocol != ProtocolIPFIX && protocol != ProtocolNetFlowV9 {
+		return nil, fmt.Errorf("unsupported flow export protocol %q, expected %q or %q", protocol, ProtocolIPFIX, ProtocolNetFlowV9)
+	}
+
+	e := &encoder{
+		protocol:         protocol,
+		domainID:         domainID,
+		enterpriseNumber: enterpriseNumber,
+		start:            start,
+	}
+	e.templates = map[network.ConnectionFamily]template{
+		network.AFINET: {
+			id: templateIDv4,
+			fields: e.templateFields(
+				field{id: ieSourceIPv4Address, length: 4},
+				field{id: ieDestinationIPv4Address, length: 4},
+				field{id: iePostNATSourceIPv4Address, length: 4},
+				field{id: iePostNATDestinationIPv4Address, length: 4},
+			),
+		},
+		network.AFINET6: {
+			id: templateIDv6,
+			fields: e.templateFields(
+				field{id: ieSourceIPv6Address, length: 16},
+				field{id: ieDestinationIPv6Address, length: 16},
+				field{id: iePostNATSourceIPv6Address, length: 16},
+				field{id: iePostNATDestinationIPv6Address, length: 16},
+			),
+		},
+	}
+	return e, nil
+}
+
+// templateFields returns the fields of a template, given the source, destination,
+// post-NAT source and post-NAT destination address fields of its family
+func (e *encoder) templateFields(src, dst, postNATSrc, postNATDst field) []field {
+	fields := []field{
+		src,
+		dst,
+		{id: ieSourceTransportPort, length: 2},
+		{id: ieDestinationTransportPort, length: 2},
+		{id: ieProtocolIdentifier, length: 1},
+		{id: ieFlowDirection, length: 1},
+		{id: ieOctetDeltaCount, length: 8},
+		{id: iePacketDeltaCount, length: 8},
+		postNATSrc,
+		postNATDst,
+		{id: iePostNAPTSourceTransportPort, length: 2},
+		{id: iePostNAPTDestinationTransportPort, length: 2},
+	}
+
+	if e.protocol == ProtocolNetFlowV9 {
+		// NetFlow v9 has neither absolute timestamps nor enterprise-specific fields
+		return append(fields,
+			field{id: ieFlowStartSysUpTime, length: 4},
+			field{id: ieFlowEndSysUpTime, length: 4},
+		)
+	}
+
+	fields = append(fields,
+		field{id: ieFlowStartMilliseconds, length: 8},
+		field{id: ieFlowEndMilliseconds, length: 8},
+	)
+	if e.enterpriseNumber != 0 {
+		fields = append(fields,
+			field{id: ieProcessID, length: 4, enterprise: true},
+			field{id: ieProcessName, length: variableLength, enterprise: true},
+			field{id: ieContainerID, length: variableLength, enterprise: true},
+			field{id: ieSourceHostname, length: variableLength, enterprise: true},
+			field{id: ieDestinationHostname, length: variableLength, enterprise: true},
+		)
+	}
+	return fields
+}
+
+// encode serializes the templates, then the records, into as many messages as needed
+func (e *encoder) encode(records []flowRecord, now time.Time) [][]byte {
+	var messages [][]byte
+
+	m := e.newMessage()
+	for _, family := range []network.ConnectionFamily{network.AFINET, network.AFINET6} {
+		m.addTemplate(e.templates[family])
+	}
+	messages = append(messages, e.finishMessage(m, now))
+
+	m = e.newMessage()
+	for i := range records {
+		r := &records[i]
+		t, ok := e.templates[r.family]
+		if !ok {
+			continue
+		}
+
+		record := e.encodeRecord(t, r)
+		if !m.fits(t.id, record) && m.count > 0 {
+			messages = append(messages, e.finishMessage(m, now))
+			m = e.newMessage()
+		}
+		m.addRecord(t.id, record)
+	}
+	if m.count > 0 {
+		messages = append(messages, e.finishMessage(m, now))
+	}
+	return messages
+}
+
+func (e *encoder) encodeRecord(t template, r *flowRecord) []byte {
+	b := make([]byte, 0, 128)
+	for _, f := range t.fields {
+		if f.enterprise {
+			switch f.id {
+			case ieProcessID:
+				b = appendUint32(b, r.pid)
+			case ieProcessName:
+				b = appendVariableLength(b, r.process.name)
+			case ieContainerID:
+				b = appendVariableLength(b, r.process.containerID)
+			case ieSourceHostname:
+				b = appendVariableLength(b, r.srcHostname)
+			case ieDestinationHostname:
+				b = appendVariableLength(b, r.dstHostname)
+			}
+			continue
+		}
+
+		switch f.id {
+		case ieSourceIPv4Address, ieSourceIPv6Address:
+			b = appendAddress(b, r.srcAddr, f.length)
+		case ieDestinationIPv4Address, ieDestinationIPv6Address:
+			b = appendAddress(b, r.dstAddr, f.length)
+		case iePostNATSourceIPv4Address, iePostNATSourceIPv6Address:
+			b = appendAddress(b, r.postNATSrcAddr, f.length)
+		case iePostNATDestinationIPv4Address, iePostNATDestinationIPv6Address:
+			b = appendAddress(b, r.postNATDstAddr, f.length)
+		case ieSourceTransportPort:
+			b = appendUint16(b, r.srcPort)
+		case ieDestinationTransportPort:
+			b = appendUint16(b, r.dstPort)
+		case iePostNAPTSourceTransportPort:
+			b = appendUint16(b, r.postNATSrcPort)
+		case iePostNAPTDestinationTransportPort:
+			b = appendUint16(b, r.postNATDstPort)
+		case ieProtocolIdentifier:
+			b = append(b, r.protocol)
+		case ieFlowDirection:
+			b = append(b, r.direction)
+		case ieOctetDeltaCount:
+			b = appendUint64(b, r.octets)
+		case iePacketDeltaCount:
+			b = appendUint64(b, r.packets)
+		case ieFlowStartMilliseconds:
+			b = appendUint64(b, uint64(r.start.UnixMilli()))
+		case ieFlowEndMilliseconds:
+			b = appendUint64(b, uint64(r.end.UnixMilli()))
+		case ieFlowStartSysUpTime:
+			b = appendUint32(b, e.sysUpTime(r.start))
+		case ieFlowEndSysUpTime:
+			b = appendUint32(b, e.sysUpTime(r.end))
+		}
+	}
+	return b
+}
+
+// sysUpTime returns the milliseconds elapsed between the start of the exporter and t, wrapping as NetFlow v9 does
+func (e *encoder) sysUpTime(t time.Time) uint32 {
+	return uint32(t.Sub(e.start).Milliseconds())
+}
+
+func (e *encoder) newMessage() *message {
+	headerSize := ipfixHeaderSize
+	if e.protocol == ProtocolNetFlowV9 {
+		headerSize = netflowHeaderSize
+	}
+	return &message{
+		buf:        make([]byte, headerSize, maxMessageSize),
+		padSets:    e.protocol == ProtocolNetFlowV9,
+		enterprise: e.enterpriseNumber,
+	}
+}
+
+// finishMessage closes the last set of the message and writes its header
+func (e *encoder) finishMessage(m *message, now time.Time) []byte {
+	m.closeSet()
+
+	b := m.buf
+	if e.protocol == ProtocolNetFlowV9 {
+		binary.BigEndian.PutUint16(b[0:], netflowV9Version)
+		binary.BigEndian.PutUint16(b[2:], uint16(m.count))
+		binary.BigEndian.PutUint32(b[4:], e.sysUpTime(now))
+		binary.BigEndian.PutUint32(b[8:], uint32(now.Unix()))
+		binary.BigEndian.PutUint32(b[12:], e.sequence)
+		binary.BigEndian.PutUint32(b[16:], e.domainID)
+		e.sequence++
+		return b
+	}
+
+	binary.BigEndian.PutUint16(b[0:], ipfixVersion)
+	binary.BigEndian.PutUint16(b[2:], uint16(len(b)))
+	binary.BigEndian.PutUint32(b[4:], uint32(now.Unix()))
+	binary.BigEndian.PutUint32(b[8:], e.sequence)
+	binary.BigEndian.PutUint32(b[12:], e.domainID)
+	e.sequence += uint32(m.dataRecords)
+	return b
+}
+
+// message is an IPFIX or NetFlow v9 message being built, made of sets of templates or data records
+type message struct {
+	buf        []byte
+	padSets    bool
+	enterprise uint32
+
+	// setID and setStart identify the set being written, if setStart is not 0
+	setID    uint16
+	setStart int
+
+	// count is the number of template and data records of the message
+	count       int
+	dataRecords int
+}
+
+func (m *message) addTemplate(t template) {
+	setID := uint16(ipfixTemplateSet)
+	if m.padSets {
+		setID = netflowTemplateSet
+	}
+	m.openSet(setID)
+
+	m.buf = appendUint16(m.buf, t.id)
+	m.buf = appendUint16(m.buf, uint16(len(t.fields)))
+	for _, f := range t.fields {
+		if f.enterprise {
+			m.buf = appendUint16(m.buf, f.id|enterpriseBit)
+			m.buf = appendUint16(m.buf, f.length)
+			m.buf = appendUint32(m.buf, m.enterprise)
+			continue
+		}
+		m.buf = appendUint16(m.buf, f.id)
+		m.buf = appendUint16(m.buf, f.length)
+	}
+	m.count++
+}
+
+// fits returns whether a data record can be added to the message without exceeding maxMessageSize
+func (m *message) fits(setID uint16, record []byte) bool {
+	size := len(m.buf) + len(record)
+	if m.setStart == 0 || m.setID != setID {
+		// the current set is closed, and possibly padded, before a new one is opened
+		size += 3 + setHeaderSize
+	}
+	return size <= maxMessageSize
+}
+
+func (m *message) addRecord(setID uint16, record []byte) {
+	m.openSet(setID)
+	m.buf = append(m.buf, record...)
+	m.count++
+	m.dataRecords++
+}
+
+func (m *message) openSet(setID uint16) {
+	if m.setStart != 0 && m.setID == setID {
+		return
+	}
+	m.closeSet()
+
+	m.setID = setID
+	m.setStart = len(m.buf)
+	m.buf = append(m.buf, 0, 0, 0, 0)
+	binary.BigEndian.PutUint16(m.buf[m.setStart:], setID)
+}
+
+// closeSet writes the length of the current set, after padding it to 32 bits for NetFlow v9
+func (m *message) closeSet() {
+	if m.setStart == 0 {
+		return
+	}
+	if m.padSets {
+		for (len(m.buf)-m.setStart)%4 != 0 {
+			m.buf = append(m.buf, 0)
+		}
+	}
+	binary.BigEndian.PutUint16(m.buf[m.setStart+2:], uint16(len(m.buf)-m.setStart))
+	m.setStart = 0
+}
+
+// appendAddress appends an address of the given length, or zeros if it is missing or of another family
+func appendAddress(b []byte, addr util.Address, length uint16) []byte {
+	start := len(b)
+	b = append(b, make([]byte, length)...)
+	if addr != nil && addr.Len() == int(length) {
+		addr.WriteTo(b[start:])
+	}
+	return b
+}
+
+// appendVariableLength appends a string as an IPFIX variable-length information element
+func appendVariableLength(b []byte, s string) []byte {
+	if len(s) > 0xffff {
+		s = s[:0xffff]
+	}
+	if len(s) < 0xff {
+		b = append(b, uint8(len(s)))
+	} else {
+		b = append(b, 0xff)
+		b = appendUint16(b, uint16(len(s)))
+	}
+	return append(b, s...)
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	return appendUint32(appendUint32(b, uint32(v>>32)), uint32(v))
+}
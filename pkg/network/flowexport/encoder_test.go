@@ -0,0 +1,251 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package flowexport
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/network"
+	"github.com/DataDog/datadog-agent/pkg/process/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// decodedSet is a set of a message, split into its records using the templates of the message
+type decodedSet struct {
+	id      uint16
+	records [][]byte
+}
+
+type decodedMessage struct {
+	version  uint16
+	count    uint16
+	sequence uint32
+	domainID uint32
+	sets     []decodedSet
+}
+
+func decodeMessage(t *testing.T, b []byte, templates map[uint16]template) decodedMessage {
+	var m decodedMessage
+	m.version = binary.BigEndian.Uint16(b)
+	headerSize := ipfixHeaderSize
+	if m.version == netflowV9Version {
+		headerSize = netflowHeaderSize
+		m.count = binary.BigEndian.Uint16(b[2:])
+		m.sequence = binary.BigEndian.Uint32(b[12:])
+		m.domainID = binary.BigEndian.Uint32(b[16:])
+	} else {
+		require.Equal(t, ipfixVersion, int(m.version))
+		require.Equal(t, len(b), int(binary.BigEndian.Uint16(b[2:])))
+		m.sequence = binary.BigEndian.Uint32(b[8:])
+		m.domainID = binary.BigEndian.Uint32(b[12:])
+	}
+
+	for b = b[headerSize:]; len(b) > 0; {
+		require.GreaterOrEqual(t, len(b), setHeaderSize)
+		set := decodedSet{id: binary.BigEndian.Uint16(b)}
+		length := int(binary.BigEndian.Uint16(b[2:]))
+		require.LessOrEqual(t, length, len(b))
+		if m.version == netflowV9Version {
+			require.Zero(t, length%4, "NetFlow v9 sets are padded to 32 bits")
+		}
+
+		body := b[setHeaderSize:length]
+		b = b[length:]
+		if set.id == ipfixTemplateSet || set.id == netflowTemplateSet {
+			for len(body) >= 4 {
+				tmpl := template{id: binary.BigEndian.Uint16(body)}
+				count := int(binary.BigEndian.Uint16(body[2:]))
+				body = body[4:]
+				for i := 0; i < count; i++ {
+					f := field{id: binary.BigEndian.Uint16(body), length: binary.BigEndian.Uint16(body[2:])}
+					body = body[4:]
+					if f.id&enterpriseBit != 0 {
+						f.id &^= enterpriseBit
+						f.enterprise = true
+						body = body[4:]
+					}
+					tmpl.fields = append(tmpl.fields, f)
+				}
+				templates[tmpl.id] = tmpl
+				set.records = append(set.records, nil)
+			}
+			m.sets = append(m.sets, set)
+			continue
+		}
+
+		tmpl, ok := templates[set.id]
+		require.True(t, ok, "data set %d without template", set.id)
+		for len(body) >= 4 {
+			size := 0
+			for _, f := range tmpl.fields {
+				if f.length != variableLength {
+					size += int(f.length)
+					continue
+				}
+				n := int(body[size])
+				size++
+				if n == 0xff {
+					n = int(binary.BigEndian.Uint16(body[size:]))
+					size += 2
+				}
+				size += n
+			}
+			set.records = append(set.records, body[:size])
+			body = body[size:]
+		}
+		m.sets = append(m.sets, set)
+	}
+	return m
+}
+
+func testRecord(family network.ConnectionFamily) flowRecord {
+	r := flowRecord{
+		family:      family,
+		protocol:    protocolTCP,
+		direction:   directionEgress,
+		srcPort:     40000,
+		dstPort:     443,
+		octets:      1234,
+		packets:     10,
+		start:       time.Unix(1000, 0),
+		end:         time.Unix(1030, 500*int64(time.Millisecond)),
+		pid:         42,
+		process:     processInfo{name: "curl", containerID: "abcd"},
+		dstHostname: "example.com",
+	}
+	if family == network.AFINET {
+		r.srcAddr, r.dstAddr = util.AddressFromString("10.0.0.1"), util.AddressFromString("93.184.216.34")
+	} else {
+		r.srcAddr, r.dstAddr = util.AddressFromString("fd00::1"), util.AddressFromString("2606:2800:220:1::1")
+	}
+	r.postNATSrcAddr, r.postNATDstAddr = r.srcAddr, r.dstAddr
+	r.postNATSrcPort, r.postNATDstPort = r.srcPort, r.dstPort
+	return r
+}
+
+func TestEncodeIPFIX(t *testing.T) {
+	enc, err := newEncoder(ProtocolIPFIX, 7, 99999, time.Unix(900, 0))
+	require.NoError(t, err)
+
+	records := []flowRecord{testRecord(network.AFINET), testRecord(network.AFINET6), testRecord(network.AFINET)}
+	messages := enc.encode(records, time.Unix(1030, 0))
+	require.Len(t, messages, 2)
+
+	templates := make(map[uint16]template)
+	tmplMsg := decodeMessage(t, messages[0], templates)
+	assert.Equal(t, uint32(7), tmplMsg.domainID)
+	require.Len(t, tmplMsg.sets, 1)
+	assert.Len(t, tmplMsg.sets[0].records, 2)
+	assert.Equal(t, enc.templates[network.AFINET], templates[templateIDv4])
+	assert.Equal(t, enc.templates[network.AFINET6], templates[templateIDv6])
+
+	dataMsg := decodeMessage(t, messages[1], templates)
+	assert.Equal(t, uint32(0), dataMsg.sequence)
+	require.Len(t, dataMsg.sets, 3)
+	assert.Equal(t, []uint16{templateIDv4, templateIDv6, templateIDv4},
+		[]uint16{dataMsg.sets[0].id, dataMsg.sets[1].id, dataMsg.sets[2].id})
+
+	// 4 + 4 addresses, 2 + 2 ports, protocol, direction, 8 + 8 counters, 4 + 4 post-NAT addresses, 2 + 2 post-NAT ports,
+	// 8 + 8 timestamps, pid, then the variable-length process name, container ID and hostnames
+	record := dataMsg.sets[0].records[0]
+	assert.Equal(t, []byte{10, 0, 0, 1}, record[0:4])
+	assert.Equal(t, []byte{93, 184, 216, 34}, record[4:8])
+	assert.Equal(t, uint16(40000), binary.BigEndian.Uint16(record[8:]))
+	assert.Equal(t, uint16(443), binary.BigEndian.Uint16(record[10:]))
+	assert.Equal(t, protocolTCP, record[12])
+	assert.Equal(t, directionEgress, record[13])
+	assert.Equal(t, uint64(1234), binary.BigEndian.Uint64(record[14:]))
+	assert.Equal(t, uint64(10), binary.BigEndian.Uint64(record[22:]))
+	assert.Equal(t, uint64(1000000), binary.BigEndian.Uint64(record[42:]))
+	assert.Equal(t, uint64(1030500), binary.BigEndian.Uint64(record[50:]))
+	assert.Equal(t, uint32(42), binary.BigEndian.Uint32(record[58:]))
+	assert.Equal(t, append([]byte{4}, "curl"...), record[62:67])
+	assert.Equal(t, append([]byte{4}, "abcd"...), record[67:72])
+	assert.Equal(t, append([]byte{0, 11}, "example.com"...), record[72:])
+
+	// the sequence number of IPFIX counts the data records
+	messages = enc.encode(records[:1], time.Unix(1060, 0))
+	assert.Equal(t, uint32(3), decodeMessage(t, messages[1], templates).sequence)
+}
+
+func TestEncodeIPFIXWithoutEnterpriseNumber(t *testing.T) {
+	enc, err := newEncoder(ProtocolIPFIX, 0, 0, time.Unix(900, 0))
+	require.NoError(t, err)
+
+	for _, f := range enc.templates[network.AFINET].fields {
+		assert.False(t, f.enterprise)
+	}
+	messages := enc.encode([]flowRecord{testRecord(network.AFINET)}, time.Unix(1030, 0))
+	templates := make(map[uint16]template)
+	decodeMessage(t, messages[0], templates)
+	dataMsg := decodeMessage(t, messages[1], templates)
+	assert.Len(t, dataMsg.sets[0].records[0], 58)
+}
+
+func TestEncodeNetFlowV9(t *testing.T) {
+	enc, err := newEncoder(ProtocolNetFlowV9, 7, 99999, time.Unix(900, 0))
+	require.NoError(t, err)
+
+	messages := enc.encode([]flowRecord{testRecord(network.AFINET), testRecord(network.AFINET6)}, time.Unix(1030, 0))
+	require.Len(t, messages, 2)
+
+	templates := make(map[uint16]template)
+	tmplMsg := decodeMessage(t, messages[0], templates)
+	assert.Equal(t, uint16(netflowV9Version), tmplMsg.version)
+	assert.Equal(t, uint16(2), tmplMsg.count)
+	assert.Equal(t, uint32(0), tmplMsg.sequence)
+	for _, f := range templates[templateIDv4].fields {
+		assert.False(t, f.enterprise)
+		assert.NotEqual(t, uint16(variableLength), f.length)
+	}
+
+	dataMsg := decodeMessage(t, messages[1], templates)
+	assert.Equal(t, uint16(2), dataMsg.count)
+	assert.Equal(t, uint32(1), dataMsg.sequence)
+	assert.Equal(t, uint32(7), dataMsg.domainID)
+	assert.Equal(t, uint32(130000), binary.BigEndian.Uint32(messages[1][4:]))
+
+	// the timestamps are relative to the start of the exporter
+	record := dataMsg.sets[0].records[0]
+	assert.Len(t, record, 50)
+	assert.Equal(t, uint32(100000), binary.BigEndian.Uint32(record[42:]))
+	assert.Equal(t, uint32(130500), binary.BigEndian.Uint32(record[46:]))
+}
+
+func TestEncodeSplitsMessages(t *testing.T) {
+	enc, err := newEncoder(ProtocolIPFIX, 0, 0, time.Unix(900, 0))
+	require.NoError(t, err)
+
+	records := make([]flowRecord, 100)
+	for i := range records {
+		records[i] = testRecord(network.AFINET)
+	}
+	messages := enc.encode(records, time.Unix(1030, 0))
+	require.Greater(t, len(messages), 2)
+
+	templates := make(map[uint16]template)
+	decodeMessage(t, messages[0], templates)
+	var total int
+	var sequence uint32
+	for _, msg := range messages[1:] {
+		assert.LessOrEqual(t, len(msg), maxMessageSize)
+		m := decodeMessage(t, msg, templates)
+		assert.Equal(t, sequence, m.sequence)
+		for _, set := range m.sets {
+			total += len(set.records)
+			sequence += uint32(len(set.records))
+		}
+	}
+	assert.Equal(t, len(records), total)
+}
+
+func TestUnsupportedProtocol(t *testing.T) {
+	_, err := newEncoder("sflow", 0, 0, time.Now())
+	assert.Error(t, err)
+}
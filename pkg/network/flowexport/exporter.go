@@ -0,0 +1,173 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package flowexport
+
+import (
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/network"
+	"github.com/DataDog/datadog-agent/pkg/network/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// ClientID is the client ID the exporter uses to retrieve the connection deltas from the tracer
+const ClientID = "flow-exporter"
+
+// ConnectionsGetter returns the connection deltas since its previous call
+type ConnectionsGetter func() (*network.Connections, error)
+
+// Exporter periodically sends the connection deltas of the network tracer to a flow collector,
+// as IPFIX or NetFlow v9 records
+type Exporter struct {
+	conn           net.Conn
+	encoder        *encoder
+	getConnections ConnectionsGetter
+	interval       time.Duration
+
+	// exportProcesses is set when the process and container context of the flows is exported
+	exportProcesses bool
+	procRoot        string
+	// processes holds the process context of the pids seen during the last export
+	processes map[uint32]processInfo
+
+	lastExport time.Time
+	exit       chan struct{}
+	done       chan struct{}
+
+	// Telemetry
+	exportedFlows int64
+	sentMessages  int64
+	sendErrors    int64
+	exportErrors  int64
+}
+
+// NewExporter creates an exporter sending the records to the collector set in the configuration
+func NewExporter(cfg *config.Config, getConnections ConnectionsGetter) (*Exporter, error) {
+	enc, err := newEncoder(cfg.FlowExportProtocol, cfg.FlowExportObservationDomainID, cfg.FlowExportEnterpriseNumber, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.Dial("udp", cfg.FlowExportCollectorAddress)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to flow collector %s: %w", cfg.FlowExportCollectorAddress, err)
+	}
+
+	return &Exporter{
+		conn:            conn,
+		encoder:         enc,
+		getConnections:  getConnections,
+		interval:        cfg.FlowExportInterval,
+		exportProcesses: enc.protocol == ProtocolIPFIX && enc.enterpriseNumber != 0,
+		procRoot:        cfg.ProcRoot,
+		processes:       make(map[uint32]processInfo),
+		exit:            make(chan struct{}),
+		done:            make(chan struct{}),
+	}, nil
+}
+
+// Start starts exporting the connection deltas every interval
+func (e *Exporter) Start() {
+	// The first delta of a client holds no traffic, it only initializes its state
+	if cs, err := e.getConnections(); err != nil {
+		log.Warnf("error initializing flow export: %s", err)
+	} else {
+		network.Reclaim(cs)
+	}
+	e.lastExport = time.Now()
+
+	go func() {
+		defer close(e.done)
+
+		ticker := time.NewTicker(e.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				if err := e.export(now); err != nil {
+					atomic.AddInt64(&e.exportErrors, 1)
+					log.Warnf("error exporting flows: %s", err)
+				}
+			case <-e.exit:
+				return
+			}
+		}
+	}()
+	log.Infof("flow export started, sending %s records to %s every %s", e.encoder.protocol, e.conn.RemoteAddr(), e.interval)
+}
+
+// Stop stops the exporter
+func (e *Exporter) Stop() {
+	close(e.exit)
+	<-e.done
+	e.conn.Close()
+}
+
+// GetStats returns the telemetry of the exporter
+func (e *Exporter) GetStats() map[string]interface{} {
+	return map[string]interface{}{
+		"exported_flows": atomic.LoadInt64(&e.exportedFlows),
+		"sent_messages":  atomic.LoadInt64(&e.sentMessages),
+		"send_errors":    atomic.LoadInt64(&e.sendErrors),
+		"export_errors":  atomic.LoadInt64(&e.exportErrors),
+	}
+}
+
+func (e *Exporter) export(now time.Time) error {
+	cs, err := e.getConnections()
+	if err != nil {
+		return err
+	}
+	defer network.Reclaim(cs)
+
+	records := e.flowRecords(cs, now)
+	e.lastExport = now
+
+	var sendErr error
+	for _, msg := range e.encoder.encode(records, now) {
+		if _, err := e.conn.Write(msg); err != nil {
+			// the collector may come back before the next message
+			atomic.AddInt64(&e.sendErrors, 1)
+			sendErr = err
+			continue
+		}
+		atomic.AddInt64(&e.sentMessages, 1)
+	}
+	if sendErr != nil {
+		return fmt.Errorf("could not send flow records to %s: %w", e.conn.RemoteAddr(), sendErr)
+	}
+
+	atomic.AddInt64(&e.exportedFlows, int64(len(records)))
+	return nil
+}
+
+func (e *Exporter) flowRecords(cs *network.Connections, now time.Time) []flowRecord {
+	processes := make(map[uint32]processInfo)
+	records := make([]flowRecord, 0, len(cs.Conns))
+	for i := range cs.Conns {
+		conn := &cs.Conns[i]
+
+		var process processInfo
+		if e.exportProcesses && conn.Pid != 0 {
+			var ok bool
+			if process, ok = processes[conn.Pid]; !ok {
+				if process, ok = e.processes[conn.Pid]; !ok {
+					process = readProcessInfo(e.procRoot, conn.Pid)
+				}
+				processes[conn.Pid] = process
+			}
+		}
+
+		records = append(records, flowRecords(conn, cs.DNS, process, e.lastExport, now)...)
+	}
+
+	// the pids not seen during this export are forgotten, as they may be reused
+	e.processes = processes
+	return records
+}
@@ -0,0 +1,142 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package flowexport
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/network"
+	"github.com/DataDog/datadog-agent/pkg/network/config"
+	"github.com/DataDog/datadog-agent/pkg/process/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlowRecords(t *testing.T) {
+	local := util.AddressFromString("10.0.0.1")
+	remote := util.AddressFromString("10.0.0.2")
+	nattedLocal := util.AddressFromString("192.168.0.1")
+	dns := map[util.Address][]string{remote: {"db.internal", "db"}}
+	start, end := time.Unix(1000, 0), time.Unix(1030, 0)
+
+	t.Run("both directions with NAT", func(t *testing.T) {
+		conn := network.ConnectionStats{
+			Source: local, Dest: remote, SPort: 40000, DPort: 5432,
+			Type: network.TCP, Family: network.AFINET, Pid: 42,
+			LastSentBytes: 100, LastSentPackets: 2,
+			LastRecvBytes: 300, LastRecvPackets: 3,
+			IPTranslation: &network.IPTranslation{
+				ReplSrcIP: remote, ReplDstIP: nattedLocal, ReplSrcPort: 5432, ReplDstPort: 50000,
+			},
+		}
+
+		records := flowRecords(&conn, dns, processInfo{name: "app"}, start, end)
+		require.Len(t, records, 2)
+
+		sent, recv := records[0], records[1]
+		assert.Equal(t, directionEgress, sent.direction)
+		assert.Equal(t, local, sent.srcAddr)
+		assert.Equal(t, remote, sent.dstAddr)
+		assert.Equal(t, nattedLocal, sent.postNATSrcAddr)
+		assert.Equal(t, remote, sent.postNATDstAddr)
+		assert.Equal(t, uint16(50000), sent.postNATSrcPort)
+		assert.Equal(t, uint16(5432), sent.postNATDstPort)
+		assert.Equal(t, uint64(100), sent.octets)
+		assert.Equal(t, "db.internal", sent.dstHostname)
+		assert.Empty(t, sent.srcHostname)
+
+		assert.Equal(t, directionIngress, recv.direction)
+		assert.Equal(t, remote, recv.srcAddr)
+		assert.Equal(t, local, recv.dstAddr)
+		assert.Equal(t, remote, recv.postNATSrcAddr)
+		assert.Equal(t, nattedLocal, recv.postNATDstAddr)
+		assert.Equal(t, uint16(5432), recv.postNATSrcPort)
+		assert.Equal(t, uint16(50000), recv.postNATDstPort)
+		assert.Equal(t, uint64(300), recv.octets)
+		assert.Equal(t, uint64(3), recv.packets)
+		assert.Equal(t, "db.internal", recv.srcHostname)
+
+		for _, r := range records {
+			assert.Equal(t, protocolTCP, r.protocol)
+			assert.Equal(t, uint32(42), r.pid)
+			assert.Equal(t, "app", r.process.name)
+			assert.Equal(t, start, r.start)
+			assert.Equal(t, end, r.end)
+		}
+	})
+
+	t.Run("one direction without NAT", func(t *testing.T) {
+		conn := network.ConnectionStats{
+			Source: local, Dest: remote, SPort: 40000, DPort: 53,
+			Type: network.UDP, Family: network.AFINET,
+			LastSentBytes: 50, LastSentPackets: 1,
+		}
+
+		records := flowRecords(&conn, nil, processInfo{}, start, end)
+		require.Len(t, records, 1)
+		assert.Equal(t, protocolUDP, records[0].protocol)
+		assert.Equal(t, local, records[0].postNATSrcAddr)
+		assert.Equal(t, uint16(53), records[0].postNATDstPort)
+	})
+
+	t.Run("no traffic", func(t *testing.T) {
+		conn := network.ConnectionStats{Source: local, Dest: remote, Type: network.TCP, MonotonicSentBytes: 100}
+		assert.Empty(t, flowRecords(&conn, dns, processInfo{}, start, end))
+	})
+}
+
+func TestExport(t *testing.T) {
+	collector, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer collector.Close()
+
+	cfg := config.New()
+	cfg.FlowExportCollectorAddress = collector.LocalAddr().String()
+	cfg.FlowExportProtocol = ProtocolIPFIX
+	cfg.FlowExportInterval = time.Hour
+
+	calls := 0
+	exporter, err := NewExporter(cfg, func() (*network.Connections, error) {
+		calls++
+		return &network.Connections{
+			BufferedData: network.BufferedData{
+				Conns: []network.ConnectionStats{{
+					Source: util.AddressFromString("10.0.0.1"), Dest: util.AddressFromString("10.0.0.2"),
+					SPort: 40000, DPort: 80, Type: network.TCP, Family: network.AFINET,
+					LastSentBytes: 100, LastSentPackets: 1, LastRecvBytes: 2000, LastRecvPackets: 2,
+				}},
+			},
+		}, nil
+	})
+	require.NoError(t, err)
+	exporter.Start()
+	defer exporter.Stop()
+	assert.Equal(t, 1, calls, "the first delta is only used to initialize the client state")
+
+	require.NoError(t, exporter.export(time.Now()))
+	assert.Equal(t, 2, calls)
+
+	templates := make(map[uint16]template)
+	buf := make([]byte, maxMessageSize)
+	var records int
+	for i := 0; i < 2; i++ {
+		require.NoError(t, collector.SetReadDeadline(time.Now().Add(5*time.Second)))
+		n, _, err := collector.ReadFrom(buf)
+		require.NoError(t, err)
+		for _, set := range decodeMessage(t, buf[:n], templates).sets {
+			if set.id == templateIDv4 {
+				records += len(set.records)
+			}
+		}
+	}
+	assert.Equal(t, 2, records)
+
+	stats := exporter.GetStats()
+	assert.Equal(t, int64(2), stats["exported_flows"])
+	assert.Equal(t, int64(2), stats["sent_messages"])
+}
@@ -0,0 +1,50 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+// +build linux
+
+package flowexport
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/util/cgroups"
+)
+
+// readProcessInfo reads the name of a process and the ID of its container, if any, from procfs
+func readProcessInfo(procRoot string, pid uint32) processInfo {
+	var info processInfo
+	procPath := filepath.Join(procRoot, strconv.FormatUint(uint64(pid), 10))
+
+	if comm, err := ioutil.ReadFile(filepath.Join(procPath, "comm")); err == nil {
+		info.name = strings.TrimSpace(string(comm))
+	}
+
+	f, err := os.Open(filepath.Join(procPath, "cgroup"))
+	if err != nil {
+		return info
+	}
+	defer f.Close()
+
+	// Each line is hierarchy-ID:controllers:path, the container ID being the last element of the path
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if id, _ := cgroups.ContainerFilter(parts[2], filepath.Base(parts[2])); id != "" {
+			info.containerID = id
+			break
+		}
+	}
+	return info
+}
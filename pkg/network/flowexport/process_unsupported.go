@@ -0,0 +1,14 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !linux
+// +build !linux
+
+package flowexport
+
+// readProcessInfo is not supported outside of Linux, the flows are only exported with their pid
+func readProcessInfo(procRoot string, pid uint32) processInfo {
+	return processInfo{}
+}
@@ -0,0 +1,135 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package flowexport
+
+import (
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/network"
+	"github.com/DataDog/datadog-agent/pkg/process/util"
+)
+
+// IANA protocol numbers
+const (
+	protocolTCP uint8 = 6
+	protocolUDP uint8 = 17
+)
+
+// flowDirection values, from the IANA IPFIX registry
+const (
+	directionIngress uint8 = 0
+	directionEgress  uint8 = 1
+)
+
+// processInfo is the process and container context of a flow
+type processInfo struct {
+	name        string
+	containerID string
+}
+
+// flowRecord is a unidirectional flow, the unit of the IPFIX and NetFlow v9 exports
+type flowRecord struct {
+	family    network.ConnectionFamily
+	protocol  uint8
+	direction uint8
+
+	srcAddr util.Address
+	dstAddr util.Address
+	srcPort uint16
+	dstPort uint16
+
+	// addresses and ports after the NAT translation, equal to the ones above for the connections without NAT
+	postNATSrcAddr util.Address
+	postNATDstAddr util.Address
+	postNATSrcPort uint16
+	postNATDstPort uint16
+
+	octets  uint64
+	packets uint64
+	start   time.Time
+	end     time.Time
+
+	pid         uint32
+	process     processInfo
+	srcHostname string
+	dstHostname string
+}
+
+// flowRecords splits the delta of a connection into the flow it sent and the flow it received,
+// leaving out the directions without traffic since the last export
+func flowRecords(conn *network.ConnectionStats, dns map[util.Address][]string, process processInfo, start, end time.Time) []flowRecord {
+	protocol := protocolTCP
+	if conn.Type == network.UDP {
+		protocol = protocolUDP
+	}
+
+	// The conntrack reply tuple goes from the translated destination to the translated source
+	postNATSrcAddr, postNATDstAddr := conn.Source, conn.Dest
+	postNATSrcPort, postNATDstPort := conn.SPort, conn.DPort
+	if t := conn.IPTranslation; t != nil {
+		postNATSrcAddr, postNATDstAddr = t.ReplDstIP, t.ReplSrcIP
+		postNATSrcPort, postNATDstPort = t.ReplDstPort, t.ReplSrcPort
+	}
+
+	srcHostname := hostname(dns, conn.Source)
+	dstHostname := hostname(dns, conn.Dest)
+
+	var records []flowRecord
+	if conn.LastSentBytes > 0 || conn.LastSentPackets > 0 {
+		records = append(records, flowRecord{
+			family:         conn.Family,
+			protocol:       protocol,
+			direction:      directionEgress,
+			srcAddr:        conn.Source,
+			dstAddr:        conn.Dest,
+			srcPort:        conn.SPort,
+			dstPort:        conn.DPort,
+			postNATSrcAddr: postNATSrcAddr,
+			postNATDstAddr: postNATDstAddr,
+			postNATSrcPort: postNATSrcPort,
+			postNATDstPort: postNATDstPort,
+			octets:         conn.LastSentBytes,
+			packets:        conn.LastSentPackets,
+			start:          start,
+			end:            end,
+			pid:            conn.Pid,
+			process:        process,
+			srcHostname:    srcHostname,
+			dstHostname:    dstHostname,
+		})
+	}
+	if conn.LastRecvBytes > 0 || conn.LastRecvPackets > 0 {
+		records = append(records, flowRecord{
+			family:         conn.Family,
+			protocol:       protocol,
+			direction:      directionIngress,
+			srcAddr:        conn.Dest,
+			dstAddr:        conn.Source,
+			srcPort:        conn.DPort,
+			dstPort:        conn.SPort,
+			postNATSrcAddr: postNATDstAddr,
+			postNATDstAddr: postNATSrcAddr,
+			postNATSrcPort: postNATDstPort,
+			postNATDstPort: postNATSrcPort,
+			octets:         conn.LastRecvBytes,
+			packets:        conn.LastRecvPackets,
+			start:          start,
+			end:            end,
+			pid:            conn.Pid,
+			process:        process,
+			srcHostname:    dstHostname,
+			dstHostname:    srcHostname,
+		})
+	}
+	return records
+}
+
+func hostname(dns map[util.Address][]string, addr util.Address) string {
+	if names := dns[addr]; len(names) > 0 {
+		return names[0]
+	}
+	return ""
+}
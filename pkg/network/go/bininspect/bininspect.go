@@ -0,0 +1,159 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package bininspect locates, in a Go binary, the functions and struct fields the
+// uprobes attached to it need: it reads the Go version the binary was built with,
+// the location of its functions and of their return instructions, and the offsets
+// of struct fields, from the debug information or per Go version when stripped.
+package bininspect
+
+import (
+	"debug/elf"
+	"errors"
+	"fmt"
+)
+
+// ErrUnsupported is returned when the binary inspected is not supported: it is not a Go binary,
+// or it was built for an architecture or by a Go version which are not supported.
+var ErrUnsupported = errors.New("unsupported binary")
+
+// minimum Go versions using the register-based calling convention, which the uprobes rely on
+var (
+	minAMD64Version = GoVersion{Major: 1, Minor: 17}
+	minARM64Version = GoVersion{Major: 1, Minor: 18}
+)
+
+// FunctionConfiguration specifies what is looked up for a function
+type FunctionConfiguration struct {
+	// IncludeReturnLocations reports whether the locations of the return instructions are looked up
+	IncludeReturnLocations bool
+}
+
+// FunctionMetadata holds the locations of a function in the binary, as offsets in the binary file
+type FunctionMetadata struct {
+	// EntryLocation is the location of the first instruction of the function
+	EntryLocation uint64
+	// ReturnLocations are the locations of the return instructions of the function, if requested
+	ReturnLocations []uint64
+}
+
+// Result is the result of the inspection of a Go binary
+type Result struct {
+	// Arch is the architecture the binary was built for, as in GOARCH
+	Arch string
+	// GoVersion is the version of the Go toolchain which built the binary
+	GoVersion GoVersion
+	// Functions holds the functions requested which are in the binary
+	Functions map[string]FunctionMetadata
+	// StructOffsets holds the offsets of the struct fields requested
+	StructOffsets map[FieldIdentifier]uint64
+}
+
+// InspectFile inspects the Go binary at path. See Inspect.
+func InspectFile(path string, functions map[string]FunctionConfiguration, fields []FieldIdentifier) (*Result, error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Inspect(f, functions, fields)
+}
+
+// Inspect inspects the Go binary f, looking up the functions and the struct fields requested.
+// The functions which are not in the binary are not in the result: Go binaries only include
+// the code of the packages they use. The offsets of all the fields are required, so an error
+// is returned if one of them is unknown.
+func Inspect(f *elf.File, functions map[string]FunctionConfiguration, fields []FieldIdentifier) (*Result, error) {
+	rawVersion, err := goVersion(f)
+	if err != nil {
+		if errors.Is(err, errNotGoBinary) {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupported, err)
+		}
+		return nil, err
+	}
+	version, err := ParseGoVersion(rawVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{GoVersion: version}
+	switch f.Machine {
+	case elf.EM_X86_64:
+		result.Arch = "amd64"
+		if !version.AfterOrEqual(minAMD64Version) {
+			return nil, fmt.Errorf("%w: %s binary built by %s", ErrUnsupported, result.Arch, version)
+		}
+	case elf.EM_AARCH64:
+		result.Arch = "arm64"
+		if !version.AfterOrEqual(minARM64Version) {
+			return nil, fmt.Errorf("%w: %s binary built by %s", ErrUnsupported, result.Arch, version)
+		}
+	default:
+		return nil, fmt.Errorf("%w: architecture %s", ErrUnsupported, f.Machine)
+	}
+
+	result.Functions, err = inspectFunctions(f, functions)
+	if err != nil {
+		return nil, err
+	}
+
+	result.StructOffsets, err = dwarfOffsets(f, fields)
+	if err != nil {
+		// the binary was built without debug information, or with an unexpected one
+		result.StructOffsets = make(map[FieldIdentifier]uint64, len(fields))
+		for _, field := range fields {
+			offset, err := knownOffset(field, version)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrUnsupported, err)
+			}
+			result.StructOffsets[field] = offset
+		}
+	}
+
+	return result, nil
+}
+
+func inspectFunctions(f *elf.File, functions map[string]FunctionConfiguration) (map[string]FunctionMetadata, error) {
+	names := make([]string, 0, len(functions))
+	for name := range functions {
+		names = append(names, name)
+	}
+	table, err := newFunctionTable(f, names)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := make(map[string]FunctionMetadata, len(functions))
+	for name, config := range functions {
+		fn, err := table.lookup(name)
+		if errors.Is(err, errFunctionNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var m FunctionMetadata
+		if m.EntryLocation, err = fileOffset(f, fn.entry); err != nil {
+			return nil, fmt.Errorf("could not locate %s: %w", name, err)
+		}
+		if config.IncludeReturnLocations {
+			returns, err := returnLocations(f, fn)
+			if err != nil {
+				return nil, fmt.Errorf("could not locate the returns of %s: %w", name, err)
+			}
+			for _, addr := range returns {
+				offset, err := fileOffset(f, addr)
+				if err != nil {
+					return nil, fmt.Errorf("could not locate the returns of %s: %w", name, err)
+				}
+				m.ReturnLocations = append(m.ReturnLocations, offset)
+			}
+		}
+		metadata[name] = m
+	}
+	return metadata, nil
+}
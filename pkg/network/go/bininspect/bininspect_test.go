@@ -0,0 +1,119 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package bininspect
+
+import (
+	"debug/elf"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testFunctions = map[string]FunctionConfiguration{
+		"crypto/tls.(*Conn).Read":  {IncludeReturnLocations: true},
+		"crypto/tls.(*Conn).Write": {},
+		"crypto/tls.(*Conn).Close": {},
+		"main.notAFunction":        {},
+	}
+	testFields = []FieldIdentifier{
+		GoroutineIDField,
+		TLSConnConnField,
+		NetConnFDField,
+		NetFDPFDField,
+		PollFDSysfdField,
+	}
+)
+
+func buildTLSClient(t *testing.T, args ...string) string {
+	if runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64" {
+		t.Skipf("unsupported architecture %s", runtime.GOARCH)
+	}
+	path := filepath.Join(t.TempDir(), "tlsclient")
+	cmd := exec.Command("go", append(append([]string{"build", "-o", path}, args...), "./testdata/tlsclient")...)
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+	return path
+}
+
+func TestInspect(t *testing.T) {
+	version, err := ParseGoVersion(runtime.Version())
+	require.NoError(t, err)
+
+	var results []*Result
+	for _, tt := range []struct {
+		name  string
+		args  []string
+		debug bool
+	}{
+		{name: "debug information", debug: true},
+		{name: "stripped", args: []string{"-ldflags=-s -w"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			path := buildTLSClient(t, tt.args...)
+			f, err := elf.Open(path)
+			require.NoError(t, err)
+			defer f.Close()
+			_, err = dwarfOffsets(f, testFields)
+			assert.Equal(t, tt.debug, err == nil)
+
+			result, err := Inspect(f, testFunctions, testFields)
+			require.NoError(t, err)
+			assert.Equal(t, runtime.GOARCH, result.Arch)
+			assert.Equal(t, version, result.GoVersion)
+
+			require.Len(t, result.Functions, 3)
+			assert.NotContains(t, result.Functions, "main.notAFunction")
+			read := result.Functions["crypto/tls.(*Conn).Read"]
+			assert.NotZero(t, read.EntryLocation)
+			assert.NotEmpty(t, read.ReturnLocations)
+			for _, ret := range read.ReturnLocations {
+				assert.Greater(t, ret, read.EntryLocation)
+			}
+			assert.Empty(t, result.Functions["crypto/tls.(*Conn).Write"].ReturnLocations)
+
+			// the offsets known per Go version must match the debug information
+			for _, field := range testFields {
+				known, err := knownOffset(field, version)
+				require.NoError(t, err)
+				assert.Equal(t, known, result.StructOffsets[field], field.String())
+			}
+			results = append(results, result)
+		})
+	}
+
+	require.Len(t, results, 2)
+	assert.Equal(t, results[0].Functions, results[1].Functions)
+}
+
+func TestInspectNotGoBinary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty")
+	require.NoError(t, os.WriteFile(path, nil, 0644))
+	_, err := InspectFile(path, testFunctions, testFields)
+	assert.Error(t, err)
+}
+
+func TestKnownOffset(t *testing.T) {
+	offset, err := knownOffset(GoroutineIDField, GoVersion{1, 22, 5})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(152), offset)
+
+	offset, err = knownOffset(GoroutineIDField, GoVersion{1, 23, 0})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(160), offset)
+
+	offset, err = knownOffset(GoroutineIDField, GoVersion{1, 25, 1})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(152), offset)
+
+	_, err = knownOffset(GoroutineIDField, GoVersion{1, 16, 0})
+	assert.Error(t, err)
+}
@@ -0,0 +1,123 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package bininspect
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	buildInfoSection = ".go.buildinfo"
+	buildInfoSize    = 32
+
+	// buildInfoFlagsBigEndian is set in the build info flags when the binary is big endian
+	buildInfoFlagsBigEndian = 0x1
+	// buildInfoFlagsInline is set in the build info flags when the version is inlined
+	// in the section (Go 1.18+), rather than referenced by a pointer
+	buildInfoFlagsInline = 0x2
+
+	// maxVersionSize bounds the size of the version string read from a binary
+	maxVersionSize = 128
+)
+
+var buildInfoMagic = []byte("\xff Go buildinf:")
+
+// errNotGoBinary is returned when the binary inspected has no Go build information
+var errNotGoBinary = errors.New("not a Go binary")
+
+// goVersion returns the version of the Go toolchain which built the binary f, read from its
+// build information. It is the version reported by runtime.Version in the binary.
+func goVersion(f *elf.File) (string, error) {
+	sec := f.Section(buildInfoSection)
+	if sec == nil {
+		return "", errNotGoBinary
+	}
+	data, err := sec.Data()
+	if err != nil {
+		return "", fmt.Errorf("could not read %s: %w", buildInfoSection, err)
+	}
+	if len(data) < buildInfoSize || !bytes.HasPrefix(data, buildInfoMagic) {
+		return "", errNotGoBinary
+	}
+
+	ptrSize := int(data[14])
+	flags := data[15]
+	if flags&buildInfoFlagsInline != 0 {
+		version, _ := decodeString(data[buildInfoSize:])
+		if version == "" {
+			return "", fmt.Errorf("invalid Go version in %s", buildInfoSection)
+		}
+		return version, nil
+	}
+
+	// Before Go 1.18, the build information holds the address of the version string header
+	if ptrSize != 4 && ptrSize != 8 {
+		return "", fmt.Errorf("invalid pointer size %d in %s", ptrSize, buildInfoSection)
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if flags&buildInfoFlagsBigEndian != 0 {
+		order = binary.BigEndian
+	}
+	readPtr := func(b []byte) uint64 {
+		if ptrSize == 4 {
+			return uint64(order.Uint32(b))
+		}
+		return order.Uint64(b)
+	}
+	header, err := readAddress(f, readPtr(data[16:]), uint64(2*ptrSize))
+	if err != nil {
+		return "", fmt.Errorf("could not read Go version string header: %w", err)
+	}
+	size := readPtr(header[ptrSize:])
+	if size == 0 || size > maxVersionSize {
+		return "", fmt.Errorf("invalid Go version size %d", size)
+	}
+	version, err := readAddress(f, readPtr(header), size)
+	if err != nil {
+		return "", fmt.Errorf("could not read Go version string: %w", err)
+	}
+	return string(version), nil
+}
+
+// decodeString decodes a string prefixed by its uvarint encoded length.
+// It returns the string and the data following it.
+func decodeString(data []byte) (string, []byte) {
+	size, n := binary.Uvarint(data)
+	if n <= 0 || size > uint64(len(data)-n) {
+		return "", nil
+	}
+	return string(data[n : n+int(size)]), data[n+int(size):]
+}
+
+// readAddress reads size bytes at the virtual address addr of the binary f
+func readAddress(f *elf.File, addr, size uint64) ([]byte, error) {
+	for _, prog := range f.Progs {
+		if prog.Type != elf.PT_LOAD || addr < prog.Vaddr || addr+size > prog.Vaddr+prog.Filesz {
+			continue
+		}
+		data := make([]byte, size)
+		if _, err := prog.ReadAt(data, int64(addr-prog.Vaddr)); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("address %#x is not mapped from the binary", addr)
+}
+
+// fileOffset translates the virtual address addr of the binary f into its offset in the file,
+// which is how the uprobes locations are specified
+func fileOffset(f *elf.File, addr uint64) (uint64, error) {
+	for _, prog := range f.Progs {
+		if prog.Type == elf.PT_LOAD && addr >= prog.Vaddr && addr < prog.Vaddr+prog.Memsz {
+			return addr - prog.Vaddr + prog.Off, nil
+		}
+	}
+	return 0, fmt.Errorf("address %#x is not mapped from the binary", addr)
+}
@@ -0,0 +1,141 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package bininspect
+
+import (
+	"debug/dwarf"
+	"debug/elf"
+	"fmt"
+)
+
+// FieldIdentifier identifies a field of a Go struct
+type FieldIdentifier struct {
+	// StructName is the fully qualified name of the struct, such as "crypto/tls.Conn"
+	StructName string
+	// FieldName is the name of the field in the struct
+	FieldName string
+}
+
+func (f FieldIdentifier) String() string {
+	return f.StructName + "." + f.FieldName
+}
+
+var (
+	// GoroutineIDField is the ID of a goroutine, in runtime.g
+	GoroutineIDField = FieldIdentifier{"runtime.g", "goid"}
+	// TLSConnConnField is the net.Conn wrapped by a crypto/tls.Conn
+	TLSConnConnField = FieldIdentifier{"crypto/tls.Conn", "conn"}
+	// NetConnFDField is the *net.netFD of a net.conn, embedded in net.TCPConn
+	NetConnFDField = FieldIdentifier{"net.conn", "fd"}
+	// NetFDPFDField is the internal/poll.FD of a net.netFD
+	NetFDPFDField = FieldIdentifier{"net.netFD", "pfd"}
+	// PollFDSysfdField is the file descriptor of an internal/poll.FD
+	PollFDSysfdField = FieldIdentifier{"internal/poll.FD", "Sysfd"}
+)
+
+// versionedOffset is the offset of a field in the binaries built by the Go versions from since
+// until the next versionedOffset
+type versionedOffset struct {
+	since  GoVersion
+	offset uint64
+}
+
+// knownOffsets holds the offsets of the fields in the binaries built by the supported Go versions,
+// on both amd64 and arm64. They are used when a binary is built without debug information.
+// The entries of each field are sorted by version.
+var knownOffsets = map[FieldIdentifier][]versionedOffset{
+	GoroutineIDField: {
+		{since: GoVersion{Major: 1, Minor: 17}, offset: 152},
+		// Go 1.23 added runtime.g.syscallbp
+		{since: GoVersion{Major: 1, Minor: 23}, offset: 160},
+		// Go 1.25 removed runtime.gobuf.ret, embedded in runtime.g.sched
+		{since: GoVersion{Major: 1, Minor: 25}, offset: 152},
+	},
+	TLSConnConnField: {
+		{since: GoVersion{Major: 1, Minor: 17}, offset: 0},
+	},
+	NetConnFDField: {
+		{since: GoVersion{Major: 1, Minor: 17}, offset: 0},
+	},
+	NetFDPFDField: {
+		{since: GoVersion{Major: 1, Minor: 17}, offset: 0},
+	},
+	PollFDSysfdField: {
+		{since: GoVersion{Major: 1, Minor: 17}, offset: 16},
+	},
+}
+
+// knownOffset returns the offset of field in the binaries built by the Go version
+func knownOffset(field FieldIdentifier, version GoVersion) (uint64, error) {
+	offsets := knownOffsets[field]
+	for i := len(offsets) - 1; i >= 0; i-- {
+		if version.AfterOrEqual(offsets[i].since) {
+			return offsets[i].offset, nil
+		}
+	}
+	return 0, fmt.Errorf("offset of %s is unknown for %s", field, version)
+}
+
+// dwarfOffsets reads the offsets of fields from the DWARF debug information of the binary f.
+// It returns an error if the binary has no debug information, or if a field is missing from it.
+func dwarfOffsets(f *elf.File, fields []FieldIdentifier) (map[FieldIdentifier]uint64, error) {
+	data, err := f.DWARF()
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string][]FieldIdentifier, len(fields))
+	for _, field := range fields {
+		wanted[field.StructName] = append(wanted[field.StructName], field)
+	}
+
+	offsets := make(map[FieldIdentifier]uint64, len(fields))
+	r := data.Reader()
+	for len(offsets) < len(fields) {
+		entry, err := r.Next()
+		if err != nil {
+			return nil, fmt.Errorf("could not read debug information: %w", err)
+		}
+		if entry == nil {
+			break
+		}
+		if entry.Tag != dwarf.TagStructType {
+			continue
+		}
+		name, _ := entry.Val(dwarf.AttrName).(string)
+		structFields, ok := wanted[name]
+		if !ok {
+			r.SkipChildren()
+			continue
+		}
+
+		typ, err := data.Type(entry.Offset)
+		if err != nil {
+			return nil, fmt.Errorf("could not read type %s: %w", name, err)
+		}
+		r.SkipChildren()
+		structType, ok := typ.(*dwarf.StructType)
+		if !ok {
+			continue
+		}
+		for _, field := range structFields {
+			for _, member := range structType.Field {
+				if member.Name == field.FieldName {
+					offsets[field] = uint64(member.ByteOffset)
+					break
+				}
+			}
+		}
+		delete(wanted, name)
+	}
+
+	for _, field := range fields {
+		if _, ok := offsets[field]; !ok {
+			return nil, fmt.Errorf("field %s not found in debug information", field)
+		}
+	}
+	return offsets, nil
+}
@@ -0,0 +1,130 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package bininspect
+
+import (
+	"debug/elf"
+	"debug/gosym"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"golang.org/x/arch/x86/x86asm"
+)
+
+// arm64RetInstruction is the encoding of the arm64 `RET` instruction (returning to x30)
+const arm64RetInstruction = 0xd65f03c0
+
+// errFunctionNotFound is returned when a function looked up is not in the binary
+var errFunctionNotFound = errors.New("function not found")
+
+// function is the location of a function in the binary, as virtual addresses
+type function struct {
+	entry uint64
+	end   uint64
+}
+
+// functionTable looks up the functions of a binary, either in its symbol table or, when the
+// binary is stripped, in the Go line table which is always kept by the linker
+type functionTable struct {
+	symbols map[string]function
+	pcln    *gosym.Table
+}
+
+func newFunctionTable(f *elf.File, names []string) (*functionTable, error) {
+	wanted := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		wanted[name] = struct{}{}
+	}
+
+	symbols, err := f.Symbols()
+	if err == nil {
+		table := &functionTable{symbols: make(map[string]function, len(names))}
+		for _, sym := range symbols {
+			if _, ok := wanted[sym.Name]; !ok || elf.ST_TYPE(sym.Info) != elf.STT_FUNC {
+				continue
+			}
+			table.symbols[sym.Name] = function{entry: sym.Value, end: sym.Value + sym.Size}
+		}
+		return table, nil
+	}
+	if !errors.Is(err, elf.ErrNoSymbols) {
+		return nil, fmt.Errorf("could not read symbols: %w", err)
+	}
+
+	pclntab := f.Section(".gopclntab")
+	text := f.Section(".text")
+	if pclntab == nil || text == nil {
+		return nil, fmt.Errorf("binary has neither symbols nor Go line table")
+	}
+	data, err := pclntab.Data()
+	if err != nil {
+		return nil, fmt.Errorf("could not read Go line table: %w", err)
+	}
+	pcln, err := gosym.NewTable(nil, gosym.NewLineTable(data, text.Addr))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse Go line table: %w", err)
+	}
+	return &functionTable{pcln: pcln}, nil
+}
+
+func (t *functionTable) lookup(name string) (function, error) {
+	if t.pcln == nil {
+		fn, ok := t.symbols[name]
+		if !ok {
+			return function{}, errFunctionNotFound
+		}
+		return fn, nil
+	}
+
+	fn := t.pcln.LookupFunc(name)
+	if fn == nil {
+		return function{}, errFunctionNotFound
+	}
+	return function{entry: fn.Entry, end: fn.End}, nil
+}
+
+// returnLocations returns the virtual addresses of the return instructions of fn. Go does not
+// support uretprobes, as they modify the stack which the Go runtime may move, so the return
+// of a function is hooked with uprobes on each of its return instructions instead.
+func returnLocations(f *elf.File, fn function) ([]uint64, error) {
+	text := f.Section(".text")
+	if text == nil || fn.entry < text.Addr || fn.end > text.Addr+text.Size || fn.end <= fn.entry {
+		return nil, fmt.Errorf("function at %#x is not in the text section", fn.entry)
+	}
+	code := make([]byte, fn.end-fn.entry)
+	if _, err := text.ReadAt(code, int64(fn.entry-text.Addr)); err != nil {
+		return nil, fmt.Errorf("could not read function code: %w", err)
+	}
+
+	var locations []uint64
+	switch f.Machine {
+	case elf.EM_X86_64:
+		for pos := 0; pos < len(code); {
+			inst, err := x86asm.Decode(code[pos:], 64)
+			if err != nil {
+				return nil, fmt.Errorf("could not decode instruction at %#x: %w", fn.entry+uint64(pos), err)
+			}
+			if inst.Op == x86asm.RET {
+				locations = append(locations, fn.entry+uint64(pos))
+			}
+			pos += inst.Len
+		}
+	case elf.EM_AARCH64:
+		for pos := 0; pos+4 <= len(code); pos += 4 {
+			if binary.LittleEndian.Uint32(code[pos:]) == arm64RetInstruction {
+				locations = append(locations, fn.entry+uint64(pos))
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported architecture %s", f.Machine)
+	}
+
+	if len(locations) == 0 {
+		return nil, fmt.Errorf("no return instruction found in function at %#x", fn.entry)
+	}
+	return locations, nil
+}
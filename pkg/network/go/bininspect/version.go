@@ -0,0 +1,70 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package bininspect
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// GoVersion is the version of the Go toolchain a binary was built with
+type GoVersion struct {
+	Major int
+	Minor int
+	Rev   int
+}
+
+// ParseGoVersion parses a Go version as reported by runtime.Version, such as "go1.17.3".
+// Pre-releases ("go1.18beta1", "go1.18rc1") are parsed as their release,
+// and any suffix following a space ("go1.17 X:framepointer") is ignored.
+func ParseGoVersion(version string) (GoVersion, error) {
+	v := strings.TrimPrefix(version, "go")
+	if v == version {
+		return GoVersion{}, fmt.Errorf("invalid Go version %q", version)
+	}
+	if i := strings.IndexByte(v, ' '); i >= 0 {
+		v = v[:i]
+	}
+	for _, pre := range []string{"beta", "rc"} {
+		if i := strings.Index(v, pre); i >= 0 {
+			v = v[:i]
+		}
+	}
+
+	parts := strings.Split(v, ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return GoVersion{}, fmt.Errorf("invalid Go version %q", version)
+	}
+	var numbers [3]int
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return GoVersion{}, fmt.Errorf("invalid Go version %q", version)
+		}
+		numbers[i] = n
+	}
+	return GoVersion{Major: numbers[0], Minor: numbers[1], Rev: numbers[2]}, nil
+}
+
+// AfterOrEqual reports whether v is the same version as other, or a later one
+func (v GoVersion) AfterOrEqual(other GoVersion) bool {
+	if v.Major != other.Major {
+		return v.Major > other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor > other.Minor
+	}
+	return v.Rev >= other.Rev
+}
+
+// String returns the version in the format of runtime.Version
+func (v GoVersion) String() string {
+	if v.Rev == 0 {
+		return fmt.Sprintf("go%d.%d", v.Major, v.Minor)
+	}
+	return fmt.Sprintf("go%d.%d.%d", v.Major, v.Minor, v.Rev)
+}
@@ -0,0 +1,48 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package bininspect
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGoVersion(t *testing.T) {
+	for _, tt := range []struct {
+		version  string
+		expected GoVersion
+	}{
+		{"go1.17", GoVersion{1, 17, 0}},
+		{"go1.17.13", GoVersion{1, 17, 13}},
+		{"go1.18beta1", GoVersion{1, 18, 0}},
+		{"go1.18rc1", GoVersion{1, 18, 0}},
+		{"go1.17 X:framepointer", GoVersion{1, 17, 0}},
+		{"go1.21.0", GoVersion{1, 21, 0}},
+	} {
+		v, err := ParseGoVersion(tt.version)
+		require.NoError(t, err, tt.version)
+		assert.Equal(t, tt.expected, v, tt.version)
+	}
+
+	for _, version := range []string{"", "1.17", "go1", "go1.x", "go1.17.1.2", "devel +abcdef"} {
+		_, err := ParseGoVersion(version)
+		assert.Error(t, err, version)
+	}
+}
+
+func TestGoVersionAfterOrEqual(t *testing.T) {
+	v := GoVersion{1, 18, 2}
+	assert.True(t, v.AfterOrEqual(GoVersion{1, 18, 2}))
+	assert.True(t, v.AfterOrEqual(GoVersion{1, 18, 0}))
+	assert.True(t, v.AfterOrEqual(GoVersion{1, 17, 9}))
+	assert.False(t, v.AfterOrEqual(GoVersion{1, 18, 3}))
+	assert.False(t, v.AfterOrEqual(GoVersion{1, 19, 0}))
+	assert.False(t, v.AfterOrEqual(GoVersion{2, 0, 0}))
+	assert.Equal(t, "go1.18.2", v.String())
+	assert.Equal(t, "go1.18", GoVersion{1, 18, 0}.String())
+}
@@ -55,6 +55,24 @@ func dumpMapsHandler(managerMap *manager.Map, manager *manager.Manager) string {
 			output.WriteString(spew.Sdump(key, value))
 		}
 
+	case http2ConnsMap: // maps/http2_conns (BPF_MAP_TYPE_HASH), key ConnTuple, value C.__u8
+		output.WriteString("Map: '" + mapName + "', key: 'ConnTuple', value: 'C.__u8'\n")
+		iter := currentMap.Iterate()
+		var key ebpf.ConnTuple
+		var value uint8
+		for iter.Next(unsafe.Pointer(&key), unsafe.Pointer(&value)) {
+			output.WriteString(spew.Sdump(key, value))
+		}
+
+	case http2BatchStateMap: // maps/http2_batch_state (BPF_MAP_TYPE_HASH), key C.__u32, value C.http_batch_state_t
+		output.WriteString("Map: '" + mapName + "', key: 'C.__u32', value: 'C.http_batch_state_t'\n")
+		iter := currentMap.Iterate()
+		var key uint32
+		var value ebpf.HTTPBatchState
+		for iter.Next(unsafe.Pointer(&key), unsafe.Pointer(&value)) {
+			output.WriteString(spew.Sdump(key, value))
+		}
+
 	case sslSockByCtxMap: // maps/ssl_sock_by_ctx (BPF_MAP_TYPE_HASH), key uintptr // C.void *, value C.ssl_sock_t
 		output.WriteString("Map: '" + mapName + "', key: 'uintptr // C.void *', value: 'C.ssl_sock_t'\n")
 		iter := currentMap.Iterate()
@@ -90,6 +108,24 @@ func dumpMapsHandler(managerMap *manager.Map, manager *manager.Manager) string {
 		for iter.Next(unsafe.Pointer(&key), unsafe.Pointer(&value)) {
 			output.WriteString(spew.Sdump(key, value))
 		}
+
+	case goTLSOffsetsMap: // maps/go_tls_offsets (BPF_MAP_TYPE_HASH), key C.__u32, value C.go_tls_offsets_t
+		output.WriteString("Map: '" + mapName + "', key: 'C.__u32', value: 'C.go_tls_offsets_t'\n")
+		iter := currentMap.Iterate()
+		var key uint32
+		var value ebpf.GoTLSOffsets
+		for iter.Next(unsafe.Pointer(&key), unsafe.Pointer(&value)) {
+			output.WriteString(spew.Sdump(key, value))
+		}
+
+	case goTLSSockByConnMap: // maps/go_tls_sock_by_conn (BPF_MAP_TYPE_HASH), key C.go_tls_conn_key_t, value C.ssl_sock_t
+		output.WriteString("Map: '" + mapName + "', key: 'C.go_tls_conn_key_t', value: 'C.ssl_sock_t'\n")
+		iter := currentMap.Iterate()
+		var key ebpf.GoTLSConnKey
+		var value ebpf.SSLSock
+		for iter.Next(unsafe.Pointer(&key), unsafe.Pointer(&value)) {
+			output.WriteString(spew.Sdump(key, value))
+		}
 	}
 	return output.String()
 }
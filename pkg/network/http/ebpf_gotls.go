@@ -0,0 +1,418 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux_bpf
+// +build linux_bpf
+
+package http
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/DataDog/datadog-agent/pkg/network/config"
+	netebpf "github.com/DataDog/datadog-agent/pkg/network/ebpf"
+	"github.com/DataDog/datadog-agent/pkg/network/ebpf/probes"
+	"github.com/DataDog/datadog-agent/pkg/network/go/bininspect"
+	"github.com/DataDog/datadog-agent/pkg/process/util"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/DataDog/ebpf"
+	"github.com/DataDog/ebpf/manager"
+)
+
+const (
+	goTLSOffsetsMap    = "go_tls_offsets"
+	goTLSReadArgsMap   = "go_tls_read_args"
+	goTLSSockByConnMap = "go_tls_sock_by_conn"
+
+	goTLSWriteProbe      = "uprobe/crypto_tls_conn_write"
+	goTLSReadProbe       = "uprobe/crypto_tls_conn_read"
+	goTLSReadReturnProbe = "uprobe/crypto_tls_conn_read_return"
+	goTLSCloseProbe      = "uprobe/crypto_tls_conn_close"
+
+	goTLSWriteFunc = "crypto/tls.(*Conn).Write"
+	goTLSReadFunc  = "crypto/tls.(*Conn).Read"
+	goTLSCloseFunc = "crypto/tls.(*Conn).Close"
+
+	// goTLSScanInterval is the interval at which the running processes are scanned for
+	// the Go binaries to hook. The processes started in between are monitored after the next scan.
+	goTLSScanInterval = 30 * time.Second
+)
+
+var goTLSFunctions = map[string]bininspect.FunctionConfiguration{
+	goTLSWriteFunc: {},
+	goTLSReadFunc:  {IncludeReturnLocations: true},
+	goTLSCloseFunc: {},
+}
+
+var goTLSFields = []bininspect.FieldIdentifier{
+	bininspect.GoroutineIDField,
+	bininspect.TLSConnConnField,
+	bininspect.NetConnFDField,
+	bininspect.NetFDPFDField,
+	bininspect.PollFDSysfdField,
+}
+
+// goTLSProgram attaches uprobes to the crypto/tls package of the Go binaries, which are
+// statically linked and so are not monitored by the OpenSSL uprobes
+type goTLSProgram struct {
+	cfg        *config.Config
+	sockFDMap  *ebpf.Map
+	manager    *manager.Manager
+	offsetsMap *ebpf.Map
+	watcher    *goTLSWatcher
+}
+
+var _ subprogram = &goTLSProgram{}
+
+func newGoTLSProgram(c *config.Config, sockFDMap *ebpf.Map) (*goTLSProgram, error) {
+	if !c.EnableGoTLSSupport {
+		return nil, nil
+	}
+
+	if runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64" {
+		return nil, fmt.Errorf("Go TLS support is not available on %s", runtime.GOARCH)
+	}
+
+	return &goTLSProgram{
+		cfg:       c,
+		sockFDMap: sockFDMap,
+	}, nil
+}
+
+func (p *goTLSProgram) ConfigureManager(m *manager.Manager) {
+	if p == nil {
+		return
+	}
+
+	p.manager = m
+	m.Maps = append(m.Maps,
+		&manager.Map{Name: goTLSOffsetsMap},
+		&manager.Map{Name: goTLSReadArgsMap},
+		&manager.Map{Name: goTLSSockByConnMap},
+	)
+}
+
+func (p *goTLSProgram) ConfigureOptions(options *manager.Options) {
+	if p == nil {
+		return
+	}
+
+	options.MapSpecEditors[goTLSSockByConnMap] = manager.MapSpecEditor{
+		Type:       ebpf.Hash,
+		MaxEntries: uint32(p.cfg.MaxTrackedConnections),
+		EditorFlag: manager.EditMaxEntries,
+	}
+
+	if options.MapEditors == nil {
+		options.MapEditors = make(map[string]*ebpf.Map)
+	}
+
+	options.MapEditors[string(probes.SockByPidFDMap)] = p.sockFDMap
+}
+
+func (p *goTLSProgram) Start() {
+	if p == nil {
+		return
+	}
+
+	offsetsMap, _, err := p.manager.GetMap(goTLSOffsetsMap)
+	if err != nil || offsetsMap == nil {
+		log.Errorf("could not start Go TLS monitoring: could not get map %s: %v", goTLSOffsetsMap, err)
+		return
+	}
+	p.offsetsMap = offsetsMap
+
+	p.watcher = newGoTLSWatcher(p.cfg.ProcRoot, p)
+	p.watcher.Start()
+}
+
+func (p *goTLSProgram) Stop() {
+	if p == nil || p.watcher == nil {
+		return
+	}
+
+	p.watcher.Stop()
+}
+
+// attach attaches the crypto/tls uprobes to the binary at path
+func (p *goTLSProgram) attach(path string, uid string, result *bininspect.Result) ([]manager.ProbeIdentificationPair, error) {
+	type hook struct {
+		section string
+		offset  uint64
+	}
+	hooks := []hook{
+		{goTLSWriteProbe, result.Functions[goTLSWriteFunc].EntryLocation},
+		{goTLSReadProbe, result.Functions[goTLSReadFunc].EntryLocation},
+		{goTLSCloseProbe, result.Functions[goTLSCloseFunc].EntryLocation},
+	}
+	for _, offset := range result.Functions[goTLSReadFunc].ReturnLocations {
+		hooks = append(hooks, hook{goTLSReadReturnProbe, offset})
+	}
+
+	var attached []manager.ProbeIdentificationPair
+	for i, h := range hooks {
+		// each probe attached to a binary needs its own UID, as several are attached to the
+		// returns of crypto/tls.(*Conn).Read from the same section
+		id := manager.ProbeIdentificationPair{UID: uid + "_" + strconv.Itoa(i), Section: h.section}
+		err := p.manager.AddHook("", manager.Probe{
+			Section:      id.Section,
+			UID:          id.UID,
+			BinaryPath:   path,
+			UprobeOffset: h.offset,
+		})
+		if err != nil {
+			p.detach(attached)
+			return nil, err
+		}
+		attached = append(attached, id)
+	}
+	return attached, nil
+}
+
+// detach detaches the uprobes previously attached to a binary
+func (p *goTLSProgram) detach(ids []manager.ProbeIdentificationPair) {
+	for _, id := range ids {
+		probe, found := p.manager.GetProbe(id)
+		if !found {
+			continue
+		}
+
+		program := probe.Program()
+		p.manager.DetachHook(id.Section, id.UID)
+		if program != nil {
+			program.Close()
+		}
+	}
+}
+
+func (p *goTLSProgram) registerProcess(pid uint32, offsets *netebpf.GoTLSOffsets) error {
+	return p.offsetsMap.Put(unsafe.Pointer(&pid), unsafe.Pointer(offsets))
+}
+
+func (p *goTLSProgram) unregisterProcess(pid uint32) {
+	_ = p.offsetsMap.Delete(unsafe.Pointer(&pid))
+}
+
+// goTLSHooks attaches the uprobes to the Go binaries, and registers the processes running them
+type goTLSHooks interface {
+	attach(path string, uid string, result *bininspect.Result) ([]manager.ProbeIdentificationPair, error)
+	detach(ids []manager.ProbeIdentificationPair)
+	registerProcess(pid uint32, offsets *netebpf.GoTLSOffsets) error
+	unregisterProcess(pid uint32)
+}
+
+// binaryID identifies a binary by its device and inode, as the same binary can be run
+// from several paths, and in several mount namespaces
+type binaryID struct {
+	dev, ino uint64
+}
+
+// goTLSBinary is a binary run by one or more of the processes watched
+type goTLSBinary struct {
+	// offsets holds the offsets of the fields read by the uprobes.
+	// It is nil when the binary is not a Go binary using crypto/tls, or is not supported.
+	offsets *netebpf.GoTLSOffsets
+	// probes identifies the uprobes attached to the binary
+	probes []manager.ProbeIdentificationPair
+	// processCount is the number of processes running the binary
+	processCount int
+}
+
+// goTLSWatcher scans the running processes for the Go binaries using crypto/tls, to attach
+// the uprobes to them and register the processes running them. The uprobes are detached
+// once no process runs the binary anymore.
+type goTLSWatcher struct {
+	procRoot string
+	ownPID   uint32
+	hooks    goTLSHooks
+
+	binaries  map[binaryID]*goTLSBinary
+	processes map[uint32]binaryID
+	nextUID   int
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newGoTLSWatcher(procRoot string, hooks goTLSHooks) *goTLSWatcher {
+	ownPID, _ := util.GetRootNSPID()
+	return &goTLSWatcher{
+		procRoot:  procRoot,
+		ownPID:    uint32(ownPID),
+		hooks:     hooks,
+		binaries:  make(map[binaryID]*goTLSBinary),
+		processes: make(map[uint32]binaryID),
+		done:      make(chan struct{}),
+	}
+}
+
+func (w *goTLSWatcher) Start() {
+	w.scan()
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		ticker := time.NewTicker(goTLSScanInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				w.scan()
+			case <-w.done:
+				return
+			}
+		}
+	}()
+}
+
+func (w *goTLSWatcher) Stop() {
+	close(w.done)
+	w.wg.Wait()
+}
+
+// scan registers the processes started since the previous scan, and unregisters the ones which exited
+func (w *goTLSWatcher) scan() {
+	dir, err := os.Open(w.procRoot)
+	if err != nil {
+		log.Debugf("could not scan processes for Go TLS monitoring: %s", err)
+		return
+	}
+	names, err := dir.Readdirnames(-1)
+	dir.Close()
+	if err != nil {
+		log.Debugf("could not scan processes for Go TLS monitoring: %s", err)
+		return
+	}
+
+	alive := make(map[uint32]struct{}, len(names))
+	for _, name := range names {
+		pid, err := strconv.ParseUint(name, 10, 32)
+		if err != nil || uint32(pid) == w.ownPID {
+			continue
+		}
+
+		id, err := w.binaryID(uint32(pid))
+		if err != nil {
+			// kernel threads have no binary, and the process may have exited already
+			continue
+		}
+		alive[uint32(pid)] = struct{}{}
+
+		if known, ok := w.processes[uint32(pid)]; ok {
+			if known == id {
+				continue
+			}
+			// the pid was reused by a process running another binary
+			w.removeProcess(uint32(pid))
+		}
+		w.addProcess(uint32(pid), id)
+	}
+
+	for pid := range w.processes {
+		if _, ok := alive[pid]; !ok {
+			w.removeProcess(pid)
+		}
+	}
+}
+
+func (w *goTLSWatcher) exePath(pid uint32) string {
+	return filepath.Join(w.procRoot, strconv.FormatUint(uint64(pid), 10), "exe")
+}
+
+func (w *goTLSWatcher) binaryID(pid uint32) (binaryID, error) {
+	info, err := os.Stat(w.exePath(pid))
+	if err != nil {
+		return binaryID{}, err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return binaryID{}, fmt.Errorf("could not stat binary of process %d", pid)
+	}
+	return binaryID{dev: uint64(stat.Dev), ino: stat.Ino}, nil
+}
+
+func (w *goTLSWatcher) addProcess(pid uint32, id binaryID) {
+	binary, ok := w.binaries[id]
+	if !ok {
+		// the binary is hooked through the exe link of the first process seen running it,
+		// which resolves it in the mount namespace of the process
+		binary = w.addBinary(w.exePath(pid))
+		w.binaries[id] = binary
+	}
+
+	binary.processCount++
+	w.processes[pid] = id
+	if binary.offsets == nil {
+		return
+	}
+	if err := w.hooks.registerProcess(pid, binary.offsets); err != nil {
+		log.Debugf("could not register process %d for Go TLS monitoring: %s", pid, err)
+	}
+}
+
+func (w *goTLSWatcher) removeProcess(pid uint32) {
+	id := w.processes[pid]
+	delete(w.processes, pid)
+
+	binary, ok := w.binaries[id]
+	if !ok {
+		return
+	}
+	if binary.offsets != nil {
+		w.hooks.unregisterProcess(pid)
+	}
+
+	binary.processCount--
+	if binary.processCount > 0 {
+		return
+	}
+	if len(binary.probes) > 0 {
+		w.hooks.detach(binary.probes)
+	}
+	delete(w.binaries, id)
+}
+
+// addBinary inspects the binary at path and, if it is a Go binary using crypto/tls, attaches the uprobes to it
+func (w *goTLSWatcher) addBinary(path string) *goTLSBinary {
+	binary := &goTLSBinary{}
+	result, err := bininspect.InspectFile(path, goTLSFunctions, goTLSFields)
+	if err != nil {
+		if !errors.Is(err, bininspect.ErrUnsupported) {
+			log.Debugf("could not inspect binary %s for Go TLS monitoring: %s", path, err)
+		}
+		return binary
+	}
+	if result.Arch != runtime.GOARCH || len(result.Functions) != len(goTLSFunctions) {
+		// the binary doesn't use crypto/tls
+		return binary
+	}
+
+	w.nextUID++
+	probes, err := w.hooks.attach(path, "gotls"+strconv.Itoa(w.nextUID), result)
+	if err != nil {
+		log.Debugf("could not attach Go TLS uprobes to %s: %s", path, err)
+		return binary
+	}
+
+	log.Debugf("attached Go TLS uprobes to %s (%s)", path, result.GoVersion)
+	binary.probes = probes
+	binary.offsets = &netebpf.GoTLSOffsets{
+		Goroutine_id_offset: result.StructOffsets[bininspect.GoroutineIDField],
+		Conn_conn_offset:    result.StructOffsets[bininspect.TLSConnConnField],
+		Net_conn_fd_offset:  result.StructOffsets[bininspect.NetConnFDField],
+		Netfd_pfd_offset:    result.StructOffsets[bininspect.NetFDPFDField],
+		Pfd_sysfd_offset:    result.StructOffsets[bininspect.PollFDSysfdField],
+	}
+	return binary
+}
@@ -0,0 +1,125 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux_bpf
+// +build linux_bpf
+
+package http
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	netebpf "github.com/DataDog/datadog-agent/pkg/network/ebpf"
+	"github.com/DataDog/datadog-agent/pkg/network/go/bininspect"
+	"github.com/DataDog/ebpf/manager"
+)
+
+type fakeGoTLSHooks struct {
+	attached   map[string]int
+	detached   int
+	registered map[uint32]*netebpf.GoTLSOffsets
+}
+
+func newFakeGoTLSHooks() *fakeGoTLSHooks {
+	return &fakeGoTLSHooks{
+		attached:   make(map[string]int),
+		registered: make(map[uint32]*netebpf.GoTLSOffsets),
+	}
+}
+
+func (f *fakeGoTLSHooks) attach(path string, uid string, result *bininspect.Result) ([]manager.ProbeIdentificationPair, error) {
+	f.attached[path]++
+	probes := []manager.ProbeIdentificationPair{
+		{UID: uid + "_0", Section: goTLSWriteProbe},
+		{UID: uid + "_1", Section: goTLSReadProbe},
+		{UID: uid + "_2", Section: goTLSCloseProbe},
+	}
+	for i := range result.Functions[goTLSReadFunc].ReturnLocations {
+		probes = append(probes, manager.ProbeIdentificationPair{UID: uid + "_" + strconv.Itoa(i+3), Section: goTLSReadReturnProbe})
+	}
+	return probes, nil
+}
+
+func (f *fakeGoTLSHooks) detach(ids []manager.ProbeIdentificationPair) {
+	f.detached++
+}
+
+func (f *fakeGoTLSHooks) registerProcess(pid uint32, offsets *netebpf.GoTLSOffsets) error {
+	f.registered[pid] = offsets
+	return nil
+}
+
+func (f *fakeGoTLSHooks) unregisterProcess(pid uint32) {
+	delete(f.registered, pid)
+}
+
+func TestGoTLSWatcher(t *testing.T) {
+	dir := t.TempDir()
+	tlsClient := filepath.Join(dir, "tlsclient")
+	cmd := exec.Command("go", "build", "-o", tlsClient, "../go/bininspect/testdata/tlsclient")
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+	notGo := filepath.Join(dir, "script")
+	require.NoError(t, os.WriteFile(notGo, []byte("#!/bin/sh\n"), 0755))
+
+	procRoot := filepath.Join(dir, "proc")
+	startProcess := func(pid int, binary string) {
+		pidDir := filepath.Join(procRoot, strconv.Itoa(pid))
+		require.NoError(t, os.MkdirAll(pidDir, 0755))
+		if binary != "" {
+			require.NoError(t, os.Symlink(binary, filepath.Join(pidDir, "exe")))
+		}
+	}
+	stopProcess := func(pid int) {
+		require.NoError(t, os.RemoveAll(filepath.Join(procRoot, strconv.Itoa(pid))))
+	}
+
+	startProcess(1, notGo)
+	startProcess(2, "") // kernel thread
+	startProcess(10, tlsClient)
+	startProcess(11, tlsClient)
+
+	hooks := newFakeGoTLSHooks()
+	w := newGoTLSWatcher(procRoot, hooks)
+	w.scan()
+
+	// the binary run by both processes is hooked once, through either process
+	require.Len(t, hooks.attached, 1)
+	for _, count := range hooks.attached {
+		assert.Equal(t, 1, count)
+	}
+	require.Len(t, hooks.registered, 2)
+	offsets := hooks.registered[10]
+	require.NotNil(t, offsets)
+	assert.Equal(t, offsets, hooks.registered[11])
+	assert.NotZero(t, offsets.Goroutine_id_offset)
+	assert.NotZero(t, offsets.Pfd_sysfd_offset)
+
+	stopProcess(10)
+	w.scan()
+	assert.Len(t, hooks.registered, 1)
+	assert.Equal(t, 0, hooks.detached)
+
+	stopProcess(11)
+	stopProcess(1)
+	w.scan()
+	assert.Empty(t, hooks.registered)
+	assert.Equal(t, 1, hooks.detached)
+	assert.Empty(t, w.binaries)
+	assert.Empty(t, w.processes)
+
+	// the binary is hooked again once a process runs it
+	startProcess(12, tlsClient)
+	w.scan()
+	assert.Equal(t, 1, hooks.attached[filepath.Join(procRoot, "12", "exe")])
+	assert.Len(t, hooks.registered, 1)
+}
@@ -30,6 +30,12 @@ const (
 	httpBatchStateMap        = "http_batch_state"
 	httpNotificationsPerfMap = "http_notifications"
 
+	http2ConnsMap             = "http2_conns"
+	http2BatchesMap           = "http2_batches"
+	http2BatchStateMap        = "http2_batch_state"
+	http2ScratchMap           = "http2_scratch"
+	http2NotificationsPerfMap = "http2_notifications"
+
 	// ELF section of the BPF_PROG_TYPE_SOCKET_FILTER program used
 	// to inspect plain HTTP traffic
 	httpSocketFilter = "socket/http_filter"
@@ -51,7 +57,8 @@ type ebpfProgram struct {
 	offsets     []manager.ConstantEditor
 	subprograms []subprogram
 
-	batchCompletionHandler *ddebpf.PerfHandler
+	batchCompletionHandler      *ddebpf.PerfHandler
+	http2BatchCompletionHandler *ddebpf.PerfHandler
 }
 
 type subprogram interface {
@@ -82,11 +89,16 @@ func newEBPFProgram(c *config.Config, offsets []manager.ConstantEditor, sockFD *
 	}
 
 	batchCompletionHandler := ddebpf.NewPerfHandler(batchNotificationsChanSize)
+	http2BatchCompletionHandler := ddebpf.NewPerfHandler(batchNotificationsChanSize)
 	mgr := &manager.Manager{
 		Maps: []*manager.Map{
 			{Name: httpInFlightMap},
 			{Name: httpBatchesMap},
 			{Name: httpBatchStateMap},
+			{Name: http2ConnsMap},
+			{Name: http2BatchesMap},
+			{Name: http2BatchStateMap},
+			{Name: http2ScratchMap},
 			{Name: sslSockByCtxMap},
 			{Name: "ssl_read_args"},
 			{Name: "bio_new_socket_args"},
@@ -102,6 +114,15 @@ func newEBPFProgram(c *config.Config, offsets []manager.ConstantEditor, sockFD *
 					LostHandler:        batchCompletionHandler.LostHandler,
 				},
 			},
+			{
+				Map: manager.Map{Name: http2NotificationsPerfMap},
+				PerfMapOptions: manager.PerfMapOptions{
+					PerfRingBufferSize: 8 * os.Getpagesize(),
+					Watermark:          1,
+					DataHandler:        http2BatchCompletionHandler.DataHandler,
+					LostHandler:        http2BatchCompletionHandler.LostHandler,
+				},
+			},
 		},
 		Probes: []*manager.Probe{
 			{Section: httpSocketFilter},
@@ -110,13 +131,18 @@ func newEBPFProgram(c *config.Config, offsets []manager.ConstantEditor, sockFD *
 	}
 
 	openSSLProgram, _ := newOpenSSLProgram(c, sockFD)
+	goTLSProgram, err := newGoTLSProgram(c, sockFD)
+	if err != nil {
+		log.Warnf("Go TLS monitoring disabled: %s", err)
+	}
 	program := &ebpfProgram{
-		Manager:                mgr,
-		bytecode:               bytecode,
-		cfg:                    c,
-		offsets:                offsets,
-		batchCompletionHandler: batchCompletionHandler,
-		subprograms:            []subprogram{openSSLProgram},
+		Manager:                     mgr,
+		bytecode:                    bytecode,
+		cfg:                         c,
+		offsets:                     offsets,
+		batchCompletionHandler:      batchCompletionHandler,
+		http2BatchCompletionHandler: http2BatchCompletionHandler,
+		subprograms:                 []subprogram{openSSLProgram, goTLSProgram},
 	}
 
 	return program, nil
@@ -130,6 +156,14 @@ func (e *ebpfProgram) Init() error {
 	}
 	setupDumpHandler(e.Manager)
 
+	constantEditors := append([]manager.ConstantEditor(nil), e.offsets...)
+	if e.cfg.EnableHTTP2Monitoring {
+		constantEditors = append(constantEditors, manager.ConstantEditor{
+			Name:  "http2_monitoring_enabled",
+			Value: uint64(1),
+		})
+	}
+
 	options := manager.Options{
 		RLimit: &unix.Rlimit{
 			Cur: math.MaxUint64,
@@ -141,6 +175,11 @@ func (e *ebpfProgram) Init() error {
 				MaxEntries: uint32(e.cfg.MaxTrackedConnections),
 				EditorFlag: manager.EditMaxEntries,
 			},
+			http2ConnsMap: {
+				Type:       ebpf.Hash,
+				MaxEntries: uint32(e.cfg.MaxTrackedConnections),
+				EditorFlag: manager.EditMaxEntries,
+			},
 		},
 		ActivatedProbes: []manager.ProbesSelector{
 			&manager.ProbeSelector{
@@ -154,7 +193,7 @@ func (e *ebpfProgram) Init() error {
 				},
 			},
 		},
-		ConstantEditors: e.offsets,
+		ConstantEditors: constantEditors,
 	}
 
 	for _, s := range e.subprograms {
@@ -185,6 +224,7 @@ func (e *ebpfProgram) Start() error {
 func (e *ebpfProgram) Close() error {
 	err := e.Manager.Stop(manager.CleanAll)
 	e.batchCompletionHandler.Stop()
+	e.http2BatchCompletionHandler.Stop()
 	for _, s := range e.subprograms {
 		s.Stop()
 	}
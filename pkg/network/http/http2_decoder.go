@@ -0,0 +1,462 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package http
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/network/latency"
+	"github.com/DataDog/datadog-agent/pkg/network/segments"
+	"golang.org/x/net/http2/hpack"
+)
+
+// HTTP/2 frame types and flags (RFC 7540, section 6)
+const (
+	http2FrameData         = 0x0
+	http2FrameHeaders      = 0x1
+	http2FramePriority     = 0x2
+	http2FrameRSTStream    = 0x3
+	http2FrameSettings     = 0x4
+	http2FramePing         = 0x6
+	http2FrameGoAway       = 0x7
+	http2FrameWindowUpdate = 0x8
+	http2FrameContinuation = 0x9
+
+	http2FlagEndStream  = 0x1
+	http2FlagAck        = 0x1
+	http2FlagEndHeaders = 0x4
+	http2FlagPadded     = 0x8
+	http2FlagPriority   = 0x20
+
+	http2FrameHeaderSize        = 9
+	http2DefaultMaxFrameSize    = 16384
+	http2DefaultHeaderTableSize = 4096
+	http2SettingHeaderTableSize = 0x1
+)
+
+// http2Preface is the connection preface sent by HTTP/2 clients.
+var http2Preface = []byte("PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n")
+
+const (
+	// http2MaxStreamsPerConn is the maximum number of in-flight streams tracked per connection.
+	http2MaxStreamsPerConn = 1000
+	// http2MaxHeaderSize is the maximum size of a header field decoded.
+	http2MaxHeaderSize = 16 * 1024
+	// http2StreamTimeout is the time after which an in-flight stream is forgotten.
+	http2StreamTimeout = 2 * 60 * 1e9
+)
+
+// http2Transaction is a request/response exchange on an HTTP/2 stream. The status of gRPC
+// calls is translated to the equivalent HTTP status.
+type http2Transaction struct {
+	key     Key
+	status  int
+	latency float64
+}
+
+// http2Stream is an in-flight HTTP/2 stream.
+type http2Stream struct {
+	method     Method
+	path       string
+	started    uint64
+	status     int
+	grpcStatus int
+}
+
+// http2Direction holds the header decoding state of one direction of an HTTP/2 connection.
+type http2Direction struct {
+	hpack *hpack.Decoder
+	// tableSize is the header table size announced by the peer, when tableSizeSet
+	tableSize    uint32
+	tableSizeSet bool
+
+	// header block being decoded from a HEADERS frame and CONTINUATION frames
+	fields      []hpack.HeaderField
+	blockStream uint32
+	blockEnd    bool
+	// blockTruncated reports whether a frame of the block was not captured entirely: only
+	// the fields captured before it are decoded
+	blockTruncated bool
+}
+
+// http2Conn decodes the frames of an HTTP/2 connection.
+type http2Conn struct {
+	d              *http2Decoder
+	key            Key
+	client, server http2Direction
+	// started reports whether a client frame was seen: the client preface is only
+	// expected before it
+	started bool
+	streams map[uint32]*http2Stream
+}
+
+// http2Decoder decodes the HTTP/2 frames of the captured segments, including their
+// HPACK-compressed headers, into transactions. Only the beginning of each segment is
+// captured, so the decoding is best-effort: when a header block is not captured entirely,
+// the fields captured are decoded and the header compression context is reset. The
+// header fields referencing the entries of the dynamic table added before the reset can't
+// be decoded until they are sent again.
+type http2Decoder struct {
+	*segments.Decoder
+}
+
+func newHTTP2Decoder(maxConns, maxBuffered int) *http2Decoder {
+	d := &http2Decoder{}
+	d.Decoder = segments.NewDecoder(maxConns, maxBuffered, func(seg segments.Segment) segments.Parser {
+		return &http2Conn{d: d, key: connKey(seg.Conn), streams: make(map[uint32]*http2Stream)}
+	})
+	return d
+}
+
+// connKey returns the Key of the connection k, without path and method.
+func connKey(k segments.ConnKey) Key {
+	return Key{
+		SrcIPHigh: k.SrcIPHigh,
+		SrcIPLow:  k.SrcIPLow,
+		SrcPort:   k.SrcPort,
+		DstIPHigh: k.DstIPHigh,
+		DstIPLow:  k.DstIPLow,
+		DstPort:   k.DstPort,
+	}
+}
+
+// Frame returns the size of the frame starting at data, or of the client preface.
+func (c *http2Conn) Frame(fromClient bool, data []byte) (int, bool) {
+	if fromClient && !c.started && bytes.HasPrefix(data, http2Preface) {
+		return len(http2Preface), true
+	}
+	if len(data) < http2FrameHeaderSize {
+		// the frame header is not captured, or spans over the next segment
+		return 0, false
+	}
+	length := int(data[0])<<16 | int(data[1])<<8 | int(data[2])
+	return http2FrameHeaderSize + length, true
+}
+
+// Starts reports whether data starts with the client preface, or with the header of a frame
+// valid for its type. The CONTINUATION frames are not decoded without their HEADERS frame,
+// and the frames larger than the default maximum size are only decoded once the frame
+// boundaries are known.
+func (c *http2Conn) Starts(fromClient bool, data []byte) bool {
+	if fromClient && !c.started && bytes.HasPrefix(data, http2Preface) {
+		return true
+	}
+	if len(data) < http2FrameHeaderSize {
+		return false
+	}
+	length := int(data[0])<<16 | int(data[1])<<8 | int(data[2])
+	typ := data[3]
+	streamID := binary.BigEndian.Uint32(data[5:])
+	if length > http2DefaultMaxFrameSize || streamID&0x80000000 != 0 {
+		return false
+	}
+	switch typ {
+	case http2FrameData, http2FrameHeaders:
+		// the streams are initiated by the client, with odd IDs
+		return streamID%2 == 1
+	case http2FramePriority:
+		return length == 5 && streamID != 0
+	case http2FrameRSTStream:
+		return length == 4 && streamID != 0
+	case http2FrameSettings:
+		return length%6 == 0 && streamID == 0
+	case http2FramePing:
+		return length == 8 && streamID == 0
+	case http2FrameGoAway:
+		return length >= 8 && streamID == 0
+	case http2FrameWindowUpdate:
+		return length == 4
+	default:
+		return false
+	}
+}
+
+// Lost resets the header decoding state of the direction: the header blocks missed may have
+// changed the header compression context.
+func (c *http2Conn) Lost(fromClient bool) {
+	dir := &c.server
+	if fromClient {
+		dir = &c.client
+	}
+	dir.resetHPACK()
+	dir.fields = nil
+	dir.blockStream = 0
+}
+
+func (c *http2Conn) Message(fromClient bool, msg []byte, truncated bool, timestamp uint64) bool {
+	if fromClient && !c.started {
+		c.started = true
+		if bytes.Equal(msg, http2Preface) {
+			return true
+		}
+	}
+	dir, peer := &c.client, &c.server
+	if !fromClient {
+		dir, peer = peer, dir
+	}
+	typ, flags := msg[3], msg[4]
+	streamID := binary.BigEndian.Uint32(msg[5:]) & 0x7fffffff
+	length := int(msg[0])<<16 | int(msg[1])<<8 | int(msg[2])
+	return c.decodeFrame(dir, peer, fromClient, timestamp, typ, flags, streamID, msg[http2FrameHeaderSize:], length)
+}
+
+func (c *http2Conn) Encrypted() bool {
+	return false
+}
+
+// Expire forgets the streams which never completed.
+func (c *http2Conn) Expire(now uint64) {
+	for id, s := range c.streams {
+		if now-s.started > http2StreamTimeout {
+			delete(c.streams, id)
+		}
+	}
+}
+
+// decodeFrame decodes a frame with a payload of length bytes, of which only the beginning
+// may be captured. It returns false if the frame is not valid.
+func (c *http2Conn) decodeFrame(dir, peer *http2Direction, fromClient bool, timestamp uint64, typ, flags uint8, streamID uint32, payload []byte, length int) bool {
+	switch typ {
+	case http2FrameHeaders:
+		fragment, complete, ok := headersFragment(flags, payload, length)
+		if !ok {
+			return false
+		}
+		dir.fields = dir.fields[:0]
+		dir.blockStream = streamID
+		dir.blockEnd = flags&http2FlagEndStream != 0
+		dir.blockTruncated = false
+		dir.decodeFragment(fragment, complete)
+		if flags&http2FlagEndHeaders != 0 {
+			c.decodeHeaders(dir, fromClient, timestamp)
+		}
+	case http2FrameContinuation:
+		if streamID != dir.blockStream {
+			return false
+		}
+		dir.decodeFragment(payload, len(payload) == length)
+		if flags&http2FlagEndHeaders != 0 {
+			c.decodeHeaders(dir, fromClient, timestamp)
+		}
+	case http2FrameData:
+		if flags&http2FlagEndStream != 0 && !fromClient {
+			c.endStream(streamID, timestamp)
+		}
+	case http2FrameRSTStream:
+		delete(c.streams, streamID)
+	case http2FrameSettings:
+		if flags&http2FlagAck != 0 {
+			break
+		}
+		// the header table size announced by an endpoint bounds the dynamic table
+		// used by its peer to encode headers
+		for i := 0; i+6 <= len(payload); i += 6 {
+			if binary.BigEndian.Uint16(payload[i:]) == http2SettingHeaderTableSize {
+				peer.tableSize = binary.BigEndian.Uint32(payload[i+2:])
+				peer.tableSizeSet = true
+				peer.decoder().SetAllowedMaxDynamicTableSize(peer.tableSize)
+			}
+		}
+	}
+	return true
+}
+
+// headersFragment returns the captured part of the header block fragment of a HEADERS frame
+// with a payload of length bytes, and whether it is captured entirely. It returns false if
+// the frame is not valid.
+func headersFragment(flags uint8, payload []byte, length int) (fragment []byte, complete, ok bool) {
+	padding := 0
+	if flags&http2FlagPadded != 0 {
+		if length < 1 {
+			return nil, false, false
+		}
+		if len(payload) < 1 {
+			return nil, false, true
+		}
+		padding = int(payload[0])
+		payload = payload[1:]
+		length--
+	}
+	if flags&http2FlagPriority != 0 {
+		if length < 5 {
+			return nil, false, false
+		}
+		if len(payload) < 5 {
+			return nil, false, true
+		}
+		payload = payload[5:]
+		length -= 5
+	}
+	if padding > length {
+		return nil, false, false
+	}
+	end := length - padding
+	if len(payload) < end {
+		return payload, false, true
+	}
+	return payload[:end], true, true
+}
+
+// decoder returns the HPACK decoder of dir.
+func (dir *http2Direction) decoder() *hpack.Decoder {
+	if dir.hpack == nil {
+		dir.hpack = hpack.NewDecoder(http2DefaultHeaderTableSize, func(f hpack.HeaderField) {
+			dir.fields = append(dir.fields, f)
+		})
+		dir.hpack.SetMaxStringLength(http2MaxHeaderSize)
+		if dir.tableSizeSet {
+			dir.hpack.SetAllowedMaxDynamicTableSize(dir.tableSize)
+		}
+	}
+	return dir.hpack
+}
+
+// resetHPACK resets the header compression context of dir, once it is lost.
+func (dir *http2Direction) resetHPACK() {
+	dir.hpack = nil
+}
+
+// decodeFragment decodes a fragment of the current header block, of which only the
+// beginning is captured when complete is false. The header compression context is reset
+// when the fragment is not captured entirely or can't be decoded, as the dynamic table
+// entries it adds are missed.
+func (dir *http2Direction) decodeFragment(fragment []byte, complete bool) {
+	if dir.blockTruncated {
+		return
+	}
+	if _, err := dir.decoder().Write(fragment); err != nil || !complete {
+		dir.blockTruncated = true
+		dir.resetHPACK()
+	}
+}
+
+// decodeHeaders completes the header block decoded in dir.
+func (c *http2Conn) decodeHeaders(dir *http2Direction, fromClient bool, timestamp uint64) {
+	if !dir.blockTruncated && dir.decoder().Close() != nil {
+		dir.resetHPACK()
+	}
+	streamID := dir.blockStream
+	dir.blockStream = 0
+	if fromClient {
+		s, ok := c.streams[streamID]
+		if !ok {
+			if len(c.streams) >= http2MaxStreamsPerConn {
+				return
+			}
+			s = &http2Stream{started: timestamp, grpcStatus: -1}
+			c.streams[streamID] = s
+		}
+		for _, f := range dir.fields {
+			switch f.Name {
+			case ":method":
+				s.method = methodFromString(f.Value)
+			case ":path":
+				s.path = f.Value
+				if i := strings.IndexByte(s.path, '?'); i >= 0 {
+					s.path = s.path[:i]
+				}
+			}
+		}
+		return
+	}
+	s, ok := c.streams[streamID]
+	if !ok {
+		return
+	}
+	for _, f := range dir.fields {
+		switch f.Name {
+		case ":status":
+			s.status, _ = strconv.Atoi(f.Value)
+		case "grpc-status":
+			if code, err := strconv.Atoi(f.Value); err == nil {
+				s.grpcStatus = code
+			}
+		}
+	}
+	if dir.blockEnd {
+		c.endStream(streamID, timestamp)
+	}
+}
+
+// endStream completes the stream streamID, ended by the server at timestamp.
+func (c *http2Conn) endStream(streamID uint32, timestamp uint64) {
+	s, ok := c.streams[streamID]
+	if !ok {
+		return
+	}
+	delete(c.streams, streamID)
+	status := s.status
+	if s.grpcStatus >= 0 {
+		status = grpcToHTTPStatus(s.grpcStatus)
+	}
+	if status == 0 || s.path == "" || timestamp < s.started {
+		return
+	}
+	key := c.key
+	key.Path = s.path
+	key.Method = s.method
+	c.d.Complete(http2Transaction{
+		key:     key,
+		status:  status,
+		latency: latency.NSTimestampToFloat(timestamp - s.started),
+	})
+}
+
+// methodFromString returns the Method named m.
+func methodFromString(m string) Method {
+	switch m {
+	case "GET":
+		return MethodGet
+	case "POST":
+		return MethodPost
+	case "PUT":
+		return MethodPut
+	case "DELETE":
+		return MethodDelete
+	case "HEAD":
+		return MethodHead
+	case "OPTIONS":
+		return MethodOptions
+	case "PATCH":
+		return MethodPatch
+	default:
+		return MethodUnknown
+	}
+}
+
+// grpcToHTTPStatus returns the HTTP status equivalent to the gRPC status code, as
+// defined by the gRPC-HTTP mapping of grpc-gateway.
+func grpcToHTTPStatus(code int) int {
+	switch code {
+	case 0: // OK
+		return 200
+	case 1: // Canceled
+		return 499
+	case 3, 9, 11: // InvalidArgument, FailedPrecondition, OutOfRange
+		return 400
+	case 4: // DeadlineExceeded
+		return 504
+	case 5: // NotFound
+		return 404
+	case 6, 10: // AlreadyExists, Aborted
+		return 409
+	case 7: // PermissionDenied
+		return 403
+	case 8: // ResourceExhausted
+		return 429
+	case 12: // Unimplemented
+		return 501
+	case 14: // Unavailable
+		return 503
+	case 16: // Unauthenticated
+		return 401
+	default: // Unknown, Internal, DataLoss
+		return 500
+	}
+}
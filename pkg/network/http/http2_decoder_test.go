@@ -0,0 +1,471 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package http
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/network/segments"
+	"github.com/DataDog/datadog-agent/pkg/process/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// http2TestCaptureSize is the size of the beginning of the segments captured by the eBPF
+// programs.
+const http2TestCaptureSize = 160
+
+// http2TestConn writes the frames of both directions of an HTTP/2 connection.
+type http2TestConn struct {
+	t    *testing.T
+	conn segments.ConnKey
+
+	client, server struct {
+		buf    bytes.Buffer
+		framer *http2.Framer
+		hbuf   bytes.Buffer
+		hpack  *hpack.Encoder
+		seq    uint32
+	}
+}
+
+func newHTTP2TestConn(t *testing.T) *http2TestConn {
+	k := NewKey(
+		util.AddressFromString("1.1.1.1"),
+		util.AddressFromString("2.2.2.2"),
+		1234,
+		50051,
+		"",
+		MethodUnknown,
+	)
+	c := &http2TestConn{
+		t: t,
+		conn: segments.ConnKey{
+			SrcIPHigh: k.SrcIPHigh,
+			SrcIPLow:  k.SrcIPLow,
+			SrcPort:   k.SrcPort,
+			DstIPHigh: k.DstIPHigh,
+			DstIPLow:  k.DstIPLow,
+			DstPort:   k.DstPort,
+		},
+	}
+	c.client.framer = http2.NewFramer(&c.client.buf, nil)
+	c.client.hpack = hpack.NewEncoder(&c.client.hbuf)
+	c.server.framer = http2.NewFramer(&c.server.buf, nil)
+	c.server.hpack = hpack.NewEncoder(&c.server.hbuf)
+	c.client.buf.Write(http2Preface)
+	return c
+}
+
+// headers writes a HEADERS frame encoding the header fields kv.
+func (c *http2TestConn) headers(fromClient bool, streamID uint32, endStream bool, kv ...string) {
+	d := &c.server
+	if fromClient {
+		d = &c.client
+	}
+	d.hbuf.Reset()
+	for i := 0; i < len(kv); i += 2 {
+		require.NoError(c.t, d.hpack.WriteField(hpack.HeaderField{Name: kv[i], Value: kv[i+1]}))
+	}
+	require.NoError(c.t, d.framer.WriteHeaders(http2.HeadersFrameParam{
+		StreamID:      streamID,
+		BlockFragment: d.hbuf.Bytes(),
+		EndStream:     endStream,
+		EndHeaders:    true,
+	}))
+}
+
+// headerBlock writes a HEADERS frame and CONTINUATION frames encoding the header fields kv,
+// the header block being split in fragments of at most size bytes.
+func (c *http2TestConn) headerBlock(fromClient bool, streamID uint32, endStream bool, size int, kv ...string) {
+	d := &c.server
+	if fromClient {
+		d = &c.client
+	}
+	d.hbuf.Reset()
+	for i := 0; i < len(kv); i += 2 {
+		require.NoError(c.t, d.hpack.WriteField(hpack.HeaderField{Name: kv[i], Value: kv[i+1]}))
+	}
+	block := d.hbuf.Bytes()
+	n := len(block)
+	if n > size {
+		n = size
+	}
+	require.NoError(c.t, d.framer.WriteHeaders(http2.HeadersFrameParam{
+		StreamID:      streamID,
+		BlockFragment: block[:n],
+		EndStream:     endStream,
+		EndHeaders:    n == len(block),
+	}))
+	for block = block[n:]; len(block) > 0; block = block[n:] {
+		n = len(block)
+		if n > size {
+			n = size
+		}
+		require.NoError(c.t, d.framer.WriteContinuation(streamID, n == len(block), block[:n]))
+	}
+}
+
+// data writes a DATA frame of n bytes.
+func (c *http2TestConn) data(fromClient bool, streamID uint32, endStream bool, n int) {
+	d := &c.server
+	if fromClient {
+		d = &c.client
+	}
+	require.NoError(c.t, d.framer.WriteData(streamID, endStream, make([]byte, n)))
+}
+
+// segment returns the frames written since the last call in the direction fromClient, as a
+// segment captured at timestamp.
+func (c *http2TestConn) segment(fromClient bool, timestamp uint64) segments.Segment {
+	d := &c.server
+	if fromClient {
+		d = &c.client
+	}
+	data := append([]byte(nil), d.buf.Bytes()...)
+	d.buf.Reset()
+	seg := segments.Segment{
+		Conn:       c.conn,
+		Timestamp:  timestamp,
+		Seq:        d.seq,
+		FromClient: fromClient,
+		Length:     len(data),
+		Data:       data,
+	}
+	d.seq += uint32(len(data))
+	return seg
+}
+
+// captured returns the segment seg of which only the first http2TestCaptureSize bytes are
+// captured.
+func captured(seg segments.Segment) segments.Segment {
+	if len(seg.Data) > http2TestCaptureSize {
+		seg.Data = seg.Data[:http2TestCaptureSize]
+	}
+	return seg
+}
+
+// streams returns the in-flight streams of the connection decoded by d.
+func (c *http2TestConn) streams(d *http2Decoder) map[uint32]*http2Stream {
+	return d.Parser(c.conn).(*http2Conn).streams
+}
+
+func decodeAll(d *http2Decoder, segs ...segments.Segment) []http2Transaction {
+	var txs []http2Transaction
+	for _, seg := range segs {
+		d.Decode(seg, func(tx interface{}) {
+			txs = append(txs, tx.(http2Transaction))
+		})
+	}
+	return txs
+}
+
+func TestHTTP2DecoderGRPC(t *testing.T) {
+	d := newHTTP2Decoder(100, 100)
+	c := newHTTP2TestConn(t)
+
+	c.headers(true, 1, false, ":method", "POST", ":path", "/helloworld.Greeter/SayHello", "content-type", "application/grpc")
+	c.data(true, 1, true, 20)
+	req := c.segment(true, 1000)
+	c.headers(false, 1, false, ":status", "200")
+	c.data(false, 1, false, 20)
+	c.headers(false, 1, true, "grpc-status", "5")
+	resp := c.segment(false, 3000)
+
+	txs := decodeAll(d, req, resp)
+	require.Len(t, txs, 1)
+	assert.Equal(t, "/helloworld.Greeter/SayHello", txs[0].key.Path)
+	assert.Equal(t, MethodPost, txs[0].key.Method)
+	assert.Equal(t, c.conn.SrcPort, txs[0].key.SrcPort)
+	assert.Equal(t, c.conn.DstPort, txs[0].key.DstPort)
+	assert.Equal(t, 404, txs[0].status)
+	assert.Equal(t, float64(2000), txs[0].latency)
+}
+
+func TestHTTP2DecoderHTTP(t *testing.T) {
+	d := newHTTP2Decoder(100, 100)
+	c := newHTTP2TestConn(t)
+
+	// the same headers are sent twice, the second time as references to the dynamic table
+	c.headers(true, 1, true, ":method", "GET", ":path", "/users?id=1", "user-agent", "test")
+	c.headers(true, 3, true, ":method", "GET", ":path", "/users?id=1", "user-agent", "test")
+	req := c.segment(true, 1000)
+	c.headers(false, 3, false, ":status", "503")
+	c.data(false, 3, true, 10)
+	c.headers(false, 1, true, ":status", "200")
+	resp := c.segment(false, 2000)
+
+	txs := decodeAll(d, req, resp)
+	require.Len(t, txs, 2)
+	for _, tx := range txs {
+		assert.Equal(t, "/users", tx.key.Path)
+		assert.Equal(t, MethodGet, tx.key.Method)
+	}
+	assert.Equal(t, 503, txs[0].status)
+	assert.Equal(t, 200, txs[1].status)
+	assert.Empty(t, c.streams(d))
+}
+
+func TestHTTP2DecoderFrameAcrossSegments(t *testing.T) {
+	d := newHTTP2Decoder(100, 100)
+	c := newHTTP2TestConn(t)
+
+	c.headers(true, 1, true, ":method", "POST", ":path", "/a")
+	req := c.segment(true, 1000)
+	c.headers(false, 1, false, ":status", "200")
+	c.data(false, 1, false, 1000)
+	resp := c.segment(false, 2000)
+	// only the beginning of the response segment is captured
+	resp.Data = resp.Data[:100]
+	c.headers(false, 1, true, "grpc-status", "0")
+	trailers := c.segment(false, 3000)
+
+	txs := decodeAll(d, req, resp, trailers)
+	require.Len(t, txs, 1)
+	assert.Equal(t, 200, txs[0].status)
+	assert.Equal(t, float64(2000), txs[0].latency)
+	assert.Zero(t, d.Lost)
+}
+
+func TestHTTP2DecoderLost(t *testing.T) {
+	d := newHTTP2Decoder(100, 100)
+	c := newHTTP2TestConn(t)
+
+	c.headers(true, 1, true, ":method", "POST", ":path", "/a")
+	c.headers(true, 3, true, ":method", "POST", ":path", "/b")
+	req := c.segment(true, 1000)
+	c.data(false, 1, false, 1000)
+	c.headers(false, 1, true, ":status", "200")
+	resp := c.segment(false, 2000)
+	// the response headers are not captured
+	resp.Data = resp.Data[:100]
+	c.headers(false, 3, true, ":status", "201")
+	next := c.segment(false, 3000)
+
+	// the server direction is decoded again from the next segment
+	txs := decodeAll(d, req, resp, next)
+	require.Len(t, txs, 1)
+	assert.Equal(t, "/b", txs[0].key.Path)
+	assert.Equal(t, 201, txs[0].status)
+	assert.EqualValues(t, 1, d.Lost)
+
+	// the client direction is still decoded
+	c.headers(true, 5, true, ":method", "POST", ":path", "/a")
+	decodeAll(d, c.segment(true, 4000))
+	assert.Len(t, c.streams(d), 2)
+}
+
+func TestHTTP2DecoderDuplicates(t *testing.T) {
+	d := newHTTP2Decoder(100, 100)
+	c := newHTTP2TestConn(t)
+
+	c.headers(true, 1, true, ":method", "POST", ":path", "/a")
+	req := c.segment(true, 1000)
+	c.headers(false, 1, true, ":status", "200")
+	resp := c.segment(false, 2000)
+
+	// segments captured twice on the loopback interface, or retransmitted
+	txs := decodeAll(d, req, req, resp, resp, req)
+	require.Len(t, txs, 1)
+	assert.Zero(t, d.Lost)
+
+	// a segment is missed: the header compression context is reset, and the headers
+	// encoded as references to the entries it added can't be decoded
+	c.headers(true, 3, true, ":method", "POST", ":path", "/a")
+	c.segment(true, 3000)
+	c.headers(true, 5, true, ":method", "POST", ":path", "/a")
+	c.headers(true, 7, true, ":method", "POST", ":path", "/b")
+	req = c.segment(true, 4000)
+	c.headers(false, 5, true, ":status", "200")
+	c.headers(false, 7, true, ":status", "200")
+	txs = decodeAll(d, req, c.segment(false, 5000))
+	require.Len(t, txs, 1)
+	assert.Equal(t, "/b", txs[0].key.Path)
+	assert.EqualValues(t, 1, d.Lost)
+	assert.Empty(t, c.streams(d))
+}
+
+func TestHTTP2DecoderHeadersNotCaptured(t *testing.T) {
+	d := newHTTP2Decoder(100, 100)
+	c := newHTTP2TestConn(t)
+	cookie := strings.Repeat("0123456789abcdefghijklmnopqrstuvwxyz", 10)
+
+	// the header blocks are larger than the part of the segments captured
+	c.headers(true, 1, true, ":method", "POST", ":path", "/a", "cookie", cookie)
+	req := c.segment(true, 1000)
+	require.Greater(t, len(req.Data), http2TestCaptureSize)
+	c.headers(false, 1, true, ":status", "200", "set-cookie", cookie)
+	resp := c.segment(false, 2000)
+	require.Greater(t, len(resp.Data), http2TestCaptureSize)
+
+	txs := decodeAll(d, captured(req), captured(resp))
+	require.Len(t, txs, 1)
+	assert.Equal(t, "/a", txs[0].key.Path)
+	assert.Equal(t, MethodPost, txs[0].key.Method)
+	assert.Equal(t, 200, txs[0].status)
+
+	// the headers added to the dynamic table after the header compression context was
+	// reset are decoded
+	c.headers(true, 3, true, ":method", "GET", ":path", "/b")
+	c.headers(true, 5, true, ":method", "GET", ":path", "/b")
+	req = c.segment(true, 3000)
+	c.headers(false, 3, true, ":status", "404")
+	c.headers(false, 5, true, ":status", "404")
+	txs = decodeAll(d, captured(req), captured(c.segment(false, 4000)))
+	require.Len(t, txs, 2)
+	for _, tx := range txs {
+		assert.Equal(t, "/b", tx.key.Path)
+		assert.Equal(t, MethodGet, tx.key.Method)
+		assert.Equal(t, 404, tx.status)
+	}
+	assert.Zero(t, d.Lost)
+	assert.Empty(t, c.streams(d))
+}
+
+func TestHTTP2DecoderContinuationNotCaptured(t *testing.T) {
+	d := newHTTP2Decoder(100, 100)
+	c := newHTTP2TestConn(t)
+	cookie := strings.Repeat("0123456789abcdefghijklmnopqrstuvwxyz", 10)
+
+	// the CONTINUATION frames are captured in separate segments, and are not captured
+	// entirely
+	c.headerBlock(true, 1, true, 200, ":method", "PUT", ":path", "/a", "cookie", cookie, "cookie", cookie)
+	req := c.segment(true, 1000)
+	c.headerBlock(false, 1, false, 200, ":status", "500", "set-cookie", cookie, "set-cookie", cookie)
+	resp := c.segment(false, 2000)
+	c.data(false, 1, true, 10)
+	end := c.segment(false, 3000)
+
+	var segs []segments.Segment
+	for _, seg := range []segments.Segment{req, resp} {
+		for len(seg.Data) > 0 {
+			n := len(http2Preface)
+			if !bytes.HasPrefix(seg.Data, http2Preface) {
+				n = http2FrameHeaderSize + int(binary.BigEndian.Uint16(seg.Data[1:]))
+			}
+			part := seg
+			part.Data = seg.Data[:n]
+			part.Length = n
+			segs = append(segs, captured(part))
+			seg.Seq += uint32(n)
+			seg.Data = seg.Data[n:]
+		}
+	}
+	require.Greater(t, len(segs), 4)
+
+	txs := decodeAll(d, append(segs, end)...)
+	require.Len(t, txs, 1)
+	assert.Equal(t, "/a", txs[0].key.Path)
+	assert.Equal(t, MethodPut, txs[0].key.Method)
+	assert.Equal(t, 500, txs[0].status)
+	assert.Zero(t, d.Lost)
+}
+
+func TestHTTP2DecoderStarts(t *testing.T) {
+	c := &http2Conn{started: true}
+	frame := func(length int, typ uint8, streamID uint32) []byte {
+		b := []byte{byte(length >> 16), byte(length >> 8), byte(length), typ, 0, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(b[5:], streamID)
+		return b
+	}
+
+	assert.True(t, c.Starts(false, frame(10, http2FrameHeaders, 1)))
+	assert.True(t, c.Starts(false, frame(100, http2FrameData, 3)))
+	assert.True(t, c.Starts(false, frame(12, http2FrameSettings, 0)))
+	assert.True(t, c.Starts(false, frame(4, http2FrameWindowUpdate, 0)))
+	assert.True(t, c.Starts(false, frame(8, http2FramePing, 0)))
+
+	assert.False(t, c.Starts(false, frame(10, http2FrameHeaders, 2)))
+	assert.False(t, c.Starts(false, frame(10, http2FrameContinuation, 1)))
+	assert.False(t, c.Starts(false, frame(10, http2FrameSettings, 0)))
+	assert.False(t, c.Starts(false, frame(8, http2FramePing, 1)))
+	assert.False(t, c.Starts(false, frame(http2DefaultMaxFrameSize+1, http2FrameData, 1)))
+	assert.False(t, c.Starts(false, frame(10, 0x42, 1)))
+	assert.False(t, c.Starts(false, frame(10, http2FrameData, 1)[:8]))
+	assert.False(t, c.Starts(true, http2Preface))
+	assert.True(t, (&http2Conn{}).Starts(true, http2Preface))
+}
+
+func TestHTTP2DecoderFlush(t *testing.T) {
+	d := newHTTP2Decoder(100, 3)
+	c := newHTTP2TestConn(t)
+
+	c.headers(true, 1, true, ":method", "POST", ":path", "/a")
+	req := c.segment(true, 1000)
+	c.headers(false, 1, true, ":status", "200")
+	resp := c.segment(false, 2000)
+	c.headers(true, 3, true, ":method", "POST", ":path", "/b")
+	next := c.segment(true, 3000)
+
+	// segments are received out of order, and over the buffer limit
+	d.Add(next)
+	d.Add(resp)
+	d.Add(req)
+	d.Add(req)
+	assert.EqualValues(t, 1, d.Dropped)
+
+	var txs []http2Transaction
+	done := func(tx interface{}) { txs = append(txs, tx.(http2Transaction)) }
+	d.Flush(3000, done)
+	require.Len(t, txs, 1)
+	assert.Equal(t, "/a", txs[0].key.Path)
+	assert.Equal(t, 1, d.Buffered())
+	assert.Empty(t, c.streams(d))
+
+	d.Flush(3001, done)
+	assert.Zero(t, d.Buffered())
+	assert.Len(t, c.streams(d), 1)
+	assert.Zero(t, d.Lost)
+}
+
+func TestHTTP2DecoderResetAndClose(t *testing.T) {
+	d := newHTTP2Decoder(100, 100)
+	c := newHTTP2TestConn(t)
+
+	c.headers(true, 1, true, ":method", "POST", ":path", "/a")
+	req := c.segment(true, 1000)
+	require.NoError(t, c.server.framer.WriteRSTStream(1, http2.ErrCodeCancel))
+	c.headers(false, 1, true, ":status", "200")
+	resp := c.segment(false, 2000)
+	resp.Fin = true
+
+	txs := decodeAll(d, req, resp)
+	assert.Empty(t, txs)
+	assert.Zero(t, d.Conns())
+}
+
+func TestHTTP2DecoderExpire(t *testing.T) {
+	d := newHTTP2Decoder(100, 100)
+	c := newHTTP2TestConn(t)
+
+	var txs []http2Transaction
+	done := func(tx interface{}) { txs = append(txs, tx.(http2Transaction)) }
+
+	c.headers(true, 1, true, ":method", "POST", ":path", "/a")
+	d.Add(c.segment(true, 1000))
+	d.Flush(1001, done)
+	require.Len(t, c.streams(d), 1)
+
+	// the stream times out when a later segment is decoded
+	later := uint64(1000 + http2StreamTimeout + 1)
+	c.headers(true, 3, true, ":method", "POST", ":path", "/b")
+	d.Add(c.segment(true, later))
+	d.Flush(later+1, done)
+	require.Len(t, c.streams(d), 1)
+	assert.Contains(t, c.streams(d), uint32(3))
+	assert.Empty(t, txs)
+}
+
+func TestGRPCToHTTPStatus(t *testing.T) {
+	for code, status := range map[int]int{0: 200, 1: 499, 2: 500, 4: 504, 8: 429, 12: 501, 14: 503, 16: 401, 42: 500} {
+		assert.Equal(t, status, grpcToHTTPStatus(code), "grpc status %d", code)
+	}
+}
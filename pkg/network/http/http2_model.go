@@ -0,0 +1,84 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux_bpf
+// +build linux_bpf
+
+package http
+
+import (
+	"unsafe"
+
+	"github.com/DataDog/datadog-agent/pkg/network/segments"
+)
+
+/*
+#include "../ebpf/c/http-types.h"
+*/
+import "C"
+
+const (
+	HTTP2BatchSize  = int(C.HTTP2_BATCH_SIZE)
+	HTTP2BatchPages = int(C.HTTP2_BATCH_PAGES)
+	HTTP2BufferSize = int(C.HTTP2_BUFFER_SIZE)
+)
+
+type http2SegmentTX C.http2_segment_t
+type http2Batch C.http2_batch_t
+
+// http2BatchSpec describes the batches of HTTP/2 segments
+var http2BatchSpec = segments.BatchSpec{
+	Size:  HTTP2BatchSize,
+	Pages: HTTP2BatchPages,
+	New:   func() segments.Batch { return new(http2Batch) },
+}
+
+func (batch *http2Batch) Pointer() unsafe.Pointer {
+	return unsafe.Pointer(batch)
+}
+
+func (batch *http2Batch) Idx() int {
+	return int(batch.idx)
+}
+
+func (batch *http2Batch) Pos() int {
+	return int(batch.pos)
+}
+
+// Segments returns a copy of the HTTP/2 segments [from, to) embedded in the batch
+func (batch *http2Batch) Segments(from, to int) []segments.Segment {
+	txs := (*(*[HTTP2BatchSize]http2SegmentTX)(unsafe.Pointer(&batch.segments)))[from:to]
+	segs := make([]segments.Segment, len(txs))
+	for i := range txs {
+		segs[i] = txs[i].Segment()
+	}
+	return segs
+}
+
+// Segment returns a copy of the segment captured in eBPF, to be decoded
+func (s *http2SegmentTX) Segment() segments.Segment {
+	b := *(*[HTTP2BufferSize]byte)(unsafe.Pointer(&s.data))
+	n := int(s.captured_len)
+	if n > len(b) {
+		n = len(b)
+	}
+
+	return segments.Segment{
+		Conn: segments.ConnKey{
+			SrcIPHigh: uint64(s.tup.saddr_h),
+			SrcIPLow:  uint64(s.tup.saddr_l),
+			SrcPort:   uint16(s.tup.sport),
+			DstIPHigh: uint64(s.tup.daddr_h),
+			DstIPLow:  uint64(s.tup.daddr_l),
+			DstPort:   uint16(s.tup.dport),
+		},
+		Timestamp:  uint64(s.timestamp),
+		Seq:        uint32(s.seq),
+		FromClient: s.from_client != 0,
+		Fin:        s.fin != 0,
+		Length:     int(s.segment_len),
+		Data:       append([]byte(nil), b[:n]...),
+	}
+}
@@ -12,8 +12,12 @@ import (
 	"sync/atomic"
 
 	"github.com/DataDog/datadog-agent/pkg/network/config"
+	"github.com/DataDog/datadog-agent/pkg/network/segments"
 )
 
+// http2MaxBufferedSegments is the maximum number of HTTP/2 segments buffered until they are decoded
+const http2MaxBufferedSegments = 50000
+
 type httpStatKeeper struct {
 	stats      map[Key]RequestStats
 	incomplete map[Key]httpTX
@@ -29,9 +33,17 @@ type httpStatKeeper struct {
 	// map containing interned path strings
 	// this is rotated  with the stats map
 	interned map[string]string
+
+	// decoder of the HTTP/2 segments, nil unless HTTP/2 monitoring is enabled
+	http2 *http2Decoder
 }
 
 func newHTTPStatkeeper(c *config.Config, telemetry *telemetry) *httpStatKeeper {
+	var http2 *http2Decoder
+	if c.EnableHTTP2Monitoring {
+		http2 = newHTTP2Decoder(int(c.MaxTrackedConnections), http2MaxBufferedSegments)
+	}
+
 	return &httpStatKeeper{
 		stats:        make(map[Key]RequestStats),
 		incomplete:   make(map[Key]httpTX),
@@ -40,6 +52,7 @@ func newHTTPStatkeeper(c *config.Config, telemetry *telemetry) *httpStatKeeper {
 		buffer:       make([]byte, HTTPBufferSize),
 		interned:     make(map[string]string),
 		telemetry:    telemetry,
+		http2:        http2,
 	}
 }
 
@@ -56,6 +69,33 @@ func (h *httpStatKeeper) Process(transactions []httpTX) {
 	atomic.StoreInt64(&h.telemetry.aggregations, int64(len(h.stats)))
 }
 
+// ProcessHTTP2 buffers the HTTP/2 segments until they are decoded by FlushHTTP2
+func (h *httpStatKeeper) ProcessHTTP2(segs []segments.Segment) {
+	if h.http2 == nil {
+		return
+	}
+
+	for _, seg := range segs {
+		h.http2.Add(seg)
+	}
+}
+
+// FlushHTTP2 decodes the HTTP/2 segments captured before the monotonic timestamp before,
+// and aggregates the transactions they complete
+func (h *httpStatKeeper) FlushHTTP2(before uint64) {
+	if h.http2 == nil {
+		return
+	}
+
+	h.http2.Flush(before, func(tx interface{}) {
+		h.addHTTP2(tx.(http2Transaction))
+	})
+	atomic.AddInt64(&h.telemetry.http2Lost, h.http2.Lost)
+	atomic.AddInt64(&h.telemetry.http2Dropped, h.http2.Dropped)
+	h.http2.Lost, h.http2.Dropped = 0, 0
+	atomic.StoreInt64(&h.telemetry.aggregations, int64(len(h.stats)))
+}
+
 func (h *httpStatKeeper) GetAndResetAllStats() map[Key]RequestStats {
 	ret := h.stats // No deep copy needed since `h.stats` gets reset
 	h.stats = make(map[Key]RequestStats)
@@ -65,20 +105,40 @@ func (h *httpStatKeeper) GetAndResetAllStats() map[Key]RequestStats {
 }
 
 func (h *httpStatKeeper) add(tx httpTX) {
-	path, rejected := h.processHTTPPath(tx)
+	path, rejected := h.processHTTPPath(tx.Path(h.buffer))
+	if rejected {
+		atomic.AddInt64(&h.telemetry.rejected, 1)
+		return
+	}
+
+	h.addRequest(h.newKey(tx, path), tx.StatusClass(), tx.RequestLatency())
+}
+
+func (h *httpStatKeeper) addHTTP2(tx http2Transaction) {
+	path, rejected := h.processHTTPPath([]byte(tx.key.Path))
 	if rejected {
 		atomic.AddInt64(&h.telemetry.rejected, 1)
 		return
 	}
 
-	key := h.newKey(tx, path)
+	statusClass := (tx.status / 100) * 100
+	if i := statusClass/100 - 1; i >= 0 && i < len(h.telemetry.hits) {
+		atomic.AddInt64(&h.telemetry.hits[i], 1)
+	}
+
+	key := tx.key
+	key.Path = path
+	h.addRequest(key, statusClass, tx.latency)
+}
+
+func (h *httpStatKeeper) addRequest(key Key, statusClass int, latency float64) {
 	stats, ok := h.stats[key]
 	if !ok && len(h.stats) >= h.maxEntries {
 		atomic.AddInt64(&h.telemetry.dropped, 1)
 		return
 	}
 
-	stats.AddRequest(tx.StatusClass(), tx.RequestLatency())
+	stats.AddRequest(statusClass, latency)
 	h.stats[key] = stats
 }
 
@@ -139,9 +199,7 @@ func (h *httpStatKeeper) newKey(tx httpTX, path string) Key {
 	}
 }
 
-func (h *httpStatKeeper) processHTTPPath(tx httpTX) (pathStr string, rejected bool) {
-	path := tx.Path(h.buffer)
-
+func (h *httpStatKeeper) processHTTPPath(path []byte) (pathStr string, rejected bool) {
 	for _, r := range h.replaceRules {
 		if r.Re.Match(path) {
 			if r.Repl == "" {
@@ -10,6 +10,8 @@ package http
 
 import (
 	"unsafe"
+
+	"github.com/DataDog/datadog-agent/pkg/network/latency"
 )
 
 /*
@@ -70,7 +72,7 @@ func (tx *httpTX) StatusClass() int {
 
 // RequestLatency returns the latency of the request in nanoseconds
 func (tx *httpTX) RequestLatency() float64 {
-	return nsTimestampToFloat(uint64(tx.response_last_seen - tx.request_started))
+	return latency.NSTimestampToFloat(uint64(tx.response_last_seen - tx.request_started))
 }
 
 // Incomplete returns true if the transaction contains only the request or response information
@@ -91,17 +93,3 @@ func (batch *httpBatch) IsDirty(notification httpNotification) bool {
 func (batch *httpBatch) Transactions() []httpTX {
 	return (*(*[HTTPBatchSize]httpTX)(unsafe.Pointer(&batch.txs)))[:]
 }
-
-// below is copied from pkg/trace/stats/statsraw.go
-// 10 bits precision (any value will be +/- 1/1024)
-const roundMask uint64 = 1 << 10
-
-// nsTimestampToFloat converts a nanosec timestamp into a float nanosecond timestamp truncated to a fixed precision
-func nsTimestampToFloat(ns uint64) float64 {
-	var shift uint
-	for ns > roundMask {
-		ns = ns >> 1
-		shift++
-	}
-	return float64(ns << shift)
-}
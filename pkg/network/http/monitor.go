@@ -12,11 +12,13 @@ import (
 	"fmt"
 
 	"sync"
+	"sync/atomic"
 	"time"
 
 	ddebpf "github.com/DataDog/datadog-agent/pkg/ebpf"
 	"github.com/DataDog/datadog-agent/pkg/network/config"
 	filterpkg "github.com/DataDog/datadog-agent/pkg/network/filter"
+	"github.com/DataDog/datadog-agent/pkg/network/segments"
 	"github.com/DataDog/ebpf"
 	"github.com/DataDog/ebpf/manager"
 )
@@ -34,16 +36,19 @@ type HTTPMonitorStats struct {
 // * Polling a perf buffer that contains notifications about HTTP transaction batches ready to be read;
 // * Querying these batches by doing a map lookup;
 // * Aggregating and emitting metrics based on the received HTTP transactions;
+// * Decoding the segments of HTTP/2 connections into transactions, when HTTP/2 monitoring is enabled;
 type Monitor struct {
 	handler func([]httpTX)
 
-	ebpfProgram            *ebpfProgram
-	batchManager           *batchManager
-	batchCompletionHandler *ddebpf.PerfHandler
-	telemetry              *telemetry
-	telemetrySnapshot      *telemetry
-	pollRequests           chan chan HTTPMonitorStats
-	statkeeper             *httpStatKeeper
+	ebpfProgram                 *ebpfProgram
+	batchManager                *batchManager
+	batchCompletionHandler      *ddebpf.PerfHandler
+	http2BatchManager           *segments.BatchManager
+	http2BatchCompletionHandler *ddebpf.PerfHandler
+	telemetry                   *telemetry
+	telemetrySnapshot           *telemetry
+	pollRequests                chan chan HTTPMonitorStats
+	statkeeper                  *httpStatKeeper
 
 	// termination
 	mux           sync.Mutex
@@ -86,6 +91,26 @@ func NewMonitor(c *config.Config, offsets []manager.ConstantEditor, sockFD *ebpf
 	notificationMap, _, _ := mgr.GetMap(httpNotificationsPerfMap)
 	numCPUs := int(notificationMap.ABI().MaxEntries)
 
+	var http2Batches *segments.BatchManager
+	if c.EnableHTTP2Monitoring {
+		http2BatchMap, _, err := mgr.GetMap(http2BatchesMap)
+		if err != nil {
+			return nil, err
+		}
+
+		http2BatchStateMap, _, err := mgr.GetMap(http2BatchStateMap)
+		if err != nil {
+			return nil, err
+		}
+
+		http2ScratchMap, _, err := mgr.GetMap(http2ScratchMap)
+		if err != nil {
+			return nil, err
+		}
+
+		http2Batches = segments.NewBatchManager(http2BatchSpec, http2BatchMap, http2BatchStateMap, http2ScratchMap, numCPUs)
+	}
+
 	telemetry := newTelemetry()
 	statkeeper := newHTTPStatkeeper(c, telemetry)
 
@@ -96,15 +121,17 @@ func NewMonitor(c *config.Config, offsets []manager.ConstantEditor, sockFD *ebpf
 	}
 
 	return &Monitor{
-		handler:                handler,
-		ebpfProgram:            mgr,
-		batchManager:           newBatchManager(batchMap, batchStateMap, numCPUs),
-		batchCompletionHandler: mgr.batchCompletionHandler,
-		telemetry:              telemetry,
-		telemetrySnapshot:      nil,
-		pollRequests:           make(chan chan HTTPMonitorStats),
-		closeFilterFn:          closeFilterFn,
-		statkeeper:             statkeeper,
+		handler:                     handler,
+		ebpfProgram:                 mgr,
+		batchManager:                newBatchManager(batchMap, batchStateMap, numCPUs),
+		batchCompletionHandler:      mgr.batchCompletionHandler,
+		http2BatchManager:           http2Batches,
+		http2BatchCompletionHandler: mgr.http2BatchCompletionHandler,
+		telemetry:                   telemetry,
+		telemetrySnapshot:           nil,
+		pollRequests:                make(chan chan HTTPMonitorStats),
+		closeFilterFn:               closeFilterFn,
+		statkeeper:                  statkeeper,
 	}, nil
 }
 
@@ -140,6 +167,18 @@ func (m *Monitor) Start() error {
 				}
 
 				m.process(nil, errLostBatch)
+			case dataEvent, ok := <-m.http2BatchCompletionHandler.DataChannel:
+				if !ok {
+					return
+				}
+
+				m.processHTTP2(dataEvent.Data)
+			case _, ok := <-m.http2BatchCompletionHandler.LostChannel:
+				if !ok {
+					return
+				}
+
+				atomic.AddInt64(&m.telemetry.http2Dropped, int64(HTTP2BatchSize))
 			case reply, ok := <-m.pollRequests:
 				if !ok {
 					return
@@ -147,6 +186,7 @@ func (m *Monitor) Start() error {
 
 				transactions := m.batchManager.GetPendingTransactions()
 				m.process(transactions, nil)
+				m.flushHTTP2()
 
 				delta := m.telemetry.reset()
 
@@ -161,6 +201,7 @@ func (m *Monitor) Start() error {
 			case <-report.C:
 				transactions := m.batchManager.GetPendingTransactions()
 				m.process(transactions, nil)
+				m.flushHTTP2()
 			}
 		}
 	}()
@@ -207,6 +248,9 @@ func (m *Monitor) GetStats() map[string]int64 {
 	return map[string]int64{
 		"http_requests_dropped": m.telemetrySnapshot.dropped,
 		"http_requests_missed":  m.telemetrySnapshot.misses,
+
+		"http2_connections_lost": m.telemetrySnapshot.http2Lost,
+		"http2_segments_dropped": m.telemetrySnapshot.http2Dropped,
 	}
 }
 
@@ -237,6 +281,36 @@ func (m *Monitor) process(transactions []httpTX, err error) {
 	}
 }
 
+func (m *Monitor) processHTTP2(data []byte) {
+	if m.http2BatchManager == nil {
+		return
+	}
+
+	segs, err := m.http2BatchManager.GetSegmentsFrom(data)
+	if err == segments.ErrLostBatch {
+		atomic.AddInt64(&m.telemetry.http2Dropped, int64(HTTP2BatchSize))
+	}
+	m.statkeeper.ProcessHTTP2(segs)
+}
+
+// flushHTTP2 decodes the HTTP/2 segments captured so far. Segments are read from
+// per-CPU batches, so the segments of a connection are decoded once all the segments
+// captured before them on the other CPUs are read.
+func (m *Monitor) flushHTTP2() {
+	if m.http2BatchManager == nil {
+		return
+	}
+
+	// every segment captured before now is either read already, or pending in its batch
+	now, err := ddebpf.NowNanoseconds()
+	if err != nil {
+		return
+	}
+
+	m.statkeeper.ProcessHTTP2(m.http2BatchManager.GetPendingSegments())
+	m.statkeeper.FlushHTTP2(uint64(now))
+}
+
 func (m *Monitor) DumpMaps(maps ...string) (string, error) {
 	return m.ebpfProgram.Manager.DumpMaps(maps...)
 }
@@ -0,0 +1,271 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux_bpf
+// +build linux_bpf
+
+package http
+
+import (
+	"syscall"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/network/config"
+	"github.com/DataDog/datadog-agent/pkg/network/replay"
+	"github.com/DataDog/datadog-agent/pkg/process/util"
+)
+
+/*
+#include "../ebpf/c/http-types.h"
+*/
+import "C"
+
+// The constants below mirror the ones of the HTTP socket filter (see ebpf/c/runtime/http.c)
+const (
+	ephemeralRangeBegin = 32768
+	ephemeralRangeEnd   = 60999
+	httpsPort           = 443
+)
+
+// Replayer feeds raw packets to the HTTP monitoring: it classifies them the way the eBPF socket
+// filter does, and hands the transactions over to httpStatKeeper by batches, as they are read from eBPF.
+// HTTPS and HTTP/2 traffic are not supported.
+type Replayer struct {
+	// inFlight is the counterpart of the http_in_flight map
+	inFlight map[C.conn_tuple_t]*httpTX
+	pkt      replay.Packet
+
+	pending    []httpTX
+	statkeeper *httpStatKeeper
+	telemetry  *telemetry
+}
+
+// NewReplayer returns a new Replayer
+func NewReplayer(c *config.Config) *Replayer {
+	telemetry := newTelemetry()
+	return &Replayer{
+		inFlight:   make(map[C.conn_tuple_t]*httpTX),
+		pending:    make([]httpTX, 0, HTTPBatchSize),
+		statkeeper: newHTTPStatkeeper(c, telemetry),
+		telemetry:  telemetry,
+	}
+}
+
+// Process processes an ethernet frame captured at the given time
+func (r *Replayer) Process(frame []byte, ts time.Time) {
+	pkt := &r.pkt
+	if !replay.DecodePacket(frame, pkt) {
+		return
+	}
+
+	// don't bother to inspect packet contents when there is no chance we're dealing with plain HTTP
+	if pkt.Protocol != syscall.IPPROTO_TCP || pkt.SPort == httpsPort || pkt.DPort == httpsPort {
+		return
+	}
+
+	// srcPort represents the source port number *before* normalization
+	srcPort := pkt.SPort
+
+	// we normalize the tuple to always be (client, server),
+	// so if sport is not in ephemeral port range we flip it
+	tup := replayConnTuple(pkt.Source, pkt.Dest, pkt.SPort, pkt.DPort)
+	if !isEphemeralPort(pkt.SPort) {
+		tup = replayConnTuple(pkt.Dest, pkt.Source, pkt.DPort, pkt.SPort)
+	}
+
+	// the fragment is left empty when the payload is shorter than the buffer
+	var buffer [HTTPBufferSize]byte
+	if len(pkt.Payload) >= HTTPBufferSize {
+		copy(buffer[:], pkt.Payload)
+	}
+
+	r.process(buffer, tup, srcPort, pkt.TCPFlags, uint64(ts.UnixNano()))
+}
+
+// process mirrors http_process
+func (r *Replayer) process(buffer [HTTPBufferSize]byte, tup C.conn_tuple_t, srcPort uint16, tcpFlags uint8, now uint64) {
+	isRequest, isResponse, method := parseHTTPData(buffer[:])
+
+	tx := r.inFlight[tup]
+	switch {
+	case isRequest:
+		if tx == nil {
+			tx = &httpTX{tup: tup, owned_by_src_port: C.ushort(srcPort)}
+			r.inFlight[tup] = tx
+		}
+		if tx.owned_by_src_port != C.ushort(srcPort) {
+			return
+		}
+		// This can happen in the context of HTTP keep-alives
+		if tx.response_status_code != 0 {
+			r.enqueue(tx)
+		}
+		tx.request_method = C.uchar(method)
+		tx.request_started = C.ulonglong(now)
+		tx.response_last_seen = 0
+		tx.response_status_code = 0
+		for i, b := range buffer {
+			tx.request_fragment[i] = C.char(b)
+		}
+	case isResponse:
+		if tx == nil {
+			tx = &httpTX{tup: tup, owned_by_src_port: C.ushort(srcPort)}
+			r.inFlight[tup] = tx
+		}
+		if code, ok := parseStatusCode(buffer[:]); ok {
+			tx.response_status_code = C.ushort(code)
+		}
+	default:
+		// We're in the middle of either a request or a response
+		if tx == nil {
+			return
+		}
+	}
+
+	// If we have a (L7/application-layer) payload we want to update the response_last_seen
+	// This is to prevent things such as a keep-alive adding up to the transaction latency
+	if buffer[0] != 0 {
+		tx.response_last_seen = C.ulonglong(now)
+	}
+
+	if tcpFlags&replay.TCPFlagFIN != 0 && tx.owned_by_src_port == C.ushort(srcPort) {
+		r.enqueue(tx)
+		delete(r.inFlight, tup)
+	}
+}
+
+// enqueue mirrors http_enqueue: the transactions are handed over to httpStatKeeper once a batch is full
+func (r *Replayer) enqueue(tx *httpTX) {
+	r.pending = append(r.pending, *tx)
+	if len(r.pending) == HTTPBatchSize {
+		r.flush()
+	}
+}
+
+func (r *Replayer) flush() {
+	r.telemetry.aggregate(r.pending, nil)
+	r.statkeeper.Process(r.pending)
+	r.pending = r.pending[:0]
+}
+
+// GetAndResetAllStats returns the stats aggregated since the last call, including the ones of the
+// transactions of the batch being filled. The transactions still in flight are not accounted for,
+// as in eBPF they are only enqueued when the server sends a FIN or the client starts another request.
+func (r *Replayer) GetAndResetAllStats() map[Key]RequestStats {
+	r.flush()
+	return r.statkeeper.GetAndResetAllStats()
+}
+
+// GetStats returns the telemetry of the replay
+func (r *Replayer) GetStats() map[string]int64 {
+	var processed int64
+	for _, n := range r.telemetry.hits {
+		processed += n
+	}
+
+	return map[string]int64{
+		"requests_processed": processed,
+		"requests_dropped":   r.telemetry.dropped,
+		"requests_rejected":  r.telemetry.rejected,
+		"aggregations":       r.telemetry.aggregations,
+		"in_flight":          int64(len(r.inFlight)),
+	}
+}
+
+// ReplayResult holds what the HTTP monitoring computed out of a capture
+type ReplayResult struct {
+	Stats     map[Key]RequestStats
+	Telemetry map[string]int64
+}
+
+// ReplayPcap feeds the packets of a pcap or pcapng file to a Replayer
+func ReplayPcap(c *config.Config, path string) (*ReplayResult, error) {
+	source, err := replay.NewSource(path)
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
+
+	r := NewReplayer(c)
+	err = source.VisitPackets(nil, func(data []byte, ts time.Time) error {
+		r.Process(data, ts)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReplayResult{
+		Stats:     r.GetAndResetAllStats(),
+		Telemetry: r.GetStats(),
+	}, nil
+}
+
+// replayConnTuple mirrors read_conn_tuple_skb for a TCP segment
+func replayConnTuple(saddr, daddr util.Address, sport, dport uint16) C.conn_tuple_t {
+	var tup C.conn_tuple_t
+	saddrl, saddrh := util.ToLowHigh(saddr)
+	daddrl, daddrh := util.ToLowHigh(daddr)
+	tup.saddr_l, tup.saddr_h = C.ulonglong(saddrl), C.ulonglong(saddrh)
+	tup.daddr_l, tup.daddr_h = C.ulonglong(daddrl), C.ulonglong(daddrh)
+	tup.sport, tup.dport = C.ushort(sport), C.ushort(dport)
+	tup.metadata = C.CONN_TYPE_TCP | C.CONN_V4
+	if len(saddr.Bytes()) == 16 {
+		tup.metadata = C.CONN_TYPE_TCP | C.CONN_V6
+	}
+	return tup
+}
+
+func isEphemeralPort(port uint16) bool {
+	return port >= ephemeralRangeBegin && port <= ephemeralRangeEnd
+}
+
+// parseHTTPData mirrors http_parse_data
+func parseHTTPData(p []byte) (isRequest, isResponse bool, method Method) {
+	switch {
+	case hasPrefix(p, "HTTP"):
+		return false, true, MethodUnknown
+	case hasPrefix(p, "GET"):
+		return true, false, MethodGet
+	case hasPrefix(p, "POST"):
+		return true, false, MethodPost
+	case hasPrefix(p, "PUT"):
+		return true, false, MethodPut
+	case hasPrefix(p, "DELETE"):
+		return true, false, MethodDelete
+	case hasPrefix(p, "HEAD"):
+		return true, false, MethodHead
+	case hasPrefix(p, "OPTIONS"):
+		return true, false, MethodOptions
+	case hasPrefix(p, "PATCH"):
+		return true, false, MethodPatch
+	}
+	return false, false, MethodUnknown
+}
+
+func hasPrefix(p []byte, prefix string) bool {
+	return len(p) >= len(prefix) && string(p[:len(prefix)]) == prefix
+}
+
+// parseStatusCode mirrors http_begin_response, including its handling of non-digit characters:
+// the characters following the first space are read as digits until the code reaches 100
+func parseStatusCode(buffer []byte) (uint16, bool) {
+	var (
+		statusCode uint16
+		spaceFound bool
+	)
+	for i := 0; i < HTTPBufferSize-1; i++ {
+		if !spaceFound && buffer[i] == ' ' {
+			spaceFound = true
+		} else if spaceFound && statusCode < 100 {
+			statusCode = uint16(int(statusCode)*10 + int(int8(buffer[i])) - '0')
+		}
+	}
+
+	if statusCode < 100 || statusCode >= 600 {
+		return 0, false
+	}
+	return statusCode, true
+}
@@ -0,0 +1,155 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux_bpf
+// +build linux_bpf
+
+package http
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/network/config"
+	"github.com/DataDog/datadog-agent/pkg/network/latency"
+	"github.com/DataDog/datadog-agent/pkg/network/replay"
+	"github.com/DataDog/datadog-agent/pkg/network/replay/testutil"
+	"github.com/DataDog/datadog-agent/pkg/process/util"
+)
+
+const (
+	replayClient = "10.0.0.1"
+	replayServer = "10.0.0.2"
+)
+
+type replayConn struct {
+	start      time.Time
+	clientPort uint16
+	serverPort uint16
+}
+
+func (c replayConn) fromClient(offset time.Duration, flags uint8, payload string) testutil.Packet {
+	return testutil.TCPPacket(c.start.Add(offset), replayClient, replayServer, c.clientPort, c.serverPort, 1, flags, []byte(payload))
+}
+
+func (c replayConn) fromServer(offset time.Duration, flags uint8, payload string) testutil.Packet {
+	return testutil.TCPPacket(c.start.Add(offset), replayServer, replayClient, c.serverPort, c.clientPort, 1, flags, []byte(payload))
+}
+
+func (c replayConn) key(path string, method Method) Key {
+	return NewKey(util.AddressFromString(replayClient), util.AddressFromString(replayServer), c.clientPort, c.serverPort, path, method)
+}
+
+func TestReplayPcap(t *testing.T) {
+	const psh = replay.TCPFlagPSH | replay.TCPFlagACK
+	const fin = replay.TCPFlagFIN | replay.TCPFlagACK
+
+	conn := replayConn{start: time.Unix(1600000000, 0), clientPort: 40000, serverPort: 8080}
+	keepAlive := replayConn{start: conn.start.Add(time.Second), clientPort: 40001, serverPort: 8080}
+	https := replayConn{start: conn.start, clientPort: 40002, serverPort: 443}
+
+	path := testutil.WritePcap(t,
+		conn.fromClient(0, psh, "GET /foo?bar=baz HTTP/1.1\r\nHost: localhost\r\n\r\n"),
+		conn.fromServer(10*time.Millisecond, psh, "HTTP/1.1 404 Not Found\r\nContent-Length: 0\r\n\r\n"),
+		conn.fromClient(20*time.Millisecond, fin, ""),
+		conn.fromServer(21*time.Millisecond, fin, ""),
+
+		keepAlive.fromClient(0, psh, "POST /items HTTP/1.1\r\nHost: localhost\r\n\r\n"),
+		keepAlive.fromServer(5*time.Millisecond, psh, "HTTP/1.1 201 Created\r\nContent-Length: 0\r\n\r\n"),
+		keepAlive.fromClient(time.Second, psh, "DELETE /items/1 HTTP/1.1\r\nHost: localhost\r\n\r\n"),
+		keepAlive.fromServer(time.Second+3*time.Millisecond, psh, "HTTP/1.1 500 Internal Server Error\r\n\r\n"),
+		// the last transaction is still in flight at the end of the capture
+
+		https.fromClient(0, psh, "GET /secret HTTP/1.1\r\nHost: localhost\r\n\r\n"),
+		https.fromServer(time.Millisecond, psh, "HTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n"),
+		https.fromClient(2*time.Millisecond, fin, ""),
+	)
+
+	cfg := config.New()
+	result, err := ReplayPcap(cfg, path)
+	require.NoError(t, err)
+	require.Len(t, result.Stats, 2)
+
+	stats := result.Stats[conn.key("/foo", MethodGet)]
+	assert.Equal(t, 1, stats[3].Count)
+	assert.InEpsilon(t, float64(10*time.Millisecond), stats[3].FirstLatencySample, latency.RelativeAccuracy)
+
+	stats = result.Stats[keepAlive.key("/items", MethodPost)]
+	assert.Equal(t, 1, stats[1].Count)
+	assert.InEpsilon(t, float64(5*time.Millisecond), stats[1].FirstLatencySample, latency.RelativeAccuracy)
+
+	assert.Equal(t, int64(2), result.Telemetry["requests_processed"])
+	assert.Equal(t, int64(1), result.Telemetry["in_flight"])
+}
+
+func TestReplayReplaceRules(t *testing.T) {
+	const psh = replay.TCPFlagPSH | replay.TCPFlagACK
+	const fin = replay.TCPFlagFIN | replay.TCPFlagACK
+
+	health := replayConn{start: time.Unix(1600000000, 0), clientPort: 40000, serverPort: 8080}
+	user := replayConn{start: health.start, clientPort: 40001, serverPort: 8080}
+	path := testutil.WritePcap(t,
+		health.fromClient(0, psh, "GET /healthz HTTP/1.1\r\nHost: localhost\r\n\r\n"),
+		health.fromServer(time.Millisecond, psh, "HTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n"),
+		health.fromClient(2*time.Millisecond, fin, ""),
+
+		user.fromClient(0, psh, "GET /users/42 HTTP/1.1\r\nHost: localhost\r\n\r\n"),
+		user.fromServer(time.Millisecond, psh, "HTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n"),
+		user.fromClient(2*time.Millisecond, fin, ""),
+	)
+
+	cfg := config.New()
+	cfg.HTTPReplaceRules = []*config.ReplaceRule{
+		{Re: regexp.MustCompile("/healthz"), Repl: ""},
+		{Re: regexp.MustCompile(`/users/\d+`), Repl: "/users/?"},
+	}
+	result, err := ReplayPcap(cfg, path)
+	require.NoError(t, err)
+
+	require.Len(t, result.Stats, 1)
+	assert.Contains(t, result.Stats, user.key("/users/?", MethodGet))
+	assert.Equal(t, int64(1), result.Telemetry["requests_rejected"])
+}
+
+func TestReplayShortPayloads(t *testing.T) {
+	const psh = replay.TCPFlagPSH | replay.TCPFlagACK
+	const fin = replay.TCPFlagFIN | replay.TCPFlagACK
+
+	// fragments shorter than the eBPF buffer are not read
+	conn := replayConn{start: time.Unix(1600000000, 0), clientPort: 40000, serverPort: 8080}
+	path := testutil.WritePcap(t,
+		conn.fromClient(0, psh, "GET / HTTP/1.1\r\n\r\n"),
+		conn.fromServer(time.Millisecond, psh, "HTTP/1.1 200 OK\r\n\r\n"),
+		conn.fromClient(2*time.Millisecond, fin, ""),
+	)
+
+	result, err := ReplayPcap(config.New(), path)
+	require.NoError(t, err)
+	assert.Empty(t, result.Stats)
+}
+
+func TestParseStatusCode(t *testing.T) {
+	for _, tc := range []struct {
+		fragment string
+		code     uint16
+		ok       bool
+	}{
+		{"HTTP/1.1 200 OK\r\nContent-Le", 200, true},
+		{"HTTP/1.0 503 Service Unava", 503, true},
+		{"HTTP/1.1 099 Whatever\r\nCont", 0, false},
+		{"HTTP/1.1 600 Whatever\r\nCont", 0, false},
+		{"HTTP/1.1\r\n\r\nContent-Length", 0, false},
+	} {
+		var buffer [HTTPBufferSize]byte
+		copy(buffer[:], tc.fragment)
+		code, ok := parseStatusCode(buffer[:])
+		assert.Equal(t, tc.ok, ok, tc.fragment)
+		assert.Equal(t, tc.code, code, tc.fragment)
+	}
+}
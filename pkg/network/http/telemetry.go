@@ -24,6 +24,9 @@ type telemetry struct {
 	dropped      int64 // this happens when httpStatKeeper reaches capacity
 	rejected     int64 // this happens when an user-defined reject-filter matches a request
 	aggregations int64
+
+	http2Lost    int64 // this happens when HTTP/2 frames are missed and the decoding of a connection resumes at its next frame
+	http2Dropped int64 // this happens when we can't cope with the rate of HTTP/2 segments
 }
 
 func newTelemetry() *telemetry {
@@ -53,6 +56,8 @@ func (t *telemetry) reset() telemetry {
 		dropped:      atomic.SwapInt64(&t.dropped, 0),
 		rejected:     atomic.SwapInt64(&t.rejected, 0),
 		aggregations: atomic.SwapInt64(&t.aggregations, 0),
+		http2Lost:    atomic.SwapInt64(&t.http2Lost, 0),
+		http2Dropped: atomic.SwapInt64(&t.http2Dropped, 0),
 		elapsed:      now.Unix() - then,
 	}
 
@@ -70,7 +75,7 @@ func (t *telemetry) report() {
 	}
 
 	log.Debugf(
-		"http stats summary: requests_processed=%d(%.2f/s) requests_missed=%d(%.2f/s) requests_dropped=%d(%.2f/s) requests_rejected=%d(%.2f/s) aggregations=%d",
+		"http stats summary: requests_processed=%d(%.2f/s) requests_missed=%d(%.2f/s) requests_dropped=%d(%.2f/s) requests_rejected=%d(%.2f/s) aggregations=%d http2_connections_lost=%d http2_segments_dropped=%d",
 		totalRequests,
 		float64(totalRequests)/float64(t.elapsed),
 		t.misses,
@@ -80,5 +85,7 @@ func (t *telemetry) report() {
 		t.rejected,
 		float64(t.rejected)/float64(t.elapsed),
 		t.aggregations,
+		t.http2Lost,
+		t.http2Dropped,
 	)
 }
@@ -0,0 +1,415 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package kafka
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/pkg/network/latency"
+	"github.com/DataDog/datadog-agent/pkg/network/segments"
+)
+
+// Kafka API keys of the requests monitored
+const (
+	apiKeyProduce = 0
+	apiKeyFetch   = 1
+)
+
+const (
+	// maxMessageSize bounds the size of the messages, as the default socket.request.max.bytes
+	// of the brokers.
+	maxMessageSize = 100 * 1024 * 1024
+	// maxAPIKey and maxAPIVersion bound the API keys and versions of the requests, when looking
+	// for the segment starting a request.
+	maxAPIKey     = 127
+	maxAPIVersion = 31
+	// maxPendingPerConn is the maximum number of requests waiting for their response per connection.
+	maxPendingPerConn = 1000
+	// requestTimeout is the time after which a request without response is forgotten.
+	requestTimeout = 2 * 60 * 1e9
+)
+
+// transaction is a Kafka request to a topic and its response.
+type transaction struct {
+	key       Key
+	errorCode int16
+	latency   float64
+}
+
+// request is a Kafka request waiting for its response.
+type request struct {
+	api     API
+	version int16
+	topics  []string
+	started uint64
+}
+
+// conn decodes the messages of a Kafka connection. Each message is prefixed by its size.
+type conn struct {
+	d   *decoder
+	key Key
+	// requests waiting for their response, by correlation ID
+	pending map[int32]*request
+}
+
+// decoder decodes the Kafka messages of the captured segments into transactions. Only the
+// beginning of each segment is captured, so a request is attributed to the topics found in
+// the captured part of its message: the first topic of Produce requests, and as many topics
+// as captured for Fetch requests.
+type decoder struct {
+	*segments.Decoder
+
+	// unparsed counts the messages whose header was not captured
+	unparsed int64
+}
+
+func newDecoder(maxConns, maxBuffered int) *decoder {
+	d := &decoder{}
+	d.Decoder = segments.NewDecoder(maxConns, maxBuffered, func(seg segments.Segment) segments.Parser {
+		return &conn{d: d, key: connKey(seg.Conn), pending: make(map[int32]*request)}
+	})
+	return d
+}
+
+func (c *conn) Frame(fromClient bool, data []byte) (int, bool) {
+	if len(data) < 4 {
+		// the size of the message is not captured
+		return 0, false
+	}
+	size := int32(binary.BigEndian.Uint32(data))
+	if size < 0 || size > maxMessageSize {
+		return 0, false
+	}
+	return 4 + int(size), true
+}
+
+// Starts reports whether data starts with the header of a request, or of the response to a
+// pending request.
+func (c *conn) Starts(fromClient bool, data []byte) bool {
+	r := reader{b: data}
+	size := r.int32()
+	if fromClient {
+		apiKey := r.int16()
+		version := r.int16()
+		r.int32() // correlation_id
+		clientID := r.int16()
+		return r.err == nil && size <= maxMessageSize &&
+			apiKey >= 0 && apiKey <= maxAPIKey &&
+			version >= 0 && version <= maxAPIVersion &&
+			clientID >= -1 && 2+2+4+2+int32(clientID) <= size
+	}
+	correlationID := r.int32()
+	_, ok := c.pending[correlationID]
+	return r.err == nil && size >= 4 && size <= maxMessageSize && ok
+}
+
+// Lost does nothing: the requests whose response is missed expire, and the responses whose
+// request is missed are ignored.
+func (c *conn) Lost(fromClient bool) {}
+
+func (c *conn) Message(fromClient bool, msg []byte, truncated bool, timestamp uint64) bool {
+	var ok bool
+	if fromClient {
+		ok = c.decodeRequest(msg[4:], timestamp)
+	} else {
+		ok = c.decodeResponse(msg[4:], timestamp)
+	}
+	if !ok {
+		c.d.unparsed++
+	}
+	return true
+}
+
+func (c *conn) Encrypted() bool {
+	return false
+}
+
+// Expire forgets the requests which never got a response.
+func (c *conn) Expire(now uint64) {
+	for id, req := range c.pending {
+		if now-req.started > requestTimeout {
+			delete(c.pending, id)
+		}
+	}
+}
+
+// decodeRequest decodes the beginning of a request message. It returns false if the header
+// of the message is not captured.
+func (c *conn) decodeRequest(msg []byte, timestamp uint64) bool {
+	r := reader{b: msg}
+	apiKey := r.int16()
+	version := r.int16()
+	correlationID := r.int32()
+	r.nullableString() // client_id
+	if r.err != nil {
+		return false
+	}
+
+	var req *request
+	switch apiKey {
+	case apiKeyProduce:
+		req = &request{api: APIProduce, version: version, started: timestamp}
+		flexible := version >= 9
+		if flexible {
+			r.taggedFields()
+		}
+		if version >= 3 {
+			r.nullableStringOf(flexible) // transactional_id
+		}
+		r.skip(2 + 4) // acks, timeout_ms
+		if r.arrayLen(flexible) > 0 {
+			if topic := r.stringOf(flexible); r.err == nil {
+				req.topics = []string{topic}
+			}
+		}
+	case apiKeyFetch:
+		req = &request{api: APIFetch, version: version, started: timestamp}
+		flexible := version >= 12
+		if flexible {
+			r.taggedFields()
+		}
+		if version < 15 {
+			r.skip(4) // replica_id
+		}
+		r.skip(4 + 4) // max_wait_ms, min_bytes
+		if version >= 3 {
+			r.skip(4) // max_bytes
+		}
+		if version >= 4 {
+			r.skip(1) // isolation_level
+		}
+		if version >= 7 {
+			r.skip(4 + 4) // session_id, session_epoch
+		}
+		for n := r.arrayLen(flexible); n > 0 && r.err == nil; n-- {
+			topic := r.topic(version >= 13, flexible)
+			if r.err != nil {
+				break
+			}
+			req.topics = append(req.topics, topic)
+			// partitions
+			partitionSize := 4 + 8 + 4 // partition, fetch_offset, partition_max_bytes
+			if version >= 9 {
+				partitionSize += 4 // current_leader_epoch
+			}
+			if version >= 12 {
+				partitionSize += 4 // last_fetched_epoch
+			}
+			if version >= 5 {
+				partitionSize += 8 // log_start_offset
+			}
+			for p := r.arrayLen(flexible); p > 0 && r.err == nil; p-- {
+				r.skip(partitionSize)
+				if flexible {
+					r.taggedFields()
+				}
+			}
+			if flexible {
+				r.taggedFields()
+			}
+		}
+	default:
+		// the requests of the other APIs are kept to find the segments starting their response
+		req = &request{api: APIUnknown, version: version, started: timestamp}
+	}
+
+	if len(req.topics) == 0 && req.api != APIUnknown {
+		return false
+	}
+	if _, ok := c.pending[correlationID]; !ok && len(c.pending) >= maxPendingPerConn {
+		return true
+	}
+	c.pending[correlationID] = req
+	return true
+}
+
+// decodeResponse decodes the beginning of a response message, completing the transactions
+// of its request. It returns false if the header of the message is not captured.
+func (c *conn) decodeResponse(msg []byte, timestamp uint64) bool {
+	r := reader{b: msg}
+	correlationID := r.int32()
+	if r.err != nil {
+		return false
+	}
+	req, ok := c.pending[correlationID]
+	if !ok {
+		return true
+	}
+	delete(c.pending, correlationID)
+
+	// the error code of the first topic, and of the whole response
+	var firstTopic string
+	var topicErr, responseErr int16
+	switch req.api {
+	case APIProduce:
+		flexible := req.version >= 9
+		if flexible {
+			r.taggedFields()
+		}
+		if r.arrayLen(flexible) > 0 {
+			firstTopic = r.stringOf(flexible)
+			if r.arrayLen(flexible) > 0 {
+				r.skip(4) // index
+				topicErr = r.int16()
+			}
+		}
+	case APIFetch:
+		flexible := req.version >= 12
+		if flexible {
+			r.taggedFields()
+		}
+		if req.version >= 1 {
+			r.skip(4) // throttle_time_ms
+		}
+		if req.version >= 7 {
+			responseErr = r.int16()
+			r.skip(4) // session_id
+		}
+		if r.arrayLen(flexible) > 0 {
+			firstTopic = r.topic(req.version >= 13, flexible)
+			if r.arrayLen(flexible) > 0 {
+				r.skip(4) // partition_index
+				topicErr = r.int16()
+			}
+		}
+	}
+	if r.err != nil {
+		// the error codes are not captured
+		topicErr = 0
+	}
+
+	if timestamp < req.started {
+		return true
+	}
+	elapsed := latency.NSTimestampToFloat(timestamp - req.started)
+	for _, topic := range req.topics {
+		key := c.key
+		key.Topic = topic
+		key.API = req.api
+		errorCode := responseErr
+		if errorCode == 0 && topic == firstTopic {
+			errorCode = topicErr
+		}
+		c.d.Complete(transaction{key: key, errorCode: errorCode, latency: elapsed})
+	}
+	return true
+}
+
+// errTruncated is set by reader when the data to read is not captured.
+var errTruncated = fmt.Errorf("truncated kafka message")
+
+// reader reads the primitive types of the Kafka protocol. Once the end of the data is
+// reached, err is set and the values read are zero.
+type reader struct {
+	b   []byte
+	pos int
+	err error
+}
+
+func (r *reader) next(n int) []byte {
+	if r.err != nil || n < 0 || r.pos+n > len(r.b) {
+		r.err = errTruncated
+		return nil
+	}
+	b := r.b[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *reader) skip(n int) {
+	r.next(n)
+}
+
+func (r *reader) int16() int16 {
+	if b := r.next(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (r *reader) int32() int32 {
+	if b := r.next(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (r *reader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.b[r.pos:])
+	if n <= 0 {
+		r.err = errTruncated
+		return 0
+	}
+	r.pos += n
+	return v
+}
+
+// nullableString reads a STRING or a NULLABLE_STRING, prefixed by its length as an INT16.
+func (r *reader) nullableString() string {
+	n := r.int16()
+	if n < 0 {
+		return ""
+	}
+	return string(r.next(int(n)))
+}
+
+// compactString reads a COMPACT_STRING or a COMPACT_NULLABLE_STRING, prefixed by its length
+// plus one as an UNSIGNED_VARINT.
+func (r *reader) compactString() string {
+	n := r.uvarint()
+	if n == 0 {
+		return ""
+	}
+	return string(r.next(int(n - 1)))
+}
+
+func (r *reader) stringOf(compact bool) string {
+	if compact {
+		return r.compactString()
+	}
+	return r.nullableString()
+}
+
+func (r *reader) nullableStringOf(compact bool) {
+	r.stringOf(compact)
+}
+
+// arrayLen reads the length of an ARRAY, or of a COMPACT_ARRAY prefixed by its length plus one.
+func (r *reader) arrayLen(compact bool) int {
+	if compact {
+		n := r.uvarint()
+		if n == 0 {
+			return 0
+		}
+		return int(n - 1)
+	}
+	return int(r.int32())
+}
+
+// topic reads a topic name, or a topic ID formatted as a UUID for the requests and responses
+// identifying topics by ID.
+func (r *reader) topic(byID, compact bool) string {
+	if !byID {
+		return r.stringOf(compact)
+	}
+	b := r.next(16)
+	if b == nil {
+		return ""
+	}
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// taggedFields skips the tagged fields of flexible versions.
+func (r *reader) taggedFields() {
+	for n := r.uvarint(); n > 0 && r.err == nil; n-- {
+		r.uvarint() // tag
+		r.skip(int(r.uvarint()))
+	}
+}
@@ -0,0 +1,400 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package kafka
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/network/segments"
+	"github.com/DataDog/datadog-agent/pkg/process/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// message writes the fields of a Kafka message, following the protocol encoding.
+type message struct {
+	b       []byte
+	compact bool
+}
+
+func (m *message) int8(v int8) *message {
+	m.b = append(m.b, byte(v))
+	return m
+}
+
+func (m *message) int16(v int16) *message {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], uint16(v))
+	m.b = append(m.b, b[:]...)
+	return m
+}
+
+func (m *message) int32(v int32) *message {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], uint32(v))
+	m.b = append(m.b, b[:]...)
+	return m
+}
+
+func (m *message) int64(v int64) *message {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(v))
+	m.b = append(m.b, b[:]...)
+	return m
+}
+
+func (m *message) uvarint(v uint64) *message {
+	var b [binary.MaxVarintLen64]byte
+	m.b = append(m.b, b[:binary.PutUvarint(b[:], v)]...)
+	return m
+}
+
+func (m *message) string(s string) *message {
+	if m.compact {
+		m.uvarint(uint64(len(s) + 1))
+	} else {
+		m.int16(int16(len(s)))
+	}
+	m.b = append(m.b, s...)
+	return m
+}
+
+func (m *message) array(n int) *message {
+	if m.compact {
+		return m.uvarint(uint64(n + 1))
+	}
+	return m.int32(int32(n))
+}
+
+func (m *message) taggedFields() *message {
+	if m.compact {
+		m.b = append(m.b, 0)
+	}
+	return m
+}
+
+// bytes returns the message prefixed by its size.
+func (m *message) bytes() []byte {
+	return append((&message{}).int32(int32(len(m.b))).b, m.b...)
+}
+
+func requestHeader(apiKey, version int16, correlationID int32, flexible bool) *message {
+	m := &message{}
+	m.int16(apiKey).int16(version).int32(correlationID).string("client")
+	m.compact = flexible
+	return m.taggedFields()
+}
+
+func produceRequest(version int16, correlationID int32, topic string) []byte {
+	m := requestHeader(apiKeyProduce, version, correlationID, version >= 9)
+	if version >= 3 {
+		m.string("")
+	}
+	m.int16(1).int32(1000)
+	m.array(1).string(topic)
+	m.array(1).int32(0).array(0).taggedFields() // partition and empty records
+	m.taggedFields()
+	return m.taggedFields().bytes()
+}
+
+func produceResponse(version int16, correlationID int32, topic string, errorCode int16) []byte {
+	m := &message{compact: version >= 9}
+	m.int32(correlationID).taggedFields()
+	m.array(1).string(topic)
+	m.array(1).int32(0).int16(errorCode).int64(0)
+	return m.bytes()
+}
+
+func fetchRequest(version int16, correlationID int32, topics ...string) []byte {
+	m := requestHeader(apiKeyFetch, version, correlationID, false)
+	m.int32(-1).int32(500).int32(1).int32(1 << 20).int8(0).int32(0).int32(-1)
+	m.array(len(topics))
+	for _, topic := range topics {
+		m.string(topic)
+		m.array(1).int32(0).int32(-1).int64(0).int64(0).int32(1 << 20)
+	}
+	return m.array(0).string("").bytes()
+}
+
+func fetchResponse(correlationID int32, responseErr int16, topic string, errorCode int16) []byte {
+	m := &message{}
+	m.int32(correlationID).int32(0).int16(responseErr).int32(0)
+	m.array(1).string(topic)
+	m.array(1).int32(0).int16(errorCode).int64(0)
+	return m.bytes()
+}
+
+// testConn writes both directions of a Kafka connection as segments.
+type testConn struct {
+	conn                 segments.ConnKey
+	clientSeq, serverSeq uint32
+}
+
+func newTestConn() *testConn {
+	return newTestConnFrom(1234)
+}
+
+func newTestConnFrom(port uint16) *testConn {
+	k := NewKey(
+		util.AddressFromString("1.1.1.1"),
+		util.AddressFromString("2.2.2.2"),
+		port,
+		9092,
+		"",
+		APIUnknown,
+	)
+	return &testConn{
+		conn: segments.ConnKey{
+			SrcIPHigh: k.SrcIPHigh,
+			SrcIPLow:  k.SrcIPLow,
+			SrcPort:   k.SrcPort,
+			DstIPHigh: k.DstIPHigh,
+			DstIPLow:  k.DstIPLow,
+			DstPort:   k.DstPort,
+		},
+	}
+}
+
+func (c *testConn) segment(fromClient bool, timestamp uint64, messages ...[]byte) segments.Segment {
+	seq := &c.serverSeq
+	if fromClient {
+		seq = &c.clientSeq
+	}
+	var data []byte
+	for _, m := range messages {
+		data = append(data, m...)
+	}
+	seg := segments.Segment{
+		Conn:       c.conn,
+		Timestamp:  timestamp,
+		Seq:        *seq,
+		FromClient: fromClient,
+		Length:     len(data),
+		Data:       data,
+	}
+	*seq += uint32(len(data))
+	return seg
+}
+
+func decodeAll(d *decoder, segs ...segments.Segment) []transaction {
+	var txs []transaction
+	for _, seg := range segs {
+		d.Decode(seg, func(tx interface{}) {
+			txs = append(txs, tx.(transaction))
+		})
+	}
+	return txs
+}
+
+func (c *testConn) key(topic string, api API) Key {
+	k := connKey(c.conn)
+	k.Topic = topic
+	k.API = api
+	return k
+}
+
+func TestDecoderProduce(t *testing.T) {
+	for _, version := range []int16{2, 7, 9} {
+		d := newDecoder(100, 100)
+		c := newTestConn()
+
+		txs := decodeAll(d,
+			c.segment(true, 1000, produceRequest(version, 7, "orders")),
+			c.segment(false, 3000, produceResponse(version, 7, "orders", 0)),
+			c.segment(true, 4000, produceRequest(version, 8, "orders")),
+			c.segment(false, 9000, produceResponse(version, 8, "orders", 6)),
+		)
+		require.Len(t, txs, 2, "version %d", version)
+		assert.Equal(t, transaction{key: c.key("orders", APIProduce), latency: 2000}, txs[0])
+		assert.Equal(t, transaction{key: c.key("orders", APIProduce), errorCode: 6, latency: 5000}, txs[1])
+	}
+}
+
+func TestDecoderFetch(t *testing.T) {
+	d := newDecoder(100, 100)
+	c := newTestConn()
+
+	txs := decodeAll(d,
+		c.segment(true, 1000, fetchRequest(11, 1, "orders", "payments")),
+		c.segment(false, 2000, fetchResponse(1, 0, "orders", 3)),
+		c.segment(true, 3000, fetchRequest(11, 2, "orders")),
+		c.segment(false, 4000, fetchResponse(2, 71, "orders", 0)),
+	)
+	require.Len(t, txs, 3)
+	assert.Equal(t, transaction{key: c.key("orders", APIFetch), errorCode: 3, latency: 1000}, txs[0])
+	assert.Equal(t, transaction{key: c.key("payments", APIFetch), latency: 1000}, txs[1])
+	assert.Equal(t, transaction{key: c.key("orders", APIFetch), errorCode: 71, latency: 1000}, txs[2])
+}
+
+func TestDecoderMessageAcrossSegments(t *testing.T) {
+	d := newDecoder(100, 100)
+	c := newTestConn()
+
+	// a large request is split over two segments, followed by a second request in the same segment
+	first := produceRequest(7, 1, "orders")
+	first = append(first, make([]byte, 100)...)
+	binary.BigEndian.PutUint32(first, uint32(len(first)-4))
+	second := produceRequest(7, 2, "payments")
+	split := 60
+
+	txs := decodeAll(d,
+		c.segment(true, 1000, first[:split]),
+		c.segment(true, 1100, append(append([]byte(nil), first[split:]...), second...)),
+		c.segment(false, 2000, append(produceResponse(7, 1, "orders", 0), produceResponse(7, 2, "payments", 0)...)),
+	)
+	require.Len(t, txs, 2)
+	assert.Equal(t, "orders", txs[0].key.Topic)
+	assert.Equal(t, "payments", txs[1].key.Topic)
+	assert.Equal(t, float64(900), txs[1].latency)
+}
+
+func TestDecoderPartialCapture(t *testing.T) {
+	d := newDecoder(100, 100)
+	c := newTestConn()
+
+	// only the beginning of the segments is captured
+	req := c.segment(true, 1000, produceRequest(7, 1, "orders"))
+	req.Data = req.Data[:40]
+	resp := c.segment(false, 2000, produceResponse(7, 1, "orders", 0))
+	resp.Data = resp.Data[:8]
+
+	txs := decodeAll(d, req, resp)
+	require.Len(t, txs, 1)
+	assert.Equal(t, transaction{key: c.key("orders", APIProduce), latency: 1000}, txs[0])
+}
+
+func TestDecoderLost(t *testing.T) {
+	d := newDecoder(100, 100)
+	c := newTestConn()
+
+	c.segment(true, 1000, produceRequest(7, 1, "orders")) // missed
+	txs := decodeAll(d,
+		c.segment(true, 2000, produceRequest(7, 2, "orders")),
+		c.segment(false, 3000, produceResponse(7, 2, "orders", 0)),
+		c.segment(true, 4000, produceRequest(7, 3, "orders")),
+		c.segment(false, 5000, produceResponse(7, 3, "orders", 0)),
+	)
+	// the first request seen sets the expected sequence number; the ones after it are decoded
+	require.Len(t, txs, 2)
+
+	// the decoding resumes at the request following the one missed
+	c.segment(true, 6000, produceRequest(7, 4, "orders")) // missed
+	txs = decodeAll(d,
+		c.segment(true, 7000, produceRequest(7, 5, "orders")),
+		c.segment(false, 8000, produceResponse(7, 5, "orders", 0)),
+	)
+	require.Len(t, txs, 1)
+	assert.Equal(t, float64(1000), txs[0].latency)
+	assert.Equal(t, int64(1), d.Lost)
+
+	// the decoding of the responses resumes at the response of a pending request
+	c.segment(false, 9000, produceResponse(7, 5, "orders", 0)) // missed
+	txs = decodeAll(d,
+		c.segment(true, 10000, produceRequest(7, 6, "orders")),
+		c.segment(false, 11000, produceResponse(7, 42, "orders", 0)),
+		c.segment(false, 12000, produceResponse(7, 6, "orders", 0)),
+	)
+	require.Len(t, txs, 1)
+	assert.Equal(t, float64(2000), txs[0].latency)
+	assert.Equal(t, int64(2), d.Lost)
+}
+
+func TestDecoderHeaderNotCaptured(t *testing.T) {
+	d := newDecoder(100, 100)
+	c := newTestConn()
+
+	// the second request of the segment is beyond the KAFKA_BUFFER_SIZE bytes captured in eBPF
+	const captured = 160
+	first := produceRequest(7, 1, "orders")
+	first = append(first, make([]byte, captured)...)
+	binary.BigEndian.PutUint32(first, uint32(len(first)-4))
+	seg := c.segment(true, 1000, append(first, produceRequest(7, 2, "payments")...))
+	seg.Data = seg.Data[:captured]
+
+	txs := decodeAll(d,
+		seg,
+		c.segment(true, 2000, produceRequest(7, 3, "orders")),
+		c.segment(false, 3000, produceResponse(7, 1, "orders", 0), produceResponse(7, 2, "payments", 0), produceResponse(7, 3, "orders", 0)),
+	)
+	require.Len(t, txs, 2)
+	assert.Equal(t, transaction{key: c.key("orders", APIProduce), latency: 2000}, txs[0])
+	assert.Equal(t, transaction{key: c.key("orders", APIProduce), latency: 1000}, txs[1])
+	assert.Equal(t, int64(1), d.Lost)
+}
+
+func TestDecoderDuplicates(t *testing.T) {
+	d := newDecoder(100, 100)
+	c := newTestConn()
+
+	req := c.segment(true, 1000, produceRequest(7, 1, "orders"))
+	resp := c.segment(false, 2000, produceResponse(7, 1, "orders", 0))
+	dupResp := resp
+	dupResp.Timestamp = 2100
+
+	txs := decodeAll(d, req, req, resp, dupResp)
+	require.Len(t, txs, 1)
+	assert.Equal(t, float64(1000), txs[0].latency)
+	assert.Zero(t, d.Lost)
+}
+
+func TestDecoderFlush(t *testing.T) {
+	d := newDecoder(100, 100)
+	c := newTestConn()
+
+	req := c.segment(true, 1000, produceRequest(7, 1, "orders"))
+	resp := c.segment(false, 2000, produceResponse(7, 1, "orders", 0))
+
+	var txs []transaction
+	done := func(tx interface{}) { txs = append(txs, tx.(transaction)) }
+
+	// the segments are received out of order
+	d.Add(resp)
+	d.Add(req)
+	d.Flush(1500, done)
+	assert.Empty(t, txs)
+	assert.Equal(t, 1, d.Buffered())
+
+	d.Flush(2500, done)
+	require.Len(t, txs, 1)
+	assert.Zero(t, d.Buffered())
+}
+
+func TestDecoderExpire(t *testing.T) {
+	d := newDecoder(100, 100)
+	c := newTestConn()
+
+	var txs []transaction
+	done := func(tx interface{}) { txs = append(txs, tx.(transaction)) }
+
+	d.Add(c.segment(true, 1000, produceRequest(7, 1, "orders")))
+	d.Flush(1500, done)
+
+	// a segment of another connection makes the request time out
+	later := uint64(1000 + requestTimeout + 1)
+	d.Add(newTestConnFrom(4321).segment(true, later, produceRequest(7, 1, "orders")))
+	d.Add(c.segment(false, later+1, produceResponse(7, 1, "orders", 0)))
+	d.Flush(later+1, done)
+	d.Flush(later+2, done)
+	assert.Empty(t, txs)
+	assert.Equal(t, 2, d.Conns())
+}
+
+func TestDecoderIgnoredAPIs(t *testing.T) {
+	d := newDecoder(100, 100)
+	c := newTestConn()
+
+	// a Metadata request and its response are skipped
+	metadata := requestHeader(3, 1, 1, false).array(1).string("orders").bytes()
+	metadataResp := (&message{}).int32(1).array(0).bytes()
+
+	txs := decodeAll(d,
+		c.segment(true, 1000, append(metadata, produceRequest(7, 2, "orders")...)),
+		c.segment(false, 2000, append(metadataResp, produceResponse(7, 2, "orders", 0)...)),
+	)
+	require.Len(t, txs, 1)
+	assert.Equal(t, APIProduce, txs[0].key.API)
+}
@@ -0,0 +1,112 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package kafka
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/network/latency"
+	"github.com/DataDog/datadog-agent/pkg/network/segments"
+	"github.com/DataDog/datadog-agent/pkg/process/util"
+)
+
+// API is the type used to represent the Kafka requests monitored
+type API int
+
+const (
+	// APIUnknown represents an unknown request
+	APIUnknown API = iota
+	// APIProduce represents the Produce request
+	APIProduce
+	// APIFetch represents the Fetch request
+	APIFetch
+)
+
+// String returns a string representing the Kafka API of the request
+func (a API) String() string {
+	switch a {
+	case APIProduce:
+		return "Produce"
+	case APIFetch:
+		return "Fetch"
+	default:
+		return "Unknown"
+	}
+}
+
+// Key is an identifier for a group of Kafka requests
+type Key struct {
+	SrcIPHigh uint64
+	SrcIPLow  uint64
+	SrcPort   uint16
+
+	DstIPHigh uint64
+	DstIPLow  uint64
+	DstPort   uint16
+
+	Topic string
+	API   API
+}
+
+// NewKey generates a new Key
+func NewKey(saddr, daddr util.Address, sport, dport uint16, topic string, api API) Key {
+	saddrl, saddrh := util.ToLowHigh(saddr)
+	daddrl, daddrh := util.ToLowHigh(daddr)
+	return Key{
+		SrcIPHigh: saddrh,
+		SrcIPLow:  saddrl,
+		SrcPort:   sport,
+		DstIPHigh: daddrh,
+		DstIPLow:  daddrl,
+		DstPort:   dport,
+		Topic:     topic,
+		API:       api,
+	}
+}
+
+// connKey returns the Key of the connection k, without topic and API.
+func connKey(k segments.ConnKey) Key {
+	return Key{
+		SrcIPHigh: k.SrcIPHigh,
+		SrcIPLow:  k.SrcIPLow,
+		SrcPort:   k.SrcPort,
+		DstIPHigh: k.DstIPHigh,
+		DstIPLow:  k.DstIPLow,
+		DstPort:   k.DstPort,
+	}
+}
+
+// RequestStats stores stats for the Kafka requests of a particular API to a particular topic
+type RequestStats struct {
+	// Count is the number of requests, including the ones which failed, and
+	// Latencies their latencies
+	latency.Stats
+	// ErrorCounts holds the number of failed requests by Kafka error code
+	ErrorCounts map[int16]int
+}
+
+// CombineWith merges the data in 2 RequestStats objects
+// newStats is kept as it is, while the method receiver gets mutated
+func (r *RequestStats) CombineWith(newStats RequestStats) {
+	for code, n := range newStats.ErrorCounts {
+		if r.ErrorCounts == nil {
+			r.ErrorCounts = make(map[int16]int, len(newStats.ErrorCounts))
+		}
+		r.ErrorCounts[code] += n
+	}
+	r.Stats.CombineWith(newStats.Stats)
+}
+
+// AddRequest takes information about a Kafka request and adds it to the request stats
+// The error code is 0 for the requests which succeeded
+func (r *RequestStats) AddRequest(errorCode int16, latency float64) {
+	if errorCode != 0 {
+		if r.ErrorCounts == nil {
+			r.ErrorCounts = make(map[int16]int)
+		}
+		r.ErrorCounts[errorCode]++
+	}
+
+	r.Add(latency)
+}
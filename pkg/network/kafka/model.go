@@ -0,0 +1,84 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux_bpf
+// +build linux_bpf
+
+package kafka
+
+import (
+	"unsafe"
+
+	"github.com/DataDog/datadog-agent/pkg/network/segments"
+)
+
+/*
+#include "../ebpf/c/kafka-types.h"
+*/
+import "C"
+
+const (
+	BatchSize  = int(C.KAFKA_BATCH_SIZE)
+	BatchPages = int(C.KAFKA_BATCH_PAGES)
+	BufferSize = int(C.KAFKA_BUFFER_SIZE)
+)
+
+type segmentTX C.kafka_segment_t
+type kafkaBatch C.kafka_batch_t
+
+// batchSpec describes the batches of Kafka segments
+var batchSpec = segments.BatchSpec{
+	Size:  BatchSize,
+	Pages: BatchPages,
+	New:   func() segments.Batch { return new(kafkaBatch) },
+}
+
+func (batch *kafkaBatch) Pointer() unsafe.Pointer {
+	return unsafe.Pointer(batch)
+}
+
+func (batch *kafkaBatch) Idx() int {
+	return int(batch.idx)
+}
+
+func (batch *kafkaBatch) Pos() int {
+	return int(batch.pos)
+}
+
+// Segments returns a copy of the Kafka segments [from, to) embedded in the batch
+func (batch *kafkaBatch) Segments(from, to int) []segments.Segment {
+	txs := (*(*[BatchSize]segmentTX)(unsafe.Pointer(&batch.segments)))[from:to]
+	segs := make([]segments.Segment, len(txs))
+	for i := range txs {
+		segs[i] = txs[i].Segment()
+	}
+	return segs
+}
+
+// Segment returns a copy of the segment captured in eBPF, to be decoded
+func (s *segmentTX) Segment() segments.Segment {
+	b := *(*[BufferSize]byte)(unsafe.Pointer(&s.data))
+	n := int(s.captured_len)
+	if n > len(b) {
+		n = len(b)
+	}
+
+	return segments.Segment{
+		Conn: segments.ConnKey{
+			SrcIPHigh: uint64(s.tup.saddr_h),
+			SrcIPLow:  uint64(s.tup.saddr_l),
+			SrcPort:   uint16(s.tup.sport),
+			DstIPHigh: uint64(s.tup.daddr_h),
+			DstIPLow:  uint64(s.tup.daddr_l),
+			DstPort:   uint16(s.tup.dport),
+		},
+		Timestamp:  uint64(s.timestamp),
+		Seq:        uint32(s.seq),
+		FromClient: s.from_client != 0,
+		Fin:        s.fin != 0,
+		Length:     int(s.segment_len),
+		Data:       append([]byte(nil), b[:n]...),
+	}
+}
@@ -0,0 +1,131 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux_bpf
+// +build linux_bpf
+
+package kafka
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/network/config"
+	netebpf "github.com/DataDog/datadog-agent/pkg/network/ebpf"
+	"github.com/DataDog/datadog-agent/pkg/network/segments"
+)
+
+const (
+	kafkaConnsMap             = "kafka_conns"
+	kafkaBatchesMap           = "kafka_batches"
+	kafkaBatchStateMap        = "kafka_batch_state"
+	kafkaScratchMap           = "kafka_scratch"
+	kafkaNotificationsPerfMap = "kafka_notifications"
+
+	// ELF section of the BPF_PROG_TYPE_SOCKET_FILTER program used
+	// to capture the Kafka traffic
+	kafkaSocketFilter = "socket/kafka_filter"
+)
+
+// Monitor captures the Kafka segments with a segments.Monitor, decodes them into Kafka
+// requests, and aggregates metrics per topic.
+type Monitor struct {
+	monitor           *segments.Monitor
+	telemetry         *segments.Telemetry
+	statkeeper        *statKeeper
+	mux               sync.Mutex
+	telemetrySnapshot *segments.Telemetry
+}
+
+// NewMonitor returns a new Monitor instance
+func NewMonitor(c *config.Config) (*Monitor, error) {
+	telemetry := segments.NewTelemetry()
+	statkeeper := newStatKeeper(c, telemetry)
+	monitor, err := segments.NewMonitor(c, segments.ProgramSpec{
+		Name:             "kafka",
+		SocketFilter:     kafkaSocketFilter,
+		ConnsMap:         kafkaConnsMap,
+		BatchesMap:       kafkaBatchesMap,
+		BatchStateMap:    kafkaBatchStateMap,
+		ScratchMap:       kafkaScratchMap,
+		NotificationsMap: kafkaNotificationsPerfMap,
+		Batches:          batchSpec,
+		RuntimeCompile:   getRuntimeCompiledKafka,
+		Prebuilt:         netebpf.ReadKafkaModule,
+	}, statkeeper)
+	if err != nil {
+		return nil, fmt.Errorf("error setting up kafka ebpf program: %s", err)
+	}
+
+	return &Monitor{
+		monitor:    monitor,
+		telemetry:  telemetry,
+		statkeeper: statkeeper,
+	}, nil
+}
+
+// Start consuming Kafka events
+func (m *Monitor) Start() error {
+	if m == nil {
+		return nil
+	}
+
+	return m.monitor.Start()
+}
+
+// GetKafkaStats returns a map of Kafka stats stored in the following format:
+// [source, dest tuple, topic, API] -> RequestStats object
+func (m *Monitor) GetKafkaStats() map[Key]RequestStats {
+	if m == nil {
+		return nil
+	}
+
+	var stats map[Key]RequestStats
+	var delta segments.Telemetry
+	ok := m.monitor.Do(func() {
+		delta = m.telemetry.Reset()
+		delta.Report("kafka")
+		stats = m.statkeeper.GetAndResetAllStats()
+	})
+	if !ok {
+		return nil
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.telemetrySnapshot = &delta
+	return stats
+}
+
+// GetStats returns the telemetry of the last GetKafkaStats call
+func (m *Monitor) GetStats() map[string]int64 {
+	if m == nil {
+		return nil
+	}
+
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if m.telemetrySnapshot == nil {
+		return nil
+	}
+
+	return map[string]int64{
+		"kafka_requests_processed": m.telemetrySnapshot.Requests,
+		"kafka_requests_failed":    m.telemetrySnapshot.Errors,
+		"kafka_requests_dropped":   m.telemetrySnapshot.Dropped,
+		"kafka_messages_unparsed":  m.telemetrySnapshot.Unparsed,
+		"kafka_segments_lost":      m.telemetrySnapshot.SegmentsLost,
+		"kafka_connections_lost":   m.telemetrySnapshot.ConnsLost,
+	}
+}
+
+// Stop Kafka monitoring
+func (m *Monitor) Stop() {
+	if m == nil {
+		return
+	}
+
+	m.monitor.Stop()
+}
@@ -0,0 +1,120 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package latency aggregates the latencies of the requests of the protocols monitored by the
+// network tracer.
+package latency
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/DataDog/sketches-go/ddsketch"
+)
+
+// RelativeAccuracy defines the acceptable error in quantile values calculated by DDSketch.
+// For example, if the actual value at p50 is 100, with a relative accuracy of 0.01 the value calculated
+// will be between 99 and 101
+const RelativeAccuracy = 0.01
+
+// Stats stores the number of requests and their latencies
+type Stats struct {
+	// Note: every time we add a latency value to the DDSketch below, it's possible for the sketch to discard that value
+	// (ie if it is outside the range that is tracked by the sketch). For that reason, in order to keep an accurate count
+	// the number of requests processed, we have our own count field (rather than relying on DDSketch.GetCount())
+	Count     int
+	Latencies *ddsketch.DDSketch
+
+	// This field holds the value (in nanoseconds) of the first request. We do this
+	// as optimization to avoid creating sketches with a single value. This is quite
+	// common in the context of short-lived TCP connections used for a single request.
+	FirstLatencySample float64
+}
+
+// CombineWith merges the data in 2 Stats objects
+// newStats is kept as it is, while the method receiver gets mutated
+func (s *Stats) CombineWith(newStats Stats) {
+	if newStats.Count == 0 {
+		// Nothing to do in this case
+		return
+	}
+
+	if newStats.Count == 1 {
+		// The other stats have a single latency sample, so we "manually" add it
+		s.Add(newStats.FirstLatencySample)
+		return
+	}
+
+	// The other stats (newStats) have multiple samples and therefore a DDSketch object
+	// We first ensure that the stats we're merging to have a DDSketch object
+	if s.Latencies == nil {
+		// TODO: Consider calling Copy() on the other sketch instead
+		if err := s.initSketch(); err != nil {
+			return
+		}
+
+		// If we have a latency sample we now add it to the DDSketch
+		if s.Count == 1 {
+			err := s.Latencies.Add(s.FirstLatencySample)
+			if err != nil {
+				log.Debugf("could not add request latency to ddsketch: %v", err)
+			}
+		}
+	}
+
+	// Finally merge both sketches
+	s.Count += newStats.Count
+	err := s.Latencies.MergeWith(newStats.Latencies)
+	if err != nil {
+		log.Debugf("error merging request latencies: %v", err)
+	}
+}
+
+// Add adds the latency of a request, in nanoseconds, to the stats
+func (s *Stats) Add(latency float64) {
+	s.Count++
+	if s.Count == 1 {
+		// We postpone the creation of histograms when we have only one latency sample
+		s.FirstLatencySample = latency
+		return
+	}
+
+	if s.Latencies == nil {
+		if err := s.initSketch(); err != nil {
+			return
+		}
+
+		// Add the defered latency sample
+		err := s.Latencies.Add(s.FirstLatencySample)
+		if err != nil {
+			log.Debugf("could not add request latency to ddsketch: %v", err)
+		}
+	}
+
+	err := s.Latencies.Add(latency)
+	if err != nil {
+		log.Debugf("could not add request latency to ddsketch: %v", err)
+	}
+}
+
+func (s *Stats) initSketch() (err error) {
+	s.Latencies, err = ddsketch.NewDefaultDDSketch(RelativeAccuracy)
+	if err != nil {
+		log.Debugf("error recording request latency: could not create new ddsketch: %v", err)
+	}
+	return
+}
+
+// below is copied from pkg/trace/stats/statsraw.go
+// 10 bits precision (any value will be +/- 1/1024)
+const roundMask uint64 = 1 << 10
+
+// NSTimestampToFloat converts a nanosec timestamp into a float nanosecond timestamp truncated to a fixed precision
+func NSTimestampToFloat(ns uint64) float64 {
+	var shift uint
+	for ns > roundMask {
+		ns = ns >> 1
+		shift++
+	}
+	return float64(ns << shift)
+}
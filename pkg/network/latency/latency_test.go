@@ -0,0 +1,65 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package latency
+
+import (
+	"testing"
+
+	"github.com/DataDog/sketches-go/ddsketch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdd(t *testing.T) {
+	var stats Stats
+	stats.Add(10.0)
+	assert.Equal(t, 1, stats.Count)
+	assert.Equal(t, 10.0, stats.FirstLatencySample)
+	assert.Nil(t, stats.Latencies)
+
+	stats.Add(15.0)
+	stats.Add(20.0)
+	assert.Equal(t, 3, stats.Count)
+	assert.Equal(t, 3.0, stats.Latencies.GetCount())
+	verifyQuantile(t, stats.Latencies, 0.0, 10.0)
+	verifyQuantile(t, stats.Latencies, 1.0, 20.0)
+}
+
+func TestCombineWith(t *testing.T) {
+	var stats, single, multiple Stats
+	single.Add(10.0)
+	multiple.Add(15.0)
+	multiple.Add(20.0)
+
+	stats.CombineWith(Stats{})
+	assert.Zero(t, stats.Count)
+
+	stats.CombineWith(single)
+	assert.Equal(t, 1, stats.Count)
+	assert.Nil(t, stats.Latencies)
+
+	stats.CombineWith(multiple)
+	assert.Equal(t, 3, stats.Count)
+	verifyQuantile(t, stats.Latencies, 0.0, 10.0)
+	verifyQuantile(t, stats.Latencies, 0.5, 15.0)
+	verifyQuantile(t, stats.Latencies, 1.0, 20.0)
+
+	// the stats combined are kept as they are
+	assert.Equal(t, 2, multiple.Count)
+}
+
+func TestNSTimestampToFloat(t *testing.T) {
+	assert.Equal(t, 1000.0, NSTimestampToFloat(1000))
+	assert.InEpsilon(t, 123456789.0, NSTimestampToFloat(123456789), 1.0/1024)
+}
+
+func verifyQuantile(t *testing.T, sketch *ddsketch.DDSketch, q float64, expectedValue float64) {
+	val, err := sketch.GetValueAtQuantile(q)
+	assert.Nil(t, err)
+
+	acceptableError := expectedValue * sketch.IndexMapping.RelativeAccuracy()
+	assert.True(t, val >= expectedValue-acceptableError)
+	assert.True(t, val <= expectedValue+acceptableError)
+}
@@ -0,0 +1,30 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package network
+
+import (
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/process/util"
+)
+
+// Listener is a socket listening for TCP connections, or a UDP socket bound to a local port,
+// along with the process owning it
+type Listener struct {
+	Pid    uint32
+	NetNS  uint32
+	Type   ConnectionType
+	Family ConnectionFamily
+	Addr   util.Address
+	Port   uint16
+	Inode  uint64
+}
+
+// ListenerEvent records the appearance of a listener on a port which is not allowed
+type ListenerEvent struct {
+	Listener
+	Timestamp time.Time
+}
@@ -0,0 +1,306 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+// +build linux
+
+package network
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/network/config"
+	"github.com/DataDog/datadog-agent/pkg/process/util"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// maxListenerEventsBuffered is the maximum number of listener events kept for a client between two requests
+const maxListenerEventsBuffered = 1000
+
+// PortRange is an inclusive range of ports
+type PortRange struct {
+	Start uint16
+	End   uint16
+}
+
+// Contains returns true if the port belongs to the range
+func (r PortRange) Contains(port uint16) bool {
+	return port >= r.Start && port <= r.End
+}
+
+// ParsePortRange parses a port, such as "22", or a range of ports, such as "8000-8999"
+func ParsePortRange(s string) (PortRange, error) {
+	start, end := s, s
+	if idx := strings.IndexByte(s, '-'); idx != -1 {
+		start, end = s[:idx], s[idx+1:]
+	}
+
+	first, err := strconv.ParseUint(strings.TrimSpace(start), 10, 16)
+	if err != nil {
+		return PortRange{}, fmt.Errorf("invalid port range %q: %w", s, err)
+	}
+	last, err := strconv.ParseUint(strings.TrimSpace(end), 10, 16)
+	if err != nil {
+		return PortRange{}, fmt.Errorf("invalid port range %q: %w", s, err)
+	}
+	if first > last {
+		return PortRange{}, fmt.Errorf("invalid port range %q: start is greater than end", s)
+	}
+	return PortRange{Start: uint16(first), End: uint16(last)}, nil
+}
+
+// listenerKey identifies a listener regardless of the process owning it, so that restarting
+// a process does not make its listeners new
+type listenerKey struct {
+	netNS  uint32
+	typ    ConnectionType
+	family ConnectionFamily
+	addr   util.Address
+	port   uint16
+}
+
+func keyOf(l Listener) listenerKey {
+	return listenerKey{netNS: l.NetNS, typ: l.Type, family: l.Family, addr: l.Addr, port: l.Port}
+}
+
+type listenerClient struct {
+	lastFetch time.Time
+	events    []ListenerEvent
+}
+
+// ListenerInventory keeps an up to date inventory of the listening sockets of the host,
+// and records an event for every new listener on a port which is not allowed.
+// The listening sockets are read on every refresh, and the process owning them is only
+// resolved for the sockets which are new to the inventory.
+// The events are buffered per client from its first request on.
+type ListenerInventory struct {
+	interval      time.Duration
+	clientExpiry  time.Duration
+	allowedPorts  []PortRange
+	readSockets   func() ([]Listener, error)
+	resolveOwners func([]Listener) error
+
+	mux sync.Mutex
+	// listeners is indexed by socket inode
+	listeners map[uint64]Listener
+	// unowned holds the inodes of the sockets which are not owned by any process, such as the kernel ones
+	unowned     map[uint64]struct{}
+	initialized bool
+	clients     map[string]*listenerClient
+
+	exit chan struct{}
+	wg   sync.WaitGroup
+
+	// telemetry
+	refreshes     int64
+	errors        int64
+	events        int64
+	droppedEvents int64
+}
+
+// NewListenerInventory creates a ListenerInventory reading the listeners from the /proc filesystem
+func NewListenerInventory(cfg *config.Config) *ListenerInventory {
+	var allowed []PortRange
+	for _, s := range cfg.ListenerAllowedPorts {
+		r, err := ParsePortRange(s)
+		if err != nil {
+			log.Errorf("ignoring listener allowed port: %s", err)
+			continue
+		}
+		allowed = append(allowed, r)
+	}
+
+	return newListenerInventory(cfg.ListenerInventoryInterval, cfg.ClientStateExpiry, allowed, func() ([]Listener, error) {
+		return ReadListenerSockets(cfg.ProcRoot, cfg.CollectIPv6Conns)
+	}, func(listeners []Listener) error {
+		return ResolveListenerOwners(cfg.ProcRoot, listeners)
+	})
+}
+
+func newListenerInventory(interval, clientExpiry time.Duration, allowed []PortRange, readSockets func() ([]Listener, error), resolveOwners func([]Listener) error) *ListenerInventory {
+	return &ListenerInventory{
+		interval:      interval,
+		clientExpiry:  clientExpiry,
+		allowedPorts:  allowed,
+		readSockets:   readSockets,
+		resolveOwners: resolveOwners,
+		listeners:     make(map[uint64]Listener),
+		unowned:       make(map[uint64]struct{}),
+		clients:       make(map[string]*listenerClient),
+		exit:          make(chan struct{}),
+	}
+}
+
+// Start takes the initial inventory and keeps it up to date in the background
+func (li *ListenerInventory) Start() {
+	li.refresh(time.Now())
+
+	li.wg.Add(1)
+	go func() {
+		defer li.wg.Done()
+		ticker := time.NewTicker(li.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				li.refresh(now)
+			case <-li.exit:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops updating the inventory
+func (li *ListenerInventory) Stop() {
+	close(li.exit)
+	li.wg.Wait()
+}
+
+// GetListeners returns the listeners of the last inventory
+func (li *ListenerInventory) GetListeners() []Listener {
+	li.mux.Lock()
+	defer li.mux.Unlock()
+
+	listeners := make([]Listener, 0, len(li.listeners))
+	for _, l := range li.listeners {
+		listeners = append(listeners, l)
+	}
+	return listeners
+}
+
+// GetEvents returns the events recorded for the client since its last request.
+// The first request of a client registers it and returns no event.
+func (li *ListenerInventory) GetEvents(clientID string) []ListenerEvent {
+	li.mux.Lock()
+	defer li.mux.Unlock()
+
+	client, ok := li.clients[clientID]
+	if !ok {
+		li.clients[clientID] = &listenerClient{lastFetch: time.Now()}
+		return nil
+	}
+
+	events := client.events
+	client.events = nil
+	client.lastFetch = time.Now()
+	return events
+}
+
+// GetStats returns the telemetry of the inventory
+func (li *ListenerInventory) GetStats() map[string]interface{} {
+	li.mux.Lock()
+	listeners, clients := len(li.listeners), len(li.clients)
+	li.mux.Unlock()
+
+	return map[string]interface{}{
+		"listeners":      listeners,
+		"clients":        clients,
+		"refreshes":      atomic.LoadInt64(&li.refreshes),
+		"errors":         atomic.LoadInt64(&li.errors),
+		"events":         atomic.LoadInt64(&li.events),
+		"dropped_events": atomic.LoadInt64(&li.droppedEvents),
+	}
+}
+
+// refresh reads the listening sockets, resolves the process owning the new ones, and records an event
+// for the new listeners. The listeners of the first refresh are not new.
+func (li *ListenerInventory) refresh(now time.Time) {
+	sockets, err := li.readSockets()
+	if err != nil {
+		atomic.AddInt64(&li.errors, 1)
+		log.Warnf("could not read the listening sockets: %s", err)
+		return
+	}
+
+	// the inventory is only updated by refresh, so it can be read without the lock
+	listeners := make(map[uint64]Listener, len(sockets))
+	unowned := make(map[uint64]struct{})
+	var unknown []Listener
+	for _, s := range sockets {
+		if l, ok := li.listeners[s.Inode]; ok {
+			listeners[s.Inode] = l
+			continue
+		}
+		if _, ok := li.unowned[s.Inode]; ok {
+			unowned[s.Inode] = struct{}{}
+			continue
+		}
+		unknown = append(unknown, s)
+	}
+
+	if err := li.resolveOwners(unknown); err != nil {
+		atomic.AddInt64(&li.errors, 1)
+		log.Warnf("could not resolve the process owning the listening sockets: %s", err)
+		return
+	}
+	atomic.AddInt64(&li.refreshes, 1)
+
+	li.mux.Lock()
+	defer li.mux.Unlock()
+
+	known := make(map[listenerKey]struct{}, len(li.listeners))
+	for _, l := range li.listeners {
+		known[keyOf(l)] = struct{}{}
+	}
+
+	for _, l := range unknown {
+		// the sockets are created along with their file descriptor, so the ones without owner are not
+		// resolved again
+		if l.Pid == 0 {
+			unowned[l.Inode] = struct{}{}
+			continue
+		}
+		listeners[l.Inode] = l
+
+		// a listener bound again, such as by a restarted process, is not new
+		key := keyOf(l)
+		if _, ok := known[key]; ok || !li.initialized || li.isExpected(l) {
+			continue
+		}
+		known[key] = struct{}{}
+
+		atomic.AddInt64(&li.events, 1)
+		log.Debugf("unexpected listener on %s port %d by pid %d", l.Type, l.Port, l.Pid)
+		for _, client := range li.clients {
+			if len(client.events) >= maxListenerEventsBuffered {
+				atomic.AddInt64(&li.droppedEvents, 1)
+				continue
+			}
+			client.events = append(client.events, ListenerEvent{Listener: l, Timestamp: now})
+		}
+	}
+
+	li.listeners = listeners
+	li.unowned = unowned
+	li.initialized = true
+
+	for id, client := range li.clients {
+		if now.Sub(client.lastFetch) > li.clientExpiry {
+			log.Debugf("expiring listener events of client %s", id)
+			delete(li.clients, id)
+		}
+	}
+}
+
+// isExpected returns true if the listener does not deserve an event
+func (li *ListenerInventory) isExpected(l Listener) bool {
+	// unconnected UDP client sockets are bound to an ephemeral port
+	if l.Type == UDP && IsEphemeralPort(int(l.Port)) {
+		return true
+	}
+
+	for _, r := range li.allowedPorts {
+		if r.Contains(l.Port) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,185 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+// +build linux
+
+package network
+
+import (
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/process/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePortRange(t *testing.T) {
+	r, err := ParsePortRange("22")
+	require.NoError(t, err)
+	assert.Equal(t, PortRange{Start: 22, End: 22}, r)
+
+	r, err = ParsePortRange("8000-8999")
+	require.NoError(t, err)
+	assert.Equal(t, PortRange{Start: 8000, End: 8999}, r)
+	assert.True(t, r.Contains(8000))
+	assert.True(t, r.Contains(8999))
+	assert.False(t, r.Contains(9000))
+
+	for _, s := range []string{"", "ssh", "70000", "9000-8000", "80-"} {
+		_, err := ParsePortRange(s)
+		assert.Error(t, err, s)
+	}
+}
+
+// testListenerSources returns the functions reading the current sockets, and resolving their owner from
+// the given owners by inode, along with the inodes resolved by the last refresh
+func testListenerSources(current *[]Listener, owners map[uint64]uint32, resolved *[]uint64) (func() ([]Listener, error), func([]Listener) error) {
+	readSockets := func() ([]Listener, error) {
+		sockets := make([]Listener, len(*current))
+		for i, l := range *current {
+			l.Pid = 0
+			sockets[i] = l
+		}
+		return sockets, nil
+	}
+	resolveOwners := func(listeners []Listener) error {
+		*resolved = nil
+		for i := range listeners {
+			listeners[i].Pid = owners[listeners[i].Inode]
+			*resolved = append(*resolved, listeners[i].Inode)
+		}
+		return nil
+	}
+	return readSockets, resolveOwners
+}
+
+func TestListenerInventoryEvents(t *testing.T) {
+	sshd := Listener{Pid: 10, NetNS: 1, Type: TCP, Family: AFINET, Addr: util.AddressFromString("0.0.0.0"), Port: 22, Inode: 100}
+	backdoor := Listener{Pid: 20, NetNS: 1, Type: TCP, Family: AFINET, Addr: util.AddressFromString("0.0.0.0"), Port: 4444, Inode: 200}
+	web := Listener{Pid: 30, NetNS: 1, Type: TCP, Family: AFINET6, Addr: util.AddressFromString("::"), Port: 8080, Inode: 300}
+	resolver := Listener{Pid: 40, NetNS: 1, Type: UDP, Family: AFINET, Addr: util.AddressFromString("0.0.0.0"), Port: 45000, Inode: 400}
+
+	var current []Listener
+	var resolved []uint64
+	owners := map[uint64]uint32{100: 10, 200: 20, 300: 30, 400: 40}
+	readSockets, resolveOwners := testListenerSources(&current, owners, &resolved)
+	li := newListenerInventory(time.Minute, time.Minute, []PortRange{{Start: 8000, End: 8999}}, readSockets, resolveOwners)
+
+	// the listeners of the initial inventory are not new
+	current = []Listener{sshd}
+	now := time.Now()
+	li.refresh(now)
+	assert.Empty(t, li.GetEvents("client"))
+	assert.Equal(t, []Listener{sshd}, li.GetListeners())
+
+	// a new listener is found, with its process, before it accepts any connection,
+	// and only the owner of the new sockets is resolved
+	current = []Listener{sshd, backdoor, web, resolver}
+	li.refresh(now.Add(time.Second))
+	assert.Equal(t, []ListenerEvent{{Listener: backdoor, Timestamp: now.Add(time.Second)}}, li.GetEvents("client"))
+	assert.Empty(t, li.GetEvents("client"))
+	assert.ElementsMatch(t, current, li.GetListeners())
+	assert.ElementsMatch(t, []uint64{200, 300, 400}, resolved)
+
+	// restarting the process does not make its listener new, but its process is resolved again
+	restarted := backdoor
+	restarted.Pid, restarted.Inode = 21, 201
+	owners[201] = 21
+	current = []Listener{sshd, restarted, web, resolver}
+	li.refresh(now.Add(2 * time.Second))
+	assert.Empty(t, li.GetEvents("client"))
+	assert.Equal(t, []uint64{201}, resolved)
+	assert.ElementsMatch(t, current, li.GetListeners())
+
+	// a listener which went away is new again when it comes back
+	current = []Listener{sshd}
+	li.refresh(now.Add(3 * time.Second))
+	current = []Listener{sshd, backdoor}
+	li.refresh(now.Add(4 * time.Second))
+	assert.Len(t, li.GetEvents("client"), 1)
+
+	assert.Equal(t, int64(2), li.GetStats()["events"])
+}
+
+func TestListenerInventoryUnowned(t *testing.T) {
+	sshd := Listener{NetNS: 1, Type: TCP, Family: AFINET, Addr: util.AddressFromString("0.0.0.0"), Port: 22, Inode: 100}
+	kernel := Listener{NetNS: 1, Type: UDP, Family: AFINET, Addr: util.AddressFromString("0.0.0.0"), Port: 4789, Inode: 200}
+
+	var current []Listener
+	var resolved []uint64
+	readSockets, resolveOwners := testListenerSources(&current, map[uint64]uint32{100: 10}, &resolved)
+	li := newListenerInventory(time.Minute, time.Minute, nil, readSockets, resolveOwners)
+
+	now := time.Now()
+	li.GetEvents("client")
+	current = []Listener{sshd}
+	li.refresh(now)
+
+	// the sockets not owned by any process are left out, and their owner is not resolved again
+	current = []Listener{sshd, kernel}
+	li.refresh(now.Add(time.Second))
+	assert.Equal(t, []uint64{200}, resolved)
+	assert.Len(t, li.GetListeners(), 1)
+	assert.Empty(t, li.GetEvents("client"))
+
+	li.refresh(now.Add(2 * time.Second))
+	assert.Empty(t, resolved)
+	assert.Len(t, li.GetListeners(), 1)
+}
+
+func TestListenerInventoryClients(t *testing.T) {
+	var current []Listener
+	var resolved []uint64
+	owners := make(map[uint64]uint32)
+	readSockets, resolveOwners := testListenerSources(&current, owners, &resolved)
+	li := newListenerInventory(time.Minute, time.Minute, nil, readSockets, resolveOwners)
+
+	now := time.Now()
+	li.refresh(now)
+	assert.Empty(t, li.GetEvents("a"))
+
+	for port := uint16(1); port <= maxListenerEventsBuffered+10; port++ {
+		owners[uint64(port)] = 1
+		current = append(current, Listener{Pid: 1, NetNS: 1, Type: TCP, Family: AFINET, Addr: util.AddressFromString("0.0.0.0"), Port: port, Inode: uint64(port)})
+	}
+	li.refresh(now.Add(time.Second))
+
+	assert.Len(t, li.GetEvents("a"), maxListenerEventsBuffered)
+	assert.Equal(t, int64(10), li.GetStats()["dropped_events"])
+
+	// a client registering after the events does not get them
+	assert.Empty(t, li.GetEvents("b"))
+
+	// clients which do not come back expire
+	li.refresh(now.Add(2 * time.Hour))
+	assert.Equal(t, 0, li.GetStats()["clients"])
+}
+
+func TestReadListenerSockets(t *testing.T) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	port := uint16(ln.Addr().(*net.TCPAddr).Port)
+
+	sockets, err := ReadListenerSockets("/proc", false)
+	require.NoError(t, err)
+
+	var listeners []Listener
+	for _, s := range sockets {
+		if s.Type == TCP && s.Port == port {
+			listeners = append(listeners, s)
+		}
+	}
+	require.Len(t, listeners, 1)
+	assert.Zero(t, listeners[0].Pid)
+	assert.Equal(t, util.AddressFromString("127.0.0.1"), listeners[0].Addr)
+
+	require.NoError(t, ResolveListenerOwners("/proc", listeners))
+	assert.Equal(t, uint32(os.Getpid()), listeners[0].Pid)
+}
@@ -0,0 +1,169 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+// +build linux
+
+package network
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/process/util"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+type listenerSource struct {
+	file   string
+	typ    ConnectionType
+	family ConnectionFamily
+	status int64
+}
+
+// errListenersResolved stops walking the processes once the owner of every listener is resolved
+var errListenersResolved = errors.New("listeners resolved")
+
+// ReadListenerSockets reads the /proc filesystem and returns the listening sockets of every network namespace,
+// without the process owning them. The net files of a single process are read per network namespace.
+func ReadListenerSockets(procRoot string, collectIPv6 bool) ([]Listener, error) {
+	start := time.Now()
+	defer func() {
+		log.Debugf("Read listening sockets in %s", time.Now().Sub(start))
+	}()
+
+	sources := []listenerSource{
+		{file: "net/tcp", typ: TCP, family: AFINET, status: tcpListen},
+		{file: "net/udp", typ: UDP, family: AFINET, status: tcpClose},
+	}
+	if collectIPv6 {
+		sources = append(sources,
+			listenerSource{file: "net/tcp6", typ: TCP, family: AFINET6, status: tcpListen},
+			listenerSource{file: "net/udp6", typ: UDP, family: AFINET6, status: tcpClose},
+		)
+	}
+
+	seen := make(map[uint32]struct{})
+	var listeners []Listener
+	err := util.WithAllProcs(procRoot, func(pid int) error {
+		nsIno, err := util.GetNetNsInoFromPid(procRoot, pid)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				log.Errorf("error getting net ns for pid %d: %s", pid, err)
+			}
+			return nil
+		}
+		if _, ok := seen[nsIno]; ok {
+			return nil
+		}
+		seen[nsIno] = struct{}{}
+
+		for _, src := range sources {
+			sockets, err := readProcNetSockets(path.Join(procRoot, fmt.Sprintf("%d", pid), src.file), src.status)
+			if err != nil {
+				log.Errorf("error reading listeners net ns ino=%d pid=%d path=%s: %s", nsIno, pid, src.file, err)
+				continue
+			}
+
+			for _, s := range sockets {
+				// sockets without inode are not fully set up yet
+				if s.inode == 0 {
+					continue
+				}
+				listeners = append(listeners, Listener{
+					NetNS:  nsIno,
+					Type:   src.typ,
+					Family: src.family,
+					Addr:   s.addr,
+					Port:   s.port,
+					Inode:  s.inode,
+				})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return listeners, nil
+}
+
+// ResolveListenerOwners sets the PID of the listeners to the process owning their socket. Only the file
+// descriptors of the processes of their network namespaces are read, until every owner is resolved.
+// The PID of the listeners whose socket is not owned by any process is left to 0.
+func ResolveListenerOwners(procRoot string, listeners []Listener) error {
+	if len(listeners) == 0 {
+		return nil
+	}
+
+	start := time.Now()
+	defer func() {
+		log.Debugf("Resolved the owner of %d listeners in %s", len(listeners), time.Now().Sub(start))
+	}()
+
+	namespaces := make(map[uint32]struct{})
+	byInode := make(map[uint64]*Listener, len(listeners))
+	for i := range listeners {
+		namespaces[listeners[i].NetNS] = struct{}{}
+		byInode[listeners[i].Inode] = &listeners[i]
+	}
+
+	unresolved := len(byInode)
+	err := util.WithAllProcs(procRoot, func(pid int) error {
+		nsIno, err := util.GetNetNsInoFromPid(procRoot, pid)
+		if err != nil {
+			return nil
+		}
+		if _, ok := namespaces[nsIno]; !ok {
+			return nil
+		}
+
+		for _, inode := range readSocketInodes(procRoot, pid) {
+			// the socket is owned by the first process holding it, the others inherited it
+			if l, ok := byInode[inode]; ok && l.Pid == 0 {
+				l.Pid = uint32(pid)
+				unresolved--
+			}
+		}
+		if unresolved == 0 {
+			return errListenersResolved
+		}
+		return nil
+	})
+	if err != nil && err != errListenersResolved {
+		return err
+	}
+	return nil
+}
+
+// readSocketInodes returns the inodes of the sockets open by a process
+func readSocketInodes(procRoot string, pid int) []uint64 {
+	fdDir := path.Join(procRoot, strconv.Itoa(pid), "fd")
+	fds, err := ioutil.ReadDir(fdDir)
+	if err != nil {
+		return nil
+	}
+
+	var inodes []uint64
+	for _, fd := range fds {
+		link, err := os.Readlink(path.Join(fdDir, fd.Name()))
+		if err != nil || !strings.HasPrefix(link, "socket:[") || !strings.HasSuffix(link, "]") {
+			continue
+		}
+
+		inode, err := strconv.ParseUint(link[len("socket:["):len(link)-1], 10, 64)
+		if err != nil {
+			continue
+		}
+		inodes = append(inodes, inode)
+	}
+	return inodes
+}
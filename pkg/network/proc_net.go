@@ -11,10 +11,13 @@ package network
 import (
 	"bufio"
 	"bytes"
+	"encoding/hex"
+	"fmt"
 	"io"
 	"os"
 	"strconv"
 
+	"github.com/DataDog/datadog-agent/pkg/process/util"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
@@ -92,6 +95,105 @@ func readProcNetWithStatus(path string, status int64) ([]uint16, error) {
 	return ports, nil
 }
 
+// procNetSocket is a socket read from a /proc/net/ file
+type procNetSocket struct {
+	addr  util.Address
+	port  uint16
+	inode uint64
+}
+
+// readProcNetSockets reads a /proc/net/ file and returns the local address, port and inode of the sockets in the given state
+func readProcNetSockets(path string, status int64) ([]procNetSocket, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	reader := bufio.NewReader(f)
+
+	var sockets []procNetSocket
+
+	// Skip header line
+	_, _ = reader.ReadBytes('\n')
+
+	for {
+		b, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		iter := &fieldIterator{data: b}
+		iter.nextField() // entry number
+		rawLocal := iter.nextField()
+		iter.nextField() // remote_address
+		rawState := iter.nextField()
+
+		state, err := strconv.ParseInt(string(rawState), 16, 0)
+		if err != nil {
+			log.Errorf("error parsing tcp state [%s] as hex: %s", rawState, err)
+			continue
+		}
+		if state != status {
+			continue
+		}
+
+		// skip tx_queue:rx_queue, tr:tm->when, retrnsmt, uid and timeout
+		for i := 0; i < 5; i++ {
+			iter.nextField()
+		}
+		rawInode := iter.nextField()
+
+		addr, port, err := parseProcNetAddress(rawLocal)
+		if err != nil {
+			log.Errorf("error parsing local address [%s]: %s", rawLocal, err)
+			continue
+		}
+
+		inode, err := strconv.ParseUint(string(rawInode), 10, 64)
+		if err != nil {
+			log.Errorf("error parsing inode [%s]: %s", rawInode, err)
+			continue
+		}
+
+		sockets = append(sockets, procNetSocket{addr: addr, port: port, inode: inode})
+	}
+
+	return sockets, nil
+}
+
+// parseProcNetAddress parses an address:port pair of a /proc/net/ file.
+// The address is printed as hex 32 bits words in host byte order, which is assumed to be little endian.
+func parseProcNetAddress(raw []byte) (util.Address, uint16, error) {
+	idx := bytes.IndexByte(raw, ':')
+	if idx == -1 {
+		return nil, 0, fmt.Errorf("missing port")
+	}
+
+	port, err := strconv.ParseUint(string(raw[idx+1:]), 16, 16)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ip, err := hex.DecodeString(string(raw[:idx]))
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(ip) != 4 && len(ip) != 16 {
+		return nil, 0, fmt.Errorf("invalid address length %d", len(ip))
+	}
+	for i := 0; i < len(ip); i += 4 {
+		ip[i], ip[i+1], ip[i+2], ip[i+3] = ip[i+3], ip[i+2], ip[i+1], ip[i]
+	}
+
+	if len(ip) == 4 {
+		return util.V4AddressFromBytes(ip), uint16(port), nil
+	}
+	return util.V6AddressFromBytes(ip), uint16(port), nil
+}
+
 type fieldIterator struct {
 	data []byte
 }
@@ -13,6 +13,7 @@ import (
 	"os"
 	"testing"
 
+	"github.com/DataDog/datadog-agent/pkg/process/util"
 	"github.com/stretchr/testify/require"
 )
 
@@ -53,6 +54,49 @@ func TestReadProcNet(t *testing.T) {
 	}
 }
 
+func TestReadProcNetSockets(t *testing.T) {
+	input := `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 0200007F:B600 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 61632 1 ffff88003cc20780 100 0 0 10 0
+   1: 00000000:0016 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 16529 1 ffff880034e45e00 100 0 0 10 0
+   2: 0F02000A:0016 0202000A:C121 01 00000000:00000000 02:00091FA3 00000000     0        0 20179 3 ffff88003cc20000 20 4 1 10 -1
+`
+	file, err := writeTestFile(input)
+	require.NoError(t, err)
+	defer func() { _ = os.Remove(file.Name()) }()
+
+	sockets, err := readProcNetSockets(file.Name(), tcpListen)
+	require.NoError(t, err)
+	require.Equal(t, []procNetSocket{
+		{addr: util.AddressFromString("127.0.0.2"), port: 46592, inode: 61632},
+		{addr: util.AddressFromString("0.0.0.0"), port: 22, inode: 16529},
+	}, sockets)
+}
+
+func TestParseProcNetAddress(t *testing.T) {
+	tests := []struct {
+		raw  string
+		addr string
+		port uint16
+	}{
+		{raw: "0100007F:0016", addr: "127.0.0.1", port: 22},
+		{raw: "0F02000A:1F90", addr: "10.0.2.15", port: 8080},
+		{raw: "00000000000000000000000001000000:0035", addr: "::1", port: 53},
+		{raw: "B80D0120000000000000000001000000:01BB", addr: "2001:db8::1", port: 443},
+	}
+
+	for _, tt := range tests {
+		addr, port, err := parseProcNetAddress([]byte(tt.raw))
+		require.NoError(t, err)
+		require.Equal(t, tt.addr, addr.String())
+		require.Equal(t, tt.port, port)
+	}
+
+	_, _, err := parseProcNetAddress([]byte("0100007F"))
+	require.Error(t, err)
+	_, _, err = parseProcNetAddress([]byte("01007F:0016"))
+	require.Error(t, err)
+}
+
 func writeTestFile(content string) (f *os.File, err error) {
 	tmpfile, err := ioutil.TempFile("", "test-proc-net")
 
@@ -0,0 +1,106 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replay
+
+import (
+	"encoding/binary"
+	"syscall"
+
+	"github.com/DataDog/datadog-agent/pkg/process/util"
+)
+
+const (
+	ipv4MinHeaderLen = 20
+	ipv6HeaderLen    = 40
+	udpHeaderLen     = 8
+	tcpMinHeaderLen  = 20
+
+	etherTypeIPv4 = 0x0800
+	etherTypeIPv6 = 0x86DD
+)
+
+// TCP flags, as found in the 14th byte of the TCP header
+const (
+	TCPFlagFIN = 1 << iota
+	TCPFlagSYN
+	TCPFlagRST
+	TCPFlagPSH
+	TCPFlagACK
+)
+
+// Packet holds the fields of a TCP or UDP packet read by the eBPF socket filters
+type Packet struct {
+	Source   util.Address
+	Dest     util.Address
+	SPort    uint16
+	DPort    uint16
+	Protocol uint8 // syscall.IPPROTO_TCP or syscall.IPPROTO_UDP
+	TCPFlags uint8
+	Payload  []byte
+}
+
+// DecodePacket decodes an ethernet frame the way read_conn_tuple_skb does in eBPF:
+// IPv6 extension headers are not followed, and the payload runs until the end of the frame.
+// It returns false if the frame does not hold a TCP or UDP packet.
+func DecodePacket(frame []byte, pkt *Packet) bool {
+	if len(frame) < ethernetHeaderLen {
+		return false
+	}
+
+	var l4Proto uint8
+	data := frame[ethernetHeaderLen:]
+	switch binary.BigEndian.Uint16(frame[ethernetHeaderLen-2:]) {
+	case etherTypeIPv4:
+		if len(data) < ipv4MinHeaderLen {
+			return false
+		}
+		hdrLen := int(data[0]&0x0f) << 2
+		if hdrLen < ipv4MinHeaderLen || len(data) < hdrLen {
+			return false
+		}
+		l4Proto = data[9]
+		pkt.Source = util.V4AddressFromBytes(data[12:16])
+		pkt.Dest = util.V4AddressFromBytes(data[16:20])
+		data = data[hdrLen:]
+	case etherTypeIPv6:
+		if len(data) < ipv6HeaderLen {
+			return false
+		}
+		l4Proto = data[6]
+		pkt.Source = util.V6AddressFromBytes(data[8:24])
+		pkt.Dest = util.V6AddressFromBytes(data[24:40])
+		data = data[ipv6HeaderLen:]
+	default:
+		return false
+	}
+
+	pkt.Protocol = l4Proto
+	pkt.TCPFlags = 0
+	switch l4Proto {
+	case syscall.IPPROTO_UDP:
+		if len(data) < udpHeaderLen {
+			return false
+		}
+		pkt.SPort = binary.BigEndian.Uint16(data[0:2])
+		pkt.DPort = binary.BigEndian.Uint16(data[2:4])
+		pkt.Payload = data[udpHeaderLen:]
+	case syscall.IPPROTO_TCP:
+		if len(data) < tcpMinHeaderLen {
+			return false
+		}
+		hdrLen := int(data[12]>>4) * 4
+		if len(data) < hdrLen {
+			return false
+		}
+		pkt.SPort = binary.BigEndian.Uint16(data[0:2])
+		pkt.DPort = binary.BigEndian.Uint16(data[2:4])
+		pkt.TCPFlags = data[13]
+		pkt.Payload = data[hdrLen:]
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,188 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package replay reads packet captures so that the network protocol parsers can be
+// exercised against recorded traffic, without eBPF nor a live network interface.
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+)
+
+const (
+	ethernetHeaderLen = 14
+	linuxSLLHeaderLen = 16
+	loopbackHeaderLen = 4
+)
+
+// pcapngMagic is the block type of the section header starting every pcapng file
+var pcapngMagic = []byte{0x0a, 0x0d, 0x0d, 0x0a}
+
+// noMACAddresses fills the addresses of the synthesized ethernet headers
+var noMACAddresses [ethernetHeaderLen - 2]byte
+
+type packetReader interface {
+	ZeroCopyReadPacketData() ([]byte, gopacket.CaptureInfo, error)
+	LinkType() layers.LinkType
+}
+
+// Source reads the packets of a pcap or pcapng file.
+// Whatever the link type of the capture, the packets are visited as ethernet frames,
+// which is what the eBPF socket filters and the DNS snooper read off a raw socket.
+type Source struct {
+	file   *os.File
+	reader packetReader
+	frame  []byte
+
+	// telemetry
+	read    int64
+	skipped int64
+}
+
+// NewSource opens a pcap or pcapng file
+func NewSource(path string) (*Source, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := newPacketReader(bufio.NewReader(f))
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("could not read capture %s: %w", path, err)
+	}
+
+	switch reader.LinkType() {
+	case layers.LinkTypeEthernet, layers.LinkTypeLinuxSLL, layers.LinkTypeRaw, layers.LinkTypeIPv4,
+		layers.LinkTypeIPv6, layers.LinkTypeNull, layers.LinkTypeLoop:
+	default:
+		f.Close()
+		return nil, fmt.Errorf("unsupported link type %s in capture %s", reader.LinkType(), path)
+	}
+
+	return &Source{
+		file:   f,
+		reader: reader,
+		frame:  make([]byte, 0, 65536),
+	}, nil
+}
+
+func newPacketReader(r *bufio.Reader) (packetReader, error) {
+	magic, err := r.Peek(len(pcapngMagic))
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(magic, pcapngMagic) {
+		return pcapgo.NewNgReader(r, pcapgo.DefaultNgReaderOptions)
+	}
+	return pcapgo.NewReader(r)
+}
+
+// VisitPackets invokes the visitor for every packet of the capture, until the end of the file
+// is reached or the cancel channel is closed.
+// The data buffer is reused between invocations of the visitor and thus should not be pointed to.
+func (s *Source) VisitPackets(cancel <-chan struct{}, visitor func(data []byte, timestamp time.Time) error) error {
+	for {
+		select {
+		case <-cancel:
+			return nil
+		default:
+		}
+
+		data, ci, err := s.reader.ZeroCopyReadPacketData()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		atomic.AddInt64(&s.read, 1)
+
+		frame, ok := s.toEthernet(data)
+		if !ok {
+			atomic.AddInt64(&s.skipped, 1)
+			continue
+		}
+
+		if err := visitor(frame, ci.Timestamp); err != nil {
+			return err
+		}
+	}
+}
+
+// PacketType returns the type of the packets visited, which are always ethernet frames
+func (s *Source) PacketType() gopacket.LayerType {
+	return layers.LayerTypeEthernet
+}
+
+// Stats returns a map of counters, meant to be reported as telemetry
+func (s *Source) Stats() map[string]int64 {
+	return map[string]int64{
+		"packets_read":    atomic.LoadInt64(&s.read),
+		"packets_skipped": atomic.LoadInt64(&s.skipped),
+	}
+}
+
+// Close closes the capture file
+func (s *Source) Close() {
+	s.file.Close()
+}
+
+// toEthernet converts a packet of the capture link type into an ethernet frame.
+// Only the ethertype of the synthesized ethernet header is meaningful.
+func (s *Source) toEthernet(data []byte) ([]byte, bool) {
+	var (
+		etherType layers.EthernetType
+		payload   []byte
+	)
+
+	switch s.reader.LinkType() {
+	case layers.LinkTypeEthernet:
+		return data, true
+	case layers.LinkTypeLinuxSLL:
+		if len(data) < linuxSLLHeaderLen {
+			return nil, false
+		}
+		etherType = layers.EthernetType(uint16(data[14])<<8 | uint16(data[15]))
+		payload = data[linuxSLLHeaderLen:]
+	case layers.LinkTypeNull, layers.LinkTypeLoop:
+		if len(data) < loopbackHeaderLen {
+			return nil, false
+		}
+		payload = data[loopbackHeaderLen:]
+	default:
+		payload = data
+	}
+
+	// the raw and loopback link types hold IP packets, whose version is in the first nibble
+	if etherType == 0 {
+		if len(payload) == 0 {
+			return nil, false
+		}
+		switch payload[0] >> 4 {
+		case 4:
+			etherType = layers.EthernetTypeIPv4
+		case 6:
+			etherType = layers.EthernetTypeIPv6
+		default:
+			return nil, false
+		}
+	}
+
+	s.frame = append(s.frame[:0], noMACAddresses[:]...)
+	s.frame = append(s.frame, byte(etherType>>8), byte(etherType))
+	s.frame = append(s.frame, payload...)
+	return s.frame, true
+}
@@ -0,0 +1,151 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replay
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/network/replay/testutil"
+	"github.com/DataDog/datadog-agent/pkg/process/util"
+)
+
+func visitAll(t *testing.T, path string) ([]Packet, []time.Time) {
+	source, err := NewSource(path)
+	require.NoError(t, err)
+	defer source.Close()
+
+	var (
+		packets    []Packet
+		timestamps []time.Time
+	)
+	err = source.VisitPackets(nil, func(data []byte, ts time.Time) error {
+		var pkt Packet
+		require.True(t, DecodePacket(data, &pkt))
+		pkt.Payload = append([]byte(nil), pkt.Payload...)
+		packets = append(packets, pkt)
+		timestamps = append(timestamps, ts)
+		return nil
+	})
+	require.NoError(t, err)
+	return packets, timestamps
+}
+
+func TestSourcePcap(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	path := testutil.WritePcap(t,
+		testutil.UDPPacket(now, "10.0.0.1", "10.0.0.2", 40000, 53, []byte("a long enough query")),
+		testutil.TCPPacket(now.Add(time.Millisecond), "fd00::1", "fd00::2", 40001, 80, 1, TCPFlagFIN|TCPFlagACK, []byte("GET / HTTP/1.1")),
+	)
+
+	packets, timestamps := visitAll(t, path)
+	require.Len(t, packets, 2)
+	assert.True(t, timestamps[0].Equal(now))
+	assert.True(t, timestamps[1].Equal(now.Add(time.Millisecond)))
+
+	assert.Equal(t, Packet{
+		Source:   util.AddressFromString("10.0.0.1"),
+		Dest:     util.AddressFromString("10.0.0.2"),
+		SPort:    40000,
+		DPort:    53,
+		Protocol: syscall.IPPROTO_UDP,
+		Payload:  []byte("a long enough query"),
+	}, packets[0])
+	assert.Equal(t, Packet{
+		Source:   util.AddressFromString("fd00::1"),
+		Dest:     util.AddressFromString("fd00::2"),
+		SPort:    40001,
+		DPort:    80,
+		Protocol: syscall.IPPROTO_TCP,
+		TCPFlags: TCPFlagFIN | TCPFlagACK,
+		Payload:  []byte("GET / HTTP/1.1"),
+	}, packets[1])
+}
+
+func TestSourceLinkTypes(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	frame := serialize(t, testutil.UDPPacket(now, "10.0.0.1", "10.0.0.2", 40000, 53, []byte("a long enough query")))
+	ipPacket := frame[ethernetHeaderLen:]
+
+	sll := make([]byte, linuxSLLHeaderLen, linuxSLLHeaderLen+len(ipPacket))
+	sll[14], sll[15] = 0x08, 0x00
+	loopback := []byte{2, 0, 0, 0} // AF_INET, in host byte order
+
+	for _, tc := range []struct {
+		name     string
+		linkType layers.LinkType
+		data     []byte
+	}{
+		{"ethernet", layers.LinkTypeEthernet, frame},
+		{"raw", layers.LinkTypeRaw, ipPacket},
+		{"linux sll", layers.LinkTypeLinuxSLL, append(sll, ipPacket...)},
+		{"null", layers.LinkTypeNull, append(loopback, ipPacket...)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, ng := range []bool{false, true} {
+				path := writeCapture(t, ng, tc.linkType, now, tc.data)
+				packets, _ := visitAll(t, path)
+				require.Len(t, packets, 1)
+				assert.Equal(t, util.AddressFromString("10.0.0.2"), packets[0].Dest)
+				assert.Equal(t, []byte("a long enough query"), packets[0].Payload)
+			}
+		})
+	}
+}
+
+func TestSourceUnsupportedLinkType(t *testing.T) {
+	path := writeCapture(t, false, layers.LinkTypeIEEE802_11, time.Now(), []byte{1, 2, 3})
+	_, err := NewSource(path)
+	assert.Error(t, err)
+}
+
+func TestDecodePacketNotTransport(t *testing.T) {
+	var pkt Packet
+	assert.False(t, DecodePacket(nil, &pkt))
+	assert.False(t, DecodePacket(make([]byte, ethernetHeaderLen), &pkt))
+
+	// ICMP
+	frame := serialize(t, testutil.UDPPacket(time.Now(), "10.0.0.1", "10.0.0.2", 1, 2, nil))
+	frame[ethernetHeaderLen+9] = 1
+	assert.False(t, DecodePacket(frame, &pkt))
+}
+
+func serialize(t *testing.T, p testutil.Packet) []byte {
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	require.NoError(t, gopacket.SerializeLayers(buf, opts, p.Layers...))
+	return buf.Bytes()
+}
+
+func writeCapture(t *testing.T, ng bool, linkType layers.LinkType, ts time.Time, data []byte) string {
+	path := filepath.Join(t.TempDir(), "capture")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	ci := gopacket.CaptureInfo{Timestamp: ts, CaptureLength: len(data), Length: len(data)}
+	if ng {
+		w, err := pcapgo.NewNgWriter(f, linkType)
+		require.NoError(t, err)
+		require.NoError(t, w.WritePacket(ci, data))
+		require.NoError(t, w.Flush())
+		return path
+	}
+
+	w := pcapgo.NewWriter(f)
+	require.NoError(t, w.WriteFileHeader(65536, linkType))
+	require.NoError(t, w.WritePacket(ci, data))
+	return path
+}
@@ -0,0 +1,99 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package testutil
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+	"github.com/stretchr/testify/require"
+)
+
+// Packet is a packet to write in a capture
+type Packet struct {
+	Timestamp time.Time
+	Layers    []gopacket.SerializableLayer
+}
+
+// UDPPacket builds an ethernet frame holding a UDP datagram
+func UDPPacket(ts time.Time, src, dst string, sport, dport uint16, payload []byte) Packet {
+	udp := &layers.UDP{SrcPort: layers.UDPPort(sport), DstPort: layers.UDPPort(dport)}
+	return ipPacket(ts, src, dst, layers.IPProtocolUDP, udp, udp.SetNetworkLayerForChecksum, payload)
+}
+
+// TCPPacket builds an ethernet frame holding a TCP segment
+func TCPPacket(ts time.Time, src, dst string, sport, dport uint16, seq uint32, flags uint8, payload []byte) Packet {
+	tcp := &layers.TCP{
+		SrcPort: layers.TCPPort(sport),
+		DstPort: layers.TCPPort(dport),
+		Seq:     seq,
+		FIN:     flags&0x01 != 0,
+		SYN:     flags&0x02 != 0,
+		RST:     flags&0x04 != 0,
+		PSH:     flags&0x08 != 0,
+		ACK:     flags&0x10 != 0,
+		Window:  65535,
+	}
+	return ipPacket(ts, src, dst, layers.IPProtocolTCP, tcp, tcp.SetNetworkLayerForChecksum, payload)
+}
+
+func ipPacket(ts time.Time, src, dst string, proto layers.IPProtocol, transport gopacket.SerializableLayer, setNetworkLayer func(gopacket.NetworkLayer) error, payload []byte) Packet {
+	srcIP, dstIP := net.ParseIP(src), net.ParseIP(dst)
+
+	var (
+		ethernet = &layers.Ethernet{
+			SrcMAC: net.HardwareAddr{0x02, 0, 0, 0, 0, 1},
+			DstMAC: net.HardwareAddr{0x02, 0, 0, 0, 0, 2},
+		}
+		network gopacket.SerializableLayer
+	)
+	if srcIP.To4() != nil {
+		ethernet.EthernetType = layers.EthernetTypeIPv4
+		ip := &layers.IPv4{Version: 4, TTL: 64, Protocol: proto, SrcIP: srcIP.To4(), DstIP: dstIP.To4()}
+		_ = setNetworkLayer(ip)
+		network = ip
+	} else {
+		ethernet.EthernetType = layers.EthernetTypeIPv6
+		ip := &layers.IPv6{Version: 6, HopLimit: 64, NextHeader: proto, SrcIP: srcIP, DstIP: dstIP}
+		_ = setNetworkLayer(ip)
+		network = ip
+	}
+
+	return Packet{
+		Timestamp: ts,
+		Layers:    []gopacket.SerializableLayer{ethernet, network, transport, gopacket.Payload(payload)},
+	}
+}
+
+// WritePcap writes the packets in a pcap file of ethernet frames, and returns the path of the file
+func WritePcap(t *testing.T, packets ...Packet) string {
+	path := filepath.Join(t.TempDir(), "capture.pcap")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	w := pcapgo.NewWriter(f)
+	require.NoError(t, w.WriteFileHeader(65536, layers.LinkTypeEthernet))
+
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	for _, p := range packets {
+		buf := gopacket.NewSerializeBuffer()
+		require.NoError(t, gopacket.SerializeLayers(buf, opts, p.Layers...))
+		data := buf.Bytes()
+		require.NoError(t, w.WritePacket(gopacket.CaptureInfo{
+			Timestamp:     p.Timestamp,
+			CaptureLength: len(data),
+			Length:        len(data),
+		}, data))
+	}
+	return path
+}
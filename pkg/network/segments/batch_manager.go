@@ -0,0 +1,173 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux_bpf
+// +build linux_bpf
+
+package segments
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"github.com/DataDog/ebpf"
+)
+
+// ErrLostBatch is returned when a batch is overwritten in eBPF before it is read.
+var ErrLostBatch = errors.New("segment batch lost (not consumed fast enough)")
+
+const maxLookupsPerCPU = 2
+
+// Batch is a page of a batch map, holding the segments written in eBPF on a CPU. The
+// batches of segments are managed like the batches of HTTP transactions: see the
+// batchManager of the http package.
+type Batch interface {
+	// Pointer returns the address of the batch, as represented in eBPF.
+	Pointer() unsafe.Pointer
+	// Idx returns the index of the batch.
+	Idx() int
+	// Pos returns the number of segments written in the batch.
+	Pos() int
+	// Segments returns a copy of the segments [from, to) of the batch.
+	Segments(from, to int) []Segment
+}
+
+// BatchSpec describes the batches of segments written by an eBPF program.
+type BatchSpec struct {
+	// Size is the number of segments in a batch.
+	Size int
+	// Pages is the number of batches per CPU.
+	Pages int
+	// New returns an empty batch.
+	New func() Batch
+}
+
+// batchKey is the key of the batch maps: the batch_key_t of the eBPF programs.
+type batchKey struct {
+	cpu     uint32
+	pageNum uint32
+}
+
+// notification is the batch_notification_t of the eBPF programs, sent when a batch is full.
+type notification struct {
+	cpu      uint32
+	_        uint32
+	batchIdx uint64
+}
+
+type usrBatchState struct {
+	idx, pos int
+}
+
+// BatchManager reads the batches of segments written in eBPF.
+type BatchManager struct {
+	spec       BatchSpec
+	batchMap   *ebpf.Map
+	stateByCPU []usrBatchState
+	numCPUs    int
+}
+
+// NewBatchManager returns a BatchManager reading the batches of batchMap, after
+// initializing the batch maps for numCPUs.
+func NewBatchManager(spec BatchSpec, batchMap, batchStateMap, scratchMap *ebpf.Map, numCPUs int) *BatchManager {
+	batch := spec.New()
+	state := make([]byte, batchStateMap.ABI().ValueSize)
+	segment := make([]byte, scratchMap.ABI().ValueSize)
+
+	for i := 0; i < numCPUs; i++ {
+		// Initialize eBPF maps
+		cpu := uint32(i)
+		batchStateMap.Put(unsafe.Pointer(&cpu), unsafe.Pointer(&state[0]))
+		scratchMap.Put(unsafe.Pointer(&cpu), unsafe.Pointer(&segment[0]))
+		for j := 0; j < spec.Pages; j++ {
+			key := &batchKey{cpu: cpu, pageNum: uint32(j)}
+			batchMap.Put(unsafe.Pointer(key), batch.Pointer())
+		}
+	}
+
+	return &BatchManager{
+		spec:       spec,
+		batchMap:   batchMap,
+		stateByCPU: make([]usrBatchState, numCPUs),
+		numCPUs:    numCPUs,
+	}
+}
+
+// GetSegmentsFrom returns the segments of the batch announced by the notification data.
+func (m *BatchManager) GetSegmentsFrom(data []byte) ([]Segment, error) {
+	n := *(*notification)(unsafe.Pointer(&data[0]))
+	if int(n.cpu) >= m.numCPUs {
+		return nil, fmt.Errorf("invalid segment batch notification for cpu=%d", n.cpu)
+	}
+
+	var (
+		state = &m.stateByCPU[n.cpu]
+		batch = m.spec.New()
+		key   = &batchKey{cpu: n.cpu, pageNum: uint32(int(n.batchIdx) % m.spec.Pages)}
+	)
+
+	err := m.batchMap.Lookup(unsafe.Pointer(key), batch.Pointer())
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving segment batch for cpu=%d", n.cpu)
+	}
+
+	if batch.Idx() < state.idx {
+		// This means this batch was processed via GetPendingSegments
+		return nil, nil
+	}
+
+	if uint64(batch.Idx()) != n.batchIdx {
+		// This means the batch was overridden before we a got chance to read it
+		return nil, ErrLostBatch
+	}
+
+	offset := state.pos
+	state.idx = int(n.batchIdx) + 1
+	state.pos = 0
+
+	return batch.Segments(offset, m.spec.Size), nil
+}
+
+// GetPendingSegments returns the segments written in the batches which are not full yet.
+func (m *BatchManager) GetPendingSegments() []Segment {
+	segments := make([]Segment, 0, m.spec.Size*m.spec.Pages/2)
+	for i := 0; i < m.numCPUs; i++ {
+		for lookup := 0; lookup < maxLookupsPerCPU; lookup++ {
+			var (
+				usrState = &m.stateByCPU[i]
+				pageNum  = usrState.idx % m.spec.Pages
+				key      = &batchKey{cpu: uint32(i), pageNum: uint32(pageNum)}
+				batch    = m.spec.New()
+			)
+
+			err := m.batchMap.Lookup(unsafe.Pointer(key), batch.Pointer())
+			if err != nil {
+				break
+			}
+
+			krnStateIDX := batch.Idx()
+			krnStatePos := batch.Pos()
+			if krnStateIDX != usrState.idx || krnStatePos <= usrState.pos {
+				break
+			}
+
+			segments = append(segments, batch.Segments(usrState.pos, krnStatePos)...)
+
+			if krnStatePos == m.spec.Size {
+				// We detected a full batch before the notification was processed
+				usrState.idx++
+				usrState.pos = 0
+				continue
+			}
+
+			usrState.pos = krnStatePos
+			// Move on to the next CPU core
+			break
+		}
+	}
+
+	return segments
+}
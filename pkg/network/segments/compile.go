@@ -0,0 +1,25 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux_bpf
+// +build linux_bpf
+
+package segments
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/network/config"
+)
+
+// CFlags returns the flags compiling the eBPF programs capturing segments at runtime.
+func CFlags(config *config.Config) []string {
+	var cflags []string
+	if config.CollectIPv6Conns {
+		cflags = append(cflags, "-DFEATURE_IPV6_ENABLED")
+	}
+	if config.BPFDebug {
+		cflags = append(cflags, "-DDEBUG=1")
+	}
+	return cflags
+}
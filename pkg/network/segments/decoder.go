@@ -0,0 +1,293 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package segments decodes the protocols whose messages can't be matched in eBPF. The
+// beginning of each TCP segment of their connections is captured in eBPF and written to
+// per-CPU batches, which are read in userspace, where the segments are put back in order
+// and split into messages decoded by a protocol Parser.
+package segments
+
+import (
+	"sort"
+)
+
+const (
+	// connTimeout is the time after which an idle connection is forgotten.
+	connTimeout = 5 * 60 * 1e9
+)
+
+// ConnKey identifies a TCP connection as (client, server).
+type ConnKey struct {
+	SrcIPHigh uint64
+	SrcIPLow  uint64
+	SrcPort   uint16
+
+	DstIPHigh uint64
+	DstIPLow  uint64
+	DstPort   uint16
+}
+
+// Segment is the beginning of a TCP segment, captured in eBPF.
+type Segment struct {
+	Conn ConnKey
+	// Protocol is the protocol the connection was classified as in eBPF, by the programs
+	// capturing several protocols.
+	Protocol uint8
+	// Timestamp is the time the segment was captured, in nanoseconds.
+	Timestamp uint64
+	// Seq is the TCP sequence number of the segment.
+	Seq uint32
+	// FromClient reports whether the segment was sent by the client.
+	FromClient bool
+	// Fin reports whether the segment closes the connection.
+	Fin bool
+	// Length is the length of the TCP payload, of which Data is the beginning.
+	Length int
+	Data   []byte
+}
+
+// Parser decodes the messages of one connection of a protocol.
+type Parser interface {
+	// Frame returns the size of the message starting at data, header included. It returns
+	// false if the header is not captured, or is not valid.
+	Frame(fromClient bool, data []byte) (int, bool)
+	// Starts reports whether data, the beginning of a segment, starts a message. The
+	// decoding of a direction starts, and resumes once its message boundaries are lost, at
+	// the first segment starting a message.
+	Starts(fromClient bool, data []byte) bool
+	// Lost is called when the message boundaries of a direction are lost: the messages
+	// missed are never decoded.
+	Lost(fromClient bool)
+	// Message decodes a message. Only its beginning is captured when truncated is true. It
+	// returns false if the decoding state of the direction is lost. The transactions the
+	// message completes are passed to Decoder.Complete.
+	Message(fromClient bool, msg []byte, truncated bool, timestamp uint64) bool
+	// Encrypted reports whether the connection switched to TLS: it can't be decoded anymore.
+	Encrypted() bool
+	// Expire forgets the requests which never completed, now being the timestamp of the
+	// most recent segment decoded.
+	Expire(now uint64)
+}
+
+// direction holds the decoding state of one direction of a connection.
+type direction struct {
+	// boundary is the TCP sequence number where the next message starts, when synced. The
+	// segments before it are part of the current message, and may be missed.
+	boundary uint32
+	synced   bool
+	// nextSeq is the TCP sequence number following the data seen, when seqKnown.
+	nextSeq  uint32
+	seqKnown bool
+}
+
+// conn holds the decoding state of a connection.
+type conn struct {
+	client, server direction
+	parser         Parser
+	lastSeen       uint64
+}
+
+// Decoder splits the captured segments into messages decoded by a Parser per connection.
+// The segments are captured on several CPUs and are not received in order: they are
+// buffered, and decoded in the order they were captured when flushed. The segments within a
+// message may be missed, but when the segment starting a message is missed, or its header
+// is not captured, the decoding of the direction resumes at the next segment starting a
+// message.
+type Decoder struct {
+	conns       map[ConnKey]*conn
+	maxConns    int
+	newParser   func(Segment) Parser
+	buffered    []Segment
+	maxBuffered int
+	now         uint64 // timestamp of the most recent segment decoded
+	// done is called with the transactions completed by the segments being decoded
+	done func(tx interface{})
+
+	// Lost counts the times the message boundaries of a connection direction were lost,
+	// because the segment starting a message was missed or not captured enough
+	Lost int64
+	// Dropped counts the segments not buffered because of maxBuffered
+	Dropped int64
+}
+
+// NewDecoder returns a Decoder tracking up to maxConns connections, and buffering up to
+// maxBuffered segments. newParser returns the Parser of the connection of the first
+// segment seen for it, or nil if the connection is not to be decoded.
+func NewDecoder(maxConns, maxBuffered int, newParser func(Segment) Parser) *Decoder {
+	return &Decoder{
+		conns:       make(map[ConnKey]*conn),
+		maxConns:    maxConns,
+		newParser:   newParser,
+		maxBuffered: maxBuffered,
+	}
+}
+
+// Add buffers seg until it is decoded by Flush.
+func (d *Decoder) Add(seg Segment) {
+	if len(d.buffered) >= d.maxBuffered {
+		d.Dropped++
+		return
+	}
+	d.buffered = append(d.buffered, seg)
+}
+
+// Flush decodes the buffered segments captured before the timestamp before, calling done
+// with each transaction they complete. The segments captured later are kept, as segments
+// captured before them on other CPUs may not have been received yet.
+func (d *Decoder) Flush(before uint64, done func(tx interface{})) {
+
+	sort.SliceStable(d.buffered, func(i, j int) bool {
+		return d.buffered[i].Timestamp < d.buffered[j].Timestamp
+	})
+	n := sort.Search(len(d.buffered), func(i int) bool {
+		return d.buffered[i].Timestamp >= before
+	})
+	d.done = done
+	for _, seg := range d.buffered[:n] {
+		d.decode(seg)
+	}
+	d.done = nil
+	d.buffered = append(d.buffered[:0], d.buffered[n:]...)
+	d.expire()
+}
+
+// Decode decodes seg right away, calling done with each transaction it completes.
+func (d *Decoder) Decode(seg Segment, done func(tx interface{})) {
+	d.done = done
+	d.decode(seg)
+	d.done = nil
+}
+
+// Complete is called by the Parsers with each transaction completed by the message they decode.
+func (d *Decoder) Complete(tx interface{}) {
+	if d.done != nil {
+		d.done(tx)
+	}
+}
+
+func (d *Decoder) decode(seg Segment) {
+	if seg.Timestamp > d.now {
+		d.now = seg.Timestamp
+	}
+	c, ok := d.conns[seg.Conn]
+	if !ok {
+		if seg.Fin || len(d.conns) >= d.maxConns {
+			return
+		}
+		parser := d.newParser(seg)
+		if parser == nil {
+			return
+		}
+		c = &conn{parser: parser}
+		d.conns[seg.Conn] = c
+	}
+	c.lastSeen = seg.Timestamp
+	dir := &c.client
+	if !seg.FromClient {
+		dir = &c.server
+	}
+	if !c.parser.Encrypted() && dir.next(seg) {
+		if dir.synced && int32(dir.boundary-seg.Seq) < 0 {
+			// the segment starting the next message was missed
+			d.lose(c, dir, seg.FromClient)
+		}
+		if !dir.synced && c.parser.Starts(seg.FromClient, seg.Data) {
+			dir.boundary = seg.Seq
+			dir.synced = true
+		}
+		if dir.synced && !c.decodeMessages(dir, seg) {
+			d.lose(c, dir, seg.FromClient)
+		}
+	}
+	if seg.Fin {
+		delete(d.conns, seg.Conn)
+	}
+}
+
+// lose records that the message boundaries of dir are lost.
+func (d *Decoder) lose(c *conn, dir *direction, fromClient bool) {
+	dir.synced = false
+	c.parser.Lost(fromClient)
+	d.Lost++
+}
+
+// Conns returns the number of connections tracked.
+func (d *Decoder) Conns() int {
+	return len(d.conns)
+}
+
+// Parser returns the Parser of the connection k, or nil if it is not tracked.
+func (d *Decoder) Parser(k ConnKey) Parser {
+	if c, ok := d.conns[k]; ok {
+		return c.parser
+	}
+	return nil
+}
+
+// Buffered returns the number of segments buffered.
+func (d *Decoder) Buffered() int {
+	return len(d.buffered)
+}
+
+// next reports whether seg holds data not seen yet in dir. The segments seen already, either
+// retransmitted or captured twice on the loopback interface, are skipped.
+func (dir *direction) next(seg Segment) bool {
+	end := seg.Seq + uint32(seg.Length)
+	if dir.seqKnown && int32(end-dir.nextSeq) <= 0 {
+		return false
+	}
+	if dir.seqKnown && int32(seg.Seq-dir.nextSeq) < 0 && !dir.synced {
+		// the beginning of the segment was seen already: it can't be used to resynchronize
+		dir.nextSeq = end
+		return false
+	}
+	dir.nextSeq = end
+	dir.seqKnown = true
+	return true
+}
+
+// decodeMessages decodes the messages starting in seg, from the message boundary of dir, which
+// is not before seg. It returns false if the message boundaries are lost, because the header
+// of a message is not captured, or is not valid.
+func (c *conn) decodeMessages(dir *direction, seg Segment) bool {
+	off := int(dir.boundary - seg.Seq)
+	data := seg.Data
+	if len(data) > seg.Length {
+		data = data[:seg.Length]
+	}
+	pos := off
+	for pos < seg.Length && !c.parser.Encrypted() {
+		if pos >= len(data) {
+			// the next message is not captured
+			return false
+		}
+		size, ok := c.parser.Frame(seg.FromClient, data[pos:])
+		if !ok || size <= 0 {
+			return false
+		}
+		end := pos + size
+		msg, truncated := data[pos:], true
+		if end <= len(data) {
+			msg, truncated = data[pos:end], false
+		}
+		if !c.parser.Message(seg.FromClient, msg, truncated, seg.Timestamp) {
+			return false
+		}
+		pos = end
+	}
+	dir.boundary = seg.Seq + uint32(pos)
+	return true
+}
+
+// expire forgets the idle connections, and the requests which never completed.
+func (d *Decoder) expire() {
+	for k, c := range d.conns {
+		if d.now-c.lastSeen > connTimeout {
+			delete(d.conns, k)
+			continue
+		}
+		c.parser.Expire(d.now)
+	}
+}
@@ -0,0 +1,282 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package segments
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testMessage is a message decoded by testParser.
+type testMessage struct {
+	fromClient bool
+	data       string
+	truncated  bool
+}
+
+// testParser decodes messages prefixed by their size, as a byte, and a '>' marker. Each
+// message completes a transaction, its data.
+type testParser struct {
+	d        *Decoder
+	messages []testMessage
+	lost     int
+	expired  uint64
+}
+
+func (p *testParser) Frame(fromClient bool, data []byte) (int, bool) {
+	if len(data) < 2 || data[0] == 0 || data[1] != '>' {
+		return 0, false
+	}
+	return 1 + int(data[0]), true
+}
+
+func (p *testParser) Starts(fromClient bool, data []byte) bool {
+	_, ok := p.Frame(fromClient, data)
+	return ok
+}
+
+func (p *testParser) Lost(fromClient bool) {
+	p.lost++
+}
+
+func (p *testParser) Message(fromClient bool, msg []byte, truncated bool, timestamp uint64) bool {
+	p.messages = append(p.messages, testMessage{fromClient: fromClient, data: string(msg[2:]), truncated: truncated})
+	p.d.Complete(string(msg[2:]))
+	return true
+}
+
+func (p *testParser) Encrypted() bool {
+	return false
+}
+
+func (p *testParser) Expire(now uint64) {
+	p.expired = now
+}
+
+// msg returns the message s prefixed by its size and the '>' marker.
+func msg(s string) []byte {
+	return append([]byte{byte(len(s) + 1), '>'}, s...)
+}
+
+// testConn writes both directions of a connection as segments.
+type testConn struct {
+	conn                 ConnKey
+	clientSeq, serverSeq uint32
+}
+
+func newTestConn(port uint16) *testConn {
+	return &testConn{conn: ConnKey{SrcIPLow: 1, SrcPort: port, DstIPLow: 2, DstPort: 80}}
+}
+
+func (c *testConn) segment(fromClient bool, timestamp uint64, data ...[]byte) Segment {
+	seq := &c.serverSeq
+	if fromClient {
+		seq = &c.clientSeq
+	}
+	var b []byte
+	for _, d := range data {
+		b = append(b, d...)
+	}
+	seg := Segment{
+		Conn:       c.conn,
+		Timestamp:  timestamp,
+		Seq:        *seq,
+		FromClient: fromClient,
+		Length:     len(b),
+		Data:       b,
+	}
+	*seq += uint32(len(b))
+	return seg
+}
+
+func newTestDecoder(maxConns, maxBuffered int) *Decoder {
+	var d *Decoder
+	d = NewDecoder(maxConns, maxBuffered, func(Segment) Parser { return &testParser{d: d} })
+	return d
+}
+
+func messages(d *Decoder, c *testConn) []testMessage {
+	return d.Parser(c.conn).(*testParser).messages
+}
+
+func TestDecoderMessages(t *testing.T) {
+	d := newTestDecoder(100, 100)
+	c := newTestConn(1234)
+
+	long := msg("a long message split over two segments")
+	d.Decode(c.segment(true, 1000, msg("ping"), long[:10]), nil)
+	d.Decode(c.segment(true, 1100, long[10:], msg("next")), nil)
+	d.Decode(c.segment(false, 1200, msg("pong")), nil)
+
+	assert.Equal(t, []testMessage{
+		{fromClient: true, data: "ping"},
+		{fromClient: true, data: string(long[2:10]), truncated: true},
+		{fromClient: true, data: "next"},
+		{fromClient: false, data: "pong"},
+	}, messages(d, c))
+	assert.Zero(t, d.Lost)
+}
+
+func TestDecoderPartialCapture(t *testing.T) {
+	d := newTestDecoder(100, 100)
+	c := newTestConn(1234)
+
+	seg := c.segment(true, 1000, msg("first"), msg("second"))
+	seg.Data = seg.Data[:5]
+	d.Decode(seg, nil)
+
+	// the second message is not captured
+	assert.Equal(t, []testMessage{{fromClient: true, data: "fir", truncated: true}}, messages(d, c))
+	assert.EqualValues(t, 1, d.Lost)
+	assert.Equal(t, 1, d.Parser(c.conn).(*testParser).lost)
+
+	// the decoding resumes at the next segment starting a message
+	d.Decode(c.segment(true, 2000, msg("third")), nil)
+	assert.Equal(t, testMessage{fromClient: true, data: "third"}, messages(d, c)[1])
+	assert.EqualValues(t, 1, d.Lost)
+}
+
+func TestDecoderMissedWithinMessage(t *testing.T) {
+	d := newTestDecoder(100, 100)
+	c := newTestConn(1234)
+
+	// the middle of a long message is missed
+	long := msg("a long message split over three segments")
+	d.Decode(c.segment(true, 1000, long[:10]), nil)
+	c.segment(true, 1100, long[10:20])
+	d.Decode(c.segment(true, 1200, long[20:], msg("next")), nil)
+
+	require.Len(t, messages(d, c), 2)
+	assert.Equal(t, testMessage{fromClient: true, data: "next"}, messages(d, c)[1])
+	assert.Zero(t, d.Lost)
+}
+
+func TestDecoderResync(t *testing.T) {
+	d := newTestDecoder(100, 100)
+	c := newTestConn(1234)
+
+	// the decoding starts at the first segment starting a message
+	long := msg("a long message split over two segments")
+	c.segment(true, 1000, long[:10])
+	d.Decode(c.segment(true, 1100, long[10:], msg("skipped")), nil)
+	d.Decode(c.segment(true, 1200, msg("first")), nil)
+	assert.Equal(t, []testMessage{{fromClient: true, data: "first"}}, messages(d, c))
+	assert.Zero(t, d.Lost)
+
+	// the segment starting the next message is missed, the following one resyncs
+	c.segment(true, 2000, long[:10])
+	d.Decode(c.segment(true, 2100, msg("second")), nil)
+	assert.Equal(t, testMessage{fromClient: true, data: "second"}, messages(d, c)[1])
+	assert.EqualValues(t, 1, d.Lost)
+
+	// the segment starting the next message is missed, and the following one is not
+	// starting a message
+	c.segment(true, 3000, long[:10])
+	d.Decode(c.segment(true, 3100, long[10:]), nil)
+	d.Decode(c.segment(true, 3200, msg("third")), nil)
+	assert.Equal(t, testMessage{fromClient: true, data: "third"}, messages(d, c)[2])
+	assert.EqualValues(t, 2, d.Lost)
+}
+
+func TestDecoderDuplicates(t *testing.T) {
+	d := newTestDecoder(100, 100)
+	c := newTestConn(1234)
+
+	first := c.segment(true, 1000, msg("first"))
+	second := c.segment(true, 2000, msg("second"))
+
+	// segments captured twice on the loopback interface, or retransmitted
+	d.Decode(first, nil)
+	d.Decode(first, nil)
+	d.Decode(second, nil)
+	d.Decode(first, nil)
+	assert.Len(t, messages(d, c), 2)
+	assert.Zero(t, d.Lost)
+
+	// a segment retransmitted with more data is decoded from the message boundary
+	third := c.segment(true, 3000, msg("third"))
+	fourth := c.segment(true, 3100, msg("fourth"))
+	d.Decode(third, nil)
+	retransmitted := third
+	retransmitted.Length += fourth.Length
+	retransmitted.Data = append(append([]byte(nil), third.Data...), fourth.Data...)
+	d.Decode(retransmitted, nil)
+	d.Decode(fourth, nil)
+	assert.Equal(t, testMessage{fromClient: true, data: "fourth"}, messages(d, c)[3])
+	assert.Len(t, messages(d, c), 4)
+	assert.Zero(t, d.Lost)
+}
+
+func TestDecoderFlush(t *testing.T) {
+	d := newTestDecoder(100, 2)
+	c := newTestConn(1234)
+
+	first := c.segment(true, 1000, msg("first"))
+	second := c.segment(true, 2000, msg("second"))
+
+	// the segments are received out of order, and over the buffer limit
+	d.Add(second)
+	d.Add(first)
+	d.Add(first)
+	assert.EqualValues(t, 1, d.Dropped)
+
+	var completed []interface{}
+	done := func(tx interface{}) {
+		completed = append(completed, tx)
+	}
+	d.Flush(1500, done)
+	assert.Equal(t, []testMessage{{fromClient: true, data: "first"}}, messages(d, c))
+	assert.Equal(t, []interface{}{"first"}, completed)
+	assert.Equal(t, 1, d.Buffered())
+
+	d.Flush(2500, done)
+	assert.Len(t, messages(d, c), 2)
+	assert.Equal(t, []interface{}{"first", "second"}, completed)
+	assert.Zero(t, d.Buffered())
+	assert.EqualValues(t, 2000, d.Parser(c.conn).(*testParser).expired)
+}
+
+func TestDecoderConns(t *testing.T) {
+	d := newTestDecoder(1, 100)
+	c := newTestConn(1234)
+
+	d.Decode(c.segment(true, 1000, msg("first")), nil)
+	require.Equal(t, 1, d.Conns())
+
+	// connections over the limit are not tracked
+	other := newTestConn(4321)
+	d.Decode(other.segment(true, 1000, msg("first")), nil)
+	assert.Equal(t, 1, d.Conns())
+	assert.Nil(t, d.Parser(other.conn))
+
+	fin := c.segment(false, 2000)
+	fin.Fin = true
+	d.Decode(fin, nil)
+	assert.Zero(t, d.Conns())
+
+	// the connections not decoded are not tracked
+	d = NewDecoder(1, 100, func(Segment) Parser { return nil })
+	d.Decode(c.segment(true, 3000, msg("first")), nil)
+	assert.Zero(t, d.Conns())
+}
+
+func TestDecoderExpire(t *testing.T) {
+	d := newTestDecoder(100, 100)
+	c := newTestConn(1234)
+	other := newTestConn(4321)
+
+	d.Add(c.segment(true, 1000, msg("first")))
+	d.Flush(1001, nil)
+	require.Equal(t, 1, d.Conns())
+
+	// the idle connection is forgotten when a later segment is decoded
+	d.Add(other.segment(true, 1000+connTimeout+1, msg("first")))
+	d.Flush(1000+connTimeout+2, nil)
+	assert.Equal(t, 1, d.Conns())
+	assert.Nil(t, d.Parser(c.conn))
+}
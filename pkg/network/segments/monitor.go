@@ -0,0 +1,306 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux_bpf
+// +build linux_bpf
+
+package segments
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	ddebpf "github.com/DataDog/datadog-agent/pkg/ebpf"
+	"github.com/DataDog/datadog-agent/pkg/ebpf/bytecode"
+	"github.com/DataDog/datadog-agent/pkg/network/config"
+	"github.com/DataDog/datadog-agent/pkg/network/ebpf/probes"
+	filterpkg "github.com/DataDog/datadog-agent/pkg/network/filter"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/DataDog/ebpf"
+	"github.com/DataDog/ebpf/manager"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// maxActive configures the maximum number of instances of the
+	// kretprobe-probed functions handled simultaneously.
+	maxActive = 128
+
+	// size of the channel containing the batch notifications
+	batchNotificationsChanSize = 100
+)
+
+// ProgramSpec describes the eBPF program capturing the segments of the connections of a
+// protocol with a socket filter.
+type ProgramSpec struct {
+	// Name is the name of the protocol, as used in the logs and errors.
+	Name string
+	// SocketFilter is the ELF section of the BPF_PROG_TYPE_SOCKET_FILTER program
+	// capturing the segments.
+	SocketFilter string
+
+	// Names of the maps of the program
+	ConnsMap         string
+	BatchesMap       string
+	BatchStateMap    string
+	ScratchMap       string
+	NotificationsMap string
+
+	// Batches describes the batches of segments written in BatchesMap.
+	Batches BatchSpec
+
+	// RuntimeCompile returns the program compiled at runtime.
+	RuntimeCompile func(c *config.Config) (bytecode.AssetReader, error)
+	// Prebuilt returns the pre-compiled program.
+	Prebuilt func(bpfDir string, debug bool) (bytecode.AssetReader, error)
+	// ConstantEditors are applied to the program when it is loaded.
+	ConstantEditors []manager.ConstantEditor
+}
+
+// Consumer decodes the segments read by a Monitor.
+type Consumer interface {
+	// Process buffers the segments until they are decoded by Flush.
+	Process(segments []Segment)
+	// Flush decodes the segments captured before the monotonic timestamp before.
+	Flush(before uint64)
+	// SegmentsLost counts the segments lost because they weren't read fast enough.
+	SegmentsLost(n int)
+}
+
+// Monitor is responsible for:
+// * Creating a raw socket and attaching the eBPF socket filter of a protocol to it;
+// * Polling a perf buffer that contains notifications about batches of segments ready to be read;
+// * Querying these batches by doing a map lookup;
+// * Passing the segments to a Consumer decoding them.
+type Monitor struct {
+	spec                   ProgramSpec
+	manager                *manager.Manager
+	batchManager           *BatchManager
+	batchCompletionHandler *ddebpf.PerfHandler
+	consumer               Consumer
+	pollRequests           chan func()
+
+	// termination
+	mux           sync.Mutex
+	eventLoopWG   sync.WaitGroup
+	closeFilterFn func()
+	stopped       bool
+}
+
+// NewMonitor returns a new Monitor passing the segments captured by the program of spec
+// to consumer.
+func NewMonitor(c *config.Config, spec ProgramSpec, consumer Consumer) (*Monitor, error) {
+	var bc bytecode.AssetReader
+	var err error
+	if c.EnableRuntimeCompiler {
+		bc, err = spec.RuntimeCompile(c)
+		if err != nil {
+			if !c.AllowPrecompiledFallback {
+				return nil, fmt.Errorf("error compiling network %s tracer: %s", spec.Name, err)
+			}
+			log.Warnf("error compiling network %s tracer, falling back to pre-compiled: %s", spec.Name, err)
+		}
+	}
+
+	if bc == nil {
+		bc, err = spec.Prebuilt(c.BPFDir, c.BPFDebug)
+		if err != nil {
+			return nil, fmt.Errorf("could not read bpf module: %s", err)
+		}
+	}
+	defer bc.Close()
+
+	batchCompletionHandler := ddebpf.NewPerfHandler(batchNotificationsChanSize)
+	mgr := &manager.Manager{
+		Maps: []*manager.Map{
+			{Name: spec.ConnsMap},
+			{Name: spec.BatchesMap},
+			{Name: spec.BatchStateMap},
+			{Name: spec.ScratchMap},
+		},
+		PerfMaps: []*manager.PerfMap{
+			{
+				Map: manager.Map{Name: spec.NotificationsMap},
+				PerfMapOptions: manager.PerfMapOptions{
+					PerfRingBufferSize: 8 * os.Getpagesize(),
+					Watermark:          1,
+					DataHandler:        batchCompletionHandler.DataHandler,
+					LostHandler:        batchCompletionHandler.LostHandler,
+				},
+			},
+		},
+		Probes: []*manager.Probe{
+			{Section: spec.SocketFilter},
+			{Section: string(probes.TCPSendMsgReturn), KProbeMaxActive: maxActive},
+		},
+	}
+
+	options := manager.Options{
+		RLimit: &unix.Rlimit{
+			Cur: math.MaxUint64,
+			Max: math.MaxUint64,
+		},
+		MapSpecEditors: map[string]manager.MapSpecEditor{
+			spec.ConnsMap: {
+				Type:       ebpf.Hash,
+				MaxEntries: uint32(c.MaxTrackedConnections),
+				EditorFlag: manager.EditMaxEntries,
+			},
+		},
+		ActivatedProbes: []manager.ProbesSelector{
+			&manager.ProbeSelector{
+				ProbeIdentificationPair: manager.ProbeIdentificationPair{
+					Section: spec.SocketFilter,
+				},
+			},
+			&manager.ProbeSelector{
+				ProbeIdentificationPair: manager.ProbeIdentificationPair{
+					Section: string(probes.TCPSendMsgReturn),
+				},
+			},
+		},
+		ConstantEditors: spec.ConstantEditors,
+	}
+
+	if err := mgr.InitWithOptions(bc, options); err != nil {
+		return nil, fmt.Errorf("error initializing %s ebpf program: %s", spec.Name, err)
+	}
+
+	filter, _ := mgr.GetProbe(manager.ProbeIdentificationPair{Section: spec.SocketFilter})
+	if filter == nil {
+		return nil, fmt.Errorf("error retrieving socket filter")
+	}
+
+	closeFilterFn, err := filterpkg.HeadlessSocketFilter(c.ProcRoot, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error enabling %s traffic inspection: %s", spec.Name, err)
+	}
+
+	batchMap, _, err := mgr.GetMap(spec.BatchesMap)
+	if err != nil {
+		return nil, err
+	}
+
+	batchStateMap, _, err := mgr.GetMap(spec.BatchStateMap)
+	if err != nil {
+		return nil, err
+	}
+
+	scratchMap, _, err := mgr.GetMap(spec.ScratchMap)
+	if err != nil {
+		return nil, err
+	}
+
+	notificationMap, _, _ := mgr.GetMap(spec.NotificationsMap)
+	numCPUs := int(notificationMap.ABI().MaxEntries)
+
+	return &Monitor{
+		spec:                   spec,
+		manager:                mgr,
+		batchManager:           NewBatchManager(spec.Batches, batchMap, batchStateMap, scratchMap, numCPUs),
+		batchCompletionHandler: batchCompletionHandler,
+		consumer:               consumer,
+		pollRequests:           make(chan func()),
+		closeFilterFn:          closeFilterFn,
+	}, nil
+}
+
+// Start consuming the segments
+func (m *Monitor) Start() error {
+	if err := m.manager.Start(); err != nil {
+		return err
+	}
+
+	m.eventLoopWG.Add(1)
+	go func() {
+		defer m.eventLoopWG.Done()
+		report := time.NewTicker(30 * time.Second)
+		defer report.Stop()
+		for {
+			select {
+			case dataEvent, ok := <-m.batchCompletionHandler.DataChannel:
+				if !ok {
+					return
+				}
+
+				// The notification we read from the perf ring tells us which batch of segments is ready to be consumed
+				segments, err := m.batchManager.GetSegmentsFrom(dataEvent.Data)
+				if err == ErrLostBatch {
+					m.consumer.SegmentsLost(m.spec.Batches.Size)
+				}
+				m.consumer.Process(segments)
+			case _, ok := <-m.batchCompletionHandler.LostChannel:
+				if !ok {
+					return
+				}
+
+				m.consumer.SegmentsLost(m.spec.Batches.Size)
+			case f, ok := <-m.pollRequests:
+				if !ok {
+					return
+				}
+
+				m.flush()
+				f()
+			case <-report.C:
+				m.flush()
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Do decodes the segments captured so far, then calls f from the goroutine consuming the
+// segments, which can then read the state of the Consumer. It returns false if the
+// Monitor is stopped.
+func (m *Monitor) Do(f func()) bool {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if m.stopped {
+		return false
+	}
+
+	done := make(chan struct{})
+	m.pollRequests <- func() {
+		f()
+		close(done)
+	}
+	<-done
+	return true
+}
+
+// Stop the monitoring
+func (m *Monitor) Stop() {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if m.stopped {
+		return
+	}
+
+	m.manager.Stop(manager.CleanAll)
+	m.batchCompletionHandler.Stop()
+	m.closeFilterFn()
+	close(m.pollRequests)
+	m.eventLoopWG.Wait()
+	m.stopped = true
+}
+
+// flush decodes the segments captured so far. Segments are read from per-CPU batches, so
+// the segments of a connection are decoded once all the segments captured before them on
+// the other CPUs are read.
+func (m *Monitor) flush() {
+	// every segment captured before now is either read already, or pending in its batch
+	now, err := ddebpf.NowNanoseconds()
+	if err != nil {
+		return
+	}
+
+	m.consumer.Process(m.batchManager.GetPendingSegments())
+	m.consumer.Flush(uint64(now))
+}
@@ -0,0 +1,77 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package segments
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// Telemetry counts the requests decoded from the segments of a protocol. The counters are
+// updated atomically.
+type Telemetry struct {
+	then    int64
+	elapsed int64
+
+	Requests     int64
+	Errors       int64 // requests which failed
+	SegmentsLost int64 // this happens when we can't cope with the rate of segments
+	ConnsLost    int64 // this happens when the segment starting a message is missed, until the decoding of the connection resumes
+	Unparsed     int64 // this happens when a message is not captured enough to be decoded, or reported
+	Dropped      int64 // this happens when the stats reach capacity
+	Aggregations int64
+}
+
+// NewTelemetry returns a new Telemetry
+func NewTelemetry() *Telemetry {
+	return &Telemetry{
+		then: time.Now().Unix(),
+	}
+}
+
+// Reset returns the counters since the previous Reset, and resets them
+func (t *Telemetry) Reset() Telemetry {
+	now := time.Now()
+	then := atomic.SwapInt64(&t.then, now.Unix())
+
+	return Telemetry{
+		Requests:     atomic.SwapInt64(&t.Requests, 0),
+		Errors:       atomic.SwapInt64(&t.Errors, 0),
+		SegmentsLost: atomic.SwapInt64(&t.SegmentsLost, 0),
+		ConnsLost:    atomic.SwapInt64(&t.ConnsLost, 0),
+		Unparsed:     atomic.SwapInt64(&t.Unparsed, 0),
+		Dropped:      atomic.SwapInt64(&t.Dropped, 0),
+		Aggregations: atomic.SwapInt64(&t.Aggregations, 0),
+		elapsed:      now.Unix() - then,
+	}
+}
+
+// Report logs the counters of a Reset, for the protocol name
+func (t *Telemetry) Report(name string) {
+	log.Debugf(
+		"%s stats summary: requests_processed=%d(%.2f/s) requests_failed=%d(%.2f/s) requests_dropped=%d(%.2f/s) messages_unparsed=%d segments_lost=%d connections_lost=%d aggregations=%d",
+		name,
+		t.Requests,
+		float64(t.Requests)/float64(t.elapsed),
+		t.Errors,
+		float64(t.Errors)/float64(t.elapsed),
+		t.Dropped,
+		float64(t.Dropped)/float64(t.elapsed),
+		t.Unparsed,
+		t.SegmentsLost,
+		t.ConnsLost,
+		t.Aggregations,
+	)
+}
+
+// Collect adds the counters of d to the telemetry, and resets them.
+func (t *Telemetry) Collect(d *Decoder) {
+	atomic.AddInt64(&t.ConnsLost, d.Lost)
+	atomic.AddInt64(&t.SegmentsLost, d.Dropped)
+	d.Lost, d.Dropped = 0, 0
+}
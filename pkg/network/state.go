@@ -9,8 +9,10 @@ import (
 	"sync"
 	"time"
 
+	"github.com/DataDog/datadog-agent/pkg/network/database"
 	"github.com/DataDog/datadog-agent/pkg/network/dns"
 	"github.com/DataDog/datadog-agent/pkg/network/http"
+	"github.com/DataDog/datadog-agent/pkg/network/kafka"
 	"github.com/DataDog/datadog-agent/pkg/process/util"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 	"go4.org/intern"
@@ -58,6 +60,12 @@ type State interface {
 	// StoreClosedConnections stores a batch of closed connections
 	StoreClosedConnections(connections []ConnectionStats)
 
+	// StoreKafkaStats stores the latest Kafka stats, returned in the next Delta of each client
+	StoreKafkaStats(stats map[kafka.Key]kafka.RequestStats)
+
+	// StoreDatabaseStats stores the latest database query stats, returned in the next Delta of each client
+	StoreDatabaseStats(stats map[database.Key]database.RequestStats)
+
 	// GetStats returns a map of statistics about the current network state
 	GetStats() map[string]interface{}
 
@@ -69,6 +77,8 @@ type State interface {
 type Delta struct {
 	BufferedData
 	HTTP     map[http.Key]http.RequestStats
+	Kafka    map[kafka.Key]kafka.RequestStats
+	Database map[database.Key]database.RequestStats
 	DNSStats dns.StatsByKeyByNameByType
 }
 
@@ -79,6 +89,8 @@ type telemetry struct {
 	timeSyncCollisions int64
 	dnsStatsDropped    int64
 	httpStatsDropped   int64
+	kafkaStatsDropped  int64
+	dbStatsDropped     int64
 	dnsPidCollisions   int64
 }
 
@@ -90,6 +102,7 @@ type stats struct {
 	totalRetransmits    uint32
 	totalTCPEstablished uint32
 	totalTCPClosed      uint32
+	totalTCPHealth      TCPHealthStats
 }
 
 const minClosedCapacity = 1024
@@ -101,8 +114,10 @@ type client struct {
 	closedConnections     []ConnectionStats
 	stats                 map[string]*stats
 	// maps by dns key the domain (string) to stats structure
-	dnsStats       dns.StatsByKeyByNameByType
-	httpStatsDelta map[http.Key]http.RequestStats
+	dnsStats        dns.StatsByKeyByNameByType
+	httpStatsDelta  map[http.Key]http.RequestStats
+	kafkaStatsDelta map[kafka.Key]kafka.RequestStats
+	dbStatsDelta    map[database.Key]database.RequestStats
 }
 
 func (c *client) Reset(active map[string]*ConnectionStats) {
@@ -115,6 +130,8 @@ func (c *client) Reset(active map[string]*ConnectionStats) {
 	c.closedConnectionsKeys = make(map[string]int)
 	c.dnsStats = make(dns.StatsByKeyByNameByType)
 	c.httpStatsDelta = make(map[http.Key]http.RequestStats)
+	c.kafkaStatsDelta = make(map[kafka.Key]kafka.RequestStats)
+	c.dbStatsDelta = make(map[database.Key]database.RequestStats)
 
 	// XXX: we should change the way we clean this map once
 	// https://github.com/golang/go/issues/20135 is solved
@@ -206,6 +223,7 @@ func (ns *networkState) GetDelta(
 			c.LastRetransmits = 0
 			c.LastTCPEstablished = 0
 			c.LastTCPClosed = 0
+			c.LastTCPHealth = TCPHealthStats{}
 		}
 		clientBuffer.Append(active)
 	} else {
@@ -228,6 +246,8 @@ func (ns *networkState) GetDelta(
 			buffer: clientBuffer,
 		},
 		HTTP:     client.httpStatsDelta,
+		Kafka:    client.kafkaStatsDelta,
+		Database: client.dbStatsDelta,
 		DNSStats: client.dnsStats,
 	}
 }
@@ -327,6 +347,8 @@ func (ns *networkState) storeDNSStats(stats dns.StatsByKeyByNameByType) {
 						prev.Timeouts += dnsStats.Timeouts
 						prev.SuccessLatencySum += dnsStats.SuccessLatencySum
 						prev.FailureLatencySum += dnsStats.FailureLatencySum
+						prev.SuccessLatencies.Merge(dnsStats.SuccessLatencies)
+						prev.FailureLatencies.Merge(dnsStats.FailureLatencies)
 						for rcode, count := range dnsStats.CountByRcode {
 							prev.CountByRcode[rcode] += count
 						}
@@ -361,6 +383,46 @@ func (ns *networkState) storeHTTPStats(allStats map[http.Key]http.RequestStats)
 	}
 }
 
+// StoreKafkaStats stores latest Kafka stats for all clients.
+// The number of Kafka stats per client is bounded like the number of HTTP stats.
+func (ns *networkState) StoreKafkaStats(allStats map[kafka.Key]kafka.RequestStats) {
+	ns.Lock()
+	defer ns.Unlock()
+
+	for key, stats := range allStats {
+		for _, client := range ns.clients {
+			prevStats, ok := client.kafkaStatsDelta[key]
+			if !ok && len(client.kafkaStatsDelta) >= ns.maxHTTPStats {
+				ns.telemetry.kafkaStatsDropped++
+				continue
+			}
+
+			prevStats.CombineWith(stats)
+			client.kafkaStatsDelta[key] = prevStats
+		}
+	}
+}
+
+// StoreDatabaseStats stores latest database query stats for all clients.
+// The number of database query stats per client is bounded like the number of HTTP stats.
+func (ns *networkState) StoreDatabaseStats(allStats map[database.Key]database.RequestStats) {
+	ns.Lock()
+	defer ns.Unlock()
+
+	for key, stats := range allStats {
+		for _, client := range ns.clients {
+			prevStats, ok := client.dbStatsDelta[key]
+			if !ok && len(client.dbStatsDelta) >= ns.maxHTTPStats {
+				ns.telemetry.dbStatsDropped++
+				continue
+			}
+
+			prevStats.CombineWith(stats)
+			client.dbStatsDelta[key] = prevStats
+		}
+	}
+}
+
 func (ns *networkState) getClient(clientID string) (*client, bool) {
 	if c, ok := ns.clients[clientID]; ok {
 		return c, true
@@ -372,6 +434,8 @@ func (ns *networkState) getClient(clientID string) (*client, bool) {
 		closedConnections: make([]ConnectionStats, 0, minClosedCapacity),
 		dnsStats:          dns.StatsByKeyByNameByType{},
 		httpStatsDelta:    map[http.Key]http.RequestStats{},
+		kafkaStatsDelta:   map[kafka.Key]kafka.RequestStats{},
+		dbStatsDelta:      map[database.Key]database.RequestStats{},
 	}
 	ns.clients[clientID] = c
 	return c, false
@@ -409,6 +473,7 @@ func (ns *networkState) mergeConnections(id string, active map[string]*Connectio
 				// The monotonic counters will be the sum of all connections that cross our interval start + finish.
 				if stats, ok := client.stats[key]; ok {
 					stats.totalRetransmits = activeConn.MonotonicRetransmits
+					stats.totalTCPHealth = activeConn.MonotonicTCPHealth
 					stats.totalSent = activeConn.MonotonicSentBytes
 					stats.totalRecv = activeConn.MonotonicRecvBytes
 				}
@@ -456,6 +521,7 @@ func (ns *networkState) updateConnWithStatWithActiveConn(client *client, key str
 		closed.LastRetransmits = closed.MonotonicRetransmits - st.totalRetransmits
 		closed.LastTCPEstablished = closed.LastTCPEstablished - st.totalTCPEstablished
 		closed.LastTCPClosed = closed.LastTCPClosed - st.totalTCPClosed
+		closed.LastTCPHealth = closed.MonotonicTCPHealth.Sub(st.totalTCPHealth)
 
 		// Update stats object with latest values
 		st.totalSent = active.MonotonicSentBytes
@@ -465,6 +531,7 @@ func (ns *networkState) updateConnWithStatWithActiveConn(client *client, key str
 		st.totalRetransmits = active.MonotonicRetransmits
 		st.totalTCPEstablished = active.MonotonicTCPEstablished
 		st.totalTCPClosed = active.MonotonicTCPClosed
+		st.totalTCPHealth = active.MonotonicTCPHealth
 	} else {
 		closed.LastSentBytes = closed.MonotonicSentBytes
 		closed.LastRecvBytes = closed.MonotonicRecvBytes
@@ -474,6 +541,7 @@ func (ns *networkState) updateConnWithStatWithActiveConn(client *client, key str
 		closed.LastRetransmits = closed.MonotonicRetransmits
 		closed.LastTCPEstablished = closed.MonotonicTCPEstablished
 		closed.LastTCPClosed = closed.MonotonicTCPClosed
+		closed.LastTCPHealth = closed.MonotonicTCPHealth
 	}
 }
 
@@ -489,6 +557,7 @@ func (ns *networkState) updateConnWithStats(client *client, key string, c *Conne
 		c.LastRetransmits = c.MonotonicRetransmits - st.totalRetransmits
 		c.LastTCPEstablished = c.MonotonicTCPEstablished - st.totalTCPEstablished
 		c.LastTCPClosed = c.MonotonicTCPClosed - st.totalTCPClosed
+		c.LastTCPHealth = c.MonotonicTCPHealth.Sub(st.totalTCPHealth)
 
 		// Update stats object with latest values
 		st.totalSent = c.MonotonicSentBytes
@@ -498,6 +567,7 @@ func (ns *networkState) updateConnWithStats(client *client, key string, c *Conne
 		st.totalRetransmits = c.MonotonicRetransmits
 		st.totalTCPEstablished = c.MonotonicTCPEstablished
 		st.totalTCPClosed = c.MonotonicTCPClosed
+		st.totalTCPHealth = c.MonotonicTCPHealth
 	} else {
 		c.LastSentBytes = c.MonotonicSentBytes
 		c.LastRecvBytes = c.MonotonicRecvBytes
@@ -506,6 +576,7 @@ func (ns *networkState) updateConnWithStats(client *client, key string, c *Conne
 		c.LastRetransmits = c.MonotonicRetransmits
 		c.LastTCPEstablished = c.MonotonicTCPEstablished
 		c.LastTCPClosed = c.MonotonicTCPClosed
+		c.LastTCPHealth = c.MonotonicTCPHealth
 	}
 }
 
@@ -517,7 +588,11 @@ func (ns *networkState) handleStatsUnderflow(key string, st *stats, c *Connectio
 		st.totalSent = 0
 		st.totalRecv = 0
 		st.totalRetransmits = 0
+		st.totalTCPHealth = TCPHealthStats{}
 	}
+
+	// the TCP health counters are checked separately, since each of them may wrap independently
+	st.totalTCPHealth = st.totalTCPHealth.resetWrapped(c.MonotonicTCPHealth)
 }
 
 // createStatsForKey will create a new stats object for a key if it doesn't already exist.
@@ -569,6 +644,8 @@ func (ns *networkState) RemoveConnections(keys []string) {
 		s += " [%d closed connections dropped]"
 		s += " [%d dns stats dropped]"
 		s += " [%d HTTP stats dropped]"
+		s += " [%d Kafka stats dropped]"
+		s += " [%d database stats dropped]"
 		s += " [%d DNS pid collisions]"
 		s += " [%d time sync collisions]"
 		log.Warnf(s,
@@ -577,6 +654,8 @@ func (ns *networkState) RemoveConnections(keys []string) {
 			ns.telemetry.closedConnDropped,
 			ns.telemetry.dnsStatsDropped,
 			ns.telemetry.httpStatsDropped,
+			ns.telemetry.kafkaStatsDropped,
+			ns.telemetry.dbStatsDropped,
 			ns.telemetry.dnsPidCollisions,
 			ns.telemetry.timeSyncCollisions)
 	}
@@ -607,6 +686,8 @@ func (ns *networkState) GetStats() map[string]interface{} {
 			"time_sync_collisions": ns.telemetry.timeSyncCollisions,
 			"dns_stats_dropped":    ns.telemetry.dnsStatsDropped,
 			"http_stats_dropped":   ns.telemetry.httpStatsDropped,
+			"kafka_stats_dropped":  ns.telemetry.kafkaStatsDropped,
+			"db_stats_dropped":     ns.telemetry.dbStatsDropped,
 			"dns_pid_collisions":   ns.telemetry.dnsPidCollisions,
 		},
 		"current_time":       time.Now().Unix(),
@@ -623,11 +704,16 @@ func (ns *networkState) DumpState(clientID string) map[string]interface{} {
 	if client, ok := ns.clients[clientID]; ok {
 		for connKey, s := range client.stats {
 			data[BeautifyKey(connKey)] = map[string]uint64{
-				"total_sent":            s.totalSent,
-				"total_recv":            s.totalRecv,
-				"total_retransmits":     uint64(s.totalRetransmits),
-				"total_tcp_established": uint64(s.totalTCPEstablished),
-				"total_tcp_closed":      uint64(s.totalTCPClosed),
+				"total_sent":               s.totalSent,
+				"total_recv":               s.totalRecv,
+				"total_retransmits":        uint64(s.totalRetransmits),
+				"total_tcp_established":    uint64(s.totalTCPEstablished),
+				"total_tcp_closed":         uint64(s.totalTCPClosed),
+				"total_rst_sent":           uint64(s.totalTCPHealth.RSTSent),
+				"total_rst_received":       uint64(s.totalTCPHealth.RSTReceived),
+				"total_zero_window_probes": uint64(s.totalTCPHealth.ZeroWindowProbes),
+				"total_syn_retransmits":    uint64(s.totalTCPHealth.SYNRetransmits),
+				"total_out_of_order":       uint64(s.totalTCPHealth.OutOfOrder),
 			}
 		}
 	}
@@ -698,6 +784,7 @@ func addConnections(a, b *ConnectionStats) {
 	a.MonotonicRetransmits += b.MonotonicRetransmits
 	a.MonotonicTCPEstablished += b.MonotonicTCPEstablished
 	a.MonotonicTCPClosed += b.MonotonicTCPClosed
+	a.MonotonicTCPHealth = a.MonotonicTCPHealth.Add(b.MonotonicTCPHealth)
 
 	if b.LastUpdateEpoch > a.LastUpdateEpoch {
 		a.LastUpdateEpoch = b.LastUpdateEpoch
@@ -15,8 +15,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/DataDog/datadog-agent/pkg/network/database"
 	"github.com/DataDog/datadog-agent/pkg/network/dns"
 	"github.com/DataDog/datadog-agent/pkg/network/http"
+	"github.com/DataDog/datadog-agent/pkg/network/kafka"
 	"github.com/DataDog/datadog-agent/pkg/process/util"
 	"go4.org/intern"
 
@@ -294,6 +296,61 @@ func TestLastStats(t *testing.T) {
 	assert.Equal(t, conn3.MonotonicRetransmits, conns[0].MonotonicRetransmits)
 }
 
+func TestLastTCPHealthStats(t *testing.T) {
+	client1 := "1"
+	client2 := "2"
+	state := newDefaultState()
+
+	conn := ConnectionStats{
+		Pid:                123,
+		Type:               TCP,
+		Family:             AFINET,
+		Source:             util.AddressFromString("127.0.0.1"),
+		Dest:               util.AddressFromString("127.0.0.1"),
+		SPort:              31890,
+		DPort:              80,
+		MonotonicTCPHealth: TCPHealthStats{RSTSent: 1, SYNRetransmits: 2},
+	}
+	delta := TCPHealthStats{RSTReceived: 1, ZeroWindowProbes: 3, OutOfOrder: 5}
+
+	conn2 := conn
+	conn2.MonotonicTCPHealth = conn.MonotonicTCPHealth.Add(delta)
+
+	// Register the clients
+	assert.Len(t, state.GetDelta(client1, latestEpochTime(), nil, nil, nil).Conns, 0)
+	assert.Len(t, state.GetDelta(client2, latestEpochTime(), nil, nil, nil).Conns, 0)
+
+	conns := state.GetDelta(client1, latestEpochTime(), []ConnectionStats{conn}, nil, nil).Conns
+	require.Len(t, conns, 1)
+	assert.Equal(t, conn.MonotonicTCPHealth, conns[0].LastTCPHealth)
+
+	// client 1 should have conn2 - conn1
+	conns = state.GetDelta(client1, latestEpochTime(), []ConnectionStats{conn2}, nil, nil).Conns
+	require.Len(t, conns, 1)
+	assert.Equal(t, delta, conns[0].LastTCPHealth)
+	assert.Equal(t, conn2.MonotonicTCPHealth, conns[0].MonotonicTCPHealth)
+
+	// client 2 didn't collect the first connection so last stats = monotonic
+	conns = state.GetDelta(client2, latestEpochTime(), []ConnectionStats{conn2}, nil, nil).Conns
+	require.Len(t, conns, 1)
+	assert.Equal(t, conn2.MonotonicTCPHealth, conns[0].LastTCPHealth)
+
+	// the counters which wrapped are reset separately, without resetting the other stats
+	conn3 := conn2
+	conn3.MonotonicSentBytes = 10
+	conns = state.GetDelta(client1, latestEpochTime(), []ConnectionStats{conn3}, nil, nil).Conns
+	require.Len(t, conns, 1)
+	conn4 := conn3
+	conn4.MonotonicSentBytes = 15
+	conn4.MonotonicTCPHealth.RSTReceived = 0
+	conn4.MonotonicTCPHealth.OutOfOrder = 2
+	conn4.MonotonicTCPHealth.ZeroWindowProbes++
+	conns = state.GetDelta(client1, latestEpochTime(), []ConnectionStats{conn4}, nil, nil).Conns
+	require.Len(t, conns, 1)
+	assert.Equal(t, TCPHealthStats{ZeroWindowProbes: 1, OutOfOrder: 2}, conns[0].LastTCPHealth)
+	assert.Equal(t, uint64(5), conns[0].LastSentBytes)
+}
+
 func TestLastStatsForClosedConnection(t *testing.T) {
 	clientID := "1"
 	state := newDefaultState()
@@ -1206,6 +1263,71 @@ func TestHTTPStats(t *testing.T) {
 	assert.Len(t, delta.HTTP, 0)
 }
 
+func TestKafkaStats(t *testing.T) {
+	c := ConnectionStats{
+		Source: util.AddressFromString("1.1.1.1"),
+		Dest:   util.AddressFromString("0.0.0.0"),
+		SPort:  1000,
+		DPort:  9092,
+	}
+
+	key := kafka.NewKey(c.Source, c.Dest, c.SPort, c.DPort, "orders", kafka.APIProduce)
+
+	var rs kafka.RequestStats
+	rs.AddRequest(0, 1000)
+
+	// Register both clients
+	state := newDefaultState()
+	state.GetDelta("client1", latestEpochTime(), []ConnectionStats{c}, nil, nil)
+	state.GetDelta("client2", latestEpochTime(), []ConnectionStats{c}, nil, nil)
+
+	state.StoreKafkaStats(map[kafka.Key]kafka.RequestStats{key: rs})
+	state.StoreKafkaStats(map[kafka.Key]kafka.RequestStats{key: rs})
+
+	// Verify both clients get the Kafka data, combined
+	delta := state.GetDelta("client1", latestEpochTime(), []ConnectionStats{c}, nil, nil)
+	require.Len(t, delta.Kafka, 1)
+	assert.Equal(t, 2, delta.Kafka[key].Count)
+	assert.Len(t, state.GetDelta("client2", latestEpochTime(), []ConnectionStats{c}, nil, nil).Kafka, 1)
+
+	// Verify Kafka data has been flushed
+	delta = state.GetDelta("client1", latestEpochTime(), []ConnectionStats{c}, nil, nil)
+	assert.Len(t, delta.Kafka, 0)
+}
+
+func TestDatabaseStats(t *testing.T) {
+	c := ConnectionStats{
+		Source: util.AddressFromString("1.1.1.1"),
+		Dest:   util.AddressFromString("0.0.0.0"),
+		SPort:  1000,
+		DPort:  5432,
+	}
+
+	key := database.NewKey(c.Source, c.Dest, c.SPort, c.DPort, database.ProtocolPostgres, "SELECT * FROM users WHERE id = ?")
+
+	var rs database.RequestStats
+	rs.AddRequest(true, 1000)
+
+	// Register both clients
+	state := newDefaultState()
+	state.GetDelta("client1", latestEpochTime(), []ConnectionStats{c}, nil, nil)
+	state.GetDelta("client2", latestEpochTime(), []ConnectionStats{c}, nil, nil)
+
+	state.StoreDatabaseStats(map[database.Key]database.RequestStats{key: rs})
+	state.StoreDatabaseStats(map[database.Key]database.RequestStats{key: rs})
+
+	// Verify both clients get the database data, combined
+	delta := state.GetDelta("client1", latestEpochTime(), []ConnectionStats{c}, nil, nil)
+	require.Len(t, delta.Database, 1)
+	assert.Equal(t, 2, delta.Database[key].Count)
+	assert.Equal(t, 2, delta.Database[key].ErrorCount)
+	assert.Len(t, state.GetDelta("client2", latestEpochTime(), []ConnectionStats{c}, nil, nil).Database, 1)
+
+	// Verify database data has been flushed
+	delta = state.GetDelta("client1", latestEpochTime(), []ConnectionStats{c}, nil, nil)
+	assert.Len(t, delta.Database, 0)
+}
+
 func TestHTTPStatsWithMultipleClients(t *testing.T) {
 	c := ConnectionStats{
 		Source: util.AddressFromString("1.1.1.1"),
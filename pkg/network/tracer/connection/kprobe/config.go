@@ -15,6 +15,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/network/config"
 	"github.com/DataDog/datadog-agent/pkg/network/ebpf/probes"
 	"github.com/DataDog/datadog-agent/pkg/util/kernel"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
 // enabledProbes returns a map of probes that are enabled per config settings.
@@ -54,6 +55,12 @@ func enabledProbes(c *config.Config, runtimeTracer bool) (map[probes.ProbeName]s
 			enabled[probes.DoSendfile] = struct{}{}
 			enabled[probes.DoSendfileRet] = struct{}{}
 		}
+
+		// the functions counting the TCP health stats may be static, and thus inlined, in which
+		// case they cannot be probed and the matching stats are not collected
+		for _, probe := range tcpHealthProbes(c) {
+			enabled[probe] = struct{}{}
+		}
 	}
 
 	if c.CollectUDPConns {
@@ -84,3 +91,38 @@ func enabledProbes(c *config.Config, runtimeTracer bool) (map[probes.ProbeName]s
 
 	return enabled, nil
 }
+
+// tcpHealthFuncs maps the probes counting the TCP health stats to the function they trace
+var tcpHealthFuncs = map[probes.ProbeName]string{
+	probes.TCPSendActiveReset: "tcp_send_active_reset",
+	probes.TCPReset:           "tcp_reset",
+	probes.TCPSendProbe0:      "tcp_send_probe0",
+	probes.TCPDataQueueOFO:    "tcp_data_queue_ofo",
+}
+
+// tcpHealthProbes returns the probes counting the TCP health stats whose function can be traced
+func tcpHealthProbes(c *config.Config) []probes.ProbeName {
+	funcs := make([]string, 0, len(tcpHealthFuncs))
+	for _, fn := range tcpHealthFuncs {
+		funcs = append(funcs, fn)
+	}
+	missing, err := ebpf.VerifyKernelFuncs(filepath.Join(c.ProcRoot, "kallsyms"), funcs)
+	if err != nil {
+		log.Warnf("could not verify the functions counting the TCP health stats: %s", err)
+		return nil
+	}
+
+	missingFuncs := make(map[string]struct{}, len(missing))
+	for _, fn := range missing {
+		missingFuncs[fn] = struct{}{}
+	}
+	var enabled []probes.ProbeName
+	for probe, fn := range tcpHealthFuncs {
+		if _, ok := missingFuncs[fn]; ok {
+			log.Debugf("%s cannot be traced, the matching TCP health stats are not collected", fn)
+			continue
+		}
+		enabled = append(enabled, probe)
+	}
+	return enabled
+}
@@ -177,6 +177,15 @@ func dumpMapsHandler(managerMap *manager.Map, manager *manager.Manager) string {
 			output.WriteString(spew.Sdump(key, value))
 		}
 
+	case string(probes.TcpHealthStatsMap): // maps/tcp_health_stats (BPF_MAP_TYPE_HASH), key ConnTuple, value TCPHealthStats
+		output.WriteString("Map: '" + mapName + "', key: 'ConnTuple', value: 'TCPHealthStats'\n")
+		iter := currentMap.Iterate()
+		var key ebpf.ConnTuple
+		var value ebpf.TCPHealthStats
+		for iter.Next(unsafe.Pointer(&key), unsafe.Pointer(&value)) {
+			output.WriteString(spew.Sdump(key, value))
+		}
+
 	}
 
 	return output.String()
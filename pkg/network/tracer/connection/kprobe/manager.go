@@ -37,6 +37,7 @@ func newManager(closedHandler *ebpf.PerfHandler, runtimeTracer bool) *manager.Ma
 			{Name: string(probes.PidFDBySockMap)},
 			{Name: string(probes.SockFDLookupArgsMap)},
 			{Name: string(probes.DoSendfileArgsMap)},
+			{Name: string(probes.TcpHealthStatsMap)},
 		},
 		PerfMaps: []*manager.PerfMap{
 			{
@@ -60,6 +61,10 @@ func newManager(closedHandler *ebpf.PerfHandler, runtimeTracer bool) *manager.Ma
 			{Section: string(probes.UDPRecvMsg)},
 			{Section: string(probes.UDPRecvMsgReturn), KProbeMaxActive: maxActive},
 			{Section: string(probes.TCPRetransmit)},
+			{Section: string(probes.TCPSendActiveReset)},
+			{Section: string(probes.TCPReset)},
+			{Section: string(probes.TCPSendProbe0)},
+			{Section: string(probes.TCPDataQueueOFO)},
 			{Section: string(probes.InetCskAcceptReturn), KProbeMaxActive: maxActive},
 			{Section: string(probes.InetCskListenStop)},
 			{Section: string(probes.UDPDestroySock)},
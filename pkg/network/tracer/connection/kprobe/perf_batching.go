@@ -29,7 +29,8 @@ const defaultExpiredStateInterval = 60 * time.Second
 // event remains stored in the eBPF map before being processed by the NetworkAgent.
 type perfBatchManager struct {
 	// eBPF
-	batchMap *ebpf.Map
+	batchMap  *ebpf.Map
+	healthMap *ebpf.Map
 
 	// stateByCPU contains the state of each batch.
 	// The slice is indexed by the CPU core number.
@@ -39,8 +40,9 @@ type perfBatchManager struct {
 }
 
 // newPerfBatchManager returns a new `perfBatchManager` and initializes the
-// eBPF map that holds the tcp_close batch objects.
-func newPerfBatchManager(batchMap *ebpf.Map, numCPUs int) (*perfBatchManager, error) {
+// eBPF map that holds the tcp_close batch objects. The TCP health stats of the
+// closed connections are read, and deleted, from `healthMap`.
+func newPerfBatchManager(batchMap, healthMap *ebpf.Map, numCPUs int) (*perfBatchManager, error) {
 	if batchMap == nil {
 		return nil, fmt.Errorf("batchMap is nil")
 	}
@@ -58,6 +60,7 @@ func newPerfBatchManager(batchMap *ebpf.Map, numCPUs int) (*perfBatchManager, er
 
 	return &perfBatchManager{
 		batchMap:             batchMap,
+		healthMap:            healthMap,
 		stateByCPU:           state,
 		expiredStateInterval: defaultExpiredStateInterval,
 	}, nil
@@ -152,6 +155,9 @@ func (p *perfBatchManager) extractBatchInto(buffer *network.ConnectionBuffer, b
 		conn := buffer.Next()
 		populateConnStats(conn, &ct.Tup, &ct.Conn_stats)
 		updateTCPStats(conn, &ct.Tcp_stats)
+		if p.healthMap != nil {
+			getTCPHealthStats(p.healthMap, conn, &ct.Tup, true)
+		}
 	}
 }
 
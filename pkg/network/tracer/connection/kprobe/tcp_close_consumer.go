@@ -46,8 +46,13 @@ func newTCPCloseConsumer(m *manager.Manager, perfHandler *ddebpf.PerfHandler) (*
 		return nil, err
 	}
 
+	tcpHealthStatsMap, _, err := m.GetMap(string(probes.TcpHealthStatsMap))
+	if err != nil {
+		return nil, err
+	}
+
 	numCPUs := int(connCloseEventMap.ABI().MaxEntries)
-	batchManager, err := newPerfBatchManager(connCloseMap, numCPUs)
+	batchManager, err := newPerfBatchManager(connCloseMap, tcpHealthStatsMap, numCPUs)
 	if err != nil {
 		return nil, err
 	}
@@ -36,9 +36,10 @@ const (
 type kprobeTracer struct {
 	m *manager.Manager
 
-	conns    *ebpf.Map
-	tcpStats *ebpf.Map
-	config   *config.Config
+	conns          *ebpf.Map
+	tcpStats       *ebpf.Map
+	tcpHealthStats *ebpf.Map
+	config         *config.Config
 
 	// tcp_close events
 	closeConsumer *tcpCloseConsumer
@@ -62,6 +63,7 @@ func New(config *config.Config, constants []manager.ConstantEditor) (connection.
 		MapSpecEditors: map[string]manager.MapSpecEditor{
 			string(probes.ConnMap):            {Type: ebpf.Hash, MaxEntries: uint32(config.MaxTrackedConnections), EditorFlag: manager.EditMaxEntries},
 			string(probes.TcpStatsMap):        {Type: ebpf.Hash, MaxEntries: uint32(config.MaxTrackedConnections), EditorFlag: manager.EditMaxEntries},
+			string(probes.TcpHealthStatsMap):  {Type: ebpf.Hash, MaxEntries: uint32(config.MaxTrackedConnections), EditorFlag: manager.EditMaxEntries},
 			string(probes.PortBindingsMap):    {Type: ebpf.Hash, MaxEntries: uint32(config.MaxTrackedConnections), EditorFlag: manager.EditMaxEntries},
 			string(probes.UdpPortBindingsMap): {Type: ebpf.Hash, MaxEntries: uint32(config.MaxTrackedConnections), EditorFlag: manager.EditMaxEntries},
 			string(probes.SockByPidFDMap):     {Type: ebpf.Hash, MaxEntries: uint32(config.MaxTrackedConnections), EditorFlag: manager.EditMaxEntries},
@@ -152,6 +154,12 @@ func New(config *config.Config, constants []manager.ConstantEditor) (connection.
 		return nil, fmt.Errorf("error retrieving the bpf %s map: %s", probes.TcpStatsMap, err)
 	}
 
+	tr.tcpHealthStats, _, err = m.GetMap(string(probes.TcpHealthStatsMap))
+	if err != nil {
+		tr.Stop()
+		return nil, fmt.Errorf("error retrieving the bpf %s map: %s", probes.TcpHealthStatsMap, err)
+	}
+
 	return tr, nil
 }
 
@@ -211,6 +219,7 @@ func (t *kprobeTracer) GetConnections(buffer *network.ConnectionBuffer, filter f
 		}
 		if t.getTCPStats(tcp, key, seen) {
 			updateTCPStats(conn, tcp)
+			getTCPHealthStats(t.tcpHealthStats, conn, key, false)
 		}
 		*buffer.Next() = *conn
 	}
@@ -255,6 +264,7 @@ func (t *kprobeTracer) Remove(conn *network.ConnectionStats) error {
 	t.removeTuple.Pid = 0
 	// We can ignore the error for this map since it will not always contain the entry
 	_ = t.tcpStats.Delete(unsafe.Pointer(t.removeTuple))
+	_ = t.tcpHealthStats.Delete(unsafe.Pointer(t.removeTuple))
 
 	return nil
 }
@@ -373,6 +383,35 @@ func (t *kprobeTracer) getTCPStats(stats *netebpf.TCPStats, tuple *netebpf.ConnT
 	return true
 }
 
+// getTCPHealthStats reads the TCP health stats of the given ConnTuple into conn. The eBPF side
+// doesn't delete them when the connection is closed, since tcp_close may still send RSTs, so the
+// entry of a closed connection is deleted once read.
+func getTCPHealthStats(healthMap *ebpf.Map, conn *network.ConnectionStats, tuple *netebpf.ConnTuple, closed bool) {
+	if tuple.Type() != netebpf.TCP {
+		return
+	}
+
+	// The PID isn't used as a key in the health stats map, we will temporarily set it to 0 here and reset it when we're done
+	pid := tuple.Pid
+	tuple.Pid = 0
+
+	stats := new(netebpf.TCPHealthStats)
+	if err := healthMap.Lookup(unsafe.Pointer(tuple), unsafe.Pointer(stats)); err == nil {
+		conn.MonotonicTCPHealth = network.TCPHealthStats{
+			RSTSent:          stats.Rst_sent,
+			RSTReceived:      stats.Rst_received,
+			ZeroWindowProbes: stats.Zero_window_probes,
+			SYNRetransmits:   stats.Syn_retransmits,
+			OutOfOrder:       stats.Out_of_order,
+		}
+		if closed {
+			_ = healthMap.Delete(unsafe.Pointer(tuple))
+		}
+	}
+
+	tuple.Pid = pid
+}
+
 func populateConnStats(stats *network.ConnectionStats, t *netebpf.ConnTuple, s *netebpf.ConnStats) {
 	*stats = network.ConnectionStats{
 		Pid:                  t.Pid,
@@ -0,0 +1,11 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package tracer
+
+import "errors"
+
+// ErrListenerInventoryDisabled is returned when the listeners are requested while the listener inventory is disabled
+var ErrListenerInventoryDisabled = errors.New("listener inventory disabled: set network_config.listeners.enabled to true")
@@ -23,10 +23,12 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/network"
 	"github.com/DataDog/datadog-agent/pkg/network/config"
 	"github.com/DataDog/datadog-agent/pkg/network/config/sysctl"
+	"github.com/DataDog/datadog-agent/pkg/network/database"
 	"github.com/DataDog/datadog-agent/pkg/network/dns"
 	netebpf "github.com/DataDog/datadog-agent/pkg/network/ebpf"
 	"github.com/DataDog/datadog-agent/pkg/network/ebpf/probes"
 	"github.com/DataDog/datadog-agent/pkg/network/http"
+	"github.com/DataDog/datadog-agent/pkg/network/kafka"
 	"github.com/DataDog/datadog-agent/pkg/network/netlink"
 	"github.com/DataDog/datadog-agent/pkg/network/tracer/connection"
 	"github.com/DataDog/datadog-agent/pkg/network/tracer/connection/kprobe"
@@ -42,12 +44,14 @@ import (
 const defaultUDPConnTimeoutNanoSeconds = uint64(time.Duration(120) * time.Second)
 
 type Tracer struct {
-	config      *config.Config
-	state       network.State
-	conntracker netlink.Conntracker
-	reverseDNS  dns.ReverseDNS
-	httpMonitor *http.Monitor
-	ebpfTracer  connection.Tracer
+	config       *config.Config
+	state        network.State
+	conntracker  netlink.Conntracker
+	reverseDNS   dns.ReverseDNS
+	httpMonitor  *http.Monitor
+	kafkaMonitor *kafka.Monitor
+	dbMonitor    *database.Monitor
+	ebpfTracer   connection.Tracer
 
 	// Telemetry
 	skippedConns int64
@@ -79,6 +83,8 @@ type Tracer struct {
 
 	gwLookup *gatewayLookup
 
+	listeners *network.ListenerInventory
+
 	sysctlUDPConnTimeout       *sysctl.Int
 	sysctlUDPConnStreamTimeout *sysctl.Int
 }
@@ -147,6 +153,8 @@ func NewTracer(config *config.Config) (*Tracer, error) {
 		state:                      state,
 		reverseDNS:                 newReverseDNS(!pre410Kernel, config),
 		httpMonitor:                newHTTPMonitor(!pre410Kernel, config, ebpfTracer, constantEditors),
+		kafkaMonitor:               newKafkaMonitor(!pre410Kernel, config),
+		dbMonitor:                  newDatabaseMonitor(!pre410Kernel, config),
 		activeBuffer:               network.NewConnectionBuffer(512, 256),
 		conntracker:                conntracker,
 		sourceExcludes:             network.ParseConnectionFilters(config.ExcludedSourceConnections),
@@ -168,6 +176,11 @@ func NewTracer(config *config.Config) (*Tracer, error) {
 		return nil, fmt.Errorf("could not start reverse dns monitor: %w", err)
 	}
 
+	if config.EnableListenerInventory {
+		tr.listeners = network.NewListenerInventory(config)
+		tr.listeners.Start()
+	}
+
 	return tr, nil
 }
 
@@ -302,7 +315,28 @@ func (t *Tracer) Stop() {
 	t.reverseDNS.Close()
 	t.ebpfTracer.Stop()
 	t.httpMonitor.Stop()
+	t.kafkaMonitor.Stop()
+	t.dbMonitor.Stop()
 	t.conntracker.Close()
+	if t.listeners != nil {
+		t.listeners.Stop()
+	}
+}
+
+// GetListeners returns the listening sockets of the host, along with the process owning them
+func (t *Tracer) GetListeners() ([]network.Listener, error) {
+	if t.listeners == nil {
+		return nil, ErrListenerInventoryDisabled
+	}
+	return t.listeners.GetListeners(), nil
+}
+
+// GetListenerEvents returns the new listeners on a port which is not allowed, appeared since the last request of the client
+func (t *Tracer) GetListenerEvents(clientID string) ([]network.ListenerEvent, error) {
+	if t.listeners == nil {
+		return nil, ErrListenerInventoryDisabled
+	}
+	return t.listeners.GetEvents(clientID), nil
 }
 
 func (t *Tracer) GetActiveConnections(clientID string) (*network.Connections, error) {
@@ -317,6 +351,8 @@ func (t *Tracer) GetActiveConnections(clientID string) (*network.Connections, er
 	}
 	active := t.activeBuffer.Connections()
 
+	t.state.StoreKafkaStats(t.kafkaMonitor.GetKafkaStats())
+	t.state.StoreDatabaseStats(t.dbMonitor.GetDatabaseStats())
 	delta := t.state.GetDelta(clientID, latestTime, active, t.reverseDNS.GetDNSStats(), t.httpMonitor.GetHTTPStats())
 	t.activeBuffer.Reset()
 
@@ -328,6 +364,9 @@ func (t *Tracer) GetActiveConnections(clientID string) (*network.Connections, er
 	}
 	names := t.reverseDNS.Resolve(ips)
 	ctm := t.getConnTelemetry(len(active))
+	if t.config.CollectTCPConns {
+		addTCPHealthTelemetry(ctm, delta.Conns)
+	}
 	rctm := t.getRuntimeCompilationTelemetry()
 
 	return &network.Connections{
@@ -335,6 +374,8 @@ func (t *Tracer) GetActiveConnections(clientID string) (*network.Connections, er
 		DNS:                         names,
 		DNSStats:                    delta.DNSStats,
 		HTTP:                        delta.HTTP,
+		Kafka:                       delta.Kafka,
+		Database:                    delta.Database,
 		ConnTelemetry:               ctm,
 		CompilationTelemetryByAsset: rctm,
 	}, nil
@@ -389,6 +430,21 @@ func (t *Tracer) getConnTelemetry(mapSize int) map[network.ConnTelemetryType]int
 	return tm
 }
 
+// addTCPHealthTelemetry adds to the telemetry the TCP health events counted since the last
+// request of the client, across all its connections
+func addTCPHealthTelemetry(tm map[network.ConnTelemetryType]int64, conns []network.ConnectionStats) {
+	var total network.TCPHealthStats
+	for i := range conns {
+		total = total.Add(conns[i].LastTCPHealth)
+	}
+
+	tm[network.TCPRSTSent] = int64(total.RSTSent)
+	tm[network.TCPRSTReceived] = int64(total.RSTReceived)
+	tm[network.TCPZeroWindowProbes] = int64(total.ZeroWindowProbes)
+	tm[network.TCPSYNRetransmits] = int64(total.SYNRetransmits)
+	tm[network.TCPOutOfOrder] = int64(total.OutOfOrder)
+}
+
 func (t *Tracer) getRuntimeCompilationTelemetry() map[string]network.RuntimeCompilationTelemetry {
 	telemetryByAsset := map[string]map[string]int64{
 		"tracer":          runtime.Tracer.GetTelemetry(),
@@ -568,6 +624,11 @@ func (t *Tracer) GetStats() (map[string]interface{}, error) {
 		"kprobes":   ddebpf.GetProbeStats(),
 		"dns":       t.reverseDNS.GetStats(),
 		"http":      t.httpMonitor.GetStats(),
+		"kafka":     t.kafkaMonitor.GetStats(),
+		"database":  t.dbMonitor.GetStats(),
+	}
+	if t.listeners != nil {
+		ret["listeners"] = t.listeners.GetStats()
 	}
 
 	return ret, nil
@@ -704,3 +765,65 @@ func newHTTPMonitor(supported bool, c *config.Config, tracer connection.Tracer,
 	log.Info("http monitoring enabled")
 	return monitor
 }
+
+func newKafkaMonitor(supported bool, c *config.Config) *kafka.Monitor {
+	if !c.EnableKafkaMonitoring {
+		return nil
+	}
+
+	if !supported {
+		log.Warnf("kafka monitoring is not supported by this kernel version. please refer to system-probe's documentation")
+		return nil
+	}
+
+	monitor, err := kafka.NewMonitor(c)
+	if err != nil {
+		log.Errorf("could not instantiate kafka monitor: %s", err)
+		return nil
+	}
+
+	err = monitor.Start()
+	if errors.Is(err, syscall.ENOMEM) {
+		log.Error("could not enable kafka monitoring: not enough memory to attach kafka ebpf socket filter. please consider raising the limit via sysctl -w net.core.optmem_max=<LIMIT>")
+		return nil
+	}
+
+	if err != nil {
+		log.Errorf("could not enable kafka monitoring: %s", err)
+		return nil
+	}
+
+	log.Info("kafka monitoring enabled")
+	return monitor
+}
+
+func newDatabaseMonitor(supported bool, c *config.Config) *database.Monitor {
+	if !c.EnablePostgresMonitoring && !c.EnableMySQLMonitoring {
+		return nil
+	}
+
+	if !supported {
+		log.Warnf("database monitoring is not supported by this kernel version. please refer to system-probe's documentation")
+		return nil
+	}
+
+	monitor, err := database.NewMonitor(c)
+	if err != nil {
+		log.Errorf("could not instantiate database monitor: %s", err)
+		return nil
+	}
+
+	err = monitor.Start()
+	if errors.Is(err, syscall.ENOMEM) {
+		log.Error("could not enable database monitoring: not enough memory to attach database ebpf socket filter. please consider raising the limit via sysctl -w net.core.optmem_max=<LIMIT>")
+		return nil
+	}
+
+	if err != nil {
+		log.Errorf("could not enable database monitoring: %s", err)
+		return nil
+	}
+
+	log.Info("database monitoring enabled")
+	return monitor
+}
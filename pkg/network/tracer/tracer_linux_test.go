@@ -281,6 +281,50 @@ func TestTCPRTT(t *testing.T) {
 	assert.EqualValues(t, int(tcpInfo.Rttvar), int(conn.RTTVar))
 }
 
+func TestTCPResets(t *testing.T) {
+	// Enable BPF-based system probe
+	tr, err := NewTracer(testConfig())
+	require.NoError(t, err)
+	defer tr.Stop()
+
+	// Create TCP Server which resets the connection after receiving a message
+	server := NewTCPServer(func(c net.Conn) {
+		r := bufio.NewReader(c)
+		r.ReadBytes(byte('\n'))
+		c.(*net.TCPConn).SetLinger(0)
+		c.Close()
+	})
+	doneChan := make(chan struct{})
+	err = server.Run(doneChan)
+	require.NoError(t, err)
+	defer close(doneChan)
+
+	c, err := net.DialTimeout("tcp", server.address, time.Second)
+	require.NoError(t, err)
+	defer c.Close()
+
+	_, err = c.Write(genPayload(clientMessageSize))
+	require.NoError(t, err)
+	// the read fails once the RST is received
+	_, err = c.Read(make([]byte, 1))
+	require.Error(t, err)
+
+	var clientConn, serverConn *network.ConnectionStats
+	require.Eventually(t, func() bool {
+		connections := getConnections(t, tr)
+		if conn, ok := findConnection(c.LocalAddr(), c.RemoteAddr(), connections); ok {
+			clientConn = conn
+		}
+		if conn, ok := findConnection(c.RemoteAddr(), c.LocalAddr(), connections); ok {
+			serverConn = conn
+		}
+		return clientConn != nil && serverConn != nil
+	}, 3*time.Second, 500*time.Millisecond, "could not find the connections")
+
+	assert.Equal(t, network.TCPHealthStats{RSTReceived: 1}, clientConn.MonotonicTCPHealth)
+	assert.Equal(t, network.TCPHealthStats{RSTSent: 1}, serverConn.MonotonicTCPHealth)
+}
+
 func TestTCPMiscount(t *testing.T) {
 	t.Skip("skipping because this test will pass/fail depending on host performance")
 	tr, err := NewTracer(testConfig())
@@ -35,6 +35,16 @@ func (t *Tracer) GetStats() (map[string]interface{}, error) {
 	return nil, ebpf.ErrNotImplemented
 }
 
+// GetListeners is not implemented on this OS for Tracer
+func (t *Tracer) GetListeners() ([]network.Listener, error) {
+	return nil, ebpf.ErrNotImplemented
+}
+
+// GetListenerEvents is not implemented on this OS for Tracer
+func (t *Tracer) GetListenerEvents(_ string) ([]network.ListenerEvent, error) {
+	return nil, ebpf.ErrNotImplemented
+}
+
 // DebugNetworkState is not implemented on this OS for Tracer
 func (t *Tracer) DebugNetworkState(clientID string) (map[string]interface{}, error) {
 	return nil, ebpf.ErrNotImplemented
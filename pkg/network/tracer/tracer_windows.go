@@ -185,6 +185,16 @@ func (t *Tracer) GetStats() (map[string]interface{}, error) {
 	return stats, nil
 }
 
+// GetListeners is not implemented on this OS for Tracer
+func (t *Tracer) GetListeners() ([]network.Listener, error) {
+	return nil, ebpf.ErrNotImplemented
+}
+
+// GetListenerEvents is not implemented on this OS for Tracer
+func (t *Tracer) GetListenerEvents(_ string) ([]network.ListenerEvent, error) {
+	return nil, ebpf.ErrNotImplemented
+}
+
 // DebugNetworkState returns a map with the current tracer's internal state, for debugging
 func (t *Tracer) DebugNetworkState(_ string) (map[string]interface{}, error) {
 	return nil, ebpf.ErrNotImplemented
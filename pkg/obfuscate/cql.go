@@ -0,0 +1,12 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+// ObfuscateCQLString quantizes and obfuscates the given Cassandra CQL query. On top of the SQL
+// obfuscation, it redacts the CQL collection, UUID and duration literals.
+func (o *Obfuscator) ObfuscateCQLString(in string) (*ObfuscatedQuery, error) {
+	return o.obfuscateSQLDialectString(in, "cql:", o.cql)
+}
@@ -0,0 +1,68 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObfuscateCQL(t *testing.T) {
+	for _, tt := range []struct {
+		in, out string
+	}{
+		{
+			"SELECT * FROM ks.users WHERE id = 123e4567-e89b-12d3-a456-426614174000",
+			"SELECT * FROM ks.users WHERE id = ?",
+		},
+		{
+			"SELECT * FROM ks.users WHERE id = e89b4567-e89b-12d3-a456-426614174000 AND age > 30",
+			"SELECT * FROM ks.users WHERE id = ? AND age > ?",
+		},
+		{
+			"INSERT INTO ks.t (id, tags, attrs) VALUES (1, {'a', 'b'}, {'k': 'v', 'n': {1, 2}}) USING TTL 86400",
+			"INSERT INTO ks.t ( id, tags, attrs ) VALUES ( ? ) USING TTL ?",
+		},
+		{
+			"UPDATE ks.t SET timeout = 1h30m WHERE id = 0xCAFE",
+			"UPDATE ks.t SET timeout = ? WHERE id = ?",
+		},
+		{
+			"UPDATE t SET m = m + {'k': '}'''} WHERE id = 3",
+			"UPDATE t SET m = m + ? WHERE id = ?",
+		},
+		{
+			"SELECT * FROM t WHERE l = [1, 2, 3] AND name = 'it''s'",
+			"SELECT * FROM t WHERE l = [ ? ] AND name = ?",
+		},
+	} {
+		t.Run("", func(t *testing.T) {
+			oq, err := NewObfuscator(Config{}).ObfuscateCQLString(tt.in)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.out, oq.Query)
+		})
+	}
+
+	t.Run("unterminated-collection", func(t *testing.T) {
+		_, err := NewObfuscator(Config{}).ObfuscateCQLString("UPDATE t SET s = {1, 2 WHERE id = 3")
+		assert.Error(t, err)
+	})
+
+	t.Run("cache", func(t *testing.T) {
+		o := NewObfuscator(Config{SQL: SQLConfig{Cache: true}})
+		defer o.Stop()
+		// the UUID is only recognized as such by CQL
+		query := "SELECT * FROM t WHERE id = 123e4567-e89b-12d3-a456-426614174000"
+		oq, err := o.ObfuscateCQLString(query)
+		assert.NoError(t, err)
+		assert.Equal(t, "SELECT * FROM t WHERE id = ?", oq.Query)
+		o.queryCache.Wait()
+		oq, err = o.ObfuscateSQLString(query)
+		assert.NoError(t, err)
+		assert.NotEqual(t, "SELECT * FROM t WHERE id = ?", oq.Query)
+	})
+}
@@ -0,0 +1,158 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// dynamoDBKeywords are the words of the expressions after which '[' can't be a list index.
+var dynamoDBKeywords = map[string]bool{
+	"AND":     true,
+	"OR":      true,
+	"NOT":     true,
+	"BETWEEN": true,
+	"IN":      true,
+	"SET":     true,
+	"REMOVE":  true,
+	"ADD":     true,
+	"DELETE":  true,
+}
+
+// ObfuscateDynamoDBExpression obfuscates the given DynamoDB condition, key condition, filter or
+// update expression. The expressions reference their values through placeholders such as ":value",
+// which are kept, but some clients inline the values in their place: these literals, strings,
+// numbers, booleans, null, lists and maps, are replaced with "?". The rest of the expression,
+// including the list indexes of the attribute paths, is kept as is.
+func (*Obfuscator) ObfuscateDynamoDBExpression(in string) (string, error) {
+	var (
+		out strings.Builder
+		// path reports whether the last token ends an attribute path, in which case
+		// '[' starts a list index rather than a list literal.
+		path bool
+	)
+	out.Grow(len(in))
+	for i := 0; i < len(in); {
+		c := in[i]
+		switch {
+		case isSpace(c):
+			out.WriteByte(c)
+			i++
+			continue
+		case c == '\'' || c == '"':
+			end, err := scanDynamoDBString(in, i)
+			if err != nil {
+				return "", err
+			}
+			out.WriteByte('?')
+			i, path = end, false
+		case c == '[' && path:
+			end := strings.IndexByte(in[i:], ']')
+			if end < 0 {
+				return "", errors.New("unterminated list index")
+			}
+			out.WriteString(in[i : i+end+1])
+			i += end + 1
+		case c == '[' || c == '{':
+			end, err := scanDynamoDBCollection(in, i)
+			if err != nil {
+				return "", err
+			}
+			out.WriteByte('?')
+			i, path = end, false
+		case isDigit(rune(c)) || c == '-' && !path && i+1 < len(in) && isDigit(rune(in[i+1])):
+			out.WriteByte('?')
+			i, path = scanDynamoDBNumber(in, i+1), false
+		case c == ':' || c == '#' || isDynamoDBNameChar(c):
+			end := i + 1
+			for end < len(in) && isDynamoDBNameChar(in[end]) {
+				end++
+			}
+			word := in[i:end]
+			switch {
+			case strings.EqualFold(word, "true") || strings.EqualFold(word, "false") || strings.EqualFold(word, "null"):
+				// reserved words, which can't be attribute names
+				out.WriteByte('?')
+				path = false
+			default:
+				out.WriteString(word)
+				path = c != ':' && !dynamoDBKeywords[strings.ToUpper(word)]
+			}
+			i = end
+		default:
+			out.WriteByte(c)
+			i++
+			path = false
+		}
+	}
+	return out.String(), nil
+}
+
+// isDynamoDBNameChar reports whether c can be part of an attribute name or a placeholder.
+// The other names must be set through the ExpressionAttributeNames of the request.
+func isDynamoDBNameChar(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || isDigit(rune(c))
+}
+
+// scanDynamoDBString returns the position following the string literal starting at `start`.
+func scanDynamoDBString(in string, start int) (int, error) {
+	quote := in[start]
+	for i := start + 1; i < len(in); i++ {
+		switch in[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1, nil
+		}
+	}
+	return 0, errors.New("unterminated string literal")
+}
+
+// scanDynamoDBNumber returns the position following the digits, decimal point and exponent of a number.
+func scanDynamoDBNumber(in string, i int) int {
+	for i < len(in) {
+		c := in[i]
+		switch {
+		case isDigit(rune(c)) || c == '.':
+		case (c == 'e' || c == 'E') && i+1 < len(in):
+			if in[i+1] == '+' || in[i+1] == '-' {
+				i++
+			}
+		default:
+			return i
+		}
+		i++
+	}
+	return i
+}
+
+// scanDynamoDBCollection returns the position following the list or map literal starting at `start`.
+func scanDynamoDBCollection(in string, start int) (int, error) {
+	var delims []byte
+	for i := start; i < len(in); i++ {
+		switch c := in[i]; c {
+		case '\'', '"':
+			end, err := scanDynamoDBString(in, i)
+			if err != nil {
+				return 0, err
+			}
+			i = end - 1
+		case '[', '{':
+			delims = append(delims, c)
+		case ']', '}':
+			if len(delims) == 0 || closingDelim(delims[len(delims)-1]) != string(c) {
+				return 0, fmt.Errorf("unexpected %q", c)
+			}
+			delims = delims[:len(delims)-1]
+			if len(delims) == 0 {
+				return i + 1, nil
+			}
+		}
+	}
+	return 0, errors.New("unterminated list or map literal")
+}
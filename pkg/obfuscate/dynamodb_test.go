@@ -0,0 +1,66 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObfuscateDynamoDBExpression(t *testing.T) {
+	for _, tt := range []struct {
+		in, out string
+	}{
+		// placeholders are kept
+		{
+			`attribute_not_exists(#id) AND Price <= :limit`,
+			`attribute_not_exists(#id) AND Price <= :limit`,
+		},
+		// condition expression
+		{
+			`ProductCategory IN ('Sporting Goods', 'Gardening Supplies') AND Price BETWEEN 500 AND 600.5`,
+			`ProductCategory IN (?, ?) AND Price BETWEEN ? AND ?`,
+		},
+		// key condition expression
+		{
+			`ForumName = "Amazon DynamoDB" AND begins_with(Subject, 'How to')`,
+			`ForumName = ? AND begins_with(Subject, ?)`,
+		},
+		// filter expression, with list indexes and nested attributes
+		{
+			`RelatedItems[1].Pictures.FrontView = 'http://example.com/1.jpg' AND size(Tags[0]) > 3 AND InStock = true`,
+			`RelatedItems[1].Pictures.FrontView = ? AND size(Tags[0]) > ? AND InStock = ?`,
+		},
+		// update expression
+		{
+			`SET Price = Price - 15, Stock = -2, Tags = list_append(Tags, ['new', 'sale']), Info = {'Color': "red", "Sizes": [1, 2]} REMOVE Brand, Colors[2] ADD Views 1e3 DELETE Codes :codes`,
+			`SET Price = Price - ?, Stock = ?, Tags = list_append(Tags, ?), Info = ? REMOVE Brand, Colors[2] ADD Views ? DELETE Codes :codes`,
+		},
+		{
+			`SET Comment = 'It\'s [great]', Owner = NULL`,
+			`SET Comment = ?, Owner = ?`,
+		},
+	} {
+		t.Run("", func(t *testing.T) {
+			out, err := NewObfuscator(Config{}).ObfuscateDynamoDBExpression(tt.in)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.out, out)
+		})
+	}
+}
+
+func TestObfuscateDynamoDBExpressionErrors(t *testing.T) {
+	for _, in := range []string{
+		`Name = 'unterminated`,
+		`SET Tags = ['a', 'b'`,
+		`SET Info = {'a': [1}]`,
+		`Tags[0 = :v`,
+	} {
+		_, err := NewObfuscator(Config{}).ObfuscateDynamoDBExpression(in)
+		assert.Error(t, err, in)
+	}
+}
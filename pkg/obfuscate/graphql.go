@@ -0,0 +1,254 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// graphQLTokenKind specifies the type of a GraphQL token.
+type graphQLTokenKind int
+
+const (
+	graphQLPunctuator graphQLTokenKind = iota
+	graphQLName
+	graphQLNumber
+	graphQLString
+)
+
+type graphQLToken struct {
+	kind graphQLTokenKind
+	text string
+}
+
+// graphQLScope is an enclosing pair of delimiters in a GraphQL document.
+type graphQLScope struct {
+	// delim is the opening delimiter: '(', '[' or '{'.
+	delim byte
+	// value reports whether the scope holds values: arguments, lists and objects.
+	value bool
+	// varDefs reports whether the scope holds the variable definitions of an operation.
+	varDefs bool
+	// defaultValue reports whether a default value of a variable definition is being scanned.
+	defaultValue bool
+}
+
+// ObfuscateGraphQLString obfuscates the given GraphQL query document. The values of the
+// arguments and the default values of the variables are replaced with "?", while the
+// operations, fields, aliases, fragments, directives and variables are kept. Comments
+// are removed and the document is written on a single line.
+func (*Obfuscator) ObfuscateGraphQLString(in string) (string, error) {
+	tokens, err := scanGraphQL(strings.TrimPrefix(in, "\ufeff"))
+	if err != nil {
+		return "", err
+	}
+	if len(tokens) == 0 {
+		return "", errors.New("empty GraphQL document")
+	}
+
+	var (
+		out    []string
+		scopes []graphQLScope
+		// operation reports whether the last tokens were an operation type followed by its name,
+		// in which case an opening parenthesis starts variable definitions.
+		operation bool
+	)
+	top := func() *graphQLScope {
+		if len(scopes) == 0 {
+			return nil
+		}
+		return &scopes[len(scopes)-1]
+	}
+	inValue := func() bool {
+		s := top()
+		return s != nil && (s.value || s.defaultValue)
+	}
+	last := func() string {
+		if len(out) == 0 {
+			return ""
+		}
+		return out[len(out)-1]
+	}
+	for i, tok := range tokens {
+		prev := last()
+		wasOperation := operation
+		operation = false
+		switch tok.kind {
+		case graphQLString, graphQLNumber:
+			out = appendGraphQLValue(out, top())
+		case graphQLName:
+			switch {
+			case prev == "$" || prev == "@" || !inValue():
+				out = append(out, tok.text)
+				isOperationType := tok.text == "query" || tok.text == "mutation" || tok.text == "subscription"
+				// an operation type may be followed by the operation name
+				operation = len(scopes) == 0 && (isOperationType || wasOperation && prev != "@")
+			case i+1 < len(tokens) && tokens[i+1].text == ":" && top().delim != '[':
+				// argument or object field name
+				out = append(out, tok.text)
+			default:
+				// enum value, boolean or null
+				out = appendGraphQLValue(out, top())
+			}
+		default:
+			switch tok.text {
+			case "(":
+				scopes = append(scopes, graphQLScope{delim: '(', value: !wasOperation, varDefs: wasOperation})
+			case "[":
+				scopes = append(scopes, graphQLScope{delim: '[', value: inValue()})
+			case "{":
+				scopes = append(scopes, graphQLScope{delim: '{', value: inValue()})
+			case ")", "]", "}":
+				s := top()
+				if s == nil || closingDelim(s.delim) != tok.text {
+					return "", fmt.Errorf("unexpected %q", tok.text)
+				}
+				scopes = scopes[:len(scopes)-1]
+			case "=":
+				if s := top(); s != nil && s.varDefs {
+					s.defaultValue = true
+				}
+			case "$", ",":
+				if s := top(); s != nil && s.varDefs {
+					s.defaultValue = false
+				}
+			}
+			out = append(out, tok.text)
+		}
+	}
+	if len(scopes) > 0 {
+		return "", fmt.Errorf("unclosed %q", scopes[len(scopes)-1].delim)
+	}
+	return joinGraphQLTokens(out), nil
+}
+
+// appendGraphQLValue appends a redacted value to out. Consecutive values of a list are grouped
+// into a single one.
+func appendGraphQLValue(out []string, scope *graphQLScope) []string {
+	if scope != nil && scope.delim == '[' && len(out) >= 2 && out[len(out)-1] == "," && out[len(out)-2] == "?" {
+		return out[:len(out)-1]
+	}
+	if scope != nil && scope.delim == '[' && len(out) >= 1 && out[len(out)-1] == "?" {
+		return out
+	}
+	return append(out, "?")
+}
+
+func closingDelim(open byte) string {
+	switch open {
+	case '(':
+		return ")"
+	case '[':
+		return "]"
+	default:
+		return "}"
+	}
+}
+
+// joinGraphQLTokens writes tokens on a single line, separated with spaces where expected.
+func joinGraphQLTokens(tokens []string) string {
+	var b strings.Builder
+	for i, tok := range tokens {
+		if i > 0 {
+			prev := tokens[i-1]
+			switch {
+			case prev == "(" || prev == "[" || prev == "$" || prev == "@":
+			case prev == "..." && tok != "on" && tok != "{" && tok != "@":
+			case tok == "(" || tok == ")" || tok == "]" || tok == "," || tok == ":" || tok == "!":
+			default:
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(tok)
+	}
+	return b.String()
+}
+
+// scanGraphQL splits the GraphQL document in into tokens, leaving out the white spaces
+// and comments.
+func scanGraphQL(in string) ([]graphQLToken, error) {
+	var tokens []graphQLToken
+	for i := 0; i < len(in); {
+		c := in[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '#':
+			for i < len(in) && in[i] != '\n' && in[i] != '\r' {
+				i++
+			}
+		case c == '.':
+			if !strings.HasPrefix(in[i:], "...") {
+				return nil, fmt.Errorf("at position %d: unexpected %q", i, c)
+			}
+			tokens = append(tokens, graphQLToken{graphQLPunctuator, "..."})
+			i += 3
+		case strings.IndexByte("!$&():=@[]{|},", c) >= 0:
+			tokens = append(tokens, graphQLToken{graphQLPunctuator, string(c)})
+			i++
+		case c == '_' || isASCIILetter(c):
+			start := i
+			for i < len(in) && (in[i] == '_' || isASCIILetter(in[i]) || isASCIIDigit(in[i])) {
+				i++
+			}
+			tokens = append(tokens, graphQLToken{graphQLName, in[start:i]})
+		case c == '-' || isASCIIDigit(c):
+			start := i
+			i++
+			for i < len(in) && (isASCIIDigit(in[i]) || in[i] == '.' || in[i] == 'e' || in[i] == 'E' ||
+				((in[i] == '+' || in[i] == '-') && (in[i-1] == 'e' || in[i-1] == 'E'))) {
+				i++
+			}
+			if in[i-1] == '-' {
+				return nil, fmt.Errorf("at position %d: invalid number", start)
+			}
+			tokens = append(tokens, graphQLToken{graphQLNumber, in[start:i]})
+		case c == '"':
+			end, err := scanGraphQLString(in, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, graphQLToken{graphQLString, in[i:end]})
+			i = end
+		default:
+			return nil, fmt.Errorf("at position %d: unexpected %q", i, c)
+		}
+	}
+	return tokens, nil
+}
+
+// scanGraphQLString returns the end position of the string or block string starting at start.
+func scanGraphQLString(in string, start int) (int, error) {
+	if strings.HasPrefix(in[start:], `"""`) {
+		for i := start + 3; i < len(in); i++ {
+			if in[i] == '\\' && strings.HasPrefix(in[i+1:], `"""`) {
+				i += 3
+				continue
+			}
+			if strings.HasPrefix(in[i:], `"""`) {
+				return i + 3, nil
+			}
+		}
+		return 0, fmt.Errorf("at position %d: unterminated block string", start)
+	}
+	for i := start + 1; i < len(in); i++ {
+		switch in[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1, nil
+		case '\n', '\r':
+			return 0, fmt.Errorf("at position %d: unterminated string", start)
+		}
+	}
+	return 0, fmt.Errorf("at position %d: unterminated string", start)
+}
+
+func isASCIILetter(c byte) bool { return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' }
+
+func isASCIIDigit(c byte) bool { return '0' <= c && c <= '9' }
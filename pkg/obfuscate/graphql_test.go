@@ -0,0 +1,82 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObfuscateGraphQL(t *testing.T) {
+	for _, tt := range []struct {
+		name, in, out string
+	}{
+		{
+			"arguments",
+			`{ user(id: 42) { name friends(first: 10, after: "Y3Vyc29y") { edges { node { name } } } } }`,
+			`{ user(id: ?) { name friends(first: ?, after: ?) { edges { node { name } } } } }`,
+		},
+		{
+			"variables",
+			`query GetUser($id: ID! = "abc", $n: [Int!] = [1, 2]) { user(id: $id) { name } }`,
+			`query GetUser($id: ID! = ?, $n: [Int!] = [?]) { user(id: $id) { name } }`,
+		},
+		{
+			"anonymous-operation-variables",
+			`mutation ($token: String) { login(token: $token) { ok } }`,
+			`mutation($token: String) { login(token: $token) { ok } }`,
+		},
+		{
+			"objects-lists-enums",
+			`{ search(filter: {name: "bob", age: 42, tags: ["a", "b", "c"], kind: ADMIN, active: true, next: null}) { id } }`,
+			`{ search(filter: { name: ?, age: ?, tags: [?], kind: ?, active: ?, next: ? }) { id } }`,
+		},
+		{
+			"nested-lists",
+			`query { a(x: [[1, 2], [3]], y: -1.5e3) }`,
+			`query { a(x: [[?], [?]], y: ?) }`,
+		},
+		{
+			"aliases-fragments-directives",
+			`query Q {
+				me: user(id: 1) @include(if: $withUser) {
+					...UserFields
+					... on Admin { level }
+				}
+			}
+			# a comment with a secret
+			fragment UserFields on User { id email }`,
+			`query Q { me: user(id: ?) @include(if: $withUser) { ...UserFields ... on Admin { level } } } fragment UserFields on User { id email }`,
+		},
+		{
+			"block-string",
+			"mutation { createUser(input: {email: \"a@b.c\", password: \"\"\"multi\nline \\\"\"\" secret\"\"\"}) { id } }",
+			`mutation { createUser(input: { email: ?, password: ? }) { id } }`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := NewObfuscator(Config{}).ObfuscateGraphQLString(tt.in)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.out, out)
+		})
+	}
+}
+
+func TestObfuscateGraphQLErrors(t *testing.T) {
+	for _, in := range []string{
+		``,
+		`{ a(x: "unterminated) }`,
+		`{ a(x: """unterminated) }`,
+		`{ a(x: 1) `,
+		`{ a(x: 1]) }`,
+		`{ a(x: 1.) } }`,
+		`{ a(x: %) }`,
+	} {
+		_, err := NewObfuscator(Config{}).ObfuscateGraphQLString(in)
+		assert.Error(t, err, in)
+	}
+}
@@ -27,6 +27,9 @@ type Obfuscator struct {
 	mongo                *jsonObfuscator // nil if disabled
 	sqlExecPlan          *jsonObfuscator // nil if disabled
 	sqlExecPlanNormalize *jsonObfuscator // nil if disabled
+	// cql and partiQL hold the SQL configuration used for the CQL and PartiQL dialects.
+	cql     *SQLConfig
+	partiQL *SQLConfig
 	// sqlLiteralEscapes reports whether we should treat escape characters literally or as escape characters.
 	// A non-zero value means 'yes'. Different SQL engines behave in different ways and the tokenizer needs
 	// to be generic.
@@ -177,6 +180,8 @@ func NewObfuscator(cfg Config) *Obfuscator {
 	o := Obfuscator{
 		opts:       &cfg,
 		queryCache: newMeasuredCache(cacheOptions{On: cfg.SQL.Cache, Statsd: cfg.Statsd}),
+		cql:        withDBMS(cfg.SQL, DBMSCassandra),
+		partiQL:    withDBMS(cfg.SQL, DBMSDynamoDB),
 	}
 	if cfg.ES.Enabled {
 		o.es = newJSONObfuscator(&cfg.ES, &o)
@@ -0,0 +1,13 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+// ObfuscatePartiQLString quantizes and obfuscates the given PartiQL statement, as sent to the
+// DynamoDB ExecuteStatement, BatchExecuteStatement and ExecuteTransaction operations. On top of
+// the SQL obfuscation, it redacts the tuple and bag literals.
+func (o *Obfuscator) ObfuscatePartiQLString(in string) (*ObfuscatedQuery, error) {
+	return o.obfuscateSQLDialectString(in, "partiql:", o.partiQL)
+}
@@ -0,0 +1,41 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObfuscatePartiQL(t *testing.T) {
+	for _, tt := range []struct {
+		in, out string
+	}{
+		{
+			`SELECT * FROM "Music" WHERE Artist='Acme Band' AND SongTitle=?`,
+			`SELECT * FROM Music WHERE Artist = ? AND SongTitle = ?`,
+		},
+		{
+			`INSERT INTO "Music" VALUE {'Artist': 'Acme', 'Year': 2020, 'Tags': <<'a', 'b'>>}`,
+			`INSERT INTO Music VALUE ?`,
+		},
+		{
+			`UPDATE "Music" SET AwardsWon=1 SET AwardDetail={'Grammys':[2020, 2018]} WHERE Artist='Acme Band'`,
+			`UPDATE Music SET AwardsWon = ? SET AwardDetail = ? WHERE Artist = ?`,
+		},
+		{
+			`SELECT * FROM "Music" WHERE Year IN <<1999, 2000>>`,
+			`SELECT * FROM Music WHERE Year IN ?`,
+		},
+	} {
+		t.Run("", func(t *testing.T) {
+			oq, err := NewObfuscator(Config{}).ObfuscatePartiQLString(tt.in)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.out, oq.Query)
+		})
+	}
+}
@@ -189,7 +189,7 @@ func (f *replaceFilter) Filter(token, lastToken TokenKind, buffer []byte) (token
 		}
 	}
 	switch token {
-	case DollarQuotedString, String, Number, Null, Variable, PreparedStatement, BooleanLiteral, EscapeSequence:
+	case DollarQuotedString, String, Number, Null, Variable, PreparedStatement, BooleanLiteral, EscapeSequence, CollectionLiteral:
 		return markFilteredGroupable(token), questionMark, nil
 	case '?':
 		// Cases like 'ARRAY [ ?, ? ]' should be collapsed into 'ARRAY [ ? ]'
@@ -307,6 +307,27 @@ func (o *Obfuscator) ObfuscateSQLStringWithOptions(in string, opts *SQLConfig) (
 	return oq, nil
 }
 
+// obfuscateSQLDialectString obfuscates the given query of an SQL dialect. Its results are cached
+// under cacheKeyPrefix+in, so that they don't collide with the ones of plain SQL queries.
+func (o *Obfuscator) obfuscateSQLDialectString(in, cacheKeyPrefix string, opts *SQLConfig) (*ObfuscatedQuery, error) {
+	key := cacheKeyPrefix + in
+	if v, ok := o.queryCache.Get(key); ok {
+		return v.(*ObfuscatedQuery), nil
+	}
+	oq, err := o.obfuscateSQLString(in, opts)
+	if err != nil {
+		return oq, err
+	}
+	o.queryCache.Set(key, oq, oq.Cost())
+	return oq, nil
+}
+
+// withDBMS returns a copy of cfg for the given DBMS.
+func withDBMS(cfg SQLConfig, dbms string) *SQLConfig {
+	cfg.DBMS = dbms
+	return &cfg
+}
+
 func (o *Obfuscator) obfuscateSQLString(in string, opts *SQLConfig) (*ObfuscatedQuery, error) {
 	lesc := o.useSQLLiteralEscapes()
 	tok := NewSQLTokenizer(in, lesc, opts)
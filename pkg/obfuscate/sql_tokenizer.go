@@ -73,6 +73,7 @@ const (
 	Join
 	TableName
 	ColonCast
+	CollectionLiteral // a CQL map, set or tuple, or a PartiQL tuple or bag, e.g. {'a': 1}
 
 	// FilteredGroupable specifies that the given token has been discarded by one of the
 	// token filters and that it is groupable together with consecutive FilteredGroupable
@@ -136,6 +137,7 @@ var tokenKindStrings = map[TokenKind]string{
 	Join:                         "Join",
 	TableName:                    "TableName",
 	ColonCast:                    "ColonCast",
+	CollectionLiteral:            "CollectionLiteral",
 	FilteredGroupable:            "FilteredGroupable",
 	FilteredGroupableParenthesis: "FilteredGroupableParenthesis",
 	Filtered:                     "Filtered",
@@ -153,8 +155,15 @@ func (k TokenKind) String() string {
 const (
 	// DBMSSQLServer is a MS SQL Server
 	DBMSSQLServer = "mssql"
+	// DBMSCassandra is Apache Cassandra, queried with CQL
+	DBMSCassandra = "cassandra"
+	// DBMSDynamoDB is Amazon DynamoDB, queried with PartiQL
+	DBMSDynamoDB = "dynamodb"
 )
 
+// uuidLength is the length of a UUID literal, e.g. 123e4567-e89b-12d3-a456-426614174000.
+const uuidLength = 36
+
 const escapeCharacter = '\\'
 
 // SQLTokenizer is the struct used to generate SQL
@@ -243,6 +252,12 @@ func (tkn *SQLTokenizer) Scan() (TokenKind, []byte) {
 	tkn.SkipBlank()
 
 	switch ch := tkn.lastChar; {
+	case tkn.cfg.DBMS == DBMSCassandra && tkn.isUUIDAhead():
+		// CQL UUID literals are not quoted and may start with either a letter or a digit
+		for i := 0; i < uuidLength; i++ {
+			tkn.advance()
+		}
+		return Number, tkn.bytes()
 	case isLeadingLetter(ch):
 		return tkn.scanIdentifier()
 	case isDigit(ch):
@@ -317,6 +332,11 @@ func (tkn *SQLTokenizer) Scan() (TokenKind, []byte) {
 			tkn.advance()
 			return tkn.scanCommentType1("#")
 		case '<':
+			if tkn.cfg.DBMS == DBMSDynamoDB && tkn.lastChar == '<' {
+				// PartiQL bag, e.g. <<1, 2>>
+				tkn.advance()
+				return tkn.scanCollectionLiteral("<<", ">>")
+			}
 			switch tkn.lastChar {
 			case '>':
 				tkn.advance()
@@ -396,6 +416,9 @@ func (tkn *SQLTokenizer) Scan() (TokenKind, []byte) {
 			}
 			return kind, tok
 		case '{':
+			if tkn.cfg.DBMS == DBMSCassandra || tkn.cfg.DBMS == DBMSDynamoDB {
+				return tkn.scanCollectionLiteral("{", "}")
+			}
 			if tkn.pos == 1 || tkn.curlys > 0 {
 				// Do not fully obfuscate top-level SQL escape sequences like {{[?=]call procedure-name[([parameter][,parameter]...)]}.
 				// We want these to display a bit more context than just a plain '?'
@@ -585,6 +608,73 @@ func (tkn *SQLTokenizer) scanEscapeSequence(braces rune) (TokenKind, []byte) {
 	return EscapeSequence, tkn.bytes()
 }
 
+// scanCollectionLiteral scans a collection literal whose opening delimiter has been read,
+// up to its matching closing delimiter. Nested collections and quoted strings are skipped.
+func (tkn *SQLTokenizer) scanCollectionLiteral(open, close string) (TokenKind, []byte) {
+	depth := 1
+	var quote rune
+	for depth > 0 {
+		ch := tkn.lastChar
+		if ch == EndChar {
+			tkn.setErr("unexpected EOF in collection literal")
+			return LexError, tkn.bytes()
+		}
+		tkn.advance()
+		switch {
+		case quote != 0:
+			if ch == quote {
+				if tkn.lastChar == quote {
+					// doubled delimiter within the string
+					tkn.advance()
+				} else {
+					quote = 0
+				}
+			}
+		case ch == '\'' || ch == '"':
+			quote = ch
+		case ch == rune(open[0]) && (len(open) == 1 || tkn.lastChar == rune(open[1])):
+			if len(open) > 1 {
+				tkn.advance()
+			}
+			depth++
+		case ch == rune(close[0]) && (len(close) == 1 || tkn.lastChar == rune(close[1])):
+			if len(close) > 1 {
+				tkn.advance()
+			}
+			depth--
+		}
+	}
+	return CollectionLiteral, tkn.bytes()
+}
+
+// isUUIDAhead reports whether the rune being scanned starts a UUID literal.
+func (tkn *SQLTokenizer) isUUIDAhead() bool {
+	if tkn.lastChar == EndChar || digitVal(tkn.lastChar) >= 16 {
+		return false
+	}
+	start := tkn.off - 1 // the first character is a single-byte hex digit
+	if len(tkn.buf)-start < uuidLength {
+		return false
+	}
+	for i, c := range tkn.buf[start : start+uuidLength] {
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if digitVal(rune(c)) >= 16 {
+				return false
+			}
+		}
+	}
+	if end := start + uuidLength; end < len(tkn.buf) {
+		next, _ := utf8.DecodeRune(tkn.buf[end:])
+		return !isLetter(next) && !isDigit(next)
+	}
+	return true
+}
+
 func (tkn *SQLTokenizer) scanBindVar() (TokenKind, []byte) {
 	token := ValueArg
 	if tkn.lastChar == ':' {
@@ -660,6 +750,12 @@ exponent:
 	}
 
 exit:
+	if tkn.cfg.DBMS == DBMSCassandra {
+		// CQL duration literal, e.g. 1h30m
+		for isLetter(tkn.lastChar) || isDigit(tkn.lastChar) {
+			tkn.advance()
+		}
+	}
 	t := tkn.bytes()
 	if len(t) == 0 {
 		return LexError, nil
@@ -54,6 +54,13 @@ func (r *RemoteSysProbeUtil) GetCheck(check string) (interface{}, error) {
 			return nil, err
 		}
 		return stats, nil
+	} else if check == "dns" {
+		var stats []probe.DNSStats
+		err = json.Unmarshal(body, &stats)
+		if err != nil {
+			return nil, err
+		}
+		return stats, nil
 	}
 
 	return nil, fmt.Errorf("Invalid check name: %s", check)
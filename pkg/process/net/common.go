@@ -164,6 +164,92 @@ func (r *RemoteSysProbeUtil) GetConnections(clientID string) (*model.Connections
 	return conns, nil
 }
 
+// GetTCPHealth returns the TCP health events of the connections, aggregated by remote endpoint,
+// counted by the system probe service since the last request of the client
+func (r *RemoteSysProbeUtil) GetTCPHealth(clientID string) ([]netEncoding.TCPHealth, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s?client_id=%s", tcpHealthURL, clientID), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("tcp health request failed: Probe Path %s, url: %s, status code: %d", r.path, tcpHealthURL, resp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var health []netEncoding.TCPHealth
+	if err := json.Unmarshal(body, &health); err != nil {
+		return nil, err
+	}
+
+	return health, nil
+}
+
+// GetListeners returns the listening sockets of the host, along with the process owning them
+func (r *RemoteSysProbeUtil) GetListeners() ([]netEncoding.Listener, error) {
+	body, err := r.getListenersBody(listenersURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var listeners []netEncoding.Listener
+	if err := json.Unmarshal(body, &listeners); err != nil {
+		return nil, err
+	}
+
+	return listeners, nil
+}
+
+// GetListenerEvents returns the new listeners on a port which is not allowed, appeared since the
+// last request of the client. The first request of a client only registers it.
+func (r *RemoteSysProbeUtil) GetListenerEvents(clientID string) ([]netEncoding.ListenerEvent, error) {
+	body, err := r.getListenersBody(fmt.Sprintf("%s?client_id=%s", listenerEventsURL, clientID))
+	if err != nil {
+		return nil, err
+	}
+
+	var events []netEncoding.ListenerEvent
+	if err := json.Unmarshal(body, &events); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (r *RemoteSysProbeUtil) getListenersBody(url string) ([]byte, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrListenerInventoryDisabled
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("listeners request failed: Probe Path %s, url: %s, status code: %d", r.path, url, resp.StatusCode)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
 // GetStats returns the expvar stats of the system probe
 func (r *RemoteSysProbeUtil) GetStats() (map[string]interface{}, error) {
 	req, err := http.NewRequest("GET", statsURL, nil)
@@ -14,10 +14,13 @@ import (
 )
 
 const (
-	connectionsURL = "http://unix/connections"
-	statsURL       = "http://unix/debug/stats"
-	procStatsURL   = "http://unix/proc/stats"
-	netType        = "unix"
+	connectionsURL    = "http://unix/connections"
+	statsURL          = "http://unix/debug/stats"
+	procStatsURL      = "http://unix/proc/stats"
+	tcpHealthURL      = "http://unix/tcp_health"
+	listenersURL      = "http://unix/listeners"
+	listenerEventsURL = "http://unix/listeners/events"
+	netType           = "unix"
 )
 
 // CheckPath is used in conjunction with calling the stats endpoint, since we are calling this
@@ -11,6 +11,7 @@ package net
 import (
 	model "github.com/DataDog/agent-payload/v5/process"
 	"github.com/DataDog/datadog-agent/pkg/ebpf"
+	netEncoding "github.com/DataDog/datadog-agent/pkg/network/encoding"
 )
 
 // RemoteSysProbeUtil is not supported
@@ -36,6 +37,21 @@ func (r *RemoteSysProbeUtil) GetConnections(clientID string) (*model.Connections
 	return nil, ebpf.ErrNotImplemented
 }
 
+// GetTCPHealth is not supported
+func (r *RemoteSysProbeUtil) GetTCPHealth(clientID string) ([]netEncoding.TCPHealth, error) {
+	return nil, ebpf.ErrNotImplemented
+}
+
+// GetListeners is not supported
+func (r *RemoteSysProbeUtil) GetListeners() ([]netEncoding.Listener, error) {
+	return nil, ebpf.ErrNotImplemented
+}
+
+// GetListenerEvents is not supported
+func (r *RemoteSysProbeUtil) GetListenerEvents(clientID string) ([]netEncoding.ListenerEvent, error) {
+	return nil, ebpf.ErrNotImplemented
+}
+
 // GetStats is not supported
 func (r *RemoteSysProbeUtil) GetStats() (map[string]interface{}, error) {
 	return nil, ebpf.ErrNotImplemented
@@ -14,8 +14,11 @@ const (
 	connectionsURL = "http://localhost:3333/connections"
 	statsURL       = "http://localhost:3333/debug/stats"
 	// procStatsURL is not used in windows, the value is added to avoid compilation error in windows
-	procStatsURL = "http://localhost:3333/proc/stats"
-	netType      = "tcp"
+	procStatsURL      = "http://localhost:3333/proc/stats"
+	tcpHealthURL      = "http://localhost:3333/tcp_health"
+	listenersURL      = "http://localhost:3333/listeners"
+	listenerEventsURL = "http://localhost:3333/listeners/events"
+	netType           = "tcp"
 )
 
 // CheckPath is used to make sure the globalSocketPath has been set before attempting to connect
@@ -0,0 +1,12 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package net
+
+import "errors"
+
+// ErrListenerInventoryDisabled is returned when the listeners are requested from a system-probe
+// which does not keep their inventory
+var ErrListenerInventoryDisabled = errors.New("listener inventory disabled in the system-probe")
@@ -129,7 +129,7 @@ func marshalSplitCompress(iterator serieIterator, bufferContext *marshaler.Buffe
 		bufferContext.CompressorOutput.Reset()
 
 		compressor, err = stream.NewCompressor(
-			bufferContext.CompressorInput, bufferContext.CompressorOutput,
+			bufferContext.Compressor, bufferContext.CompressorInput, bufferContext.CompressorOutput,
 			maxPayloadSize, maxUncompressedSize,
 			[]byte{}, []byte{}, []byte{})
 		if err != nil {
@@ -22,6 +22,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/serializer/internal/stream"
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
 	"github.com/DataDog/datadog-agent/pkg/tagset"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -373,7 +374,7 @@ func TestPayloadsSeries(t *testing.T) {
 	originalLength := len(testSeries)
 	builder := stream.NewJSONPayloadBuilder(true)
 	iterableSeries := &IterableSeries{IterableSeries: CreateIterableSeries(testSeries)}
-	payloads, err := builder.BuildWithOnErrItemTooBigPolicy(iterableSeries, stream.DropItemOnErrItemTooBig)
+	payloads, err := builder.BuildWithOnErrItemTooBigPolicy(iterableSeries, stream.DropItemOnErrItemTooBig, compression.NewDefaultCompressor())
 	require.Nil(t, err)
 	var splitSeries = []Series{}
 	for _, compressedPayload := range payloads {
@@ -421,7 +422,7 @@ func BenchmarkPayloadsSeries(b *testing.B) {
 		// always record the result of Payloads to prevent
 		// the compiler eliminating the function call.
 		iterableSeries := &IterableSeries{IterableSeries: CreateIterableSeries(testSeries)}
-		r, _ = builder.BuildWithOnErrItemTooBigPolicy(iterableSeries, stream.DropItemOnErrItemTooBig)
+		r, _ = builder.BuildWithOnErrItemTooBigPolicy(iterableSeries, stream.DropItemOnErrItemTooBig, compression.NewDefaultCompressor())
 	}
 	// ensure we actually had to split
 	if len(r) != 13 {
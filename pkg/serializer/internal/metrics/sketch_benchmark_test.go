@@ -13,6 +13,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
 	"github.com/DataDog/datadog-agent/pkg/serializer/split"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 	"github.com/stretchr/testify/require"
 )
 
@@ -26,7 +27,7 @@ func benchmarkSplitPayloadsSketchesSplit(b *testing.B, numPoints int) {
 	b.ResetTimer()
 
 	for n := 0; n < b.N; n++ {
-		split.Payloads(testSketchSeries, true, split.ProtoMarshalFct)
+		split.Payloads(testSketchSeries, compression.NewDefaultCompressor(), split.ProtoMarshalFct)
 	}
 }
 
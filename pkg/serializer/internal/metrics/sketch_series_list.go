@@ -158,7 +158,7 @@ func (sl SketchSeriesList) MarshalSplitCompress(bufferContext *marshaler.BufferC
 		bufferContext.CompressorOutput.Reset()
 
 		compressor, err = stream.NewCompressor(
-			bufferContext.CompressorInput, bufferContext.CompressorOutput,
+			bufferContext.Compressor, bufferContext.CompressorInput, bufferContext.CompressorOutput,
 			maxPayloadSize, maxUncompressedSize,
 			[]byte{}, footer, []byte{})
 		if err != nil {
@@ -19,6 +19,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
 	"github.com/DataDog/datadog-agent/pkg/tagset"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -99,11 +100,17 @@ func TestSketchSeriesListJSONMarshal(t *testing.T) {
 	assert.JSONEq(t, string(json), `{"sketches":[{"host":"host.0","interval":0,"metric":"name.0","points":[{"bins":"","binsCount":0,"sketch":{"summary":{"Avg":0,"Cnt":0,"Max":0,"Min":0,"Sum":0}},"ts":0},{"bins":"0:1","binsCount":1,"sketch":{"summary":{"Avg":0,"Cnt":1,"Max":0,"Min":0,"Sum":0}},"ts":10},{"bins":"0:1 1338:1","binsCount":2,"sketch":{"summary":{"Avg":0.5,"Cnt":2,"Max":1,"Min":0,"Sum":1}},"ts":20},{"bins":"0:1 1338:1 1383:1","binsCount":3,"sketch":{"summary":{"Avg":1,"Cnt":3,"Max":2,"Min":0,"Sum":3}},"ts":30},{"bins":"0:1 1338:1 1383:1 1409:1","binsCount":4,"sketch":{"summary":{"Avg":1.5,"Cnt":4,"Max":3,"Min":0,"Sum":6}},"ts":40}],"tags":["a:0","b:0"]},{"host":"host.1","interval":1,"metric":"name.1","points":[{"bins":"","binsCount":0,"sketch":{"summary":{"Avg":0,"Cnt":0,"Max":0,"Min":0,"Sum":0}},"ts":0},{"bins":"0:1","binsCount":1,"sketch":{"summary":{"Avg":0,"Cnt":1,"Max":0,"Min":0,"Sum":0}},"ts":10},{"bins":"0:1 1338:1","binsCount":2,"sketch":{"summary":{"Avg":0.5,"Cnt":2,"Max":1,"Min":0,"Sum":1}},"ts":20},{"bins":"0:1 1338:1 1383:1","binsCount":3,"sketch":{"summary":{"Avg":1,"Cnt":3,"Max":2,"Min":0,"Sum":3}},"ts":30},{"bins":"0:1 1338:1 1383:1 1409:1","binsCount":4,"sketch":{"summary":{"Avg":1.5,"Cnt":4,"Max":3,"Min":0,"Sum":6}},"ts":40},{"bins":"0:1 1338:1 1383:1 1409:1 1427:1","binsCount":5,"sketch":{"summary":{"Avg":2,"Cnt":5,"Max":4,"Min":0,"Sum":10}},"ts":50}],"tags":["a:1","b:1"]}]}`)
 }
 
+func newZlibBufferContext(t *testing.T) *marshaler.BufferContext {
+	compressor, err := compression.NewCompressor(compression.ZlibKind, 0)
+	require.NoError(t, err)
+	return marshaler.NewBufferContext(compressor)
+}
+
 func TestSketchSeriesMarshalSplitCompressEmpty(t *testing.T) {
 
 	sl := SketchSeriesList{}
 	payload, _ := sl.Marshal()
-	payloads, err := sl.MarshalSplitCompress(marshaler.DefaultBufferContext())
+	payloads, err := sl.MarshalSplitCompress(newZlibBufferContext(t))
 
 	assert.Nil(t, err)
 
@@ -134,7 +141,7 @@ func TestSketchSeriesMarshalSplitCompressItemTooBigIsDropped(t *testing.T) {
 		Interval: 0,
 	}
 
-	payloads, err := sl.MarshalSplitCompress(marshaler.DefaultBufferContext())
+	payloads, err := sl.MarshalSplitCompress(newZlibBufferContext(t))
 
 	assert.Nil(t, err)
 
@@ -160,7 +167,7 @@ func TestSketchSeriesMarshalSplitCompress(t *testing.T) {
 	}
 
 	payload, _ := sl.Marshal()
-	payloads, err := sl.MarshalSplitCompress(marshaler.DefaultBufferContext())
+	payloads, err := sl.MarshalSplitCompress(newZlibBufferContext(t))
 	require.NoError(t, err)
 
 	reader := bytes.NewReader(*payloads[0])
@@ -205,7 +212,7 @@ func TestSketchSeriesMarshalSplitCompressSplit(t *testing.T) {
 		sl[i] = Makeseries(i)
 	}
 
-	payloads, err := sl.MarshalSplitCompress(marshaler.DefaultBufferContext())
+	payloads, err := sl.MarshalSplitCompress(newZlibBufferContext(t))
 	assert.Nil(t, err)
 
 	recoveredSketches := []gogen.SketchPayload{}
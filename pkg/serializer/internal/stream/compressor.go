@@ -3,14 +3,10 @@
 // This product includes software developed at Datadog (https://www.datadoghq.com/).
 // Copyright 2018-present Datadog, Inc.
 
-//go:build zlib
-// +build zlib
-
 package stream
 
 import (
 	"bytes"
-	"compress/zlib"
 	"errors"
 	"expvar"
 
@@ -18,11 +14,6 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/util/compression"
 )
 
-const (
-	// Available is true if the code is compiled in
-	Available = true
-)
-
 var (
 	compressorExpvars    = expvar.NewMap("compressor")
 	expvarsTotalPayloads = expvar.Int{}
@@ -63,7 +54,8 @@ func init() {
 type Compressor struct {
 	input               *bytes.Buffer // temporary buffer for data that has not been compressed yet
 	compressed          *bytes.Buffer // output buffer containing the compressed payload
-	zipper              *zlib.Writer
+	algorithm           compression.Compressor
+	zipper              compression.StreamWriter
 	header              []byte // json header to print at the beginning of the payload
 	footer              []byte // json footer to append at the end of the payload
 	uncompressedWritten int    // uncompressed bytes written
@@ -76,8 +68,10 @@ type Compressor struct {
 	separator           []byte
 }
 
-func NewCompressor(input, output *bytes.Buffer, maxPayloadSize, maxUncompressedSize int, header, footer []byte, separator []byte) (*Compressor, error) {
+// NewCompressor returns a new Compressor writing the payload compressed with `algorithm` to `output`
+func NewCompressor(algorithm compression.Compressor, input, output *bytes.Buffer, maxPayloadSize, maxUncompressedSize int, header, footer []byte, separator []byte) (*Compressor, error) {
 	c := &Compressor{
+		algorithm:           algorithm,
 		header:              header,
 		footer:              footer,
 		input:               input,
@@ -86,11 +80,11 @@ func NewCompressor(input, output *bytes.Buffer, maxPayloadSize, maxUncompressedS
 		maxPayloadSize:      maxPayloadSize,
 		maxUncompressedSize: maxUncompressedSize,
 		maxUnzippedItemSize: maxPayloadSize - len(footer) - len(header),
-		maxZippedItemSize:   maxUncompressedSize - compression.CompressBound(len(footer)+len(header)),
+		maxZippedItemSize:   maxUncompressedSize - algorithm.CompressBound(len(footer)+len(header)),
 		separator:           separator,
 	}
 
-	c.zipper = zlib.NewWriter(c.compressed)
+	c.zipper = algorithm.NewStreamWriter(c.compressed)
 	n, err := c.zipper.Write(header)
 	c.uncompressedWritten += n
 
@@ -102,7 +96,7 @@ func NewCompressor(input, output *bytes.Buffer, maxPayloadSize, maxUncompressedS
 // that could actually fit after compression. That said it is probably impossible
 // to have a 2MB+ item that is valid for the backend.
 func (c *Compressor) checkItemSize(data []byte) bool {
-	return len(data) < c.maxUnzippedItemSize && compression.CompressBound(len(data)) < c.maxZippedItemSize
+	return len(data) < c.maxUnzippedItemSize && c.algorithm.CompressBound(len(data)) < c.maxZippedItemSize
 }
 
 // hasRoomForItem checks if the current payload has enough room to store the given item
@@ -111,7 +105,7 @@ func (c *Compressor) hasRoomForItem(item []byte) bool {
 	if !c.firstItem {
 		uncompressedDataSize += len(c.separator)
 	}
-	return compression.CompressBound(uncompressedDataSize) <= c.remainingSpace() && c.uncompressedWritten+uncompressedDataSize <= c.maxUncompressedSize
+	return c.algorithm.CompressBound(uncompressedDataSize) <= c.remainingSpace() && c.uncompressedWritten+uncompressedDataSize <= c.maxUncompressedSize
 }
 
 // pack flushes the temporary uncompressed buffer input to the compression writer
@@ -123,7 +117,10 @@ func (c *Compressor) pack() error {
 		return err
 	}
 	c.uncompressedWritten += int(n)
-	c.zipper.Flush()
+	err = c.zipper.Flush()
+	if err != nil {
+		return err
+	}
 	c.input.Reset()
 	return nil
 }
@@ -184,7 +181,7 @@ func (c *Compressor) Close() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	// Add zlib footer and close
+	// Add the compression footer and close
 	err = c.zipper.Close()
 	if err != nil {
 		return nil, err
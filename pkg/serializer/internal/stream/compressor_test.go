@@ -18,6 +18,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 )
 
 var (
@@ -54,7 +55,7 @@ func TestCompressorSimple(t *testing.T) {
 	maxPayloadSize := config.Datadog.GetInt("serializer_max_payload_size")
 	maxUncompressedSize := config.Datadog.GetInt("serializer_max_uncompressed_payload_size")
 	c, err := NewCompressor(
-		&bytes.Buffer{}, &bytes.Buffer{},
+		compression.NewDefaultCompressor(), &bytes.Buffer{}, &bytes.Buffer{},
 		maxPayloadSize, maxUncompressedSize,
 		[]byte("{["), []byte("]}"), []byte(","))
 	require.NoError(t, err)
@@ -68,6 +69,48 @@ func TestCompressorSimple(t *testing.T) {
 	require.Equal(t, "{[A,A,A,A,A]}", payloadToString(p))
 }
 
+func TestCompressorKinds(t *testing.T) {
+	maxPayloadSize := config.Datadog.GetInt("serializer_max_payload_size")
+	maxUncompressedSize := config.Datadog.GetInt("serializer_max_uncompressed_payload_size")
+	m := &marshaler.DummyMarshaller{
+		Items:  []string{"A", "B", "C"},
+		Header: "{[",
+		Footer: "]}",
+	}
+
+	for _, kind := range []string{compression.ZlibKind, compression.ZstdKind, compression.ZstdV1Kind, compression.NoneKind} {
+		t.Run(kind, func(t *testing.T) {
+			algorithm, err := compression.NewCompressor(kind, 0)
+			if err != nil && kind == compression.ZstdKind {
+				t.Skipf("%s is not available in this build: %v", kind, err)
+			}
+			require.NoError(t, err)
+
+			c, err := NewCompressor(
+				algorithm, &bytes.Buffer{}, &bytes.Buffer{},
+				maxPayloadSize, maxUncompressedSize,
+				[]byte("{["), []byte("]}"), []byte(","))
+			require.NoError(t, err)
+			for i := 0; i < 5; i++ {
+				require.NoError(t, c.AddItem([]byte("A")))
+			}
+			p, err := c.Close()
+			require.NoError(t, err)
+			decompressed, err := algorithm.Decompress(p)
+			require.NoError(t, err)
+			require.Equal(t, "{[A,A,A,A,A]}", string(decompressed))
+
+			builder := NewJSONPayloadBuilder(true)
+			payloads, err := builder.BuildWithOnErrItemTooBigPolicy(marshaler.NewIterableStreamJSONMarshalerAdapter(m), DropItemOnErrItemTooBig, algorithm)
+			require.NoError(t, err)
+			require.Len(t, payloads, 1)
+			decompressed, err = algorithm.Decompress(*payloads[0])
+			require.NoError(t, err)
+			require.Equal(t, "{[A,B,C]}", string(decompressed))
+		})
+	}
+}
+
 func TestOnePayloadSimple(t *testing.T) {
 	m := &marshaler.DummyMarshaller{
 		Items:  []string{"A", "B", "C"},
@@ -3,9 +3,6 @@
 // This product includes software developed at Datadog (https://www.datadoghq.com/).
 // Copyright 2019-present Datadog, Inc.
 
-//go:build zlib
-// +build zlib
-
 package stream
 
 import (
@@ -20,6 +17,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/forwarder"
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
 	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
@@ -101,13 +99,14 @@ const (
 // Build serializes a metadata payload and sends it to the forwarder
 func (b *JSONPayloadBuilder) Build(m marshaler.StreamJSONMarshaler) (forwarder.Payloads, error) {
 	adapter := marshaler.NewIterableStreamJSONMarshalerAdapter(m)
-	return b.BuildWithOnErrItemTooBigPolicy(adapter, DropItemOnErrItemTooBig)
+	return b.BuildWithOnErrItemTooBigPolicy(adapter, DropItemOnErrItemTooBig, compression.NewSerializerCompressor())
 }
 
-// BuildWithOnErrItemTooBigPolicy serializes a metadata payload and sends it to the forwarder
+// BuildWithOnErrItemTooBigPolicy serializes a metadata payload, compressed with `algorithm`, and sends it to the forwarder
 func (b *JSONPayloadBuilder) BuildWithOnErrItemTooBigPolicy(
 	m marshaler.IterableStreamJSONMarshaler,
-	policy OnErrItemTooBigPolicy) (forwarder.Payloads, error) {
+	policy OnErrItemTooBigPolicy,
+	algorithm compression.Compressor) (forwarder.Payloads, error) {
 	var input, output *bytes.Buffer
 
 	// the backend accepts payloads up to specific compressed / uncompressed
@@ -158,7 +157,7 @@ func (b *JSONPayloadBuilder) BuildWithOnErrItemTooBigPolicy(
 	}
 
 	compressor, err := NewCompressor(
-		input, output,
+		algorithm, input, output,
 		maxPayloadSize, maxUncompressedSize,
 		header.Bytes(), footer.Bytes(), []byte(","))
 	if err != nil {
@@ -192,7 +191,7 @@ func (b *JSONPayloadBuilder) BuildWithOnErrItemTooBigPolicy(
 			input.Reset()
 			output.Reset()
 			compressor, err = NewCompressor(
-				input, output,
+				algorithm, input, output,
 				maxPayloadSize, maxUncompressedSize,
 				header.Bytes(), footer.Bytes(), []byte(","))
 			if err != nil {
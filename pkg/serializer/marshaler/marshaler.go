@@ -9,6 +9,8 @@ import (
 	"bytes"
 
 	jsoniter "github.com/json-iterator/go"
+
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 )
 
 // JSONMarshaler is a AbstractMarshaler that implement JSON marshaling.
@@ -62,17 +64,25 @@ type IterableStreamJSONMarshaler interface {
 }
 
 // BufferContext contains the buffers used for MarshalSplitCompress so they can be shared between invocations
+// and the compression algorithm used for the payloads
 type BufferContext struct {
 	CompressorInput   *bytes.Buffer
 	CompressorOutput  *bytes.Buffer
 	PrecompressionBuf *bytes.Buffer
+	Compressor        compression.Compressor
 }
 
-// DefaultBufferContext initialize the default compression buffers
+// DefaultBufferContext initialize the default compression buffers with the serializer compressor
 func DefaultBufferContext() *BufferContext {
+	return NewBufferContext(compression.NewSerializerCompressor())
+}
+
+// NewBufferContext initialize the default compression buffers with `compressor`
+func NewBufferContext(compressor compression.Compressor) *BufferContext {
 	return &BufferContext{
-		bytes.NewBuffer(make([]byte, 0, 1024)),
-		bytes.NewBuffer(make([]byte, 0, 1024)),
-		bytes.NewBuffer(make([]byte, 0, 1024)),
+		CompressorInput:   bytes.NewBuffer(make([]byte, 0, 1024)),
+		CompressorOutput:  bytes.NewBuffer(make([]byte, 0, 1024)),
+		PrecompressionBuf: bytes.NewBuffer(make([]byte, 0, 1024)),
+		Compressor:        compressor,
 	}
 }
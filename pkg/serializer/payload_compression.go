@@ -0,0 +1,86 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package serializer
+
+import (
+	"net/http"
+
+	"github.com/DataDog/datadog-agent/pkg/forwarder"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
+)
+
+// payloadCompression is the compression of the payloads with the extra headers
+// to send along the compressed payloads.
+type payloadCompression struct {
+	compressor           compression.Compressor
+	jsonExtraHeaders     http.Header
+	protobufExtraHeaders http.Header
+}
+
+func newPayloadCompression(compressor compression.Compressor) *payloadCompression {
+	c := &payloadCompression{
+		compressor:           compressor,
+		jsonExtraHeaders:     jsonExtraHeaders.Clone(),
+		protobufExtraHeaders: protobufExtraHeaders.Clone(),
+	}
+	if contentEncoding := compressor.ContentEncoding(); contentEncoding != "" {
+		c.jsonExtraHeaders.Set("Content-Encoding", contentEncoding)
+		c.protobufExtraHeaders.Set("Content-Encoding", contentEncoding)
+	}
+	return c
+}
+
+// compressedForwarder is a forwarder with the compression of the payloads submitted to it.
+type compressedForwarder struct {
+	forwarder   forwarder.Forwarder
+	compression *payloadCompression
+}
+
+// newCompressedForwarders returns the forwarders of the payloads built with each compression used by the
+// domains of `f`: `compressor`, and the compression of each domain with its own compression.
+func newCompressedForwarders(f forwarder.Forwarder, compressor compression.Compressor) []compressedForwarder {
+	domainCompressionForwarder, ok := f.(forwarder.DomainCompressionForwarder)
+	if !ok || len(domainCompressionForwarder.DomainCompressors()) == 0 {
+		return []compressedForwarder{{forwarder: f, compression: newPayloadCompression(compressor)}}
+	}
+
+	forwarders := []compressedForwarder{{
+		forwarder:   domainCompressionForwarder.ForCompressor(nil),
+		compression: newPayloadCompression(compressor),
+	}}
+	for _, domainCompressor := range domainCompressionForwarder.DomainCompressors() {
+		forwarders = append(forwarders, compressedForwarder{
+			forwarder:   domainCompressionForwarder.ForCompressor(domainCompressor),
+			compression: newPayloadCompression(domainCompressor),
+		})
+	}
+	return forwarders
+}
+
+// sendWithEachCompression builds and submits the payloads once per compression with `send`.
+// The payloads are sent with every compression even if some fail, the first error is returned.
+func (s *Serializer) sendWithEachCompression(send func(f forwarder.Forwarder, compression *payloadCompression) error) error {
+	var firstErr error
+	for _, c := range s.compressedForwarders {
+		if err := send(c.forwarder, c.compression); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// newIterableSeries returns an IterableSeries iterating over `series`.
+func newIterableSeries(series metrics.Series) *metrics.IterableSeries {
+	iterableSeries := metrics.NewIterableSeries(func(*metrics.Serie) {}, 200, 4000)
+	go func() {
+		for _, serie := range series {
+			iterableSeries.Append(serie)
+		}
+		iterableSeries.SenderStopped()
+	}()
+	return iterableSeries
+}
@@ -41,10 +41,8 @@ var (
 	// used to serialize to protobuf
 	AgentPayloadVersion string
 
-	jsonExtraHeaders                    http.Header
-	protobufExtraHeaders                http.Header
-	jsonExtraHeadersWithCompression     http.Header
-	protobufExtraHeadersWithCompression http.Header
+	jsonExtraHeaders     http.Header
+	protobufExtraHeaders http.Header
 
 	expvars                                 = expvar.NewMap("serializer")
 	expvarsSendEventsErrItemTooBigs         = expvar.Int{}
@@ -63,24 +61,9 @@ func initExtraHeaders() {
 	jsonExtraHeaders = make(http.Header)
 	jsonExtraHeaders.Set("Content-Type", jsonContentType)
 
-	jsonExtraHeadersWithCompression = make(http.Header)
-	for k := range jsonExtraHeaders {
-		jsonExtraHeadersWithCompression.Set(k, jsonExtraHeaders.Get(k))
-	}
-
 	protobufExtraHeaders = make(http.Header)
 	protobufExtraHeaders.Set("Content-Type", protobufContentType)
 	protobufExtraHeaders.Set(payloadVersionHTTPHeader, AgentPayloadVersion)
-
-	protobufExtraHeadersWithCompression = make(http.Header)
-	for k := range protobufExtraHeaders {
-		protobufExtraHeadersWithCompression.Set(k, protobufExtraHeaders.Get(k))
-	}
-
-	if compression.ContentEncoding != "" {
-		jsonExtraHeadersWithCompression.Set("Content-Encoding", compression.ContentEncoding)
-		protobufExtraHeadersWithCompression.Set("Content-Encoding", compression.ContentEncoding)
-	}
 }
 
 // MetricSerializer represents the interface of method needed by the aggregator to serialize its data
@@ -105,6 +88,10 @@ type Serializer struct {
 
 	seriesJSONPayloadBuilder *stream.JSONPayloadBuilder
 
+	// compressedForwarders are the forwarders of the payloads built with each compression: the serializer
+	// compression, and the compression of each domain of the forwarder with its own compression
+	compressedForwarders []compressedForwarder
+
 	// Those variables allow users to blacklist any kind of payload
 	// from being sent by the agent. This was introduced for
 	// environment where, for example, events or serviceChecks
@@ -129,15 +116,16 @@ func NewSerializer(forwarder forwarder.Forwarder, orchestratorForwarder, contlcy
 		orchestratorForwarder:         orchestratorForwarder,
 		contlcycleForwarder:           contlcycleForwarder,
 		seriesJSONPayloadBuilder:      stream.NewJSONPayloadBuilder(config.Datadog.GetBool("enable_json_stream_shared_compressor_buffers")),
+		compressedForwarders:          newCompressedForwarders(forwarder, compression.NewSerializerCompressor()),
 		enableEvents:                  config.Datadog.GetBool("enable_payloads.events"),
 		enableSeries:                  config.Datadog.GetBool("enable_payloads.series"),
 		enableServiceChecks:           config.Datadog.GetBool("enable_payloads.service_checks"),
 		enableSketches:                config.Datadog.GetBool("enable_payloads.sketches"),
 		enableJSONToV1Intake:          config.Datadog.GetBool("enable_payloads.json_to_v1_intake"),
-		enableJSONStream:              stream.Available && config.Datadog.GetBool("enable_stream_payload_serialization"),
-		enableServiceChecksJSONStream: stream.Available && config.Datadog.GetBool("enable_service_checks_stream_payload_serialization"),
-		enableEventsJSONStream:        stream.Available && config.Datadog.GetBool("enable_events_stream_payload_serialization"),
-		enableSketchProtobufStream:    stream.Available && config.Datadog.GetBool("enable_sketch_stream_payload_serialization"),
+		enableJSONStream:              config.Datadog.GetBool("enable_stream_payload_serialization"),
+		enableServiceChecksJSONStream: config.Datadog.GetBool("enable_service_checks_stream_payload_serialization"),
+		enableEventsJSONStream:        config.Datadog.GetBool("enable_events_stream_payload_serialization"),
+		enableSketchProtobufStream:    config.Datadog.GetBool("enable_sketch_stream_payload_serialization"),
 	}
 
 	if !s.enableEvents {
@@ -162,38 +150,24 @@ func NewSerializer(forwarder forwarder.Forwarder, orchestratorForwarder, contlcy
 func (s Serializer) serializePayload(
 	jsonMarshaler marshaler.JSONMarshaler,
 	protoMarshaler marshaler.ProtoMarshaler,
-	compress bool,
+	compression *payloadCompression,
 	useV1API bool) (forwarder.Payloads, http.Header, error) {
 	if useV1API {
-		return s.serializePayloadJSON(jsonMarshaler, compress)
+		return s.serializePayloadJSON(jsonMarshaler, compression)
 	}
-	return s.serializePayloadProto(protoMarshaler, compress)
+	return s.serializePayloadProto(protoMarshaler, compression)
 }
 
-func (s Serializer) serializePayloadJSON(payload marshaler.JSONMarshaler, compress bool) (forwarder.Payloads, http.Header, error) {
-	var extraHeaders http.Header
-
-	if compress {
-		extraHeaders = jsonExtraHeadersWithCompression
-	} else {
-		extraHeaders = jsonExtraHeaders
-	}
-
-	return s.serializePayloadInternal(payload, compress, extraHeaders, split.JSONMarshalFct)
+func (s Serializer) serializePayloadJSON(payload marshaler.JSONMarshaler, compression *payloadCompression) (forwarder.Payloads, http.Header, error) {
+	return s.serializePayloadInternal(payload, compression, compression.jsonExtraHeaders, split.JSONMarshalFct)
 }
 
-func (s Serializer) serializePayloadProto(payload marshaler.ProtoMarshaler, compress bool) (forwarder.Payloads, http.Header, error) {
-	var extraHeaders http.Header
-	if compress {
-		extraHeaders = protobufExtraHeadersWithCompression
-	} else {
-		extraHeaders = protobufExtraHeaders
-	}
-	return s.serializePayloadInternal(payload, compress, extraHeaders, split.ProtoMarshalFct)
+func (s Serializer) serializePayloadProto(payload marshaler.ProtoMarshaler, compression *payloadCompression) (forwarder.Payloads, http.Header, error) {
+	return s.serializePayloadInternal(payload, compression, compression.protobufExtraHeaders, split.ProtoMarshalFct)
 }
 
-func (s Serializer) serializePayloadInternal(payload marshaler.AbstractMarshaler, compress bool, extraHeaders http.Header, marshalFct split.MarshalFct) (forwarder.Payloads, http.Header, error) {
-	payloads, err := split.Payloads(payload, compress, marshalFct)
+func (s Serializer) serializePayloadInternal(payload marshaler.AbstractMarshaler, compression *payloadCompression, extraHeaders http.Header, marshalFct split.MarshalFct) (forwarder.Payloads, http.Header, error) {
+	payloads, err := split.Payloads(payload, compression.compressor, marshalFct)
 
 	if err != nil {
 		return nil, nil, fmt.Errorf("could not split payload into small enough chunks: %s", err)
@@ -202,14 +176,14 @@ func (s Serializer) serializePayloadInternal(payload marshaler.AbstractMarshaler
 	return payloads, extraHeaders, nil
 }
 
-func (s Serializer) serializeStreamablePayload(payload marshaler.StreamJSONMarshaler, policy stream.OnErrItemTooBigPolicy) (forwarder.Payloads, http.Header, error) {
+func (s Serializer) serializeStreamablePayload(payload marshaler.StreamJSONMarshaler, policy stream.OnErrItemTooBigPolicy, compression *payloadCompression) (forwarder.Payloads, http.Header, error) {
 	adapter := marshaler.NewIterableStreamJSONMarshalerAdapter(payload)
-	return s.serializeIterableStreamablePayload(adapter, policy)
+	return s.serializeIterableStreamablePayload(adapter, policy, compression)
 }
 
-func (s Serializer) serializeIterableStreamablePayload(payload marshaler.IterableStreamJSONMarshaler, policy stream.OnErrItemTooBigPolicy) (forwarder.Payloads, http.Header, error) {
-	payloads, err := s.seriesJSONPayloadBuilder.BuildWithOnErrItemTooBigPolicy(payload, policy)
-	return payloads, jsonExtraHeadersWithCompression, err
+func (s Serializer) serializeIterableStreamablePayload(payload marshaler.IterableStreamJSONMarshaler, policy stream.OnErrItemTooBigPolicy, compression *payloadCompression) (forwarder.Payloads, http.Header, error) {
+	payloads, err := s.seriesJSONPayloadBuilder.BuildWithOnErrItemTooBigPolicy(payload, policy, compression.compressor)
+	return payloads, compression.jsonExtraHeaders, err
 }
 
 // As events are gathered by SourceType, the serialization logic is more complex than for the other serializations.
@@ -222,9 +196,9 @@ func (s Serializer) serializeIterableStreamablePayload(payload marshaler.Iterabl
 //
 // If none of the previous methods work, we fallback to the old serialization method (Serializer.serializePayload).
 func (s Serializer) serializeEventsStreamJSONMarshalerPayload(
-	eventsSerializer metricsserializer.Events, useV1API bool) (forwarder.Payloads, http.Header, error) {
+	eventsSerializer metricsserializer.Events, useV1API bool, compression *payloadCompression) (forwarder.Payloads, http.Header, error) {
 	marshaler := eventsSerializer.CreateSingleMarshaler()
-	eventPayloads, extraHeaders, err := s.serializeStreamablePayload(marshaler, stream.FailOnErrItemTooBig)
+	eventPayloads, extraHeaders, err := s.serializeStreamablePayload(marshaler, stream.FailOnErrItemTooBig, compression)
 
 	if err == stream.ErrItemTooBig {
 		expvarsSendEventsErrItemTooBigs.Add(1)
@@ -232,12 +206,12 @@ func (s Serializer) serializeEventsStreamJSONMarshalerPayload(
 		// Do not use CreateMarshalersBySourceType when there are too many source types (Performance issue).
 		if marshaler.Len() > maxItemCountForCreateMarshalersBySourceType {
 			expvarsSendEventsErrItemTooBigsFallback.Add(1)
-			eventPayloads, extraHeaders, err = s.serializePayload(eventsSerializer, eventsSerializer, true, useV1API)
+			eventPayloads, extraHeaders, err = s.serializePayload(eventsSerializer, eventsSerializer, compression, useV1API)
 		} else {
 			eventPayloads = nil
 			for _, v := range eventsSerializer.CreateMarshalersBySourceType() {
 				var eventPayloadsForSourceType forwarder.Payloads
-				eventPayloadsForSourceType, extraHeaders, err = s.serializeStreamablePayload(v, stream.DropItemOnErrItemTooBig)
+				eventPayloadsForSourceType, extraHeaders, err = s.serializeStreamablePayload(v, stream.DropItemOnErrItemTooBig, compression)
 				if err != nil {
 					return nil, nil, err
 				}
@@ -255,21 +229,23 @@ func (s *Serializer) SendEvents(events metrics.Events) error {
 		return nil
 	}
 
-	var eventPayloads forwarder.Payloads
-	var extraHeaders http.Header
-	var err error
-
 	eventsSerializer := metricsserializer.Events(events)
-	if s.enableEventsJSONStream {
-		eventPayloads, extraHeaders, err = s.serializeEventsStreamJSONMarshalerPayload(eventsSerializer, true)
-	} else {
-		eventPayloads, extraHeaders, err = s.serializePayload(eventsSerializer, eventsSerializer, true, true)
-	}
-	if err != nil {
-		return fmt.Errorf("dropping event payload: %s", err)
-	}
+	return s.sendWithEachCompression(func(f forwarder.Forwarder, compression *payloadCompression) error {
+		var eventPayloads forwarder.Payloads
+		var extraHeaders http.Header
+		var err error
 
-	return s.Forwarder.SubmitV1Intake(eventPayloads, extraHeaders)
+		if s.enableEventsJSONStream {
+			eventPayloads, extraHeaders, err = s.serializeEventsStreamJSONMarshalerPayload(eventsSerializer, true, compression)
+		} else {
+			eventPayloads, extraHeaders, err = s.serializePayload(eventsSerializer, eventsSerializer, compression, true)
+		}
+		if err != nil {
+			return fmt.Errorf("dropping event payload: %s", err)
+		}
+
+		return f.SubmitV1Intake(eventPayloads, extraHeaders)
+	})
 }
 
 // SendServiceChecks serializes a list of serviceChecks and sends the payload to the forwarder
@@ -280,20 +256,22 @@ func (s *Serializer) SendServiceChecks(serviceChecks metrics.ServiceChecks) erro
 	}
 
 	serviceChecksSerializer := metricsserializer.ServiceChecks(serviceChecks)
-	var serviceCheckPayloads forwarder.Payloads
-	var extraHeaders http.Header
-	var err error
-
-	if s.enableServiceChecksJSONStream {
-		serviceCheckPayloads, extraHeaders, err = s.serializeStreamablePayload(serviceChecksSerializer, stream.DropItemOnErrItemTooBig)
-	} else {
-		serviceCheckPayloads, extraHeaders, err = s.serializePayloadJSON(serviceChecksSerializer, true)
-	}
-	if err != nil {
-		return fmt.Errorf("dropping service check payload: %s", err)
-	}
+	return s.sendWithEachCompression(func(f forwarder.Forwarder, compression *payloadCompression) error {
+		var serviceCheckPayloads forwarder.Payloads
+		var extraHeaders http.Header
+		var err error
 
-	return s.Forwarder.SubmitV1CheckRuns(serviceCheckPayloads, extraHeaders)
+		if s.enableServiceChecksJSONStream {
+			serviceCheckPayloads, extraHeaders, err = s.serializeStreamablePayload(serviceChecksSerializer, stream.DropItemOnErrItemTooBig, compression)
+		} else {
+			serviceCheckPayloads, extraHeaders, err = s.serializePayloadJSON(serviceChecksSerializer, compression)
+		}
+		if err != nil {
+			return fmt.Errorf("dropping service check payload: %s", err)
+		}
+
+		return f.SubmitV1CheckRuns(serviceCheckPayloads, extraHeaders)
+	})
 }
 
 // SendIterableSeries serializes a list of series and sends the payload to the forwarder
@@ -303,30 +281,46 @@ func (s *Serializer) SendIterableSeries(series *metrics.IterableSeries) error {
 		return nil
 	}
 
-	seriesSerializer := metricsserializer.IterableSeries{IterableSeries: series}
 	useV1API := !config.Datadog.GetBool("use_v2_api.series")
 
-	var seriesPayloads forwarder.Payloads
-	var extraHeaders http.Header
-	var err error
-
-	if useV1API && s.enableJSONStream {
-		seriesPayloads, extraHeaders, err = s.serializeIterableStreamablePayload(seriesSerializer, stream.DropItemOnErrItemTooBig)
-	} else if useV1API && !s.enableJSONStream {
-		seriesPayloads, extraHeaders, err = s.serializePayloadJSON(seriesSerializer, true)
-	} else {
-		seriesPayloads, err = seriesSerializer.MarshalSplitCompress(marshaler.DefaultBufferContext())
-		extraHeaders = protobufExtraHeadersWithCompression
+	// The series can be iterated only once, so they are collected to be iterated once per compression.
+	var collectedSeries metrics.Series
+	if len(s.compressedForwarders) > 1 {
+		for series.MoveNext() {
+			collectedSeries = append(collectedSeries, series.Current())
+		}
 	}
 
-	if err != nil {
-		return fmt.Errorf("dropping series payload: %s", err)
-	}
+	return s.sendWithEachCompression(func(f forwarder.Forwarder, compression *payloadCompression) error {
+		seriesSerializer := metricsserializer.IterableSeries{IterableSeries: series}
+		if collectedSeries != nil {
+			seriesSerializer.IterableSeries = newIterableSeries(collectedSeries)
+			// Stops the goroutine appending the series when the serialization fails
+			defer seriesSerializer.IterationStopped()
+		}
 
-	if useV1API {
-		return s.Forwarder.SubmitV1Series(seriesPayloads, extraHeaders)
-	}
-	return s.Forwarder.SubmitSeries(seriesPayloads, extraHeaders)
+		var seriesPayloads forwarder.Payloads
+		var extraHeaders http.Header
+		var err error
+
+		if useV1API && s.enableJSONStream {
+			seriesPayloads, extraHeaders, err = s.serializeIterableStreamablePayload(seriesSerializer, stream.DropItemOnErrItemTooBig, compression)
+		} else if useV1API && !s.enableJSONStream {
+			seriesPayloads, extraHeaders, err = s.serializePayloadJSON(seriesSerializer, compression)
+		} else {
+			seriesPayloads, err = seriesSerializer.MarshalSplitCompress(marshaler.NewBufferContext(compression.compressor))
+			extraHeaders = compression.protobufExtraHeaders
+		}
+
+		if err != nil {
+			return fmt.Errorf("dropping series payload: %s", err)
+		}
+
+		if useV1API {
+			return f.SubmitV1Series(seriesPayloads, extraHeaders)
+		}
+		return f.SubmitSeries(seriesPayloads, extraHeaders)
+	})
 }
 
 // SendSketch serializes a list of SketSeriesList and sends the payload to the forwarder
@@ -336,57 +330,60 @@ func (s *Serializer) SendSketch(sketches metrics.SketchSeriesList) error {
 		return nil
 	}
 	sketchesSerializer := metricsserializer.SketchSeriesList(sketches)
-	if s.enableSketchProtobufStream {
-		payloads, err := sketchesSerializer.MarshalSplitCompress(marshaler.DefaultBufferContext())
-		if err == nil {
-			return s.Forwarder.SubmitSketchSeries(payloads, protobufExtraHeadersWithCompression)
+	return s.sendWithEachCompression(func(f forwarder.Forwarder, compression *payloadCompression) error {
+		if s.enableSketchProtobufStream {
+			payloads, err := sketchesSerializer.MarshalSplitCompress(marshaler.NewBufferContext(compression.compressor))
+			if err == nil {
+				return f.SubmitSketchSeries(payloads, compression.protobufExtraHeaders)
+			}
+			log.Warnf("Error: %v trying to stream compress SketchSeriesList - falling back to split/compress method", err)
 		}
-		log.Warnf("Error: %v trying to stream compress SketchSeriesList - falling back to split/compress method", err)
-	}
 
-	compress := true
-	useV1API := false // Sketches only have a v2 endpoint
-	splitSketches, extraHeaders, err := s.serializePayload(sketchesSerializer, sketchesSerializer, compress, useV1API)
-	if err != nil {
-		return fmt.Errorf("dropping sketch payload: %s", err)
-	}
+		useV1API := false // Sketches only have a v2 endpoint
+		splitSketches, extraHeaders, err := s.serializePayload(sketchesSerializer, sketchesSerializer, compression, useV1API)
+		if err != nil {
+			return fmt.Errorf("dropping sketch payload: %s", err)
+		}
 
-	return s.Forwarder.SubmitSketchSeries(splitSketches, extraHeaders)
+		return f.SubmitSketchSeries(splitSketches, extraHeaders)
+	})
 }
 
 // SendMetadata serializes a metadata payload and sends it to the forwarder
 func (s *Serializer) SendMetadata(m marshaler.JSONMarshaler) error {
-	return s.sendMetadata(m, s.Forwarder.SubmitMetadata)
+	return s.sendMetadata(m, forwarder.Forwarder.SubmitMetadata)
 }
 
 // SendHostMetadata serializes a metadata payload and sends it to the forwarder
 func (s *Serializer) SendHostMetadata(m marshaler.JSONMarshaler) error {
-	return s.sendMetadata(m, s.Forwarder.SubmitHostMetadata)
+	return s.sendMetadata(m, forwarder.Forwarder.SubmitHostMetadata)
 }
 
 // SendAgentchecksMetadata serializes a metadata payload and sends it to the forwarder
 func (s *Serializer) SendAgentchecksMetadata(m marshaler.JSONMarshaler) error {
-	return s.sendMetadata(m, s.Forwarder.SubmitAgentChecksMetadata)
+	return s.sendMetadata(m, forwarder.Forwarder.SubmitAgentChecksMetadata)
 }
 
-func (s *Serializer) sendMetadata(m marshaler.JSONMarshaler, submit func(payload forwarder.Payloads, extra http.Header) error) error {
-	mustSplit, compressedPayload, payload, err := split.CheckSizeAndSerialize(m, true, split.JSONMarshalFct)
-	if err != nil {
-		return fmt.Errorf("could not determine size of metadata payload: %s", err)
-	}
+func (s *Serializer) sendMetadata(m marshaler.JSONMarshaler, submit func(f forwarder.Forwarder, payload forwarder.Payloads, extra http.Header) error) error {
+	return s.sendWithEachCompression(func(f forwarder.Forwarder, compression *payloadCompression) error {
+		mustSplit, compressedPayload, payload, err := split.CheckSizeAndSerialize(m, compression.compressor, split.JSONMarshalFct)
+		if err != nil {
+			return fmt.Errorf("could not determine size of metadata payload: %s", err)
+		}
 
-	log.Debugf("Sending metadata payload, content: %v", string(payload))
+		log.Debugf("Sending metadata payload, content: %v", string(payload))
 
-	if mustSplit {
-		return fmt.Errorf("metadata payload was too big to send (%d bytes compressed, %d bytes uncompressed), metadata payloads cannot be split", len(compressedPayload), len(payload))
-	}
+		if mustSplit {
+			return fmt.Errorf("metadata payload was too big to send (%d bytes compressed, %d bytes uncompressed), metadata payloads cannot be split", len(compressedPayload), len(payload))
+		}
 
-	if err := submit(forwarder.Payloads{&compressedPayload}, jsonExtraHeadersWithCompression); err != nil {
-		return err
-	}
+		if err := submit(f, forwarder.Payloads{&compressedPayload}, compression.jsonExtraHeaders); err != nil {
+			return err
+		}
 
-	log.Infof("Sent metadata payload, size (raw/compressed): %d/%d bytes.", len(payload), len(compressedPayload))
-	return nil
+		log.Infof("Sent metadata payload, size (raw/compressed): %d/%d bytes.", len(payload), len(compressedPayload))
+		return nil
+	})
 }
 
 // SendProcessesMetadata serializes a payload and sends it to the forwarder.
@@ -401,11 +398,14 @@ func (s *Serializer) SendProcessesMetadata(data interface{}) error {
 	if err != nil {
 		return fmt.Errorf("could not serialize processes metadata payload: %s", err)
 	}
-	compressedPayload, err := compression.Compress(payload)
+	err = s.sendWithEachCompression(func(f forwarder.Forwarder, compression *payloadCompression) error {
+		compressedPayload, err := compression.compressor.Compress(payload)
+		if err != nil {
+			return fmt.Errorf("could not compress processes metadata payload: %s", err)
+		}
+		return f.SubmitV1Intake(forwarder.Payloads{&compressedPayload}, compression.jsonExtraHeaders)
+	})
 	if err != nil {
-		return fmt.Errorf("could not compress processes metadata payload: %s", err)
-	}
-	if err := s.Forwarder.SubmitV1Intake(forwarder.Payloads{&compressedPayload}, jsonExtraHeadersWithCompression); err != nil {
 		return err
 	}
 
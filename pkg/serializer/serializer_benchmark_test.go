@@ -17,6 +17,7 @@ import (
 	metricsserializer "github.com/DataDog/datadog-agent/pkg/serializer/internal/metrics"
 	"github.com/DataDog/datadog-agent/pkg/serializer/internal/stream"
 	"github.com/DataDog/datadog-agent/pkg/serializer/split"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 )
 
 func buildEvents(numberOfEvents int) metricsserializer.Events {
@@ -56,7 +57,7 @@ func benchmarkSplit(b *testing.B, numberOfEvents int) {
 	b.ResetTimer()
 
 	for n := 0; n < b.N; n++ {
-		results, _ = split.Payloads(events, true, split.JSONMarshalFct)
+		results, _ = split.Payloads(events, compression.NewDefaultCompressor(), split.JSONMarshalFct)
 	}
 }
 
@@ -28,17 +28,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/util/compression"
 )
 
-var initialContentEncoding = compression.ContentEncoding
-
-func resetContentEncoding() {
-	compression.ContentEncoding = initialContentEncoding
-	initExtraHeaders()
-}
-
-func TestInitExtraHeadersNoopCompression(t *testing.T) {
-	compression.ContentEncoding = ""
-	defer resetContentEncoding()
-
+func TestInitExtraHeaders(t *testing.T) {
 	initExtraHeaders()
 
 	expected := make(http.Header)
@@ -49,44 +39,44 @@ func TestInitExtraHeadersNoopCompression(t *testing.T) {
 	expected.Set(payloadVersionHTTPHeader, AgentPayloadVersion)
 	expected.Set("Content-Type", protobufContentType)
 	assert.Equal(t, expected, protobufExtraHeaders)
+}
+
+func TestPayloadCompressionNoopCompression(t *testing.T) {
+	compressor, err := compression.NewCompressor(compression.NoneKind, 0)
+	require.NoError(t, err)
+	c := newPayloadCompression(compressor)
 
 	// No "Content-Encoding" header
-	expected = make(http.Header)
+	expected := make(http.Header)
 	expected.Set("Content-Type", jsonContentType)
-	assert.Equal(t, expected, jsonExtraHeadersWithCompression)
+	assert.Equal(t, expected, c.jsonExtraHeaders)
 
 	expected = make(http.Header)
 	expected.Set("Content-Type", protobufContentType)
 	expected.Set(payloadVersionHTTPHeader, AgentPayloadVersion)
-	assert.Equal(t, expected, protobufExtraHeadersWithCompression)
+	assert.Equal(t, expected, c.protobufExtraHeaders)
 }
 
-func TestInitExtraHeadersWithCompression(t *testing.T) {
-	compression.ContentEncoding = "zstd"
-	defer resetContentEncoding()
-
-	initExtraHeaders()
+func TestPayloadCompressionWithCompression(t *testing.T) {
+	compressor, err := compression.NewCompressor(compression.ZlibKind, 0)
+	require.NoError(t, err)
+	c := newPayloadCompression(compressor)
 
+	// "Content-Encoding" header present with correct value
 	expected := make(http.Header)
 	expected.Set("Content-Type", jsonContentType)
-	assert.Equal(t, expected, jsonExtraHeaders)
+	expected.Set("Content-Encoding", "deflate")
+	assert.Equal(t, expected, c.jsonExtraHeaders)
 
 	expected = make(http.Header)
 	expected.Set("Content-Type", protobufContentType)
+	expected.Set("Content-Encoding", "deflate")
 	expected.Set(payloadVersionHTTPHeader, AgentPayloadVersion)
-	assert.Equal(t, expected, protobufExtraHeaders)
-
-	// "Content-Encoding" header present with correct value
-	expected = make(http.Header)
-	expected.Set("Content-Type", jsonContentType)
-	expected.Set("Content-Encoding", compression.ContentEncoding)
-	assert.Equal(t, expected, jsonExtraHeadersWithCompression)
+	assert.Equal(t, expected, c.protobufExtraHeaders)
 
-	expected = make(http.Header)
-	expected.Set("Content-Type", protobufContentType)
-	expected.Set("Content-Encoding", compression.ContentEncoding)
-	expected.Set(payloadVersionHTTPHeader, AgentPayloadVersion)
-	assert.Equal(t, expected, protobufExtraHeadersWithCompression)
+	// The global headers are not modified
+	assert.Empty(t, jsonExtraHeaders.Get("Content-Encoding"))
+	assert.Empty(t, protobufExtraHeaders.Get("Content-Encoding"))
 }
 
 func TestAgentPayloadVersion(t *testing.T) {
@@ -94,6 +84,12 @@ func TestAgentPayloadVersion(t *testing.T) {
 }
 
 var (
+	defaultCompressor = compression.NewDefaultCompressor()
+
+	// the extra headers of the payloads compressed with the default compressor
+	jsonExtraHeadersWithCompression     http.Header
+	protobufExtraHeadersWithCompression http.Header
+
 	jsonPayloads     = forwarder.Payloads{}
 	protobufPayloads = forwarder.Payloads{}
 	jsonHeader       = []byte("{")
@@ -104,6 +100,10 @@ var (
 )
 
 func init() {
+	defaultCompression := newPayloadCompression(defaultCompressor)
+	jsonExtraHeadersWithCompression = defaultCompression.jsonExtraHeaders
+	protobufExtraHeadersWithCompression = defaultCompression.protobufExtraHeaders
+
 	jsonPayloads, _ = mkPayloads(jsonString, true)
 	protobufPayloads, _ = mkPayloads(protobufString, true)
 }
@@ -114,7 +114,7 @@ func (p *testPayload) MarshalJSON() ([]byte, error) { return jsonString, nil }
 func (p *testPayload) Marshal() ([]byte, error)     { return protobufString, nil }
 func (p *testPayload) MarshalSplitCompress(bufferContext *marshaler.BufferContext) ([]*[]byte, error) {
 	payloads := forwarder.Payloads{}
-	payload, err := compression.Compress(protobufString)
+	payload, err := defaultCompressor.Compress(protobufString)
 	if err != nil {
 		return nil, err
 	}
@@ -166,7 +166,7 @@ func mkPayloads(payload []byte, compress bool) (forwarder.Payloads, error) {
 	payloads := forwarder.Payloads{}
 	var err error
 	if compress {
-		payload, err = compression.Compress(payload)
+		payload, err = defaultCompressor.Compress(payload)
 		if err != nil {
 			return nil, err
 		}
@@ -178,7 +178,7 @@ func mkPayloads(payload []byte, compress bool) (forwarder.Payloads, error) {
 func createJSONPayloadMatcher(prefix string) interface{} {
 	return mock.MatchedBy(func(payloads forwarder.Payloads) bool {
 		for _, compressedPayload := range payloads {
-			if payload, err := compression.Decompress(*compressedPayload); err != nil {
+			if payload, err := defaultCompressor.Decompress(*compressedPayload); err != nil {
 				return false
 			} else {
 				if strings.HasPrefix(string(payload), prefix) {
@@ -193,7 +193,7 @@ func createJSONPayloadMatcher(prefix string) interface{} {
 func createProtoPayloadMatcher(content []byte) interface{} {
 	return mock.MatchedBy(func(payloads forwarder.Payloads) bool {
 		for _, compressedPayload := range payloads {
-			if payload, err := compression.Decompress(*compressedPayload); err != nil {
+			if payload, err := defaultCompressor.Decompress(*compressedPayload); err != nil {
 				return false
 			} else {
 				if reflect.DeepEqual(content, payload) {
@@ -351,6 +351,50 @@ func TestSendProcessesMetadata(t *testing.T) {
 	require.NotNil(t, err)
 }
 
+// domainCompressionForwarder is a MockedForwarder with a domain compressing the payloads with its own compression
+type domainCompressionForwarder struct {
+	*forwarder.MockedForwarder
+	domainCompressor compression.Compressor
+	domainForwarder  *forwarder.MockedForwarder
+}
+
+func (f *domainCompressionForwarder) DomainCompressors() []compression.Compressor {
+	return []compression.Compressor{f.domainCompressor}
+}
+
+func (f *domainCompressionForwarder) ForCompressor(compressor compression.Compressor) forwarder.Forwarder {
+	if compressor == f.domainCompressor {
+		return f.domainForwarder
+	}
+	return f.MockedForwarder
+}
+
+func TestSendWithDomainCompression(t *testing.T) {
+	config.Datadog.Set("use_v2_api.series", true)
+	defer config.Datadog.Set("use_v2_api.series", false)
+
+	noneCompressor, err := compression.NewCompressor(compression.NoneKind, 0)
+	require.NoError(t, err)
+	f := &domainCompressionForwarder{
+		MockedForwarder:  &forwarder.MockedForwarder{},
+		domainCompressor: noneCompressor,
+		domainForwarder:  &forwarder.MockedForwarder{},
+	}
+	serie := []byte{10, 8, 10, 6, 10, 4, 104, 111, 115, 116}
+	f.MockedForwarder.On("SubmitSeries", createProtoPayloadMatcher(serie), protobufExtraHeadersWithCompression).Return(nil).Times(1)
+	f.MockedForwarder.On("SubmitMetadata", jsonPayloads, jsonExtraHeadersWithCompression).Return(nil).Times(1)
+	// The payloads of the domain are built with its own compression
+	uncompressedPayloads := forwarder.Payloads{&jsonString}
+	f.domainForwarder.On("SubmitSeries", forwarder.Payloads{&serie}, protobufExtraHeaders).Return(nil).Times(1)
+	f.domainForwarder.On("SubmitMetadata", uncompressedPayloads, jsonExtraHeaders).Return(nil).Times(1)
+
+	s := NewSerializer(f, nil, nil)
+	require.NoError(t, s.SendIterableSeries(metricsserializer.CreateIterableSeries(metrics.Series{&metrics.Serie{}})))
+	require.NoError(t, s.SendMetadata(&testPayload{}))
+	f.MockedForwarder.AssertExpectations(t)
+	f.domainForwarder.AssertExpectations(t)
+}
+
 func TestSendWithDisabledKind(t *testing.T) {
 	mockConfig := config.Mock()
 
@@ -46,6 +46,14 @@ func TestSplitPayloadsSeries(t *testing.T) {
 	})
 }
 
+func getCompressor(compress bool) compression.Compressor {
+	if compress {
+		return compression.NewDefaultCompressor()
+	}
+	c, _ := compression.NewCompressor(compression.NoneKind, 0)
+	return c
+}
+
 func testSplitPayloadsSeries(t *testing.T, numPoints int, compress bool) {
 	testSeries := metricsserializer.Series{}
 	for i := 0; i < numPoints; i++ {
@@ -72,7 +80,7 @@ func testSplitPayloadsSeries(t *testing.T, numPoints int, compress bool) {
 		testSeries = append(testSeries, &point)
 	}
 
-	payloads, err := Payloads(testSeries, compress, JSONMarshalFct)
+	payloads, err := Payloads(testSeries, getCompressor(compress), JSONMarshalFct)
 	require.Nil(t, err)
 
 	originalLength := len(testSeries)
@@ -81,7 +89,7 @@ func testSplitPayloadsSeries(t *testing.T, numPoints int, compress bool) {
 		var s = map[string]metricsserializer.Series{}
 
 		if compress {
-			*payload, err = compression.Decompress(*payload)
+			*payload, err = compression.NewDefaultCompressor().Decompress(*payload)
 			require.Nil(t, err)
 		}
 
@@ -122,7 +130,7 @@ func BenchmarkSplitPayloadsSeries(b *testing.B) {
 	for n := 0; n < b.N; n++ {
 		// always record the result of Payloads to prevent
 		// the compiler eliminating the function call.
-		r, _ = Payloads(testSeries, true, JSONMarshalFct)
+		r, _ = Payloads(testSeries, compression.NewDefaultCompressor(), JSONMarshalFct)
 
 	}
 	// ensure we actually had to split
@@ -184,7 +192,7 @@ func testSplitPayloadsEvents(t *testing.T, numPoints int, compress bool) {
 		testEvent = append(testEvent, &event)
 	}
 
-	payloads, err := Payloads(testEvent, compress, JSONMarshalFct)
+	payloads, err := Payloads(testEvent, getCompressor(compress), JSONMarshalFct)
 	require.Nil(t, err)
 
 	originalLength := len(testEvent)
@@ -193,7 +201,7 @@ func testSplitPayloadsEvents(t *testing.T, numPoints int, compress bool) {
 		var s map[string]interface{}
 
 		if compress {
-			*payload, err = compression.Decompress(*payload)
+			*payload, err = compression.NewDefaultCompressor().Decompress(*payload)
 			require.Nil(t, err)
 		}
 
@@ -249,7 +257,7 @@ func testSplitPayloadsServiceChecks(t *testing.T, numPoints int, compress bool)
 		testServiceChecks = append(testServiceChecks, &sc)
 	}
 
-	payloads, err := Payloads(testServiceChecks, compress, JSONMarshalFct)
+	payloads, err := Payloads(testServiceChecks, getCompressor(compress), JSONMarshalFct)
 	require.Nil(t, err)
 
 	originalLength := len(testServiceChecks)
@@ -258,7 +266,7 @@ func testSplitPayloadsServiceChecks(t *testing.T, numPoints int, compress bool)
 		var s []interface{}
 
 		if compress {
-			*payload, err = compression.Decompress(*payload)
+			*payload, err = compression.NewDefaultCompressor().Decompress(*payload)
 			require.Nil(t, err)
 		}
 
@@ -304,7 +312,7 @@ func testSplitPayloadsSketches(t *testing.T, numPoints int, compress bool) {
 		testSketchSeries[i] = metricsserializer.Makeseries(i)
 	}
 
-	payloads, err := Payloads(testSketchSeries, compress, JSONMarshalFct)
+	payloads, err := Payloads(testSketchSeries, getCompressor(compress), JSONMarshalFct)
 	require.Nil(t, err)
 
 	var splitSketches = []metricsserializer.SketchSeriesList{}
@@ -312,7 +320,7 @@ func testSplitPayloadsSketches(t *testing.T, numPoints int, compress bool) {
 		var s = map[string]metricsserializer.SketchSeriesList{}
 
 		if compress {
-			*payload, err = compression.Decompress(*payload)
+			*payload, err = compression.NewDefaultCompressor().Decompress(*payload)
 			require.Nil(t, err)
 		}
 
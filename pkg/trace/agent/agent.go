@@ -38,10 +38,14 @@ const (
 type Agent struct {
 	Receiver              *api.HTTPReceiver
 	OTLPReceiver          *api.OTLPReceiver
+	JaegerReceiver        *api.JaegerReceiver
 	Concentrator          *stats.Concentrator
 	ClientStatsAggregator *stats.ClientStatsAggregator
 	Blacklister           *filters.Blacklister
 	Replacer              *filters.Replacer
+	Scrubber              *filters.Scrubber
+	SpanRules             *filters.SpanRules
+	SpanMetrics           *filters.SpanMetrics
 	PrioritySampler       *sampler.PrioritySampler
 	ErrorsSampler         *sampler.ErrorsSampler
 	RareSampler           *sampler.RareSampler
@@ -50,6 +54,14 @@ type Agent struct {
 	TraceWriter           *writer.TraceWriter
 	StatsWriter           *writer.StatsWriter
 
+	// TailSampler buffers the traces to sample them once complete. It is nil
+	// unless tail sampling is enabled.
+	TailSampler *TailSampler
+
+	// ServiceMap derives the calls between services from the traces. It is nil
+	// unless the service map is enabled.
+	ServiceMap *ServiceMap
+
 	// obfuscator is used to obfuscate sensitive data from various span
 	// tags based on their type.
 	obfuscator     *obfuscate.Obfuscator
@@ -84,6 +96,9 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig) *Agent {
 		ClientStatsAggregator: stats.NewClientStatsAggregator(conf, statsChan),
 		Blacklister:           filters.NewBlacklister(conf.Ignore["resource"]),
 		Replacer:              filters.NewReplacer(conf.ReplaceTags),
+		Scrubber:              filters.NewScrubber(conf.Obfuscation.SensitiveData),
+		SpanRules:             filters.NewSpanRules(conf.SpanRules),
+		SpanMetrics:           filters.NewSpanMetrics(conf.SpanMetrics, conf.SpanMetricsMaxCardinality),
 		PrioritySampler:       sampler.NewPrioritySampler(conf, dynConf),
 		ErrorsSampler:         sampler.NewErrorsSampler(conf),
 		RareSampler:           sampler.NewRareSampler(),
@@ -97,8 +112,15 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig) *Agent {
 		conf:                  conf,
 		ctx:                   ctx,
 	}
+	if conf.TailSampling.Enabled {
+		agnt.TailSampler = NewTailSampler(&conf.TailSampling, agnt.releaseTailChunk)
+	}
+	if conf.ServiceMap.Enabled {
+		agnt.ServiceMap = NewServiceMap(&conf.ServiceMap)
+	}
 	agnt.Receiver = api.NewHTTPReceiver(conf, dynConf, in, agnt)
 	agnt.OTLPReceiver = api.NewOTLPReceiver(in, conf)
+	agnt.JaegerReceiver = api.NewJaegerReceiver(in, conf)
 	return agnt
 }
 
@@ -113,9 +135,16 @@ func (a *Agent) Run() {
 		a.NoPrioritySampler,
 		a.EventProcessor,
 		a.OTLPReceiver,
+		a.JaegerReceiver,
 	} {
 		starter.Start()
 	}
+	if a.TailSampler != nil {
+		a.TailSampler.Start()
+	}
+	if a.ServiceMap != nil {
+		a.ServiceMap.Start()
+	}
 
 	go a.TraceWriter.Run()
 	go a.StatsWriter.Run()
@@ -166,6 +195,13 @@ func (a *Agent) loop() {
 			if err := a.Receiver.Stop(); err != nil {
 				log.Error(err)
 			}
+			if a.TailSampler != nil {
+				// decide the buffered traces while the trace writer is still running
+				a.TailSampler.Stop()
+			}
+			if a.ServiceMap != nil {
+				a.ServiceMap.Stop()
+			}
 			for _, stopper := range []interface{ Stop() }{
 				a.Concentrator,
 				a.ClientStatsAggregator,
@@ -177,6 +213,7 @@ func (a *Agent) loop() {
 				a.RareSampler,
 				a.EventProcessor,
 				a.OTLPReceiver,
+				a.JaegerReceiver,
 				a.obfuscator,
 				a.obfuscator,
 				a.cardObfuscator,
@@ -239,6 +276,25 @@ func (a *Agent) Process(p *api.Payload) {
 			continue
 		}
 
+		if !a.SpanRules.Empty() {
+			switch action, rate := a.SpanRules.Apply(chunk.Spans); action {
+			case filters.RuleActionDrop:
+				log.Debugf("Trace rejected by span rules. root: %v", root)
+				atomic.AddInt64(&ts.TracesFiltered, 1)
+				atomic.AddInt64(&ts.SpansFiltered, tracen)
+				p.RemoveChunk(i)
+				continue
+			case filters.RuleActionKeep:
+				chunk.Priority = int32(sampler.PriorityUserKeep)
+			case filters.RuleActionSample:
+				if sampler.SampleByRate(root.TraceID, rate) {
+					chunk.Priority = int32(sampler.PriorityUserKeep)
+				} else {
+					chunk.Priority = int32(sampler.PriorityUserDrop)
+				}
+			}
+		}
+
 		// Extra sanitization steps of the trace.
 		for _, span := range chunk.Spans {
 			for k, v := range a.conf.GlobalTags {
@@ -258,6 +314,10 @@ func (a *Agent) Process(p *api.Payload) {
 			}
 		}
 		a.Replacer.Replace(chunk.Spans)
+		a.Scrubber.Scrub(chunk.Spans)
+		if !a.SpanMetrics.Empty() {
+			a.SpanMetrics.Compute(root, chunk.Spans)
+		}
 
 		{
 			// this section sets up any necessary tags on the root:
@@ -297,6 +357,16 @@ func (a *Agent) Process(p *api.Payload) {
 		if !p.ClientComputedStats {
 			statsInput.Traces = append(statsInput.Traces, pt)
 		}
+		if a.ServiceMap != nil {
+			a.ServiceMap.Add(pt)
+		}
+
+		if a.TailSampler != nil {
+			// The chunk is sent by the tail sampler once its trace is decided.
+			a.tailSample(now, ts, p.TracerPayload, pt)
+			p.RemoveChunk(i)
+			continue
+		}
 
 		numEvents, keep, filteredChunk := a.sample(now, ts, pt)
 		if !keep {
@@ -314,6 +384,9 @@ func (a *Agent) Process(p *api.Payload) {
 		if !chunk.DroppedTrace {
 			ss.SpanCount += int64(len(chunk.Spans))
 		}
+		if keep {
+			a.Receiver.LocalTraces.Add(p.TracerPayload, chunk)
+		}
 		ss.EventCount += numEvents
 		ss.Size += chunk.Msgsize()
 		i++
@@ -372,6 +445,7 @@ func (a *Agent) processStats(in pb.ClientStatsPayload, lang, tracerVersion strin
 			}
 			a.obfuscateStatsGroup(&b)
 			a.Replacer.ReplaceStatsGroup(&b)
+			a.Scrubber.ScrubStatsGroup(&b)
 			group.Stats[n] = b
 			n++
 		}
@@ -433,6 +507,84 @@ func (a *Agent) sample(now time.Time, ts *info.TagStats, pt traceutil.ProcessedT
 	return numEvents, sampled, filteredChunk
 }
 
+// tailSample hands pt to the tail sampler, unless its sampling priority drops it.
+func (a *Agent) tailSample(now time.Time, ts *info.TagStats, tp *pb.TracerPayload, pt traceutil.ProcessedTrace) {
+	priority, hasPriority := sampler.GetSamplingPriority(pt.TraceChunk)
+	if hasPriority {
+		ts.TracesPerSamplingPriority.CountSamplingPriority(priority)
+	} else {
+		atomic.AddInt64(&ts.TracesPriorityNone, 1)
+	}
+	if priority < 0 {
+		return
+	}
+	if hasPriority {
+		// The decision is the tail sampler's, but the PrioritySampler still counts the trace
+		// to keep updating the rates sent back to the tracers.
+		a.PrioritySampler.Sample(now, pt.TraceChunk, pt.Root, pt.TracerEnv, pt.ClientDroppedP0sWeight)
+	}
+
+	header := *tp
+	header.Chunks = nil
+	chunk, root := copyTailChunk(pt.TraceChunk, pt.Root)
+	a.TailSampler.Add(now, &tailChunk{
+		header: &header,
+		chunk:  chunk,
+		root:   root,
+		source: ts,
+	})
+}
+
+// copyTailChunk returns a copy of chunk whose spans have their own metrics, along with the
+// copy of its root. The chunk is still read by the Concentrator while the tail sampler
+// extracts its events, which sets metrics on its spans.
+func copyTailChunk(chunk *pb.TraceChunk, root *pb.Span) (*pb.TraceChunk, *pb.Span) {
+	c := *chunk
+	c.Spans = make([]*pb.Span, len(chunk.Spans))
+	var rootCopy *pb.Span
+	for i, s := range chunk.Spans {
+		span := *s
+		span.Metrics = make(map[string]float64, len(s.Metrics))
+		for k, v := range s.Metrics {
+			span.Metrics[k] = v
+		}
+		c.Spans[i] = &span
+		if s == root {
+			rootCopy = &span
+		}
+	}
+	return &c, rootCopy
+}
+
+// releaseTailChunk sends c to the trace writer once its trace has been decided by the
+// tail sampler. The chunks of dropped traces are only sent if they have events.
+func (a *Agent) releaseTailChunk(c *tailChunk, keep bool) {
+	chunk := c.chunk
+	if !keep {
+		chunk = new(pb.TraceChunk)
+		*chunk = *c.chunk
+		chunk.DroppedTrace = true
+	}
+	numEvents, numExtracted := a.EventProcessor.Process(c.root, chunk)
+	atomic.AddInt64(&c.source.EventsExtracted, numExtracted)
+	atomic.AddInt64(&c.source.EventsSampled, numEvents)
+	if !keep && numEvents == 0 {
+		return
+	}
+
+	ss := &writer.SampledChunks{
+		TracerPayload: c.header,
+		EventCount:    numEvents,
+		Size:          chunk.Msgsize(),
+	}
+	if keep {
+		ss.SpanCount = int64(len(chunk.Spans))
+		a.Receiver.LocalTraces.Add(c.header, chunk)
+	}
+	ss.TracerPayload.Chunks = []*pb.TraceChunk{chunk}
+	a.TraceWriter.In <- ss
+}
+
 // runSamplers runs all the agent's samplers on pt and returns the sampling decision
 // along with the sampling rate.
 func (a *Agent) runSamplers(now time.Time, pt traceutil.ProcessedTrace, hasPriority bool) bool {
@@ -18,6 +18,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -28,6 +29,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/trace/filters"
 	"github.com/DataDog/datadog-agent/pkg/trace/info"
 	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
 	"github.com/DataDog/datadog-agent/pkg/trace/pb"
 	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
 	"github.com/DataDog/datadog-agent/pkg/trace/stats"
@@ -103,6 +105,94 @@ func TestProcess(t *testing.T) {
 		assert.Equal("SELECT name FROM people WHERE age = ? AND extra = ?", span.Meta["sql.query"])
 	})
 
+	t.Run("Scrubber", func(t *testing.T) {
+		cfg := config.New()
+		cfg.Endpoints[0].APIKey = "test"
+		cfg.Obfuscation.SensitiveData = []*config.SensitiveDataRule{
+			{Builtin: "email"},
+			{Name: "secrets", Keys: []string{"token"}, Action: "hash", HashKey: "s3cr3t"},
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		agnt := NewAgent(ctx, cfg)
+		defer cancel()
+
+		span := &pb.Span{
+			TraceID:  1,
+			SpanID:   1,
+			Resource: "GET /users",
+			Start:    time.Now().Add(-time.Second).UnixNano(),
+			Duration: (500 * time.Millisecond).Nanoseconds(),
+			Meta:     map[string]string{"user": "mail bob@example.com", "auth.token": "42"},
+		}
+		agnt.Process(&api.Payload{
+			TracerPayload: testutil.TracerPayloadWithChunk(testutil.TraceChunkWithSpan(span)),
+			Source:        info.NewReceiverStats().GetTagStats(info.Tags{}),
+		})
+
+		assert.Equal(t, "mail ?", span.Meta["user"])
+		assert.Equal(t, "7b25340b646377b8707a55deb0f09149bfd93b074f7fadbf7316f978f236fb53", span.Meta["auth.token"])
+	})
+
+	t.Run("SpanMetrics", func(t *testing.T) {
+		stats := &testutil.TestStatsClient{}
+		defer func(old metrics.StatsClient) { metrics.Client = old }(metrics.Client)
+		metrics.Client = stats
+
+		cfg := config.New()
+		cfg.Endpoints[0].APIKey = "test"
+		cfg.SpanMetrics = []*config.SpanMetric{
+			{Name: "users.latency", Type: "distribution", Value: "@duration", Resource: "GET /users"},
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		agnt := NewAgent(ctx, cfg)
+		defer cancel()
+
+		span := &pb.Span{
+			TraceID:  1,
+			SpanID:   1,
+			Resource: "GET /users",
+			Start:    time.Now().Add(-time.Second).UnixNano(),
+			Duration: (500 * time.Millisecond).Nanoseconds(),
+		}
+		agnt.Process(&api.Payload{
+			TracerPayload: testutil.TracerPayloadWithChunk(testutil.TraceChunkWithSpan(span)),
+			Source:        info.NewReceiverStats().GetTagStats(info.Tags{}),
+		})
+
+		assert.Equal(t, []testutil.MetricsArgs{{Name: "users.latency", Value: 0.5, Rate: 1}}, stats.DistributionCalls)
+	})
+
+	t.Run("LocalTraces", func(t *testing.T) {
+		cfg := config.New()
+		cfg.Endpoints[0].APIKey = "test"
+		cfg.LocalTraces.Enabled = true
+		ctx, cancel := context.WithCancel(context.Background())
+		agnt := NewAgent(ctx, cfg)
+		defer cancel()
+
+		for id, priority := range map[uint64]sampler.SamplingPriority{1: sampler.PriorityUserKeep, 2: sampler.PriorityUserDrop} {
+			chunk := testutil.TraceChunkWithSpan(&pb.Span{
+				TraceID:  id,
+				SpanID:   1,
+				Service:  "web",
+				Resource: "GET /users",
+				Start:    time.Now().Add(-time.Second).UnixNano(),
+				Duration: (500 * time.Millisecond).Nanoseconds(),
+			})
+			chunk.Priority = int32(priority)
+			agnt.Process(&api.Payload{
+				TracerPayload: testutil.TracerPayloadWithChunk(chunk),
+				Source:        info.NewReceiverStats().GetTagStats(info.Tags{}),
+			})
+		}
+
+		traces := agnt.Receiver.LocalTraces.Search(api.LocalTracesQuery{})
+		require.Len(t, traces, 1)
+		assert.Equal(t, "1", traces[0].TraceID)
+		assert.Equal(t, "web", traces[0].Service)
+		assert.Nil(t, agnt.Receiver.LocalTraces.Get(2))
+	})
+
 	t.Run("Blacklister", func(t *testing.T) {
 		cfg := config.New()
 		cfg.Endpoints[0].APIKey = "test"
@@ -352,6 +442,39 @@ func TestProcess(t *testing.T) {
 		// without missing a trace
 		assert.Equal(t, gotCount, 3)
 	})
+
+	t.Run("SpanRules", func(t *testing.T) {
+		cfg := config.New()
+		cfg.Endpoints[0].APIKey = "test"
+		cfg.SpanRules = []*config.SpanRule{
+			{Name: "health", Resource: "GET /health", Action: "drop"},
+			{Name: "checkout", Service: "checkout", Action: "keep", AddTags: map[string]string{"team": "payments"}},
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		agnt := NewAgent(ctx, cfg)
+		defer cancel()
+
+		health := testutil.TraceChunkWithSpanAndPriority(&pb.Span{TraceID: 1, SpanID: 1, Service: "web", Resource: "GET /health"}, 2)
+		checkout := testutil.TraceChunkWithSpanAndPriority(&pb.Span{TraceID: 2, SpanID: 2, Service: "checkout", Resource: "POST /pay"}, 0)
+		stats := agnt.Receiver.Stats.GetTagStats(info.Tags{})
+		go agnt.Process(&api.Payload{
+			TracerPayload: testutil.TracerPayloadWithChunks([]*pb.TraceChunk{health, checkout}),
+			Source:        stats,
+		})
+
+		timeout := time.After(2 * time.Second)
+		select {
+		case ss := <-agnt.TraceWriter.In:
+			require.Len(t, ss.TracerPayload.Chunks, 1)
+			chunk := ss.TracerPayload.Chunks[0]
+			assert.EqualValues(t, sampler.PriorityUserKeep, chunk.Priority)
+			assert.False(t, chunk.DroppedTrace)
+			assert.Equal(t, "payments", chunk.Spans[0].Meta["team"])
+		case <-timeout:
+			t.Fatal("timed out")
+		}
+		assert.EqualValues(t, 1, atomic.LoadInt64(&stats.TracesFiltered))
+	})
 }
 
 func spansToChunk(spans ...*pb.Span) *pb.TraceChunk {
@@ -892,6 +1015,7 @@ func TestPartialSamplingFree(t *testing.T) {
 		Concentrator:      stats.NewConcentrator(cfg, statsChan, time.Now()),
 		Blacklister:       filters.NewBlacklister(cfg.Ignore["resource"]),
 		Replacer:          filters.NewReplacer(cfg.ReplaceTags),
+		SpanRules:         filters.NewSpanRules(cfg.SpanRules),
 		NoPrioritySampler: sampler.NewNoPrioritySampler(cfg),
 		ErrorsSampler:     sampler.NewErrorsSampler(cfg),
 		PrioritySampler:   sampler.NewPrioritySampler(cfg, &sampler.DynamicConfig{}),
@@ -1344,6 +1468,7 @@ func TestConvertStats(t *testing.T) {
 								Resource:       "SET k v",
 								HTTPStatusCode: 400,
 								Type:           "redis",
+								Tags:           []string{"customer:a@example.com"},
 							},
 						},
 					},
@@ -1375,6 +1500,7 @@ func TestConvertStats(t *testing.T) {
 								Resource:       "SET",
 								HTTPStatusCode: 200,
 								Type:           "redis",
+								Tags:           []string{"customer:?"},
 							},
 						},
 					},
@@ -1386,6 +1512,7 @@ func TestConvertStats(t *testing.T) {
 		Blacklister: filters.NewBlacklister([]string{"blocked_resource"}),
 		obfuscator:  obfuscate.NewObfuscator(obfuscate.Config{}),
 		Replacer:    filters.NewReplacer([]*config.ReplaceRule{{Name: "http.status_code", Pattern: "400", Re: regexp.MustCompile("400"), Repl: "200"}}),
+		Scrubber:    filters.NewScrubber([]*config.SensitiveDataRule{{Builtin: "email"}}),
 		conf:        &config.AgentConfig{DefaultEnv: "agent_env", Hostname: "agent_hostname"},
 	}
 	for _, testCase := range testCases {
@@ -16,16 +16,32 @@ import (
 )
 
 const (
-	tagRedisRawCommand  = "redis.raw_command"
-	tagMemcachedCommand = "memcached.command"
-	tagMongoDBQuery     = "mongodb.query"
-	tagElasticBody      = "elasticsearch.body"
-	tagSQLQuery         = "sql.query"
-	tagHTTPURL          = "http.url"
+	tagRedisRawCommand   = "redis.raw_command"
+	tagMemcachedCommand  = "memcached.command"
+	tagMongoDBQuery      = "mongodb.query"
+	tagElasticBody       = "elasticsearch.body"
+	tagSQLQuery          = "sql.query"
+	tagHTTPURL           = "http.url"
+	tagGraphQLQuery      = "graphql.query"
+	tagGraphQLSource     = "graphql.source"
+	tagDynamoDBStatement = "dynamodb.statement"
 )
 
+// dynamoDBExpressionTags holds the tags of the condition, filter and update
+// expressions of the DynamoDB requests.
+var dynamoDBExpressionTags = []string{
+	"dynamodb.condition_expression",
+	"dynamodb.key_condition_expression",
+	"dynamodb.filter_expression",
+	"dynamodb.update_expression",
+}
+
 const (
-	textNonParsable = "Non-parsable SQL query"
+	textNonParsable        = "Non-parsable SQL query"
+	textNonParsableGraphQL = "Non-parsable GraphQL query"
+	textNonParsablePartiQL = "Non-parsable PartiQL statement"
+
+	textNonParsableDynamoDBExpression = "Non-parsable DynamoDB expression"
 )
 
 func (a *Agent) obfuscateSpan(span *pb.Span) {
@@ -35,7 +51,7 @@ func (a *Agent) obfuscateSpan(span *pb.Span) {
 		if span.Resource == "" {
 			return
 		}
-		oq, err := o.ObfuscateSQLString(span.Resource)
+		oq, err := obfuscateSQLResource(o, span.Type, span.Resource)
 		if err != nil {
 			// we have an error, discard the SQL to avoid polluting user resources.
 			log.Debugf("Error parsing SQL query: %v. Resource: %q", err, span.Resource)
@@ -97,14 +113,71 @@ func (a *Agent) obfuscateSpan(span *pb.Span) {
 			return
 		}
 		span.Meta[tagElasticBody] = o.ObfuscateElasticSearchString(v)
+	case "graphql":
+		if !a.conf.Obfuscation.GraphQL.Enabled {
+			return
+		}
+		if strings.ContainsRune(span.Resource, '{') {
+			// the resource is the query document rather than the operation name
+			span.Resource = obfuscateGraphQL(o, span.Resource)
+		}
+		for _, k := range []string{tagGraphQLQuery, tagGraphQLSource} {
+			if v, ok := span.Meta[k]; ok && v != "" {
+				span.Meta[k] = obfuscateGraphQL(o, v)
+			}
+		}
+	case "dynamodb":
+		if !a.conf.Obfuscation.DynamoDB.Enabled {
+			return
+		}
+		if v, ok := span.Meta[tagDynamoDBStatement]; ok && v != "" {
+			if oq, err := o.ObfuscatePartiQLString(v); err != nil {
+				log.Debugf("Error parsing PartiQL statement: %v. Statement: %q", err, v)
+				span.Meta[tagDynamoDBStatement] = textNonParsablePartiQL
+			} else {
+				span.Meta[tagDynamoDBStatement] = oq.Query
+			}
+		}
+		for _, k := range dynamoDBExpressionTags {
+			v, ok := span.Meta[k]
+			if !ok || v == "" {
+				continue
+			}
+			oe, err := o.ObfuscateDynamoDBExpression(v)
+			if err != nil {
+				log.Debugf("Error parsing DynamoDB expression: %v. Expression: %q", err, v)
+				span.Meta[k] = textNonParsableDynamoDBExpression
+				continue
+			}
+			span.Meta[k] = oe
+		}
+	}
+}
+
+// obfuscateSQLResource obfuscates the resource of a span or stats group of the given type,
+// with the CQL dialect for Cassandra.
+func obfuscateSQLResource(o *obfuscate.Obfuscator, typ, resource string) (*obfuscate.ObfuscatedQuery, error) {
+	if typ == "cassandra" {
+		return o.ObfuscateCQLString(resource)
+	}
+	return o.ObfuscateSQLString(resource)
+}
+
+// obfuscateGraphQL returns the obfuscated GraphQL query document, or a placeholder if it can't be parsed.
+func obfuscateGraphQL(o *obfuscate.Obfuscator, query string) string {
+	out, err := o.ObfuscateGraphQLString(query)
+	if err != nil {
+		log.Debugf("Error parsing GraphQL query: %v. Query: %q", err, query)
+		return textNonParsableGraphQL
 	}
+	return out
 }
 
 func (a *Agent) obfuscateStatsGroup(b *pb.ClientGroupedStats) {
 	o := a.obfuscator
 	switch b.Type {
 	case "sql", "cassandra":
-		oq, err := o.ObfuscateSQLString(b.Resource)
+		oq, err := obfuscateSQLResource(o, b.Type, b.Resource)
 		if err != nil {
 			log.Errorf("Error obfuscating stats group resource %q: %v", b.Resource, err)
 			b.Resource = textNonParsable
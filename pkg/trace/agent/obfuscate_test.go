@@ -63,6 +63,7 @@ func TestObfuscateStatsGroup(t *testing.T) {
 	}{
 		{statsGroup("sql", "SELECT 1 FROM db"), "SELECT ? FROM db"},
 		{statsGroup("sql", "SELECT 1\nFROM Blogs AS [b\nORDER BY [b]"), textNonParsable},
+		{statsGroup("cassandra", "SELECT * FROM t WHERE id = 123e4567-e89b-12d3-a456-426614174000"), "SELECT * FROM t WHERE id = ?"},
 		{statsGroup("redis", "ADD 1, 2"), "ADD"},
 		{statsGroup("other", "ADD 1, 2"), "ADD 1, 2"},
 	} {
@@ -103,6 +104,18 @@ func TestObfuscateDefaults(t *testing.T) {
 		assert.Equal(t, query, span.Meta["sql.query"])
 		assert.Equal(t, "UPDATE users ( name ) SET ( ? )", span.Resource)
 	})
+
+	t.Run("cassandra", func(t *testing.T) {
+		span := &pb.Span{
+			Type:     "cassandra",
+			Resource: "UPDATE ks.users SET emails = emails + {'jim@example.com'} WHERE id = 123e4567-e89b-12d3-a456-426614174000",
+		}
+		agnt, stop := agentWithDefaults()
+		defer stop()
+		agnt.obfuscateSpan(span)
+		assert.Equal(t, "UPDATE ks.users SET emails = emails + ? WHERE id = ?", span.Resource)
+		assert.Equal(t, span.Resource, span.Meta["sql.query"])
+	})
 }
 
 func agentWithDefaults() (agnt *Agent, stop func()) {
@@ -220,6 +233,87 @@ func TestObfuscateConfig(t *testing.T) {
 		"set key 0 0 0 noreply\r\nvalue",
 		&config.ObfuscationConfig{},
 	))
+
+	t.Run("graphql/enabled", testConfig(
+		"graphql",
+		"graphql.query",
+		`query Q { user(email: "jim@example.com") { id } }`,
+		`query Q { user(email: ?) { id } }`,
+		&config.ObfuscationConfig{GraphQL: config.Enablable{Enabled: true}},
+	))
+
+	t.Run("graphql/non-parsable", testConfig(
+		"graphql",
+		"graphql.source",
+		`query Q { user(email: "jim@example.com) { id } }`,
+		textNonParsableGraphQL,
+		&config.ObfuscationConfig{GraphQL: config.Enablable{Enabled: true}},
+	))
+
+	t.Run("graphql/disabled", testConfig(
+		"graphql",
+		"graphql.query",
+		`query Q { user(email: "jim@example.com") { id } }`,
+		`query Q { user(email: "jim@example.com") { id } }`,
+		&config.ObfuscationConfig{},
+	))
+
+	t.Run("dynamodb/enabled", testConfig(
+		"dynamodb",
+		"dynamodb.statement",
+		`SELECT * FROM "Users" WHERE Email = 'jim@example.com'`,
+		`SELECT * FROM Users WHERE Email = ?`,
+		&config.ObfuscationConfig{DynamoDB: config.Enablable{Enabled: true}},
+	))
+
+	t.Run("dynamodb/update_expression", testConfig(
+		"dynamodb",
+		"dynamodb.update_expression",
+		`SET Price = :p, Tags = list_append(Tags, ['sale']) ADD Views 1`,
+		`SET Price = :p, Tags = list_append(Tags, ?) ADD Views ?`,
+		&config.ObfuscationConfig{DynamoDB: config.Enablable{Enabled: true}},
+	))
+
+	t.Run("dynamodb/filter_expression", testConfig(
+		"dynamodb",
+		"dynamodb.filter_expression",
+		`Email = 'jim@example.com' AND Tags[0] <> #t`,
+		`Email = ? AND Tags[0] <> #t`,
+		&config.ObfuscationConfig{DynamoDB: config.Enablable{Enabled: true}},
+	))
+
+	t.Run("dynamodb/non-parsable", testConfig(
+		"dynamodb",
+		"dynamodb.condition_expression",
+		`Email = 'jim@example.com`,
+		textNonParsableDynamoDBExpression,
+		&config.ObfuscationConfig{DynamoDB: config.Enablable{Enabled: true}},
+	))
+
+	t.Run("dynamodb/disabled", testConfig(
+		"dynamodb",
+		"dynamodb.statement",
+		`SELECT * FROM "Users" WHERE Email = 'jim@example.com'`,
+		`SELECT * FROM "Users" WHERE Email = 'jim@example.com'`,
+		&config.ObfuscationConfig{},
+	))
+}
+
+func TestObfuscateGraphQLResource(t *testing.T) {
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+	cfg := config.New()
+	cfg.Endpoints[0].APIKey = "test"
+	cfg.Obfuscation = &config.ObfuscationConfig{GraphQL: config.Enablable{Enabled: true}}
+	agnt := NewAgent(ctx, cfg)
+
+	span := &pb.Span{Type: "graphql", Resource: `{ user(id: 42) { name } }`}
+	agnt.obfuscateSpan(span)
+	assert.Equal(t, `{ user(id: ?) { name } }`, span.Resource)
+
+	span = &pb.Span{Type: "graphql", Resource: "GetUser"}
+	agnt.obfuscateSpan(span)
+	assert.Equal(t, "GetUser", span.Resource)
 }
 
 func SQLSpan(query string) *pb.Span {
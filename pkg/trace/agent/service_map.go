@@ -0,0 +1,245 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agent
+
+import (
+	"container/list"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/watchdog"
+)
+
+// serviceMapFlushPeriod is the frequency at which the service map is published and
+// its metrics are submitted.
+const serviceMapFlushPeriod = 10 * time.Second
+
+// peerTags hold the name of the peer resource called by a client span, by order of preference.
+var peerTags = []string{"peer.service", "out.host", "peer.hostname", "net.peer.name"}
+
+// serviceMapKey identifies an edge of the service map.
+type serviceMapKey struct {
+	env    string
+	client string
+	server string
+	peer   bool
+}
+
+// serviceMapStats holds the calls of an edge of the service map.
+type serviceMapStats struct {
+	key serviceMapKey
+
+	calls    int64
+	errors   int64
+	duration int64
+	lastSeen time.Time
+
+	// calls, errors and duration since the last flush
+	recentCalls    int64
+	recentErrors   int64
+	recentDuration int64
+}
+
+// ServiceMap derives the calls between services from the parent/child relationships of the
+// spans of the traces received, before they are sampled. A call is an edge from the service
+// of a span to the service of its child, or to the peer resource of a client span without
+// traced children. The map is published in the agent info and on /debug/service_map, and
+// the calls of each edge are submitted as metrics.
+type ServiceMap struct {
+	maxEdges int
+	edgeTTL  time.Duration
+
+	mu    sync.Mutex
+	edges map[serviceMapKey]*list.Element
+	// ll holds the *serviceMapStats of the edges, from the most to the least recently seen.
+	ll      *list.List
+	evicted int64 // edges evicted since the last flush
+
+	exit   chan struct{}
+	exitWG sync.WaitGroup
+}
+
+// NewServiceMap returns a ServiceMap for conf.
+func NewServiceMap(conf *config.ServiceMapConfig) *ServiceMap {
+	return &ServiceMap{
+		maxEdges: conf.MaxEdges,
+		edgeTTL:  conf.EdgeTTL,
+		edges:    make(map[serviceMapKey]*list.Element),
+		ll:       list.New(),
+		exit:     make(chan struct{}),
+	}
+}
+
+// Start starts publishing the service map and submitting its metrics periodically.
+func (m *ServiceMap) Start() {
+	m.exitWG.Add(1)
+	go func() {
+		defer watchdog.LogOnPanic()
+		defer m.exitWG.Done()
+		ticker := time.NewTicker(serviceMapFlushPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.flush()
+			case <-m.exit:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the ServiceMap, flushing it one last time.
+func (m *ServiceMap) Stop() {
+	close(m.exit)
+	m.exitWG.Wait()
+	m.flush()
+}
+
+// Add adds the calls found in the chunk of pt to the service map.
+func (m *ServiceMap) Add(pt traceutil.ProcessedTrace) {
+	spans := pt.TraceChunk.Spans
+	if len(spans) == 0 {
+		return
+	}
+	byID := make(map[uint64]*pb.Span, len(spans))
+	for _, s := range spans {
+		byID[s.SpanID] = s
+	}
+	// spans calling a traced service, not to be counted again as calling a peer resource
+	remote := make(map[uint64]struct{})
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// taken with m.mu held to keep m.ll ordered by lastSeen
+	now := time.Now()
+	for _, s := range spans {
+		parent, ok := byID[s.ParentID]
+		if !ok || parent == s || parent.Service == s.Service {
+			continue
+		}
+		remote[parent.SpanID] = struct{}{}
+		m.addCall(serviceMapKey{env: pt.TracerEnv, client: parent.Service, server: s.Service}, s, now)
+	}
+	for _, s := range spans {
+		if _, ok := remote[s.SpanID]; ok {
+			continue
+		}
+		if peer := peerOf(s); peer != "" {
+			m.addCall(serviceMapKey{env: pt.TracerEnv, client: s.Service, server: peer, peer: true}, s, now)
+		}
+	}
+}
+
+// addCall adds the call made by s to the edge k. Once maxEdges is reached, a new edge
+// replaces the least recently seen one. m.mu must be held.
+func (m *ServiceMap) addCall(k serviceMapKey, s *pb.Span, now time.Time) {
+	var e *serviceMapStats
+	if el, ok := m.edges[k]; ok {
+		m.ll.MoveToFront(el)
+		e = el.Value.(*serviceMapStats)
+	} else {
+		if m.maxEdges > 0 && m.ll.Len() >= m.maxEdges {
+			m.evict(m.ll.Back())
+		}
+		e = &serviceMapStats{key: k}
+		m.edges[k] = m.ll.PushFront(e)
+	}
+	e.calls++
+	e.recentCalls++
+	e.duration += s.Duration
+	e.recentDuration += s.Duration
+	if s.Error != 0 {
+		e.errors++
+		e.recentErrors++
+	}
+	e.lastSeen = now
+}
+
+// evict removes the edge of el from the service map. m.mu must be held.
+func (m *ServiceMap) evict(el *list.Element) {
+	e := m.ll.Remove(el).(*serviceMapStats)
+	delete(m.edges, e.key)
+	m.evicted++
+}
+
+// peerOf returns the peer resource called by s if it is a client span, and "" otherwise.
+func peerOf(s *pb.Span) string {
+	if kind := s.Meta["span.kind"]; kind != "client" && kind != "producer" {
+		return ""
+	}
+	for _, k := range peerTags {
+		if v := s.Meta[k]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// flush submits the metrics of the calls since the last flush, evicts the edges not seen
+// within edgeTTL and publishes the service map.
+func (m *ServiceMap) flush() {
+	m.mu.Lock()
+	edges := make([]info.ServiceMapEdge, 0, len(m.edges))
+	cutoff := time.Now().Add(-m.edgeTTL)
+	var next *list.Element
+	for el := m.ll.Front(); el != nil; el = next {
+		next = el.Next()
+		e := el.Value.(*serviceMapStats)
+		k := e.key
+		if e.recentCalls > 0 {
+			tags := []string{"env:" + k.env, "client:" + k.client, "server:" + k.server, "edge_type:service"}
+			if k.peer {
+				tags[3] = "edge_type:peer"
+			}
+			metrics.Count("datadog.trace_agent.service_map.calls", e.recentCalls, tags, 1)
+			metrics.Count("datadog.trace_agent.service_map.errors", e.recentErrors, tags, 1)
+			metrics.Gauge("datadog.trace_agent.service_map.avg_duration", time.Duration(e.recentDuration/e.recentCalls).Seconds(), tags, 1)
+			e.recentCalls, e.recentErrors, e.recentDuration = 0, 0, 0
+		}
+		if m.edgeTTL > 0 && e.lastSeen.Before(cutoff) {
+			m.evict(el)
+			continue
+		}
+		edges = append(edges, info.ServiceMapEdge{
+			Env:         k.env,
+			Client:      k.client,
+			Server:      k.server,
+			Peer:        k.peer,
+			Calls:       e.calls,
+			Errors:      e.errors,
+			AvgDuration: e.duration / e.calls,
+			LastSeen:    e.lastSeen,
+		})
+	}
+	evicted := m.evicted
+	m.evicted = 0
+	m.mu.Unlock()
+
+	metrics.Gauge("datadog.trace_agent.service_map.edges", float64(len(edges)), nil, 1)
+	if evicted > 0 {
+		metrics.Count("datadog.trace_agent.service_map.evicted_edges", evicted, nil, 1)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		a, b := edges[i], edges[j]
+		if a.Env != b.Env {
+			return a.Env < b.Env
+		}
+		if a.Client != b.Client {
+			return a.Client < b.Client
+		}
+		if a.Server != b.Server {
+			return a.Server < b.Server
+		}
+		return !a.Peer && b.Peer
+	})
+	info.UpdateServiceMap(edges)
+}
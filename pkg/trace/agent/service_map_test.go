@@ -0,0 +1,115 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/testutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testServiceMapTrace() traceutil.ProcessedTrace {
+	ms := int64(time.Millisecond)
+	spans := []*pb.Span{
+		{SpanID: 1, Service: "web", Name: "http.request", Duration: 100 * ms},
+		{SpanID: 2, ParentID: 1, Service: "web", Name: "http.client", Duration: 60 * ms, Meta: map[string]string{"span.kind": "client", "peer.service": "users"}},
+		{SpanID: 3, ParentID: 2, Service: "users", Name: "grpc.server", Duration: 50 * ms, Error: 1},
+		{SpanID: 4, ParentID: 3, Service: "users", Name: "postgres.query", Duration: 10 * ms, Meta: map[string]string{"span.kind": "client", "out.host": "pg-main"}},
+		{SpanID: 5, ParentID: 3, Service: "users", Name: "postgres.query", Duration: 20 * ms, Meta: map[string]string{"span.kind": "client", "out.host": "pg-main"}},
+		{SpanID: 6, ParentID: 1, Service: "web", Name: "redis.command", Duration: 1 * ms, Meta: map[string]string{"span.kind": "client"}},
+	}
+	return traceutil.ProcessedTrace{
+		TraceChunk: &pb.TraceChunk{Spans: spans},
+		Root:       spans[0],
+		TracerEnv:  "prod",
+	}
+}
+
+func TestServiceMap(t *testing.T) {
+	stats := &testutil.TestStatsClient{}
+	defer func(old metrics.StatsClient) { metrics.Client = old }(metrics.Client)
+	metrics.Client = stats
+	defer info.UpdateServiceMap(nil)
+
+	t.Run("edges", func(t *testing.T) {
+		assert := assert.New(t)
+		m := NewServiceMap(&config.ServiceMapConfig{MaxEdges: 10})
+		m.Add(testServiceMapTrace())
+		m.Add(testServiceMapTrace())
+		m.flush()
+
+		edges := info.ServiceMap()
+		for i := range edges {
+			assert.False(edges[i].LastSeen.IsZero())
+			edges[i].LastSeen = time.Time{}
+		}
+		assert.Equal([]info.ServiceMapEdge{
+			{Env: "prod", Client: "users", Server: "pg-main", Peer: true, Calls: 4, AvgDuration: int64(15 * time.Millisecond)},
+			{Env: "prod", Client: "web", Server: "users", Calls: 2, Errors: 2, AvgDuration: int64(50 * time.Millisecond)},
+		}, edges)
+
+		counts := stats.GetCountSummaries()
+		assert.EqualValues(6, counts["datadog.trace_agent.service_map.calls"].Sum)
+		assert.EqualValues(2, counts["datadog.trace_agent.service_map.errors"].Sum)
+		assert.Equal([]string{"env:prod", "client:users", "server:pg-main", "edge_type:peer"}, peerCallTags(counts["datadog.trace_agent.service_map.calls"]))
+
+		// nothing new is submitted until new calls are observed
+		stats.Reset()
+		m.flush()
+		assert.NotContains(stats.GetCountSummaries(), "datadog.trace_agent.service_map.calls")
+		assert.Len(info.ServiceMap(), 2)
+	})
+
+	t.Run("max-edges", func(t *testing.T) {
+		assert := assert.New(t)
+		stats.Reset()
+		m := NewServiceMap(&config.ServiceMapConfig{MaxEdges: 1})
+		m.Add(testServiceMapTrace())
+		m.flush()
+		// the peer edge, added last, replaces the least recently seen edge
+		edges := info.ServiceMap()
+		assert.Len(edges, 1)
+		assert.Equal("pg-main", edges[0].Server)
+		assert.EqualValues(1, stats.GetCountSummaries()["datadog.trace_agent.service_map.evicted_edges"].Sum)
+	})
+
+	t.Run("edge-ttl", func(t *testing.T) {
+		assert := assert.New(t)
+		stats.Reset()
+		m := NewServiceMap(&config.ServiceMapConfig{MaxEdges: 10, EdgeTTL: time.Minute})
+		m.Add(testServiceMapTrace())
+		m.mu.Lock()
+		m.edges[serviceMapKey{env: "prod", client: "web", server: "users"}].Value.(*serviceMapStats).lastSeen = time.Now().Add(-2 * time.Minute)
+		m.mu.Unlock()
+		m.flush()
+		edges := info.ServiceMap()
+		assert.Len(edges, 1)
+		assert.Equal("pg-main", edges[0].Server)
+		// the calls of the evicted edge since the last flush are still submitted
+		assert.EqualValues(3, stats.GetCountSummaries()["datadog.trace_agent.service_map.calls"].Sum)
+		assert.EqualValues(1, stats.GetCountSummaries()["datadog.trace_agent.service_map.evicted_edges"].Sum)
+	})
+}
+
+// peerCallTags returns the tags of the calls to a peer resource in c.
+func peerCallTags(c *testutil.CountSummary) []string {
+	for _, call := range c.Calls {
+		for _, tag := range call.Tags {
+			if tag == "edge_type:peer" {
+				return call.Tags
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agent
+
+import (
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+	"github.com/DataDog/datadog-agent/pkg/trace/watchdog"
+)
+
+// tailChunk is a chunk buffered by the TailSampler along with what is needed
+// to send it once the decision is taken.
+type tailChunk struct {
+	// header is a copy of the payload the chunk was received in, without its chunks.
+	header *pb.TracerPayload
+	chunk  *pb.TraceChunk
+	root   *pb.Span
+	source *info.TagStats
+}
+
+// tailTrace holds the chunks of a trace received so far.
+type tailTrace struct {
+	firstSeen time.Time
+	chunks    []*tailChunk
+}
+
+// tailDecision is a decision taken recently, applied to the late chunks of its trace.
+type tailDecision struct {
+	traceID uint64
+	keep    bool
+	at      time.Time
+}
+
+// TailSampler buffers the chunks of each trace for a configured time and then decides
+// whether to keep the whole trace with its policies. The chunks are then handed to
+// release with the decision.
+type TailSampler struct {
+	decisionWait time.Duration
+	maxTraces    int
+	policies     []sampler.TailPolicy
+	release      func(c *tailChunk, keep bool)
+
+	mu        sync.Mutex
+	traces    map[uint64]*tailTrace
+	order     []uint64 // buffered trace IDs, oldest first
+	decisions map[uint64]bool
+	recent    []tailDecision // recent decisions, oldest first
+
+	exit   chan struct{}
+	exitWG sync.WaitGroup
+}
+
+// NewTailSampler returns a TailSampler for conf calling release on each chunk once
+// its trace is decided. Invalid policies are reported and ignored.
+func NewTailSampler(conf *config.TailSamplingConfig, release func(c *tailChunk, keep bool)) *TailSampler {
+	policies := make([]sampler.TailPolicy, 0, len(conf.Policies))
+	for _, pconf := range conf.Policies {
+		p, err := sampler.NewTailPolicy(pconf)
+		if err != nil {
+			log.Errorf("Ignoring invalid tail sampling policy: %v", err)
+			continue
+		}
+		policies = append(policies, p)
+	}
+	if len(policies) == 0 {
+		log.Warn("Tail sampling is enabled without any valid policy, only the traces kept by the user will be sent.")
+	}
+	return &TailSampler{
+		decisionWait: conf.DecisionWait,
+		maxTraces:    conf.MaxTraces,
+		policies:     policies,
+		release:      release,
+		traces:       make(map[uint64]*tailTrace),
+		decisions:    make(map[uint64]bool),
+		exit:         make(chan struct{}),
+	}
+}
+
+// Start starts deciding the traces buffered for longer than the decision wait.
+func (s *TailSampler) Start() {
+	period := time.Second
+	if s.decisionWait < period {
+		period = s.decisionWait
+	}
+	s.exitWG.Add(1)
+	go func() {
+		defer watchdog.LogOnPanic()
+		defer s.exitWG.Done()
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				s.flush(now, false)
+			case <-s.exit:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the TailSampler and decides all the buffered traces.
+func (s *TailSampler) Stop() {
+	close(s.exit)
+	s.exitWG.Wait()
+	s.flush(time.Now(), true)
+}
+
+// Add buffers c until its trace is decided. The chunks of a trace decided recently
+// are released right away with the same decision.
+func (s *TailSampler) Add(now time.Time, c *tailChunk) {
+	traceID := c.chunk.Spans[0].TraceID
+
+	s.mu.Lock()
+	if keep, ok := s.decisions[traceID]; ok {
+		s.mu.Unlock()
+		s.release(c, keep)
+		return
+	}
+	t, ok := s.traces[traceID]
+	if !ok {
+		t = &tailTrace{firstSeen: now}
+		s.traces[traceID] = t
+		s.order = append(s.order, traceID)
+	}
+	t.chunks = append(t.chunks, c)
+
+	var evicted []*tailChunk
+	var keep bool
+	if len(s.traces) > s.maxTraces {
+		metrics.Count("datadog.trace_agent.tail_sampler.evicted", 1, nil, 1)
+		evicted, keep = s.decideOldest(now)
+	}
+	s.mu.Unlock()
+
+	for _, c := range evicted {
+		s.release(c, keep)
+	}
+}
+
+// flush decides the traces buffered for longer than the decision wait, or all of
+// them if all is true.
+func (s *TailSampler) flush(now time.Time, all bool) {
+	type decided struct {
+		chunks []*tailChunk
+		keep   bool
+	}
+	var released []decided
+
+	s.mu.Lock()
+	for len(s.order) > 0 {
+		if t := s.traces[s.order[0]]; !all && now.Sub(t.firstSeen) < s.decisionWait {
+			break
+		}
+		chunks, keep := s.decideOldest(now)
+		released = append(released, decided{chunks, keep})
+	}
+	for len(s.recent) > 0 && now.Sub(s.recent[0].at) >= s.decisionWait {
+		delete(s.decisions, s.recent[0].traceID)
+		s.recent = s.recent[1:]
+	}
+	metrics.Gauge("datadog.trace_agent.tail_sampler.buffered_traces", float64(len(s.traces)), nil, 1)
+	s.mu.Unlock()
+
+	for _, d := range released {
+		for _, c := range d.chunks {
+			s.release(c, d.keep)
+		}
+	}
+}
+
+// decideOldest removes the oldest buffered trace and returns its chunks along with
+// the decision. It must be called with s.mu held.
+func (s *TailSampler) decideOldest(now time.Time) (chunks []*tailChunk, keep bool) {
+	traceID := s.order[0]
+	s.order = s.order[1:]
+	t := s.traces[traceID]
+	delete(s.traces, traceID)
+
+	keep = s.decide(traceID, t.chunks)
+	s.decisions[traceID] = keep
+	s.recent = append(s.recent, tailDecision{traceID: traceID, keep: keep, at: now})
+	return t.chunks, keep
+}
+
+// decide reports whether the trace made of chunks must be kept.
+func (s *TailSampler) decide(traceID uint64, chunks []*tailChunk) bool {
+	var spans []*pb.Span
+	var root *pb.Span
+	for _, c := range chunks {
+		if priority, ok := sampler.GetSamplingPriority(c.chunk); ok && priority == sampler.PriorityUserKeep {
+			metrics.Count("datadog.trace_agent.tail_sampler.kept", 1, []string{"policy:user_keep"}, 1)
+			return true
+		}
+		spans = append(spans, c.chunk.Spans...)
+		if c.root.ParentID == 0 || root == nil {
+			root = c.root
+		}
+	}
+	for _, p := range s.policies {
+		if p.Keep(traceID, root, spans) {
+			metrics.Count("datadog.trace_agent.tail_sampler.kept", 1, []string{"policy:" + p.Name()}, 1)
+			return true
+		}
+	}
+	metrics.Count("datadog.trace_agent.tail_sampler.dropped", 1, nil, 1)
+	return false
+}
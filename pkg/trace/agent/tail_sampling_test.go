@@ -0,0 +1,162 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agent
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/api"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type releasedChunk struct {
+	chunk *pb.TraceChunk
+	keep  bool
+}
+
+func newTestTailSampler(conf *config.TailSamplingConfig) (*TailSampler, func() []releasedChunk) {
+	var mu sync.Mutex
+	var released []releasedChunk
+	s := NewTailSampler(conf, func(c *tailChunk, keep bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		released = append(released, releasedChunk{c.chunk, keep})
+	})
+	return s, func() []releasedChunk {
+		mu.Lock()
+		defer mu.Unlock()
+		return released
+	}
+}
+
+func newTestTailChunk(traceID, parentID uint64, err int32) *tailChunk {
+	span := &pb.Span{TraceID: traceID, SpanID: traceID*10 + parentID, ParentID: parentID, Service: "web", Error: err}
+	return &tailChunk{
+		header: &pb.TracerPayload{},
+		chunk:  testutil.TraceChunkWithSpan(span),
+		root:   span,
+		source: info.NewReceiverStats().GetTagStats(info.Tags{}),
+	}
+}
+
+func TestTailSampler(t *testing.T) {
+	conf := &config.TailSamplingConfig{
+		DecisionWait: time.Minute,
+		MaxTraces:    10,
+		Policies:     []*config.TailSamplingPolicy{{Type: "error"}},
+	}
+
+	t.Run("buffers-until-decision-wait", func(t *testing.T) {
+		s, released := newTestTailSampler(conf)
+		now := time.Now()
+		s.Add(now, newTestTailChunk(1, 0, 0))
+		s.Add(now, newTestTailChunk(1, 1, 1))
+		s.Add(now, newTestTailChunk(2, 0, 0))
+
+		s.flush(now.Add(time.Second), false)
+		assert.Empty(t, released())
+
+		s.flush(now.Add(time.Minute), false)
+		require.Len(t, released(), 3)
+		for _, r := range released() {
+			assert.Equal(t, r.chunk.Spans[0].TraceID == 1, r.keep)
+		}
+	})
+
+	t.Run("late-chunks", func(t *testing.T) {
+		s, released := newTestTailSampler(conf)
+		now := time.Now()
+		s.Add(now, newTestTailChunk(1, 1, 1))
+		s.flush(now.Add(time.Minute), false)
+		s.Add(now.Add(time.Minute), newTestTailChunk(1, 0, 0))
+		require.Len(t, released(), 2)
+		assert.True(t, released()[1].keep)
+
+		// the decision is forgotten after the decision wait
+		s.flush(now.Add(2*time.Minute), false)
+		s.Add(now.Add(2*time.Minute), newTestTailChunk(1, 0, 0))
+		assert.Len(t, released(), 2)
+	})
+
+	t.Run("max-traces", func(t *testing.T) {
+		s, released := newTestTailSampler(conf)
+		now := time.Now()
+		for i := uint64(1); i <= 11; i++ {
+			s.Add(now, newTestTailChunk(i, 0, 0))
+		}
+		require.Len(t, released(), 1)
+		assert.EqualValues(t, 1, released()[0].chunk.Spans[0].TraceID)
+		assert.False(t, released()[0].keep)
+	})
+
+	t.Run("user-keep", func(t *testing.T) {
+		s, released := newTestTailSampler(conf)
+		c := newTestTailChunk(1, 0, 0)
+		c.chunk.Priority = 2
+		s.Add(time.Now(), c)
+		s.Stop()
+		require.Len(t, released(), 1)
+		assert.True(t, released()[0].keep)
+	})
+}
+
+func TestProcessTailSampling(t *testing.T) {
+	cfg := config.New()
+	cfg.Endpoints[0].APIKey = "test"
+	cfg.TailSampling.Enabled = true
+	cfg.TailSampling.DecisionWait = 50 * time.Millisecond
+	cfg.TailSampling.Policies = []*config.TailSamplingPolicy{{Type: "latency", ThresholdMs: 100}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	agnt := NewAgent(ctx, cfg)
+	agnt.TailSampler.Start()
+	defer agnt.TailSampler.Stop()
+
+	now := time.Now()
+	root := &pb.Span{TraceID: 1, SpanID: 1, Service: "web", Name: "request", Start: now.UnixNano(), Duration: int64(10 * time.Millisecond)}
+	child := &pb.Span{TraceID: 1, SpanID: 2, ParentID: 1, Service: "db", Name: "query", Start: now.UnixNano(), Duration: int64(200 * time.Millisecond)}
+	fast := &pb.Span{TraceID: 2, SpanID: 3, Service: "web", Name: "request", Start: now.UnixNano(), Duration: int64(10 * time.Millisecond)}
+	for _, span := range []*pb.Span{root, child, fast} {
+		agnt.Process(&api.Payload{
+			TracerPayload: testutil.TracerPayloadWithChunk(testutil.TraceChunkWithSpanAndPriority(span, 1)),
+			Source:        agnt.Receiver.Stats.GetTagStats(info.Tags{}),
+		})
+	}
+	assert.Len(t, agnt.TraceWriter.In, 0)
+	// the PrioritySampler still counts the traces and sets its rate on the roots
+	assert.Contains(t, fast.Metrics, "_sampling_priority_rate_v1")
+
+	var traceIDs []uint64
+	timeout := time.After(2 * time.Second)
+	for len(traceIDs) < 2 {
+		select {
+		case ss := <-agnt.TraceWriter.In:
+			require.Len(t, ss.TracerPayload.Chunks, 1)
+			assert.False(t, ss.TracerPayload.Chunks[0].DroppedTrace)
+			// the spans sent to the Concentrator are not modified by the tail sampler
+			span := ss.TracerPayload.Chunks[0].Spans[0]
+			assert.NotSame(t, root, span)
+			assert.NotSame(t, child, span)
+			traceIDs = append(traceIDs, span.TraceID)
+		case <-timeout:
+			t.Fatal("timed out")
+		}
+	}
+	assert.Equal(t, []uint64{1, 1}, traceIDs)
+	select {
+	case <-agnt.TraceWriter.In:
+		t.Fatal("the fast trace should be dropped")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
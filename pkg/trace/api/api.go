@@ -64,6 +64,9 @@ func putBuffer(buffer *bytes.Buffer) {
 type HTTPReceiver struct {
 	Stats       *info.ReceiverStats
 	RateLimiter *rateLimiter
+	// LocalTraces keeps the recent traces served by the local trace search API. It is
+	// nil unless the API is enabled.
+	LocalTraces *LocalTraces
 
 	out            chan *Payload
 	conf           *config.AgentConfig
@@ -89,9 +92,14 @@ func NewHTTPReceiver(conf *config.AgentConfig, dynConf *sampler.DynamicConfig, o
 	if err != nil {
 		log.Errorf("Could not instantiate AppSec: %v", err)
 	}
+	var localTraces *LocalTraces
+	if conf.LocalTraces.Enabled {
+		localTraces = NewLocalTraces(conf.LocalTraces.MaxTraces, conf.LocalTraces.MaxSpansPerTrace)
+	}
 	return &HTTPReceiver{
 		Stats:       info.NewReceiverStats(),
 		RateLimiter: newRateLimiter(),
+		LocalTraces: localTraces,
 
 		out:            out,
 		statsProcessor: statsProcessor,
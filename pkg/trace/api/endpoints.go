@@ -116,4 +116,32 @@ var endpoints = []Endpoint{
 		Pattern: "/debugger/v1/input",
 		Handler: func(r *HTTPReceiver) http.Handler { return r.debuggerProxyHandler() },
 	},
+	{
+		Pattern:   "/api/v2/spans",
+		Handler:   func(r *HTTPReceiver) http.Handler { return r.handleForeignSpans("zipkin_v2", decodeZipkin) },
+		IsEnabled: func(cfg *config.AgentConfig) bool { return cfg.Zipkin.Enabled },
+	},
+	{
+		Pattern:   "/api/traces",
+		Handler:   func(r *HTTPReceiver) http.Handler { return r.handleForeignSpans("jaeger_thrift", decodeJaegerThrift) },
+		IsEnabled: func(cfg *config.AgentConfig) bool { return cfg.Jaeger.Enabled },
+	},
+	{
+		Pattern:   "/debug/traces",
+		Handler:   func(r *HTTPReceiver) http.Handler { return http.HandlerFunc(r.handleLocalTraces) },
+		Hidden:    true,
+		IsEnabled: func(cfg *config.AgentConfig) bool { return cfg.LocalTraces.Enabled },
+	},
+	{
+		Pattern:   "/debug/traces/",
+		Handler:   func(r *HTTPReceiver) http.Handler { return http.HandlerFunc(r.handleLocalTrace) },
+		Hidden:    true,
+		IsEnabled: func(cfg *config.AgentConfig) bool { return cfg.LocalTraces.Enabled },
+	},
+	{
+		Pattern:   "/debug/service_map",
+		Handler:   func(r *HTTPReceiver) http.Handler { return http.HandlerFunc(handleServiceMap) },
+		Hidden:    true,
+		IsEnabled: func(cfg *config.AgentConfig) bool { return cfg.ServiceMap.Enabled },
+	},
 }
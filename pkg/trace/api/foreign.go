@@ -0,0 +1,221 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/api/apiutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics/timing"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+
+	"go.opentelemetry.io/collector/model/pdata"
+	semconv "go.opentelemetry.io/collector/model/semconv/v1.6.1"
+)
+
+// foreignSpans holds spans received in a foreign tracing format, such as Zipkin or Jaeger,
+// converted to Datadog spans.
+type foreignSpans struct {
+	spans []*pb.Span
+	// debug holds the IDs of the traces flagged for debugging by the client. They are
+	// kept by the user.
+	debug map[uint64]bool
+}
+
+// add adds the span s, flagged for debugging if debug is true.
+func (f *foreignSpans) add(s *pb.Span, debug bool) {
+	f.spans = append(f.spans, s)
+	if debug {
+		if f.debug == nil {
+			f.debug = make(map[uint64]bool)
+		}
+		f.debug[s.TraceID] = true
+	}
+}
+
+// chunks returns the spans grouped by trace. The spans received in foreign formats were
+// sampled by the client, so the traces are auto-kept unless they are kept by the user.
+func (f *foreignSpans) chunks() []*pb.TraceChunk {
+	byID := make(map[uint64][]*pb.Span)
+	for _, s := range f.spans {
+		byID[s.TraceID] = append(byID[s.TraceID], s)
+	}
+	chunks := make([]*pb.TraceChunk, 0, len(byID))
+	for id, spans := range byID {
+		priority := sampler.PriorityAutoKeep
+		if f.debug[id] {
+			priority = sampler.PriorityUserKeep
+		}
+		chunks = append(chunks, &pb.TraceChunk{
+			Priority: int32(priority),
+			Spans:    spans,
+		})
+	}
+	return chunks
+}
+
+// foreignDecoder decodes the body of a request holding spans in a foreign tracing format.
+type foreignDecoder func(req *http.Request, body []byte) (*foreignSpans, error)
+
+// handleForeignSpans returns a handler of the requests holding spans in the foreign tracing
+// format named format, decoded by decode.
+func (r *HTTPReceiver) handleForeignSpans(format string, decode foreignDecoder) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		defer timing.Since("datadog.trace_agent.receiver.serve_"+format+"_ms", time.Now())
+		ts := r.tagStats(Version(format), req.Header)
+		tags := []string{"handler:" + format}
+
+		body, err := readForeignBody(req, r.conf.MaxRequestBytes)
+		if err != nil {
+			httpDecodingError(err, tags, w)
+			return
+		}
+		in, err := decode(req, body)
+		if err != nil {
+			httpDecodingError(err, tags, w)
+			atomic.AddInt64(&ts.TracesDropped.DecodingError, 1)
+			log.Errorf("Cannot decode %s spans: %v", format, err)
+			return
+		}
+		chunks := in.chunks()
+		if r.rateLimited(int64(len(chunks))) {
+			w.WriteHeader(r.rateLimiterResponse)
+			atomic.AddInt64(&ts.PayloadRefused, 1)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+
+		atomic.AddInt64(&ts.TracesReceived, int64(len(chunks)))
+		atomic.AddInt64(&ts.TracesBytes, int64(len(body)))
+		atomic.AddInt64(&ts.PayloadAccepted, 1)
+		metrics.Count("datadog.trace_agent.receiver.foreign_spans", int64(len(in.spans)), tags, 1)
+
+		tp := &pb.TracerPayload{
+			Chunks:          chunks,
+			ContainerID:     req.Header.Get(headerContainerID),
+			LanguageName:    ts.Lang,
+			LanguageVersion: ts.LangVersion,
+			TracerVersion:   ts.TracerVersion,
+		}
+		if ctags := getContainerTags(r.conf.ContainerTags, tp.ContainerID); ctags != "" {
+			tp.Tags = map[string]string{tagContainersTags: ctags}
+		}
+		r.out <- &Payload{Source: ts, TracerPayload: tp}
+	})
+}
+
+// readForeignBody reads the body of req, decompressing it if needed, up to limit bytes.
+func readForeignBody(req *http.Request, limit int64) ([]byte, error) {
+	var rd io.Reader = apiutil.NewLimitedReader(req.Body, limit)
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(rd)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		rd = apiutil.NewLimitedReader(gz, limit)
+	}
+	return ioutil.ReadAll(rd)
+}
+
+// foreignEvent is an event of a span, such as a Zipkin annotation or a Jaeger log. The events
+// of a span are marshaled as JSON in its "events" tag, as done for OpenTelemetry spans.
+type foreignEvent struct {
+	TimeUnixNano int64             `json:"time_unix_nano,omitempty"`
+	Name         string            `json:"name,omitempty"`
+	Attributes   map[string]string `json:"attributes,omitempty"`
+}
+
+// marshalForeignEvents marshals events into JSON.
+func marshalForeignEvents(events []foreignEvent) string {
+	b, err := json.Marshal(events)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// foreignSpanKinds maps the span kinds of the foreign formats to OpenTelemetry span kinds.
+var foreignSpanKinds = map[string]pdata.SpanKind{
+	"client":   pdata.SpanKindClient,
+	"server":   pdata.SpanKindServer,
+	"producer": pdata.SpanKindProducer,
+	"consumer": pdata.SpanKindConsumer,
+}
+
+// finishForeignSpan sets the name, resource and type of span from its kind and tags. The
+// name is formed after format and kind, similarly to OpenTelemetry spans, while the original
+// span name is used as resource unless a more accurate one is found in the tags.
+func finishForeignSpan(format string, kind string, name string, span *pb.Span) {
+	k, ok := foreignSpanKinds[kind]
+	if !ok {
+		k = pdata.SpanKindInternal
+	}
+	span.Name = format + "." + spanKindName(k)
+	span.Resource = name
+	if r := resourceFromTags(span.Meta); r != "" {
+		span.Resource = r
+	}
+	if span.Resource == "" {
+		span.Resource = span.Name
+	}
+	span.Type = spanKind2Type(k, span)
+	if _, ok := span.Meta["env"]; !ok {
+		if env := span.Meta[string(semconv.AttributeDeploymentEnvironment)]; env != "" {
+			span.Meta["env"] = env
+		}
+	}
+}
+
+// parseHexID parses the hexadecimal ID s, of up to 128 bits, returning its lower and
+// higher 64 bits.
+func parseHexID(s string) (low, high uint64, err error) {
+	if len(s) == 0 || len(s) > 32 {
+		return 0, 0, fmt.Errorf("invalid ID %q", s)
+	}
+	if len(s) > 16 {
+		if high, err = strconv.ParseUint(s[:len(s)-16], 16, 64); err != nil {
+			return 0, 0, fmt.Errorf("invalid ID %q", s)
+		}
+		s = s[len(s)-16:]
+	}
+	if low, err = strconv.ParseUint(s, 16, 64); err != nil {
+		return 0, 0, fmt.Errorf("invalid ID %q", s)
+	}
+	return low, high, nil
+}
+
+// sharedSpanID returns the ID of the server side of a span whose ID id is shared between
+// its client and server sides, as done by some Zipkin instrumentations. Datadog spans must
+// have unique IDs, so the server side becomes a child of the client side with a new ID.
+func sharedSpanID(id uint64) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(strconv.FormatUint(id, 16) + ":server")) //nolint:errcheck
+	return h.Sum64()
+}
+
+// sortedKeys returns the keys of m, sorted.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,305 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics/timing"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// jaegerFormat names the Jaeger format in the names of the spans.
+const jaegerFormat = "jaeger"
+
+// jaegerFlagDebug is set in the flags of the spans of traces flagged for debugging.
+const jaegerFlagDebug = 2
+
+// jaegerTagType specifies the type of the value of a Jaeger tag. Its values are the ones
+// of the Thrift model; the values of the Protobuf model are translated.
+type jaegerTagType int
+
+const (
+	jaegerTagString jaegerTagType = iota
+	jaegerTagDouble
+	jaegerTagBool
+	jaegerTagLong
+	jaegerTagBinary
+)
+
+// jaegerTag is a tag of a Jaeger span or process, or a field of a log.
+type jaegerTag struct {
+	Key    string
+	Type   jaegerTagType
+	Str    string
+	Double float64
+	Bool   bool
+	Long   int64
+	Binary []byte
+}
+
+// String returns the value of the tag as a string.
+func (t *jaegerTag) String() string {
+	switch t.Type {
+	case jaegerTagDouble:
+		return strconv.FormatFloat(t.Double, 'f', -1, 64)
+	case jaegerTagBool:
+		return strconv.FormatBool(t.Bool)
+	case jaegerTagLong:
+		return strconv.FormatInt(t.Long, 10)
+	case jaegerTagBinary:
+		return hex.EncodeToString(t.Binary)
+	default:
+		return t.Str
+	}
+}
+
+// jaegerLog is a log of a Jaeger span.
+type jaegerLog struct {
+	Timestamp int64 // epoch nanoseconds
+	Fields    []jaegerTag
+}
+
+// jaegerRef is a reference of a Jaeger span to another span.
+type jaegerRef struct {
+	TraceIDLow  uint64
+	TraceIDHigh uint64
+	SpanID      uint64
+}
+
+// jaegerProcess describes the process emitting Jaeger spans.
+type jaegerProcess struct {
+	ServiceName string
+	Tags        []jaegerTag
+}
+
+// jaegerSpan is a span of the Jaeger Thrift or Protobuf models.
+type jaegerSpan struct {
+	TraceIDLow    uint64
+	TraceIDHigh   uint64
+	SpanID        uint64
+	ParentSpanID  uint64
+	OperationName string
+	References    []jaegerRef
+	Flags         int32
+	StartTime     int64 // epoch nanoseconds
+	Duration      int64 // nanoseconds
+	Tags          []jaegerTag
+	Logs          []jaegerLog
+	// Process is set when the span was emitted by another process than the one of its batch.
+	Process *jaegerProcess
+}
+
+// jaegerBatch holds the spans emitted by a process.
+type jaegerBatch struct {
+	Process *jaegerProcess
+	Spans   []*jaegerSpan
+}
+
+// spans returns the spans of the batch converted to Datadog spans.
+func (b *jaegerBatch) spans() *foreignSpans {
+	var out foreignSpans
+	for _, s := range b.Spans {
+		p := b.Process
+		if s.Process != nil {
+			p = s.Process
+		}
+		out.add(convertJaegerSpan(p, s), s.Flags&jaegerFlagDebug != 0)
+	}
+	return &out
+}
+
+// convertJaegerSpan converts the Jaeger span in, emitted by the process p, to a Datadog span.
+func convertJaegerSpan(p *jaegerProcess, in *jaegerSpan) *pb.Span {
+	span := &pb.Span{
+		TraceID:  in.TraceIDLow,
+		SpanID:   in.SpanID,
+		ParentID: in.ParentSpanID,
+		Start:    in.StartTime,
+		Duration: in.Duration,
+		Meta:     make(map[string]string, len(in.Tags)+1),
+		Metrics:  map[string]float64{},
+	}
+	if span.ParentID == 0 {
+		for _, ref := range in.References {
+			if ref.TraceIDLow == in.TraceIDLow && ref.TraceIDHigh == in.TraceIDHigh {
+				span.ParentID = ref.SpanID
+				break
+			}
+		}
+	}
+	if in.TraceIDHigh != 0 {
+		span.Meta["jaeger.trace_id"] = fmt.Sprintf("%016x%016x", in.TraceIDHigh, in.TraceIDLow)
+	}
+	if p != nil {
+		span.Service = p.ServiceName
+		for i := range p.Tags {
+			setJaegerTag(span, &p.Tags[i])
+		}
+	}
+	for i := range in.Tags {
+		setJaegerTag(span, &in.Tags[i])
+	}
+	if v, ok := span.Meta["error"]; ok {
+		if v == "true" {
+			span.Error = 1
+		}
+		delete(span.Meta, "error")
+	}
+	if len(in.Logs) > 0 {
+		events := make([]foreignEvent, 0, len(in.Logs))
+		for _, l := range in.Logs {
+			events = append(events, jaegerLogEvent(span, l))
+		}
+		span.Meta["events"] = marshalForeignEvents(events)
+	}
+	finishForeignSpan(jaegerFormat, span.Meta["span.kind"], in.OperationName, span)
+	return span
+}
+
+// setJaegerTag sets the tag t on span, as a metric if its value is numeric.
+func setJaegerTag(span *pb.Span, t *jaegerTag) {
+	switch t.Type {
+	case jaegerTagDouble:
+		span.Metrics[t.Key] = t.Double
+	case jaegerTagLong:
+		span.Metrics[t.Key] = float64(t.Long)
+	default:
+		span.Meta[t.Key] = t.String()
+	}
+}
+
+// jaegerLogErrorFields maps the fields of the error logs of the OpenTracing conventions to
+// the Datadog error tags.
+var jaegerLogErrorFields = map[string]string{
+	"message":      "error.msg",
+	"error.object": "error.msg",
+	"error.kind":   "error.type",
+	"stack":        "error.stack",
+}
+
+// jaegerLogEvent returns the event of the log l of span. The details of error logs are set
+// on the span.
+func jaegerLogEvent(span *pb.Span, l jaegerLog) foreignEvent {
+	e := foreignEvent{TimeUnixNano: l.Timestamp}
+	var isError bool
+	for i := range l.Fields {
+		f := &l.Fields[i]
+		if f.Key == "event" {
+			e.Name = f.String()
+			isError = e.Name == "error"
+			continue
+		}
+		if e.Attributes == nil {
+			e.Attributes = make(map[string]string, len(l.Fields))
+		}
+		e.Attributes[f.Key] = f.String()
+	}
+	if isError {
+		for _, k := range sortedKeys(e.Attributes) {
+			if tag, ok := jaegerLogErrorFields[k]; ok && span.Meta[tag] == "" {
+				span.Meta[tag] = e.Attributes[k]
+			}
+		}
+	}
+	return e
+}
+
+// decodeJaegerThrift decodes a Jaeger batch encoded in Thrift with the binary protocol.
+func decodeJaegerThrift(_ *http.Request, body []byte) (*foreignSpans, error) {
+	b, err := unmarshalJaegerThrift(body)
+	if err != nil {
+		return nil, err
+	}
+	return b.spans(), nil
+}
+
+// JaegerReceiver implements the gRPC API of the Jaeger collector, receiving Jaeger spans
+// and sending them to the agent as traces.
+type JaegerReceiver struct {
+	wg   sync.WaitGroup      // waits for a graceful shutdown
+	srv  *grpc.Server        // the running gRPC server on a started receiver, if enabled
+	out  chan<- *Payload     // the outgoing payload channel
+	conf *config.AgentConfig // receiver config
+}
+
+// NewJaegerReceiver returns a new JaegerReceiver which sends any incoming traces down the out channel.
+func NewJaegerReceiver(out chan<- *Payload, cfg *config.AgentConfig) *JaegerReceiver {
+	return &JaegerReceiver{out: out, conf: cfg}
+}
+
+// Start starts the gRPC server of the receiver, if Jaeger intake and its gRPC port are enabled.
+func (j *JaegerReceiver) Start() {
+	if !j.conf.Jaeger.Enabled || j.conf.Jaeger.GRPCPort == 0 {
+		return
+	}
+	addr := net.JoinHostPort(j.conf.ReceiverHost, strconv.Itoa(j.conf.Jaeger.GRPCPort))
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Criticalf("Error starting Jaeger gRPC server: %v", err)
+		return
+	}
+	j.srv = grpc.NewServer()
+	j.srv.RegisterService(&jaegerCollectorServiceDesc, j)
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+		if err := j.srv.Serve(ln); err != nil {
+			log.Criticalf("Error starting Jaeger gRPC server: %v", err)
+		}
+	}()
+	log.Debugf("Listening for Jaeger traces on gRPC address %s", addr)
+}
+
+// Stop stops the running server, if any.
+func (j *JaegerReceiver) Stop() {
+	if j.srv != nil {
+		go j.srv.Stop()
+	}
+	j.wg.Wait()
+}
+
+// PostSpans implements the PostSpans method of the jaeger.api_v2.CollectorService.
+func (j *JaegerReceiver) PostSpans(ctx context.Context, req *jaegerPostSpansRequest) (*jaegerPostSpansResponse, error) {
+	defer timing.Since("datadog.trace_agent.receiver.serve_jaeger_grpc_ms", time.Now())
+	var containerID string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(headerContainerID); len(v) > 0 {
+			containerID = v[0]
+		}
+	}
+	in := req.batch.spans()
+	chunks := in.chunks()
+	tagstats := &info.TagStats{
+		Tags:  info.Tags{EndpointVersion: "jaeger_grpc"},
+		Stats: info.NewStats(),
+	}
+	tags := tagstats.AsTags()
+	metrics.Count("datadog.trace_agent.receiver.foreign_spans", int64(len(in.spans)), tags, 1)
+	tp := &pb.TracerPayload{
+		Chunks:      chunks,
+		ContainerID: containerID,
+	}
+	if ctags := getContainerTags(j.conf.ContainerTags, containerID); ctags != "" {
+		tp.Tags = map[string]string{tagContainersTags: ctags}
+	}
+	j.out <- &Payload{Source: tagstats, TracerPayload: tp}
+	return &jaegerPostSpansResponse{}, nil
+}
@@ -0,0 +1,261 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// jaegerCollectorServiceDesc describes the jaeger.api_v2.CollectorService, as defined in
+// https://github.com/jaegertracing/jaeger-idl/blob/main/proto/api_v2/collector.proto.
+var jaegerCollectorServiceDesc = grpc.ServiceDesc{
+	ServiceName: "jaeger.api_v2.CollectorService",
+	HandlerType: (*jaegerCollectorServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "PostSpans",
+			Handler:    jaegerPostSpansHandler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "collector.proto",
+}
+
+// jaegerCollectorServer is the server API of the jaeger.api_v2.CollectorService.
+type jaegerCollectorServer interface {
+	PostSpans(context.Context, *jaegerPostSpansRequest) (*jaegerPostSpansResponse, error)
+}
+
+func jaegerPostSpansHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(jaegerPostSpansRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(jaegerCollectorServer).PostSpans(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/jaeger.api_v2.CollectorService/PostSpans",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(jaegerCollectorServer).PostSpans(ctx, req.(*jaegerPostSpansRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// jaegerPostSpansRequest is the jaeger.api_v2.PostSpansRequest message. It implements the
+// legacy Protobuf message interface, decoding itself.
+type jaegerPostSpansRequest struct {
+	batch jaegerBatch
+}
+
+// Reset implements proto.Message.
+func (r *jaegerPostSpansRequest) Reset() { *r = jaegerPostSpansRequest{} }
+
+// String implements proto.Message.
+func (r *jaegerPostSpansRequest) String() string { return "PostSpansRequest" }
+
+// ProtoMessage implements proto.Message.
+func (*jaegerPostSpansRequest) ProtoMessage() {}
+
+// Marshal is not supported: requests are only received.
+func (*jaegerPostSpansRequest) Marshal() ([]byte, error) { return nil, errInvalidProto }
+
+// Unmarshal decodes the message b.
+func (r *jaegerPostSpansRequest) Unmarshal(b []byte) error {
+	r.Reset()
+	return protoFields(b, func(num protowire.Number, _ protowire.Type, v []byte, _ uint64) error {
+		if num != 1 {
+			return nil
+		}
+		return unmarshalJaegerProtoBatch(v, &r.batch)
+	})
+}
+
+// jaegerPostSpansResponse is the empty jaeger.api_v2.PostSpansResponse message.
+type jaegerPostSpansResponse struct{}
+
+// Reset implements proto.Message.
+func (*jaegerPostSpansResponse) Reset() {}
+
+// String implements proto.Message.
+func (*jaegerPostSpansResponse) String() string { return "PostSpansResponse" }
+
+// ProtoMessage implements proto.Message.
+func (*jaegerPostSpansResponse) ProtoMessage() {}
+
+// Marshal encodes the message.
+func (*jaegerPostSpansResponse) Marshal() ([]byte, error) { return nil, nil }
+
+// Unmarshal decodes the message b.
+func (*jaegerPostSpansResponse) Unmarshal([]byte) error { return nil }
+
+// jaegerProtoTagTypes maps the value types of the Protobuf model to the ones of the Thrift model.
+var jaegerProtoTagTypes = []jaegerTagType{jaegerTagString, jaegerTagBool, jaegerTagLong, jaegerTagDouble, jaegerTagBinary}
+
+func unmarshalJaegerProtoBatch(b []byte, batch *jaegerBatch) error {
+	return protoFields(b, func(num protowire.Number, _ protowire.Type, v []byte, _ uint64) error {
+		switch num {
+		case 1:
+			s, err := unmarshalJaegerProtoSpan(v)
+			if err != nil {
+				return err
+			}
+			batch.Spans = append(batch.Spans, s)
+		case 2:
+			p, err := unmarshalJaegerProtoProcess(v)
+			if err != nil {
+				return err
+			}
+			batch.Process = p
+		}
+		return nil
+	})
+}
+
+func unmarshalJaegerProtoProcess(b []byte) (*jaegerProcess, error) {
+	var p jaegerProcess
+	err := protoFields(b, func(num protowire.Number, _ protowire.Type, v []byte, _ uint64) error {
+		switch num {
+		case 1:
+			p.ServiceName = string(v)
+		case 2:
+			t, err := unmarshalJaegerProtoTag(v)
+			if err != nil {
+				return err
+			}
+			p.Tags = append(p.Tags, t)
+		}
+		return nil
+	})
+	return &p, err
+}
+
+func unmarshalJaegerProtoSpan(b []byte) (*jaegerSpan, error) {
+	var s jaegerSpan
+	err := protoFields(b, func(num protowire.Number, _ protowire.Type, v []byte, n uint64) error {
+		var err error
+		switch num {
+		case 1:
+			s.TraceIDLow, s.TraceIDHigh, err = jaegerProtoTraceID(v)
+		case 2:
+			s.SpanID, err = jaegerProtoSpanID(v)
+		case 3:
+			s.OperationName = string(v)
+		case 4:
+			var ref jaegerRef
+			err = protoFields(v, func(num protowire.Number, _ protowire.Type, v []byte, _ uint64) error {
+				var err error
+				switch num {
+				case 1:
+					ref.TraceIDLow, ref.TraceIDHigh, err = jaegerProtoTraceID(v)
+				case 2:
+					ref.SpanID, err = jaegerProtoSpanID(v)
+				}
+				return err
+			})
+			s.References = append(s.References, ref)
+		case 5:
+			s.Flags = int32(n)
+		case 6:
+			s.StartTime, err = unmarshalProtoDuration(v)
+		case 7:
+			s.Duration, err = unmarshalProtoDuration(v)
+		case 8:
+			var t jaegerTag
+			t, err = unmarshalJaegerProtoTag(v)
+			s.Tags = append(s.Tags, t)
+		case 9:
+			var l jaegerLog
+			err = protoFields(v, func(num protowire.Number, _ protowire.Type, v []byte, _ uint64) error {
+				var err error
+				switch num {
+				case 1:
+					l.Timestamp, err = unmarshalProtoDuration(v)
+				case 2:
+					var t jaegerTag
+					t, err = unmarshalJaegerProtoTag(v)
+					l.Fields = append(l.Fields, t)
+				}
+				return err
+			})
+			s.Logs = append(s.Logs, l)
+		case 10:
+			s.Process, err = unmarshalJaegerProtoProcess(v)
+		}
+		return err
+	})
+	return &s, err
+}
+
+func unmarshalJaegerProtoTag(b []byte) (jaegerTag, error) {
+	var t jaegerTag
+	err := protoFields(b, func(num protowire.Number, _ protowire.Type, v []byte, n uint64) error {
+		switch num {
+		case 1:
+			t.Key = string(v)
+		case 2:
+			if n >= uint64(len(jaegerProtoTagTypes)) {
+				return errInvalidProto
+			}
+			t.Type = jaegerProtoTagTypes[n]
+		case 3:
+			t.Str = string(v)
+		case 4:
+			t.Bool = n != 0
+		case 5:
+			t.Long = int64(n)
+		case 6:
+			t.Double = math.Float64frombits(n)
+		case 7:
+			t.Binary = v
+		}
+		return nil
+	})
+	return t, err
+}
+
+// unmarshalProtoDuration decodes the google.protobuf.Timestamp or google.protobuf.Duration
+// message b, returning its value in nanoseconds.
+func unmarshalProtoDuration(b []byte) (int64, error) {
+	var secs, nanos int64
+	err := protoFields(b, func(num protowire.Number, _ protowire.Type, _ []byte, n uint64) error {
+		switch num {
+		case 1:
+			secs = int64(n)
+		case 2:
+			nanos = int64(int32(n))
+		}
+		return nil
+	})
+	return secs*1e9 + nanos, err
+}
+
+// jaegerProtoTraceID decodes the 128-bit trace ID b, returning its lower and higher 64 bits.
+func jaegerProtoTraceID(b []byte) (low, high uint64, err error) {
+	switch len(b) {
+	case 16:
+		return binary.BigEndian.Uint64(b[8:]), binary.BigEndian.Uint64(b[:8]), nil
+	case 8:
+		return binary.BigEndian.Uint64(b), 0, nil
+	default:
+		return 0, 0, errInvalidProto
+	}
+}
+
+// jaegerProtoSpanID decodes the 64-bit span ID b.
+func jaegerProtoSpanID(b []byte) (uint64, error) {
+	if len(b) != 8 {
+		return 0, errInvalidProto
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
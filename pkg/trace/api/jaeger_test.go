@@ -0,0 +1,306 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+	"github.com/DataDog/datadog-agent/pkg/trace/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// thriftWriter encodes Thrift values with the binary protocol.
+type thriftWriter struct{ bytes.Buffer }
+
+func (w *thriftWriter) field(typ byte, id int16) {
+	w.WriteByte(typ)
+	binary.Write(w, binary.BigEndian, id) //nolint:errcheck
+}
+
+func (w *thriftWriter) i32(id int16, v int32) {
+	w.field(thriftI32, id)
+	binary.Write(w, binary.BigEndian, v) //nolint:errcheck
+}
+
+func (w *thriftWriter) i64(id int16, v int64) {
+	w.field(thriftI64, id)
+	binary.Write(w, binary.BigEndian, v) //nolint:errcheck
+}
+
+func (w *thriftWriter) str(id int16, v string) {
+	w.field(thriftString, id)
+	binary.Write(w, binary.BigEndian, int32(len(v))) //nolint:errcheck
+	w.WriteString(v)
+}
+
+func (w *thriftWriter) list(id int16, typ byte, n int) {
+	w.field(thriftList, id)
+	w.WriteByte(typ)
+	binary.Write(w, binary.BigEndian, int32(n)) //nolint:errcheck
+}
+
+func (w *thriftWriter) stop() { w.WriteByte(thriftStop) }
+
+// tag writes a jaeger.thrift Tag struct.
+func (w *thriftWriter) tag(key string, v interface{}) {
+	w.str(1, key)
+	switch v := v.(type) {
+	case string:
+		w.i32(2, int32(jaegerTagString))
+		w.str(3, v)
+	case float64:
+		w.i32(2, int32(jaegerTagDouble))
+		w.field(thriftDouble, 4)
+		binary.Write(w, binary.BigEndian, math.Float64bits(v)) //nolint:errcheck
+	case bool:
+		w.i32(2, int32(jaegerTagBool))
+		w.field(thriftBool, 5)
+		if v {
+			w.WriteByte(1)
+		} else {
+			w.WriteByte(0)
+		}
+	case int64:
+		w.i32(2, int32(jaegerTagLong))
+		w.i64(6, v)
+	}
+	w.stop()
+}
+
+// testJaegerThriftBatch returns a Batch holding a client span, with an error log, and its
+// child server span.
+func testJaegerThriftBatch() []byte {
+	var w thriftWriter
+	w.field(thriftStruct, 1) // process
+	w.str(1, "frontend")
+	w.list(2, thriftStruct, 1)
+	w.tag("hostname", "host-1")
+	w.stop()
+
+	w.list(2, thriftStruct, 2) // spans
+
+	w.i64(1, 0xabc)
+	w.i64(2, 0x5af7183f)
+	w.i64(3, 1)
+	w.i64(4, 0)
+	w.str(5, "HTTP GET")
+	w.i32(7, 3) // sampled, debug
+	w.i64(8, 1556604172355737)
+	w.i64(9, 1431)
+	w.list(10, thriftStruct, 4)
+	w.tag("span.kind", "client")
+	w.tag("error", true)
+	w.tag("http.status_code", int64(500))
+	w.tag("sampler.param", float64(0.5))
+	w.list(11, thriftStruct, 1)
+	w.i64(1, 1556604172355800)
+	w.list(2, thriftStruct, 3)
+	w.tag("event", "error")
+	w.tag("error.kind", "Timeout")
+	w.tag("message", "deadline exceeded")
+	w.stop()
+	w.field(thriftBool, 99) // unknown field
+	w.WriteByte(1)
+	w.stop()
+
+	w.i64(1, 0xabc)
+	w.i64(2, 0x5af7183f)
+	w.i64(3, 2)
+	w.str(5, "/api")
+	w.list(6, thriftStruct, 1) // references
+	w.i32(1, 0)
+	w.i64(2, 0xabc)
+	w.i64(3, 0x5af7183f)
+	w.i64(4, 1)
+	w.stop()
+	w.i64(8, 1556604172355900)
+	w.i64(9, 1200)
+	w.list(10, thriftStruct, 1)
+	w.tag("span.kind", "server")
+	w.stop()
+
+	w.stop()
+	return w.Bytes()
+}
+
+func TestUnmarshalJaegerThrift(t *testing.T) {
+	assert := assert.New(t)
+	body := testJaegerThriftBatch()
+	out, err := decodeJaegerThrift(nil, body)
+	require.NoError(t, err)
+	require.Len(t, out.spans, 2)
+
+	client, server := out.spans[0], out.spans[1]
+	assert.Equal(uint64(0xabc), client.TraceID)
+	assert.Equal(uint64(1), client.SpanID)
+	assert.Equal("frontend", client.Service)
+	assert.Equal("jaeger.client", client.Name)
+	assert.Equal("HTTP GET", client.Resource)
+	assert.Equal("http", client.Type)
+	assert.Equal(int64(1556604172355737000), client.Start)
+	assert.Equal(int64(1431000), client.Duration)
+	assert.Equal(int32(1), client.Error)
+	assert.Equal("deadline exceeded", client.Meta["error.msg"])
+	assert.Equal("Timeout", client.Meta["error.type"])
+	assert.Equal("host-1", client.Meta["hostname"])
+	assert.Equal(float64(500), client.Metrics["http.status_code"])
+	assert.Equal(0.5, client.Metrics["sampler.param"])
+	assert.Equal("000000005af7183f0000000000000abc", client.Meta["jaeger.trace_id"])
+	assert.Equal(`[{"time_unix_nano":1556604172355800000,"name":"error","attributes":{"error.kind":"Timeout","message":"deadline exceeded"}}]`, client.Meta["events"])
+	assert.True(out.debug[client.TraceID])
+
+	assert.Equal(uint64(1), server.ParentID)
+	assert.Equal("jaeger.server", server.Name)
+	assert.Equal("/api", server.Resource)
+	assert.Equal(int32(0), server.Error)
+
+	for _, n := range []int{1, 10, len(body) / 2, len(body) - 1} {
+		_, err = unmarshalJaegerThrift(body[:n])
+		assert.Error(err, n)
+	}
+}
+
+func TestHandleJaegerThrift(t *testing.T) {
+	assert := assert.New(t)
+	r := newTestReceiverFromConfig(newTestReceiverConfig())
+	h := r.handleForeignSpans("jaeger_thrift", decodeJaegerThrift)
+
+	req := httptest.NewRequest("POST", "/api/traces", bytes.NewReader(testJaegerThriftBatch()))
+	req.Header.Set("Content-Type", "application/x-thrift")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(http.StatusAccepted, rec.Code)
+
+	p := <-r.out
+	assert.Equal("jaeger_thrift", p.Source.EndpointVersion)
+	require.Len(t, p.TracerPayload.Chunks, 1)
+	assert.Equal(int32(sampler.PriorityUserKeep), p.TracerPayload.Chunks[0].Priority)
+	assert.Len(p.TracerPayload.Chunks[0].Spans, 2)
+}
+
+// rawProto is a Protobuf message holding its encoding, used to send requests to the
+// Jaeger gRPC receiver.
+type rawProto struct{ b []byte }
+
+func (m *rawProto) Reset()                   { m.b = nil }
+func (m *rawProto) String() string           { return fmt.Sprintf("%x", m.b) }
+func (*rawProto) ProtoMessage()              {}
+func (m *rawProto) Marshal() ([]byte, error) { return m.b, nil }
+func (m *rawProto) Unmarshal(b []byte) error { m.b = append(m.b[:0], b...); return nil }
+
+// testJaegerProtoRequest returns a PostSpansRequest holding a server span.
+func testJaegerProtoRequest() []byte {
+	appendMsg := func(b []byte, num protowire.Number, msg []byte) []byte {
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		return protowire.AppendBytes(b, msg)
+	}
+	keyValue := func(key string, typ uint64, f func(b []byte) []byte) []byte {
+		var b []byte
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendString(b, key)
+		b = protowire.AppendTag(b, 2, protowire.VarintType)
+		b = protowire.AppendVarint(b, typ)
+		return f(b)
+	}
+	var ts, dur []byte
+	ts = protowire.AppendTag(ts, 1, protowire.VarintType)
+	ts = protowire.AppendVarint(ts, 1556604172)
+	ts = protowire.AppendTag(ts, 2, protowire.VarintType)
+	ts = protowire.AppendVarint(ts, 355737000)
+	dur = protowire.AppendTag(dur, 2, protowire.VarintType)
+	dur = protowire.AppendVarint(dur, 1431000)
+
+	var span []byte
+	span = appendMsg(span, 1, []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x0a, 0xbc})
+	span = appendMsg(span, 2, []byte{0, 0, 0, 0, 0, 0, 0, 0x07})
+	span = appendMsg(span, 3, []byte("GET /users"))
+	span = appendMsg(span, 6, ts)
+	span = appendMsg(span, 7, dur)
+	span = appendMsg(span, 8, keyValue("span.kind", 0, func(b []byte) []byte {
+		b = protowire.AppendTag(b, 3, protowire.BytesType)
+		return protowire.AppendString(b, "server")
+	}))
+	span = appendMsg(span, 8, keyValue("error", 1, func(b []byte) []byte {
+		b = protowire.AppendTag(b, 4, protowire.VarintType)
+		return protowire.AppendVarint(b, 1)
+	}))
+	span = appendMsg(span, 8, keyValue("retries", 2, func(b []byte) []byte {
+		b = protowire.AppendTag(b, 5, protowire.VarintType)
+		return protowire.AppendVarint(b, 3)
+	}))
+
+	var process, batch, req []byte
+	process = appendMsg(process, 1, []byte("users"))
+	batch = appendMsg(batch, 1, span)
+	batch = appendMsg(batch, 2, process)
+	req = appendMsg(req, 1, batch)
+	return req
+}
+
+func TestJaegerReceiver(t *testing.T) {
+	t.Run("Start/disabled", func(t *testing.T) {
+		cfg := config.New()
+		cfg.Jaeger.GRPCPort = testutil.FreeTCPPort(t)
+		j := NewJaegerReceiver(nil, cfg)
+		j.Start()
+		defer j.Stop()
+		assert.Nil(t, j.srv)
+	})
+
+	t.Run("PostSpans", func(t *testing.T) {
+		assert := assert.New(t)
+		cfg := config.New()
+		cfg.ReceiverHost = "localhost"
+		cfg.Jaeger.Enabled = true
+		cfg.Jaeger.GRPCPort = testutil.FreeTCPPort(t)
+		out := make(chan *Payload, 1)
+		j := NewJaegerReceiver(out, cfg)
+		j.Start()
+		defer j.Stop()
+		require.NotNil(t, j.srv)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		conn, err := grpc.DialContext(ctx, fmt.Sprintf("localhost:%d", cfg.Jaeger.GRPCPort), grpc.WithInsecure(), grpc.WithBlock())
+		require.NoError(t, err)
+		defer conn.Close()
+		err = conn.Invoke(ctx, "/jaeger.api_v2.CollectorService/PostSpans", &rawProto{b: testJaegerProtoRequest()}, &rawProto{})
+		require.NoError(t, err)
+
+		p := <-out
+		assert.Equal("jaeger_grpc", p.Source.EndpointVersion)
+		require.Len(t, p.TracerPayload.Chunks, 1)
+		chunk := p.TracerPayload.Chunks[0]
+		assert.Equal(int32(sampler.PriorityAutoKeep), chunk.Priority)
+		require.Len(t, chunk.Spans, 1)
+		s := chunk.Spans[0]
+		assert.Equal(uint64(0xabc), s.TraceID)
+		assert.Equal(uint64(7), s.SpanID)
+		assert.Equal("users", s.Service)
+		assert.Equal("jaeger.server", s.Name)
+		assert.Equal("GET /users", s.Resource)
+		assert.Equal("web", s.Type)
+		assert.Equal(int64(1556604172355737000), s.Start)
+		assert.Equal(int64(1431000), s.Duration)
+		assert.Equal(int32(1), s.Error)
+		assert.Equal(float64(3), s.Metrics["retries"])
+		assert.NotContains(s.Meta, "jaeger.trace_id")
+	})
+}
@@ -0,0 +1,307 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Types of the Thrift binary protocol.
+const (
+	thriftStop   = 0
+	thriftBool   = 2
+	thriftByte   = 3
+	thriftDouble = 4
+	thriftI16    = 6
+	thriftI32    = 8
+	thriftI64    = 10
+	thriftString = 11
+	thriftStruct = 12
+	thriftMap    = 13
+	thriftSet    = 14
+	thriftList   = 15
+)
+
+// thriftMaxDepth limits the nesting of the skipped Thrift values.
+const thriftMaxDepth = 64
+
+// errThriftEOF is returned when a Thrift message is truncated.
+var errThriftEOF = errors.New("thrift: unexpected end of message")
+
+// thriftReader reads the values of a message encoded with the Thrift binary protocol.
+// Once an error occurs, the reader returns zero values and err holds the error.
+type thriftReader struct {
+	b   []byte
+	err error
+}
+
+func (r *thriftReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.b) {
+		r.err = errThriftEOF
+		return nil
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *thriftReader) readByte() byte {
+	if b := r.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *thriftReader) readBool() bool { return r.readByte() != 0 }
+
+func (r *thriftReader) readI16() int16 {
+	if b := r.next(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (r *thriftReader) readI32() int32 {
+	if b := r.next(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (r *thriftReader) readI64() int64 {
+	if b := r.next(8); b != nil {
+		return int64(binary.BigEndian.Uint64(b))
+	}
+	return 0
+}
+
+func (r *thriftReader) readDouble() float64 {
+	return math.Float64frombits(uint64(r.readI64()))
+}
+
+func (r *thriftReader) readBinary() []byte {
+	return r.next(int(r.readI32()))
+}
+
+func (r *thriftReader) readString() string {
+	return string(r.readBinary())
+}
+
+// readStruct reads a struct, calling field with the ID and type of each of its fields.
+// field must read the value of the field, or skip it.
+func (r *thriftReader) readStruct(field func(id int16, typ byte)) {
+	for r.err == nil {
+		typ := r.readByte()
+		if typ == thriftStop {
+			return
+		}
+		field(r.readI16(), typ)
+	}
+}
+
+// readList reads a list or a set, calling elem for each of its elements, which must be
+// of type typ.
+func (r *thriftReader) readList(typ byte, elem func()) {
+	if t := r.readByte(); t != typ && r.err == nil {
+		r.err = fmt.Errorf("thrift: unexpected list element type %d", t)
+	}
+	n := int(r.readI32())
+	if n < 0 || n > len(r.b) {
+		// every element takes at least a byte
+		r.err = errThriftEOF
+	}
+	for i := 0; i < n && r.err == nil; i++ {
+		elem()
+	}
+}
+
+// skip skips a value of type typ.
+func (r *thriftReader) skip(typ byte) { r.skipDepth(typ, 0) }
+
+func (r *thriftReader) skipDepth(typ byte, depth int) {
+	if depth > thriftMaxDepth {
+		r.err = errors.New("thrift: maximum depth exceeded")
+		return
+	}
+	switch typ {
+	case thriftBool, thriftByte:
+		r.next(1)
+	case thriftI16:
+		r.next(2)
+	case thriftI32:
+		r.next(4)
+	case thriftDouble, thriftI64:
+		r.next(8)
+	case thriftString:
+		r.readBinary()
+	case thriftStruct:
+		r.readStruct(func(_ int16, typ byte) { r.skipDepth(typ, depth+1) })
+	case thriftMap:
+		kt, vt := r.readByte(), r.readByte()
+		n := int(r.readI32())
+		if n < 0 || n > len(r.b) {
+			r.err = errThriftEOF
+		}
+		for i := 0; i < n && r.err == nil; i++ {
+			r.skipDepth(kt, depth+1)
+			r.skipDepth(vt, depth+1)
+		}
+	case thriftSet, thriftList:
+		et := r.readByte()
+		n := int(r.readI32())
+		if n < 0 || n > len(r.b) {
+			r.err = errThriftEOF
+		}
+		for i := 0; i < n && r.err == nil; i++ {
+			r.skipDepth(et, depth+1)
+		}
+	default:
+		if r.err == nil {
+			r.err = fmt.Errorf("thrift: unknown type %d", typ)
+		}
+	}
+}
+
+// unmarshalJaegerThrift decodes the jaeger.thrift Batch b, encoded with the binary protocol.
+func unmarshalJaegerThrift(b []byte) (*jaegerBatch, error) {
+	r := &thriftReader{b: b}
+	var batch jaegerBatch
+	r.readStruct(func(id int16, typ byte) {
+		switch {
+		case id == 1 && typ == thriftStruct:
+			batch.Process = r.readJaegerProcess()
+		case id == 2 && typ == thriftList:
+			r.readList(thriftStruct, func() {
+				batch.Spans = append(batch.Spans, r.readJaegerSpan())
+			})
+		default:
+			r.skip(typ)
+		}
+	})
+	if r.err != nil {
+		return nil, r.err
+	}
+	return &batch, nil
+}
+
+func (r *thriftReader) readJaegerProcess() *jaegerProcess {
+	var p jaegerProcess
+	r.readStruct(func(id int16, typ byte) {
+		switch {
+		case id == 1 && typ == thriftString:
+			p.ServiceName = r.readString()
+		case id == 2 && typ == thriftList:
+			p.Tags = r.readJaegerTags()
+		default:
+			r.skip(typ)
+		}
+	})
+	return &p
+}
+
+func (r *thriftReader) readJaegerSpan() *jaegerSpan {
+	var s jaegerSpan
+	r.readStruct(func(id int16, typ byte) {
+		switch {
+		case id == 1 && typ == thriftI64:
+			s.TraceIDLow = uint64(r.readI64())
+		case id == 2 && typ == thriftI64:
+			s.TraceIDHigh = uint64(r.readI64())
+		case id == 3 && typ == thriftI64:
+			s.SpanID = uint64(r.readI64())
+		case id == 4 && typ == thriftI64:
+			s.ParentSpanID = uint64(r.readI64())
+		case id == 5 && typ == thriftString:
+			s.OperationName = r.readString()
+		case id == 6 && typ == thriftList:
+			r.readList(thriftStruct, func() {
+				s.References = append(s.References, r.readJaegerRef())
+			})
+		case id == 7 && typ == thriftI32:
+			s.Flags = r.readI32()
+		case id == 8 && typ == thriftI64:
+			s.StartTime = r.readI64() * 1000
+		case id == 9 && typ == thriftI64:
+			s.Duration = r.readI64() * 1000
+		case id == 10 && typ == thriftList:
+			s.Tags = r.readJaegerTags()
+		case id == 11 && typ == thriftList:
+			r.readList(thriftStruct, func() {
+				s.Logs = append(s.Logs, r.readJaegerLog())
+			})
+		default:
+			r.skip(typ)
+		}
+	})
+	return &s
+}
+
+func (r *thriftReader) readJaegerRef() jaegerRef {
+	var ref jaegerRef
+	r.readStruct(func(id int16, typ byte) {
+		switch {
+		case id == 2 && typ == thriftI64:
+			ref.TraceIDLow = uint64(r.readI64())
+		case id == 3 && typ == thriftI64:
+			ref.TraceIDHigh = uint64(r.readI64())
+		case id == 4 && typ == thriftI64:
+			ref.SpanID = uint64(r.readI64())
+		default:
+			r.skip(typ)
+		}
+	})
+	return ref
+}
+
+func (r *thriftReader) readJaegerLog() jaegerLog {
+	var l jaegerLog
+	r.readStruct(func(id int16, typ byte) {
+		switch {
+		case id == 1 && typ == thriftI64:
+			l.Timestamp = r.readI64() * 1000
+		case id == 2 && typ == thriftList:
+			l.Fields = r.readJaegerTags()
+		default:
+			r.skip(typ)
+		}
+	})
+	return l
+}
+
+func (r *thriftReader) readJaegerTags() []jaegerTag {
+	var tags []jaegerTag
+	r.readList(thriftStruct, func() {
+		var t jaegerTag
+		r.readStruct(func(id int16, typ byte) {
+			switch {
+			case id == 1 && typ == thriftString:
+				t.Key = r.readString()
+			case id == 2 && typ == thriftI32:
+				t.Type = jaegerTagType(r.readI32())
+			case id == 3 && typ == thriftString:
+				t.Str = r.readString()
+			case id == 4 && typ == thriftDouble:
+				t.Double = r.readDouble()
+			case id == 5 && typ == thriftBool:
+				t.Bool = r.readBool()
+			case id == 6 && typ == thriftI64:
+				t.Long = r.readI64()
+			case id == 7 && typ == thriftString:
+				t.Binary = r.readBinary()
+			default:
+				r.skip(typ)
+			}
+		})
+		tags = append(tags, t)
+	})
+	return tags
+}
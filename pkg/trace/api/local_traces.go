@@ -0,0 +1,283 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+)
+
+// defaultLocalTracesLimit is the default number of traces returned by a search.
+const defaultLocalTracesLimit = 100
+
+// LocalTraces keeps the most recent traces sent by the agent in memory, so that they can
+// be searched and inspected through the local trace search API. It is safe for concurrent use.
+// A nil LocalTraces keeps nothing.
+type LocalTraces struct {
+	mu     sync.RWMutex
+	traces map[uint64]*localTrace
+	// ring holds the IDs of the traces in the order they were added. next is the index
+	// of the oldest trace, evicted when the ring is full.
+	ring []uint64
+	next int
+	// maxSpans is the maximum number of spans kept per trace.
+	maxSpans int
+}
+
+// localTrace is a trace kept by LocalTraces.
+type localTrace struct {
+	env      string
+	hostname string
+	received time.Time
+	spans    []*pb.Span
+}
+
+// NewLocalTraces returns a LocalTraces keeping up to max traces of up to maxSpans spans each.
+func NewLocalTraces(max, maxSpans int) *LocalTraces {
+	if max <= 0 {
+		max = 1
+	}
+	if maxSpans <= 0 {
+		maxSpans = 1
+	}
+	return &LocalTraces{
+		traces:   make(map[uint64]*localTrace, max),
+		ring:     make([]uint64, 0, max),
+		maxSpans: maxSpans,
+	}
+}
+
+// Add keeps the spans of chunk, received in the payload tp. The chunks of a trace
+// received separately are kept together, up to the maximum number of spans per trace.
+func (l *LocalTraces) Add(tp *pb.TracerPayload, chunk *pb.TraceChunk) {
+	if l == nil || len(chunk.Spans) == 0 {
+		return
+	}
+	id := chunk.Spans[0].TraceID
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if t, ok := l.traces[id]; ok {
+		t.spans = append(t.spans, l.truncate(chunk.Spans, len(t.spans))...)
+		return
+	}
+	if len(l.ring) < cap(l.ring) {
+		l.ring = append(l.ring, id)
+	} else {
+		delete(l.traces, l.ring[l.next])
+		l.ring[l.next] = id
+		l.next = (l.next + 1) % len(l.ring)
+	}
+	l.traces[id] = &localTrace{
+		env:      tp.Env,
+		hostname: tp.Hostname,
+		received: time.Now(),
+		spans:    append([]*pb.Span(nil), l.truncate(chunk.Spans, 0)...),
+	}
+}
+
+// truncate returns the spans which can be added to a trace already holding n spans.
+func (l *LocalTraces) truncate(spans []*pb.Span, n int) []*pb.Span {
+	if n+len(spans) > l.maxSpans {
+		return spans[:l.maxSpans-n]
+	}
+	return spans
+}
+
+// LocalTracesQuery filters the traces returned by LocalTraces.Search.
+type LocalTracesQuery struct {
+	// Service, if set, matches the traces having a span of this service.
+	Service string
+	// Error, if set, matches the traces having an error span, or no error span if false.
+	Error *bool
+	// MinDuration and MaxDuration, if set, bound the duration of the root span of the traces.
+	MinDuration time.Duration
+	MaxDuration time.Duration
+	// Limit is the maximum number of traces returned.
+	Limit int
+}
+
+// LocalTraceSummary describes a trace kept by LocalTraces.
+type LocalTraceSummary struct {
+	// TraceID is formatted as a decimal string, as JSON numbers can not represent it accurately.
+	TraceID   string    `json:"trace_id"`
+	Service   string    `json:"service"`
+	Name      string    `json:"name"`
+	Resource  string    `json:"resource"`
+	Env       string    `json:"env,omitempty"`
+	Hostname  string    `json:"hostname,omitempty"`
+	Start     int64     `json:"start"`
+	Duration  int64     `json:"duration"`
+	Error     bool      `json:"error"`
+	SpanCount int       `json:"span_count"`
+	Received  time.Time `json:"received"`
+}
+
+// LocalTraceDetail holds a trace kept by LocalTraces with all its spans.
+type LocalTraceDetail struct {
+	LocalTraceSummary
+	Spans []*pb.Span `json:"spans"`
+}
+
+// summary returns the summary of the trace t with ID id.
+func (t *localTrace) summary(id uint64) LocalTraceSummary {
+	root := traceutil.GetRoot(t.spans)
+	s := LocalTraceSummary{
+		TraceID:   strconv.FormatUint(id, 10),
+		Service:   root.Service,
+		Name:      root.Name,
+		Resource:  root.Resource,
+		Env:       t.env,
+		Hostname:  t.hostname,
+		Start:     root.Start,
+		Duration:  root.Duration,
+		SpanCount: len(t.spans),
+		Received:  t.received,
+	}
+	for _, span := range t.spans {
+		if span.Error != 0 {
+			s.Error = true
+			break
+		}
+	}
+	return s
+}
+
+// matches reports whether the trace t, summarized as s, matches q.
+func (q *LocalTracesQuery) matches(t *localTrace, s *LocalTraceSummary) bool {
+	if q.Error != nil && *q.Error != s.Error {
+		return false
+	}
+	if q.MinDuration > 0 && time.Duration(s.Duration) < q.MinDuration {
+		return false
+	}
+	if q.MaxDuration > 0 && time.Duration(s.Duration) > q.MaxDuration {
+		return false
+	}
+	if q.Service == "" {
+		return true
+	}
+	for _, span := range t.spans {
+		if span.Service == q.Service {
+			return true
+		}
+	}
+	return false
+}
+
+// Search returns the summaries of the traces matching q, the most recent first.
+func (l *LocalTraces) Search(q LocalTracesQuery) []LocalTraceSummary {
+	if l == nil {
+		return nil
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	res := []LocalTraceSummary{}
+	for i := range l.ring {
+		// walk the ring backwards from the most recent trace
+		id := l.ring[(l.next-1-i+2*len(l.ring))%len(l.ring)]
+		t := l.traces[id]
+		s := t.summary(id)
+		if !q.matches(t, &s) {
+			continue
+		}
+		res = append(res, s)
+		if q.Limit > 0 && len(res) >= q.Limit {
+			break
+		}
+	}
+	return res
+}
+
+// Get returns the trace with ID id, or nil if it is not kept.
+func (l *LocalTraces) Get(id uint64) *LocalTraceDetail {
+	if l == nil {
+		return nil
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	t, ok := l.traces[id]
+	if !ok {
+		return nil
+	}
+	return &LocalTraceDetail{
+		LocalTraceSummary: t.summary(id),
+		Spans:             append([]*pb.Span(nil), t.spans...),
+	}
+}
+
+// parseLocalTracesQuery parses the query parameters of a search request: service, error,
+// min_duration, max_duration (e.g. "150ms") and limit.
+func parseLocalTracesQuery(req *http.Request) (LocalTracesQuery, error) {
+	v := req.URL.Query()
+	q := LocalTracesQuery{
+		Service: v.Get("service"),
+		Limit:   defaultLocalTracesLimit,
+	}
+	var err error
+	if s := v.Get("error"); s != "" {
+		var b bool
+		if b, err = strconv.ParseBool(s); err != nil {
+			return q, err
+		}
+		q.Error = &b
+	}
+	if s := v.Get("min_duration"); s != "" {
+		if q.MinDuration, err = time.ParseDuration(s); err != nil {
+			return q, err
+		}
+	}
+	if s := v.Get("max_duration"); s != "" {
+		if q.MaxDuration, err = time.ParseDuration(s); err != nil {
+			return q, err
+		}
+	}
+	if s := v.Get("limit"); s != "" {
+		if q.Limit, err = strconv.Atoi(s); err != nil {
+			return q, err
+		}
+	}
+	return q, nil
+}
+
+// handleLocalTraces serves the search of the local traces on /debug/traces.
+func (r *HTTPReceiver) handleLocalTraces(w http.ResponseWriter, req *http.Request) {
+	q, err := parseLocalTracesQuery(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, r.LocalTraces.Search(q))
+}
+
+// handleLocalTrace serves the local trace whose ID ends the path, on /debug/traces/<id>.
+func (r *HTTPReceiver) handleLocalTrace(w http.ResponseWriter, req *http.Request) {
+	id, err := strconv.ParseUint(strings.TrimPrefix(req.URL.Path, "/debug/traces/"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid trace ID", http.StatusBadRequest)
+		return
+	}
+	t := r.LocalTraces.Get(id)
+	if t == nil {
+		http.Error(w, "trace not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, t)
+}
+
+// writeJSON writes v to w as JSON.
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
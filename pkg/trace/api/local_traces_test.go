@@ -0,0 +1,165 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testLocalChunk(traceID uint64, service string, duration time.Duration, isError bool) *pb.TraceChunk {
+	root := &pb.Span{TraceID: traceID, SpanID: 1, Service: service, Name: "http.request", Resource: "GET /", Duration: int64(duration)}
+	child := &pb.Span{TraceID: traceID, SpanID: 2, ParentID: 1, Service: "db", Name: "query", Resource: "SELECT ?", Duration: int64(duration / 2)}
+	if isError {
+		child.Error = 1
+	}
+	return &pb.TraceChunk{Spans: []*pb.Span{root, child}}
+}
+
+func traceIDs(summaries []LocalTraceSummary) []string {
+	ids := make([]string, 0, len(summaries))
+	for _, s := range summaries {
+		ids = append(ids, s.TraceID)
+	}
+	return ids
+}
+
+func TestLocalTraces(t *testing.T) {
+	tp := &pb.TracerPayload{Env: "dev", Hostname: "laptop"}
+
+	t.Run("search", func(t *testing.T) {
+		assert := assert.New(t)
+		l := NewLocalTraces(10, 100)
+		l.Add(tp, testLocalChunk(1, "web", 10*time.Millisecond, false))
+		l.Add(tp, testLocalChunk(2, "web", 200*time.Millisecond, true))
+		l.Add(tp, testLocalChunk(3, "api", 50*time.Millisecond, false))
+
+		all := l.Search(LocalTracesQuery{})
+		assert.Equal([]string{"3", "2", "1"}, traceIDs(all))
+		assert.Equal(LocalTraceSummary{
+			TraceID:   "2",
+			Service:   "web",
+			Name:      "http.request",
+			Resource:  "GET /",
+			Env:       "dev",
+			Hostname:  "laptop",
+			Duration:  int64(200 * time.Millisecond),
+			Error:     true,
+			SpanCount: 2,
+			Received:  all[1].Received,
+		}, all[1])
+
+		yes, no := true, false
+		assert.Equal([]string{"2", "1"}, traceIDs(l.Search(LocalTracesQuery{Service: "web"})))
+		assert.Equal([]string{"3", "2", "1"}, traceIDs(l.Search(LocalTracesQuery{Service: "db"})))
+		assert.Empty(l.Search(LocalTracesQuery{Service: "unknown"}))
+		assert.Equal([]string{"2"}, traceIDs(l.Search(LocalTracesQuery{Error: &yes})))
+		assert.Equal([]string{"3", "1"}, traceIDs(l.Search(LocalTracesQuery{Error: &no})))
+		assert.Equal([]string{"3", "2"}, traceIDs(l.Search(LocalTracesQuery{MinDuration: 50 * time.Millisecond})))
+		assert.Equal([]string{"3", "1"}, traceIDs(l.Search(LocalTracesQuery{MaxDuration: 100 * time.Millisecond})))
+		assert.Equal([]string{"3"}, traceIDs(l.Search(LocalTracesQuery{Limit: 1})))
+	})
+
+	t.Run("eviction", func(t *testing.T) {
+		assert := assert.New(t)
+		l := NewLocalTraces(2, 100)
+		for id := uint64(1); id <= 5; id++ {
+			l.Add(tp, testLocalChunk(id, "web", time.Millisecond, false))
+		}
+		assert.Equal([]string{"5", "4"}, traceIDs(l.Search(LocalTracesQuery{})))
+		assert.Nil(l.Get(3))
+		assert.NotNil(l.Get(4))
+	})
+
+	t.Run("chunks", func(t *testing.T) {
+		assert := assert.New(t)
+		l := NewLocalTraces(2, 100)
+		l.Add(tp, testLocalChunk(1, "web", time.Millisecond, false))
+		l.Add(tp, &pb.TraceChunk{Spans: []*pb.Span{{TraceID: 1, SpanID: 3, ParentID: 1}}})
+		l.Add(tp, &pb.TraceChunk{})
+		trace := l.Get(1)
+		require.NotNil(t, trace)
+		assert.Equal(3, trace.SpanCount)
+		assert.Len(trace.Spans, 3)
+		assert.Len(l.Search(LocalTracesQuery{}), 1)
+	})
+
+	t.Run("max-spans", func(t *testing.T) {
+		assert := assert.New(t)
+		l := NewLocalTraces(2, 3)
+		l.Add(tp, testLocalChunk(1, "web", time.Millisecond, false))
+		l.Add(tp, &pb.TraceChunk{Spans: []*pb.Span{{TraceID: 1, SpanID: 3}, {TraceID: 1, SpanID: 4}}})
+		l.Add(tp, &pb.TraceChunk{Spans: []*pb.Span{{TraceID: 1, SpanID: 5}}})
+		trace := l.Get(1)
+		require.NotNil(t, trace)
+		assert.Len(trace.Spans, 3)
+		assert.EqualValues(3, trace.Spans[2].SpanID)
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		var l *LocalTraces
+		l.Add(tp, testLocalChunk(1, "web", time.Millisecond, false))
+		assert.Nil(t, l.Search(LocalTracesQuery{}))
+		assert.Nil(t, l.Get(1))
+	})
+}
+
+func TestLocalTracesHandlers(t *testing.T) {
+	conf := newTestReceiverConfig()
+	conf.LocalTraces.Enabled = true
+	r := newTestReceiverFromConfig(conf)
+	require.NotNil(t, r.LocalTraces)
+	r.LocalTraces.Add(&pb.TracerPayload{}, testLocalChunk(42, "web", 200*time.Millisecond, true))
+	r.LocalTraces.Add(&pb.TracerPayload{}, testLocalChunk(43, "web", time.Millisecond, false))
+	mux := r.buildMux()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec
+	}
+
+	t.Run("search", func(t *testing.T) {
+		rec := get("/debug/traces?service=web&error=true&min_duration=100ms")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		var res []LocalTraceSummary
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		assert.Equal(t, []string{"42"}, traceIDs(res))
+
+		for _, q := range []string{"error=maybe", "min_duration=1", "max_duration=x", "limit=none"} {
+			assert.Equal(t, http.StatusBadRequest, get("/debug/traces?"+q).Code, q)
+		}
+	})
+
+	t.Run("get", func(t *testing.T) {
+		rec := get("/debug/traces/42")
+		require.Equal(t, http.StatusOK, rec.Code)
+		var res LocalTraceDetail
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		assert.Equal(t, "42", res.TraceID)
+		assert.Len(t, res.Spans, 2)
+
+		assert.Equal(t, http.StatusNotFound, get("/debug/traces/7").Code)
+		assert.Equal(t, http.StatusBadRequest, get("/debug/traces/abc").Code)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		r := newTestReceiverFromConfig(newTestReceiverConfig())
+		assert.Nil(t, r.LocalTraces)
+		rec := httptest.NewRecorder()
+		r.buildMux().ServeHTTP(rec, httptest.NewRequest("GET", "/debug/traces", nil))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
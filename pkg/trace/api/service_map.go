@@ -0,0 +1,33 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+)
+
+// handleServiceMap serves the service map published by the agent on /debug/service_map,
+// as JSON or, with the format=dot query parameter, as a Graphviz DOT graph.
+func handleServiceMap(w http.ResponseWriter, req *http.Request) {
+	edges := info.ServiceMap()
+	switch req.URL.Query().Get("format") {
+	case "", "json":
+		writeJSON(w, edges)
+	case "dot":
+		var buf bytes.Buffer
+		if err := info.WriteServiceMapDOT(&buf, edges); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/vnd.graphviz")
+		w.Write(buf.Bytes()) //nolint:errcheck
+	default:
+		http.Error(w, "unknown format, valid formats are json and dot", http.StatusBadRequest)
+	}
+}
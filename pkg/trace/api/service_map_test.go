@@ -0,0 +1,49 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceMapHandler(t *testing.T) {
+	info.UpdateServiceMap([]info.ServiceMapEdge{{Env: "prod", Client: "web", Server: "users", Calls: 3}})
+	defer info.UpdateServiceMap(nil)
+	conf := newTestReceiverConfig()
+	conf.ServiceMap.Enabled = true
+	mux := newTestReceiverFromConfig(conf).buildMux()
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec
+	}
+
+	rec := get("/debug/service_map")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var edges []info.ServiceMapEdge
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &edges))
+	assert.Equal(t, info.ServiceMap(), edges)
+
+	rec = get("/debug/service_map?format=dot")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/vnd.graphviz", rec.Header().Get("Content-Type"))
+	assert.True(t, strings.HasPrefix(rec.Body.String(), "digraph service_map {"))
+
+	assert.Equal(t, http.StatusBadRequest, get("/debug/service_map?format=svg").Code)
+
+	rec = httptest.NewRecorder()
+	newTestReceiverFromConfig(newTestReceiverConfig()).buildMux().ServeHTTP(rec, httptest.NewRequest("GET", "/debug/service_map", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,290 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// zipkinFormat names the Zipkin v2 format in the names of the spans and in telemetry.
+const zipkinFormat = "zipkin"
+
+// zipkinSpan is a span of the Zipkin v2 API, see https://zipkin.io/zipkin-api/#/default/post_spans.
+type zipkinSpan struct {
+	TraceID        string             `json:"traceId"`
+	ParentID       string             `json:"parentId"`
+	ID             string             `json:"id"`
+	Kind           string             `json:"kind"`
+	Name           string             `json:"name"`
+	Timestamp      int64              `json:"timestamp"` // epoch microseconds
+	Duration       int64              `json:"duration"`  // microseconds
+	Debug          bool               `json:"debug"`
+	Shared         bool               `json:"shared"`
+	LocalEndpoint  *zipkinEndpoint    `json:"localEndpoint"`
+	RemoteEndpoint *zipkinEndpoint    `json:"remoteEndpoint"`
+	Annotations    []zipkinAnnotation `json:"annotations"`
+	Tags           map[string]string  `json:"tags"`
+}
+
+type zipkinEndpoint struct {
+	ServiceName string `json:"serviceName"`
+	IPv4        string `json:"ipv4"`
+	IPv6        string `json:"ipv6"`
+	Port        int32  `json:"port"`
+}
+
+type zipkinAnnotation struct {
+	Timestamp int64  `json:"timestamp"` // epoch microseconds
+	Value     string `json:"value"`
+}
+
+// decodeZipkin decodes a list of Zipkin v2 spans encoded in JSON or, depending on the
+// request content type, in Protobuf.
+func decodeZipkin(req *http.Request, body []byte) (*foreignSpans, error) {
+	var (
+		spans []*zipkinSpan
+		err   error
+	)
+	switch getMediaType(req) {
+	case "application/x-protobuf", "application/protobuf":
+		spans, err = unmarshalZipkinProto(body)
+	default:
+		err = json.Unmarshal(body, &spans)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out foreignSpans
+	for _, s := range spans {
+		span, err := convertZipkinSpan(s)
+		if err != nil {
+			return nil, err
+		}
+		out.add(span, s.Debug)
+	}
+	return &out, nil
+}
+
+// convertZipkinSpan converts the Zipkin span in to a Datadog span.
+func convertZipkinSpan(in *zipkinSpan) (*pb.Span, error) {
+	traceID, traceIDHigh, err := parseHexID(in.TraceID)
+	if err != nil {
+		return nil, fmt.Errorf("traceId: %v", err)
+	}
+	spanID, _, err := parseHexID(in.ID)
+	if err != nil {
+		return nil, fmt.Errorf("id: %v", err)
+	}
+	var parentID uint64
+	if in.ParentID != "" {
+		if parentID, _, err = parseHexID(in.ParentID); err != nil {
+			return nil, fmt.Errorf("parentId: %v", err)
+		}
+	}
+	kind := strings.ToLower(in.Kind)
+	if in.Shared && kind == "server" {
+		parentID, spanID = spanID, sharedSpanID(spanID)
+	}
+	span := &pb.Span{
+		TraceID:  traceID,
+		SpanID:   spanID,
+		ParentID: parentID,
+		Start:    in.Timestamp * 1000,
+		Duration: in.Duration * 1000,
+		Meta:     make(map[string]string, len(in.Tags)+2),
+		Metrics:  map[string]float64{},
+	}
+	if traceIDHigh != 0 {
+		span.Meta["zipkin.trace_id"] = in.TraceID
+	}
+	if e := in.LocalEndpoint; e != nil {
+		span.Service = e.ServiceName
+	}
+	if e := in.RemoteEndpoint; e != nil {
+		if e.ServiceName != "" {
+			span.Meta["peer.service"] = e.ServiceName
+		}
+		if host := e.IPv4; host != "" {
+			span.Meta["out.host"] = host
+		} else if host := e.IPv6; host != "" {
+			span.Meta["out.host"] = host
+		}
+		if e.Port != 0 {
+			span.Metrics["out.port"] = float64(e.Port)
+		}
+	}
+	for k, v := range in.Tags {
+		span.Meta[k] = v
+	}
+	if msg, ok := in.Tags["error"]; ok {
+		span.Error = 1
+		delete(span.Meta, "error")
+		if msg != "" && msg != "true" {
+			span.Meta["error.msg"] = msg
+		}
+	}
+	if len(in.Annotations) > 0 {
+		events := make([]foreignEvent, 0, len(in.Annotations))
+		for _, a := range in.Annotations {
+			events = append(events, foreignEvent{TimeUnixNano: a.Timestamp * 1000, Name: a.Value})
+		}
+		span.Meta["events"] = marshalForeignEvents(events)
+	}
+	finishForeignSpan(zipkinFormat, kind, in.Name, span)
+	return span, nil
+}
+
+// zipkinProtoKinds holds the names of the span kinds of the Zipkin Protobuf encoding.
+var zipkinProtoKinds = []string{"", "CLIENT", "SERVER", "PRODUCER", "CONSUMER"}
+
+// errInvalidProto is returned when decoding invalid Protobuf messages.
+var errInvalidProto = errors.New("invalid protobuf message")
+
+// protoFields calls f with the number, type and value of the fields of the Protobuf message b.
+// Values are the bytes of length-delimited fields, or the varint or fixed integer of other ones.
+func protoFields(b []byte, f func(num protowire.Number, typ protowire.Type, v []byte, n uint64) error) error {
+	for len(b) > 0 {
+		num, typ, l := protowire.ConsumeTag(b)
+		if l < 0 {
+			return errInvalidProto
+		}
+		b = b[l:]
+		var (
+			v []byte
+			n uint64
+		)
+		switch typ {
+		case protowire.VarintType:
+			n, l = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			n, l = protowire.ConsumeFixed64(b)
+		case protowire.Fixed32Type:
+			var n32 uint32
+			n32, l = protowire.ConsumeFixed32(b)
+			n = uint64(n32)
+		case protowire.BytesType:
+			v, l = protowire.ConsumeBytes(b)
+		default:
+			l = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if l < 0 {
+			return errInvalidProto
+		}
+		b = b[l:]
+		if err := f(num, typ, v, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unmarshalZipkinProto decodes the zipkin.proto3.ListOfSpans message b.
+func unmarshalZipkinProto(b []byte) ([]*zipkinSpan, error) {
+	var spans []*zipkinSpan
+	err := protoFields(b, func(num protowire.Number, _ protowire.Type, v []byte, _ uint64) error {
+		if num != 1 {
+			return nil
+		}
+		s, err := unmarshalZipkinProtoSpan(v)
+		spans = append(spans, s)
+		return err
+	})
+	return spans, err
+}
+
+func unmarshalZipkinProtoSpan(b []byte) (*zipkinSpan, error) {
+	var s zipkinSpan
+	err := protoFields(b, func(num protowire.Number, _ protowire.Type, v []byte, n uint64) error {
+		var err error
+		switch num {
+		case 1:
+			s.TraceID = hex.EncodeToString(v)
+		case 2:
+			s.ParentID = hex.EncodeToString(v)
+		case 3:
+			s.ID = hex.EncodeToString(v)
+		case 4:
+			if n < uint64(len(zipkinProtoKinds)) {
+				s.Kind = zipkinProtoKinds[n]
+			}
+		case 5:
+			s.Name = string(v)
+		case 6:
+			s.Timestamp = int64(n)
+		case 7:
+			s.Duration = int64(n)
+		case 8:
+			s.LocalEndpoint, err = unmarshalZipkinProtoEndpoint(v)
+		case 9:
+			s.RemoteEndpoint, err = unmarshalZipkinProtoEndpoint(v)
+		case 10:
+			var a zipkinAnnotation
+			err = protoFields(v, func(num protowire.Number, _ protowire.Type, v []byte, n uint64) error {
+				switch num {
+				case 1:
+					a.Timestamp = int64(n)
+				case 2:
+					a.Value = string(v)
+				}
+				return nil
+			})
+			s.Annotations = append(s.Annotations, a)
+		case 11:
+			var k, val string
+			err = protoFields(v, func(num protowire.Number, _ protowire.Type, v []byte, _ uint64) error {
+				switch num {
+				case 1:
+					k = string(v)
+				case 2:
+					val = string(v)
+				}
+				return nil
+			})
+			if s.Tags == nil {
+				s.Tags = make(map[string]string)
+			}
+			s.Tags[k] = val
+		case 12:
+			s.Debug = n != 0
+		case 13:
+			s.Shared = n != 0
+		}
+		return err
+	})
+	return &s, err
+}
+
+func unmarshalZipkinProtoEndpoint(b []byte) (*zipkinEndpoint, error) {
+	var e zipkinEndpoint
+	err := protoFields(b, func(num protowire.Number, _ protowire.Type, v []byte, n uint64) error {
+		switch num {
+		case 1:
+			e.ServiceName = string(v)
+		case 2:
+			e.IPv4 = net.IP(v).String()
+		case 3:
+			e.IPv6 = net.IP(v).String()
+		case 4:
+			if n > math.MaxUint16 {
+				return errInvalidProto
+			}
+			e.Port = int32(n)
+		}
+		return nil
+	})
+	return &e, err
+}
@@ -0,0 +1,217 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const testZipkinJSON = `[
+  {
+    "traceId": "5af7183fb1d4cf5f0000000000000abc",
+    "id": "352bff9a74ca9ad2",
+    "kind": "CLIENT",
+    "name": "get /api",
+    "timestamp": 1556604172355737,
+    "duration": 1431,
+    "localEndpoint": {"serviceName": "frontend", "ipv4": "192.168.99.1"},
+    "remoteEndpoint": {"serviceName": "backend", "ipv4": "172.19.0.2", "port": 8080},
+    "annotations": [{"timestamp": 1556604172355800, "value": "ws"}],
+    "tags": {"http.method": "GET", "http.route": "/api", "error": "connection refused"}
+  },
+  {
+    "traceId": "5af7183fb1d4cf5f0000000000000abc",
+    "id": "352bff9a74ca9ad2",
+    "kind": "SERVER",
+    "name": "get /api",
+    "timestamp": 1556604172355900,
+    "duration": 1200,
+    "shared": true,
+    "debug": true,
+    "localEndpoint": {"serviceName": "backend"}
+  }
+]`
+
+func TestDecodeZipkin(t *testing.T) {
+	t.Run("json", func(t *testing.T) {
+		assert := assert.New(t)
+		req := httptest.NewRequest("POST", "/api/v2/spans", nil)
+		req.Header.Set("Content-Type", "application/json")
+		out, err := decodeZipkin(req, []byte(testZipkinJSON))
+		require.NoError(t, err)
+		require.Len(t, out.spans, 2)
+
+		client, server := out.spans[0], out.spans[1]
+		assert.Equal(uint64(0xabc), client.TraceID)
+		assert.Equal(uint64(0x352bff9a74ca9ad2), client.SpanID)
+		assert.Equal(uint64(0), client.ParentID)
+		assert.Equal("frontend", client.Service)
+		assert.Equal("zipkin.client", client.Name)
+		assert.Equal("GET /api", client.Resource)
+		assert.Equal("http", client.Type)
+		assert.Equal(int64(1556604172355737000), client.Start)
+		assert.Equal(int64(1431000), client.Duration)
+		assert.Equal(int32(1), client.Error)
+		assert.Equal("connection refused", client.Meta["error.msg"])
+		assert.Equal("backend", client.Meta["peer.service"])
+		assert.Equal("172.19.0.2", client.Meta["out.host"])
+		assert.Equal(float64(8080), client.Metrics["out.port"])
+		assert.Equal("5af7183fb1d4cf5f0000000000000abc", client.Meta["zipkin.trace_id"])
+		assert.Equal(`[{"time_unix_nano":1556604172355800000,"name":"ws"}]`, client.Meta["events"])
+		assert.NotContains(client.Meta, "error")
+
+		// the shared server side becomes a child of the client side
+		assert.Equal(client.SpanID, server.ParentID)
+		assert.NotEqual(client.SpanID, server.SpanID)
+		assert.Equal("zipkin.server", server.Name)
+		assert.Equal("web", server.Type)
+		assert.True(out.debug[server.TraceID])
+	})
+
+	t.Run("proto", func(t *testing.T) {
+		assert := assert.New(t)
+		var endpoint, span, list []byte
+		endpoint = protowire.AppendTag(endpoint, 1, protowire.BytesType)
+		endpoint = protowire.AppendString(endpoint, "frontend")
+		endpoint = protowire.AppendTag(endpoint, 4, protowire.VarintType)
+		endpoint = protowire.AppendVarint(endpoint, 8080)
+		span = protowire.AppendTag(span, 1, protowire.BytesType)
+		span = protowire.AppendBytes(span, []byte{0, 0, 0, 0, 0, 0, 0x0a, 0xbc})
+		span = protowire.AppendTag(span, 3, protowire.BytesType)
+		span = protowire.AppendBytes(span, []byte{0, 0, 0, 0, 0, 0, 0, 0x02})
+		span = protowire.AppendTag(span, 4, protowire.VarintType)
+		span = protowire.AppendVarint(span, 2)
+		span = protowire.AppendTag(span, 5, protowire.BytesType)
+		span = protowire.AppendString(span, "get /api")
+		span = protowire.AppendTag(span, 6, protowire.Fixed64Type)
+		span = protowire.AppendFixed64(span, 1556604172355737)
+		span = protowire.AppendTag(span, 7, protowire.VarintType)
+		span = protowire.AppendVarint(span, 1431)
+		span = protowire.AppendTag(span, 8, protowire.BytesType)
+		span = protowire.AppendBytes(span, endpoint)
+		span = protowire.AppendTag(span, 9, protowire.BytesType)
+		span = protowire.AppendBytes(span, endpoint)
+		var tag []byte
+		tag = protowire.AppendTag(tag, 1, protowire.BytesType)
+		tag = protowire.AppendString(tag, "http.method")
+		tag = protowire.AppendTag(tag, 2, protowire.BytesType)
+		tag = protowire.AppendString(tag, "GET")
+		span = protowire.AppendTag(span, 11, protowire.BytesType)
+		span = protowire.AppendBytes(span, tag)
+		list = protowire.AppendTag(list, 1, protowire.BytesType)
+		list = protowire.AppendBytes(list, span)
+
+		req := httptest.NewRequest("POST", "/api/v2/spans", nil)
+		req.Header.Set("Content-Type", "application/x-protobuf")
+		out, err := decodeZipkin(req, list)
+		require.NoError(t, err)
+		require.Len(t, out.spans, 1)
+		s := out.spans[0]
+		assert.Equal(uint64(0xabc), s.TraceID)
+		assert.Equal(uint64(2), s.SpanID)
+		assert.Equal("zipkin.server", s.Name)
+		assert.Equal("frontend", s.Service)
+		assert.Equal("GET", s.Meta["http.method"])
+		assert.Equal(float64(8080), s.Metrics["out.port"])
+		assert.Equal(int64(1431000), s.Duration)
+
+		_, err = decodeZipkin(req, list[:len(list)-3])
+		assert.Error(err)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/v2/spans", nil)
+		_, err := decodeZipkin(req, []byte(`[{"traceId": "xyz", "id": "1"}]`))
+		assert.Error(t, err)
+		_, err = decodeZipkin(req, []byte(`{`))
+		assert.Error(t, err)
+	})
+}
+
+func TestHandleZipkinSpans(t *testing.T) {
+	assert := assert.New(t)
+	conf := newTestReceiverConfig()
+	conf.Zipkin.Enabled = true
+	r := newTestReceiverFromConfig(conf)
+	h := r.handleForeignSpans("zipkin_v2", decodeZipkin)
+
+	req := httptest.NewRequest("POST", "/api/v2/spans", bytes.NewBufferString(testZipkinJSON))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(headerContainerID, "abc123")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(http.StatusAccepted, rec.Code)
+
+	p := <-r.out
+	assert.Equal("zipkin_v2", p.Source.EndpointVersion)
+	assert.Equal("abc123", p.TracerPayload.ContainerID)
+	require.Len(t, p.TracerPayload.Chunks, 1)
+	chunk := p.TracerPayload.Chunks[0]
+	assert.Equal(int32(sampler.PriorityUserKeep), chunk.Priority)
+	assert.Len(chunk.Spans, 2)
+	assert.EqualValues(1, p.Source.TracesReceived)
+
+	req = httptest.NewRequest("POST", "/api/v2/spans", strings.NewReader(`[{`))
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(http.StatusBadRequest, rec.Code)
+	assert.EqualValues(1, p.Source.TracesDropped.DecodingError)
+}
+
+func TestForeignSpansChunks(t *testing.T) {
+	var f foreignSpans
+	f.add(&pb.Span{TraceID: 1, SpanID: 1}, false)
+	f.add(&pb.Span{TraceID: 2, SpanID: 2}, true)
+	f.add(&pb.Span{TraceID: 1, SpanID: 3}, false)
+	priorities := make(map[uint64]int32)
+	for _, c := range f.chunks() {
+		priorities[c.Spans[0].TraceID] = c.Priority
+		if c.Spans[0].TraceID == 1 {
+			assert.Len(t, c.Spans, 2)
+		}
+	}
+	assert.Equal(t, map[uint64]int32{
+		1: int32(sampler.PriorityAutoKeep),
+		2: int32(sampler.PriorityUserKeep),
+	}, priorities)
+}
+
+func TestParseHexID(t *testing.T) {
+	for _, tt := range []struct {
+		in        string
+		low, high uint64
+		err       bool
+	}{
+		{in: "1", low: 1},
+		{in: "352bff9a74ca9ad2", low: 0x352bff9a74ca9ad2},
+		{in: "5af7183fb1d4cf5f0000000000000abc", low: 0xabc, high: 0x5af7183fb1d4cf5f},
+		{in: "", err: true},
+		{in: "xyz", err: true},
+		{in: "5af7183fb1d4cf5f0000000000000abc1", err: true},
+	} {
+		t.Run(tt.in, func(t *testing.T) {
+			low, high, err := parseHexID(tt.in)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.low, low)
+			assert.Equal(t, tt.high, high)
+		})
+	}
+}
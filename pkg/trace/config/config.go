@@ -54,6 +54,54 @@ type OTLP struct {
 	MaxRequestBytes int64 `mapstructure:"-"`
 }
 
+// ZipkinConfig holds the configuration for the Zipkin receiver.
+type ZipkinConfig struct {
+	// Enabled reports whether the HTTP receiver accepts Zipkin v2 spans, encoded in
+	// JSON or Protobuf, on /api/v2/spans.
+	Enabled bool
+}
+
+// JaegerConfig holds the configuration for the Jaeger receiver.
+type JaegerConfig struct {
+	// Enabled reports whether the HTTP receiver accepts Jaeger Thrift batches on /api/traces.
+	Enabled bool
+
+	// GRPCPort specifies the port of the Jaeger gRPC collector.
+	// If unset (or 0), the gRPC collector will be off.
+	GRPCPort int
+}
+
+// LocalTracesConfig holds the configuration for the local trace search API, which keeps
+// the most recent traces sent by the agent in memory so that they can be inspected.
+type LocalTracesConfig struct {
+	// Enabled reports whether the traces are kept and served on /debug/traces.
+	Enabled bool
+
+	// MaxTraces is the maximum number of traces kept. Once reached, the oldest
+	// trace is evicted.
+	MaxTraces int
+
+	// MaxSpansPerTrace is the maximum number of spans kept per trace. The spans
+	// of a trace received once it is reached are dropped.
+	MaxSpansPerTrace int
+}
+
+// ServiceMapConfig holds the configuration of the service map, derived from the calls
+// between services observed in the traces received by the agent.
+type ServiceMapConfig struct {
+	// Enabled reports whether the service map is computed, published and served
+	// on /debug/service_map.
+	Enabled bool
+
+	// MaxEdges is the maximum number of edges in the service map. Once reached,
+	// a new edge replaces the least recently seen one.
+	MaxEdges int
+
+	// EdgeTTL is the duration after which an edge without new calls is removed from
+	// the service map. Zero keeps the edges until MaxEdges is reached.
+	EdgeTTL time.Duration
+}
+
 // ObfuscationConfig holds the configuration for obfuscating sensitive data
 // for various span types.
 type ObfuscationConfig struct {
@@ -85,8 +133,50 @@ type ObfuscationConfig struct {
 	// for spans of type "memcached".
 	Memcached Enablable `mapstructure:"memcached"`
 
+	// GraphQL holds the configuration for obfuscating the "graphql.query" and "graphql.source"
+	// tags, and the resource when it is a query document, for spans of type "graphql".
+	GraphQL Enablable `mapstructure:"graphql"`
+
+	// DynamoDB holds the configuration for obfuscating the PartiQL statements of the
+	// "dynamodb.statement" tag and the condition, filter and update expressions of
+	// spans of type "dynamodb".
+	DynamoDB Enablable `mapstructure:"dynamodb"`
+
 	// CreditCards holds the configuration for obfuscating credit cards.
 	CreditCards CreditCardsConfig `mapstructure:"credit_cards"`
+
+	// SensitiveData holds the rules scrubbing sensitive data from the values of all the
+	// span tags. They are applied in order.
+	SensitiveData []*SensitiveDataRule `mapstructure:"sensitive_data"`
+}
+
+// SensitiveDataRule specifies sensitive data to scrub from span tag values. Exactly one of
+// Keys, Pattern or Builtin must be set.
+type SensitiveDataRule struct {
+	// Name identifies the rule in the agent status. It defaults to Builtin.
+	Name string `mapstructure:"name"`
+
+	// Keys are the tag keys whose values are scrubbed entirely. They are case-insensitive
+	// and also match the last segments of dotted keys, e.g. "password" matches "db.password".
+	Keys []string `mapstructure:"keys"`
+
+	// Pattern is a regular expression matching the sensitive parts of the tag values.
+	Pattern string `mapstructure:"pattern"`
+
+	// Builtin is the name of a predefined scanner: "email", "jwt", "iban" or "credit_card".
+	Builtin string `mapstructure:"builtin"`
+
+	// Action is "redact" (default) to replace the sensitive data with Replacement, or
+	// "hash" to replace it with its HMAC-SHA256 keyed with HashKey, keeping equal values
+	// correlated.
+	Action string `mapstructure:"action"`
+
+	// HashKey is the secret keying the hashes of the "hash" action, which requires it.
+	// Without it, the hashes of guessable values such as IDs or emails could be reversed.
+	HashKey string `mapstructure:"hash_key"`
+
+	// Replacement is the text replacing the redacted data. It defaults to "?".
+	Replacement string `mapstructure:"replacement"`
 }
 
 // AppSecConfig ...
@@ -225,6 +315,130 @@ type WriterConfig struct {
 	FlushPeriodSeconds float64 `mapstructure:"flush_period_seconds"`
 }
 
+// DiskQueueConfig holds the configuration of the on-disk retry queue of the writers. When
+// enabled, the payloads that would be dropped because the intake is unreachable and the
+// sender queue is full are stored on disk, and sent again once the intake is back, including
+// after a restart of the agent.
+type DiskQueueConfig struct {
+	// Enabled reports whether payloads are stored on disk instead of being dropped.
+	Enabled bool
+
+	// Path is the directory where the payloads are stored.
+	Path string
+
+	// MaxSizeBytes is the maximum disk space used by the payloads of each writer. The
+	// oldest payloads are removed to make room for new ones.
+	MaxSizeBytes int64
+
+	// MaxAge is the age after which a stored payload is discarded instead of being sent.
+	MaxAge time.Duration
+}
+
+// SpanRule matches spans and specifies the actions to take on them and on their trace.
+// A span matches when all the criteria set on the rule match.
+type SpanRule struct {
+	// Name identifies the rule in the telemetry.
+	Name string `mapstructure:"name"`
+
+	// MatchType is the syntax of the patterns of the rule: "glob" (default) or "regex".
+	// Glob patterns support "*" and "?" wildcards and match whole values.
+	MatchType string `mapstructure:"match_type"`
+
+	// Service, OperationName and Resource are patterns matching the span fields.
+	Service       string `mapstructure:"service"`
+	OperationName string `mapstructure:"operation_name"`
+	Resource      string `mapstructure:"resource"`
+
+	// Meta and Metrics map tag keys to patterns matching their values. Metrics
+	// values are formatted in their shortest decimal representation, e.g. "200".
+	Meta    map[string]string `mapstructure:"meta"`
+	Metrics map[string]string `mapstructure:"metrics"`
+
+	// MinDurationMs and MaxDurationMs bound the duration of the spans matched, in milliseconds.
+	// Zero means no bound.
+	MinDurationMs float64 `mapstructure:"min_duration_ms"`
+	MaxDurationMs float64 `mapstructure:"max_duration_ms"`
+
+	// Action is the decision taken on the trace of a matched span: "drop" drops the trace,
+	// "keep" forces it to be kept and "sample" keeps it with the probability SampleRate.
+	// It can be left empty for rules only changing tags.
+	Action     string  `mapstructure:"action"`
+	SampleRate float64 `mapstructure:"sample_rate"`
+
+	// AddTags and RemoveTags are applied to the matched spans.
+	AddTags    map[string]string `mapstructure:"add_tags"`
+	RemoveTags []string          `mapstructure:"remove_tags"`
+}
+
+// SpanMetric is a custom metric generated from the spans matching its criteria, which
+// are the same as the ones of a SpanRule.
+type SpanMetric struct {
+	// Name is the name of the metric.
+	Name string `mapstructure:"name"`
+
+	// Type is "count", counting the spans matched, or "distribution", submitting
+	// the value of each span matched.
+	Type string `mapstructure:"type"`
+
+	// Value is the key of the meta or metrics tag holding the value of a distribution,
+	// or "@duration" for the duration of the span in seconds.
+	Value string `mapstructure:"value"`
+
+	// GroupBy are the keys of the tags of the spans matched used to tag the metric. The
+	// "service", "operation_name" and "resource" keys refer to the span fields. Values
+	// should have a low cardinality.
+	GroupBy []string `mapstructure:"group_by"`
+
+	MatchType     string            `mapstructure:"match_type"`
+	Service       string            `mapstructure:"service"`
+	OperationName string            `mapstructure:"operation_name"`
+	Resource      string            `mapstructure:"resource"`
+	Meta          map[string]string `mapstructure:"meta"`
+	Metrics       map[string]string `mapstructure:"metrics"`
+	MinDurationMs float64           `mapstructure:"min_duration_ms"`
+	MaxDurationMs float64           `mapstructure:"max_duration_ms"`
+}
+
+// TailSamplingConfig specifies the configuration of the tail-based sampling. When enabled,
+// the chunks of a trace are buffered for DecisionWait before deciding whether to keep
+// the trace based on all the spans received in the meantime.
+type TailSamplingConfig struct {
+	Enabled bool
+
+	// DecisionWait is the time to wait for the chunks of a trace before deciding.
+	DecisionWait time.Duration
+
+	// MaxTraces is the maximum number of traces buffered. When it is reached,
+	// the decision for the oldest trace is taken early.
+	MaxTraces int
+
+	// Policies are the rules deciding whether to keep a trace. A trace is
+	// kept as soon as one of them keeps it.
+	Policies []*TailSamplingPolicy
+}
+
+// TailSamplingPolicy is a rule of the tail-based sampling.
+type TailSamplingPolicy struct {
+	// Name identifies the policy in the telemetry.
+	Name string `mapstructure:"name"`
+
+	// Type is one of "latency", "error", "attribute" or "rate".
+	Type string `mapstructure:"type"`
+
+	// ThresholdMs is the minimum duration of the traces kept by a "latency" policy, in milliseconds.
+	ThresholdMs float64 `mapstructure:"threshold_ms"`
+
+	// Key and Values select the spans of the traces kept by an "attribute" policy.
+	// When Values is empty, any span with the tag Key matches.
+	Key    string   `mapstructure:"key"`
+	Values []string `mapstructure:"values"`
+
+	// Service and Rate are used by a "rate" policy, which keeps a ratio of the traces
+	// whose root span has the service Service, or of all the traces if it is empty.
+	Service string  `mapstructure:"service"`
+	Rate    float64 `mapstructure:"rate"`
+}
+
 // FargateOrchestratorName is a Fargate orchestrator name.
 type FargateOrchestratorName string
 
@@ -278,8 +492,13 @@ type AgentConfig struct {
 	Endpoints []*Endpoint
 
 	// Concentrator
-	BucketInterval   time.Duration // the size of our pre-aggregation per bucket
+	BucketInterval time.Duration // the size of our pre-aggregation per bucket
+	// ExtraAggregators holds the span tags used as extra dimensions of the stats, in addition
+	// to the service, name, resource, type, status code and synthetics.
 	ExtraAggregators []string
+	// ExtraAggregatorsMaxCardinality is the maximum number of distinct values of each extra
+	// dimension per stats bucket. Values beyond it are aggregated together. 0 means no limit.
+	ExtraAggregatorsMaxCardinality int
 
 	// Sampler configuration
 	ExtraSampleRate    float64
@@ -288,6 +507,7 @@ type AgentConfig struct {
 	DisableRareSampler bool
 	MaxEPS             float64
 	MaxRemoteTPS       float64
+	TailSampling       TailSamplingConfig
 
 	// Receiver
 	ReceiverHost    string
@@ -307,6 +527,7 @@ type AgentConfig struct {
 	SynchronousFlushing     bool // Mode where traces are only submitted when FlushAsync is called, used for Serverless Extension
 	StatsWriter             *WriterConfig
 	TraceWriter             *WriterConfig
+	DiskQueue               DiskQueueConfig
 	ConnectionResetInterval time.Duration // frequency at which outgoing connections are reset. 0 means no reset is performed
 
 	// internal telemetry
@@ -332,6 +553,16 @@ type AgentConfig struct {
 	// filtering
 	Ignore map[string][]string
 
+	// SpanRules are matched against all the spans received, in order, to filter and
+	// sample their traces or change their tags.
+	SpanRules []*SpanRule
+
+	// SpanMetrics are custom metrics generated from the spans received.
+	SpanMetrics []*SpanMetric
+	// SpanMetricsMaxCardinality is the maximum number of distinct values of each group_by
+	// tag of a span metric every 10 seconds. Values beyond it are reported together. 0 means no limit.
+	SpanMetricsMaxCardinality int
+
 	// ReplaceTags is used to filter out sensitive information from tag values.
 	// It maps tag keys to a set of replacements. Only supported in A6.
 	ReplaceTags []*ReplaceRule
@@ -358,6 +589,18 @@ type AgentConfig struct {
 	// OTLPReceiver holds the configuration for OpenTelemetry receiver.
 	OTLPReceiver *OTLP
 
+	// Zipkin holds the configuration for the Zipkin receiver.
+	Zipkin ZipkinConfig
+
+	// Jaeger holds the configuration for the Jaeger receiver.
+	Jaeger JaegerConfig
+
+	// LocalTraces holds the configuration for the local trace search API.
+	LocalTraces LocalTracesConfig
+
+	// ServiceMap holds the configuration of the service map.
+	ServiceMap ServiceMapConfig
+
 	// ProfilingProxy specifies settings for the profiling proxy.
 	ProfilingProxy ProfilingProxyConfig
 
@@ -412,13 +655,27 @@ func New() *AgentConfig {
 		Site:                "datadoghq.com",
 		MaxCatalogEntries:   5000,
 
-		BucketInterval: time.Duration(10) * time.Second,
+		BucketInterval:                 time.Duration(10) * time.Second,
+		ExtraAggregatorsMaxCardinality: 100,
+		SpanMetricsMaxCardinality:      100,
 
 		ExtraSampleRate: 1.0,
 		TargetTPS:       10,
 		ErrorTPS:        10,
 		MaxEPS:          200,
 		MaxRemoteTPS:    100,
+		TailSampling: TailSamplingConfig{
+			DecisionWait: 10 * time.Second,
+			MaxTraces:    50000,
+		},
+		LocalTraces: LocalTracesConfig{
+			MaxTraces:        1000,
+			MaxSpansPerTrace: 5000,
+		},
+		ServiceMap: ServiceMapConfig{
+			MaxEdges: 1000,
+			EdgeTTL:  10 * time.Minute,
+		},
 
 		ReceiverHost:           "localhost",
 		ReceiverPort:           8126,
@@ -430,6 +687,10 @@ func New() *AgentConfig {
 		StatsWriter:             new(WriterConfig),
 		TraceWriter:             new(WriterConfig),
 		ConnectionResetInterval: 0, // disabled
+		DiskQueue: DiskQueueConfig{
+			MaxSizeBytes: 500 * 1024 * 1024, // 500MB
+			MaxAge:       24 * time.Hour,
+		},
 
 		StatsdHost: "localhost",
 		StatsdPort: 8125,
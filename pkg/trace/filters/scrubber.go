@@ -0,0 +1,234 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package filters
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"github.com/DataDog/datadog-agent/pkg/obfuscate"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+)
+
+// builtinPatterns holds the patterns of the predefined sensitive data scanners.
+var builtinPatterns = map[string]*regexp.Regexp{
+	"email": regexp.MustCompile(`[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}`),
+	"jwt":   regexp.MustCompile(`eyJ[a-zA-Z0-9_\-]+\.eyJ[a-zA-Z0-9_\-]+\.[a-zA-Z0-9_\-]*`),
+	"iban":  regexp.MustCompile(`\b[A-Z]{2}[0-9]{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,4})?\b`),
+}
+
+// scrubRule is a compiled config.SensitiveDataRule.
+type scrubRule struct {
+	// keys holds the lower-cased tag keys whose values are scrubbed entirely.
+	keys []string
+	// re matches the sensitive parts of the tag values.
+	re *regexp.Regexp
+	// validate, if set, reports whether a match of re is sensitive data.
+	validate func(string) bool
+	// value, if set, reports whether a whole tag value is sensitive data.
+	value func(string) bool
+	// hashKey, if set, is the secret keying the HMAC-SHA256 replacing the sensitive data
+	// instead of redacting it.
+	hashKey     []byte
+	replacement string
+	hits        *int64
+}
+
+// Scrubber is a filter scrubbing sensitive data from the tag values of spans. It keeps
+// all spans.
+type Scrubber struct {
+	rules []*scrubRule
+}
+
+// NewScrubber returns a Scrubber applying as many of the given rules as possible.
+// Invalid rules are reported and ignored.
+func NewScrubber(rules []*config.SensitiveDataRule) *Scrubber {
+	compiled := make([]*scrubRule, 0, len(rules))
+	for i, r := range rules {
+		rule, err := compileScrubRule(r)
+		if err != nil {
+			log.Errorf("Invalid sensitive data rule #%d %q: %v", i, r.Name, err)
+			continue
+		}
+		compiled = append(compiled, rule)
+	}
+	return &Scrubber{rules: compiled}
+}
+
+// Scrub scrubs the sensitive data found in the Meta values of the spans of trace.
+// Internal tags, prefixed with "_dd", are left untouched.
+func (f *Scrubber) Scrub(trace pb.Trace) {
+	if f == nil || len(f.rules) == 0 {
+		return
+	}
+	for _, s := range trace {
+		for k, v := range s.Meta {
+			s.Meta[k] = f.scrubTag(k, v)
+		}
+	}
+}
+
+// ScrubStatsGroup scrubs the sensitive data found in the values of the key:value tags of
+// grouped stats computed by a tracer.
+func (f *Scrubber) ScrubStatsGroup(b *pb.ClientGroupedStats) {
+	if f == nil || len(f.rules) == 0 {
+		return
+	}
+	for i, tag := range b.Tags {
+		k, v := tag, ""
+		if j := strings.IndexByte(tag, ':'); j >= 0 {
+			k, v = tag[:j], tag[j+1:]
+		}
+		if out := f.scrubTag(k, v); out != v {
+			b.Tags[i] = k + ":" + out
+		}
+	}
+}
+
+// scrubTag returns the value v of the tag with the key k scrubbed by all the rules.
+func (f *Scrubber) scrubTag(k, v string) string {
+	if strings.HasPrefix(k, "_dd") {
+		return v
+	}
+	lk := strings.ToLower(k)
+	for _, rule := range f.rules {
+		if out, ok := rule.scrub(lk, v); ok {
+			v = out
+			atomic.AddInt64(rule.hits, 1)
+		}
+	}
+	return v
+}
+
+// scrub returns the value v of the tag with the lower-cased key k scrubbed from the
+// sensitive data matched by r, and whether any was found.
+func (r *scrubRule) scrub(k, v string) (string, bool) {
+	if v == "" {
+		return v, false
+	}
+	for _, key := range r.keys {
+		if k == key || strings.HasSuffix(k, "."+key) {
+			return r.replace(v), true
+		}
+	}
+	if r.value != nil && r.value(v) {
+		return r.replace(v), true
+	}
+	if r.re == nil {
+		return v, false
+	}
+	found := false
+	out := r.re.ReplaceAllStringFunc(v, func(m string) string {
+		if r.validate != nil && !r.validate(m) {
+			return m
+		}
+		found = true
+		return r.replace(m)
+	})
+	return out, found
+}
+
+func (r *scrubRule) replace(v string) string {
+	if r.hashKey == nil {
+		return r.replacement
+	}
+	mac := hmac.New(sha256.New, r.hashKey)
+	mac.Write([]byte(v))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func compileScrubRule(r *config.SensitiveDataRule) (*scrubRule, error) {
+	rule := &scrubRule{replacement: r.Replacement}
+	if rule.replacement == "" {
+		rule.replacement = "?"
+	}
+	switch r.Action {
+	case "", "redact":
+	case "hash":
+		if r.HashKey == "" {
+			return nil, errors.New("the hash action requires a hash_key")
+		}
+		rule.hashKey = []byte(r.HashKey)
+	default:
+		return nil, fmt.Errorf("unknown action %q, valid actions are redact and hash", r.Action)
+	}
+
+	set := 0
+	if len(r.Keys) > 0 {
+		set++
+		for _, k := range r.Keys {
+			rule.keys = append(rule.keys, strings.ToLower(k))
+		}
+	}
+	if r.Pattern != "" {
+		set++
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		rule.re = re
+	}
+	if r.Builtin != "" {
+		set++
+		switch r.Builtin {
+		case "credit_card":
+			rule.value = func(v string) bool { return obfuscate.IsCardNumber(v, true) }
+		case "iban":
+			rule.re = builtinPatterns[r.Builtin]
+			rule.validate = validIBAN
+		default:
+			re, ok := builtinPatterns[r.Builtin]
+			if !ok {
+				return nil, fmt.Errorf("unknown builtin %q, valid builtins are email, jwt, iban and credit_card", r.Builtin)
+			}
+			rule.re = re
+		}
+	}
+	if set != 1 {
+		return nil, errors.New("exactly one of keys, pattern and builtin must be set")
+	}
+
+	name := r.Name
+	if name == "" {
+		name = r.Builtin
+	}
+	if name == "" {
+		return nil, errors.New("the rule has no name")
+	}
+	rule.hits = info.ScrubberCounter(name)
+	return rule, nil
+}
+
+// validIBAN reports whether iban has a valid ISO 13616 checksum.
+func validIBAN(iban string) bool {
+	iban = strings.ReplaceAll(iban, " ", "")
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+	var digits strings.Builder
+	for _, c := range iban[4:] + iban[:4] {
+		switch {
+		case '0' <= c && c <= '9':
+			digits.WriteRune(c)
+		case 'A' <= c && c <= 'Z':
+			fmt.Fprintf(&digits, "%d", c-'A'+10)
+		default:
+			return false
+		}
+	}
+	n, ok := new(big.Int).SetString(digits.String(), 10)
+	return ok && n.Mod(n, big.NewInt(97)).Int64() == 1
+}
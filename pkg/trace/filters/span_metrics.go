@@ -0,0 +1,274 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package filters
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+)
+
+// spanMetricDuration is the value of a distribution of the span durations.
+const spanMetricDuration = "@duration"
+
+// spanMetricOther replaces the values of a group_by tag of a metric once its cardinality
+// limit is reached.
+const spanMetricOther = "_other"
+
+// spanMetricsCardinalityPeriod is the period over which the cardinality of the group_by
+// tags is limited.
+const spanMetricsCardinalityPeriod = 10 * time.Second
+
+// spanMetric is a compiled config.SpanMetric.
+type spanMetric struct {
+	spanMatcher
+	name         string
+	distribution bool
+	value        string
+	groupBy      []string
+	// seen holds the values of each group_by tag seen during the current cardinality period.
+	seen map[string]map[string]struct{}
+}
+
+// SpanMetrics generates user-defined metrics from the spans matching their criteria:
+// counts of the spans, or distributions of a value they hold. The metrics are weighted
+// by the client sample rate of the traces and submitted through the statsd client of
+// the agent. It is safe for concurrent use.
+type SpanMetrics struct {
+	metrics        []*spanMetric
+	maxCardinality int
+
+	mu sync.Mutex
+	// periodStart is the start of the current cardinality period.
+	periodStart time.Time
+}
+
+// NewSpanMetrics returns SpanMetrics generating as many of the given metrics as possible.
+// Invalid metrics are reported and ignored. Each group_by tag of a metric takes at most
+// maxCardinality distinct values every 10 seconds, further values being reported as
+// "_other". 0 means no limit.
+func NewSpanMetrics(defs []*config.SpanMetric, maxCardinality int) *SpanMetrics {
+	compiled := make([]*spanMetric, 0, len(defs))
+	for i, d := range defs {
+		m, err := compileSpanMetric(d)
+		if err != nil {
+			log.Errorf("Invalid span metric #%d %q: %v", i, d.Name, err)
+			continue
+		}
+		compiled = append(compiled, m)
+	}
+	return &SpanMetrics{metrics: compiled, maxCardinality: maxCardinality}
+}
+
+// Empty returns true if there are no metrics to generate. A nil SpanMetrics is empty.
+func (f *SpanMetrics) Empty() bool {
+	return f == nil || len(f.metrics) == 0
+}
+
+// spanMetricCount is a count submitted once per trace.
+type spanMetricCount struct {
+	name  string
+	tags  []string
+	value float64
+}
+
+// spanMetricCounts accumulates the counts of a trace, by name and tags.
+type spanMetricCounts struct {
+	index  map[string]int
+	counts []spanMetricCount
+}
+
+func (c *spanMetricCounts) add(name string, tags []string, value float64) {
+	key := name + "|" + strings.Join(tags, ",")
+	if i, ok := c.index[key]; ok {
+		c.counts[i].value += value
+		return
+	}
+	if c.index == nil {
+		c.index = make(map[string]int)
+	}
+	c.index[key] = len(c.counts)
+	c.counts = append(c.counts, spanMetricCount{name: name, tags: tags, value: value})
+}
+
+// Compute submits the metrics generated from the spans of trace, whose root is root.
+// Each span counts for the weight of the trace, the inverse of its client sample rate,
+// as in the stats computed by the Concentrator.
+func (f *SpanMetrics) Compute(root *pb.Span, trace pb.Trace) {
+	w := weight(root)
+	var counts spanMetricCounts
+	for _, s := range trace {
+		for _, m := range f.metrics {
+			if !m.match(s) {
+				continue
+			}
+			tags := f.tags(m, s, &counts)
+			if !m.distribution {
+				counts.add(m.name, tags, w)
+				continue
+			}
+			v, ok := m.valueOf(s)
+			if !ok {
+				counts.add("datadog.trace_agent.span_metrics.missing_value", []string{"metric:" + m.name}, 1)
+				continue
+			}
+			// The statsd client samples the values at their sample rate, and dogstatsd counts
+			// each value it receives 1/rate times. The value is submitted as many times as the
+			// weight at the rate 1/weight: it is sent once on average and counted weight times.
+			rate := 1 / w
+			for n := weightedCount(w); n > 0; n-- {
+				metrics.Distribution(m.name, v, tags, rate)
+			}
+		}
+	}
+	for _, c := range counts.counts {
+		if n := weightedCount(c.value); n > 0 {
+			metrics.Count(c.name, n, c.tags, 1)
+		}
+	}
+}
+
+// weight returns the weight of the trace of root, the inverse of its client sample rate.
+func weight(root *pb.Span) float64 {
+	if root == nil {
+		return 1
+	}
+	rate, ok := root.Metrics[sampler.KeySamplingRateGlobal]
+	if !ok || rate <= 0 || rate > 1 {
+		return 1
+	}
+	return 1 / rate
+}
+
+// weightedCount randomly rounds w to one of the two integers closest to it, so that the
+// counts submitted add up to the weighted counts on average.
+func weightedCount(w float64) int64 {
+	n := math.Floor(w)
+	if rand.Float64() < w-n {
+		n++
+	}
+	return int64(n)
+}
+
+// tags returns the tags of the metric m generated from s. The values beyond the cardinality
+// limit of their tag are replaced by spanMetricOther and counted in counts.
+func (f *SpanMetrics) tags(m *spanMetric, s *pb.Span, counts *spanMetricCounts) []string {
+	if len(m.groupBy) == 0 {
+		return nil
+	}
+	tags := make([]string, 0, len(m.groupBy))
+	for _, k := range m.groupBy {
+		var v string
+		switch k {
+		case "service":
+			v = s.Service
+		case "operation_name":
+			v = s.Name
+		case "resource":
+			v = s.Resource
+		default:
+			var ok bool
+			if v, ok = s.Meta[k]; !ok {
+				if f, ok := s.Metrics[k]; ok {
+					v = strconv.FormatFloat(f, 'f', -1, 64)
+				}
+			}
+		}
+		if v == "" {
+			continue
+		}
+		if !f.allow(m, k, v) {
+			v = spanMetricOther
+			counts.add("datadog.trace_agent.span_metrics.overflow", []string{"metric:" + m.name, "tag:" + k}, 1)
+		}
+		tags = append(tags, k+":"+v)
+	}
+	return tags
+}
+
+// allow returns whether the value v of the group_by tag k of m is within the cardinality limit.
+func (f *SpanMetrics) allow(m *spanMetric, k, v string) bool {
+	if f.maxCardinality <= 0 {
+		return true
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if now := time.Now(); now.Sub(f.periodStart) >= spanMetricsCardinalityPeriod {
+		f.periodStart = now
+		for _, m := range f.metrics {
+			m.seen = nil
+		}
+	}
+	if m.seen == nil {
+		m.seen = make(map[string]map[string]struct{}, len(m.groupBy))
+	}
+	seen, ok := m.seen[k]
+	if !ok {
+		seen = make(map[string]struct{})
+		m.seen[k] = seen
+	}
+	if _, ok := seen[v]; ok {
+		return true
+	}
+	if len(seen) >= f.maxCardinality {
+		return false
+	}
+	seen[v] = struct{}{}
+	return true
+}
+
+// valueOf returns the value of the distribution held by s.
+func (m *spanMetric) valueOf(s *pb.Span) (float64, bool) {
+	if m.value == spanMetricDuration {
+		return time.Duration(s.Duration).Seconds(), true
+	}
+	if v, ok := s.Metrics[m.value]; ok {
+		return v, true
+	}
+	if v, ok := s.Meta[m.value]; ok {
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func compileSpanMetric(d *config.SpanMetric) (*spanMetric, error) {
+	if d.Name == "" {
+		return nil, errors.New("the metric has no name")
+	}
+	matcher, err := compileSpanMatcher(d.MatchType, d.Service, d.OperationName, d.Resource, d.Meta, d.Metrics, d.MinDurationMs, d.MaxDurationMs)
+	if err != nil {
+		return nil, err
+	}
+	m := &spanMetric{
+		spanMatcher: matcher,
+		name:        d.Name,
+		value:       d.Value,
+		groupBy:     d.GroupBy,
+	}
+	switch d.Type {
+	case "", "count":
+	case "distribution":
+		if d.Value == "" {
+			return nil, errors.New("a distribution needs a value")
+		}
+		m.distribution = true
+	default:
+		return nil, fmt.Errorf("unknown type %q, valid types are count and distribution", d.Type)
+	}
+	return m, nil
+}
@@ -0,0 +1,130 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package filters
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpanMetrics(t *testing.T) {
+	stats := &testutil.TestStatsClient{}
+	defer func(old metrics.StatsClient) { metrics.Client = old }(metrics.Client)
+	metrics.Client = stats
+
+	f := NewSpanMetrics([]*config.SpanMetric{
+		{Name: "payments.count", Service: "payments", GroupBy: []string{"resource", "currency"}},
+		{Name: "payments.amount", Type: "distribution", Value: "payment.amount", OperationName: "charge", GroupBy: []string{"currency"}},
+		{Name: "payments.latency", Type: "distribution", Value: "@duration", Service: "payments", Meta: map[string]string{"currency": "EUR"}},
+		{Name: "invalid", Type: "gauge"},
+	}, 0)
+	assert.False(t, f.Empty())
+	assert.Len(t, f.metrics, 3)
+
+	f.Compute(nil, pb.Trace{
+		{Service: "payments", Name: "charge", Resource: "POST /charge", Duration: int64(250 * time.Millisecond), Meta: map[string]string{"currency": "EUR", "payment.amount": "12.5"}},
+		{Service: "payments", Name: "charge", Resource: "POST /charge", Metrics: map[string]float64{"payment.amount": 30}},
+		{Service: "payments", Name: "charge", Resource: "POST /refund", Meta: map[string]string{"currency": "USD", "payment.amount": "n/a"}},
+		{Service: "web", Name: "http.request"},
+	})
+
+	var counts []testutil.MetricsArgs
+	for _, c := range stats.CountCalls {
+		if c.Name == "payments.count" {
+			counts = append(counts, c)
+		}
+	}
+	assert.Equal(t, []testutil.MetricsArgs{
+		{Name: "payments.count", Value: 1, Tags: []string{"resource:POST /charge", "currency:EUR"}, Rate: 1},
+		{Name: "payments.count", Value: 1, Tags: []string{"resource:POST /charge"}, Rate: 1},
+		{Name: "payments.count", Value: 1, Tags: []string{"resource:POST /refund", "currency:USD"}, Rate: 1},
+	}, counts)
+	assert.Equal(t, []testutil.MetricsArgs{
+		{Name: "payments.amount", Value: 12.5, Tags: []string{"currency:EUR"}, Rate: 1},
+		{Name: "payments.latency", Value: 0.25, Rate: 1},
+		{Name: "payments.amount", Value: 30, Tags: []string{}, Rate: 1},
+	}, stats.DistributionCalls)
+	assert.EqualValues(t, 1, stats.GetCountSummaries()["datadog.trace_agent.span_metrics.missing_value"].Sum)
+}
+
+func TestSpanMetricsWeight(t *testing.T) {
+	stats := &testutil.TestStatsClient{}
+	defer func(old metrics.StatsClient) { metrics.Client = old }(metrics.Client)
+	metrics.Client = stats
+
+	f := NewSpanMetrics([]*config.SpanMetric{
+		{Name: "requests", Service: "web"},
+		{Name: "latency", Type: "distribution", Value: "@duration", Service: "web"},
+	}, 0)
+	root := &pb.Span{Service: "web", Duration: int64(time.Second), Metrics: map[string]float64{"_sample_rate": 0.25}}
+	f.Compute(root, pb.Trace{root, {Service: "web", Duration: int64(time.Second)}})
+
+	// each span counts for the 4 spans sampled by the tracer, and the spans of a trace are counted once
+	assert.Equal(t, []testutil.MetricsArgs{
+		{Name: "requests", Value: 8, Rate: 1},
+	}, stats.CountCalls)
+	// the durations are sampled by the statsd client at the client sample rate, and counted as many times
+	require.Len(t, stats.DistributionCalls, 8)
+	for _, call := range stats.DistributionCalls {
+		assert.Equal(t, testutil.MetricsArgs{Name: "latency", Value: 1, Rate: 0.25}, call)
+	}
+
+	assert.EqualValues(t, 1, weight(nil))
+	assert.EqualValues(t, 1, weight(&pb.Span{Metrics: map[string]float64{"_sample_rate": 0}}))
+	assert.EqualValues(t, 2, weight(&pb.Span{Metrics: map[string]float64{"_sample_rate": 0.5}}))
+}
+
+func TestSpanMetricsMaxCardinality(t *testing.T) {
+	stats := &testutil.TestStatsClient{}
+	defer func(old metrics.StatsClient) { metrics.Client = old }(metrics.Client)
+	metrics.Client = stats
+
+	f := NewSpanMetrics([]*config.SpanMetric{
+		{Name: "requests", GroupBy: []string{"service", "resource"}},
+	}, 2)
+	f.Compute(nil, pb.Trace{
+		{Service: "web", Resource: "a"},
+		{Service: "web", Resource: "b"},
+		{Service: "web", Resource: "c"},
+		{Service: "web", Resource: "d"},
+		{Service: "web", Resource: "a"},
+	})
+	assert.Equal(t, []testutil.MetricsArgs{
+		{Name: "requests", Value: 2, Tags: []string{"service:web", "resource:a"}, Rate: 1},
+		{Name: "requests", Value: 1, Tags: []string{"service:web", "resource:b"}, Rate: 1},
+		{Name: "datadog.trace_agent.span_metrics.overflow", Value: 2, Tags: []string{"metric:requests", "tag:resource"}, Rate: 1},
+		{Name: "requests", Value: 2, Tags: []string{"service:web", "resource:_other"}, Rate: 1},
+	}, stats.CountCalls)
+
+	// the limits are reset every period
+	stats.Reset()
+	f.periodStart = time.Time{}
+	f.Compute(nil, pb.Trace{{Service: "web", Resource: "c"}})
+	assert.Equal(t, []testutil.MetricsArgs{
+		{Name: "requests", Value: 1, Tags: []string{"service:web", "resource:c"}, Rate: 1},
+	}, stats.CountCalls)
+}
+
+func TestSpanMetricsInvalid(t *testing.T) {
+	for _, m := range []*config.SpanMetric{
+		{Type: "count"},
+		{Name: "m", Type: "gauge"},
+		{Name: "m", Type: "distribution"},
+		{Name: "m", MatchType: "exact"},
+		{Name: "m", MatchType: "regex", Service: "("},
+	} {
+		_, err := compileSpanMetric(m)
+		assert.Error(t, err, m)
+	}
+	assert.True(t, NewSpanMetrics(nil, 0).Empty())
+}
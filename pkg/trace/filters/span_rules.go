@@ -0,0 +1,263 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package filters
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+)
+
+// RuleAction is the decision taken by a span rule on the trace of the spans it matches.
+type RuleAction int
+
+const (
+	// RuleActionNone leaves the sampling decision to the samplers.
+	RuleActionNone RuleAction = iota
+	// RuleActionDrop drops the trace.
+	RuleActionDrop
+	// RuleActionKeep forces the trace to be kept.
+	RuleActionKeep
+	// RuleActionSample keeps the trace with the sample rate of the rule.
+	RuleActionSample
+)
+
+// spanMatcher matches the spans on their fields, tags and duration. A span matches
+// when all the criteria set match.
+type spanMatcher struct {
+	service       *regexp.Regexp
+	operationName *regexp.Regexp
+	resource      *regexp.Regexp
+	meta          map[string]*regexp.Regexp
+	metrics       map[string]*regexp.Regexp
+	minDuration   time.Duration
+	maxDuration   time.Duration
+}
+
+// spanRule is a compiled config.SpanRule.
+type spanRule struct {
+	spanMatcher
+	action     RuleAction
+	sampleRate float64
+	addTags    map[string]string
+	removeTags []string
+	tags       []string
+}
+
+// SpanRules is a filter applying user-defined rules to the spans of traces. The rules
+// can drop, keep or sample the traces and add or remove tags on the spans they match.
+type SpanRules struct {
+	rules []*spanRule
+}
+
+// NewSpanRules returns SpanRules applying as many of the given rules as possible.
+// Invalid rules are reported and ignored.
+func NewSpanRules(rules []*config.SpanRule) *SpanRules {
+	compiled := make([]*spanRule, 0, len(rules))
+	for i, r := range rules {
+		rule, err := compileSpanRule(r)
+		if err != nil {
+			log.Errorf("Invalid span rule #%d %q: %v", i, r.Name, err)
+			continue
+		}
+		compiled = append(compiled, rule)
+	}
+	return &SpanRules{rules: compiled}
+}
+
+// Empty returns true if there are no rules to apply.
+func (f *SpanRules) Empty() bool {
+	return len(f.rules) == 0
+}
+
+// Apply applies the rules to the spans of trace, changing their tags as specified
+// by the rules they match. It returns the action to take on the trace along with
+// the sample rate for RuleActionSample. The action is the one of the first rule,
+// in configuration order, with an action and matching any span.
+func (f *SpanRules) Apply(trace pb.Trace) (action RuleAction, rate float64) {
+	decided := len(f.rules)
+	// matched counts the spans matched by each rule, reported once per trace.
+	var matched []int64
+	for _, s := range trace {
+		for i, rule := range f.rules {
+			if !rule.match(s) {
+				continue
+			}
+			if matched == nil {
+				matched = make([]int64, len(f.rules))
+			}
+			matched[i]++
+			rule.modify(s)
+			if rule.action != RuleActionNone && i < decided {
+				decided = i
+				action, rate = rule.action, rule.sampleRate
+			}
+		}
+	}
+	for i, n := range matched {
+		if n > 0 {
+			metrics.Count("datadog.trace_agent.span_rules.matched", n, f.rules[i].tags, 1)
+		}
+	}
+	return action, rate
+}
+
+func (r *spanMatcher) match(s *pb.Span) bool {
+	if r.service != nil && !r.service.MatchString(s.Service) {
+		return false
+	}
+	if r.operationName != nil && !r.operationName.MatchString(s.Name) {
+		return false
+	}
+	if r.resource != nil && !r.resource.MatchString(s.Resource) {
+		return false
+	}
+	if r.minDuration > 0 && time.Duration(s.Duration) < r.minDuration {
+		return false
+	}
+	if r.maxDuration > 0 && time.Duration(s.Duration) > r.maxDuration {
+		return false
+	}
+	for k, re := range r.meta {
+		v, ok := s.Meta[k]
+		if !ok || !re.MatchString(v) {
+			return false
+		}
+	}
+	for k, re := range r.metrics {
+		v, ok := s.Metrics[k]
+		if !ok || !re.MatchString(strconv.FormatFloat(v, 'f', -1, 64)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *spanRule) modify(s *pb.Span) {
+	for _, k := range r.removeTags {
+		delete(s.Meta, k)
+		delete(s.Metrics, k)
+	}
+	if len(r.addTags) > 0 && s.Meta == nil {
+		s.Meta = make(map[string]string, len(r.addTags))
+	}
+	for k, v := range r.addTags {
+		s.Meta[k] = v
+	}
+}
+
+// compileSpanMatcher compiles the criteria of a span matcher. matchType is the syntax of
+// the patterns, "glob" (default) or "regex". Empty patterns and zero durations match any span.
+func compileSpanMatcher(matchType, service, operationName, resource string, meta, metrics map[string]string, minDurationMs, maxDurationMs float64) (spanMatcher, error) {
+	var compile func(string) (*regexp.Regexp, error)
+	switch matchType {
+	case "", "glob":
+		compile = compileGlob
+	case "regex":
+		compile = regexp.Compile
+	default:
+		return spanMatcher{}, fmt.Errorf("unknown match_type %q, valid types are glob and regex", matchType)
+	}
+	compileOptional := func(pattern string) (*regexp.Regexp, error) {
+		if pattern == "" {
+			return nil, nil
+		}
+		return compile(pattern)
+	}
+	compileMap := func(patterns map[string]string) (map[string]*regexp.Regexp, error) {
+		res := make(map[string]*regexp.Regexp, len(patterns))
+		for k, pattern := range patterns {
+			re, err := compile(pattern)
+			if err != nil {
+				return nil, err
+			}
+			res[k] = re
+		}
+		return res, nil
+	}
+
+	m := spanMatcher{
+		minDuration: time.Duration(minDurationMs * float64(time.Millisecond)),
+		maxDuration: time.Duration(maxDurationMs * float64(time.Millisecond)),
+	}
+	var err error
+	if m.service, err = compileOptional(service); err != nil {
+		return m, err
+	}
+	if m.operationName, err = compileOptional(operationName); err != nil {
+		return m, err
+	}
+	if m.resource, err = compileOptional(resource); err != nil {
+		return m, err
+	}
+	if m.meta, err = compileMap(meta); err != nil {
+		return m, err
+	}
+	if m.metrics, err = compileMap(metrics); err != nil {
+		return m, err
+	}
+	return m, nil
+}
+
+func compileSpanRule(r *config.SpanRule) (*spanRule, error) {
+	m, err := compileSpanMatcher(r.MatchType, r.Service, r.OperationName, r.Resource, r.Meta, r.Metrics, r.MinDurationMs, r.MaxDurationMs)
+	if err != nil {
+		return nil, err
+	}
+	rule := &spanRule{
+		spanMatcher: m,
+		sampleRate:  r.SampleRate,
+		addTags:     r.AddTags,
+		removeTags:  r.RemoveTags,
+	}
+
+	switch r.Action {
+	case "":
+		rule.action = RuleActionNone
+	case "drop":
+		rule.action = RuleActionDrop
+	case "keep":
+		rule.action = RuleActionKeep
+	case "sample":
+		if r.SampleRate < 0 || r.SampleRate > 1 {
+			return nil, fmt.Errorf("sample_rate must be between 0 and 1")
+		}
+		rule.action = RuleActionSample
+	default:
+		return nil, fmt.Errorf("unknown action %q, valid actions are drop, keep and sample", r.Action)
+	}
+	if rule.action == RuleActionNone && len(rule.addTags) == 0 && len(rule.removeTags) == 0 {
+		return nil, fmt.Errorf("the rule has no action and changes no tags")
+	}
+	rule.tags = []string{"rule:" + r.Name, "action:" + r.Action}
+	return rule, nil
+}
+
+// compileGlob compiles a glob pattern matching whole values, where "*" matches
+// any sequence of characters and "?" any single character.
+func compileGlob(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("(?s)^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
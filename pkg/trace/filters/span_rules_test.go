@@ -0,0 +1,130 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package filters
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpanRulesMatch(t *testing.T) {
+	span := &pb.Span{
+		Service:  "web-store",
+		Name:     "http.request",
+		Resource: "GET /users/42",
+		Duration: int64(150 * time.Millisecond),
+		Meta:     map[string]string{"env": "prod", "http.url": "https://example.com/users/42"},
+		Metrics:  map[string]float64{"http.status_code": 500},
+	}
+
+	for _, tt := range []struct {
+		name  string
+		rule  config.SpanRule
+		match bool
+	}{
+		{"service-glob", config.SpanRule{Service: "web-*"}, true},
+		{"service-glob-whole-value", config.SpanRule{Service: "web"}, false},
+		{"service-glob-single-char", config.SpanRule{Service: "web?store"}, true},
+		{"name", config.SpanRule{OperationName: "http.*"}, true},
+		{"name-mismatch", config.SpanRule{OperationName: "grpc.*"}, false},
+		{"resource-regex", config.SpanRule{MatchType: "regex", Resource: `^GET /users/\d+$`}, true},
+		{"resource-regex-mismatch", config.SpanRule{MatchType: "regex", Resource: `^POST`}, false},
+		{"meta", config.SpanRule{Meta: map[string]string{"env": "prod", "http.url": "*/users/*"}}, true},
+		{"meta-missing", config.SpanRule{Meta: map[string]string{"version": "*"}}, false},
+		{"metrics", config.SpanRule{Metrics: map[string]string{"http.status_code": "5??"}}, true},
+		{"metrics-mismatch", config.SpanRule{Metrics: map[string]string{"http.status_code": "2??"}}, false},
+		{"min-duration", config.SpanRule{MinDurationMs: 100}, true},
+		{"min-duration-mismatch", config.SpanRule{MinDurationMs: 200}, false},
+		{"max-duration-mismatch", config.SpanRule{MaxDurationMs: 100}, false},
+		{"all", config.SpanRule{Service: "web-store", OperationName: "http.request", MinDurationMs: 100, Meta: map[string]string{"env": "prod"}}, true},
+		{"one-mismatch", config.SpanRule{Service: "web-store", OperationName: "http.request", MaxDurationMs: 100}, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.rule.Action = "drop"
+			rule, err := compileSpanRule(&tt.rule)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.match, rule.match(span))
+		})
+	}
+}
+
+func TestSpanRulesInvalid(t *testing.T) {
+	for _, rule := range []*config.SpanRule{
+		{MatchType: "exact", Action: "drop"},
+		{MatchType: "regex", Service: "(", Action: "drop"},
+		{Action: "sample", SampleRate: 2},
+		{Action: "delete"},
+		{Service: "web"},
+	} {
+		_, err := compileSpanRule(rule)
+		assert.Error(t, err)
+	}
+	assert.True(t, NewSpanRules([]*config.SpanRule{{Action: "delete"}}).Empty())
+}
+
+func TestSpanRulesApply(t *testing.T) {
+	rules := NewSpanRules([]*config.SpanRule{
+		{Name: "tag-db", Service: "*-db", AddTags: map[string]string{"team": "storage"}, RemoveTags: []string{"db.password", "internal.score"}},
+		{Name: "drop-health", Resource: "GET /health", Action: "drop"},
+		{Name: "keep-errors", Metrics: map[string]string{"http.status_code": "5??"}, Action: "keep"},
+		{Name: "sample-web", Service: "web", Action: "sample", SampleRate: 0.5},
+	})
+	assert.False(t, rules.Empty())
+
+	t.Run("tags", func(t *testing.T) {
+		db := &pb.Span{Service: "users-db", Meta: map[string]string{"db.password": "secret"}, Metrics: map[string]float64{"internal.score": 1}}
+		action, _ := rules.Apply(pb.Trace{db})
+		assert.Equal(t, RuleActionNone, action)
+		assert.Equal(t, map[string]string{"team": "storage"}, db.Meta)
+		assert.Empty(t, db.Metrics)
+
+		bare := &pb.Span{Service: "orders-db"}
+		rules.Apply(pb.Trace{bare})
+		assert.Equal(t, map[string]string{"team": "storage"}, bare.Meta)
+	})
+
+	t.Run("first-rule-wins", func(t *testing.T) {
+		root := &pb.Span{Service: "web", Resource: "GET /users"}
+		failed := &pb.Span{Service: "web", Resource: "GET /users", Metrics: map[string]float64{"http.status_code": 503}}
+		action, _ := rules.Apply(pb.Trace{root, failed})
+		assert.Equal(t, RuleActionKeep, action)
+
+		health := &pb.Span{Service: "web", Resource: "GET /health"}
+		action, _ = rules.Apply(pb.Trace{root, failed, health})
+		assert.Equal(t, RuleActionDrop, action)
+	})
+
+	t.Run("sample", func(t *testing.T) {
+		action, rate := rules.Apply(pb.Trace{{Service: "web", Resource: "GET /users"}})
+		assert.Equal(t, RuleActionSample, action)
+		assert.Equal(t, 0.5, rate)
+	})
+}
+
+func TestSpanRulesMatchedCount(t *testing.T) {
+	stats := &testutil.TestStatsClient{}
+	defer func(old metrics.StatsClient) { metrics.Client = old }(metrics.Client)
+	metrics.Client = stats
+
+	rules := NewSpanRules([]*config.SpanRule{
+		{Name: "drop-health", Resource: "GET /health", Action: "drop"},
+		{Name: "keep-web", Service: "web", Action: "keep"},
+	})
+	health := &pb.Span{Service: "web", Resource: "GET /health"}
+	rules.Apply(pb.Trace{health, health, {Service: "db"}})
+
+	// one count per matching rule and per trace
+	assert.Equal(t, []testutil.MetricsArgs{
+		{Name: "datadog.trace_agent.span_rules.matched", Value: 2, Tags: []string{"rule:drop-health", "action:drop"}, Rate: 1},
+		{Name: "datadog.trace_agent.span_rules.matched", Value: 2, Tags: []string{"rule:keep-web", "action:keep"}, Rate: 1},
+	}, stats.CountCalls)
+}
@@ -77,6 +77,9 @@ const (
   {{if lt .Status.RateLimiter.TargetRate 1.0}}
   WARNING: Rate-limiter keep percentage: {{percent .Status.RateLimiter.TargetRate}} %
   {{end}}
+  {{ range $rule, $hits := .Status.Scrubber }}
+  Sensitive data scrubbed by rule '{{ $rule }}': {{ $hits }} tag values
+  {{ end }}
 
   --- Writer stats (1 min) ---
 
@@ -219,6 +222,8 @@ func InitInfo(conf *config.AgentConfig) error {
 		expvar.Publish("ratebyservice", expvar.Func(publishRateByService))
 		expvar.Publish("watchdog", expvar.Func(publishWatchdogInfo))
 		expvar.Publish("ratelimiter", expvar.Func(publishRateLimiterStats))
+		expvar.Publish("scrubber", expvar.Func(publishScrubberHits))
+		expvar.Publish("service_map", expvar.Func(publishServiceMap))
 
 		// copy the config to ensure we don't expose sensitive data such as API keys
 		c := *conf
@@ -276,6 +281,7 @@ type StatusInfo struct {
 	StatsWriter   StatsWriterInfo    `json:"stats_writer"`
 	Watchdog      watchdog.Info      `json:"watchdog"`
 	RateLimiter   RateLimiterStats   `json:"ratelimiter"`
+	Scrubber      map[string]int64   `json:"scrubber"`
 	Config        config.AgentConfig `json:"config"`
 }
 
@@ -0,0 +1,45 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package info
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	scrubberMu   sync.RWMutex
+	scrubberHits = make(map[string]*int64)
+)
+
+// ScrubberCounter returns the counter of the span tag values scrubbed by the sensitive data
+// rule with the given name. It is created if needed and must be incremented atomically.
+func ScrubberCounter(rule string) *int64 {
+	scrubberMu.Lock()
+	defer scrubberMu.Unlock()
+	c, ok := scrubberHits[rule]
+	if !ok {
+		c = new(int64)
+		scrubberHits[rule] = c
+	}
+	return c
+}
+
+// ScrubberHits returns the number of span tag values scrubbed by each sensitive data rule
+// since the agent started.
+func ScrubberHits() map[string]int64 {
+	scrubberMu.RLock()
+	defer scrubberMu.RUnlock()
+	hits := make(map[string]int64, len(scrubberHits))
+	for rule, c := range scrubberHits {
+		hits[rule] = atomic.LoadInt64(c)
+	}
+	return hits
+}
+
+func publishScrubberHits() interface{} {
+	return ScrubberHits()
+}
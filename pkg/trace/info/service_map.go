@@ -0,0 +1,83 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package info
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ServiceMapEdge holds the calls observed from a client service to a server service or,
+// when Peer is true, to a peer resource (e.g. a database or a host) which is not traced.
+type ServiceMapEdge struct {
+	Env    string `json:"env"`
+	Client string `json:"client"`
+	Server string `json:"server"`
+	Peer   bool   `json:"peer"`
+	Calls  int64  `json:"calls"`
+	Errors int64  `json:"errors"`
+	// AvgDuration is the average duration of the calls, in nanoseconds.
+	AvgDuration int64     `json:"avg_duration"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+var (
+	serviceMapMu sync.RWMutex
+	serviceMap   = []ServiceMapEdge{}
+)
+
+// UpdateServiceMap updates the service map published by the agent.
+func UpdateServiceMap(edges []ServiceMapEdge) {
+	serviceMapMu.Lock()
+	defer serviceMapMu.Unlock()
+	serviceMap = edges
+}
+
+// ServiceMap returns the service map published by the agent.
+func ServiceMap() []ServiceMapEdge {
+	serviceMapMu.RLock()
+	defer serviceMapMu.RUnlock()
+	return serviceMap
+}
+
+func publishServiceMap() interface{} {
+	return ServiceMap()
+}
+
+// WriteServiceMapDOT writes edges to w as a graph in the DOT language of Graphviz. Peer
+// resources are drawn as boxes. The edges are labelled with their number of calls and
+// errors, and are split by environment when there are several.
+func WriteServiceMapDOT(w io.Writer, edges []ServiceMapEdge) error {
+	envs := make(map[string]struct{})
+	for _, e := range edges {
+		envs[e.Env] = struct{}{}
+	}
+	node := func(env, name string) string {
+		if len(envs) > 1 {
+			name = env + "/" + name
+		}
+		return strconv.Quote(name)
+	}
+	if _, err := io.WriteString(w, "digraph service_map {\n"); err != nil {
+		return err
+	}
+	for _, e := range edges {
+		if e.Peer {
+			if _, err := fmt.Fprintf(w, "\t%s [shape=box];\n", node(e.Env, e.Server)); err != nil {
+				return err
+			}
+		}
+		label := fmt.Sprintf("%d calls, %d errors, avg %s", e.Calls, e.Errors, time.Duration(e.AvgDuration).Round(time.Microsecond))
+		if _, err := fmt.Fprintf(w, "\t%s -> %s [label=%q];\n", node(e.Env, e.Client), node(e.Env, e.Server), label); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "}\n")
+	return err
+}
@@ -0,0 +1,34 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package info
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteServiceMapDOT(t *testing.T) {
+	edges := []ServiceMapEdge{
+		{Env: "prod", Client: "web", Server: "users", Calls: 10, Errors: 1, AvgDuration: int64(1500 * time.Microsecond)},
+		{Env: "prod", Client: "users", Server: "pg-main", Peer: true, Calls: 4, AvgDuration: int64(time.Millisecond)},
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, WriteServiceMapDOT(&buf, edges))
+	assert.Equal(t, `digraph service_map {
+	"web" -> "users" [label="10 calls, 1 errors, avg 1.5ms"];
+	"pg-main" [shape=box];
+	"users" -> "pg-main" [label="4 calls, 0 errors, avg 1ms"];
+}
+`, buf.String())
+
+	buf.Reset()
+	edges[1].Env = "staging"
+	assert.NoError(t, WriteServiceMapDOT(&buf, edges))
+	assert.Contains(t, buf.String(), `"staging/users" -> "staging/pg-main"`)
+}
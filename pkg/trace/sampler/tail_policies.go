@@ -0,0 +1,138 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sampler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+)
+
+// TailPolicy decides whether to keep a trace once all its spans have been received.
+type TailPolicy interface {
+	// Name identifies the policy.
+	Name() string
+	// Keep returns true if the trace made of spans must be kept. root is the root
+	// span of the trace, it can be nil if it hasn't been received.
+	Keep(traceID uint64, root *pb.Span, spans []*pb.Span) bool
+}
+
+// NewTailPolicy returns the TailPolicy described by conf.
+func NewTailPolicy(conf *config.TailSamplingPolicy) (TailPolicy, error) {
+	name := conf.Name
+	if name == "" {
+		name = conf.Type
+	}
+	switch conf.Type {
+	case "latency":
+		if conf.ThresholdMs <= 0 {
+			return nil, fmt.Errorf("tail sampling policy %q: threshold_ms must be positive", name)
+		}
+		return &latencyPolicy{name: name, threshold: time.Duration(conf.ThresholdMs * float64(time.Millisecond))}, nil
+	case "error":
+		return &errorPolicy{name: name}, nil
+	case "attribute":
+		if conf.Key == "" {
+			return nil, fmt.Errorf("tail sampling policy %q: key is required", name)
+		}
+		values := make(map[string]struct{}, len(conf.Values))
+		for _, v := range conf.Values {
+			values[v] = struct{}{}
+		}
+		return &attributePolicy{name: name, key: conf.Key, values: values}, nil
+	case "rate":
+		if conf.Rate < 0 || conf.Rate > 1 {
+			return nil, fmt.Errorf("tail sampling policy %q: rate must be between 0 and 1", name)
+		}
+		return &ratePolicy{name: name, service: conf.Service, rate: conf.Rate}, nil
+	default:
+		return nil, fmt.Errorf("tail sampling policy %q: unknown type %q, valid types are latency, error, attribute and rate", name, conf.Type)
+	}
+}
+
+// latencyPolicy keeps the traces lasting at least threshold, from the start of
+// their first span to the end of their last span.
+type latencyPolicy struct {
+	name      string
+	threshold time.Duration
+}
+
+func (p *latencyPolicy) Name() string { return p.name }
+
+func (p *latencyPolicy) Keep(_ uint64, _ *pb.Span, spans []*pb.Span) bool {
+	if len(spans) == 0 {
+		return false
+	}
+	start, end := spans[0].Start, spans[0].Start+spans[0].Duration
+	for _, s := range spans[1:] {
+		if s.Start < start {
+			start = s.Start
+		}
+		if s.Start+s.Duration > end {
+			end = s.Start + s.Duration
+		}
+	}
+	return time.Duration(end-start) >= p.threshold
+}
+
+// errorPolicy keeps the traces with an error on any of their spans.
+type errorPolicy struct {
+	name string
+}
+
+func (p *errorPolicy) Name() string { return p.name }
+
+func (p *errorPolicy) Keep(_ uint64, _ *pb.Span, spans []*pb.Span) bool {
+	for _, s := range spans {
+		if s.Error != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// attributePolicy keeps the traces with a span having the tag key set to one of values.
+type attributePolicy struct {
+	name   string
+	key    string
+	values map[string]struct{}
+}
+
+func (p *attributePolicy) Name() string { return p.name }
+
+func (p *attributePolicy) Keep(_ uint64, _ *pb.Span, spans []*pb.Span) bool {
+	for _, s := range spans {
+		v, ok := s.Meta[p.key]
+		if !ok {
+			continue
+		}
+		if len(p.values) == 0 {
+			return true
+		}
+		if _, ok := p.values[v]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ratePolicy keeps a ratio of the traces of a service, based on their trace ID.
+type ratePolicy struct {
+	name    string
+	service string
+	rate    float64
+}
+
+func (p *ratePolicy) Name() string { return p.name }
+
+func (p *ratePolicy) Keep(traceID uint64, root *pb.Span, _ []*pb.Span) bool {
+	if p.service != "" && (root == nil || root.Service != p.service) {
+		return false
+	}
+	return SampleByRate(traceID, p.rate)
+}
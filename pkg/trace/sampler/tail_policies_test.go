@@ -0,0 +1,74 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sampler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTailPolicyErrors(t *testing.T) {
+	for _, conf := range []*config.TailSamplingPolicy{
+		{Type: "latency"},
+		{Type: "attribute"},
+		{Type: "rate", Rate: 1.5},
+		{Type: "unknown"},
+	} {
+		_, err := NewTailPolicy(conf)
+		assert.Error(t, err, conf.Type)
+	}
+}
+
+func TestTailPolicies(t *testing.T) {
+	root := &pb.Span{Service: "web", Start: 0, Duration: int64(10 * time.Millisecond)}
+	child := &pb.Span{Service: "db", ParentID: 1, Start: int64(50 * time.Millisecond), Duration: int64(60 * time.Millisecond), Meta: map[string]string{"customer": "acme"}}
+	failed := &pb.Span{Service: "db", ParentID: 1, Error: 1}
+
+	for _, tc := range []struct {
+		name     string
+		conf     config.TailSamplingPolicy
+		spans    []*pb.Span
+		expected bool
+	}{
+		{"latency-kept", config.TailSamplingPolicy{Type: "latency", ThresholdMs: 100}, []*pb.Span{root, child}, true},
+		{"latency-dropped", config.TailSamplingPolicy{Type: "latency", ThresholdMs: 100}, []*pb.Span{root}, false},
+		{"error-kept", config.TailSamplingPolicy{Type: "error"}, []*pb.Span{root, failed}, true},
+		{"error-dropped", config.TailSamplingPolicy{Type: "error"}, []*pb.Span{root, child}, false},
+		{"attribute-value-kept", config.TailSamplingPolicy{Type: "attribute", Key: "customer", Values: []string{"acme"}}, []*pb.Span{root, child}, true},
+		{"attribute-value-dropped", config.TailSamplingPolicy{Type: "attribute", Key: "customer", Values: []string{"other"}}, []*pb.Span{root, child}, false},
+		{"attribute-presence-kept", config.TailSamplingPolicy{Type: "attribute", Key: "customer"}, []*pb.Span{root, child}, true},
+		{"rate-all-kept", config.TailSamplingPolicy{Type: "rate", Service: "web", Rate: 1}, []*pb.Span{root}, true},
+		{"rate-none-dropped", config.TailSamplingPolicy{Type: "rate", Rate: 0}, []*pb.Span{root}, false},
+		{"rate-other-service-dropped", config.TailSamplingPolicy{Type: "rate", Service: "db", Rate: 1}, []*pb.Span{root, child}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := NewTailPolicy(&tc.conf)
+			require.NoError(t, err)
+			assert.Equal(t, tc.conf.Type, p.Name())
+			assert.Equal(t, tc.expected, p.Keep(42, root, tc.spans))
+		})
+	}
+}
+
+func TestTailRatePolicyIsDeterministic(t *testing.T) {
+	p, err := NewTailPolicy(&config.TailSamplingPolicy{Name: "half", Type: "rate", Rate: 0.5})
+	require.NoError(t, err)
+	assert.Equal(t, "half", p.Name())
+
+	var kept int
+	for id := uint64(1); id <= 1000; id++ {
+		if p.Keep(id, nil, nil) {
+			kept++
+			assert.True(t, p.Keep(id, nil, nil))
+		}
+	}
+	assert.InDelta(t, 500, kept, 100)
+}
@@ -33,6 +33,8 @@ type BucketsAggregationKey struct {
 	Type       string
 	StatusCode uint32
 	Synthetics bool
+	// Tags holds the extra aggregation dimensions, see joinTags.
+	Tags string
 }
 
 // PayloadAggregationKey specifies the key by which a payload is aggregated.
@@ -72,6 +74,23 @@ func NewAggregationFromSpan(s *pb.Span, origin string, aggKey PayloadAggregation
 	}
 }
 
+// tagsSeparator separates the key:value pairs of the extra aggregation dimensions in
+// BucketsAggregationKey.Tags. It is not expected in tag values.
+const tagsSeparator = "\x00"
+
+// joinTags returns the key:value pairs tags, sorted by key, as a BucketsAggregationKey.Tags.
+func joinTags(tags []string) string {
+	return strings.Join(tags, tagsSeparator)
+}
+
+// splitTags returns the key:value pairs of the BucketsAggregationKey.Tags tags.
+func splitTags(tags string) []string {
+	if tags == "" {
+		return nil
+	}
+	return strings.Split(tags, tagsSeparator)
+}
+
 // NewAggregationFromGroup gets the Aggregation key of grouped stats.
 func NewAggregationFromGroup(g pb.ClientGroupedStats) Aggregation {
 	return Aggregation{
@@ -81,6 +100,7 @@ func NewAggregationFromGroup(g pb.ClientGroupedStats) Aggregation {
 			Name:       g.Name,
 			StatusCode: g.HTTPStatusCode,
 			Synthetics: g.Synthetics,
+			Tags:       joinTags(g.Tags),
 		},
 	}
 }
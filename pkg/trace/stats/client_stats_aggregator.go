@@ -40,6 +40,10 @@ type ClientStatsAggregator struct {
 	out     chan pb.StatsPayload
 	buckets map[int64]*bucket // buckets used to aggregate client stats
 
+	// extra holds the extra aggregation dimensions, limited until extraResetTs.
+	extra        *extraAggregators
+	extraResetTs time.Time
+
 	flushTicker   *time.Ticker
 	oldestTs      time.Time
 	agentEnv      string
@@ -55,6 +59,7 @@ func NewClientStatsAggregator(conf *config.AgentConfig, out chan pb.StatsPayload
 		flushTicker:   time.NewTicker(time.Second),
 		In:            make(chan pb.ClientStatsPayload, 10),
 		buckets:       make(map[int64]*bucket, 20),
+		extra:         newExtraAggregators(conf.ExtraAggregators, conf.ExtraAggregatorsMaxCardinality),
 		out:           out,
 		agentEnv:      conf.DefaultEnv,
 		agentHostname: conf.Hostname,
@@ -99,6 +104,10 @@ func (a *ClientStatsAggregator) flushOnTime(now time.Time) {
 		}
 	}
 	a.oldestTs = flushTs
+	if !now.Before(a.extraResetTs) {
+		a.extra.reset()
+		a.extraResetTs = now.Add(clientBucketDuration)
+	}
 }
 
 func (a *ClientStatsAggregator) flushAll() {
@@ -128,6 +137,9 @@ func (a *ClientStatsAggregator) add(now time.Time, p pb.ClientStatsPayload) {
 			clientBucket.AgentTimeShift = ts.Sub(clientBucketStart).Nanoseconds()
 			clientBucket.Start = uint64(ts.UnixNano())
 		}
+		for i := range clientBucket.Stats {
+			clientBucket.Stats[i].Tags = a.extra.fromTags(clientBucket.Stats[i].Tags)
+		}
 		b, ok := a.buckets[ts.Unix()]
 		if !ok {
 			b = &bucket{ts: ts}
@@ -236,6 +248,7 @@ func (b *bucket) aggregationToPayloads() []pb.ClientStatsPayload {
 				HTTPStatusCode: aggrKey.StatusCode,
 				Type:           aggrKey.Type,
 				Synthetics:     aggrKey.Synthetics,
+				Tags:           splitTags(aggrKey.Tags),
 				Hits:           counts.hits,
 				Errors:         counts.errors,
 				Duration:       counts.duration,
@@ -270,6 +283,7 @@ func newBucketAggregationKey(b pb.ClientGroupedStats) BucketsAggregationKey {
 		Type:       b.Type,
 		Synthetics: b.Synthetics,
 		StatusCode: b.HTTPStatusCode,
+		Tags:       joinTags(b.Tags),
 	}
 }
 
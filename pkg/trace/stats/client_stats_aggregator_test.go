@@ -19,8 +19,9 @@ var fuzzer = fuzz.NewWithSeed(1)
 
 func newTestAggregator() *ClientStatsAggregator {
 	conf := &config.AgentConfig{
-		DefaultEnv: "agentEnv",
-		Hostname:   "agentHostname",
+		DefaultEnv:       "agentEnv",
+		Hostname:         "agentHostname",
+		ExtraAggregators: []string{"peer.service"},
 	}
 	a := NewClientStatsAggregator(conf, make(chan pb.StatsPayload, 100))
 	a.Start()
@@ -56,6 +57,7 @@ func payloadWithCounts(ts time.Time, k BucketsAggregationKey, hits, errors, dura
 						HTTPStatusCode: k.StatusCode,
 						Type:           k.Type,
 						Synthetics:     k.Synthetics,
+						Tags:           splitTags(k.Tags),
 						Hits:           hits,
 						Errors:         errors,
 						Duration:       duration,
@@ -70,6 +72,10 @@ func getTestStatsWithStart(start time.Time) pb.ClientStatsPayload {
 	b := pb.ClientStatsBucket{}
 	fuzzer.Fuzz(&b)
 	b.Start = uint64(start.UnixNano())
+	for i := range b.Stats {
+		// tags are dropped unless they are extra aggregation dimensions
+		b.Stats[i].Tags = nil
+	}
 	p := pb.ClientStatsPayload{}
 	fuzzer.Fuzz(&p)
 	p.Tags = nil
@@ -289,6 +295,11 @@ func TestCountAggregation(t *testing.T) {
 			pb.ClientGroupedStats{HTTPStatusCode: 10},
 			"status",
 		},
+		{
+			BucketsAggregationKey{Tags: "peer.service:users-db"},
+			pb.ClientGroupedStats{Tags: []string{"peer.service:users-db"}},
+			"tags",
+		},
 	}
 	for _, tc := range tts {
 		t.Run(tc.name, func(t *testing.T) {
@@ -43,6 +43,7 @@ type Concentrator struct {
 	exit          chan struct{}
 	exitWG        sync.WaitGroup
 	buckets       map[int64]*RawBucket // buckets used to aggregate stats per timestamp
+	extra         *extraAggregators    // extra aggregation dimensions, limited per flush
 	mu            sync.Mutex
 	agentEnv      string
 	agentHostname string
@@ -54,6 +55,7 @@ func NewConcentrator(conf *config.AgentConfig, out chan pb.StatsPayload, now tim
 	c := Concentrator{
 		bsize:   bsize,
 		buckets: make(map[int64]*RawBucket),
+		extra:   newExtraAggregators(conf.ExtraAggregators, conf.ExtraAggregatorsMaxCardinality),
 		// At start, only allow stats for the current time bucket. Ensure we don't
 		// override buckets which could have been sent before an Agent restart.
 		oldestTs: alignTs(now.UnixNano(), bsize),
@@ -179,7 +181,7 @@ func (c *Concentrator) addNow(pt *traceutil.ProcessedTrace, containerID string)
 			b = NewRawBucket(uint64(btime), uint64(c.bsize))
 			c.buckets[btime] = b
 		}
-		b.HandleSpan(s, weight, isTop, pt.TraceChunk.Origin, aggKey)
+		b.handleSpan(s, weight, isTop, pt.TraceChunk.Origin, aggKey, c.extra.fromSpan(s))
 	}
 }
 
@@ -205,6 +207,7 @@ func (c *Concentrator) flushNow(now int64) pb.StatsPayload {
 		}
 		delete(c.buckets, ts)
 	}
+	c.extra.reset()
 	// After flushing, update the oldest timestamp allowed to prevent having stats for
 	// an already-flushed bucket.
 	newOldestTs := alignTs(now, c.bsize) - int64(c.bufferLen-1)*c.bsize
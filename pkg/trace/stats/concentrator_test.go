@@ -528,3 +528,32 @@ func TestIgnoresPartialSpans(t *testing.T) {
 	stats := c.flushNow(now.UnixNano() + int64(c.bufferLen)*testBucketInterval)
 	assert.Empty(stats.GetStats())
 }
+
+func TestConcentratorExtraAggregators(t *testing.T) {
+	assert := assert.New(t)
+	now := time.Now()
+	spans := []*pb.Span{
+		testSpan(1, 0, 50, 5, "A1", "resource1", 0),
+		testSpan(2, 1, 40, 5, "A1", "resource1", 0),
+		testSpan(3, 1, 30, 5, "A1", "resource1", 0),
+		testSpan(4, 1, 20, 5, "A1", "resource1", 0),
+	}
+	for _, s := range spans {
+		s.Metrics = map[string]float64{"_dd.measured": 1}
+	}
+	spans[1].Meta = map[string]string{"peer.service": "users-db"}
+	spans[2].Meta = map[string]string{"peer.service": "users-db"}
+	spans[3].Meta = map[string]string{"peer.service": "orders-db"}
+	traceutil.ComputeTopLevel(spans)
+
+	c := NewTestConcentrator(now)
+	c.extra = newExtraAggregators([]string{"peer.service"}, 1)
+	c.addNow(toProcessedTrace(spans, "none", ""), "")
+	stats := c.flushNow(now.UnixNano() + int64(c.bufferLen)*testBucketInterval)
+
+	hits := make(map[string]uint64)
+	for _, g := range stats.Stats[0].Stats[0].Stats {
+		hits[joinTags(g.Tags)] += g.Hits
+	}
+	assert.Equal(map[string]uint64{"": 1, "peer.service:users-db": 2, "peer.service:_other": 1}, hits)
+}
@@ -0,0 +1,101 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package stats
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+)
+
+// tagValueOther replaces the values of an extra aggregation dimension once its
+// cardinality limit is reached.
+const tagValueOther = "_other"
+
+// extraAggregators computes the extra aggregation dimensions of the stats, taken from
+// the span tags. The number of distinct values of each dimension is limited until the
+// next reset, values beyond the limit being aggregated together as tagValueOther.
+// It is not safe for concurrent use.
+type extraAggregators struct {
+	// keys holds the tag keys of the dimensions, sorted.
+	keys           []string
+	maxCardinality int
+	// seen holds the values of each dimension seen since the last reset.
+	seen map[string]map[string]struct{}
+}
+
+func newExtraAggregators(keys []string, maxCardinality int) *extraAggregators {
+	sorted := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if k != "" {
+			sorted = append(sorted, k)
+		}
+	}
+	sort.Strings(sorted)
+	e := &extraAggregators{keys: sorted, maxCardinality: maxCardinality}
+	e.reset()
+	return e
+}
+
+// fromSpan returns the extra aggregation dimensions of s, as a BucketsAggregationKey.Tags.
+func (e *extraAggregators) fromSpan(s *pb.Span) string {
+	if len(e.keys) == 0 {
+		return ""
+	}
+	var tags []string
+	for _, k := range e.keys {
+		if v, ok := s.Meta[k]; ok {
+			tags = append(tags, k+":"+e.limit(k, v))
+		}
+	}
+	return joinTags(tags)
+}
+
+// fromTags returns the extra aggregation dimensions held by the key:value pairs tags of
+// grouped stats computed by a tracer. Tags which are not dimensions are dropped.
+func (e *extraAggregators) fromTags(tags []string) []string {
+	if len(e.keys) == 0 || len(tags) == 0 {
+		return nil
+	}
+	values := make(map[string]string, len(tags))
+	for _, t := range tags {
+		if i := strings.IndexByte(t, ':'); i > 0 {
+			values[t[:i]] = t[i+1:]
+		}
+	}
+	var res []string
+	for _, k := range e.keys {
+		if v, ok := values[k]; ok {
+			res = append(res, k+":"+e.limit(k, v))
+		}
+	}
+	return res
+}
+
+// limit returns v, or tagValueOther if the cardinality limit of the dimension k is reached.
+func (e *extraAggregators) limit(k, v string) string {
+	seen, ok := e.seen[k]
+	if !ok {
+		seen = make(map[string]struct{})
+		e.seen[k] = seen
+	}
+	if _, ok := seen[v]; ok {
+		return v
+	}
+	if e.maxCardinality > 0 && len(seen) >= e.maxCardinality {
+		metrics.Count("datadog.trace_agent.stats.extra_aggregators.overflow", 1, []string{"tag:" + k}, 1)
+		return tagValueOther
+	}
+	seen[v] = struct{}{}
+	return v
+}
+
+// reset resets the cardinality limits of the dimensions.
+func (e *extraAggregators) reset() {
+	e.seen = make(map[string]map[string]struct{}, len(e.keys))
+}
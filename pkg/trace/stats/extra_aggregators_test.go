@@ -0,0 +1,42 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package stats
+
+import (
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtraAggregatorsFromSpan(t *testing.T) {
+	assert := assert.New(t)
+	e := newExtraAggregators([]string{"peer.service", "db.instance", ""}, 2)
+
+	span := &pb.Span{Meta: map[string]string{"peer.service": "users-db", "db.instance": "users", "env": "prod"}}
+	assert.Equal([]string{"db.instance:users", "peer.service:users-db"}, splitTags(e.fromSpan(span)))
+	assert.Equal("", e.fromSpan(&pb.Span{}))
+
+	// the third value of a dimension exceeds the limit
+	assert.Equal("peer.service:orders-db", e.fromSpan(&pb.Span{Meta: map[string]string{"peer.service": "orders-db"}}))
+	assert.Equal("peer.service:_other", e.fromSpan(&pb.Span{Meta: map[string]string{"peer.service": "carts-db"}}))
+	assert.Equal("peer.service:users-db", e.fromSpan(&pb.Span{Meta: map[string]string{"peer.service": "users-db"}}))
+
+	e.reset()
+	assert.Equal("peer.service:carts-db", e.fromSpan(&pb.Span{Meta: map[string]string{"peer.service": "carts-db"}}))
+
+	assert.Equal("", newExtraAggregators(nil, 10).fromSpan(span))
+}
+
+func TestExtraAggregatorsFromTags(t *testing.T) {
+	assert := assert.New(t)
+	e := newExtraAggregators([]string{"peer.service", "db.instance"}, 0)
+
+	assert.Equal([]string{"db.instance:users:v2", "peer.service:users-db"}, e.fromTags([]string{"peer.service:users-db", "env:prod", "db.instance:users:v2", "invalid"}))
+	assert.Nil(e.fromTags([]string{"env:prod"}))
+	assert.Nil(e.fromTags(nil))
+	assert.Nil(newExtraAggregators(nil, 0).fromTags([]string{"peer.service:users-db"}))
+}
@@ -72,6 +72,7 @@ func (s *groupedStats) export(a Aggregation) (pb.ClientGroupedStats, error) {
 		OkSummary:      okSummary,
 		ErrorSummary:   errSummary,
 		Synthetics:     a.Synthetics,
+		Tags:           splitTags(a.Tags),
 	}, nil
 }
 
@@ -145,10 +146,16 @@ func (sb *RawBucket) Export() map[PayloadAggregationKey]pb.ClientStatsBucket {
 
 // HandleSpan adds the span to this bucket stats, aggregated with the finest grain matching given aggregators
 func (sb *RawBucket) HandleSpan(s *pb.Span, weight float64, isTop bool, origin string, aggKey PayloadAggregationKey) {
+	sb.handleSpan(s, weight, isTop, origin, aggKey, "")
+}
+
+// handleSpan is HandleSpan aggregating the span by the extra aggregation dimensions tags too.
+func (sb *RawBucket) handleSpan(s *pb.Span, weight float64, isTop bool, origin string, aggKey PayloadAggregationKey, tags string) {
 	if aggKey.Env == "" {
 		panic("env should never be empty")
 	}
 	aggr := NewAggregationFromSpan(s, origin, aggKey)
+	aggr.Tags = tags
 	sb.add(s, weight, isTop, aggr)
 }
 
@@ -36,14 +36,16 @@ type GaugeSummary struct {
 type TestStatsClient struct {
 	mu sync.RWMutex
 
-	GaugeErr       error
-	GaugeCalls     []MetricsArgs
-	CountErr       error
-	CountCalls     []MetricsArgs
-	HistogramErr   error
-	HistogramCalls []MetricsArgs
-	TimingErr      error
-	TimingCalls    []MetricsArgs
+	GaugeErr          error
+	GaugeCalls        []MetricsArgs
+	CountErr          error
+	CountCalls        []MetricsArgs
+	HistogramErr      error
+	HistogramCalls    []MetricsArgs
+	DistributionErr   error
+	DistributionCalls []MetricsArgs
+	TimingErr         error
+	TimingCalls       []MetricsArgs
 }
 
 // Reset resets client's internal records.
@@ -56,6 +58,8 @@ func (c *TestStatsClient) Reset() {
 	c.CountCalls = c.CountCalls[:0]
 	c.HistogramErr = nil
 	c.HistogramCalls = c.HistogramCalls[:0]
+	c.DistributionErr = nil
+	c.DistributionCalls = c.DistributionCalls[:0]
 	c.TimingErr = nil
 	c.TimingCalls = c.TimingCalls[:0]
 }
@@ -90,6 +94,14 @@ func (c *TestStatsClient) Histogram(name string, value float64, tags []string, r
 	return c.HistogramErr
 }
 
+// Distribution records a call to a Distribution operation and replies with DistributionErr
+func (c *TestStatsClient) Distribution(name string, value float64, tags []string, rate float64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.DistributionCalls = append(c.DistributionCalls, MetricsArgs{Name: name, Value: value, Tags: tags, Rate: rate})
+	return c.DistributionErr
+}
+
 // Timing records a call to a Timing operation.
 func (c *TestStatsClient) Timing(name string, value time.Duration, tags []string, rate float64) error {
 	c.mu.Lock()
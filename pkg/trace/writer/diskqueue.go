@@ -0,0 +1,192 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package writer
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+)
+
+const (
+	// diskQueueExtension is the extension of the files holding the stored payloads.
+	diskQueueExtension = ".payload"
+	// diskQueueFileFormat prefixes the names of the stored payloads, so that they sort
+	// in the order they were stored.
+	diskQueueFileFormat = "20060102T150405.000000000_"
+)
+
+// errCorruptPayload is returned when loading a stored payload which can not be decoded.
+var errCorruptPayload = errors.New("corrupt payload file")
+
+// diskQueue stores payloads on disk so that they can be sent later, including after a
+// restart of the agent. It is bounded in size, dropping its oldest payloads to make room
+// for new ones, and discards the payloads older than its maximum age when loading them.
+// It is safe for concurrent use.
+type diskQueue struct {
+	dir     string
+	maxSize int64
+	maxAge  time.Duration
+
+	mu    sync.Mutex      // guards below
+	files []diskQueueFile // stored payloads, oldest first
+	size  int64           // total size of files, in bytes
+}
+
+// diskQueueFile is a payload stored by a diskQueue.
+type diskQueueFile struct {
+	path   string
+	size   int64
+	stored time.Time
+}
+
+// newDiskQueue returns a diskQueue storing payloads in dir, up to maxSize bytes. Stored
+// payloads older than maxAge are discarded; 0 means no limit. The payloads already in dir,
+// stored by a previous run, are kept.
+func newDiskQueue(dir string, maxSize int64, maxAge time.Duration) (*diskQueue, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	q := &diskQueue{
+		dir:     dir,
+		maxSize: maxSize,
+		maxAge:  maxAge,
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if !e.Mode().IsRegular() || filepath.Ext(e.Name()) != diskQueueExtension {
+			continue
+		}
+		q.files = append(q.files, diskQueueFile{
+			path:   filepath.Join(dir, e.Name()),
+			size:   e.Size(),
+			stored: e.ModTime(),
+		})
+		q.size += e.Size()
+	}
+	sort.Slice(q.files, func(i, j int) bool {
+		return q.files[i].path < q.files[j].path
+	})
+	if len(q.files) > 0 {
+		log.Infof("Found %d payloads (%d bytes) to send again in %s", len(q.files), q.size, dir)
+	}
+	return q, nil
+}
+
+// len returns the number of stored payloads.
+func (q *diskQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.files)
+}
+
+// store writes p to disk.
+func (q *diskQueue) store(p *payload) error {
+	headers, err := json.Marshal(p.headers)
+	if err != nil {
+		return err
+	}
+	size := int64(4 + len(headers) + p.body.Len())
+	if size > q.maxSize {
+		return fmt.Errorf("payload is too big to be stored (%d bytes, maximum is %d)", size, q.maxSize)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.files) > 0 && q.size+size > q.maxSize {
+		log.Warnf("Maximum disk space for stored payloads is reached. Removing %s", q.files[0].path)
+		q.removeFirst()
+	}
+	now := time.Now()
+	f, err := ioutil.TempFile(q.dir, now.UTC().Format(diskQueueFileFormat)+"*"+diskQueueExtension)
+	if err != nil {
+		return err
+	}
+	var n [4]byte
+	binary.BigEndian.PutUint32(n[:], uint32(len(headers)))
+	for _, b := range [][]byte{n[:], headers, p.body.Bytes()} {
+		if _, err = f.Write(b); err != nil {
+			break
+		}
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	q.files = append(q.files, diskQueueFile{path: f.Name(), size: size, stored: now})
+	q.size += size
+	return nil
+}
+
+// load removes the oldest stored payload from disk and returns it. It returns nil when
+// no payload is stored. The payloads older than the maximum age are discarded.
+func (q *diskQueue) load() (*payload, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.files) > 0 {
+		f := q.files[0]
+		if q.maxAge > 0 && time.Since(f.stored) > q.maxAge {
+			log.Warnf("Discarding stored payload %s older than %s", f.path, q.maxAge)
+			q.removeFirst()
+			continue
+		}
+		data, err := ioutil.ReadFile(f.path)
+		// remove the file even if it can not be read, to not fail on it again
+		q.removeFirst()
+		if err != nil {
+			return nil, err
+		}
+		return decodeStoredPayload(data)
+	}
+	return nil, nil
+}
+
+// removeFirst removes the oldest stored payload. q.mu must be held.
+func (q *diskQueue) removeFirst() {
+	f := q.files[0]
+	q.files = q.files[1:]
+	q.size -= f.size
+	if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+		log.Errorf("Error removing stored payload: %v", err)
+	}
+}
+
+// decodeStoredPayload decodes a payload written by diskQueue.store.
+func decodeStoredPayload(data []byte) (*payload, error) {
+	if len(data) < 4 {
+		return nil, errCorruptPayload
+	}
+	n := binary.BigEndian.Uint32(data)
+	data = data[4:]
+	if uint64(n) > uint64(len(data)) {
+		return nil, errCorruptPayload
+	}
+	var headers map[string]string
+	if err := json.Unmarshal(data[:n], &headers); err != nil {
+		return nil, errCorruptPayload
+	}
+	if headers == nil {
+		headers = make(map[string]string)
+	}
+	p := newPayload(headers)
+	p.body.Write(data[n:])
+	return p, nil
+}
@@ -0,0 +1,97 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package writer
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testDiskPayload(body string) *payload {
+	p := newPayload(map[string]string{"Content-Type": "application/msgpack", "X-Body": body})
+	p.body.WriteString(body)
+	return p
+}
+
+func TestDiskQueue(t *testing.T) {
+	t.Run("load", func(t *testing.T) {
+		assert := assert.New(t)
+		dir := t.TempDir()
+		q, err := newDiskQueue(dir, 1024*1024, time.Hour)
+		require.NoError(t, err)
+		p, err := q.load()
+		assert.NoError(err)
+		assert.Nil(p)
+
+		for _, body := range []string{"1", "2", "3"} {
+			require.NoError(t, q.store(testDiskPayload(body)))
+		}
+		assert.Equal(3, q.len())
+
+		// payloads are kept across restarts, and loaded oldest first
+		q, err = newDiskQueue(dir, 1024*1024, time.Hour)
+		require.NoError(t, err)
+		assert.Equal(3, q.len())
+		for _, body := range []string{"1", "2", "3"} {
+			p, err := q.load()
+			require.NoError(t, err)
+			assert.Equal(body, p.body.String())
+			assert.Equal(map[string]string{"Content-Type": "application/msgpack", "X-Body": body}, p.headers)
+		}
+		assert.Equal(0, q.len())
+		files, err := ioutil.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Empty(files)
+	})
+
+	t.Run("max-size", func(t *testing.T) {
+		assert := assert.New(t)
+		size := int64(4 + len(`{"Content-Type":"application/msgpack","X-Body":"1"}`) + 1)
+		q, err := newDiskQueue(t.TempDir(), 2*size, time.Hour)
+		require.NoError(t, err)
+		for _, body := range []string{"1", "2", "3"} {
+			require.NoError(t, q.store(testDiskPayload(body)))
+		}
+		assert.Equal(2, q.len())
+		assert.Equal(2*size, q.size)
+		p, err := q.load()
+		require.NoError(t, err)
+		assert.Equal("2", p.body.String())
+		assert.Error(q.store(testDiskPayload(strings.Repeat("x", int(2*size)))))
+	})
+
+	t.Run("max-age", func(t *testing.T) {
+		assert := assert.New(t)
+		q, err := newDiskQueue(t.TempDir(), 1024*1024, time.Hour)
+		require.NoError(t, err)
+		require.NoError(t, q.store(testDiskPayload("1")))
+		require.NoError(t, q.store(testDiskPayload("2")))
+		q.files[0].stored = time.Now().Add(-2 * time.Hour)
+		p, err := q.load()
+		require.NoError(t, err)
+		assert.Equal("2", p.body.String())
+		assert.Equal(0, q.len())
+	})
+
+	t.Run("corrupt", func(t *testing.T) {
+		assert := assert.New(t)
+		dir := t.TempDir()
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "0"+diskQueueExtension), []byte{0, 0, 1, 0, '{'}, 0600))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "ignored.tmp"), []byte("x"), 0600))
+		q, err := newDiskQueue(dir, 1024*1024, time.Hour)
+		require.NoError(t, err)
+		assert.Equal(1, q.len())
+		_, err = q.load()
+		assert.Equal(errCorruptPayload, err)
+		assert.Equal(0, q.len())
+	})
+}
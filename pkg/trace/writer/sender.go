@@ -7,8 +7,10 @@ package writer
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"io"
 	"io/ioutil"
 	"math"
@@ -16,6 +18,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
 	"sync"
 	"sync/atomic"
@@ -28,6 +31,8 @@ import (
 
 // newSenders returns a list of senders based on the given agent configuration, using climit
 // as the maximum number of concurrent outgoing connections, writing to path.
+// When the disk queue is enabled, each sender stores the payloads it would drop in its own
+// directory, named after the writer and the endpoint.
 func newSenders(cfg *config.AgentConfig, r eventRecorder, path string, climit, qsize int) []*sender {
 	if e := cfg.Endpoints; len(e) == 0 || e[0].Host == "" || e[0].APIKey == "" {
 		panic(errors.New("config was not properly validated"))
@@ -41,6 +46,14 @@ func newSenders(cfg *config.AgentConfig, r eventRecorder, path string, climit, q
 			log.Criticalf("Invalid host endpoint: %q", endpoint.Host)
 			os.Exit(1)
 		}
+		var dq *diskQueue
+		if dqc := cfg.DiskQueue; dqc.Enabled {
+			dir := filepath.Join(dqc.Path, diskQueueDir(url))
+			if dq, err = newDiskQueue(dir, dqc.MaxSizeBytes, dqc.MaxAge); err != nil {
+				log.Errorf("Error creating the disk queue in %s, payloads will not be stored: %v", dir, err)
+				dq = nil
+			}
+		}
 		senders[i] = newSender(&senderConfig{
 			client:    cfg.NewHTTPClient(),
 			maxConns:  int(maxConns),
@@ -48,11 +61,19 @@ func newSenders(cfg *config.AgentConfig, r eventRecorder, path string, climit, q
 			url:       url,
 			apiKey:    endpoint.APIKey,
 			recorder:  r,
+			diskQueue: dq,
 		})
 	}
 	return senders
 }
 
+// diskQueueDir returns the name of the directory where the payloads sent to u are stored.
+func diskQueueDir(u *url.URL) string {
+	h := fnv.New32a()
+	h.Write([]byte(u.String())) //nolint:errcheck
+	return fmt.Sprintf("%s_%08x", u.Hostname(), h.Sum32())
+}
+
 // eventRecorder implementations are able to take note of events happening in
 // the sender.
 type eventRecorder interface {
@@ -74,6 +95,12 @@ const (
 	// eventTypeDropped specifies that a payload had to be dropped to make room
 	// in the queue.
 	eventTypeDropped
+	// eventTypeStored specifies that a payload was stored on disk instead of
+	// being dropped.
+	eventTypeStored
+	// eventTypeReplayed specifies that a payload stored on disk was queued to
+	// be sent again.
+	eventTypeReplayed
 )
 
 var eventTypeStrings = map[eventType]string{
@@ -81,6 +108,8 @@ var eventTypeStrings = map[eventType]string{
 	eventTypeSent:     "eventTypeSent",
 	eventTypeRejected: "eventTypeRejected",
 	eventTypeDropped:  "eventTypeDropped",
+	eventTypeStored:   "eventTypeStored",
+	eventTypeReplayed: "eventTypeReplayed",
 }
 
 // String implements fmt.Stringer.
@@ -125,6 +154,9 @@ type senderConfig struct {
 	// recorder specifies the eventRecorder to use when reporting events occurring
 	// in the sender.
 	recorder eventRecorder
+	// diskQueue, if set, stores the payloads which would otherwise be dropped, to
+	// send them again later.
+	diskQueue *diskQueue
 }
 
 // sender is responsible for sending payloads to a given URL. It uses a size-limited
@@ -139,16 +171,30 @@ type sender struct {
 
 	mu     sync.RWMutex // guards closed
 	closed bool         // closed reports if the loop is stopped
+
+	// done is closed when the sender stops, to interrupt the backoff.
+	done chan struct{}
+	// ctx is the context of the requests. It is cancelled when a sender with a disk
+	// queue stops, so that the payloads being sent are stored instead.
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 // newSender returns a new sender based on the given config cfg.
 func newSender(cfg *senderConfig) *sender {
+	ctx, cancel := context.WithCancel(context.Background())
 	s := sender{
 		cfg:    cfg,
 		queue:  make(chan *payload, cfg.maxQueued),
 		climit: make(chan struct{}, cfg.maxConns),
+		done:   make(chan struct{}),
+		ctx:    ctx,
+		cancel: cancel,
 	}
 	go s.loop()
+	// payloads stored by a previous run are sent again one after the other,
+	// starting with this one
+	s.replay()
 	return &s
 }
 
@@ -171,23 +217,58 @@ func (s *sender) backoff() {
 	if delay == 0 {
 		return
 	}
-	time.Sleep(delay)
+	select {
+	case <-time.After(delay):
+	case <-s.done:
+	}
 }
 
+// senderStopTimeout is the maximum time spent by Stop waiting for the inflight payloads
+// to be sent, and then to be stored on disk.
+var senderStopTimeout = 5 * time.Second
+
 // Stop stops the sender. It attempts to wait for all inflight payloads to complete
-// with a timeout of 5 seconds.
+// with a timeout of 5 seconds. When the sender has a disk queue, the payloads still
+// queued, being sent or retried are then stored on disk, to be sent by the next run.
 func (s *sender) Stop() {
 	s.WaitForInflight()
 	s.mu.Lock()
 	s.closed = true
 	s.mu.Unlock()
+	close(s.done)
+	if s.cfg.diskQueue != nil {
+		// the requests fail and their payloads are stored by sendPayload
+		s.cancel()
+		s.storeQueued()
+		s.waitForInflight(senderStopTimeout)
+	}
 	close(s.queue)
 }
 
+// storeQueued stores the payloads of the queue on disk.
+func (s *sender) storeQueued() {
+	for {
+		select {
+		case p := <-s.queue:
+			s.dropPayload(p, &eventData{
+				bytes: p.body.Len(),
+				count: 1,
+			})
+		default:
+			return
+		}
+	}
+}
+
 // WaitForInflight blocks until all in progress payloads are sent,
 // or the timeout is reached.
 func (s *sender) WaitForInflight() {
-	timeout := time.After(5 * time.Second)
+	s.waitForInflight(senderStopTimeout)
+}
+
+// waitForInflight blocks until there are no more inflight payloads, or the timeout is reached.
+func (s *sender) waitForInflight(d time.Duration) {
+	timeout := time.After(d)
 outer:
 	for {
 		select {
@@ -214,7 +295,7 @@ func (s *sender) Push(p *payload) {
 			// drop the oldest item in the queue to make room
 			select {
 			case p := <-s.queue:
-				s.releasePayload(p, eventTypeDropped, &eventData{
+				s.dropPayload(p, &eventData{
 					bytes: p.body.Len(),
 					count: 1,
 				})
@@ -234,6 +315,7 @@ func (s *sender) sendPayload(p *payload) {
 		log.Errorf("http.Request: %s", err)
 		return
 	}
+	req = req.WithContext(s.ctx)
 	start := time.Now()
 	err = s.do(req)
 	stats := &eventData{
@@ -248,7 +330,10 @@ func (s *sender) sendPayload(p *payload) {
 		s.mu.RLock()
 		defer s.mu.RUnlock()
 		if s.closed {
-			// sender is stopped
+			// sender is stopped; keep the payload for the next run, if possible
+			if s.cfg.diskQueue != nil {
+				s.dropPayload(p, stats)
+			}
 			return
 		}
 		atomic.AddInt32(&s.attempt, 1)
@@ -265,7 +350,7 @@ func (s *sender) sendPayload(p *payload) {
 			return
 		default:
 			// queue is full; since this is the oldest payload, we drop it
-			s.releasePayload(p, eventTypeDropped, stats)
+			s.dropPayload(p, stats)
 		}
 	case nil:
 		// request was successful; the retry queue may have grown large - we should
@@ -278,6 +363,7 @@ func (s *sender) sendPayload(p *payload) {
 			}
 		}
 		s.releasePayload(p, eventTypeSent, stats)
+		s.replay()
 	default:
 		// this is a fatal error, we have to drop this payload
 		s.releasePayload(p, eventTypeRejected, stats)
@@ -305,6 +391,52 @@ func (s *sender) releasePayload(p *payload, t eventType, data *eventData) {
 	atomic.AddInt32(&s.inflight, -1)
 }
 
+// dropPayload releases the payload p which can not be kept in the queue. It is stored on
+// disk if the sender has a disk queue, and dropped otherwise.
+func (s *sender) dropPayload(p *payload, data *eventData) {
+	if q := s.cfg.diskQueue; q != nil {
+		err := q.store(p)
+		if err == nil {
+			s.releasePayload(p, eventTypeStored, data)
+			return
+		}
+		log.Errorf("Error storing payload on disk: %v", err)
+	}
+	s.releasePayload(p, eventTypeDropped, data)
+}
+
+// replay queues the oldest payload stored on disk to be sent again, if the queue is
+// not filling up. It is called after each successful send, so that the stored payloads
+// are sent again one after the other once the destination is reachable.
+func (s *sender) replay() {
+	q := s.cfg.diskQueue
+	if q == nil || q.len() == 0 || len(s.queue) > cap(s.queue)/2 {
+		return
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return
+	}
+	p, err := q.load()
+	if err != nil {
+		log.Errorf("Error loading payload stored on disk: %v", err)
+		return
+	}
+	if p == nil {
+		return
+	}
+	atomic.AddInt32(&s.inflight, 1)
+	data := &eventData{bytes: p.body.Len(), count: 1}
+	select {
+	case s.queue <- p:
+		s.recordEvent(eventTypeReplayed, data)
+	default:
+		// the queue filled up in the meantime
+		s.dropPayload(p, data)
+	}
+}
+
 // recordEvent records the occurrence of the given event type t. It additionally
 // passes on the data and augments it with additional information.
 func (s *sender) recordEvent(t eventType, data *eventData) {
@@ -127,6 +127,64 @@ func TestSender(t *testing.T) {
 		assert.Empty(t, s.queue)
 	})
 
+	t.Run("disk", func(t *testing.T) {
+		assert := assert.New(t)
+		server := newTestServer()
+		defer server.Close()
+		dq, err := newDiskQueue(t.TempDir(), 1024*1024, time.Hour)
+		assert.NoError(err)
+
+		// payloads dropped from a full queue are stored
+		var recorder mockRecorder
+		cfg := testSenderConfig(server.URL)
+		cfg.recorder = &recorder
+		cfg.diskQueue = dq
+		s := &sender{cfg: cfg, queue: make(chan *payload, 2), climit: make(chan struct{}, 1)}
+		for i := 0; i < 5; i++ {
+			s.Push(expectResponses(200))
+		}
+		assert.Len(recorder.data(eventTypeStored), 3)
+		assert.Empty(recorder.data(eventTypeDropped))
+		assert.Equal(3, dq.len())
+
+		// and sent again by the next sender, as soon as it starts
+		var replays mockRecorder
+		cfg.recorder = &replays
+		s = newSender(cfg)
+		assert.Eventually(func() bool { return dq.len() == 0 }, 5*time.Second, 10*time.Millisecond)
+		s.Stop()
+		assert.Equal(3, server.Accepted(), "accepted")
+		assert.Len(replays.data(eventTypeReplayed), 3)
+	})
+
+	t.Run("disk-stop", func(t *testing.T) {
+		assert := assert.New(t)
+		server := newTestServerWithLatency(500 * time.Millisecond)
+		defer server.Close()
+		dq, err := newDiskQueue(t.TempDir(), 1024*1024, time.Hour)
+		assert.NoError(err)
+		defer func(old time.Duration) { senderStopTimeout = old }(senderStopTimeout)
+		senderStopTimeout = 100 * time.Millisecond
+
+		// the intake is down: one payload is being sent, the others are queued
+		var recorder mockRecorder
+		cfg := testSenderConfig(server.URL)
+		cfg.recorder = &recorder
+		cfg.diskQueue = dq
+		cfg.maxConns = 1
+		s := newSender(cfg)
+		for i := 0; i < 3; i++ {
+			s.Push(expectResponses(503))
+		}
+		s.Stop()
+
+		// they are all stored by the time Stop returns
+		assert.Equal(3, dq.len())
+		assert.Len(recorder.data(eventTypeStored), 3)
+		assert.Empty(recorder.data(eventTypeDropped))
+		assert.Zero(server.Accepted())
+	})
+
 	t.Run("failed", func(t *testing.T) {
 		assert := assert.New(t)
 		server := newTestServer()
@@ -306,8 +364,8 @@ func useBackoffDuration(d time.Duration) func() {
 
 // mockRecorder is a mock eventRecorder which records all calls to recordEvent.
 type mockRecorder struct {
-	mu                             sync.RWMutex
-	retry, sent, dropped, rejected []*eventData
+	mu                                               sync.RWMutex
+	retry, sent, dropped, rejected, stored, replayed []*eventData
 }
 
 // data returns all call data for the given eventType.
@@ -323,6 +381,10 @@ func (r *mockRecorder) data(t eventType) []*eventData {
 		return r.dropped
 	case eventTypeRejected:
 		return r.rejected
+	case eventTypeStored:
+		return r.stored
+	case eventTypeReplayed:
+		return r.replayed
 	default:
 		panic("unknown event")
 	}
@@ -341,5 +403,9 @@ func (r *mockRecorder) recordEvent(t eventType, data *eventData) {
 		r.dropped = append(r.dropped, data)
 	case eventTypeRejected:
 		r.rejected = append(r.rejected, data)
+	case eventTypeStored:
+		r.stored = append(r.stored, data)
+	case eventTypeReplayed:
+		r.replayed = append(r.replayed, data)
 	}
 }
@@ -308,5 +308,14 @@ func (w *TraceWriter) recordEvent(t eventType, data *eventData) {
 		w.easylog.Warn("Trace writer queue full. Payload dropped (%.2fKB).", float64(data.bytes)/1024)
 		metrics.Count("datadog.trace_agent.trace_writer.dropped", 1, nil, 1)
 		metrics.Count("datadog.trace_agent.trace_writer.dropped_bytes", int64(data.bytes), nil, 1)
+
+	case eventTypeStored:
+		w.easylog.Warn("Trace writer queue full. Payload stored on disk (%.2fKB).", float64(data.bytes)/1024)
+		metrics.Count("datadog.trace_agent.trace_writer.disk_queue.stored", 1, nil, 1)
+		metrics.Count("datadog.trace_agent.trace_writer.disk_queue.stored_bytes", int64(data.bytes), nil, 1)
+
+	case eventTypeReplayed:
+		log.Debugf("Sending trace payload stored on disk again (%.2fKB)", float64(data.bytes)/1024)
+		metrics.Count("datadog.trace_agent.trace_writer.disk_queue.replayed", 1, nil, 1)
 	}
 }
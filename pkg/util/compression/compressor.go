@@ -0,0 +1,75 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package compression
+
+import (
+	"fmt"
+	"io"
+)
+
+const (
+	// ZlibKind compresses the payloads with zlib (deflate)
+	ZlibKind = "zlib"
+	// ZstdKind compresses the payloads with the pre-v1 zstd format accepted by the Datadog intake
+	ZstdKind = "zstd"
+	// ZstdV1Kind compresses the payloads with the stable (v1) zstd format, which the Datadog
+	// intake doesn't accept. It is never the default and must be configured explicitly.
+	ZstdV1Kind = "zstd_v1"
+	// NoneKind doesn't compress the payloads
+	NoneKind = "none"
+)
+
+// Compressor compresses and decompresses payloads with a given algorithm and level.
+type Compressor interface {
+	// Compress compresses `src` in a single call
+	Compress(src []byte) ([]byte, error)
+	// Decompress reverses Compress
+	Decompress(src []byte) ([]byte, error)
+	// CompressBound returns the worst case size needed for a destination buffer
+	CompressBound(sourceLen int) int
+	// ContentEncoding returns the HTTP header value associated with the compression method,
+	// empty when there is no compression
+	ContentEncoding() string
+	// NewStreamWriter returns a writer compressing its input into `output`
+	NewStreamWriter(output io.Writer) StreamWriter
+}
+
+// StreamWriter compresses the data written to it.
+type StreamWriter interface {
+	io.Writer
+	// Flush writes the pending compressed data to the output
+	Flush() error
+	// Close flushes the pending data and writes the footer of the compression format
+	Close() error
+}
+
+// NewCompressor returns the Compressor for `kind`. A level of 0 uses the
+// default level of the algorithm. The zstd kind returns an error when the agent
+// is built without cgo or without the zstd tag.
+func NewCompressor(kind string, level int) (Compressor, error) {
+	switch kind {
+	case ZlibKind:
+		return newZlibCompressor(level)
+	case ZstdKind:
+		return newZstdCompressor(level)
+	case ZstdV1Kind:
+		return newZstdV1Compressor(level)
+	case NoneKind:
+		return &noneCompressor{}, nil
+	default:
+		return nil, fmt.Errorf("unknown compression kind '%s', valid values are '%s', '%s', '%s' and '%s'", kind, ZlibKind, ZstdKind, ZstdV1Kind, NoneKind)
+	}
+}
+
+// NewDefaultCompressor returns the Compressor for DefaultKind with the default level.
+func NewDefaultCompressor() Compressor {
+	c, err := NewCompressor(DefaultKind, 0)
+	if err != nil {
+		// DefaultKind is always valid
+		panic(err)
+	}
+	return c
+}
@@ -0,0 +1,100 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package compression
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+type compressorTestCase struct {
+	kind            string
+	level           int
+	contentEncoding string
+}
+
+func TestCompressors(t *testing.T) {
+	testCompressors(t, []compressorTestCase{
+		{ZlibKind, 0, "deflate"},
+		{ZlibKind, 9, "deflate"},
+		// zstd_v1 is available whatever the build tags
+		{ZstdV1Kind, 0, "zstd"},
+		{ZstdV1Kind, 1, "zstd"},
+		{ZstdV1Kind, 19, "zstd"},
+		{NoneKind, 0, ""},
+	})
+}
+
+func testCompressors(t *testing.T, testCases []compressorTestCase) {
+	payload := []byte(strings.Repeat("some payload to compress ", 100))
+
+	for _, tc := range testCases {
+		t.Run(tc.kind, func(t *testing.T) {
+			c, err := NewCompressor(tc.kind, tc.level)
+			require.NoError(t, err)
+			assert.Equal(t, tc.contentEncoding, c.ContentEncoding())
+
+			compressed, err := c.Compress(payload)
+			require.NoError(t, err)
+			assert.LessOrEqual(t, len(compressed), c.CompressBound(len(payload)))
+			decompressed, err := c.Decompress(compressed)
+			require.NoError(t, err)
+			assert.Equal(t, payload, decompressed)
+
+			var output bytes.Buffer
+			w := c.NewStreamWriter(&output)
+			_, err = w.Write(payload[:100])
+			require.NoError(t, err)
+			require.NoError(t, w.Flush())
+			_, err = w.Write(payload[100:])
+			require.NoError(t, err)
+			require.NoError(t, w.Close())
+			decompressed, err = c.Decompress(output.Bytes())
+			require.NoError(t, err)
+			assert.Equal(t, payload, decompressed)
+		})
+	}
+}
+
+func TestNewCompressorErrors(t *testing.T) {
+	_, err := NewCompressor("lz4", 0)
+	assert.Error(t, err)
+
+	_, err = NewCompressor(ZlibKind, 10)
+	assert.Error(t, err)
+
+	_, err = NewCompressor(ZstdV1Kind, 23)
+	assert.Error(t, err)
+}
+
+func TestNewDefaultCompressor(t *testing.T) {
+	c := NewDefaultCompressor()
+	expected, err := NewCompressor(DefaultKind, 0)
+	require.NoError(t, err)
+	assert.Equal(t, expected, c)
+}
+
+func TestNewSerializerCompressor(t *testing.T) {
+	mockConfig := config.Mock()
+	mockConfig.Set("serializer_compressor_kind", "zlib")
+	mockConfig.Set("serializer_compressor_level", 1)
+	defer mockConfig.Set("serializer_compressor_kind", "")
+	defer mockConfig.Set("serializer_compressor_level", 0)
+
+	expected, err := NewCompressor(ZlibKind, 1)
+	require.NoError(t, err)
+	assert.Equal(t, expected, NewSerializerCompressor())
+
+	// Invalid settings fall back to the default compression
+	mockConfig.Set("serializer_compressor_kind", "lz4")
+	assert.Equal(t, NewDefaultCompressor(), NewSerializerCompressor())
+}
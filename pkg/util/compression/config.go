@@ -0,0 +1,28 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package compression
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// NewSerializerCompressor returns the Compressor set with `serializer_compressor_kind` and
+// `serializer_compressor_level`, which the serializer compresses the payloads with.
+// Invalid settings are reported and the compression falls back to DefaultKind.
+func NewSerializerCompressor() Compressor {
+	kind := config.Datadog.GetString("serializer_compressor_kind")
+	if kind == "" {
+		kind = DefaultKind
+	}
+
+	c, err := NewCompressor(kind, config.Datadog.GetInt("serializer_compressor_level"))
+	if err != nil {
+		log.Errorf("Invalid serializer compressor settings, using '%s': %v", DefaultKind, err)
+		return NewDefaultCompressor()
+	}
+	return c
+}
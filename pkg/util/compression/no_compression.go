@@ -3,27 +3,12 @@
 // This product includes software developed at Datadog (https://www.datadoghq.com/).
 // Copyright 2016-present Datadog, Inc.
 
-//go:build !zlib && !zstd
-// +build !zlib,!zstd
+//go:build !zlib && (!cgo || !zstd)
+// +build !zlib
+// +build !cgo !zstd
 
 package compression
 
-// ContentEncoding describes the HTTP header value associated with the compression method
-// empty here since there's no compression
-// var instead of const to ease testing
-var ContentEncoding = ""
-
-// Compress will not compress anything
-func Compress(src []byte) ([]byte, error) {
-	return src, nil
-}
-
-// Decompress will not decompress anything
-func Decompress(src []byte) ([]byte, error) {
-	return src, nil
-}
-
-// CompressBound returns the worst case size needed for a destination buffer
-func CompressBound(sourceLen int) int {
-	return sourceLen
-}
+// DefaultKind is the compression used when none is configured.
+// It can be overridden at runtime with NewCompressor.
+const DefaultKind = NoneKind
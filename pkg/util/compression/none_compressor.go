@@ -0,0 +1,44 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package compression
+
+import (
+	"io"
+)
+
+type noneCompressor struct{}
+
+// Compress will not compress anything
+func (c *noneCompressor) Compress(src []byte) ([]byte, error) {
+	return src, nil
+}
+
+// Decompress will not decompress anything
+func (c *noneCompressor) Decompress(src []byte) ([]byte, error) {
+	return src, nil
+}
+
+// CompressBound returns the worst case size needed for a destination buffer
+func (c *noneCompressor) CompressBound(sourceLen int) int {
+	return sourceLen
+}
+
+// ContentEncoding is empty since there's no compression
+func (c *noneCompressor) ContentEncoding() string {
+	return ""
+}
+
+// NewStreamWriter returns a writer copying its input to `output`
+func (c *noneCompressor) NewStreamWriter(output io.Writer) StreamWriter {
+	return noneStreamWriter{output}
+}
+
+type noneStreamWriter struct {
+	io.Writer
+}
+
+func (noneStreamWriter) Flush() error { return nil }
+func (noneStreamWriter) Close() error { return nil }
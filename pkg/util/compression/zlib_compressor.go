@@ -0,0 +1,75 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package compression
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+type zlibCompressor struct {
+	level int
+}
+
+func newZlibCompressor(level int) (*zlibCompressor, error) {
+	if level == 0 {
+		level = zlib.DefaultCompression
+	}
+	if level < zlib.HuffmanOnly || level > zlib.BestCompression {
+		return nil, fmt.Errorf("invalid zlib compression level %d, valid values are between %d and %d", level, zlib.HuffmanOnly, zlib.BestCompression)
+	}
+	return &zlibCompressor{level: level}, nil
+}
+
+// Compress will compress the data with zlib
+func (c *zlibCompressor) Compress(src []byte) ([]byte, error) {
+	var b bytes.Buffer
+	w, err := zlib.NewWriterLevel(&b, c.level)
+	if err != nil {
+		return nil, err
+	}
+	_, err = w.Write(src)
+	if err != nil {
+		return nil, err
+	}
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Decompress will decompress the data with zlib
+func (c *zlibCompressor) Decompress(src []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return ioutil.ReadAll(r)
+}
+
+// CompressBound returns the worst case size needed for a destination buffer
+func (c *zlibCompressor) CompressBound(sourceLen int) int {
+	// From https://code.woboq.org/gcc/zlib/compress.c.html#compressBound
+	return sourceLen + (sourceLen >> 12) + (sourceLen >> 14) + (sourceLen >> 25) + 13
+}
+
+// ContentEncoding returns the HTTP header value for zlib
+func (c *zlibCompressor) ContentEncoding() string {
+	return "deflate"
+}
+
+// NewStreamWriter returns a zlib writer
+func (c *zlibCompressor) NewStreamWriter(output io.Writer) StreamWriter {
+	// The level is validated by newZlibCompressor so there is no error
+	w, _ := zlib.NewWriterLevel(output, c.level)
+	return w
+}
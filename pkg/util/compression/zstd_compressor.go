@@ -0,0 +1,65 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build cgo && zstd
+// +build cgo,zstd
+
+package compression
+
+import (
+	"fmt"
+	"io"
+
+	zstd_0 "github.com/DataDog/zstd_0"
+)
+
+// zstdCompressor uses the pre-v1 (unstable) zstd format, which is the one the
+// Datadog intake accepts.
+type zstdCompressor struct {
+	level int
+}
+
+func newZstdCompressor(level int) (*zstdCompressor, error) {
+	if level == 0 {
+		level = zstd_0.DefaultCompression
+	}
+	if level < zstd_0.BestSpeed || level > zstd_0.BestCompression {
+		return nil, fmt.Errorf("invalid zstd compression level %d, valid values are between %d and %d", level, zstd_0.BestSpeed, zstd_0.BestCompression)
+	}
+	return &zstdCompressor{level: level}, nil
+}
+
+// Compress will compress the data with zstd
+func (c *zstdCompressor) Compress(src []byte) ([]byte, error) {
+	return zstd_0.CompressLevel(nil, src, c.level)
+}
+
+// Decompress will decompress the data with zstd
+func (c *zstdCompressor) Decompress(src []byte) ([]byte, error) {
+	return zstd_0.Decompress(nil, src)
+}
+
+// CompressBound returns the worst case size needed for a destination buffer
+func (c *zstdCompressor) CompressBound(sourceLen int) int {
+	return zstd_0.CompressBound(sourceLen)
+}
+
+// ContentEncoding returns the HTTP header value for zstd
+func (c *zstdCompressor) ContentEncoding() string {
+	return "zstd"
+}
+
+// NewStreamWriter returns a zstd writer
+func (c *zstdCompressor) NewStreamWriter(output io.Writer) StreamWriter {
+	return zstd0StreamWriter{zstd_0.NewWriterLevel(output, c.level)}
+}
+
+// zstd0StreamWriter adds Flush to the zstd_0 writer, which compresses each
+// write to the output right away.
+type zstd0StreamWriter struct {
+	*zstd_0.Writer
+}
+
+func (zstd0StreamWriter) Flush() error { return nil }
@@ -0,0 +1,37 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build cgo && zstd
+// +build cgo,zstd
+
+package compression
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZstdCompressors(t *testing.T) {
+	testCompressors(t, []compressorTestCase{
+		{ZstdKind, 0, "zstd"},
+		{ZstdKind, 1, "zstd"},
+	})
+}
+
+func TestZstdFormats(t *testing.T) {
+	payload := []byte("some payload to compress")
+	zstd0, err := NewCompressor(ZstdKind, 0)
+	require.NoError(t, err)
+	zstdV1, err := NewCompressor(ZstdV1Kind, 0)
+	require.NoError(t, err)
+
+	// The pre-v1 format accepted by the intake is not the stable one
+	compressed, err := zstd0.Compress(payload)
+	require.NoError(t, err)
+	_, err = zstdV1.Decompress(compressed)
+	assert.Error(t, err)
+}
@@ -0,0 +1,20 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !cgo || !zstd
+// +build !cgo !zstd
+
+package compression
+
+import (
+	"errors"
+)
+
+// The pre-v1 zstd library uses cgo, so zstd is only available in the builds with cgo and the zstd tag.
+var errZstdUnavailable = errors.New("zstd compression is not available in this build of the agent, use zstd_v1 with endpoints accepting it")
+
+func newZstdCompressor(level int) (Compressor, error) {
+	return nil, errZstdUnavailable
+}
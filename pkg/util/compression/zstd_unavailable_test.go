@@ -0,0 +1,20 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !cgo || !zstd
+// +build !cgo !zstd
+
+package compression
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestZstdUnavailable(t *testing.T) {
+	_, err := NewCompressor(ZstdKind, 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,81 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package compression
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	zstdV1BestSpeed          = 1
+	zstdV1BestCompression    = 22
+	zstdV1DefaultCompression = 3
+)
+
+// zstdV1Compressor uses the stable (v1) zstd format. The Datadog intake doesn't
+// accept it, so it must only be used with endpoints which do, such as a proxy.
+// It is written in pure Go, so it is available in every build of the agent.
+type zstdV1Compressor struct {
+	level   zstd.EncoderLevel
+	encoder *zstd.Encoder
+}
+
+func newZstdV1Compressor(level int) (*zstdV1Compressor, error) {
+	if level == 0 {
+		level = zstdV1DefaultCompression
+	}
+	if level < zstdV1BestSpeed || level > zstdV1BestCompression {
+		return nil, fmt.Errorf("invalid zstd compression level %d, valid values are between %d and %d", level, zstdV1BestSpeed, zstdV1BestCompression)
+	}
+	encoderLevel := zstd.EncoderLevelFromZstd(level)
+	// The encoder only compresses whole payloads with EncodeAll, which is safe for concurrent use.
+	encoder, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(encoderLevel), zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return &zstdV1Compressor{level: encoderLevel, encoder: encoder}, nil
+}
+
+// Compress will compress the data with zstd
+func (c *zstdV1Compressor) Compress(src []byte) ([]byte, error) {
+	return c.encoder.EncodeAll(src, make([]byte, 0, c.CompressBound(len(src)))), nil
+}
+
+// Decompress will decompress the data with zstd
+func (c *zstdV1Compressor) Decompress(src []byte) ([]byte, error) {
+	decoder, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	defer decoder.Close()
+	return decoder.DecodeAll(src, nil)
+}
+
+// CompressBound returns the worst case size needed for a destination buffer,
+// as computed by ZSTD_compressBound in the reference implementation.
+func (c *zstdV1Compressor) CompressBound(sourceLen int) int {
+	const blockSize = 128 << 10
+	bound := sourceLen + sourceLen>>8
+	if sourceLen < blockSize {
+		bound += (blockSize - sourceLen) >> 11
+	}
+	return bound
+}
+
+// ContentEncoding returns the HTTP header value for zstd
+func (c *zstdV1Compressor) ContentEncoding() string {
+	return "zstd"
+}
+
+// NewStreamWriter returns a zstd writer
+func (c *zstdV1Compressor) NewStreamWriter(output io.Writer) StreamWriter {
+	// The options are valid, as they are the ones of the encoder of the compressor.
+	w, _ := zstd.NewWriter(output, zstd.WithEncoderLevel(c.level), zstd.WithEncoderConcurrency(1))
+	return w
+}
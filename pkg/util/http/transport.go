@@ -20,6 +20,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/net/http2"
 )
 
 var (
@@ -110,6 +111,22 @@ func CreateHTTPTransport() *http.Transport {
 	return transport
 }
 
+// ConfigureHTTP2 enables HTTP/2 on a transport created by CreateHTTPTransport.
+// When `strictMaxConcurrentStreams` is true, requests wait for a free stream on the existing
+// connection instead of opening a new connection once the server's limit of concurrent streams is reached.
+func ConfigureHTTP2(transport *http.Transport, strictMaxConcurrentStreams bool) error {
+	http2Transport, err := http2.ConfigureTransports(transport)
+	if err != nil {
+		return err
+	}
+	http2Transport.StrictMaxConcurrentStreams = strictMaxConcurrentStreams
+	// Send a ping when no frame is received for this duration to detect broken connections
+	// as a single connection is used for all the requests.
+	http2Transport.ReadIdleTimeout = 30 * time.Second
+	http2Transport.PingTimeout = 15 * time.Second
+	return nil
+}
+
 // GetProxyTransportFunc return a proxy function for a http.Transport that
 // would return the right proxy depending on the configuration.
 func GetProxyTransportFunc(p *config.Proxy) func(*http.Request) (*url.URL, error) {
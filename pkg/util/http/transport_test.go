@@ -16,6 +16,16 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/config"
 )
 
+func TestConfigureHTTP2(t *testing.T) {
+	transport := CreateHTTPTransport()
+	require.NoError(t, ConfigureHTTP2(transport, true))
+	assert.Contains(t, transport.TLSClientConfig.NextProtos, "h2")
+	assert.Contains(t, transport.TLSNextProto, "h2")
+
+	// HTTP/2 cannot be configured twice on the same transport.
+	assert.Error(t, ConfigureHTTP2(transport, true))
+}
+
 func TestEmptyProxy(t *testing.T) {
 	r, err := http.NewRequest("GET", "https://test.com", nil)
 	require.Nil(t, err)
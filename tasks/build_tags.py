@@ -42,6 +42,7 @@ ALL_TAGS = {
     "systemd",
     "zk",
     "zlib",
+    "zstd",
 }
 
 ### Tag inclusion lists
@@ -70,6 +71,7 @@ AGENT_TAGS = {
     "systemd",
     "zk",
     "zlib",
+    "zstd",
 }
 
 # AGENT_HEROKU_TAGS lists the tags for Heroku agent build
@@ -537,7 +537,7 @@ def build_network_ebpf_files(ctx, build_dir, parallel_build=True):
     network_c_dir = os.path.join(network_bpf_dir, "c")
     network_prebuilt_dir = os.path.join(network_c_dir, "prebuilt")
 
-    compiled_programs = ["dns", "http", "offset-guess", "tracer"]
+    compiled_programs = ["database", "dns", "http", "kafka", "offset-guess", "tracer"]
 
     network_flags = get_ebpf_build_flags()
     network_flags.append(f"-I{network_c_dir}")
@@ -687,6 +687,8 @@ def generate_runtime_files(ctx):
         "./pkg/collector/corechecks/ebpf/probe/oom_kill.go",
         "./pkg/collector/corechecks/ebpf/probe/tcp_queue_length.go",
         "./pkg/network/http/compile.go",
+        "./pkg/network/kafka/compile.go",
+        "./pkg/network/database/compile.go",
         "./pkg/network/tracer/compile.go",
         "./pkg/network/tracer/connection/kprobe/compile.go",
         "./pkg/security/ebpf/compile.go",